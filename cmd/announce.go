@@ -38,7 +38,7 @@ func RunAnnouncements() string {
 			continue
 		}
 		if err := a.Setup(); err != nil {
-			logger.Debug("Announcement setup failed: %v", err)
+			logger.Debugf("Announcement setup failed: %v", err)
 			continue
 		}
 		messages = append(messages, a.Message)
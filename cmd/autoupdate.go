@@ -60,7 +60,7 @@ func runAutoupdateDisable(cmd *cobra.Command, args []string) error {
 }
 
 func setAutoUpdate(enabled bool) error {
-	cfg, err := config.GetUploadConfig()
+	cfg, err := config.GetGlobalUploadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to read config: %w", err)
 	}
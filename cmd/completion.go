@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish]",
+	Short: "Generate a shell completion script",
+	Long: `Print a completion script for confab subcommands and flags.
+
+Besides subcommands, --provider completes with provider names,
+--backend-url with the backend URL(s) already in your config, and
+--config-dir with directories.
+
+Bash (requires the bash-completion package):
+  confab completion bash > /etc/bash_completion.d/confab
+  # or, per user:
+  confab completion bash > ~/.local/share/bash-completion/completions/confab
+
+Zsh:
+  echo "autoload -U compinit; compinit" >> ~/.zshrc   # if not already enabled
+  confab completion zsh > "${fpath[1]}/_confab"
+
+Fish:
+  confab completion fish > ~/.config/fish/completions/confab.fish
+
+Start a new shell for the completions to take effect.`,
+	ValidArgs:             []string{"bash", "zsh", "fish"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := cmd.OutOrStdout()
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(w, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(w)
+		case "fish":
+			return rootCmd.GenFishCompletion(w, true)
+		}
+		return fmt.Errorf("unsupported shell %q", args[0])
+	},
+}
+
+var registerCompletionsOnce sync.Once
+
+// registerFlagCompletions attaches context-aware completions to every
+// command that defines one of the shared flags. It walks the tree rather
+// than living in each command's init because those inits run in file order
+// and some define their flags after root.go's. Runs once, from
+// cobra.OnInitialize, before the hidden __complete command resolves flags.
+func registerFlagCompletions() {
+	registerCompletionsOnce.Do(func() { registerFlagCompletionsOn(rootCmd) })
+}
+
+func registerFlagCompletionsOn(c *cobra.Command) {
+	completions := map[string]cobra.CompletionFunc{
+		"provider":    completeProviderNames,
+		"backend-url": completeBackendURLs,
+		"config-dir": func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		},
+		"log-format": cobra.FixedCompletions([]string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp),
+	}
+	for name, fn := range completions {
+		if definesFlag(c, name) {
+			c.RegisterFlagCompletionFunc(name, fn)
+		}
+	}
+	for _, sub := range c.Commands() {
+		registerFlagCompletionsOn(sub)
+	}
+}
+
+// definesFlag reports whether c itself (not an ancestor) defines the flag;
+// a completion registered on the defining command covers its children.
+func definesFlag(c *cobra.Command, name string) bool {
+	return c.LocalNonPersistentFlags().Lookup(name) != nil || c.PersistentFlags().Lookup(name) != nil
+}
+
+func completeProviderNames(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return filterPrefix(provider.OrderedNames(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeBackendURLs offers the configured backend URL, then any
+// per-config-dir binding URLs, deduplicated.
+func completeBackendURLs(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.GetUploadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var urls []string
+	seen := map[string]bool{}
+	add := func(u string) {
+		if u != "" && !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	add(cfg.BackendURL)
+	for _, p := range provider.OrderedNames() {
+		for _, creds := range cfg.Bindings[p] {
+			add(creds.BackendURL)
+		}
+	}
+	return filterPrefix(urls, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func filterPrefix(values []string, prefix string) []string {
+	var out []string
+	for _, v := range values {
+		if strings.HasPrefix(v, prefix) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
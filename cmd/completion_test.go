@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// runCompletion drives cobra's hidden __complete command (what the shell
+// scripts call) and returns the offered completions and the directive line.
+func runCompletion(t *testing.T, args ...string) ([]string, string) {
+	t.Helper()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs(append([]string{cobra.ShellCompRequestCmd}, args...))
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+	})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("__complete %v: %v", args, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	directive := lines[len(lines)-1]
+	var values []string
+	for _, l := range lines[:len(lines)-1] {
+		values = append(values, strings.SplitN(l, "\t", 2)[0])
+	}
+	return values, directive
+}
+
+func TestCompletions(t *testing.T) {
+	_, configPath := setupSetupTestEnv(t, "")
+	cfg := config.UploadConfig{
+		BackendURL: "https://confab.example.com",
+		Bindings: map[string]map[string]config.BindingCreds{
+			"claude-code": {"/work/.claude": {BackendURL: "https://confab.work.example.com"}},
+		},
+	}
+	data, _ := json.Marshal(cfg)
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	providers := []string{"claude-code", "codex", "opencode", "cursor"}
+	hooks := []string{"post-tool-use", "pre-compact", "pre-tool-use", "session-end", "session-start", "stop", "test", "user-prompt-submit"}
+	tests := []struct {
+		name      string
+		args      []string
+		want      []string
+		directive string
+	}{
+		{"list --provider", []string{"list", "--provider", ""}, providers, ":4"},
+		{"save --provider prefix", []string{"save", "--provider", "c"}, []string{"claude-code", "codex", "cursor"}, ":4"},
+		{"verify --provider", []string{"verify", "--provider", "o"}, []string{"opencode"}, ":4"},
+		{"hooks add --provider (persistent)", []string{"hooks", "add", "--provider", ""}, providers, ":4"},
+		{"hook session-start --provider (inherited)", []string{"hook", "session-start", "--provider", "cu"}, []string{"cursor"}, ":4"},
+		{"session download --provider", []string{"session", "download", "--provider", ""}, providers, ":4"},
+		{"hook subcommands", []string{"hook", ""}, hooks, ":4"},
+		{"hook subcommand prefix", []string{"hook", "session-"}, []string{"session-end", "session-start"}, ":4"},
+		{"hook test events", []string{"hook", "test", "pre-"}, []string{"pre-tool-use", "pre-compact"}, ":4"},
+		{"setup --backend-url", []string{"setup", "--backend-url", ""}, []string{"https://confab.example.com", "https://confab.work.example.com"}, ":4"},
+		{"login --backend-url prefix", []string{"login", "--backend-url", "https://confab.work"}, []string{"https://confab.work.example.com"}, ":4"},
+		{"verify --config-dir", []string{"verify", "--config-dir", ""}, nil, ":16"},
+		{"completion shells", []string{"completion", ""}, []string{"bash", "zsh", "fish"}, ":4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, directive := runCompletion(t, tt.args...)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("completions = %v, want %v", got, tt.want)
+			}
+			if directive != tt.directive {
+				t.Errorf("directive = %s, want %s", directive, tt.directive)
+			}
+		})
+	}
+}
+
+func TestCompletionCommandGeneratesScripts(t *testing.T) {
+	for shell, marker := range map[string]string{
+		"bash": "__start_confab",
+		"zsh":  "#compdef confab",
+		"fish": "complete -c confab",
+	} {
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetArgs([]string{"completion", shell})
+		err := rootCmd.Execute()
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+		if err != nil {
+			t.Fatalf("completion %s: %v", shell, err)
+		}
+		if !strings.Contains(out.String(), marker) {
+			t.Errorf("completion %s output missing %q", shell, marker)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage confab configuration",
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a per-project .confab/config.json",
+	Long: `Create .confab/config.json in the current directory, listing every setting
+a project may override, all unset.
+
+When confab runs anywhere under this directory, each setting filled in
+here replaces the global value from ~/.confab/config.json; settings left
+empty or null keep the global value. The nearest .confab/config.json
+above the working directory wins (the search stops at your home
+directory).
+
+Overridable: log_level, active_redaction_profile, compression,
+compression_level, max_upload_bps, sync_schedule.
+
+Everything else is global-only and rejected in a project file —
+credentials (api_key and friends always come from the global config, so
+don't commit secrets), backend_url and redaction (a cloned repository
+can't redirect your sessions or turn redaction off), proxy and TLS
+settings, auto_update, retries and concurrency.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		return runConfigInit(os.Stdout, cwd)
+	},
+}
+
+func runConfigInit(w io.Writer, dir string) error {
+	path, err := config.WriteProjectConfigTemplate(dir)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "✓ Created %s\n", path)
+	fmt.Fprintln(w, "Fill in the settings this project should override; empty ones keep the global value.")
+	return nil
+}
+
+var configProfilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List config profiles",
+	Long: `List the profiles in ~/.confab/config.json, marking the active one.
+
+A profile is a complete config (backend, credentials, settings) stored
+under "profiles" in config.json; the top-level config is the profile
+named "default". Select one per command with --profile or
+CONFAB_PROFILE, or make one the default with 'confab config use-profile'.
+Create one by running setup with it selected, e.g.
+'confab --profile work setup --backend-url ...'.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigProfiles(os.Stdout)
+	},
+}
+
+func runConfigProfiles(w io.Writer) error {
+	names, err := config.ListProfiles()
+	if err != nil {
+		return err
+	}
+	active, err := config.ActiveProfile()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		marker := " "
+		if name == active {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s %s\n", marker, name)
+	}
+	if !slices.Contains(names, active) {
+		fmt.Fprintf(w, "* %s (not saved yet)\n", active)
+	}
+	return nil
+}
+
+var configUseProfileCmd = &cobra.Command{
+	Use:   "use-profile <name>",
+	Short: "Set the default config profile",
+	Long: `Make <name> the profile used when neither --profile nor CONFAB_PROFILE
+is set, including by the sync daemons that hooks start. "default"
+selects the top-level config.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.SetDefaultProfile(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Default profile is now %s\n", args[0])
+		return nil
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a config setting",
+	Long: `Print one setting of the active profile in ~/.confab/config.json.
+
+Keys are the JSON field names, with dots for nested fields, e.g.
+backend_url, log_level, redaction.enabled or static_metadata.team.
+Strings are printed as-is and other values as JSON. Exits non-zero if
+the key is unknown or not set.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := config.GetConfigValue(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a config setting",
+	Long: `Set one setting of the active profile in ~/.confab/config.json.
+
+Keys are as for 'confab config get'. String settings take the value
+as-is; others take JSON: true/false, numbers, or a list or object for
+settings such as redaction.patterns. "null" removes an optional setting.
+
+The resulting config is validated (backend URL, API key format and so
+on) before it is saved, and every other setting — including ones this
+version of confab doesn't know — is kept. Examples:
+
+  confab config set log_level debug
+  confab config set redaction.enabled false`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.SetConfigValue(args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Set %s\n", args[0])
+		return nil
+	},
+}
+
+var configValidateConnectivity bool
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check ~/.confab/config.json for mistakes",
+	Long: `Check every setting in ~/.confab/config.json and print one line per
+problem: backend_url, api_key format, per-config-dir bindings, each custom
+redaction pattern (name, type, and a pattern and/or field_pattern that
+compiles), proxy and TLS settings, compression, limits and the sync
+schedule.
+
+With --check-connectivity the backend is also asked to validate the API
+key, as 'confab status' does.
+
+Exits 0 when the config is valid and 1 otherwise. Per-project
+.confab/config.json overrides are not included.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.UploadConfigPath()
+		if err != nil {
+			return err
+		}
+		cfg, err := config.LoadUnvalidatedUploadConfig()
+		if err != nil {
+			return err
+		}
+		return runConfigValidate(os.Stdout, path, cfg, configValidateConnectivity)
+	},
+}
+
+// runConfigValidate prints a ✓/✗ line per checked field of cfg and returns
+// an error, failing the command, if any check failed.
+func runConfigValidate(w io.Writer, path string, cfg *config.UploadConfig, checkConnectivity bool) error {
+	errs := config.ValidateConfig(cfg)
+	fmt.Fprintf(w, "Config: %s\n", path)
+
+	// Fields that always get a line, so a clean run shows what was checked.
+	checked := []string{"backend_url", "api_key"}
+	if cfg.Redaction != nil {
+		for i := range cfg.Redaction.Patterns {
+			checked = append(checked, fmt.Sprintf("redaction.patterns[%d]", i))
+		}
+	}
+	for _, field := range checked {
+		ok := true
+		for _, e := range errs {
+			if e.Field == field || strings.HasPrefix(e.Field, field+".") {
+				ok = false
+			}
+		}
+		if ok {
+			fmt.Fprintf(w, "  ✓ %s\n", field)
+		}
+	}
+	for _, e := range errs {
+		fmt.Fprintf(w, "  ✗ %s\n", e)
+	}
+
+	failed := len(errs)
+	if checkConnectivity {
+		if failed > 0 {
+			fmt.Fprintln(w, "  - backend: skipped until the config is valid")
+		} else if c := diagnoseBackend(cfg); c.Status == diagnoseOK {
+			fmt.Fprintf(w, "  ✓ backend: %s\n", c.Detail)
+		} else {
+			fmt.Fprintf(w, "  ✗ backend: %s\n", c.Detail)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("config is invalid: %d check(s) failed", failed)
+	}
+	fmt.Fprintln(w, "✓ Config is valid")
+	return nil
+}
+
+func init() {
+	configValidateCmd.Flags().BoolVar(&configValidateConnectivity, "check-connectivity", false, "Also check that the backend is reachable and accepts the API key")
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configProfilesCmd)
+	configCmd.AddCommand(configUseProfileCmd)
+	configCmd.AddCommand(configInitCmd)
+	rootCmd.AddCommand(configCmd)
+}
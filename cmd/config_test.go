@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+)
+
+func TestConfigInit_WritesOverridableFieldsOnly(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	if err := runConfigInit(&out, dir); err != nil {
+		t.Fatalf("runConfigInit: %v", err)
+	}
+	path := filepath.Join(dir, config.ProjectConfigDir, config.ProjectConfigFile)
+	if !strings.Contains(out.String(), path) {
+		t.Errorf("output %q does not name %s", out.String(), path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read project config: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("template is not JSON: %v\n%s", err, data)
+	}
+	if _, ok := fields["log_level"]; !ok {
+		t.Errorf("template missing log_level:\n%s", data)
+	}
+	for _, key := range []string{"api_key", "backend_url", "redaction"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("template offers global-only %s:\n%s", key, data)
+		}
+	}
+	// The untouched template loads and overrides nothing.
+	if _, err := config.LoadProjectConfig(path); err != nil {
+		t.Errorf("template does not load: %v", err)
+	}
+
+	if err := runConfigInit(&out, dir); err == nil {
+		t.Error("expected error when the project config already exists")
+	}
+}
+
+func TestRunConfigValidate(t *testing.T) {
+	cfg := &config.UploadConfig{
+		BackendURL: "https://confab.example.com",
+		APIKey:     "cfb_abcdefghijklmnopqrstuvwxyz12345678901234",
+		Redaction: &config.RedactionConfig{Patterns: []config.RedactionPattern{
+			{Name: "Ticket", Pattern: `TICKET-\d+`, Type: "ticket"},
+		}},
+	}
+	var out bytes.Buffer
+	if err := runConfigValidate(&out, "/tmp/config.json", cfg, false); err != nil {
+		t.Fatalf("runConfigValidate(valid) = %v\n%s", err, out.String())
+	}
+	for _, want := range []string{"Config: /tmp/config.json", "✓ backend_url", "✓ api_key", "✓ redaction.patterns[0]", "✓ Config is valid"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	cfg.APIKey = ""
+	cfg.Redaction.Patterns[0].Pattern = "(unclosed"
+	out.Reset()
+	err := runConfigValidate(&out, "/tmp/config.json", cfg, true)
+	if err == nil {
+		t.Fatalf("runConfigValidate(invalid) succeeded:\n%s", out.String())
+	}
+	for _, want := range []string{"✓ backend_url", "✗ api_key", "✗ redaction.patterns[0].pattern", "backend: skipped"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Config is valid") {
+		t.Errorf("invalid config reported valid:\n%s", out.String())
+	}
+}
+
+func TestConfigProfiles_MarksActive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(config.ConfigPathEnv, path)
+	const file = `{"backend_url":"https://a.example.com","api_key":"","default_profile":"work",
+"profiles":{"work":{"backend_url":"https://w.example.com","api_key":""},"home":{"backend_url":"https://h.example.com","api_key":""}}}`
+	if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(config.ProfileEnv, "")
+	var out bytes.Buffer
+	if err := runConfigProfiles(&out); err != nil {
+		t.Fatalf("runConfigProfiles: %v", err)
+	}
+	if want := "  default\n  home\n* work\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	t.Setenv(config.ProfileEnv, "laptop")
+	out.Reset()
+	if err := runConfigProfiles(&out); err != nil {
+		t.Fatalf("runConfigProfiles: %v", err)
+	}
+	if !strings.HasSuffix(out.String(), "  work\n* laptop (not saved yet)\n") {
+		t.Errorf("unsaved active profile not listed:\n%s", out.String())
+	}
+}
@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	confabhttp "github.com/ConfabulousDev/confab/pkg/http"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/ConfabulousDev/confab/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diagnoseJSON bool
+	diagnoseFix  bool
+)
+
+// diagnoseErrorWindow is how far back the log is scanned for ERROR lines.
+const diagnoseErrorWindow = 24 * time.Hour
+
+// Check outcomes, rendered as ✓ / ✗ / ⚠.
+const (
+	diagnoseOK   = "ok"
+	diagnoseFail = "fail"
+	diagnoseWarn = "warn"
+)
+
+var diagnoseCmd = &cobra.Command{
+	Use:     "diagnose",
+	Aliases: []string{"doctor"},
+	Short:   "Check local setup for common sync problems",
+	Long: `Runs a series of local checks and prints a report: resolved
+config and data paths, config file,
+API key format, backend reachability, Claude Code hooks, running daemons,
+transcript access, last sync time and recent errors in the log.
+
+Nothing is changed and no new backend API is used; the only request is
+the same key validation 'confab status' performs. Use --json to attach
+the report to a support ticket.
+
+With --fix, duplicate confab hooks in Claude Code's settings.json (e.g.
+several SessionEnd commands left by manual edits) are collapsed to one per
+event and matcher before the checks run. Other hooks are left untouched.
+
+Also available as 'confab doctor'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger.Info("Running diagnose command")
+		if diagnoseFix {
+			var w io.Writer = os.Stdout
+			if diagnoseJSON {
+				w = os.Stderr // keep stdout valid JSON
+			}
+			if err := repairClaudeHooks(w); err != nil {
+				return err
+			}
+		}
+		checks := runDiagnoseChecks()
+		if diagnoseJSON {
+			return writeDiagnoseJSON(os.Stdout, checks)
+		}
+		printDiagnoseReport(os.Stdout, checks)
+		return nil
+	},
+}
+
+// diagnoseCheck is one line of the diagnose report.
+type diagnoseCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// runDiagnoseChecks runs every check in report order. Checks never fail
+// the command; problems are reported as fail/warn entries.
+func runDiagnoseChecks() []diagnoseCheck {
+	cfg, cfgCheck := diagnoseConfigFile()
+	checks := []diagnoseCheck{diagnosePaths(), cfgCheck, diagnoseAPIKey(cfg), diagnoseBackend(cfg), diagnoseClaudeHooks(), diagnoseDuplicateHooks()}
+
+	states, err := daemon.ListAllStates()
+	if err != nil {
+		checks = append(checks, diagnoseCheck{"Daemons", diagnoseFail, fmt.Sprintf("cannot list state files: %v", err)})
+	} else {
+		checks = append(checks, diagnoseDaemons(states), diagnoseTranscripts(states), diagnoseLastSync(states))
+	}
+	return append(checks, diagnoseRecentErrors(time.Now()))
+}
+
+// diagnosePaths lists the resolved config and data locations, which
+// CONFAB_CONFIG_DIR, CONFAB_DATA_DIR, CONFAB_CONFIG_PATH and
+// XDG_CONFIG_HOME can move.
+func diagnosePaths() diagnoseCheck {
+	p, err := config.ResolvePaths()
+	if err != nil {
+		return diagnoseCheck{"Paths", diagnoseFail, err.Error()}
+	}
+	return diagnoseCheck{"Paths", diagnoseOK, fmt.Sprintf("config dir %s, config file %s, data dir %s, Claude dir %s", p.ConfigDir, p.ConfigFile, p.DataDir, p.ClaudeStateDir)}
+}
+
+// diagnoseConfigFile reports whether config.json exists and parses. The
+// returned config is nil when it can't be used by later checks.
+func diagnoseConfigFile() (*config.UploadConfig, diagnoseCheck) {
+	path, err := config.UploadConfigPath()
+	if err != nil {
+		return nil, diagnoseCheck{"Config file", diagnoseFail, err.Error()}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, diagnoseCheck{"Config file", diagnoseFail, fmt.Sprintf("%s not found (run 'confab login')", path)}
+	}
+	cfg, err := config.GetUploadConfig()
+	if err != nil {
+		return nil, diagnoseCheck{"Config file", diagnoseFail, err.Error()}
+	}
+	return cfg, diagnoseCheck{"Config file", diagnoseOK, path}
+}
+
+func diagnoseAPIKey(cfg *config.UploadConfig) diagnoseCheck {
+	if cfg == nil || cfg.APIKey == "" {
+		return diagnoseCheck{"API key", diagnoseFail, "not configured (run 'confab login')"}
+	}
+	if err := config.ValidateAPIKey(cfg.APIKey); err != nil {
+		return diagnoseCheck{"API key", diagnoseFail, err.Error()}
+	}
+	detail := utils.TruncateSecret(cfg.APIKey, 8, 4)
+	if cfg.KeyName != "" {
+		detail += fmt.Sprintf(" (%q)", cfg.KeyName)
+	}
+	return diagnoseCheck{"API key", diagnoseOK, detail + ", format valid"}
+}
+
+// diagnoseBackend times the key validation request. A 401 still proves
+// the backend is reachable, so it is reported with the latency.
+func diagnoseBackend(cfg *config.UploadConfig) diagnoseCheck {
+	if cfg == nil || cfg.BackendURL == "" {
+		return diagnoseCheck{"Backend", diagnoseFail, "no backend URL configured"}
+	}
+	start := time.Now()
+	err := verifyAPIKey(cfg)
+	latency := time.Since(start).Round(time.Millisecond)
+	switch {
+	case err == nil:
+		return diagnoseCheck{"Backend", diagnoseOK, fmt.Sprintf("%s reachable in %s, API key accepted", cfg.BackendURL, latency)}
+	case errors.Is(err, confabhttp.ErrUnauthorized):
+		return diagnoseCheck{"Backend", diagnoseFail, fmt.Sprintf("%s reachable in %s, but API key rejected (run 'confab login')", cfg.BackendURL, latency)}
+	default:
+		return diagnoseCheck{"Backend", diagnoseFail, fmt.Sprintf("%s unreachable: %v", cfg.BackendURL, err)}
+	}
+}
+
+func diagnoseClaudeHooks() diagnoseCheck {
+	path, err := config.GetSettingsPath()
+	if err != nil {
+		return diagnoseCheck{"Claude settings", diagnoseFail, err.Error()}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return diagnoseCheck{"Claude settings", diagnoseWarn, fmt.Sprintf("%s not found", path)}
+	}
+	installed, err := provider.ClaudeCode{}.IsHooksInstalled()
+	switch {
+	case err != nil:
+		return diagnoseCheck{"Claude settings", diagnoseFail, fmt.Sprintf("%s: %v", path, err)}
+	case installed:
+		return diagnoseCheck{"Claude settings", diagnoseOK, fmt.Sprintf("%s, hooks installed", path)}
+	default:
+		return diagnoseCheck{"Claude settings", diagnoseFail, fmt.Sprintf("%s, hooks not installed (run 'confab setup')", path)}
+	}
+}
+
+// diagnoseDuplicateHooks reports confab hooks that 'doctor --fix' would
+// de-duplicate or move. Missing settings are covered by diagnoseClaudeHooks.
+func diagnoseDuplicateHooks() diagnoseCheck {
+	n, err := provider.ClaudeCode{}.HookRepairs()
+	switch {
+	case err != nil:
+		return diagnoseCheck{"Duplicate hooks", diagnoseFail, err.Error()}
+	case n > 0:
+		return diagnoseCheck{"Duplicate hooks", diagnoseWarn, fmt.Sprintf("%d duplicate or misplaced confab hook(s) (run 'confab doctor --fix')", n)}
+	default:
+		return diagnoseCheck{"Duplicate hooks", diagnoseOK, "none"}
+	}
+}
+
+// repairClaudeHooks runs the --fix repair and reports what changed.
+func repairClaudeHooks(w io.Writer) error {
+	n, err := provider.ClaudeCode{}.RepairHooks()
+	if err != nil {
+		return fmt.Errorf("failed to repair hooks: %w", err)
+	}
+	if n == 0 {
+		fmt.Fprintln(w, "No duplicate hooks to fix")
+	} else {
+		fmt.Fprintf(w, "Fixed %d duplicate or misplaced confab hook(s)\n", n)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+func diagnoseDaemons(states []*daemon.State) diagnoseCheck {
+	running := 0
+	for _, st := range states {
+		if st.IsDaemonRunning() {
+			running++
+		}
+	}
+	if running == 0 {
+		return diagnoseCheck{"Daemons", diagnoseWarn, fmt.Sprintf("none running (%d state files)", len(states))}
+	}
+	return diagnoseCheck{"Daemons", diagnoseOK, fmt.Sprintf("%d running (%d state files)", running, len(states))}
+}
+
+// diagnoseTranscripts checks that every running daemon's transcript can
+// be opened; a daemon that can't read its transcript never syncs.
+func diagnoseTranscripts(states []*daemon.State) diagnoseCheck {
+	checked := 0
+	var unreadable []string
+	for _, st := range states {
+		if !st.IsDaemonRunning() {
+			continue
+		}
+		checked++
+		f, err := os.Open(st.TranscriptPath)
+		if err != nil {
+			unreadable = append(unreadable, st.TranscriptPath)
+			continue
+		}
+		f.Close()
+	}
+	switch {
+	case checked == 0:
+		return diagnoseCheck{"Transcripts", diagnoseWarn, "no active sessions to check"}
+	case len(unreadable) > 0:
+		return diagnoseCheck{"Transcripts", diagnoseFail, "cannot read " + strings.Join(unreadable, ", ")}
+	default:
+		return diagnoseCheck{"Transcripts", diagnoseOK, fmt.Sprintf("%d readable", checked)}
+	}
+}
+
+func diagnoseLastSync(states []*daemon.State) diagnoseCheck {
+	var last time.Time
+	for _, st := range states {
+		if st.SyncProgress != nil && st.SyncProgress.LastSyncAt.After(last) {
+			last = st.SyncProgress.LastSyncAt
+		}
+	}
+	if last.IsZero() {
+		return diagnoseCheck{"Last sync", diagnoseWarn, "no completed sync recorded"}
+	}
+	return diagnoseCheck{"Last sync", diagnoseOK, last.Local().Format(time.RFC3339)}
+}
+
+// diagnoseRecentErrors counts ERROR lines logged within diagnoseErrorWindow
+// of now. Only the current log file is read, not rotated backups.
+func diagnoseRecentErrors(now time.Time) diagnoseCheck {
+	path, err := logger.FilePath()
+	if err != nil {
+		return diagnoseCheck{"Recent errors", diagnoseWarn, err.Error()}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return diagnoseCheck{"Recent errors", diagnoseWarn, fmt.Sprintf("cannot read log: %v", err)}
+	}
+	defer f.Close()
+
+	cutoff := now.Add(-diagnoseErrorWindow)
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if ts, ok := logErrorTime(scanner.Text()); ok && ts.After(cutoff) {
+			count++
+		}
+	}
+	if count > 0 {
+		return diagnoseCheck{"Recent errors", diagnoseWarn, fmt.Sprintf("%d in the last 24h (see %s)", count, path)}
+	}
+	return diagnoseCheck{"Recent errors", diagnoseOK, "none in the last 24h"}
+}
+
+// logErrorTime returns the timestamp of an ERROR log line in either log
+// format, and false for any other line.
+func logErrorTime(line string) (time.Time, bool) {
+	if strings.HasPrefix(line, "{") {
+		// --log-format json: {"time":"…RFC3339…","level":"ERROR",…}
+		var entry struct {
+			Time  time.Time `json:"time"`
+			Level string    `json:"level"`
+		}
+		if json.Unmarshal([]byte(line), &entry) != nil || entry.Level != logger.ERROR.String() {
+			return time.Time{}, false
+		}
+		return entry.Time, true
+	}
+	// Text lines are "[2006-01-02 15:04:05] [ctx] LEVEL: message".
+	if !strings.Contains(line, " ERROR: ") || len(line) < 21 || line[0] != '[' {
+		return time.Time{}, false
+	}
+	ts, err := time.ParseInLocation("2006-01-02 15:04:05", line[1:20], time.Local)
+	return ts, err == nil
+}
+
+func writeDiagnoseJSON(w io.Writer, checks []diagnoseCheck) error {
+	out := struct {
+		Checks []diagnoseCheck `json:"checks"`
+	}{Checks: checks}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func printDiagnoseReport(w io.Writer, checks []diagnoseCheck) {
+	fmt.Fprintln(w, "=== Confab Diagnose ===")
+	fmt.Fprintln(w)
+	for _, c := range checks {
+		mark := "✓"
+		switch c.Status {
+		case diagnoseFail:
+			mark = "✗"
+		case diagnoseWarn:
+			mark = "⚠"
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", mark, c.Name, c.Detail)
+	}
+}
+
+func init() {
+	diagnoseCmd.Flags().BoolVar(&diagnoseJSON, "json", false, "Print the report as JSON")
+	diagnoseCmd.Flags().BoolVar(&diagnoseFix, "fix", false, "Remove duplicate confab hooks from Claude Code settings first")
+	rootCmd.AddCommand(diagnoseCmd)
+}
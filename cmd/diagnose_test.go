@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+func findDiagnoseCheck(t *testing.T, checks []diagnoseCheck, name string) diagnoseCheck {
+	t.Helper()
+	for _, c := range checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no %q check in %+v", name, checks)
+	return diagnoseCheck{}
+}
+
+func TestDiagnose_ReportsConfigBackendAndErrors(t *testing.T) {
+	server := httptest.NewServer(&setupTestBackend{validateValid: true})
+	defer server.Close()
+
+	_, configPath := setupSetupTestEnv(t, server.URL)
+	cfg := config.UploadConfig{BackendURL: server.URL, APIKey: "cfb_diagnose-test-key-12345678", KeyName: "devbox-alice"}
+	cfgData, _ := json.Marshal(cfg)
+	if err := os.WriteFile(configPath, cfgData, 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	logDir := t.TempDir()
+	t.Setenv(logger.LogDirEnv, logDir)
+	now := time.Now()
+	recent := now.Add(-time.Hour).Format("2006-01-02 15:04:05")
+	old := now.Add(-48 * time.Hour).Format("2006-01-02 15:04:05")
+	logContent := fmt.Sprintf("[%s] ERROR: old failure\n[%s] [ext=abc] ERROR: upload failed\n[%s] INFO: fine\n", old, recent, recent)
+	if err := os.WriteFile(filepath.Join(logDir, "confab.log"), []byte(logContent), 0600); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+
+	checks := runDiagnoseChecks()
+
+	if c := findDiagnoseCheck(t, checks, "Paths"); c.Status != diagnoseOK || !strings.Contains(c.Detail, "config file "+configPath) {
+		t.Errorf("paths check = %+v, want ok listing %s", c, configPath)
+	}
+	if c := findDiagnoseCheck(t, checks, "Config file"); c.Status != diagnoseOK {
+		t.Errorf("config check = %+v, want ok", c)
+	}
+	if c := findDiagnoseCheck(t, checks, "API key"); c.Status != diagnoseOK || !strings.Contains(c.Detail, `cfb_diag...5678 ("devbox-alice")`) {
+		t.Errorf("api key check = %+v, want ok with the masked key and its name", c)
+	}
+	if c := findDiagnoseCheck(t, checks, "Backend"); c.Status != diagnoseOK || !strings.Contains(c.Detail, "reachable in") {
+		t.Errorf("backend check = %+v, want ok with latency", c)
+	}
+	if c := findDiagnoseCheck(t, checks, "Daemons"); c.Status != diagnoseWarn {
+		t.Errorf("daemons check = %+v, want warn with none running", c)
+	}
+	if c := findDiagnoseCheck(t, checks, "Recent errors"); c.Status != diagnoseWarn || !strings.HasPrefix(c.Detail, "1 in the last 24h") {
+		t.Errorf("recent errors check = %+v, want 1 recent error", c)
+	}
+
+	var out bytes.Buffer
+	printDiagnoseReport(&out, checks)
+	for _, want := range []string{"✓ Config file:", "⚠ Recent errors: 1 in the last 24h"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDiagnose_MissingConfigAndJSON(t *testing.T) {
+	setupSetupTestEnv(t, "")
+	t.Setenv(logger.LogDirEnv, t.TempDir())
+
+	checks := runDiagnoseChecks()
+	for _, name := range []string{"Config file", "API key", "Backend"} {
+		if c := findDiagnoseCheck(t, checks, name); c.Status != diagnoseFail {
+			t.Errorf("%s check = %+v, want fail without config", name, c)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writeDiagnoseJSON(&out, checks); err != nil {
+		t.Fatalf("writeDiagnoseJSON: %v", err)
+	}
+	var decoded struct {
+		Checks []diagnoseCheck `json:"checks"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(decoded.Checks) != len(checks) {
+		t.Errorf("JSON has %d checks, want %d", len(decoded.Checks), len(checks))
+	}
+}
+
+func TestLogErrorTime(t *testing.T) {
+	ts, ok := logErrorTime(`{"time":"2026-01-02T03:04:05.000Z","level":"ERROR","msg":"upload failed"}`)
+	if !ok || !ts.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("JSON ERROR line = %v, %v", ts, ok)
+	}
+	if _, ok := logErrorTime(`{"time":"2026-01-02T03:04:05.000Z","level":"INFO","msg":"ERROR: not really"}`); ok {
+		t.Error("JSON INFO line counted as an error")
+	}
+	if _, ok := logErrorTime("[2026-01-02 03:04:05] [ext=abc] ERROR: upload failed"); !ok {
+		t.Error("text ERROR line not recognized")
+	}
+}
+
+func TestDiagnose_DuplicateHooksAndFix(t *testing.T) {
+	tmpDir, _ := setupSetupTestEnv(t, "")
+	t.Setenv(logger.LogDirEnv, t.TempDir())
+	settingsPath := filepath.Join(tmpDir, ".claude", "settings.json")
+	const settings = `{"hooks": {"SessionEnd": [{"matcher": "*", "hooks": [
+  {"type":"command","command":"/usr/local/bin/confab hook session-end"},
+  {"type":"command","command":"/usr/local/bin/confab hook session-end"},
+  {"type":"command","command":"/opt/bin/confab hook session-end"}
+]}]}}`
+	if err := os.WriteFile(settingsPath, []byte(settings), 0600); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+
+	if c := findDiagnoseCheck(t, runDiagnoseChecks(), "Duplicate hooks"); c.Status != diagnoseWarn || !strings.HasPrefix(c.Detail, "2 duplicate") {
+		t.Errorf("duplicate hooks check = %+v, want warn with 2", c)
+	}
+
+	var out bytes.Buffer
+	if err := repairClaudeHooks(&out); err != nil {
+		t.Fatalf("repairClaudeHooks: %v", err)
+	}
+	if !strings.Contains(out.String(), "Fixed 2 duplicate") {
+		t.Errorf("fix output = %q", out.String())
+	}
+	if c := findDiagnoseCheck(t, runDiagnoseChecks(), "Duplicate hooks"); c.Status != diagnoseOK {
+		t.Errorf("duplicate hooks check after --fix = %+v, want ok", c)
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	confabhttp "github.com/ConfabulousDev/confab/pkg/http"
+	"github.com/ConfabulousDev/confab/pkg/sync"
+	"github.com/ConfabulousDev/confab/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffProviderName string
+	diffConfigDir    string
+	diffFile         string
+)
+
+// diffMaxLineWidth truncates displayed lines; transcript lines can run to
+// megabytes and the point is to locate the difference, not dump it.
+const diffMaxLineWidth = 200
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <session-id>",
+	Short: "Show lines that differ between a local session and the backend",
+	Long: `Download the backend's stored copy of each session file and print the
+lines that differ from the local file. The local side is redacted first,
+as it would be for an upload, so redacted values don't show as
+differences. Lines are compared by position. Nothing is uploaded.
+
+Use 'confab verify' for a quick per-file summary; use diff to see which
+lines diverge. Exits non-zero if any line differs.
+
+Examples:
+  confab diff --provider claude-code abc123de
+  confab diff --provider claude-code --file agent-1a2b3c.jsonl abc123de`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defer NotifyIfUpdateAvailable()
+		cfg, p, err := resolveSaveContext(diffProviderName, diffConfigDir)
+		if err != nil {
+			return err
+		}
+		fullID, transcriptPath, err := p.FindSessionByID(args[0])
+		if err != nil {
+			return err
+		}
+		return runDiff(os.Stdout, cfg, p.Name(), fullID, transcriptPath, p.DefaultCWD(transcriptPath), diffFile)
+	},
+}
+
+// runDiff initializes the session to learn which files the backend holds,
+// then prints every differing line grouped by file. Returns an error when
+// any line differs so the command exits non-zero.
+func runDiff(w io.Writer, cfg *config.UploadConfig, providerName, sessionID, transcriptPath, cwd, fileName string) error {
+	engine, err := sync.New(cfg, sync.EngineConfig{
+		Provider:       providerName,
+		ExternalID:     sessionID,
+		TranscriptPath: transcriptPath,
+		CWD:            cwd,
+	})
+	if err != nil {
+		return err
+	}
+	if err := engine.Init(); err != nil {
+		return fmt.Errorf("failed to initialize session: %w", err)
+	}
+
+	client, err := confabhttp.NewClient(cfg, utils.DefaultHTTPTimeout)
+	if err != nil {
+		return err
+	}
+	diffs, err := engine.Diff(fileName, func(name string, dst io.Writer) error {
+		return client.GetRawToWriter(buildSessionFileDownloadPath(engine.SessionID(), name), dst)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Session %s\n", utils.TruncateSecret(sessionID, 8, 0))
+	current := ""
+	for _, d := range diffs {
+		if d.FileName != current {
+			current = d.FileName
+			fmt.Fprintf(w, "\n--- local/%s\n+++ backend/%s\n", d.FileName, d.FileName)
+		}
+		fmt.Fprintf(w, "@@ line %d @@\n", d.Line)
+		if !d.LocalMissing {
+			fmt.Fprintf(w, "- %s\n", utils.TruncateEnd(d.Local, diffMaxLineWidth))
+		}
+		if !d.BackendMissing {
+			fmt.Fprintf(w, "+ %s\n", utils.TruncateEnd(d.Backend, diffMaxLineWidth))
+		}
+	}
+
+	if len(diffs) > 0 {
+		return fmt.Errorf("%d lines differ", len(diffs))
+	}
+	fmt.Fprintln(w, "\n✓ No differences")
+	return nil
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffProviderName, "provider", "", "Provider the session belongs to (claude-code, codex, cursor, or opencode)")
+	diffCmd.MarkFlagRequired("provider")
+	diffCmd.Flags().StringVar(&diffConfigDir, "config-dir", "", "Diff against the backend bound to this config dir (requires --provider; claude-code only)")
+	diffCmd.Flags().StringVar(&diffFile, "file", "", "Only diff this backend file name (default: every file)")
+	rootCmd.AddCommand(diffCmd)
+}
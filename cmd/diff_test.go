@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+)
+
+func TestDiff(t *testing.T) {
+	full := `{"type":"system"}` + "\n" + `{"type":"user"}` + "\n" + `{"type":"assistant"}` + "\n"
+
+	tests := []struct {
+		name           string
+		lastSyncedLine int
+		content        string
+		wantErr        bool
+		wantOutput     []string
+	}{
+		{"identical", 3, full, false, []string{"No differences"}},
+		{"line differs", 3, strings.Replace(full, "user", "USER", 1), true,
+			[]string{"--- local/abc123.jsonl", "@@ line 2 @@", `- {"type":"user"}`, `+ {"type":"USER"}`}},
+		{"backend behind", 2, `{"type":"system"}` + "\n" + `{"type":"user"}`, true,
+			[]string{"@@ line 3 @@", `- {"type":"assistant"}`}},
+		{"backend ahead", 4, full + `{"type":"extra"}` + "\n", true,
+			[]string{"@@ line 4 @@", `+ {"type":"extra"}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newVerifyTestServer(t, tt.lastSyncedLine, tt.content, true)
+			t.Setenv("HOME", t.TempDir())
+			cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "cfb_diff-test-key-12345678"}
+
+			var out bytes.Buffer
+			err := runDiff(&out, cfg, provider.NameClaudeCode, "abc123", writeReplayTranscript(t), t.TempDir(), "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runDiff error = %v, wantErr %v\n%s", err, tt.wantErr, out.String())
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+			if tt.name == "line differs" && strings.Contains(out.String(), "line 1 @@") {
+				t.Errorf("matching line reported as different:\n%s", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/redactor"
+	"github.com/ConfabulousDev/confab/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportOutput string
+	exportFormat string
+	exportRedact bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [session-id]",
+	Short: "Write a local copy of the files confab syncs for a session",
+	Long: `Copy the transcript and agent files of a session to a local directory,
+for backup or debugging. No backend calls are made.
+
+Sessions are found through sync daemon state files, so this covers running
+sessions and ones whose daemon kept its state (e.g. after a failed final
+sync). With a session ID (or prefix), only that session is exported;
+without one, every session with a state file is. Each session is written
+to <output>/<session-id>/, with files under their synced names (agent
+files of workflow runs keep their subagents/... paths).
+
+Formats:
+  jsonl  Files as-is (default).
+  json   Each file as {"file": "<name>.jsonl", "lines": [...]} in <name>.json.
+
+With --redact, lines pass through your redaction rules (the default
+patterns if none are configured) before being written.
+
+Files recorded in the state but missing on disk are reported, and the
+command exits non-zero after exporting the rest.
+
+Examples:
+  confab export abc123de --output ./backup
+  confab export --format json --redact --output /tmp/confab-debug`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger.Info("Running export command")
+		var r *redactor.Redactor
+		if exportRedact {
+			var err error
+			if r, err = previewRedactor(); err != nil {
+				return err
+			}
+		}
+		return runExport(os.Stdout, firstArg(args), exportOutput, exportFormat, r)
+	},
+}
+
+// exportedFile is one file of the --format json output.
+type exportedFile struct {
+	File  string            `json:"file"`
+	Lines []json.RawMessage `json:"lines"`
+}
+
+// runExport exports every session whose ID starts with sessionPrefix to
+// outDir, printing one line per file. r, when non-nil, redacts each line.
+func runExport(w io.Writer, sessionPrefix, outDir, format string, r *redactor.Redactor) error {
+	if format != "jsonl" && format != "json" {
+		return fmt.Errorf("invalid --format %q (must be jsonl or json)", format)
+	}
+	states, err := daemon.ListAllStates()
+	if err != nil {
+		return fmt.Errorf("failed to list daemon states: %w", err)
+	}
+	var matched []*daemon.State
+	for _, state := range states {
+		if strings.HasPrefix(state.ExternalID, sessionPrefix) {
+			matched = append(matched, state)
+		}
+	}
+	if len(matched) == 0 {
+		if sessionPrefix != "" {
+			return fmt.Errorf("no sync daemon state for session %s", sessionPrefix)
+		}
+		fmt.Fprintln(w, "No sessions to export")
+		return nil
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ExternalID < matched[j].ExternalID })
+
+	missing := 0
+	for _, state := range matched {
+		sessionDir := filepath.Join(outDir, state.ExternalID)
+		fmt.Fprintf(w, "Session %s → %s\n", utils.TruncateSecret(state.ExternalID, 8, 0), sessionDir)
+
+		files := state.LocalFiles()
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			src := files[name]
+			if !filepath.IsLocal(filepath.FromSlash(name)) {
+				fmt.Fprintf(w, "  ✗ %s: unsafe file name, skipped\n", name)
+				continue
+			}
+			if _, err := os.Stat(src); err != nil {
+				missing++
+				fmt.Fprintf(w, "  ✗ %s: missing (%s)\n", name, src)
+				continue
+			}
+			dest := filepath.Join(sessionDir, filepath.FromSlash(name))
+			if format == "json" {
+				dest = strings.TrimSuffix(dest, ".jsonl") + ".json"
+			}
+			lines, err := exportFile(src, dest, name, format, r)
+			if err != nil {
+				return fmt.Errorf("failed to export %s: %w", name, err)
+			}
+			fmt.Fprintf(w, "  ✓ %s (%d lines)\n", name, lines)
+		}
+	}
+	if missing > 0 {
+		return fmt.Errorf("%d file(s) missing on disk", missing)
+	}
+	return nil
+}
+
+// exportFile copies src to dest in format, redacting with r if non-nil,
+// and returns the number of lines written.
+func exportFile(src, dest, name, format string, r *redactor.Redactor) (int, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return 0, err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	doc := exportedFile{File: name, Lines: []json.RawMessage{}}
+	n := 0
+	// ReadBytes rather than a Scanner: transcript lines (inlined images,
+	// large tool results) have no size limit.
+	reader := bufio.NewReader(in)
+	for {
+		raw, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return 0, readErr
+		}
+		if len(raw) == 0 {
+			break
+		}
+		n++
+		if format == "jsonl" && r == nil {
+			// Copied as is; only an unterminated last line gets a newline.
+			if raw[len(raw)-1] != '\n' {
+				raw = append(raw, '\n')
+			}
+			if _, err := out.Write(raw); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		line := strings.TrimSuffix(strings.TrimSuffix(string(raw), "\n"), "\r")
+		if r != nil {
+			line = r.RedactJSONLine(line)
+		}
+		if format == "jsonl" {
+			if _, err := io.WriteString(out, line+"\n"); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if json.Valid([]byte(line)) {
+			doc.Lines = append(doc.Lines, json.RawMessage(line))
+		} else {
+			// Not JSON (e.g. a line still being written): keep it as a string.
+			quoted, _ := json.Marshal(line)
+			doc.Lines = append(doc.Lines, quoted)
+		}
+	}
+	if format == "json" {
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return 0, err
+		}
+		if _, err := out.Write(append(data, '\n')); err != nil {
+			return 0, err
+		}
+	}
+	return n, out.Close()
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", ".", "Directory to write the export to")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "jsonl", "Output format: jsonl or json")
+	exportCmd.Flags().BoolVar(&exportRedact, "redact", false, "Apply redaction rules before writing")
+	rootCmd.AddCommand(exportCmd)
+}
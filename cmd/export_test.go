@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/ConfabulousDev/confab/pkg/redactor"
+	"github.com/ConfabulousDev/confab/pkg/types"
+)
+
+// saveExportTestState writes a session with a transcript, an agent file
+// and a recorded-but-missing agent file, and its daemon state.
+func saveExportTestState(t *testing.T, tmpDir, sessionID string) {
+	t.Helper()
+	transcript := filepath.Join(tmpDir, "project", sessionID+".jsonl")
+	agent := filepath.Join(tmpDir, "project", sessionID, "subagents", "agent-a1.jsonl")
+	os.MkdirAll(filepath.Dir(agent), 0755)
+	os.WriteFile(transcript, []byte(`{"type":"user","text":"key tk_abcd1234"}`+"\n"+`{"type":"assistant"}`+"\n"), 0644)
+	os.WriteFile(agent, []byte(`{"type":"user","agent":true}`+"\n"), 0644)
+
+	state := daemon.NewStateForProvider(provider.NameClaudeCode, sessionID, transcript, tmpDir, 0)
+	state.FilePaths = map[string]string{
+		filepath.Base(transcript): transcript,
+		"agent-a1.jsonl":          agent,
+	}
+	// Recorded by an older daemon without a path; derived, and missing.
+	state.SyncProgress = &daemon.SyncProgress{FileLines: map[string]int{"agent-gone.jsonl": 3}}
+	if err := state.Save(); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+}
+
+func TestRunExport_JSONLWithMissingFile(t *testing.T) {
+	tmpDir, _ := setupSetupTestEnv(t, "")
+	saveExportTestState(t, tmpDir, "exp12345-session")
+	outDir := filepath.Join(tmpDir, "out")
+
+	var out bytes.Buffer
+	err := runExport(&out, "exp123", outDir, "jsonl", nil)
+	if err == nil || !strings.Contains(err.Error(), "1 file(s) missing") {
+		t.Fatalf("runExport error = %v, want the missing agent reported\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "✗ agent-gone.jsonl: missing") {
+		t.Errorf("output doesn't report the missing file:\n%s", out.String())
+	}
+
+	sessionDir := filepath.Join(outDir, "exp12345-session")
+	got, _ := os.ReadFile(filepath.Join(sessionDir, "exp12345-session.jsonl"))
+	want, _ := os.ReadFile(filepath.Join(tmpDir, "project", "exp12345-session.jsonl"))
+	if !bytes.Equal(got, want) {
+		t.Errorf("exported transcript = %q, want a verbatim copy %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(sessionDir, "agent-a1.jsonl")); err != nil {
+		t.Errorf("agent file not exported: %v", err)
+	}
+}
+
+func TestRunExport_JSONRedacted(t *testing.T) {
+	tmpDir, _ := setupSetupTestEnv(t, "")
+	saveExportTestState(t, tmpDir, "exp67890-session")
+	outDir := filepath.Join(tmpDir, "out")
+
+	var out bytes.Buffer
+	runExport(&out, "", outDir, "json", newPreviewTestRedactor(t))
+
+	data, err := os.ReadFile(filepath.Join(outDir, "exp67890-session", "exp67890-session.json"))
+	if err != nil {
+		t.Fatalf("read exported transcript: %v\n%s", err, out.String())
+	}
+	var doc exportedFile
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("exported file isn't JSON: %v\n%s", err, data)
+	}
+	if doc.File != "exp67890-session.jsonl" || len(doc.Lines) != 2 {
+		t.Fatalf("exported doc = %s, want file exp67890-session.jsonl with 2 lines", data)
+	}
+	if strings.Contains(string(data), "tk_abcd1234") {
+		t.Errorf("--redact left the secret in the export:\n%s", data)
+	}
+}
+
+func TestExportFile_LongLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "long.jsonl")
+	long := `{"text":"` + strings.Repeat("x", types.MaxJSONLLineSize) + `"}`
+	content := long + "\n" + `{"type":"tail"}`
+	if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		r    *redactor.Redactor
+	}{
+		{"verbatim", nil},
+		{"redacted", newPreviewTestRedactor(t)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := filepath.Join(tmpDir, tc.name, "long.jsonl")
+			n, err := exportFile(src, dest, "long.jsonl", "jsonl", tc.r)
+			if err != nil {
+				t.Fatalf("exportFile: %v", err)
+			}
+			got, _ := os.ReadFile(dest)
+			if n != 2 || string(got) != content+"\n" {
+				t.Errorf("exported %d lines of %d bytes, want 2 lines of %d bytes", n, len(got), len(content)+1)
+			}
+		})
+	}
+}
+
+func TestRunExport_Errors(t *testing.T) {
+	setupSetupTestEnv(t, "")
+	if err := runExport(&bytes.Buffer{}, "", t.TempDir(), "xml", nil); err == nil {
+		t.Error("runExport accepted --format xml")
+	}
+	if err := runExport(&bytes.Buffer{}, "nosuch", t.TempDir(), "jsonl", nil); err == nil {
+		t.Error("runExport succeeded for a session without state")
+	}
+}
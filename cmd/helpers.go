@@ -7,6 +7,7 @@ import (
 	"github.com/ConfabulousDev/confab/pkg/config"
 	confabhttp "github.com/ConfabulousDev/confab/pkg/http"
 	"github.com/ConfabulousDev/confab/pkg/provider"
+	pkgsync "github.com/ConfabulousDev/confab/pkg/sync"
 	"github.com/ConfabulousDev/confab/pkg/utils"
 )
 
@@ -44,22 +45,50 @@ func clientForFlags(providerName, configDir string) (*confabhttp.Client, error)
 	if providerName == "" && configDir == "" {
 		return newAuthedClient()
 	}
-	if configDir != "" && providerName == "" {
-		return nil, fmt.Errorf("--config-dir requires --provider (a config dir is provider-specific)")
-	}
-
-	p, err := provider.Get(providerName)
+	b, err := bindingForFlags(providerName, configDir)
 	if err != nil {
 		return nil, err
 	}
-	b := provider.BindingFor(p, configDir)
 	client, err := newAuthedClientForBinding(b)
 	if err != nil {
-		return nil, withSetupHint(err, p.Name(), configDir)
+		return nil, withSetupHint(err, b.Provider, configDir)
 	}
 	return client, nil
 }
 
+// syncClientForFlags is clientForFlags for commands that call the backend
+// through a pkg/sync Client (confab sessions).
+func syncClientForFlags(providerName, configDir string) (*pkgsync.Client, error) {
+	b := config.Binding{IsDefault: true}
+	if providerName != "" || configDir != "" {
+		var err error
+		if b, err = bindingForFlags(providerName, configDir); err != nil {
+			return nil, err
+		}
+	}
+	cfg, err := config.EnsureAuthenticatedFor(b)
+	if err != nil {
+		if !b.IsDefault {
+			err = withSetupHint(err, b.Provider, configDir)
+		}
+		return nil, err
+	}
+	return pkgsync.NewClient(cfg, 0)
+}
+
+// bindingForFlags resolves the binding named by --provider/--config-dir. A
+// config dir requires a provider.
+func bindingForFlags(providerName, configDir string) (config.Binding, error) {
+	if configDir != "" && providerName == "" {
+		return config.Binding{}, fmt.Errorf("--config-dir requires --provider (a config dir is provider-specific)")
+	}
+	p, err := provider.Get(providerName)
+	if err != nil {
+		return config.Binding{}, err
+	}
+	return provider.BindingFor(p, configDir), nil
+}
+
 // withSetupHint annotates a config.ErrNoBinding with the exact `confab setup`
 // command that would create the missing (provider, config-dir) binding, and
 // passes any other error through unchanged. Shared by the retrieval commands
@@ -36,7 +36,12 @@ Available handlers:
   session-end         Handle SessionEnd events (Claude Code only)
   pre-tool-use        Handle PreToolUse events
   post-tool-use       Handle PostToolUse events
-  user-prompt-submit  Handle UserPromptSubmit events (Claude Code only)`,
+  user-prompt-submit  Handle UserPromptSubmit events (Claude Code only)
+  stop                Handle Stop events (Claude Code only)
+  pre-compact         Handle PreCompact events (Claude Code only)
+
+To try a handler by hand, 'confab hook test <event>' runs it with
+synthesized input and prints the response.`,
 }
 
 func init() {
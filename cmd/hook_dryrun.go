@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/ConfabulousDev/confab/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hookTestCWD        string
+	hookTestSessionID  string
+	hookTestTranscript string
+	hookTestToolName   string
+	hookTestCommand    string
+	hookTestPrompt     string
+)
+
+// hookDryRun is set while `confab hook test` runs a handler: SessionStart
+// then skips the auto-update check, and no daemon is spawned (see
+// runHookTest).
+var hookDryRun bool
+
+// hookTestEvent is a hook `confab hook test` can exercise: the
+// hook_event_name Claude Code sends and the production handler.
+type hookTestEvent struct {
+	eventName string
+	handler   func(r io.Reader, w io.Writer) error
+}
+
+// hookTestEvents lists the testable hooks by command name. session-end is
+// left out: it stops the session's daemon and writes no response, so there
+// is nothing to dry-run.
+var hookTestEvents = map[string]hookTestEvent{
+	"session-start":      {"SessionStart", sessionStartFromReader},
+	"pre-tool-use":       {"PreToolUse", handlePreToolUse},
+	"post-tool-use":      {"PostToolUse", handlePostToolUse},
+	"user-prompt-submit": {"UserPromptSubmit", handleUserPromptSubmit},
+	"stop":               {"Stop", handleStop},
+	"pre-compact":        {"PreCompact", handlePreCompact},
+}
+
+var hookTestCmd = &cobra.Command{
+	Use:   "test <event>",
+	Short: "Dry-run a hook handler with synthesized input",
+	Long: `Run a hook handler the way Claude Code would, with synthesized input,
+and print its JSON response.
+
+Events: session-start, pre-tool-use, post-tool-use, user-prompt-submit,
+stop, pre-compact.
+
+The input is a Claude Code hook payload built from the flags, with dummy
+values for the rest, and goes to the same handler the installed hook
+runs. session-start and user-prompt-submit report the daemon they would
+start instead of starting one; the other handlers act as they would in a
+session (e.g. stop asks the --session-id daemon to sync). Pass
+--session-id of a session with a running daemon to see pre-tool-use
+enforce the Confab link; a deny is printed with its reason.
+
+Examples:
+  confab hook test session-start --cwd /tmp/test
+  confab hook test pre-tool-use --tool-name Bash --command "git commit -m test"`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"session-start", "pre-tool-use", "post-tool-use", "user-prompt-submit", "stop", "pre-compact"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runHookTest(os.Stdout, args[0], stdoutIsTerminal())
+	},
+}
+
+func init() {
+	hookTestCmd.Flags().StringVar(&hookTestCWD, "cwd", "", "Working directory in the hook input (default: the current directory)")
+	hookTestCmd.Flags().StringVar(&hookTestSessionID, "session-id", "confab-hook-test", "Session ID in the hook input")
+	hookTestCmd.Flags().StringVar(&hookTestTranscript, "transcript", "", "Transcript path in the hook input (default: one under the Claude projects directory for --cwd)")
+	hookTestCmd.Flags().StringVar(&hookTestToolName, "tool-name", "Bash", "Tool name for pre-tool-use and post-tool-use")
+	hookTestCmd.Flags().StringVar(&hookTestCommand, "command", "git commit -m test", "Bash command for pre-tool-use and post-tool-use")
+	hookTestCmd.Flags().StringVar(&hookTestPrompt, "prompt", "test prompt", "Prompt for user-prompt-submit")
+	hookCmd.AddCommand(hookTestCmd)
+}
+
+// runHookTest synthesizes the input for event, runs its handler and prints
+// the input and the handler's response to w.
+func runHookTest(w io.Writer, event string, color bool) error {
+	ev, ok := hookTestEvents[event]
+	if !ok {
+		names := make([]string, 0, len(hookTestEvents))
+		for name := range hookTestEvents {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown hook event %q (want one of: %s)", event, strings.Join(names, ", "))
+	}
+	providerName, err := provider.NormalizeName(hookProviderName)
+	if err != nil {
+		return err
+	}
+	if providerName != provider.NameClaudeCode {
+		return fmt.Errorf("confab hook test synthesizes Claude Code hook input; --provider %s is not supported", providerName)
+	}
+
+	input, err := hookTestInput(ev.eventName)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "Input:")
+	printHookJSON(w, data, color)
+
+	origSpawn := spawnDaemonFunc
+	hookDryRun = true
+	defer func() {
+		spawnDaemonFunc = origSpawn
+		hookDryRun = false
+	}()
+	var spawned *daemonLaunchInput
+	spawnDaemonFunc = func(launch *daemonLaunchInput) error {
+		spawned = launch
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err := ev.handler(bytes.NewReader(data), &out); err != nil {
+		return fmt.Errorf("%s handler failed: %w", event, err)
+	}
+
+	if spawned != nil {
+		fmt.Fprintf(w, "\nDry run: would start a sync daemon for session %s (transcript %s)\n", spawned.ExternalID, spawned.TranscriptPath)
+	}
+	fmt.Fprintln(w, "\nResponse:")
+	if out.Len() == 0 {
+		fmt.Fprintln(w, "  (none: the hook lets the event proceed)")
+		return nil
+	}
+	printHookJSON(w, out.Bytes(), color)
+
+	var resp types.PreToolUseResponse
+	if json.Unmarshal(out.Bytes(), &resp) == nil && resp.HookSpecificOutput != nil &&
+		resp.HookSpecificOutput.PermissionDecision == "deny" {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, colorize("Denied: "+resp.HookSpecificOutput.PermissionDecisionReason, ansiRed, color))
+	}
+	return nil
+}
+
+// hookTestInput builds the Claude Code hook payload for eventName from the
+// hook test flags.
+func hookTestInput(eventName string) (*types.ClaudeHookInput, error) {
+	cwd := hookTestCWD
+	if cwd == "" {
+		var err error
+		if cwd, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	}
+	transcript := hookTestTranscript
+	if transcript == "" {
+		// Where Claude Code would keep the transcript: the projects dir,
+		// in a directory named after cwd. It needn't exist.
+		projectsDir, err := provider.ClaudeCode{}.ProjectsDir()
+		if err != nil {
+			return nil, err
+		}
+		project := strings.NewReplacer("/", "-", ".", "-").Replace(cwd)
+		transcript = filepath.Join(projectsDir, project, hookTestSessionID+".jsonl")
+	}
+	input := &types.ClaudeHookInput{
+		SessionID:      hookTestSessionID,
+		TranscriptPath: transcript,
+		CWD:            cwd,
+		PermissionMode: "default",
+		HookEventName:  eventName,
+	}
+	switch eventName {
+	case "PreToolUse", "PostToolUse":
+		input.ToolName = hookTestToolName
+		input.ToolInput = map[string]any{"command": hookTestCommand}
+		input.ToolUseID = "toolu_confab_hook_test"
+		if eventName == "PostToolUse" {
+			input.ToolResponse = map[string]any{"stdout": "", "stderr": "", "interrupted": false}
+		}
+	case "UserPromptSubmit":
+		input.Prompt = hookTestPrompt
+	}
+	return input, nil
+}
+
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+)
+
+// colorize wraps s in an ANSI color when color is set.
+func colorize(s, ansi string, color bool) string {
+	if !color {
+		return s
+	}
+	return ansi + s + ansiReset
+}
+
+// printHookJSON prints a JSON document indented by two spaces, with keys,
+// strings and literals highlighted when color is set. Output that isn't
+// JSON is printed as-is.
+func printHookJSON(w io.Writer, data []byte, color bool) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(data), "  ", "  "); err != nil {
+		fmt.Fprintf(w, "  %s\n", bytes.TrimSpace(data))
+		return
+	}
+	s := buf.String()
+	if color {
+		s = highlightJSON(s)
+	}
+	fmt.Fprintf(w, "  %s\n", s)
+}
+
+// highlightJSON colors the tokens of valid, indented JSON: object keys
+// cyan, string values green, and numbers, booleans and null yellow.
+func highlightJSON(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++ // closing quote
+			rest := strings.TrimLeft(s[end:], " \n")
+			ansi := ansiGreen
+			if strings.HasPrefix(rest, ":") {
+				ansi = ansiCyan
+			}
+			b.WriteString(ansi + s[i:end] + ansiReset)
+			i = end
+		case c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z':
+			end := i
+			for end < len(s) && strings.IndexByte(",]} \n", s[end]) < 0 {
+				end++
+			}
+			b.WriteString(ansiYellow + s[i:end] + ansiReset)
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// setHookTestFlags sets the hook test flags for one test, restoring the
+// defaults afterwards.
+func setHookTestFlags(t *testing.T, sessionID, command string) {
+	t.Helper()
+	hookTestSessionID, hookTestCommand = sessionID, command
+	hookTestCWD, hookTestTranscript, hookTestToolName, hookTestPrompt = t.TempDir(), "", "Bash", "test prompt"
+	t.Cleanup(func() {
+		hookTestSessionID, hookTestCommand = "confab-hook-test", "git commit -m test"
+		hookTestCWD, hookTestToolName = "", "Bash"
+	})
+}
+
+func TestHookTest_PreToolUseDenyPrintsReason(t *testing.T) {
+	cleanup := setupTestState(t, "hook-test-session", "confab-session-123")
+	defer cleanup()
+	setHookTestFlags(t, "hook-test-session", "git commit -m test")
+
+	var out bytes.Buffer
+	if err := runHookTest(&out, "pre-tool-use", false); err != nil {
+		t.Fatalf("runHookTest: %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, `"tool_name": "Bash"`) || !strings.Contains(got, `"command": "git commit -m test"`) {
+		t.Errorf("input not printed:\n%s", got)
+	}
+	if !strings.Contains(got, `"permissionDecision": "deny"`) {
+		t.Errorf("expected a deny response:\n%s", got)
+	}
+	if !strings.Contains(got, "Denied: ") || !strings.Contains(got, testBackendURL+"/sessions/confab-session-123") {
+		t.Errorf("deny reason with the session URL not printed:\n%s", got)
+	}
+}
+
+func TestHookTest_PreToolUseOtherCommandHasNoResponse(t *testing.T) {
+	cleanup := setupTestState(t, "hook-test-session", "confab-session-123")
+	defer cleanup()
+	setHookTestFlags(t, "hook-test-session", "ls -la")
+
+	var out bytes.Buffer
+	if err := runHookTest(&out, "pre-tool-use", true); err != nil {
+		t.Fatalf("runHookTest: %v", err)
+	}
+	if !strings.Contains(out.String(), "(none: the hook lets the event proceed)") {
+		t.Errorf("expected no response:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "Denied") {
+		t.Errorf("unexpected deny:\n%s", out.String())
+	}
+}
+
+func TestHookTest_SessionStartDoesNotSpawn(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	setHookTestFlags(t, "hook-test-session", "")
+	spawns := 0
+	origSpawn := spawnDaemonFunc
+	spawnDaemonFunc = func(*daemonLaunchInput) error { spawns++; return nil }
+	defer func() { spawnDaemonFunc = origSpawn }()
+
+	var out bytes.Buffer
+	if err := runHookTest(&out, "session-start", false); err != nil {
+		t.Fatalf("runHookTest: %v", err)
+	}
+	if spawns != 0 {
+		t.Errorf("hook test called the real spawn %d time(s)", spawns)
+	}
+	if !strings.Contains(out.String(), "Dry run: would start a sync daemon for session hook-test-session") {
+		t.Errorf("dry-run spawn not reported:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), `"continue": true`) {
+		t.Errorf("session-start response not printed:\n%s", out.String())
+	}
+	if hookDryRun {
+		t.Error("hookDryRun left set after the test run")
+	}
+}
+
+func TestHookTest_UnknownEvent(t *testing.T) {
+	err := runHookTest(&bytes.Buffer{}, "session-end", false)
+	if err == nil || !strings.Contains(err.Error(), "pre-tool-use") {
+		t.Errorf("runHookTest(session-end) = %v, want an error listing the events", err)
+	}
+}
+
+func TestHighlightJSON(t *testing.T) {
+	got := highlightJSON(`{"key": "value", "n": 1, "ok": true}`)
+	for _, want := range []string{
+		ansiCyan + `"key"` + ansiReset,
+		ansiGreen + `"value"` + ansiReset,
+		ansiYellow + "1" + ansiReset,
+		ansiYellow + "true" + ansiReset,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("highlightJSON output %q missing %q", got, want)
+		}
+	}
+}
@@ -49,13 +49,13 @@ func init() {
 // and visible errors would be too noisy. See SessionStart hook for visible errors.
 func handlePostToolUse(r io.Reader, w io.Writer) error {
 	if config.IsLinkFromGitHubDisabled() {
-		logger.Info("GitHub linking disabled via %s", config.DisableLinkFromGitHubEnv)
+		logger.Infof("GitHub linking disabled via %s", config.DisableLinkFromGitHubEnv)
 		return nil
 	}
 
 	p, err := resolveCommitLinkingProvider()
 	if err != nil {
-		logger.Warn("PostToolUse skipped: %v", err)
+		logger.Warnf("PostToolUse skipped: %v", err)
 		return nil
 	}
 
@@ -68,7 +68,7 @@ func handlePostToolUse(r io.Reader, w io.Writer) error {
 
 	hookInput, err := readToolUseHookInput(p, r)
 	if err != nil {
-		logger.Warn("Failed to read hook input: %v", err)
+		logger.Warnf("Failed to read hook input: %v", err)
 		return nil
 	}
 
@@ -119,11 +119,11 @@ func linkPRFromResponse(p provider.Provider, hookInput *toolUseHookInput) error
 // session. Walks up to the root session for providers with a thread tree
 // (Codex) so subagent-initiated commits/PRs link to the user-facing root.
 func linkGitHubURL(p provider.Provider, sessionID, githubURL, transcriptPath string) error {
-	logger.Info("Linking GitHub URL to session: %s", githubURL)
+	logger.Infof("Linking GitHub URL to session: %s", githubURL)
 
 	confabSessionID, err := getConfabSessionID(p, sessionID)
 	if err != nil || confabSessionID == "" {
-		logger.Warn("GitHub link failed: no Confab session ID available (err=%v)", err)
+		logger.Warn("GitHub link failed: no Confab session ID available", "error", err)
 		return nil // Can't link without session ID, but don't error
 	}
 
@@ -131,14 +131,14 @@ func linkGitHubURL(p provider.Provider, sessionID, githubURL, transcriptPath str
 	// config dir's commits/PRs link via its own backend (kata hpec).
 	cfg, err := uploadConfigForHook(p, transcriptPath)
 	if err != nil {
-		logger.Warn("GitHub link failed: %v", err)
+		logger.Warnf("GitHub link failed: %v", err)
 		return nil // Best-effort linking
 	}
 
 	// Create sync client
-	client, err := pkgsync.NewClient(cfg)
+	client, err := pkgsync.NewClient(cfg, 0)
 	if err != nil {
-		logger.Warn("GitHub link failed: %v", err)
+		logger.Warnf("GitHub link failed: %v", err)
 		return nil
 	}
 
@@ -149,14 +149,14 @@ func linkGitHubURL(p provider.Provider, sessionID, githubURL, transcriptPath str
 	})
 	if err != nil {
 		if errors.Is(err, http.ErrConflict) {
-			logger.Info("GitHub link already exists: %s -> session %s", githubURL, confabSessionID)
+			logger.Infof("GitHub link already exists: %s -> session %s", githubURL, confabSessionID)
 			return nil
 		}
-		logger.Warn("GitHub link failed: %v", err)
+		logger.Warnf("GitHub link failed: %v", err)
 		return nil // Best-effort, log and continue
 	}
 
-	logger.Info("GitHub link success: %s -> session %s", githubURL, confabSessionID)
+	logger.Infof("GitHub link success: %s -> session %s", githubURL, confabSessionID)
 	return nil
 }
 
@@ -171,21 +171,21 @@ func linkCommitToSession(p provider.Provider, sessionID, cwd, transcriptPath str
 
 	commitSHA, err := git.GetHeadSHA(cwd)
 	if err != nil || commitSHA == "" {
-		logger.Warn("GitHub commit link failed: could not get HEAD SHA from %s (err=%v)", cwd, err)
+		logger.Warn("GitHub commit link failed: could not get HEAD SHA", "dir", cwd, "error", err)
 		return nil
 	}
 
-	logger.Info("Linking commit to session: %s", commitSHA)
+	logger.Infof("Linking commit to session: %s", commitSHA)
 
 	repoURL, err := git.GetRepoURL(cwd)
 	if err != nil || repoURL == "" {
-		logger.Warn("GitHub commit link failed: could not get repo URL from %s (err=%v)", cwd, err)
+		logger.Warn("GitHub commit link failed: could not get repo URL", "dir", cwd, "error", err)
 		return nil
 	}
 
 	githubURL := git.ToGitHubURL(repoURL)
 	if githubURL == "" {
-		logger.Info("GitHub commit link skipped: repo is not on GitHub (%s)", repoURL)
+		logger.Infof("GitHub commit link skipped: repo is not on GitHub (%s)", repoURL)
 		return nil
 	}
 
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/spf13/cobra"
+)
+
+var hookPreCompactCmd = &cobra.Command{
+	Use:   "pre-compact",
+	Short: "Handle PreCompact hook events",
+	Long: `Handler for PreCompact hook events.
+
+This hook fires just before Claude compacts the conversation, manually
+(/compact) or automatically. It has the session's sync daemon sync
+everything written so far and waits for it, then records the
+transcript's size. Compaction may rewrite lines that were already
+uploaded; when the daemon next sees the transcript changed, it
+re-initializes from the backend instead of appending at stale offsets.
+
+This command is typically invoked by Claude Code, not directly by users.
+
+Claude Code only.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return handlePreCompact(os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	hookCmd.AddCommand(hookPreCompactCmd)
+}
+
+// handlePreCompact processes PreCompact hook events. Like handleStop, a
+// session without a running daemon is not an error; compaction is never
+// blocked.
+func handlePreCompact(r io.Reader, w io.Writer) error {
+	logger.Info("PreCompact hook triggered")
+
+	defer writeClaudeHookResponse(w, true)
+
+	claude := provider.ClaudeCode{}
+	hookInput, err := claude.ReadHookInput(r)
+	if err != nil {
+		logger.Warnf("Failed to read hook input: %v", err)
+		return nil
+	}
+
+	if err := daemon.PreCompactForProvider(claude.Name(), hookInput.SessionID); err != nil {
+		logger.Debug("PreCompact hook: no flush", "session_id", hookInput.SessionID, "error", err)
+		return nil
+	}
+	logger.Info("Flushed and marked transcript before compaction", "session_id", hookInput.SessionID)
+	return nil
+}
@@ -6,14 +6,19 @@ import (
 	"io"
 	"math/rand/v2"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/ConfabulousDev/confab/pkg/daemon"
+	"github.com/ConfabulousDev/confab/pkg/git"
 	"github.com/ConfabulousDev/confab/pkg/logger"
 	"github.com/ConfabulousDev/confab/pkg/provider"
 	"github.com/ConfabulousDev/confab/pkg/types"
+	"github.com/mattn/go-shellwords"
 	"github.com/spf13/cobra"
 )
 
@@ -63,6 +68,44 @@ var gitPushPattern = regexp.MustCompile(`\bgit\b\s+(-\S+(\s+\S+)?\s+)*push\b`)
 // Matches: gh pr create, gh -R owner/repo pr create, etc.
 var ghPRCreatePattern = regexp.MustCompile(`\bgh\b\s+(-\S+(\s+\S+)?\s+)*pr\s+create\b`)
 
+// skipLinkEnv bypasses link enforcement for a single command, set as an
+// environment prefix on the command itself (CONFAB_SKIP_LINK=1 git commit
+// ...) — the only per-command environment the hook can see.
+const skipLinkEnv = "CONFAB_SKIP_LINK"
+
+// linkEnforcementBypassed reports whether a git commit, git push or gh pr
+// create in command carries a CONFAB_SKIP_LINK=1 (or =true) prefix. Only
+// an assignment leading that simple command counts — not one in quoted
+// text, or on another command in the chain — and a command the tokenizer
+// can't parse is never bypassed.
+func linkEnforcementBypassed(command string) bool {
+	cmds, ok := shellCommands(command)
+	if !ok {
+		return false
+	}
+	for _, words := range cmds {
+		skip := false
+		i := 0
+		for ; i < len(words) && isEnvAssignment(words[i]); i++ {
+			name, value, _ := strings.Cut(words[i], "=")
+			if name == skipLinkEnv {
+				skip = value == "1" || value == "true"
+			}
+		}
+		if !skip || i == len(words) {
+			continue
+		}
+		if name := filepath.Base(words[i]); name != "git" && name != "gh" {
+			continue
+		}
+		rest := strings.Join(words[i:], " ")
+		if gitCommitPattern.MatchString(rest) || gitPushPattern.MatchString(rest) || ghPRCreatePattern.MatchString(rest) {
+			return true
+		}
+	}
+	return false
+}
+
 var hookPreToolUseCmd = &cobra.Command{
 	Use:   "pre-tool-use",
 	Short: "Handle PreToolUse hook events",
@@ -71,6 +114,12 @@ var hookPreToolUseCmd = &cobra.Command{
 For git commit commands, ensures the commit message includes a
 Confab session URL trailer (Confab-Link: {backend_url}/sessions/{session_id}).
 
+For git push commands that push the current branch, ensures its most recent
+commit carries that trailer when that commit was made in this session and
+isn't on the upstream yet, asking for it to be added with 'git commit
+--amend' if not. Merges, tag and delete pushes, and pushes of other refs are
+left alone.
+
 For PR creation (gh pr create, GitHub MCP tool), ensures the PR body includes a
 Confab session link (📝 [Confab link]({backend_url}/sessions/{session_id})).
 
@@ -80,6 +129,9 @@ by appending the shell comment marker from the deny message ('# confab-linked-'
 plus a short random token) to the Bash command. The random suffix keeps a bare
 mention of '# confab-linked' in prose from being misread as certification.
 
+For an emergency commit or PR without a link, prefix the command with
+CONFAB_SKIP_LINK=1; it is allowed as-is and the bypass is logged.
+
 For all other tool calls, exits silently (code 0) to allow normal flow.
 
 This command is typically invoked by the provider runtime (Claude Code or
@@ -98,13 +150,13 @@ func init() {
 // and visible errors would be too noisy. See SessionStart hook for visible errors.
 func handlePreToolUse(r io.Reader, w io.Writer) error {
 	if config.IsLinkFromGitHubDisabled() {
-		logger.Info("GitHub linking disabled via %s", config.DisableLinkFromGitHubEnv)
+		logger.Infof("GitHub linking disabled via %s", config.DisableLinkFromGitHubEnv)
 		return nil
 	}
 
 	p, err := resolveCommitLinkingProvider()
 	if err != nil {
-		logger.Warn("PreToolUse skipped: %v", err)
+		logger.Warnf("PreToolUse skipped: %v", err)
 		return nil
 	}
 
@@ -116,7 +168,7 @@ func handlePreToolUse(r io.Reader, w io.Writer) error {
 
 	hookInput, err := readToolUseHookInput(p, r)
 	if err != nil {
-		logger.Warn("Failed to read hook input: %v", err)
+		logger.Warnf("Failed to read hook input: %v", err)
 		return nil // Exit silently, don't block the firing provider.
 	}
 
@@ -136,32 +188,54 @@ func handlePreToolUse(r io.Reader, w io.Writer) error {
 	}
 
 	// Earlier match wins so "git commit -m 'mentions gh pr create'" is treated
-	// as a commit, not a PR.
+	// as a commit, not a PR, and "git commit ... && git push" as a commit
+	// (the push then sends the commit being checked).
 	commitPos := firstMatch(gitCommitPattern, command)
 	prCreatePos := firstMatch(ghPRCreatePattern, command)
-	if commitPos < 0 && prCreatePos < 0 {
+	pushPos := firstMatch(gitPushPattern, command)
+	if commitPos < 0 && prCreatePos < 0 && pushPos < 0 {
+		return nil
+	}
+	isCommit := commitPos >= 0 && (prCreatePos < 0 || commitPos < prCreatePos) && (pushPos < 0 || commitPos < pushPos)
+	isPush := !isCommit && pushPos >= 0 && (prCreatePos < 0 || pushPos < prCreatePos)
+
+	if linkEnforcementBypassed(command) {
+		logger.Infof("Confab link enforcement bypassed via %s: %s", skipLinkEnv, command)
+		outputPreToolUseDecision(w, "allow", "Confab link enforcement bypassed")
 		return nil
 	}
-	isCommit := commitPos >= 0 && (prCreatePos < 0 || commitPos < prCreatePos)
 
 	confabSessionID, err := getConfabSessionID(p, hookInput.SessionID)
 	if err != nil || confabSessionID == "" {
-		logger.Warn("Confab link skipped: no session ID available (err=%v)", err)
+		logger.Warn("Confab link skipped: no session ID available", "error", err)
 		return nil
 	}
 
 	cfg, err := uploadConfigForHook(p, hookInput.TranscriptPath)
 	if err != nil {
-		logger.Warn("Confab link skipped: %v", err)
+		logger.Warnf("Confab link skipped: %v", err)
 		return nil
 	}
 
 	sessionURL, err := formatSessionURL(confabSessionID, cfg.BackendURL)
 	if err != nil {
-		logger.Warn("Confab link skipped: %v", err)
+		logger.Warnf("Confab link skipped: %v", err)
 		return nil
 	}
 
+	if isPush {
+		push, ok := parseGitPush(command)
+		if !ok {
+			logger.Debugf("Confab link check skipped: can't parse push command %q", command)
+			return nil
+		}
+		var startedAt time.Time
+		if state, err := loadSessionState(p, hookInput.SessionID); err == nil && state != nil {
+			startedAt = state.StartedAt
+		}
+		return checkPushedCommitLink(w, hookInput.CWD, push, startedAt, confabSessionID, cfg.BackendURL, sessionURL)
+	}
+
 	if commandContainsConfabLink(command, confabSessionID, cfg.BackendURL) {
 		logger.Info("Confab link already present in command")
 		outputPreToolUseDecision(w, "allow", "Confab link present")
@@ -170,34 +244,201 @@ func handlePreToolUse(r io.Reader, w io.Writer) error {
 
 	marker := newConfabLinkedMarker()
 	if isCommit {
-		logger.Info("Requesting Confab link for git commit -> session %s", confabSessionID)
+		logger.Infof("Requesting Confab link for git commit -> session %s", confabSessionID)
 		outputPreToolUseDecision(w, "deny", formatCommitDenyReason(sessionURL, marker))
 		return nil
 	}
 
-	logger.Info("Requesting Confab link for PR -> session %s", confabSessionID)
+	logger.Infof("Requesting Confab link for PR -> session %s", confabSessionID)
 	outputPreToolUseDecision(w, "deny", formatBashPRDenyReason(sessionURL, marker))
 	return nil
 }
 
+// gitPush is a git push command as the link check reads it.
+type gitPush struct {
+	dir      string   // from -C, relative to the hook's cwd; "" for the cwd
+	refspecs []string // after the remote
+	// notBranch marks a push of tags, a deletion, a mirror or every
+	// branch, rather than of the current branch's commits.
+	notBranch bool
+}
+
+// gitPushValueFlags are the git push options that take the next word as
+// their value.
+var gitPushValueFlags = map[string]bool{"-o": true, "--push-option": true, "--repo": true, "--receive-pack": true, "--exec": true}
+
+// parseGitPush finds the first git push among command's simple commands.
+// ok is false when there is none or the command can't be tokenized.
+func parseGitPush(command string) (push gitPush, ok bool) {
+	cmds, parsed := shellCommands(command)
+	if !parsed {
+		return gitPush{}, false
+	}
+	for _, words := range cmds {
+		args, isGit := gitArgs(words)
+		if !isGit {
+			continue
+		}
+		var push gitPush
+		i := 0
+		for ; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+			switch args[i] {
+			case "-C":
+				if i+1 < len(args) {
+					push.dir = filepath.Join(push.dir, args[i+1])
+				}
+				i++
+			case "-c":
+				i++
+			}
+		}
+		if i >= len(args) || args[i] != "push" {
+			continue
+		}
+		var positional []string
+		for j := i + 1; j < len(args); j++ {
+			arg := args[j]
+			switch {
+			case gitPushValueFlags[arg]:
+				j++
+			case arg == "--tags", arg == "--delete", arg == "-d", arg == "--mirror", arg == "--all":
+				push.notBranch = true
+			case strings.HasPrefix(arg, "-"):
+			default:
+				positional = append(positional, arg)
+			}
+		}
+		if len(positional) > 1 {
+			push.refspecs = positional[1:]
+		}
+		return push, true
+	}
+	return gitPush{}, false
+}
+
+// gitArgs returns the arguments of a git invocation, after any leading
+// environment assignments; isGit is false for any other command.
+func gitArgs(words []string) (args []string, isGit bool) {
+	for len(words) > 0 && isEnvAssignment(words[0]) {
+		words = words[1:]
+	}
+	if len(words) == 0 || filepath.Base(words[0]) != "git" {
+		return nil, false
+	}
+	return words[1:], true
+}
+
+// shellCommands tokenizes command the way a POSIX shell would and splits
+// it into its simple commands at ;, &&, || and |. ok is false when command
+// uses syntax the tokenizer doesn't handle, such as subshells.
+func shellCommands(command string) (cmds [][]string, ok bool) {
+	rest := command
+	for {
+		parser := shellwords.NewParser()
+		words, err := parser.Parse(rest)
+		if err != nil {
+			return nil, false
+		}
+		if len(words) > 0 {
+			cmds = append(cmds, words)
+		}
+		if parser.Position < 0 {
+			return cmds, true
+		}
+		rest = rest[parser.Position+1:]
+	}
+}
+
+// isEnvAssignment reports whether word is a NAME=value prefix of a command.
+func isEnvAssignment(word string) bool {
+	name, _, found := strings.Cut(word, "=")
+	if !found || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// pushesHead reports whether a push of refspecs from dir sends the current
+// branch: no refspec (the default push), or one whose source is HEAD or
+// the branch.
+func pushesHead(dir string, refspecs []string) bool {
+	if len(refspecs) == 0 {
+		return true
+	}
+	branch := git.DetectBranch(dir)
+	for _, spec := range refspecs {
+		src, _, _ := strings.Cut(strings.TrimPrefix(spec, "+"), ":")
+		if src == "HEAD" || (branch != "" && (src == branch || src == "refs/heads/"+branch)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPushedCommitLink handles a git push: it allows the push when the
+// current branch's most recent commit already carries the session link,
+// and otherwise denies it, asking for the trailer to be amended onto that
+// commit. Only a commit this push would publish for the first time and
+// that was made since the session started (startedAt) is checked, so a
+// merge, a commit already on the upstream or one from before the session
+// is never amended. Outside a git repo (or when git fails) the push is
+// left alone.
+func checkPushedCommitLink(w io.Writer, cwd string, push gitPush, startedAt time.Time, confabSessionID, backendURL, sessionURL string) error {
+	dir := cwd
+	if push.dir != "" {
+		if filepath.IsAbs(push.dir) {
+			dir = push.dir
+		} else {
+			dir = filepath.Join(cwd, push.dir)
+		}
+	}
+	if push.notBranch || !pushesHead(dir, push.refspecs) {
+		logger.Debug("Confab link check skipped: push doesn't send the current branch")
+		return nil
+	}
+	head, err := git.GetHeadCommit(dir)
+	if err != nil || head == nil {
+		logger.Debug("Confab link check skipped for push: no last commit", "dir", dir, "error", err)
+		return nil
+	}
+	if head.Merge || git.HeadOnUpstream(dir) || head.CommitTime.Before(startedAt.Truncate(time.Second)) {
+		logger.Debug("Confab link check skipped for push: last commit is a merge, already pushed or from before this session")
+		return nil
+	}
+	if containsSessionURL(head.Message, confabSessionID, backendURL) {
+		logger.Info("Confab link already present in last commit")
+		outputPreToolUseDecision(w, "allow", "Confab link present in last commit")
+		return nil
+	}
+
+	logger.Infof("Requesting Confab link on last commit before push -> session %s", confabSessionID)
+	outputPreToolUseDecision(w, "deny", formatPushDenyReason(sessionURL))
+	return nil
+}
+
 // handleMCPPRCreate handles the Claude GitHub MCP PR creation tool. Codex
 // doesn't install this matcher, so this is invoked only for Claude.
 func handleMCPPRCreate(p provider.Provider, hookInput *toolUseHookInput, w io.Writer) error {
 	confabSessionID, err := getConfabSessionID(p, hookInput.SessionID)
 	if err != nil || confabSessionID == "" {
-		logger.Warn("Confab link skipped: no session ID available (err=%v)", err)
+		logger.Warn("Confab link skipped: no session ID available", "error", err)
 		return nil
 	}
 
 	cfg, err := uploadConfigForHook(p, hookInput.TranscriptPath)
 	if err != nil {
-		logger.Warn("Confab link skipped: %v", err)
+		logger.Warnf("Confab link skipped: %v", err)
 		return nil
 	}
 
 	sessionURL, err := formatSessionURL(confabSessionID, cfg.BackendURL)
 	if err != nil {
-		logger.Warn("Confab link skipped: %v", err)
+		logger.Warnf("Confab link skipped: %v", err)
 		return nil
 	}
 
@@ -209,7 +450,7 @@ func handleMCPPRCreate(p provider.Provider, hookInput *toolUseHookInput, w io.Wr
 		}
 	}
 
-	logger.Info("Requesting Confab link for MCP PR -> session %s", confabSessionID)
+	logger.Infof("Requesting Confab link for MCP PR -> session %s", confabSessionID)
 	outputPreToolUseDecision(w, "deny", formatPRDenyReason(sessionURL))
 	return nil
 }
@@ -238,23 +479,27 @@ func resolveCommitLinkingProvider() (provider.Provider, error) {
 // state is keyed off the root rollout). Identity for Claude; SQLite walk
 // for Codex.
 func getConfabSessionID(p provider.Provider, sessionID string) (string, error) {
-	state, err := daemon.LoadStateForProvider(p.Name(), sessionID)
-	if err != nil {
+	state, err := loadSessionState(p, sessionID)
+	if err != nil || state == nil {
 		return "", err
 	}
-	if state != nil {
-		return state.ConfabSessionID, nil
+	return state.ConfabSessionID, nil
+}
+
+// loadSessionState loads the daemon state of sessionID, or of its root
+// session when it is a child without a daemon of its own. Nil when there
+// is neither.
+func loadSessionState(p provider.Provider, sessionID string) (*daemon.State, error) {
+	state, err := daemon.LoadStateForProvider(p.Name(), sessionID)
+	if err != nil || state != nil {
+		return state, err
 	}
 
 	rootID, _, _ := p.WalkUpToRoot(sessionID)
 	if rootID == "" || rootID == sessionID {
-		return "", nil
+		return nil, nil
 	}
-	rootState, err := daemon.LoadStateForProvider(p.Name(), rootID)
-	if err != nil || rootState == nil {
-		return "", err
-	}
-	return rootState.ConfabSessionID, nil
+	return daemon.LoadStateForProvider(p.Name(), rootID)
 }
 
 func firstMatch(re *regexp.Regexp, s string) int {
@@ -343,6 +588,20 @@ func formatCommitDenyReason(sessionURL, marker string) string {
 	)
 }
 
+// formatPushDenyReason is the git push deny message: the commit already
+// exists, so the trailer is amended onto it rather than added to a message.
+func formatPushDenyReason(sessionURL string) string {
+	return fmt.Sprintf(
+		"✓ Confab is linking this push to your session, but the commit you made at "+
+			"HEAD has no Confab link. It isn't on the remote yet, so add the trailer "+
+			"to it, then push again:\n\n"+
+			"    git commit --amend --no-edit --trailer %q\n\n"+
+			"IMPORTANT: Copy the trailer verbatim. The value is a URL, NOT a ticket ID like CF-123. "+
+			"Amend only this unpushed commit; never amend or force-push commits that are already on the remote.",
+		formatTrailerLine(sessionURL),
+	)
+}
+
 func outputPreToolUseDecision(w io.Writer, decision, reason string) {
 	response := types.PreToolUseResponse{
 		HookSpecificOutput: &types.PreToolUseOutput{
@@ -352,6 +611,6 @@ func outputPreToolUseDecision(w io.Writer, decision, reason string) {
 		},
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		logger.Debug("Failed to write %s response: %v", decision, err)
+		logger.Debugf("Failed to write %s response: %v", decision, err)
 	}
 }
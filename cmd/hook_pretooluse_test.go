@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -110,6 +112,8 @@ func TestFindGitPushPosition(t *testing.T) {
 		{"npm install", "npm install", -1},
 		{"empty command", "", -1},
 		{"git with -C flag", "git -C /some/path push origin main", 0},
+		{"amend then push", "git log --format=%B -n 1 && git push origin HEAD", 28},
+		{"git stash push", "git stash push -m wip", -1},
 	}
 
 	for _, tt := range tests {
@@ -369,6 +373,191 @@ func TestHandlePreToolUse_GitCommitWithTrailer(t *testing.T) {
 	}
 }
 
+func TestParseGitPush(t *testing.T) {
+	tests := []struct {
+		command string
+		ok      bool
+		want    gitPush
+	}{
+		{"git push", true, gitPush{}},
+		{"git push origin main", true, gitPush{refspecs: []string{"main"}}},
+		{"git add . && GIT_TRACE=1 git push -u origin +HEAD:main", true, gitPush{refspecs: []string{"+HEAD:main"}}},
+		{"git -C sub -c push.default=current push -o ci.skip origin", true, gitPush{dir: "sub"}},
+		{"git push --tags", true, gitPush{notBranch: true}},
+		{"git push -d origin topic", true, gitPush{refspecs: []string{"topic"}, notBranch: true}},
+		{"git status; echo 'git push'", false, gitPush{}},
+		{"(cd sub && git push)", false, gitPush{}},
+	}
+	for _, tt := range tests {
+		got, ok := parseGitPush(tt.command)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseGitPush(%q) = %+v, %v; want %+v, %v", tt.command, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestHandlePreToolUse_GitPush checks a push against the trailer of the
+// current branch's most recent commit, only while that commit is unpushed
+// and from this session.
+func TestHandlePreToolUse_GitPush(t *testing.T) {
+	claudeSessionID := "claude-session-123"
+	confabSessionID := "confab-session-456"
+	cleanup := setupTestState(t, claudeSessionID, confabSessionID)
+	defer cleanup()
+
+	sessionURL, err := formatSessionURL(confabSessionID, testBackendURL)
+	if err != nil {
+		t.Fatalf("formatSessionURL() error = %v", err)
+	}
+	repo, _ := initGitRepoWithCommit(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	push := func(cwd, command string) *types.PreToolUseOutput {
+		t.Helper()
+		inputJSON, _ := json.Marshal(types.ClaudeHookInput{
+			SessionID:     claudeSessionID,
+			HookEventName: "PreToolUse",
+			ToolName:      config.ToolNameBash,
+			ToolInput:     map[string]any{"command": command},
+			CWD:           cwd,
+		})
+		var w bytes.Buffer
+		if err := handlePreToolUse(bytes.NewReader(inputJSON), &w); err != nil {
+			t.Fatalf("handlePreToolUse() error = %v", err)
+		}
+		if w.Len() == 0 {
+			return nil
+		}
+		var response types.PreToolUseResponse
+		if err := json.Unmarshal(w.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return response.HookSpecificOutput
+	}
+
+	// Last commit without the trailer: deny, asking for an amend.
+	out := push(repo, "git push origin HEAD")
+	if out == nil || out.PermissionDecision != "deny" {
+		t.Fatalf("push without trailer = %+v, want deny", out)
+	}
+	wantAmend := "git commit --amend --no-edit --trailer \"Confab-Link: " + sessionURL + "\""
+	if !strings.Contains(out.PermissionDecisionReason, wantAmend) {
+		t.Errorf("deny reason = %q, want it to contain %q", out.PermissionDecisionReason, wantAmend)
+	}
+
+	// -C points the check at another repository.
+	if out := push(t.TempDir(), "git -C "+repo+" push"); out == nil || out.PermissionDecision != "deny" {
+		t.Errorf("push with -C = %+v, want deny", out)
+	}
+
+	// Pushes that don't send the current branch are left alone.
+	for _, command := range []string{
+		"git push --tags",
+		"git push origin --delete old-branch",
+		"git push origin some-other-branch",
+		"git push --mirror backup",
+		"echo 'git push' is how you publish",
+	} {
+		if out := push(repo, command); out != nil {
+			t.Errorf("%q = %+v, want no decision", command, out)
+		}
+	}
+
+	// Once the commit is on its upstream it can't be amended: no decision.
+	remote := t.TempDir()
+	if output, err := exec.Command("git", "init", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v\n%s", err, output)
+	}
+	git("remote", "set-url", "origin", remote)
+	git("push", "-u", "origin", "HEAD")
+	if out := push(repo, "git push"); out != nil {
+		t.Errorf("push of an already-pushed commit = %+v, want no decision", out)
+	}
+
+	// A new unpushed commit is checked again, and goes through once amended.
+	git("commit", "--allow-empty", "-m", "more")
+	if out := push(repo, "git push"); out == nil || out.PermissionDecision != "deny" {
+		t.Errorf("push of a new commit without trailer = %+v, want deny", out)
+	}
+	git("commit", "--amend", "--allow-empty", "--no-edit", "--trailer", formatTrailerLine(sessionURL))
+	if out := push(repo, "git push"); out == nil || out.PermissionDecision != "allow" {
+		t.Errorf("push with trailer = %+v, want allow", out)
+	}
+
+	// A commit from before the session started isn't this session's.
+	dated := exec.Command("git", "commit", "--allow-empty", "-m", "old")
+	dated.Dir = repo
+	dated.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2001-01-01T00:00:00Z")
+	if output, err := dated.CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v\n%s", err, output)
+	}
+	if out := push(repo, "git push"); out != nil {
+		t.Errorf("push of a commit from before the session = %+v, want no decision", out)
+	}
+
+	// Outside a git repo there's no commit to check.
+	if out := push(t.TempDir(), "git push origin main"); out != nil {
+		t.Errorf("push outside a repo = %+v, want no decision", out)
+	}
+}
+
+// TestHandlePreToolUse_SkipLinkBypass verifies a CONFAB_SKIP_LINK=1 prefix
+// lets a commit through that would otherwise be denied, and that only a
+// prefix on the commit itself counts.
+func TestHandlePreToolUse_SkipLinkBypass(t *testing.T) {
+	claudeSessionID := "claude-session-123"
+	cleanup := setupTestState(t, claudeSessionID, "confab-session-456")
+	defer cleanup()
+	// The hook's own environment doesn't bypass every command.
+	t.Setenv(skipLinkEnv, "1")
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"git commit -m 'Hotfix'", "deny"},
+		{"CONFAB_SKIP_LINK=1 git commit -m 'Hotfix'", "allow"},
+		{"git add . && CONFAB_SKIP_LINK=1 git commit -m 'Hotfix'", "allow"},
+		{"CONFAB_SKIP_LINK=0 git commit -m 'Hotfix'", "deny"},
+		{"FOO=bar CONFAB_SKIP_LINK=true git commit -m 'Hotfix'", "allow"},
+		{"git commit -m 'mentions XCONFAB_SKIP_LINK=1'", "deny"},
+		{"git commit -m 'CONFAB_SKIP_LINK=1 git commit'", "deny"},
+		{"echo CONFAB_SKIP_LINK=1 && git commit -m 'Hotfix'", "deny"},
+		{"CONFAB_SKIP_LINK=1 git add . && git commit -m 'Hotfix'", "deny"},
+	}
+	for _, tt := range tests {
+		input := types.ClaudeHookInput{
+			SessionID:     claudeSessionID,
+			HookEventName: "PreToolUse",
+			ToolName:      config.ToolNameBash,
+			ToolInput:     map[string]any{"command": tt.command},
+		}
+		inputJSON, _ := json.Marshal(input)
+		var w bytes.Buffer
+		if err := handlePreToolUse(strings.NewReader(string(inputJSON)), &w); err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.command, err)
+		}
+
+		var response types.PreToolUseResponse
+		if err := json.Unmarshal(w.Bytes(), &response); err != nil {
+			t.Fatalf("%q: failed to parse response: %v", tt.command, err)
+		}
+		if response.HookSpecificOutput == nil {
+			t.Fatalf("%q: expected hookSpecificOutput, got nil", tt.command)
+		}
+		if got := response.HookSpecificOutput.PermissionDecision; got != tt.want {
+			t.Errorf("%q: permissionDecision = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
 func TestHandlePreToolUse_GitCommitNoState(t *testing.T) {
 	// When there's no daemon state (sync not set up), commits should be allowed silently
 	input := types.ClaudeHookInput{
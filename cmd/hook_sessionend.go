@@ -95,7 +95,7 @@ func sessionEndFromReader(r io.Reader) error {
 	// Signal daemon to stop (it will do final sync in background)
 	// Pass hookInput so daemon can access the full SessionEnd payload
 	if err := daemon.StopDaemon(hookInput.SessionID, hookInput); err != nil {
-		logger.Warn("Could not stop daemon: %v", err)
+		logger.Warnf("Could not stop daemon: %v", err)
 		fmt.Fprintf(os.Stderr, "Note: %v\n", err)
 	} else {
 		fmt.Fprintln(os.Stderr, "Daemon signaled to stop (final sync in background)")
@@ -118,7 +118,7 @@ func sessionEndOpencode(r io.Reader) error {
 	}
 
 	if err := daemon.StopDaemonForProvider(provider.NameOpencode, in.SessionID, nil); err != nil {
-		logger.Warn("Could not stop daemon: %v", err)
+		logger.Warnf("Could not stop daemon: %v", err)
 		fmt.Fprintf(os.Stderr, "Note: %v\n", err)
 	} else {
 		fmt.Fprintln(os.Stderr, "Daemon signaled to stop (final sync in background)")
@@ -159,7 +159,7 @@ func sessionEndCursor(r io.Reader) error {
 	}
 
 	if err := daemon.StopDaemonForProvider(provider.NameCursor, in.SessionID, hookInput); err != nil {
-		logger.Warn("Could not stop daemon: %v", err)
+		logger.Warnf("Could not stop daemon: %v", err)
 		fmt.Fprintf(os.Stderr, "Note: %v\n", err)
 	} else {
 		fmt.Fprintln(os.Stderr, "Daemon signaled to stop (final sync in background)")
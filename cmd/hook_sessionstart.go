@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -22,6 +23,16 @@ const maxSyncEnvMS = 3600000
 
 var bgDaemonData string // Hidden flag for daemon mode
 
+// daemonPIDFile is the --pidfile path handed to the spawned daemon.
+var daemonPIDFile string
+
+// Sync timing flags handed to the spawned daemon; zero means unset.
+var (
+	daemonSyncInterval  time.Duration
+	daemonSyncJitter    time.Duration
+	daemonDeterministic bool
+)
+
 var hookSessionStartCmd = &cobra.Command{
 	Use:   "session-start",
 	Short: "Handle SessionStart hook events",
@@ -32,11 +43,22 @@ provider's settings file. It starts a background sync daemon that
 uploads session transcripts incrementally.
 
 When called from a hook, it reads session info from stdin and spawns a
-background daemon process. Provider is selected via --provider.`,
+background daemon process. Provider is selected via --provider.
+
+Use --pidfile to have the daemon write its PID to a file for a process
+supervisor; the file is removed when the daemon shuts down. The daemon
+logs its PID and state file path on startup either way.
+
+--sync-interval and --sync-jitter override sync_interval_ms and
+sync_jitter_ms for this daemon; --deterministic drops jitter entirely so
+syncs run exactly every interval.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if bgDaemonData != "" {
 			return runDaemon(bgDaemonData)
 		}
+		if err := validateDaemonTimingFlags(); err != nil {
+			return err
+		}
 		return sessionStartFromHook()
 	},
 }
@@ -45,6 +67,28 @@ func init() {
 	hookCmd.AddCommand(hookSessionStartCmd)
 	hookSessionStartCmd.Flags().StringVar(&bgDaemonData, "bg-daemon", "", "")
 	hookSessionStartCmd.Flags().MarkHidden("bg-daemon")
+	hookSessionStartCmd.Flags().StringVar(&daemonPIDFile, "pidfile", "", "Write the daemon's PID to this file (removed on shutdown)")
+	addDaemonTimingFlags(hookSessionStartCmd)
+}
+
+// addDaemonTimingFlags registers the daemon sync timing flags shared by
+// "hook session-start" and "sync start".
+func addDaemonTimingFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&daemonSyncInterval, "sync-interval", 0, "Base sync interval (default from sync_interval_ms, or 30s)")
+	cmd.Flags().DurationVar(&daemonSyncJitter, "sync-jitter", 0, "Random delay of up to this much added to each interval (default from sync_jitter_ms)")
+	cmd.Flags().BoolVar(&daemonDeterministic, "deterministic", false, "Sync exactly every interval, with no jitter")
+}
+
+// validateDaemonTimingFlags rejects negative durations and a jitter
+// larger than the interval it is added to.
+func validateDaemonTimingFlags() error {
+	if daemonSyncInterval < 0 || daemonSyncJitter < 0 {
+		return fmt.Errorf("--sync-interval and --sync-jitter must not be negative")
+	}
+	if daemonSyncInterval > 0 && daemonSyncJitter > daemonSyncInterval {
+		return fmt.Errorf("--sync-jitter (%s) must not exceed --sync-interval (%s)", daemonSyncJitter, daemonSyncInterval)
+	}
+	return nil
 }
 
 func sessionStartFromHook() error {
@@ -63,7 +107,7 @@ func sessionStartFromReader(r io.Reader, w io.Writer) error {
 		return err
 	}
 
-	logger.Info("Starting %s sync daemon (hook mode)", p.Name())
+	logger.Infof("Starting %s sync daemon (hook mode)", p.Name())
 
 	// CF-549 F-up A: opportunistic cleanup of stale state files left by
 	// crashed/killed daemons. Provider-agnostic; runs in a goroutine so it
@@ -71,19 +115,21 @@ func sessionStartFromReader(r io.Reader, w io.Writer) error {
 	// is best-effort).
 	go func() {
 		if reaped, rerr := daemon.ReapStaleStates(); rerr != nil {
-			logger.Debug("reaper: %v", rerr)
+			logger.Debugf("reaper: %v", rerr)
 		} else if reaped > 0 {
-			logger.Info("reaper: cleaned %d stale state files", reaped)
+			logger.Infof("reaper: cleaned %d stale state files", reaped)
 		}
 	}()
 
-	AutoUpdateIfNeeded()
+	if !hookDryRun {
+		AutoUpdateIfNeeded()
+	}
 
 	var systemMessage string
 	if p.Name() == provider.NameClaudeCode {
 		systemMessage = RunAnnouncements()
 	} else if err := p.InstallSkills(); err != nil {
-		logger.Warn("Failed to ensure %s skills on SessionStart: %v", p.Name(), err)
+		logger.Warnf("Failed to ensure %s skills on SessionStart: %v", p.Name(), err)
 	}
 
 	defer func() { _ = p.WriteHookResponse(w, false, systemMessage) }()
@@ -111,6 +157,18 @@ func sessionStartFromReader(r io.Reader, w io.Writer) error {
 	}
 	fmt.Fprintf(os.Stderr, "\n")
 
+	if daemonPIDFile != "" {
+		// The daemon runs from a different working directory.
+		if launch.PIDFile, err = filepath.Abs(daemonPIDFile); err != nil {
+			logger.ErrorPrint("Error resolving pidfile path: %v", err)
+			return nil
+		}
+	}
+
+	launch.SyncIntervalMS = int(daemonSyncInterval.Milliseconds())
+	launch.SyncJitterMS = int(daemonSyncJitter.Milliseconds())
+	launch.Deterministic = daemonDeterministic
+
 	spawned, err := maybeSpawnDaemon(p, launch)
 	if err != nil {
 		logger.ErrorPrint("Error spawning %s daemon: %v", p.Name(), err)
@@ -151,8 +209,8 @@ func buildStandardLaunchArgs(p provider.Provider, r io.Reader) (*daemonLaunchInp
 	if launch.ExternalID != "" {
 		rootID, rootPath, _ := p.WalkUpToRoot(launch.ExternalID)
 		if rootID != "" && rootID != launch.ExternalID {
-			logger.Info("%s SessionStart resolved to root: firing=%s root=%s rollout=%s",
-				p.Name(), launch.ExternalID, rootID, rootPath)
+			logger.Info("SessionStart resolved to root", "provider", p.Name(),
+				"firing", launch.ExternalID, "root", rootID, "rollout", rootPath)
 			launch.ExternalID = rootID
 			if rootPath != "" {
 				launch.TranscriptPath = rootPath
@@ -182,7 +240,7 @@ func configDirForHook(providerName, transcriptPath string) string {
 	}
 	dir, err := provider.ClaudeCode{}.ConfigDirFromTranscript(transcriptPath)
 	if err != nil {
-		logger.Warn("config-dir derivation failed for %q: %v; using default binding", transcriptPath, err)
+		logger.Warnf("config-dir derivation failed for %q: %v; using default binding", transcriptPath, err)
 		return ""
 	}
 	return dir
@@ -230,7 +288,7 @@ func buildOpencodeLaunchArgs(r io.Reader) (*daemonLaunchInput, error) {
 	if launch.CWD == "" {
 		cwd, parentID, lookupErr := resolveOpencodeSessionInfo(in.SessionID)
 		if lookupErr != nil {
-			logger.Warn("Failed to resolve OpenCode session info for %s: %v; using defaults",
+			logger.Warnf("Failed to resolve OpenCode session info for %s: %v; using defaults",
 				in.SessionID, lookupErr)
 		} else {
 			launch.CWD = cwd
@@ -262,23 +320,68 @@ func resolveOpencodeSessionInfo(sessionID string) (cwd, parentID string, _ error
 	return reader.ReadSessionInfo(ctx, sessionID)
 }
 
-// parseSyncEnvConfig reads sync configuration from environment variables.
-//
-//   - CONFAB_SYNC_INTERVAL_MS: sync interval in milliseconds (e.g., "2000")
-//   - CONFAB_SYNC_JITTER_MS: jitter in milliseconds (e.g., "0" to disable)
-func parseSyncEnvConfig() (interval, jitter time.Duration) {
+// syncEnvDuration reads a millisecond env var, reporting false when it is
+// unset, unparsable, below minMS or above maxSyncEnvMS.
+func syncEnvDuration(name string, minMS int) (time.Duration, bool) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, false
+	}
+	ms, err := strconv.Atoi(v)
+	if err != nil || ms < minMS || ms > maxSyncEnvMS {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// resolveSyncTiming picks the daemon's sync interval and jitter. Each
+// layer overrides the one before it: the config file's sync_interval_ms
+// and sync_jitter_ms, then CONFAB_SYNC_INTERVAL_MS / CONFAB_SYNC_JITTER_MS,
+// then the launch's --sync-interval / --sync-jitter. Deterministic mode,
+// from the config or --deterministic, forces jitter to 0. Jitter is
+// capped at the interval, since env vars skip config validation.
+func resolveSyncTiming(uploadCfg *config.UploadConfig, launch *daemonLaunchInput) (interval, jitter time.Duration) {
 	interval = daemon.DefaultSyncInterval
-	if envInterval := os.Getenv("CONFAB_SYNC_INTERVAL_MS"); envInterval != "" {
-		if ms, err := strconv.Atoi(envInterval); err == nil && ms > 0 && ms <= maxSyncEnvMS {
-			interval = time.Duration(ms) * time.Millisecond
-		}
+	deterministic := launch.Deterministic
+	if uploadCfg != nil {
+		interval = uploadCfg.SyncInterval()
+		jitter = uploadCfg.SyncJitter()
+		deterministic = deterministic || uploadCfg.SyncDeterministic
 	}
-	if envJitter := os.Getenv("CONFAB_SYNC_JITTER_MS"); envJitter != "" {
-		if ms, err := strconv.Atoi(envJitter); err == nil && ms >= 0 && ms <= maxSyncEnvMS {
-			jitter = time.Duration(ms) * time.Millisecond
-		}
+	if d, ok := syncEnvDuration("CONFAB_SYNC_INTERVAL_MS", 1); ok {
+		interval = d
+	}
+	if d, ok := syncEnvDuration("CONFAB_SYNC_JITTER_MS", 0); ok {
+		jitter = d
+	}
+	if launch.SyncIntervalMS > 0 {
+		interval = time.Duration(launch.SyncIntervalMS) * time.Millisecond
+	}
+	if launch.SyncJitterMS > 0 {
+		jitter = time.Duration(launch.SyncJitterMS) * time.Millisecond
 	}
-	return
+	if deterministic {
+		jitter = 0
+	}
+	return interval, min(jitter, interval)
+}
+
+// parseSyncWatchEnv reports whether CONFAB_SYNC_WATCH enables the daemon's
+// filesystem watch mode (any strconv.ParseBool true value, e.g. "1").
+// Polling stays the default since not every filesystem delivers events.
+func parseSyncWatchEnv() bool {
+	watch, _ := strconv.ParseBool(os.Getenv("CONFAB_SYNC_WATCH"))
+	return watch
+}
+
+// parseMetricsPortEnv reads CONFAB_DAEMON_METRICS_PORT, the port for the
+// daemon's Prometheus endpoint. Unset or invalid values disable it.
+func parseMetricsPortEnv() int {
+	port, err := strconv.Atoi(os.Getenv("CONFAB_DAEMON_METRICS_PORT"))
+	if err != nil || port <= 0 || port > 65535 {
+		return 0
+	}
+	return port
 }
 
 // runDaemon decodes a daemonLaunchInput from JSON and runs the daemon
@@ -295,7 +398,12 @@ func runDaemon(hookInputJSON string) error {
 	if err != nil {
 		return err
 	}
-	syncInterval, syncJitter := parseSyncEnvConfig()
+	// An unreadable config is reported by the daemon's own backend init.
+	uploadCfg, err := config.GetUploadConfig()
+	if err != nil {
+		uploadCfg = nil
+	}
+	syncInterval, syncJitter := resolveSyncTiming(uploadCfg, &launch)
 	cfg := daemon.Config{
 		Provider:           providerName,
 		ExternalID:         launch.ExternalID,
@@ -306,6 +414,19 @@ func runDaemon(hookInputJSON string) error {
 		ParentPID:          launch.ParentPID,
 		SyncInterval:       syncInterval,
 		SyncIntervalJitter: syncJitter,
+		WatchMode:          parseSyncWatchEnv(),
+		// Debug aid; with several sessions running only the first daemon
+		// gets the port, the rest log a warning and run without it.
+		MetricsAddr: os.Getenv("CONFAB_DAEMON_METRICS_ADDR"),
+		MetricsPort: parseMetricsPortEnv(),
+		PIDFile:     launch.PIDFile,
+	}
+	// Global daemon tuning.
+	if uploadCfg != nil {
+		cfg.MaxConsecutive404 = uploadCfg.MaxConsecutive404
+		cfg.MaxSessions = uploadCfg.MaxSessions
+		cfg.TargetChunkDuration = time.Duration(uploadCfg.TargetChunkDurationMS) * time.Millisecond
+		cfg.MinChunkBytes = uploadCfg.MinChunkBytes
 	}
 	d := daemon.New(cfg)
 	return d.Run(context.Background())
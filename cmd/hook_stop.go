@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/spf13/cobra"
+)
+
+var hookStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Handle Stop hook events",
+	Long: `Handler for Stop hook events.
+
+This hook fires each time Claude finishes responding. It asks the
+session's sync daemon to sync right away instead of at its next
+interval, so a finished response reaches the backend without waiting
+for SessionEnd. The daemon keeps running.
+
+This command is typically invoked by Claude Code, not directly by users.
+
+Claude Code only.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return handleStop(os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	hookCmd.AddCommand(hookStopCmd)
+}
+
+// handleStop processes Stop hook events by flushing the session's daemon.
+// Hard-bound to ClaudeCode, like handleUserPromptSubmit. A session without
+// a running daemon is not an error: there is nothing to flush, and
+// SessionStart/UserPromptSubmit are what start one.
+func handleStop(r io.Reader, w io.Writer) error {
+	logger.Info("Stop hook triggered")
+
+	defer writeClaudeHookResponse(w, true)
+
+	claude := provider.ClaudeCode{}
+	hookInput, err := claude.ReadHookInput(r)
+	if err != nil {
+		logger.Warnf("Failed to read hook input: %v", err)
+		return nil
+	}
+
+	if err := daemon.RequestSyncForProvider(claude.Name(), hookInput.SessionID); err != nil {
+		logger.Debug("Stop hook: no flush", "session_id", hookInput.SessionID, "error", err)
+		return nil
+	}
+	logger.Info("Requested sync flush from Stop hook", "session_id", hookInput.SessionID)
+	return nil
+}
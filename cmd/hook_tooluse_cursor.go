@@ -23,7 +23,7 @@ import (
 func handlePreToolUseCursor(p provider.Provider, r io.Reader, w io.Writer) error {
 	in, err := types.ReadCursorToolUseHookInput(r)
 	if err != nil {
-		logger.Warn("Failed to read cursor tool-use hook input: %v", err)
+		logger.Warnf("Failed to read cursor tool-use hook input: %v", err)
 		return nil // Don't block the firing tool.
 	}
 
@@ -45,21 +45,26 @@ func handlePreToolUseCursor(p provider.Provider, r io.Reader, w io.Writer) error
 	}
 	isCommit := commitPos >= 0 && (prCreatePos < 0 || commitPos < prCreatePos)
 
+	if linkEnforcementBypassed(command) {
+		logger.Infof("Confab link enforcement bypassed via %s: %s", skipLinkEnv, command)
+		return allow()
+	}
+
 	confabSessionID, err := getConfabSessionID(p, in.SessionID)
 	if err != nil || confabSessionID == "" {
-		logger.Warn("Confab link skipped: no session ID available (err=%v)", err)
+		logger.Warn("Confab link skipped: no session ID available", "error", err)
 		return allow()
 	}
 
 	cfg, err := uploadConfigForHook(p, in.TranscriptPath)
 	if err != nil {
-		logger.Warn("Confab link skipped: %v", err)
+		logger.Warnf("Confab link skipped: %v", err)
 		return allow()
 	}
 
 	sessionURL, err := formatSessionURL(confabSessionID, cfg.BackendURL)
 	if err != nil {
-		logger.Warn("Confab link skipped: %v", err)
+		logger.Warnf("Confab link skipped: %v", err)
 		return allow()
 	}
 
@@ -71,10 +76,10 @@ func handlePreToolUseCursor(p provider.Provider, r io.Reader, w io.Writer) error
 	var rewritten string
 	if isCommit {
 		rewritten = rewriteCursorCommitCommand(command, sessionURL)
-		logger.Info("Rewriting cursor git commit to add Confab link -> session %s", confabSessionID)
+		logger.Infof("Rewriting cursor git commit to add Confab link -> session %s", confabSessionID)
 	} else {
 		rewritten = rewriteCursorPRCommand(command, sessionURL)
-		logger.Info("Rewriting cursor gh pr create to add Confab link -> session %s", confabSessionID)
+		logger.Infof("Rewriting cursor gh pr create to add Confab link -> session %s", confabSessionID)
 	}
 
 	// If the rewrite produced no change (e.g. an unsupported body form), allow
@@ -163,7 +168,7 @@ func shellSingleQuote(s string) string {
 func writeCursorToolUseResponse(w io.Writer, permission string, updatedInput map[string]any) error {
 	resp := types.CursorToolUseResponse{Permission: permission, UpdatedInput: updatedInput}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		logger.Debug("Failed to write cursor tool-use response: %v", err)
+		logger.Debugf("Failed to write cursor tool-use response: %v", err)
 	}
 	return nil
 }
@@ -178,7 +183,7 @@ func writeCursorToolUseResponse(w io.Writer, permission string, updatedInput map
 func handlePostToolUseCursor(p provider.Provider, r io.Reader, _ io.Writer) error {
 	in, err := types.ReadCursorToolUseHookInput(r)
 	if err != nil {
-		logger.Warn("Failed to read cursor tool-use hook input: %v", err)
+		logger.Warnf("Failed to read cursor tool-use hook input: %v", err)
 		return nil
 	}
 
@@ -207,7 +212,7 @@ func handlePostToolUseCursor(p provider.Provider, r io.Reader, _ io.Writer) erro
 
 	if firstMatch(gitCommitPattern, command) >= 0 || firstMatch(gitPushPattern, command) >= 0 {
 		if out.ExitCode != 0 {
-			logger.Debug("Cursor git command exited %d, skipping link", out.ExitCode)
+			logger.Debugf("Cursor git command exited %d, skipping link", out.ExitCode)
 			return nil
 		}
 		return linkCommitToSession(p, in.SessionID, in.CWD, in.TranscriptPath)
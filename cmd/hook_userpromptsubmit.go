@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 
+	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/ConfabulousDev/confab/pkg/logger"
 	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/ConfabulousDev/confab/pkg/types"
 	"github.com/spf13/cobra"
 )
 
@@ -18,6 +22,11 @@ This hook fires when a user submits a prompt, before Claude processes
 it. It ensures a sync daemon is running for the session, which handles
 the teleport case where SessionStart doesn't fire.
 
+Once the session is registered with the backend, it also adds the
+session URL to Claude's context, with the Confab-Link trailer to use in
+commits and PRs, so links are added up front instead of after a denied
+commit.
+
 This command is typically invoked by Claude Code, not directly by users.
 
 Claude Code only — Codex daemon liveness is driven by parent-PID
@@ -38,17 +47,18 @@ func init() {
 func handleUserPromptSubmit(r io.Reader, w io.Writer) error {
 	logger.Info("UserPromptSubmit hook triggered")
 
-	defer writeClaudeHookResponse(w, true)
+	var sessionContext string
+	defer func() { writeUserPromptSubmitResponse(w, sessionContext) }()
 
 	claude := provider.ClaudeCode{}
 	hookInput, err := claude.ReadHookInput(r)
 	if err != nil {
-		logger.Warn("Failed to read hook input: %v", err)
+		logger.Warnf("Failed to read hook input: %v", err)
 		return nil
 	}
 
-	logger.Debug("UserPromptSubmit session_id=%s prompt_length=%d",
-		hookInput.SessionID, len(hookInput.Prompt))
+	logger.Debug("UserPromptSubmit",
+		"session_id", hookInput.SessionID, "prompt_length", len(hookInput.Prompt))
 
 	launch := &daemonLaunchInput{
 		Provider:       claude.Name(),
@@ -60,11 +70,61 @@ func handleUserPromptSubmit(r io.Reader, w io.Writer) error {
 
 	spawned, err := maybeSpawnDaemon(claude, launch)
 	if err != nil {
-		logger.Warn("Failed to spawn daemon: %v", err)
+		logger.Warnf("Failed to spawn daemon: %v", err)
 		return nil
 	}
 	if spawned {
 		logger.Info("Spawned daemon from UserPromptSubmit (teleport case)")
+		return nil // Not registered with the backend yet
 	}
+
+	sessionContext = sessionLinkContext(claude, hookInput.SessionID, hookInput.TranscriptPath)
 	return nil
 }
+
+// sessionLinkContext returns the context fragment telling Claude where the
+// session is logged and which trailer to put in commits and PRs, or "" when
+// GitHub linking is disabled or the daemon hasn't registered the session
+// with the backend yet.
+func sessionLinkContext(p provider.Provider, sessionID, transcriptPath string) string {
+	if config.IsLinkFromGitHubDisabled() {
+		return ""
+	}
+	confabSessionID, err := getConfabSessionID(p, sessionID)
+	if err != nil || confabSessionID == "" {
+		logger.Debug("No session link context: no Confab session ID yet", "error", err)
+		return ""
+	}
+	cfg, err := uploadConfigForHook(p, transcriptPath)
+	if err != nil {
+		logger.Debugf("No session link context: %v", err)
+		return ""
+	}
+	sessionURL, err := formatSessionURL(confabSessionID, cfg.BackendURL)
+	if err != nil {
+		logger.Debugf("No session link context: %v", err)
+		return ""
+	}
+	return fmt.Sprintf("[This session is logged at %s. When creating git commits or PRs, include: %s]",
+		sessionURL, formatTrailerLine(sessionURL))
+}
+
+// writeUserPromptSubmitResponse is writeClaudeHookResponse (output
+// suppressed) with sessionContext, if any, added to Claude's context.
+func writeUserPromptSubmitResponse(w io.Writer, sessionContext string) {
+	response := types.UserPromptSubmitResponse{
+		ClaudeHookResponse: types.ClaudeHookResponse{
+			Continue:       true,
+			SuppressOutput: true,
+		},
+	}
+	if sessionContext != "" {
+		response.HookSpecificOutput = &types.UserPromptSubmitOutput{
+			HookEventName:     "UserPromptSubmit",
+			AdditionalContext: sessionContext,
+		}
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Debugf("Failed to write UserPromptSubmit response: %v", err)
+	}
+}
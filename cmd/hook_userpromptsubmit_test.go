@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/types"
+)
+
+// runUserPromptSubmit runs the hook for claudeSessionID with daemon spawning
+// stubbed out and returns the decoded response.
+func runUserPromptSubmit(t *testing.T, claudeSessionID string) types.UserPromptSubmitResponse {
+	t.Helper()
+	origSpawnDaemon := spawnDaemonFunc
+	t.Cleanup(func() { spawnDaemonFunc = origSpawnDaemon })
+	spawnDaemonFunc = func(*daemonLaunchInput) error { return nil }
+
+	inputJSON, _ := json.Marshal(types.ClaudeHookInput{
+		SessionID:      claudeSessionID,
+		HookEventName:  "UserPromptSubmit",
+		TranscriptPath: "/fake/transcript.jsonl",
+		Prompt:         "Fix the bug",
+	})
+	var w bytes.Buffer
+	if err := handleUserPromptSubmit(bytes.NewReader(inputJSON), &w); err != nil {
+		t.Fatalf("handleUserPromptSubmit() error = %v", err)
+	}
+	var response types.UserPromptSubmitResponse
+	if err := json.Unmarshal(w.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response %q: %v", w.String(), err)
+	}
+	if !response.Continue {
+		t.Error("expected continue=true in response")
+	}
+	return response
+}
+
+func TestHandleUserPromptSubmit_InjectsSessionURL(t *testing.T) {
+	claudeSessionID := "claude-session-123"
+	confabSessionID := "confab-session-456"
+	cleanup := setupTestState(t, claudeSessionID, confabSessionID)
+	defer cleanup()
+
+	sessionURL, err := formatSessionURL(confabSessionID, testBackendURL)
+	if err != nil {
+		t.Fatalf("formatSessionURL() error = %v", err)
+	}
+
+	response := runUserPromptSubmit(t, claudeSessionID)
+	out := response.HookSpecificOutput
+	if out == nil {
+		t.Fatal("expected hookSpecificOutput with session context, got nil")
+	}
+	if out.HookEventName != "UserPromptSubmit" {
+		t.Errorf("hookEventName = %q, want UserPromptSubmit", out.HookEventName)
+	}
+	for _, want := range []string{"logged at " + sessionURL, "include: Confab-Link: " + sessionURL + "]"} {
+		if !strings.Contains(out.AdditionalContext, want) {
+			t.Errorf("additionalContext = %q, want it to contain %q", out.AdditionalContext, want)
+		}
+	}
+}
+
+func TestHandleUserPromptSubmit_NoStateNoContext(t *testing.T) {
+	cleanup := setupTestState(t, "other-claude-session", "confab-session-456")
+	defer cleanup()
+
+	response := runUserPromptSubmit(t, "claude-session-without-state")
+	if response.HookSpecificOutput != nil {
+		t.Errorf("hookSpecificOutput = %+v, want none without daemon state", response.HookSpecificOutput)
+	}
+}
@@ -2,13 +2,18 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/ConfabulousDev/confab/pkg/logger"
 	"github.com/ConfabulousDev/confab/pkg/provider"
 	"github.com/spf13/cobra"
 )
 
-var hooksProviderName string
+var (
+	hooksProviderName string
+	hooksScope        string
+)
 
 var hooksCmd = &cobra.Command{
 	Use:   "hooks",
@@ -21,8 +26,12 @@ var hooksAddCmd = &cobra.Command{
 	Short: "Install hooks",
 	Long: `Installs the full Confab hook set for the selected provider.
 
-For Claude Code: SessionStart/End, PreToolUse, PostToolUse, and
-UserPromptSubmit hooks are installed in ~/.claude/settings.json.
+For Claude Code: SessionStart/End, PreToolUse, PostToolUse,
+UserPromptSubmit, Stop, and PreCompact hooks are installed in
+~/.claude/settings.json, or with
+--scope project (or CONFAB_SETTINGS_SCOPE=project) in the current
+directory's .claude/settings.local.json so only that project's sessions
+sync.
 
 For Codex: SessionStart, PreToolUse, and PostToolUse hooks are installed
 in ~/.codex/config.toml. Shutdown stays parent-PID driven.
@@ -41,13 +50,15 @@ For Cursor: sessionStart and sessionEnd hooks are installed in
 		}
 		for _, p := range targets {
 			fmt.Printf("Installing %s hooks...\n", p.Name())
+			before := settingsSnapshot(p)
 			path, err := p.InstallHooks()
 			if err != nil {
-				logger.Error("Failed to install %s hooks: %v", p.Name(), err)
+				logger.Errorf("Failed to install %s hooks: %v", p.Name(), err)
 				return fmt.Errorf("failed to install %s hooks: %w", p.Name(), err)
 			}
-			logger.Info("%s hooks installed in %s", p.Name(), path)
+			logger.Infof("%s hooks installed in %s", p.Name(), path)
 			fmt.Printf("✓ %s hooks installed in %s\n", p.Name(), path)
+			printSettingsChange(p, before)
 		}
 		return nil
 	},
@@ -65,24 +76,92 @@ var hooksRemoveCmd = &cobra.Command{
 		}
 		for _, p := range targets {
 			fmt.Printf("Removing %s hooks...\n", p.Name())
+			before := settingsSnapshot(p)
 			path, err := p.UninstallHooks()
 			if err != nil {
-				logger.Error("Failed to remove %s hooks: %v", p.Name(), err)
+				logger.Errorf("Failed to remove %s hooks: %v", p.Name(), err)
 				return fmt.Errorf("failed to remove %s hooks: %w", p.Name(), err)
 			}
-			logger.Info("%s hooks removed from %s", p.Name(), path)
+			logger.Infof("%s hooks removed from %s", p.Name(), path)
 			fmt.Printf("✓ %s hooks removed from %s\n", p.Name(), path)
+			printSettingsChange(p, before)
 		}
 		return nil
 	},
 }
 
+// settingsSnapshot reads p's Claude settings file before a change, so
+// printSettingsChange can show what the change did. Nil for providers
+// without one, or when it can't be read.
+func settingsSnapshot(p provider.Provider) *config.ClaudeSettings {
+	c, ok := p.(provider.ClaudeCode)
+	if !ok {
+		return nil
+	}
+	path, err := c.SettingsPath()
+	if err != nil {
+		return nil
+	}
+	settings, err := config.ReadSettingsAt(path)
+	if err != nil {
+		return nil
+	}
+	return settings
+}
+
+// printSettingsChange prints the diff between before (a settingsSnapshot)
+// and p's settings file now. Prints nothing if there was no snapshot or
+// nothing changed.
+func printSettingsChange(p provider.Provider, before *config.ClaudeSettings) {
+	if before == nil {
+		return
+	}
+	after := settingsSnapshot(p)
+	if after == nil {
+		return
+	}
+	if diff := config.PrettyDiff(before, after); diff != "" {
+		printIndentedDiff(os.Stdout, diff)
+	}
+}
+
 func hooksAddTargets() ([]provider.Provider, error) {
-	return detectedOrNamedProviders(hooksProviderName)
+	targets, err := detectedOrNamedProviders(hooksProviderName)
+	if err != nil {
+		return nil, err
+	}
+	return withSettingsScope(targets, hooksScope)
 }
 
 func hooksRemoveTargets() ([]provider.Provider, error) {
-	return allOrNamedProviders(hooksProviderName)
+	targets, err := allOrNamedProviders(hooksProviderName)
+	if err != nil {
+		return nil, err
+	}
+	return withSettingsScope(targets, hooksScope)
+}
+
+// withSettingsScope applies a --scope flag to targets. An empty flag
+// leaves them alone, so CONFAB_SETTINGS_SCOPE (or the user scope)
+// applies. The project scope exists only for claude-code and must be
+// asked for with --provider claude-code.
+func withSettingsScope(targets []provider.Provider, scopeFlag string) ([]provider.Provider, error) {
+	if scopeFlag == "" {
+		return targets, nil
+	}
+	scope, err := config.ParseSettingsScope(scopeFlag)
+	if err != nil {
+		return nil, err
+	}
+	scoped := make([]provider.Provider, 0, len(targets))
+	for _, p := range targets {
+		sp, err := provider.WithSettingsScope(p, scope)
+		if err != nil {
+			return nil, fmt.Errorf("%w (use --provider %s with --scope %s)", err, provider.NameClaudeCode, scope)
+		}
+		scoped = append(scoped, sp)
+	}
+	return scoped, nil
 }
 
 // providersByName resolves each name via provider.Get.
@@ -128,6 +207,7 @@ func allOrNamedProviders(providerName string) ([]provider.Provider, error) {
 
 func init() {
 	hooksCmd.PersistentFlags().StringVar(&hooksProviderName, "provider", "", "Provider to manage hooks for (claude-code, codex, opencode, or cursor); defaults to detected providers for add and all providers for remove")
+	hooksCmd.PersistentFlags().StringVar(&hooksScope, "scope", "", "Claude Code settings file: user (~/.claude/settings.json) or project (./.claude/settings.local.json); defaults to $CONFAB_SETTINGS_SCOPE, then user")
 	rootCmd.AddCommand(hooksCmd)
 	hooksCmd.AddCommand(hooksAddCmd)
 	hooksCmd.AddCommand(hooksRemoveCmd)
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ConfabulousDev/confab/pkg/provider"
@@ -83,3 +85,32 @@ func TestHooksRemoveTargetsAllProviders(t *testing.T) {
 		}
 	}
 }
+
+// TestHooksScopeFlag verifies --scope project retargets claude-code and is
+// refused for providers without a project settings file.
+func TestHooksScopeFlag(t *testing.T) {
+	resetHooksProviderName(t)
+	orig := hooksScope
+	t.Cleanup(func() { hooksScope = orig })
+	projectDir := t.TempDir()
+	t.Chdir(projectDir)
+
+	hooksProviderName, hooksScope = provider.NameClaudeCode, "project"
+	targets, err := hooksAddTargets()
+	if err != nil {
+		t.Fatalf("hooksAddTargets: %v", err)
+	}
+	path, err := targets[0].(provider.ClaudeCode).SettingsPath()
+	if err != nil || !strings.HasSuffix(path, filepath.Join(".claude", "settings.local.json")) {
+		t.Errorf("claude-code with --scope project: SettingsPath() = %q, %v", path, err)
+	}
+
+	hooksProviderName = provider.NameCodex
+	if _, err := hooksAddTargets(); err == nil {
+		t.Error("codex with --scope project succeeded, want an error")
+	}
+	hooksProviderName, hooksScope = provider.NameClaudeCode, "team"
+	if _, err := hooksRemoveTargets(); err == nil {
+		t.Error("--scope team succeeded, want an error")
+	}
+}
@@ -34,24 +34,24 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	// Get current executable path
 	execPath, err := os.Executable()
 	if err != nil {
-		logger.Error("Failed to get executable path: %v", err)
+		logger.Errorf("Failed to get executable path: %v", err)
 		return fmt.Errorf("failed to get executable path: %w", err)
 	}
 
 	// Resolve symlinks to get the real path
 	execPath, err = filepath.EvalSymlinks(execPath)
 	if err != nil {
-		logger.Error("Failed to resolve executable path: %v", err)
+		logger.Errorf("Failed to resolve executable path: %v", err)
 		return fmt.Errorf("failed to resolve executable path: %w", err)
 	}
-	logger.Debug("Current executable: %s", execPath)
+	logger.Debugf("Current executable: %s", execPath)
 
 	// Determine destination directory
 	destDir := installDest
 	if destDir == "" {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
-			logger.Error("Failed to get home directory: %v", err)
+			logger.Errorf("Failed to get home directory: %v", err)
 			return fmt.Errorf("failed to get home directory: %w", err)
 		}
 		destDir = filepath.Join(homeDir, ".local", "bin")
@@ -61,14 +61,14 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	if strings.HasPrefix(destDir, "~/") {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
-			logger.Error("Failed to get home directory: %v", err)
+			logger.Errorf("Failed to get home directory: %v", err)
 			return fmt.Errorf("failed to get home directory: %w", err)
 		}
 		destDir = filepath.Join(homeDir, destDir[2:])
 	}
 
 	destPath := filepath.Join(destDir, "confab")
-	logger.Info("Installing to %s", destPath)
+	logger.Infof("Installing to %s", destPath)
 
 	// Check if source and destination are the same
 	srcAbs, _ := filepath.Abs(execPath)
@@ -80,7 +80,7 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		// Create destination directory if it doesn't exist
 		fmt.Printf("Installing confab to %s...\n", destPath)
 		if err := os.MkdirAll(destDir, 0755); err != nil {
-			logger.Error("Failed to create directory %s: %v", destDir, err)
+			logger.Errorf("Failed to create directory %s: %v", destDir, err)
 			return fmt.Errorf("failed to create directory %s: %w", destDir, err)
 		}
 
@@ -89,19 +89,19 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		// a file reuses the inode, causing signature verification to fail.
 		// See: https://developer.apple.com/forums/thread/669145
 		if err := os.Remove(destPath); err != nil && !os.IsNotExist(err) {
-			logger.Error("Failed to remove existing binary: %v", err)
+			logger.Errorf("Failed to remove existing binary: %v", err)
 			return fmt.Errorf("failed to remove existing binary at %s: %w\n\nTry removing it manually: rm %s", destPath, err, destPath)
 		}
 
 		// Copy the binary
 		if err := copyFile(execPath, destPath); err != nil {
-			logger.Error("Failed to copy binary: %v", err)
+			logger.Errorf("Failed to copy binary: %v", err)
 			return fmt.Errorf("failed to copy binary: %w", err)
 		}
 
 		// Set executable permissions
 		if err := os.Chmod(destPath, 0755); err != nil {
-			logger.Error("Failed to set permissions: %v", err)
+			logger.Errorf("Failed to set permissions: %v", err)
 			return fmt.Errorf("failed to set executable permissions: %w", err)
 		}
 
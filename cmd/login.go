@@ -9,8 +9,10 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"os/user"
 	"runtime"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
@@ -22,10 +24,22 @@ import (
 // maxLoginResponseSize is the maximum size of auth response bodies.
 const maxLoginResponseSize = 10 * 1024 * 1024 // 10MB
 
-// loginHTTPClient is the HTTP client used for device-code auth requests.
-// A per-request timeout prevents a hanging backend from blocking login
-// indefinitely (the default http.Post has no timeout).
-var loginHTTPClient = &http.Client{Timeout: 30 * time.Second}
+// loginHTTPTimeout bounds each device-code auth request, so a hanging
+// backend can't block login indefinitely (the default http.Post has no
+// timeout).
+const loginHTTPTimeout = 30 * time.Second
+
+// newLoginHTTPClient returns the HTTP client for device-code auth requests
+// to backendURL. It uses the sync client's transport, so the configured
+// proxy and TLS settings apply to login too. Built per request so settings
+// saved by `setup` apply to the login that follows.
+func newLoginHTTPClient(backendURL string) (*http.Client, error) {
+	transport, err := confabhttp.NewTransport(withConnectionSettings(&config.UploadConfig{BackendURL: backendURL}))
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Timeout: loginHTTPTimeout, Transport: transport}, nil
+}
 
 // doDeviceLoginFunc is the function used to perform device login.
 // It can be overridden in tests to avoid actual authentication.
@@ -39,6 +53,11 @@ var loginCmd = &cobra.Command{
 You'll receive a code to enter at a URL. This works on any machine, including
 remote/headless servers - authenticate from any device with a browser.
 
+The new key is labeled with --name (default: <hostname>-<username>, e.g.
+devbox-alice) so you can tell your machines' keys apart in the backend's
+key list; the name is saved as key_name and shown by 'confab diagnose'.
+'confab login list-keys' lists your keys.
+
 Use --api-key to provide an API key directly (bypasses device auth flow).`,
 	RunE: runLogin,
 }
@@ -64,11 +83,14 @@ Please delete some unused API keys before creating a new one.
 Manage your keys at: %s/keys`, e.BackendURL)
 }
 
-// DeviceTokenResponse is the response from /auth/device/token
+// DeviceTokenResponse is the response from /auth/device/token.
+// RefreshToken and ExpiresIn (seconds) are set only for expiring tokens.
 type DeviceTokenResponse struct {
-	AccessToken string `json:"access_token,omitempty"`
-	TokenType   string `json:"token_type,omitempty"`
-	Error       string `json:"error,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	Error        string `json:"error,omitempty"`
 }
 
 func runLogin(cmd *cobra.Command, args []string) error {
@@ -87,7 +109,6 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get api-key flag: %w", err)
 	}
 
-	// Default key name to hostname
 	if keyName == "" {
 		keyName = defaultKeyName()
 	}
@@ -99,12 +120,12 @@ func runLogin(cmd *cobra.Command, args []string) error {
 	// Per-config-dir credentials are written only by `confab setup --config-dir`.
 	defaultBinding := config.Binding{IsDefault: true}
 	if apiKey != "" {
-		if err := loginWithAPIKey(backendURL, apiKey, defaultBinding); err != nil {
+		if err := loginWithAPIKey(os.Stdout, backendURL, apiKey, defaultBinding); err != nil {
 			return err
 		}
 	} else {
 		// Standard device auth flow
-		if err := doDeviceLogin(backendURL, keyName, defaultBinding); err != nil {
+		if err := doDeviceLogin(os.Stdout, backendURL, keyName, defaultBinding); err != nil {
 			return err
 		}
 	}
@@ -118,12 +139,13 @@ func runLogin(cmd *cobra.Command, args []string) error {
 }
 
 // loginWithAPIKey validates and saves the provided API key.
-// This is the core logic shared between `login --api-key` and `setup --api-key`.
-func loginWithAPIKey(backendURL, apiKey string, b config.Binding) error {
+// This is the core logic shared between `login --api-key` and `setup --api-key`;
+// progress goes to w.
+func loginWithAPIKey(w io.Writer, backendURL, apiKey string, b config.Binding) error {
 	logger.Info("API key provided via flag, skipping device auth")
 
-	fmt.Println("Validating API key...")
-	if err := verifyAPIKey(&config.UploadConfig{BackendURL: backendURL, APIKey: apiKey}); err != nil {
+	fmt.Fprintln(w, "Validating API key...")
+	if err := verifyAPIKey(withConnectionSettings(&config.UploadConfig{BackendURL: backendURL, APIKey: apiKey})); err != nil {
 		return fmt.Errorf("invalid API key: %w", err)
 	}
 
@@ -134,10 +156,24 @@ func loginWithAPIKey(backendURL, apiKey string, b config.Binding) error {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
-	fmt.Println("✓ API key validated and saved")
+	fmt.Fprintln(w, "✓ API key validated and saved")
 	return nil
 }
 
+// withConnectionSettings copies the global proxy and TLS settings onto cfg,
+// for requests made with credentials that aren't saved yet. Settings are
+// left unset if the config can't be read.
+func withConnectionSettings(cfg *config.UploadConfig) *config.UploadConfig {
+	global, err := config.GetUploadConfig()
+	if err != nil {
+		return cfg
+	}
+	cfg.ProxyURL = global.ProxyURL
+	cfg.CACertFile = global.CACertFile
+	cfg.TLSSkipVerify = global.TLSSkipVerify
+	return cfg
+}
+
 // verifyAPIKey checks if the API key works by calling the validate endpoint
 func verifyAPIKey(cfg *config.UploadConfig) error {
 	client, err := confabhttp.NewClient(cfg, 5*time.Second)
@@ -157,70 +193,101 @@ func verifyAPIKey(cfg *config.UploadConfig) error {
 	return nil
 }
 
-// defaultKeyName returns a descriptive name for the API key
+// defaultKeyName names a new API key after this machine and user, e.g.
+// "devbox-alice", dropping whichever part is unknown.
 func defaultKeyName() string {
-	hostname, err := os.Hostname()
-	if err != nil || hostname == "" {
-		return "Confab CLI"
+	hostname, _ := os.Hostname()
+	hostname, _, _ = strings.Cut(hostname, ".")
+	username := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		username = u.Username
+	}
+	// Windows usernames are DOMAIN\user.
+	if i := strings.LastIndex(username, "\\"); i >= 0 {
+		username = username[i+1:]
+	}
+	switch {
+	case hostname != "" && username != "":
+		return hostname + "-" + username
+	case hostname != "":
+		return hostname
+	case username != "":
+		return username
+	default:
+		return "confab-cli"
 	}
-	return fmt.Sprintf("%s (Confab CLI)", hostname)
 }
 
 // doDeviceLogin performs the device code login flow and saves credentials
-// to the given binding (top-level for the default binding).
-func doDeviceLogin(backendURL, keyName string, b config.Binding) error {
-	return doDeviceLoginFunc(backendURL, keyName, b)
+// to the given binding (top-level for the default binding). The prompt and
+// progress go to w.
+func doDeviceLogin(w io.Writer, backendURL, keyName string, b config.Binding) error {
+	return doDeviceLoginFunc(w, backendURL, keyName, b)
 }
 
 // doDeviceLoginImpl is the actual implementation of doDeviceLogin
-func doDeviceLoginImpl(backendURL, keyName string, b config.Binding) error {
-	logger.Debug("Login parameters: backend=%s, keyName=%s", backendURL, keyName)
+func doDeviceLoginImpl(w io.Writer, backendURL, keyName string, b config.Binding) error {
+	logger.Debug("Login parameters", "backend", backendURL, "key_name", keyName)
 
-	fmt.Printf("Backend: %s\n", backendURL)
-	fmt.Println()
+	fmt.Fprintf(w, "Backend: %s\n", backendURL)
+	fmt.Fprintln(w)
 
 	// Request device code
 	deviceCode, err := requestDeviceCode(backendURL, keyName)
 	if err != nil {
-		logger.Error("Failed to get device code: %v", err)
+		logger.Errorf("Failed to get device code: %v", err)
 		return fmt.Errorf("failed to initiate login: %w", err)
 	}
 
 	// Display instructions
 	verificationURL := addQueryParam(deviceCode.VerificationURI, "code", deviceCode.UserCode)
-	fmt.Println("To authenticate, visit:")
-	fmt.Printf("  %s\n", verificationURL)
-	fmt.Println()
-	fmt.Printf("Your code: %s\n", deviceCode.UserCode)
-	fmt.Println()
+	fmt.Fprintln(w, "To authenticate, visit:")
+	fmt.Fprintf(w, "  %s\n", verificationURL)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Your code: %s\n", deviceCode.UserCode)
+	fmt.Fprintln(w)
 
 	// Try to open browser
 	if err := openBrowser(verificationURL); err != nil {
-		logger.Debug("Failed to open browser: %v", err)
+		logger.Debugf("Failed to open browser: %v", err)
 	}
 
-	fmt.Printf("Waiting for authorization... (expires in %d minutes)\n", deviceCode.ExpiresIn/60)
+	fmt.Fprintf(w, "Waiting for authorization... (expires in %d minutes)\n", deviceCode.ExpiresIn/60)
 
 	// Poll for token
-	apiKey, err := pollForToken(backendURL, deviceCode)
+	token, err := pollForToken(backendURL, deviceCode)
 	if err != nil {
 		return err
 	}
 
-	if err := config.SetBindingCredentials(b, backendURL, apiKey); err != nil {
-		logger.Error("Failed to save config: %v", err)
+	if err := config.SetBindingCredentials(b, backendURL, token.AccessToken); err != nil {
+		logger.Errorf("Failed to save config: %v", err)
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if token.RefreshToken != "" {
+		var expiresAt time.Time
+		if token.ExpiresIn > 0 {
+			expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+		}
+		if err := config.SetBindingToken(b, token.AccessToken, token.RefreshToken, expiresAt); err != nil {
+			logger.Errorf("Failed to save refresh token: %v", err)
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+	if err := config.SetBindingKeyName(b, keyName); err != nil {
+		logger.Errorf("Failed to save key name: %v", err)
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
 	logger.Info("Login successful, config saved")
-	fmt.Println()
-	fmt.Println("Authentication successful!")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Authentication successful!")
 
 	return nil
 }
 
 // pollForToken polls the backend until authorization completes or times out
-func pollForToken(backendURL string, deviceCode *DeviceCodeResponse) (string, error) {
+func pollForToken(backendURL string, deviceCode *DeviceCodeResponse) (*DeviceTokenResponse, error) {
 	pollInterval := time.Duration(deviceCode.Interval) * time.Second
 	if pollInterval < 5*time.Second {
 		pollInterval = 5 * time.Second
@@ -230,15 +297,15 @@ func pollForToken(backendURL string, deviceCode *DeviceCodeResponse) (string, er
 
 	for {
 		if time.Now().After(expiresAt) {
-			return "", fmt.Errorf("authorization timed out - please try again")
+			return nil, fmt.Errorf("authorization timed out - please try again")
 		}
 
 		time.Sleep(pollInterval)
 
 		token, err := pollDeviceToken(backendURL, deviceCode.DeviceCode)
 		if err != nil {
-			logger.Error("Error polling for token: %v", err)
-			return "", fmt.Errorf("failed to complete authorization: %w", err)
+			logger.Errorf("Error polling for token: %v", err)
+			return nil, fmt.Errorf("failed to complete authorization: %w", err)
 		}
 
 		switch token.Error {
@@ -248,13 +315,13 @@ func pollForToken(backendURL string, deviceCode *DeviceCodeResponse) (string, er
 			pollInterval += 5 * time.Second
 			continue
 		case "api_key_limit_exceeded":
-			return "", &APIKeyLimitError{BackendURL: backendURL}
+			return nil, &APIKeyLimitError{BackendURL: backendURL}
 		case "":
 			if token.AccessToken != "" {
-				return token.AccessToken, nil
+				return token, nil
 			}
 		default:
-			return "", fmt.Errorf("authorization failed: %s", token.Error)
+			return nil, fmt.Errorf("authorization failed: %s", token.Error)
 		}
 	}
 }
@@ -267,7 +334,11 @@ func requestDeviceCode(backendURL, keyName string) (*DeviceCodeResponse, error)
 		return nil, err
 	}
 
-	resp, err := loginHTTPClient.Post(backendURL+"/auth/device/code", "application/json", bytes.NewReader(jsonBody))
+	client, err := newLoginHTTPClient(backendURL)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(backendURL+"/auth/device/code", "application/json", bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to contact server: %w", err)
 	}
@@ -298,7 +369,11 @@ func pollDeviceToken(backendURL, deviceCode string) (*DeviceTokenResponse, error
 		return nil, err
 	}
 
-	resp, err := loginHTTPClient.Post(backendURL+"/auth/device/token", "application/json", bytes.NewReader(jsonBody))
+	client, err := newLoginHTTPClient(backendURL)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(backendURL+"/auth/device/token", "application/json", bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to contact server: %w", err)
 	}
@@ -317,6 +392,64 @@ func pollDeviceToken(backendURL, deviceCode string) (*DeviceTokenResponse, error
 	return &token, nil
 }
 
+var loginListKeysCmd = &cobra.Command{
+	Use:   "list-keys",
+	Short: "List your API keys on the backend",
+	Long: `Lists the API keys of your account on the configured backend, by the
+name each was created under (see 'confab login --name').`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newAuthedClient()
+		if err != nil {
+			return err
+		}
+		cfg, err := config.GetUploadConfig()
+		if err != nil {
+			return err
+		}
+		var resp apiKeysResponse
+		if err := client.Get("/api/v1/auth/keys", &resp); err != nil {
+			return fmt.Errorf("failed to list API keys: %w", err)
+		}
+		return printAPIKeys(os.Stdout, resp.Keys, cfg.KeyName)
+	},
+}
+
+// apiKeyInfo is one entry of GET /api/v1/auth/keys.
+type apiKeyInfo struct {
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// apiKeysResponse is the response from GET /api/v1/auth/keys.
+type apiKeysResponse struct {
+	Keys []apiKeyInfo `json:"keys"`
+}
+
+// printAPIKeys prints keys as a table, starring those named currentName
+// (this machine's key_name).
+func printAPIKeys(w io.Writer, keys []apiKeyInfo, currentName string) error {
+	if len(keys) == 0 {
+		fmt.Fprintln(w, "No API keys.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  NAME\tCREATED\tLAST USED")
+	for _, k := range keys {
+		marker := " "
+		if currentName != "" && k.Name == currentName {
+			marker = "*"
+		}
+		lastUsed := "never"
+		if k.LastUsedAt != nil {
+			lastUsed = k.LastUsedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\n", marker, k.Name, k.CreatedAt.Local().Format("2006-01-02"), lastUsed)
+	}
+	return tw.Flush()
+}
+
 // openBrowser opens a URL in the default browser
 func openBrowser(url string) error {
 	var cmd *exec.Cmd
@@ -345,9 +478,10 @@ func addQueryParam(baseURL, key, value string) string {
 
 func init() {
 	rootCmd.AddCommand(loginCmd)
+	loginCmd.AddCommand(loginListKeysCmd)
 
 	loginCmd.Flags().String("backend-url", "", "Backend API URL (required)")
 	loginCmd.MarkFlagRequired("backend-url")
-	loginCmd.Flags().String("name", "", "Name for this API key (default: hostname)")
+	loginCmd.Flags().String("name", "", "Name for this API key (default: <hostname>-<username>)")
 	loginCmd.Flags().String("api-key", "", "API key (bypasses device auth flow)")
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -301,7 +302,7 @@ func TestRunLogin_WithAPIKeyFlag(t *testing.T) {
 
 	// Track if device login was called
 	var loginCalled bool
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		loginCalled = true
 		return nil
 	}
@@ -354,7 +355,7 @@ func TestRunLogin_WithAPIKeyFlag_InvalidKey(t *testing.T) {
 	_, configPath := setupSetupTestEnv(t, server.URL)
 
 	var loginCalled bool
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		loginCalled = true
 		return nil
 	}
@@ -419,7 +420,7 @@ func TestRunLogin_WithoutAPIKeyFlag(t *testing.T) {
 
 	var loginCalled bool
 	var loginBackendURL string
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		loginCalled = true
 		loginBackendURL = backendURL
 		newCfg := &config.UploadConfig{
@@ -561,7 +562,7 @@ func TestLoginDeviceFlow_PreservesRedactionConfig(t *testing.T) {
 	os.WriteFile(configPath, cfgData, 0600)
 
 	// Mock device login to simulate the FIXED behavior (preserves config)
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		// Load existing config (this is what the fix does)
 		cfg, err := config.GetUploadConfig()
 		if err != nil {
@@ -677,3 +678,63 @@ func TestLoginCmd_BackendURLRequired(t *testing.T) {
 		t.Error("expected backend-url flag to be marked as required")
 	}
 }
+
+func TestRunLogin_KeyName(t *testing.T) {
+	origDoDeviceLogin := doDeviceLoginFunc
+	defer func() { doDeviceLoginFunc = origDoDeviceLogin }()
+	setupSetupTestEnv(t, "https://confab.example.com")
+
+	var gotName string
+	doDeviceLoginFunc = func(_ io.Writer, _, keyName string, _ config.Binding) error {
+		gotName = keyName
+		return nil
+	}
+	for flag, want := range map[string]string{"laptop": "laptop", "": defaultKeyName()} {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("backend-url", "https://confab.example.com", "")
+		cmd.Flags().String("name", flag, "")
+		cmd.Flags().String("api-key", "", "")
+		captureStdout(t, func() {
+			if err := runLogin(cmd, nil); err != nil {
+				t.Fatalf("runLogin: %v", err)
+			}
+		})
+		if gotName != want {
+			t.Errorf("--name %q: key name = %q, want %q", flag, gotName, want)
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	hostname, _, _ = strings.Cut(hostname, ".")
+	if name := defaultKeyName(); hostname != "" && !strings.HasPrefix(name, hostname+"-") {
+		t.Errorf("defaultKeyName = %q, want %s-<username>", name, hostname)
+	}
+}
+
+func TestPrintAPIKeys(t *testing.T) {
+	used := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+	keys := []apiKeyInfo{
+		{Name: "devbox-alice", CreatedAt: time.Date(2026, 1, 5, 0, 0, 0, 0, time.Local), LastUsedAt: &used},
+		{Name: "laptop-alice", CreatedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)},
+	}
+	var out strings.Builder
+	if err := printAPIKeys(&out, keys, "devbox-alice"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want header + 2:\n%s", len(lines), out.String())
+	}
+	if !strings.HasPrefix(lines[1], "* devbox-alice") || !strings.Contains(lines[1], "2026-03-02 10:00") {
+		t.Errorf("current key row = %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "  laptop-alice") || !strings.Contains(lines[2], "never") {
+		t.Errorf("other key row = %q", lines[2])
+	}
+
+	out.Reset()
+	printAPIKeys(&out, nil, "")
+	if !strings.Contains(out.String(), "No API keys") {
+		t.Errorf("empty list output = %q", out.String())
+	}
+}
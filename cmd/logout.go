@@ -1,27 +1,48 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
+	confabhttp "github.com/ConfabulousDev/confab/pkg/http"
 	"github.com/ConfabulousDev/confab/pkg/logger"
 	"github.com/spf13/cobra"
 )
 
+var (
+	logoutRevoke      bool
+	logoutRemoveHooks bool
+)
+
+// logoutRevokeTimeout bounds the revoke request, so an unreachable backend
+// can't hold up logout.
+const logoutRevokeTimeout = 10 * time.Second
+
 var logoutCmd = &cobra.Command{
 	Use:   "logout",
 	Short: "Clear API key and disable sync",
-	Long:  `Removes the stored API key and disables sync.`,
-	RunE:  runLogout,
+	Long: `Removes the stored API key (and refresh token) and disables sync.
+Backend URL, redaction and log level settings are kept.
+
+With --revoke the key is first revoked on the backend, so a copy of it
+elsewhere stops working too; a failed revoke is reported but the key is
+still cleared locally. With --remove-hooks the confab hooks are removed
+from every provider as well. Safe to run when already logged out.`,
+	Args: cobra.NoArgs,
+	RunE: runLogout,
 }
 
 func runLogout(cmd *cobra.Command, args []string) error {
 	logger.Info("Starting logout")
 
 	// Get current config
-	cfg, err := config.GetUploadConfig()
+	cfg, err := config.GetGlobalUploadConfig()
 	if err != nil {
-		logger.Error("Failed to get config: %v", err)
+		logger.Errorf("Failed to get config: %v", err)
 		return fmt.Errorf("failed to get config: %w", err)
 	}
 
@@ -29,15 +50,32 @@ func runLogout(cmd *cobra.Command, args []string) error {
 	if cfg.APIKey == "" {
 		logger.Info("Already logged out, no API key found")
 		fmt.Println("Already logged out. No API key found.")
+		if logoutRemoveHooks {
+			return removeAllHooks(os.Stdout)
+		}
 		return nil
 	}
 
-	// Clear API key
+	if logoutRevoke {
+		if err := revokeAPIKey(cfg); err != nil {
+			logger.Warnf("Failed to revoke API key: %v", err)
+			fmt.Printf("⚠ Could not revoke the API key on the backend: %v\n", err)
+			fmt.Println("  It is still cleared locally; delete it from the web dashboard if needed.")
+		} else {
+			logger.Info("API key revoked on backend")
+			fmt.Println("✓ API key revoked on the backend")
+		}
+	}
+
+	// Clear API key, and the device-login token state that goes with it
 	cfg.APIKey = ""
+	cfg.RefreshToken = ""
+	cfg.ExpiresAt = time.Time{}
+	cfg.KeyName = ""
 
 	// Save config
 	if err := config.SaveUploadConfig(cfg); err != nil {
-		logger.Error("Failed to save config: %v", err)
+		logger.Errorf("Failed to save config: %v", err)
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
@@ -49,9 +87,57 @@ func runLogout(cmd *cobra.Command, args []string) error {
 	fmt.Println("To login again, run:")
 	fmt.Println("  confab login")
 
+	if logoutRemoveHooks {
+		fmt.Println()
+		return removeAllHooks(os.Stdout)
+	}
+	return nil
+}
+
+// revokeAPIKey asks the backend to revoke cfg's API key
+// (POST /api/v1/auth/revoke, authenticated with the key itself).
+func revokeAPIKey(cfg *config.UploadConfig) error {
+	client, err := confabhttp.NewClient(cfg, logoutRevokeTimeout)
+	if err != nil {
+		return err
+	}
+	err = client.Post("/api/v1/auth/revoke", struct{}{}, nil)
+	switch {
+	case errors.Is(err, confabhttp.ErrSessionNotFound):
+		return fmt.Errorf("backend does not support key revocation")
+	case errors.Is(err, confabhttp.ErrUnauthorized):
+		return fmt.Errorf("key already invalid or revoked")
+	}
+	return err
+}
+
+// removeAllHooks removes the confab hooks from every provider, printing a
+// line for each. A provider that fails doesn't stop the others.
+func removeAllHooks(w io.Writer) error {
+	providers, err := allOrNamedProviders("")
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, p := range providers {
+		path, err := p.UninstallHooks()
+		if err != nil {
+			logger.Errorf("Failed to remove %s hooks: %v", p.Name(), err)
+			fmt.Fprintf(w, "✗ remove %s hooks: %v\n", p.Name(), err)
+			failed++
+			continue
+		}
+		logger.Infof("%s hooks removed from %s", p.Name(), path)
+		fmt.Fprintf(w, "✓ removed %s hooks from %s\n", p.Name(), path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to remove hooks for %d of %d provider(s)", failed, len(providers))
+	}
 	return nil
 }
 
 func init() {
+	logoutCmd.Flags().BoolVar(&logoutRevoke, "revoke", false, "Revoke the API key on the backend before clearing it")
+	logoutCmd.Flags().BoolVar(&logoutRemoveHooks, "remove-hooks", false, "Also remove confab hooks from every provider")
 	rootCmd.AddCommand(logoutCmd)
 }
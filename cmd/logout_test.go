@@ -2,11 +2,16 @@ package cmd
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/provider"
 	"github.com/spf13/cobra"
 )
 
@@ -268,3 +273,116 @@ func TestLogout_AlreadyLoggedOut(t *testing.T) {
 		t.Errorf("log_level was changed from 'warn' to '%s'", savedCfg.LogLevel)
 	}
 }
+
+// setLogoutFlags sets the logout flags for one test.
+func setLogoutFlags(t *testing.T, revoke, removeHooks bool) {
+	t.Helper()
+	oldRevoke, oldRemoveHooks := logoutRevoke, logoutRemoveHooks
+	logoutRevoke, logoutRemoveHooks = revoke, removeHooks
+	t.Cleanup(func() { logoutRevoke, logoutRemoveHooks = oldRevoke, oldRemoveHooks })
+}
+
+// TestLogout_RevokeClearsTokenState verifies that --revoke sends the key
+// to the revoke endpoint and that the refresh token state is cleared with
+// it, while other settings are kept
+func TestLogout_RevokeClearsTokenState(t *testing.T) {
+	var revokedWith string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/auth/revoke" {
+			http.NotFound(w, r)
+			return
+		}
+		revokedWith = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	_, configPath := setupLogoutTestEnv(t)
+	setLogoutFlags(t, true, false)
+	existingCfg := config.UploadConfig{
+		BackendURL:   server.URL,
+		APIKey:       "test-api-key-12345678",
+		RefreshToken: "refresh-token",
+		ExpiresAt:    time.Now().Add(time.Hour),
+		KeyName:      "laptop",
+		LogLevel:     "debug",
+	}
+	cfgData, _ := json.Marshal(existingCfg)
+	os.WriteFile(configPath, cfgData, 0600)
+
+	if err := runLogout(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runLogout failed: %v", err)
+	}
+	if revokedWith != "Bearer test-api-key-12345678" {
+		t.Errorf("revoke Authorization = %q, want the API key", revokedWith)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	var savedCfg config.UploadConfig
+	if err := json.Unmarshal(data, &savedCfg); err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if savedCfg.APIKey != "" || savedCfg.RefreshToken != "" || !savedCfg.ExpiresAt.IsZero() || savedCfg.KeyName != "" {
+		t.Errorf("credentials not cleared: %+v", savedCfg)
+	}
+	if savedCfg.BackendURL != server.URL || savedCfg.LogLevel != "debug" {
+		t.Errorf("settings not preserved: backend %q, log_level %q", savedCfg.BackendURL, savedCfg.LogLevel)
+	}
+}
+
+// TestLogout_RevokeFailureStillClearsKey verifies that a backend without
+// the revoke endpoint doesn't block logout
+func TestLogout_RevokeFailureStillClearsKey(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, configPath := setupLogoutTestEnv(t)
+	setLogoutFlags(t, true, false)
+	cfgData, _ := json.Marshal(config.UploadConfig{BackendURL: server.URL, APIKey: "test-api-key-12345678"})
+	os.WriteFile(configPath, cfgData, 0600)
+
+	if err := runLogout(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runLogout failed: %v", err)
+	}
+	cfg, err := config.GetGlobalUploadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.APIKey != "" {
+		t.Errorf("API key not cleared after a failed revoke: %q", cfg.APIKey)
+	}
+}
+
+// TestLogout_RemoveHooks verifies that --remove-hooks uninstalls the
+// hooks, both on logout and when already logged out
+func TestLogout_RemoveHooks(t *testing.T) {
+	for _, apiKey := range []string{"test-api-key-12345678", ""} {
+		tmpDir, configPath := setupSetupTestEnv(t, "https://confab.example.com")
+		t.Setenv(provider.CodexStateDirEnv, filepath.Join(tmpDir, ".codex"))
+		t.Setenv(provider.CursorStateDirEnv, filepath.Join(tmpDir, ".cursor"))
+		t.Setenv("CONFAB_OPENCODE_CONFIG_DIR", filepath.Join(tmpDir, ".config", "opencode"))
+		setLogoutFlags(t, false, true)
+
+		claude, err := provider.Get(provider.NameClaudeCode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := claude.InstallHooks(); err != nil {
+			t.Fatalf("InstallHooks: %v", err)
+		}
+		verifyHooksInstalled(t)
+		cfgData, _ := json.Marshal(config.UploadConfig{BackendURL: "https://confab.example.com", APIKey: apiKey})
+		os.WriteFile(configPath, cfgData, 0600)
+
+		if err := runLogout(&cobra.Command{}, nil); err != nil {
+			t.Fatalf("api key %q: runLogout failed: %v", apiKey, err)
+		}
+		settingsPath, _ := config.GetSettingsPath()
+		if data, _ := os.ReadFile(settingsPath); strings.Contains(string(data), "hook session-start") {
+			t.Errorf("api key %q: hooks still installed:\n%s", apiKey, data)
+		}
+	}
+}
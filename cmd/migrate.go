@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateFromVersion string
+	migrateDryRun      bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade config and hooks written by an older confab",
+	Long: `Upgrade ~/.confab/config.json, and the Claude Code hooks installed with
+it, to the schema this confab writes.
+
+The config's "version" field records its schema version (none means 0).
+Every registered migration from that version up is applied in order, then
+the config is stamped with the current version, so running migrate again
+does nothing. --from-version overrides the detected version, e.g. to
+re-run a migration after restoring an old settings.json.
+
+Migrations:
+  v0 → v1  rewrite "confab save" and "confab sync start/stop" hooks as
+           "confab hook session-start/session-end"
+
+Examples:
+  confab migrate --dry-run
+  confab migrate
+  confab migrate --from-version v0`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from := -1
+		if migrateFromVersion != "" {
+			v, err := parseConfigVersion(migrateFromVersion)
+			if err != nil {
+				return err
+			}
+			from = v
+		}
+		settingsPath, err := config.GetSettingsPath()
+		if err != nil {
+			return err
+		}
+		return runMigrate(os.Stdout, settingsPath, from, migrateDryRun)
+	},
+}
+
+func init() {
+	migrateCmd.Flags().StringVar(&migrateFromVersion, "from-version", "", `Schema version to migrate from, e.g. "v0" (default: the config's version)`)
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Show what would change without writing anything")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// parseConfigVersion parses a --from-version value such as "v1" or "1".
+func parseConfigVersion(s string) (int, error) {
+	v, err := strconv.Atoi(strings.TrimPrefix(s, "v"))
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid --from-version %q: want a version like v1", s)
+	}
+	return v, nil
+}
+
+// runMigrate applies the migrations pending from the config's schema
+// version (or from, when non-negative) to config.json and the Claude
+// settings at settingsPath. A missing config.json is left missing.
+func runMigrate(w io.Writer, settingsPath string, from int, dryRun bool) error {
+	raw, err := config.ReadRawConfig()
+	if err != nil {
+		return err
+	}
+	configExists := raw != nil
+	if !configExists {
+		raw = map[string]any{}
+	}
+	if from >= 0 {
+		raw["version"] = from
+	}
+	migrated, from, err := config.MigrateConfig(raw)
+	if err != nil {
+		return err
+	}
+	pending, err := config.PendingMigrations(from)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Fprintf(w, "Config is at schema version v%d; nothing to migrate.\n", from)
+		return nil
+	}
+
+	verb := "Migrating"
+	if dryRun {
+		verb = "Would migrate"
+	}
+	fmt.Fprintf(w, "%s config schema v%d → v%d:\n", verb, from, config.CurrentConfigVersion)
+	for _, m := range pending {
+		fmt.Fprintf(w, "  v%d → v%d: %s\n", m.From, m.To, m.Description)
+	}
+
+	settings, err := config.ReadSettingsAt(settingsPath)
+	if err != nil {
+		return err
+	}
+	changed, err := config.MigrateHooks(settings, from)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "Would update %d hook(s) in %s\n", changed, settingsPath)
+		fmt.Fprintln(w, "Dry run: nothing written.")
+		return nil
+	}
+
+	if changed > 0 {
+		err := config.AtomicUpdateSettingsAt(settingsPath, func(s *config.ClaudeSettings) error {
+			_, err := config.MigrateHooks(s, from)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update hooks: %w", err)
+		}
+	}
+	fmt.Fprintf(w, "Updated %d hook(s) in %s\n", changed, settingsPath)
+
+	if configExists {
+		if err := config.WriteRawConfig(migrated); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Config is now at schema version v%d.\n", config.CurrentConfigVersion)
+	}
+	return nil
+}
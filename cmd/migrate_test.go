@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+)
+
+func TestRunMigrate(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	settingsPath := filepath.Join(dir, "settings.json")
+	t.Setenv(config.ConfigPathEnv, configPath)
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(configPath, `{"backend_url": "https://confab.example.com", "api_key": ""}`)
+	legacySettings := `{"model": "opus", "hooks": {"SessionEnd": [{"matcher": "*", "hooks": [{"type": "command", "command": "confab save"}]}]}}`
+	writeFile(settingsPath, legacySettings)
+
+	var out bytes.Buffer
+	if err := runMigrate(&out, settingsPath, -1, true); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(out.String(), "Would migrate config schema v0 → v1") || !strings.Contains(out.String(), "Would update 2 hook(s)") {
+		t.Errorf("unexpected dry-run output:\n%s", out.String())
+	}
+	if data, _ := os.ReadFile(settingsPath); string(data) != legacySettings {
+		t.Errorf("dry run rewrote settings.json:\n%s", data)
+	}
+
+	out.Reset()
+	if err := runMigrate(&out, settingsPath, -1, false); err != nil {
+		t.Fatalf("migrate: %v\n%s", err, out.String())
+	}
+	hooks, err := config.GetInstalledHooksAt(settingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var commands []string
+	for _, h := range hooks {
+		commands = append(commands, h.Event+": "+h.Command)
+	}
+	want := []string{
+		"SessionEnd: confab hook session-end --provider claude-code",
+		"SessionStart: confab hook session-start --provider claude-code",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("hooks after migrate = %q, want %q", commands, want)
+	}
+	cfg, err := config.GetGlobalUploadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != config.CurrentConfigVersion || cfg.BackendURL != "https://confab.example.com" {
+		t.Errorf("config after migrate: version %d, backend %q", cfg.Version, cfg.BackendURL)
+	}
+
+	out.Reset()
+	if err := runMigrate(&out, settingsPath, -1, false); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	if !strings.Contains(out.String(), "nothing to migrate") {
+		t.Errorf("second migrate should be a no-op:\n%s", out.String())
+	}
+}
+
+func TestParseConfigVersion(t *testing.T) {
+	for in, want := range map[string]int{"v0": 0, "v1": 1, "1": 1} {
+		if got, err := parseConfigVersion(in); err != nil || got != want {
+			t.Errorf("parseConfigVersion(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "v", "one", "v-1"} {
+		if _, err := parseConfigVersion(in); err == nil {
+			t.Errorf("parseConfigVersion(%q) succeeded, want an error", in)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	"github.com/ConfabulousDev/confab/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause [session-id]",
+	Short: "Temporarily stop running sync daemons from uploading",
+	Long: `Pause uploads from running sync daemons, e.g. during a video call or a
+large build. Daemons keep running and keep the session's lines on disk;
+they upload everything they missed once resumed.
+
+A pause lasts at most an hour, after which each daemon resumes on its own.
+With a session ID (or prefix), only that session's daemon is paused.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDaemonControl(os.Stdout, daemon.ControlPause, firstArg(args))
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [session-id]",
+	Short: "Resume uploads from paused sync daemons",
+	Long: `Resume uploads from sync daemons paused with 'confab pause'. With a
+session ID (or prefix), only that session's daemon is resumed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDaemonControl(os.Stdout, daemon.ControlResume, firstArg(args))
+	},
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// runDaemonControl sends controlCmd to every running daemon whose session
+// ID starts with sessionPrefix (all of them when empty) and prints one line
+// per daemon. Fails if no daemon matched or any of them couldn't be reached.
+func runDaemonControl(w io.Writer, controlCmd, sessionPrefix string) error {
+	states, err := daemon.ListAllStates()
+	if err != nil {
+		return fmt.Errorf("failed to list daemon states: %w", err)
+	}
+
+	matched, failed := 0, 0
+	for _, state := range states {
+		if !state.IsDaemonRunning() || !strings.HasPrefix(state.ExternalID, sessionPrefix) {
+			continue
+		}
+		matched++
+		id := utils.TruncateSecret(state.ExternalID, 8, 0)
+		socketPath, err := daemon.GetSocketPathForProvider(state.Provider, state.ExternalID)
+		if err != nil {
+			return err
+		}
+		resp, err := daemon.SendControl(socketPath, controlCmd)
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "✗ %s: %v\n", id, err)
+			continue
+		}
+		if resp.Paused && resp.PausedUntil != nil {
+			fmt.Fprintf(w, "✓ %s paused until %s\n", id, resp.PausedUntil.Format(time.Kitchen))
+		} else {
+			fmt.Fprintf(w, "✓ %s syncing\n", id)
+		}
+	}
+
+	switch {
+	case matched == 0 && sessionPrefix != "":
+		return fmt.Errorf("no running sync daemon for session %s", sessionPrefix)
+	case matched == 0:
+		fmt.Fprintln(w, "No sync daemons running")
+	case failed > 0:
+		return fmt.Errorf("%d of %d daemons could not be reached", failed, matched)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+)
+
+// fakeControlSocket stands in for a running daemon: it saves a state file
+// owned by this (live) process and answers control requests, recording
+// the commands it receives.
+func fakeControlSocket(t *testing.T, sessionID string) chan string {
+	t.Helper()
+	state := daemon.NewStateForProvider(provider.NameClaudeCode, sessionID, "/fake/t.jsonl", "/work", 0)
+	if err := state.Save(); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+	socketPath, err := daemon.GetSocketPathForProvider(provider.NameClaudeCode, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	cmds := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			var req daemon.ControlRequest
+			line, _ := bufio.NewReader(conn).ReadBytes('\n')
+			json.Unmarshal(line, &req)
+			cmds <- req.Cmd
+			resp := daemon.ControlResponse{OK: true}
+			if req.Cmd == daemon.ControlPause {
+				until := time.Now().Add(time.Hour)
+				resp.Paused, resp.PausedUntil = true, &until
+			}
+			json.NewEncoder(conn).Encode(resp)
+			conn.Close()
+		}
+	}()
+	return cmds
+}
+
+func TestPauseResume_SendsToRunningDaemons(t *testing.T) {
+	setupSetupTestEnv(t, "")
+	cmds := fakeControlSocket(t, "abc12345-pause")
+
+	var out bytes.Buffer
+	if err := runDaemonControl(&out, daemon.ControlPause, ""); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if got := <-cmds; got != daemon.ControlPause {
+		t.Errorf("daemon received %q, want pause", got)
+	}
+	if !strings.Contains(out.String(), "✓ abc12345... paused until") {
+		t.Errorf("unexpected pause output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runDaemonControl(&out, daemon.ControlResume, "abc1"); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if got := <-cmds; got != daemon.ControlResume {
+		t.Errorf("daemon received %q, want resume", got)
+	}
+	if !strings.Contains(out.String(), "✓ abc12345... syncing") {
+		t.Errorf("unexpected resume output:\n%s", out.String())
+	}
+
+	if err := runDaemonControl(&out, daemon.ControlPause, "zzz"); err == nil || !strings.Contains(err.Error(), "no running sync daemon") {
+		t.Errorf("unmatched session: err = %v", err)
+	}
+}
+
+func TestPause_NoDaemons(t *testing.T) {
+	setupSetupTestEnv(t, "")
+
+	var out bytes.Buffer
+	if err := runDaemonControl(&out, daemon.ControlPause, ""); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if !strings.Contains(out.String(), "No sync daemons running") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/redactor"
+	"github.com/ConfabulousDev/confab/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	redactPreview bool
+	redactJSON    bool
+	redactLine    string
+)
+
+var redactCmd = &cobra.Command{
+	Use:   "redact --preview <file>",
+	Short: "Preview what redaction would remove from a JSONL file",
+	Long: `Preview which spans of a JSONL file your redaction configuration would
+redact, without uploading anything or modifying any file.
+
+Loads the redaction settings from ~/.confab/config.json (including the
+built-in default patterns unless use_default_patterns is false), prints
+each line with matched spans highlighted, and summarizes the number of
+matches per pattern name. Exits non-zero if any pattern fails to compile.
+
+With --json, prints an array of {line, pattern_name, start, end} objects
+(1-based line numbers, byte offsets within the line) for scripted audits.
+
+Examples:
+  confab redact --preview transcript.jsonl
+  confab redact --preview --json transcript.jsonl | jq 'group_by(.pattern_name)'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !redactPreview {
+			return fmt.Errorf("only preview mode is supported; pass --preview")
+		}
+		logger.Infof("Running redact preview on %s", args[0])
+
+		r, err := previewRedactor()
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer f.Close()
+
+		if redactJSON {
+			return writeRedactionPreviewJSON(os.Stdout, f, r)
+		}
+		return writeRedactionPreview(os.Stdout, f, r, stdoutIsTerminal())
+	},
+}
+
+var redactTestCmd = &cobra.Command{
+	Use:   "test --line <json>",
+	Short: "Print one line as it would be uploaded after redaction",
+	Long: `Apply your redaction configuration to a single line and print the
+result: regex patterns first, then field_names rules. Uses the same rules
+as 'confab redact --preview' (the defaults if none are configured).
+Uploads nothing.
+
+Example:
+  confab redact test --line '{"password":"secret","user":{"token":"abc"}}'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if redactLine == "" {
+			return fmt.Errorf("--line is required")
+		}
+		logger.Info("Running redact test")
+		return runRedactTest(os.Stdout, redactLine)
+	},
+}
+
+// runRedactTest writes line as previewRedactor's rules would upload it.
+func runRedactTest(w io.Writer, line string) error {
+	r, err := previewRedactor()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, r.RedactJSONLine(line))
+	return err
+}
+
+// redactionPreviewMatch is one element of the --json output.
+type redactionPreviewMatch struct {
+	Line        int    `json:"line"`
+	PatternName string `json:"pattern_name"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+}
+
+// previewRedactor builds the redactor the preview runs with. A missing
+// redaction section previews the defaults (what `confab setup` would
+// install); enabled=false is ignored so disabled rules can still be audited.
+func previewRedactor() (*redactor.Redactor, error) {
+	cfg, err := config.GetUploadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	redactionCfg := cfg.GetEffectiveRedactionConfig()
+	if redactionCfg == nil {
+		redactionCfg = &config.RedactionConfig{Enabled: true}
+	}
+	r, err := redactor.NewFromConfig(redactionCfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction config: %w", err)
+	}
+	if r == nil {
+		return nil, fmt.Errorf("no redaction patterns configured")
+	}
+	return r, nil
+}
+
+// scanPreviewLines calls fn for every line of in with its 1-based number.
+func scanPreviewLines(in io.Reader, fn func(lineNum int, line string)) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), types.MaxJSONLLineSize)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		fn(lineNum, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	return nil
+}
+
+// writeRedactionPreviewJSON writes every match as a JSON array.
+func writeRedactionPreviewJSON(w io.Writer, in io.Reader, r *redactor.Redactor) error {
+	out := []redactionPreviewMatch{}
+	err := scanPreviewLines(in, func(lineNum int, line string) {
+		for _, m := range r.FindMatches(line) {
+			out = append(out, redactionPreviewMatch{
+				Line:        lineNum,
+				PatternName: m.PatternName,
+				Start:       m.Start,
+				End:         m.End,
+			})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeRedactionPreview writes each line with its matched spans highlighted,
+// followed by a per-pattern match count. color selects ANSI reverse video;
+// otherwise spans are wrapped in «».
+func writeRedactionPreview(w io.Writer, in io.Reader, r *redactor.Redactor, color bool) error {
+	counts := make(map[string]int)
+	err := scanPreviewLines(in, func(lineNum int, line string) {
+		matches := r.FindMatches(line)
+		for _, m := range matches {
+			counts[m.PatternName]++
+		}
+		fmt.Fprintf(w, "%d: %s\n", lineNum, highlightSpans(line, matches, color))
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	if len(counts) == 0 {
+		fmt.Fprintln(w, "No redaction matches found.")
+		return nil
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "Matches by pattern:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s: %d\n", name, counts[name])
+	}
+	return nil
+}
+
+// highlightSpans wraps each matched span of line in highlight markers.
+// Overlapping spans (several patterns hitting the same text) are merged.
+func highlightSpans(line string, matches []redactor.Match, color bool) string {
+	if len(matches) == 0 {
+		return line
+	}
+	openMark, closeMark := "«", "»"
+	if color {
+		openMark, closeMark = "\033[7m", "\033[0m"
+	}
+
+	var b strings.Builder
+	pos := 0
+	for i := 0; i < len(matches); {
+		start, end := matches[i].Start, matches[i].End
+		i++
+		for i < len(matches) && matches[i].Start <= end {
+			if matches[i].End > end {
+				end = matches[i].End
+			}
+			i++
+		}
+		if start < pos {
+			start = pos
+		}
+		b.WriteString(line[pos:start])
+		b.WriteString(openMark)
+		b.WriteString(line[start:end])
+		b.WriteString(closeMark)
+		pos = end
+	}
+	b.WriteString(line[pos:])
+	return b.String()
+}
+
+// stdoutIsTerminal reports whether stdout is an interactive terminal, so
+// highlighting uses ANSI escapes only when they will render. Honors NO_COLOR.
+func stdoutIsTerminal() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	redactCmd.Flags().BoolVar(&redactPreview, "preview", false, "Show what would be redacted without uploading or modifying anything")
+	redactCmd.Flags().BoolVar(&redactJSON, "json", false, "Emit matches as a JSON array of {line, pattern_name, start, end}")
+	redactTestCmd.Flags().StringVar(&redactLine, "line", "", "JSON line to redact")
+	redactCmd.AddCommand(redactTestCmd)
+	rootCmd.AddCommand(redactCmd)
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/redactor"
+)
+
+func newPreviewTestRedactor(t *testing.T) *redactor.Redactor {
+	t.Helper()
+	useDefaults := false
+	r, err := redactor.NewFromConfig(&config.RedactionConfig{
+		Enabled:            true,
+		UseDefaultPatterns: &useDefaults,
+		Patterns: []config.RedactionPattern{
+			{Name: "Test Key", Pattern: `tk_[a-z0-9]{8}`, Type: "test_key"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	return r
+}
+
+func TestWriteRedactionPreview_HighlightsAndSummarizes(t *testing.T) {
+	r := newPreviewTestRedactor(t)
+	input := `{"text":"key tk_abcd1234 here"}` + "\n" + `{"text":"clean"}` + "\n"
+
+	var out bytes.Buffer
+	if err := writeRedactionPreview(&out, strings.NewReader(input), r, false); err != nil {
+		t.Fatalf("writeRedactionPreview: %v", err)
+	}
+
+	got := out.String()
+	if !strings.Contains(got, `1: {"text":"key «tk_abcd1234» here"}`) {
+		t.Errorf("expected highlighted span in output, got:\n%s", got)
+	}
+	if !strings.Contains(got, `2: {"text":"clean"}`) {
+		t.Errorf("expected unmatched line printed as-is, got:\n%s", got)
+	}
+	if !strings.Contains(got, "Test Key: 1") {
+		t.Errorf("expected per-pattern summary, got:\n%s", got)
+	}
+}
+
+func TestWriteRedactionPreviewJSON(t *testing.T) {
+	r := newPreviewTestRedactor(t)
+	input := `{"text":"clean"}` + "\n" + `{"text":"tk_abcd1234"}` + "\n"
+
+	var out bytes.Buffer
+	if err := writeRedactionPreviewJSON(&out, strings.NewReader(input), r); err != nil {
+		t.Fatalf("writeRedactionPreviewJSON: %v", err)
+	}
+
+	var matches []redactionPreviewMatch
+	if err := json.Unmarshal(out.Bytes(), &matches); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, out.String())
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+	m := matches[0]
+	if m.Line != 2 || m.PatternName != "Test Key" {
+		t.Errorf("unexpected match %+v", m)
+	}
+	line := `{"text":"tk_abcd1234"}`
+	if line[m.Start:m.End] != "tk_abcd1234" {
+		t.Errorf("span = %q", line[m.Start:m.End])
+	}
+}
+
+func TestWriteRedactionPreviewJSON_EmptyIsArray(t *testing.T) {
+	r := newPreviewTestRedactor(t)
+	var out bytes.Buffer
+	if err := writeRedactionPreviewJSON(&out, strings.NewReader(`{"a":"b"}`+"\n"), r); err != nil {
+		t.Fatalf("writeRedactionPreviewJSON: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("expected empty JSON array, got %q", out.String())
+	}
+}
+
+func TestPreviewRedactor_InvalidRegexFails(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("CONFAB_CONFIG_PATH", configPath)
+	cfgJSON := `{"redaction":{"enabled":true,"patterns":[{"name":"Broken","pattern":"(unclosed","type":"x"}]}}`
+	if err := os.WriteFile(configPath, []byte(cfgJSON), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := previewRedactor(); err == nil {
+		t.Fatal("expected error for uncompilable pattern")
+	}
+}
+
+func TestRunRedactTest_FieldNames(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("CONFAB_CONFIG_PATH", configPath)
+	cfgJSON := `{"redaction":{"enabled":true,"use_default_patterns":false,"patterns":[{"name":"Passwords","field_names":["password"]}]}}`
+	if err := os.WriteFile(configPath, []byte(cfgJSON), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runRedactTest(&out, `{"password":"secret","db":{"password":"s2"}}`); err != nil {
+		t.Fatalf("runRedactTest: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != `{"db":{"password":"[REDACTED]"},"password":"[REDACTED]"}` {
+		t.Errorf("runRedactTest output = %s", got)
+	}
+}
+
+func TestHighlightSpans_MergesOverlaps(t *testing.T) {
+	line := "abcdefgh"
+	got := highlightSpans(line, []redactor.Match{
+		{PatternName: "a", Start: 1, End: 4},
+		{PatternName: "b", Start: 3, End: 6},
+	}, false)
+	if got != "a«bcdef»gh" {
+		t.Errorf("highlightSpans = %q", got)
+	}
+}
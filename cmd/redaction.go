@@ -27,7 +27,7 @@ Example:
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		filePath := args[0]
-		logger.Info("Running redaction-test command on %s", filePath)
+		logger.Infof("Running redaction-test command on %s", filePath)
 
 		// Load config
 		cfg, err := config.GetUploadConfig()
@@ -36,12 +36,13 @@ Example:
 		}
 
 		// Check if redaction is configured
-		if cfg.Redaction == nil {
+		redaction := cfg.GetEffectiveRedactionConfig()
+		if redaction == nil {
 			return fmt.Errorf("redaction is not configured in ~/.confab/config.json")
 		}
 
 		// Create redactor (works even if disabled, for testing purposes)
-		r, err := redactor.NewFromConfig(cfg.Redaction)
+		r, err := redactor.NewFromConfig(redaction)
 		if err != nil {
 			return fmt.Errorf("failed to create redactor: %w", err)
 		}
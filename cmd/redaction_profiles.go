@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var redactionCmd = &cobra.Command{
+	Use:   "redaction",
+	Short: "Manage redaction profiles",
+	Long: `Manage named redaction profiles.
+
+A profile is a redaction section kept under redaction_profiles in
+~/.confab/config.json (e.g. "hipaa" or "pci"). The active profile
+(active_redaction_profile) is used instead of the top-level redaction
+section. A project's .confab/config.json can set active_redaction_profile
+to pick a profile for sessions in that project.`,
+}
+
+var redactionAddProfileCmd = &cobra.Command{
+	Use:   "add-profile <name>",
+	Short: "Add a redaction profile",
+	Long: `Add a redaction profile to ~/.confab/config.json, starting as a copy of
+the top-level redaction section (or the default patterns, enabled, if there
+is none). Edit its patterns under redaction_profiles.<name> in the config.
+
+Example:
+  confab redaction add-profile hipaa`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRedactionAddProfile(os.Stdout, args[0])
+	},
+}
+
+var redactionActivateCmd = &cobra.Command{
+	Use:   "activate <profile>",
+	Short: "Use a redaction profile for uploads",
+	Long: `Make <profile> the active redaction profile in ~/.confab/config.json.
+Running sync daemons pick it up when they next load the config; a
+project's active_redaction_profile still takes precedence there.
+
+Example:
+  confab redaction activate hipaa`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRedactionActivate(os.Stdout, args[0])
+	},
+}
+
+func init() {
+	redactionCmd.AddCommand(redactionAddProfileCmd, redactionActivateCmd)
+	rootCmd.AddCommand(redactionCmd)
+}
+
+func runRedactionAddProfile(w io.Writer, name string) error {
+	if err := config.AddRedactionProfile(name); err != nil {
+		return err
+	}
+	path, _ := config.UploadConfigPath()
+	fmt.Fprintf(w, "✓ Added redaction profile %s; edit redaction_profiles.%s in %s\n", name, name, path)
+	return nil
+}
+
+func runRedactionActivate(w io.Writer, name string) error {
+	if err := config.ActivateRedactionProfile(name); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "✓ Redaction profile %s is now active\n", name)
+	return nil
+}
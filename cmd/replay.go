@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/ConfabulousDev/confab/pkg/redactor"
+	"github.com/ConfabulousDev/confab/pkg/sync"
+	"github.com/ConfabulousDev/confab/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayProviderName string
+	replaySessionID    string
+	replayConfirm      bool
+	replayDryRun       bool
+	replayFromLine     int
+	replayToLine       int
+	replayFile         string
+)
+
+// replayProgressInterval is how often the progress line is refreshed.
+const replayProgressInterval = time.Second
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <transcript-path>",
+	Short: "Re-upload a session transcript from line 1",
+	Long: `Re-upload a local transcript (and its agent files) to the backend from
+line 1, ignoring how far the backend says it has already synced.
+
+Use this when a backend session was deleted or corrupted. Because it
+re-sends everything, --confirm is required. With --dry-run, prints what
+would be uploaded without sending any requests (no --confirm needed).
+
+With --from-line, re-uploads only lines N..M (--to-line, default EOF) of
+one file: the transcript, or the backend file named by --file. A range
+replay doesn't need --confirm and leaves every other file alone.
+
+The session ID defaults to the transcript file name without its extension
+(the Claude Code layout); pass --session-id for other layouts.
+
+Examples:
+  confab replay --provider claude-code ~/.claude/projects/p/abc123.jsonl --dry-run
+  confab replay --provider claude-code ~/.claude/projects/p/abc123.jsonl --confirm
+  confab replay --provider claude-code ~/.claude/projects/p/abc123.jsonl --from-line 120 --to-line 180`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		transcriptPath, err := filepath.Abs(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid transcript path: %w", err)
+		}
+		if _, err := os.Stat(transcriptPath); err != nil {
+			return fmt.Errorf("cannot read transcript: %w", err)
+		}
+		ranged := replayFromLine > 0
+		if !ranged && (replayToLine > 0 || replayFile != "") {
+			return fmt.Errorf("--to-line and --file require --from-line")
+		}
+		if !ranged && !replayDryRun && !replayConfirm {
+			return fmt.Errorf("replay re-uploads the entire transcript; pass --confirm to proceed (or --dry-run to preview)")
+		}
+
+		sessionID := replaySessionID
+		if sessionID == "" {
+			sessionID = strings.TrimSuffix(filepath.Base(transcriptPath), filepath.Ext(transcriptPath))
+		}
+
+		p, err := provider.Get(replayProviderName)
+		if err != nil {
+			return err
+		}
+		cwd := p.DefaultCWD(transcriptPath)
+
+		if ranged && replayDryRun {
+			cfg, err := config.GetUploadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			return runReplayRange(os.Stdout, cfg, true, p.Name(), sessionID, transcriptPath, cwd, replayFile, replayFromLine, replayToLine)
+		}
+		if replayDryRun {
+			return runReplayDryRun(os.Stdout, p.Name(), sessionID, transcriptPath, cwd)
+		}
+
+		defer NotifyIfUpdateAvailable()
+		cfg, err := config.EnsureAuthenticated()
+		if err != nil {
+			return err
+		}
+		if ranged {
+			return runReplayRange(os.Stdout, cfg, false, p.Name(), sessionID, transcriptPath, cwd, replayFile, replayFromLine, replayToLine)
+		}
+		return runReplay(os.Stdout, cfg, p.Name(), sessionID, transcriptPath, cwd)
+	},
+}
+
+// replayEngineConfig builds the engine config shared by real and dry-run
+// replays: an empty init override so every file syncs from line 1.
+func replayEngineConfig(providerName, sessionID, transcriptPath, cwd string, onChunk func(string, int)) sync.EngineConfig {
+	return sync.EngineConfig{
+		Provider:        providerName,
+		ExternalID:      sessionID,
+		TranscriptPath:  transcriptPath,
+		CWD:             cwd,
+		InitOverride:    &sync.InitResponse{Files: map[string]sync.FileState{}},
+		OnChunkUploaded: onChunk,
+	}
+}
+
+// runReplay re-uploads the session through the sync engine, printing a
+// progress line every replayProgressInterval until SyncAll returns.
+func runReplay(w io.Writer, cfg *config.UploadConfig, providerName, sessionID, transcriptPath, cwd string) error {
+	logger.Infof("Replaying session %s from %s", sessionID, transcriptPath)
+
+	var uploaded atomic.Int64
+	engine, err := sync.New(cfg, replayEngineConfig(providerName, sessionID, transcriptPath, cwd,
+		func(_ string, lines int) { uploaded.Add(int64(lines)) }))
+	if err != nil {
+		return err
+	}
+	if err := engine.Init(); err != nil {
+		return fmt.Errorf("failed to initialize session: %w", err)
+	}
+	if err := setupOpencodeSaveEngine(engine, providerName); err != nil {
+		return err
+	}
+
+	total := countReplayLines(transcriptPath, engine.Tracker().SubagentsDir())
+	fmt.Fprintf(w, "Replaying session %s (%d lines)...\n", utils.TruncateSecret(sessionID, 8, 0), total)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(replayProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fmt.Fprintf(w, "  %d / %d lines uploaded\n", uploaded.Load(), total)
+			}
+		}
+	}()
+
+	// Replays cover whole files, so don't hold back an unterminated last
+	// line the way a live sync cycle would.
+	chunks, err := engine.SyncAllFinal()
+	close(done)
+	<-stopped
+
+	fmt.Fprintf(w, "  %d / %d lines uploaded\n", uploaded.Load(), total)
+	if err != nil {
+		return fmt.Errorf("replay incomplete: %w", err)
+	}
+	fmt.Fprintf(w, "✓ Replayed %d chunks to session %s\n", chunks, engine.SessionID())
+	return nil
+}
+
+// runReplayDryRun drives the same engine path against a backend that records
+// chunks instead of sending them, so output reflects redaction and chunking
+// exactly as a real replay would, without any HTTP requests.
+func runReplayDryRun(w io.Writer, providerName, sessionID, transcriptPath, cwd string) error {
+	cfg, err := config.GetUploadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	r, err := sync.NewRedactor(cfg)
+	if err != nil {
+		return err
+	}
+
+	engineCfg := replayEngineConfig(providerName, sessionID, transcriptPath, cwd, nil)
+	engineCfg.BypassMarker = sync.BypassMarker(cfg)
+	backend := &dryRunBackend{w: w}
+	engine, err := sync.NewWithBackend(backend, r, engineCfg)
+	if err != nil {
+		return err
+	}
+	if err := engine.Init(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Dry run: would replay session %s (nothing will be sent)\n", utils.TruncateSecret(sessionID, 8, 0))
+	if _, err := engine.SyncAllFinal(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Would upload %d lines (%d bytes) in %d chunks\n", backend.lines, backend.bytes, backend.chunks)
+	return nil
+}
+
+// runReplayRange re-uploads one line range of one file via
+// Engine.ReplayRange. Unlike a full replay, Init runs without an override:
+// the backend's real file list is what --file is resolved against. With
+// dryRun, cfg only supplies redaction settings and nothing is sent.
+func runReplayRange(w io.Writer, cfg *config.UploadConfig, dryRun bool, providerName, sessionID, transcriptPath, cwd, fileName string, fromLine, toLine int) error {
+	engineCfg := sync.EngineConfig{
+		Provider:       providerName,
+		ExternalID:     sessionID,
+		TranscriptPath: transcriptPath,
+		CWD:            cwd,
+	}
+
+	var engine *sync.Engine
+	var err error
+	if dryRun {
+		var r *redactor.Redactor
+		if r, err = sync.NewRedactor(cfg); err != nil {
+			return err
+		}
+		engineCfg.BypassMarker = sync.BypassMarker(cfg)
+		engine, err = sync.NewWithBackend(&dryRunBackend{w: w}, r, engineCfg)
+	} else {
+		engine, err = sync.New(cfg, engineCfg)
+	}
+	if err != nil {
+		return err
+	}
+	if err := engine.Init(); err != nil {
+		return fmt.Errorf("failed to initialize session: %w", err)
+	}
+
+	sent, err := engine.ReplayRange(fileName, fromLine, toLine)
+	if err != nil {
+		return fmt.Errorf("replay incomplete after %d lines: %w", sent, err)
+	}
+	if dryRun {
+		fmt.Fprintf(w, "Would re-send %d lines\n", sent)
+		return nil
+	}
+	fmt.Fprintf(w, "✓ Re-sent %d lines to session %s\n", sent, engine.SessionID())
+	return nil
+}
+
+// dryRunBackend is a sync.Backend that accepts every chunk without any
+// network I/O, printing what would have been uploaded.
+type dryRunBackend struct {
+	w      io.Writer
+	chunks int
+	lines  int
+	bytes  int
+}
+
+func (b *dryRunBackend) Init(_, _, _ string, _ *sync.InitMetadata, _ *sync.InitOptions) (*sync.InitResponse, error) {
+	return &sync.InitResponse{SessionID: "dry-run", Files: map[string]sync.FileState{}}, nil
+}
+
+func (b *dryRunBackend) UploadChunk(_, fileName, _ string, firstLine int, lines []string, _ *sync.ChunkMetadata) (int, error) {
+	size := 0
+	for _, l := range lines {
+		size += len(l) + 1
+	}
+	b.chunks++
+	b.lines += len(lines)
+	b.bytes += size
+	lastLine := firstLine + len(lines) - 1
+	fmt.Fprintf(b.w, "  %s: lines %d-%d (%d bytes)\n", fileName, firstLine, lastLine, size)
+	return lastLine, nil
+}
+
+func (b *dryRunBackend) SendEvent(string, string, time.Time, json.RawMessage) error { return nil }
+
+func (b *dryRunBackend) UpdateSessionSummary(string, string) error { return nil }
+
+// Capabilities reports nothing supported, so workflow files are skipped in
+// a dry run just as they would be against a backend without the feature.
+func (b *dryRunBackend) Capabilities() (sync.Capabilities, error) {
+	return sync.Capabilities{}, nil
+}
+
+// countReplayLines counts lines in the transcript plus any .jsonl files under
+// the subagents dir, as the progress denominator. Agent files discovered
+// elsewhere (e.g. Codex descendants) aren't counted, so progress can
+// overshoot; it's a guide, not an invariant.
+func countReplayLines(transcriptPath, subagentsDir string) int64 {
+	n, _ := sync.CountLines(transcriptPath)
+	total := int64(n)
+	filepath.WalkDir(subagentsDir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".jsonl" {
+			n, _ := sync.CountLines(path)
+			total += int64(n)
+		}
+		return nil
+	})
+	return total
+}
+
+func init() {
+	replayCmd.Flags().StringVar(&replayProviderName, "provider", "", "Provider the transcript belongs to (claude-code, codex, cursor, or opencode)")
+	replayCmd.MarkFlagRequired("provider")
+	replayCmd.Flags().StringVar(&replaySessionID, "session-id", "", "Session ID to replay into (default: transcript file name without extension)")
+	replayCmd.Flags().BoolVar(&replayConfirm, "confirm", false, "Confirm re-uploading the whole transcript")
+	replayCmd.Flags().BoolVar(&replayDryRun, "dry-run", false, "Print what would be uploaded without sending anything")
+	replayCmd.Flags().IntVar(&replayFromLine, "from-line", 0, "Re-upload only from this line (1-based) instead of the whole session")
+	replayCmd.Flags().IntVar(&replayToLine, "to-line", 0, "Last line to re-upload with --from-line (default: end of file)")
+	replayCmd.Flags().StringVar(&replayFile, "file", "", "Backend file name to replay with --from-line (default: the transcript)")
+	rootCmd.AddCommand(replayCmd)
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/ConfabulousDev/confab/pkg/sync"
+)
+
+// replayTestBackend reports every file as already fully synced, so only an
+// engine honoring the replay init override uploads anything.
+type replayTestBackend struct {
+	chunks []sync.ChunkRequest
+}
+
+func (b *replayTestBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/api/v1/sync/init":
+		json.NewEncoder(w).Encode(sync.InitResponse{
+			SessionID: "internal-replay",
+			Files:     map[string]sync.FileState{"abc123.jsonl": {LastSyncedLine: 3}},
+		})
+	case "/api/v1/sync/chunk":
+		var req sync.ChunkRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		b.chunks = append(b.chunks, req)
+		json.NewEncoder(w).Encode(sync.ChunkResponse{LastSyncedLine: req.FirstLine + len(req.Lines) - 1})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeReplayTranscript(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "abc123.jsonl")
+	content := `{"type":"system"}` + "\n" + `{"type":"user"}` + "\n" + `{"type":"assistant"}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write transcript: %v", err)
+	}
+	return path
+}
+
+func TestReplay_UploadsFromLineOne(t *testing.T) {
+	backend := &replayTestBackend{}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	transcriptPath := writeReplayTranscript(t)
+	cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "cfb_replay-test-key-12345678"}
+
+	var out bytes.Buffer
+	if err := runReplay(&out, cfg, provider.NameClaudeCode, "abc123", transcriptPath, t.TempDir()); err != nil {
+		t.Fatalf("runReplay: %v", err)
+	}
+
+	if len(backend.chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(backend.chunks))
+	}
+	if got := backend.chunks[0]; got.FirstLine != 1 || len(got.Lines) != 3 {
+		t.Errorf("expected lines 1-3 re-uploaded, got first_line=%d lines=%d", got.FirstLine, len(got.Lines))
+	}
+	if !strings.Contains(out.String(), "3 / 3 lines uploaded") {
+		t.Errorf("expected final progress line, got:\n%s", out.String())
+	}
+}
+
+func TestReplay_DryRunSendsNothing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("dry run must not send requests, got %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+	setupSetupTestEnv(t, server.URL)
+
+	var out bytes.Buffer
+	if err := runReplayDryRun(&out, provider.NameClaudeCode, "abc123", writeReplayTranscript(t), t.TempDir()); err != nil {
+		t.Fatalf("runReplayDryRun: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{"abc123.jsonl: lines 1-3", "Would upload 3 lines"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dry-run output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestReplay_RequiresConfirm(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	transcriptPath := writeReplayTranscript(t)
+
+	rootCmd.SetArgs([]string{"replay", "--provider", "claude-code", transcriptPath})
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "--confirm") {
+		t.Fatalf("expected --confirm error, got %v", err)
+	}
+}
+
+func TestReplay_RangeResendsOnlyRequestedLines(t *testing.T) {
+	backend := &replayTestBackend{}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	transcriptPath := writeReplayTranscript(t)
+	cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "cfb_replay-test-key-12345678"}
+
+	var out bytes.Buffer
+	if err := runReplayRange(&out, cfg, false, provider.NameClaudeCode, "abc123", transcriptPath, t.TempDir(), "", 2, 2); err != nil {
+		t.Fatalf("runReplayRange: %v", err)
+	}
+	if len(backend.chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(backend.chunks))
+	}
+	if got := backend.chunks[0]; got.FirstLine != 2 || len(got.Lines) != 1 || got.Lines[0] != `{"type":"user"}` {
+		t.Errorf("expected only line 2 re-sent, got first_line=%d lines=%v", got.FirstLine, got.Lines)
+	}
+	if !strings.Contains(out.String(), "Re-sent 1 lines") {
+		t.Errorf("expected re-sent count, got:\n%s", out.String())
+	}
+
+	err := runReplayRange(&out, cfg, false, provider.NameClaudeCode, "abc123", transcriptPath, t.TempDir(), "", 4, 0)
+	if err == nil || !strings.Contains(err.Error(), "beyond end") {
+		t.Errorf("expected beyond-EOF refusal, got %v", err)
+	}
+	if len(backend.chunks) != 1 {
+		t.Errorf("refused replay must not upload, got %d chunks", len(backend.chunks))
+	}
+}
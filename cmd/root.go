@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/ConfabulousDev/confab/pkg/logger"
 	"github.com/ConfabulousDev/confab/pkg/loginit"
 	"github.com/spf13/cobra"
@@ -16,10 +17,17 @@ var rootCmd = &cobra.Command{
 Claude Code and Codex, and uploads them to the backend for retrieval, search,
 and analytics.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Before anything reads the config. Set in the environment so
+		// daemons this command spawns use the same profile.
+		if profileName != "" {
+			os.Setenv(config.ProfileEnv, profileName)
+		}
 		// Initialize logger for all commands (except --help which doesn't run this)
 		logger.Init()
 		// Apply log level from config
 		loginit.ApplyLogLevel()
+		loginit.ApplyLogRotation()
+		loginit.ApplyLogFormat(logFormat)
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		// Close logger after all commands
@@ -27,6 +35,20 @@ and analytics.`,
 	},
 }
 
+// profileName is the persistent --profile flag: the config.json profile to
+// use. Empty defers to CONFAB_PROFILE, then the file's default_profile.
+var profileName string
+
+// logFormat is the persistent --log-format flag ("text" or "json"); empty
+// defers to LOG_FORMAT.
+var logFormat string
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Config profile to use (default: $CONFAB_PROFILE, else the config's default_profile)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", `Log line format: "text" or "json" (default: $LOG_FORMAT, else text)`)
+	cobra.OnInitialize(registerFlagCompletions)
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
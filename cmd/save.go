@@ -144,7 +144,9 @@ func uploadSingleSession(cfg *config.UploadConfig, providerName, sessionID, tran
 
 	result.InternalID = engine.SessionID()
 
-	chunks, err := engine.SyncAll()
+	// A saved session is finished: upload its last line even if it was
+	// never newline-terminated.
+	chunks, err := engine.SyncAllFinal()
 	if err != nil {
 		result.Error = err
 		return result
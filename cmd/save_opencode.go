@@ -58,7 +58,7 @@ func (r *opencodeOfflineRegistrar) RegisterOpencodeChild(childID, localPath stri
 	ctx, cancel := context.WithTimeout(context.Background(), opencodeSaveMaterializeTimeout)
 	defer cancel()
 	if _, err := provider.MaterializeOpenCodeSession(ctx, r.source, childID, localPath, 0); err != nil {
-		logger.Warn("opencode save: materialize child %s failed: %v", childID, err)
+		logger.Warnf("opencode save: materialize child %s failed: %v", childID, err)
 		return
 	}
 	name := provider.OpencodeChildBackendName(childID)
@@ -1,4 +1,4 @@
-// ABOUTME: Parent command for session-related subcommands (get-summary, download, list-files).
+// ABOUTME: Parent command for session-related subcommands (get-summary, download, list-files, list, show, delete).
 // ABOUTME: Groups commands for querying and retrieving session data from the backend.
 package cmd
 
@@ -13,9 +13,10 @@ var (
 )
 
 var sessionCmd = &cobra.Command{
-	Use:   "session",
-	Short: "Query and retrieve sessions",
-	Long:  `Commands for querying and retrieving session data from the backend. Works for sessions captured from any supported provider.`,
+	Use:     "session",
+	Aliases: []string{"sessions"},
+	Short:   "Query and retrieve sessions",
+	Long:    `Commands for querying and retrieving session data from the backend. Works for sessions captured from any supported provider.`,
 }
 
 func init() {
@@ -0,0 +1,183 @@
+// ABOUTME: CLI commands to list, show and delete the user's sessions on the backend.
+// ABOUTME: `confab sessions list|show|delete` (sessions is an alias of session).
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	pkgsync "github.com/ConfabulousDev/confab/pkg/sync"
+	"github.com/ConfabulousDev/confab/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sessionsListLimit     int
+	sessionsListFormat    string
+	sessionsDeleteConfirm bool
+)
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your sessions on the backend",
+	Long: `List the sessions synced to the backend, newest first.
+
+Prints the session ID, when it was created and last synced, its line
+count, git branch and summary. --format json prints the sessions as a
+JSON array instead.
+
+Examples:
+  confab sessions list
+  confab sessions list --limit 50 --format json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defer NotifyIfUpdateAvailable()
+		if sessionsListLimit <= 0 {
+			return fmt.Errorf("--limit must be positive")
+		}
+		if sessionsListFormat != "table" && sessionsListFormat != "json" {
+			return fmt.Errorf("unknown --format %q (want table or json)", sessionsListFormat)
+		}
+		client, err := syncClientForFlags(sessionProviderName, sessionConfigDir)
+		if err != nil {
+			return err
+		}
+		return runSessionsList(os.Stdout, client, sessionsListLimit, sessionsListFormat)
+	},
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <session-id>",
+	Short: "Show a session's metadata",
+	Long: `Print the full metadata the backend holds for a session, as indented JSON.
+
+Examples:
+  confab sessions show abc123-uuid-here`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defer NotifyIfUpdateAvailable()
+		client, err := syncClientForFlags(sessionProviderName, sessionConfigDir)
+		if err != nil {
+			return err
+		}
+		return runSessionsShow(os.Stdout, client, args[0])
+	},
+}
+
+var sessionsDeleteCmd = &cobra.Command{
+	Use:   "delete <session-id>",
+	Short: "Delete a session from the backend",
+	Long: `Delete a session and its synced files from the backend. Local
+transcripts are not touched. Nothing is deleted without --confirm.
+
+Examples:
+  confab sessions delete abc123-uuid-here --confirm`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !sessionsDeleteConfirm {
+			return fmt.Errorf("nothing deleted: re-run with --confirm to delete session %s", args[0])
+		}
+		client, err := syncClientForFlags(sessionProviderName, sessionConfigDir)
+		if err != nil {
+			return err
+		}
+		return runSessionsDelete(os.Stdout, client, args[0])
+	},
+}
+
+func init() {
+	sessionsListCmd.Flags().IntVar(&sessionsListLimit, "limit", 20, "Maximum number of sessions to list")
+	sessionsListCmd.Flags().StringVar(&sessionsListFormat, "format", "table", "Output format: table or json")
+	sessionsDeleteCmd.Flags().BoolVar(&sessionsDeleteConfirm, "confirm", false, "Confirm deleting the session")
+	sessionCmd.AddCommand(sessionsListCmd, sessionsShowCmd, sessionsDeleteCmd)
+}
+
+// runSessionsList fetches up to limit sessions, following the backend's
+// pagination cursor, and prints them in format ("table" or "json").
+func runSessionsList(w io.Writer, client *pkgsync.Client, limit int, format string) error {
+	sessions := []pkgsync.SessionListItem{}
+	cursor := ""
+	for len(sessions) < limit {
+		page, err := client.ListSessions(limit-len(sessions), cursor)
+		if err != nil {
+			return translateSessionErr(err, "list sessions")
+		}
+		sessions = append(sessions, page.Sessions...)
+		if page.NextCursor == "" || len(page.Sessions) == 0 {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(sessions) > limit {
+		sessions = sessions[:limit]
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sessions)
+	}
+
+	if len(sessions) == 0 {
+		fmt.Fprintln(w, "No sessions found.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SESSION_ID\tCREATED\tLAST_SYNCED\tLINES\tBRANCH\tSUMMARY")
+	for _, s := range sessions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			s.ID,
+			formatSessionTime(s.CreatedAt),
+			formatSessionTime(s.LastSyncAt),
+			s.LineCount,
+			orDash(s.GitBranch),
+			orDash(utils.TruncateEnd(s.Summary, 50)),
+		)
+	}
+	return tw.Flush()
+}
+
+// runSessionsShow prints a session's metadata document as indented JSON.
+func runSessionsShow(w io.Writer, client *pkgsync.Client, id string) error {
+	data, err := client.GetSession(id)
+	if err != nil {
+		return translateSessionErr(err, "get session")
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return fmt.Errorf("failed to format session metadata: %w", err)
+	}
+	fmt.Fprintln(w, buf.String())
+	return nil
+}
+
+// runSessionsDelete deletes a session from the backend.
+func runSessionsDelete(w io.Writer, client *pkgsync.Client, id string) error {
+	if err := client.DeleteSession(id); err != nil {
+		return translateSessionErr(err, "delete session")
+	}
+	fmt.Fprintf(w, "Deleted session %s\n", id)
+	return nil
+}
+
+// formatSessionTime formats a backend timestamp for the sessions table, or
+// "-" when the backend didn't send one.
+func formatSessionTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("Jan 02 15:04")
+}
+
+// orDash returns s, or "-" when it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
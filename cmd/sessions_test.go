@@ -0,0 +1,148 @@
+// ABOUTME: Tests for the confab sessions list/show/delete commands.
+// ABOUTME: Drives them against an httptest backend serving the sessions API.
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	pkgsync "github.com/ConfabulousDev/confab/pkg/sync"
+)
+
+// sessionsBackend serves GET /api/v1/sessions two sessions per page, and
+// GET/DELETE /api/v1/sessions/{id}.
+func sessionsBackend(t *testing.T, sessions []pkgsync.SessionListItem, deleted *[]string) *pkgsync.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/sessions" {
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+			end := min(start+min(limit, 2), len(sessions))
+			resp := pkgsync.ListSessionsResponse{Sessions: sessions[start:end]}
+			if end < len(sessions) {
+				resp.NextCursor = strconv.Itoa(end)
+			}
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/sessions/")
+		for _, s := range sessions {
+			if s.ID != id {
+				continue
+			}
+			if r.Method == http.MethodDelete {
+				*deleted = append(*deleted, id)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			json.NewEncoder(w).Encode(s)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := pkgsync.NewClient(&config.UploadConfig{BackendURL: server.URL, APIKey: "test-key"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+func testRemoteSessions() []pkgsync.SessionListItem {
+	at := time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)
+	return []pkgsync.SessionListItem{
+		{ID: "s1", CreatedAt: at, LastSyncAt: at.Add(time.Hour), LineCount: 120, GitBranch: "main", Summary: "Fix the flaky test"},
+		{ID: "s2", CreatedAt: at, LineCount: 7},
+		{ID: "s3", CreatedAt: at, LineCount: 3, GitBranch: "feature"},
+	}
+}
+
+func TestRunSessionsList_TableFollowsCursor(t *testing.T) {
+	client := sessionsBackend(t, testRemoteSessions(), nil)
+
+	var out bytes.Buffer
+	if err := runSessionsList(&out, client, 20, "table"); err != nil {
+		t.Fatalf("runSessionsList: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want header + 3 sessions:\n%s", len(lines), out.String())
+	}
+	if !strings.HasPrefix(lines[0], "SESSION_ID") {
+		t.Errorf("header = %q", lines[0])
+	}
+	for _, want := range []string{"s1", "120", "main", "Fix the flaky test"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("row %q missing %q", lines[1], want)
+		}
+	}
+	if fields := strings.Fields(lines[2]); fields[len(fields)-1] != "-" || fields[len(fields)-2] != "-" {
+		t.Errorf("s2 row %q: want - for the missing branch and summary", lines[2])
+	}
+}
+
+func TestRunSessionsList_LimitAndJSON(t *testing.T) {
+	client := sessionsBackend(t, testRemoteSessions(), nil)
+
+	var out bytes.Buffer
+	if err := runSessionsList(&out, client, 1, "json"); err != nil {
+		t.Fatalf("runSessionsList: %v", err)
+	}
+	var got []pkgsync.SessionListItem
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, out.String())
+	}
+	if len(got) != 1 || got[0].ID != "s1" {
+		t.Errorf("got %+v, want only s1", got)
+	}
+}
+
+func TestRunSessionsShow(t *testing.T) {
+	client := sessionsBackend(t, testRemoteSessions(), nil)
+
+	var out bytes.Buffer
+	if err := runSessionsShow(&out, client, "s1"); err != nil {
+		t.Fatalf("runSessionsShow: %v", err)
+	}
+	if !strings.Contains(out.String(), "\n  \"git_branch\": \"main\"") {
+		t.Errorf("metadata not pretty-printed:\n%s", out.String())
+	}
+
+	err := runSessionsShow(&out, client, "missing")
+	if err == nil || err.Error() != "session not found" {
+		t.Errorf("runSessionsShow(missing) = %v, want session not found", err)
+	}
+}
+
+func TestRunSessionsDelete(t *testing.T) {
+	var deleted []string
+	client := sessionsBackend(t, testRemoteSessions(), &deleted)
+
+	var out bytes.Buffer
+	if err := runSessionsDelete(&out, client, "s3"); err != nil {
+		t.Fatalf("runSessionsDelete: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "s3" {
+		t.Errorf("deleted = %v, want [s3]", deleted)
+	}
+	if !strings.Contains(out.String(), "Deleted session s3") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestSessionsDelete_RequiresConfirm(t *testing.T) {
+	sessionsDeleteConfirm = false
+	err := sessionsDeleteCmd.RunE(sessionsDeleteCmd, []string{"s1"})
+	if err == nil || !strings.Contains(err.Error(), "--confirm") {
+		t.Errorf("delete without --confirm = %v, want an error asking for it", err)
+	}
+}
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
@@ -12,10 +15,47 @@ import (
 )
 
 var (
-	setupProviderName string
-	setupConfigDir    string
+	setupProviderName  string
+	setupConfigDir     string
+	setupScope         string
+	setupProxyURL      string
+	setupCACertFile    string
+	setupTLSSkipVerify bool
+	setupUseKeyring    bool
+	setupJSON          bool
+	setupDryRun        bool
+	setupKeyName       string
 )
 
+// setupResult is what `setup --json` prints: what setup did, for
+// provisioning scripts to check.
+type setupResult struct {
+	OK         bool   `json:"ok"`
+	BackendURL string `json:"backend_url"`
+	ConfigPath string `json:"config_path"`
+	// LoggedIn is true when this run saved new credentials (device login
+	// or --api-key), false when existing ones were still valid.
+	LoggedIn bool `json:"logged_in"`
+	// Auth is the login step's outcome: "logged_in" (new credentials
+	// saved), "existing" (saved credentials still valid) or "failed";
+	// empty when setup stopped before it.
+	Auth      string                `json:"auth,omitempty"`
+	Providers []setupProviderResult `json:"providers"`
+	Error     string                `json:"error,omitempty"`
+}
+
+// setupProviderResult is one provider's outcome. Hooks is "installed"
+// (written, fresh or upgraded), "unchanged" (already installed) or
+// "failed". Skills is "installed" or "failed", and empty when the hooks
+// step failed first.
+type setupProviderResult struct {
+	Provider     string `json:"provider"`
+	Hooks        string `json:"hooks"`
+	Skills       string `json:"skills,omitempty"`
+	SettingsPath string `json:"settings_path,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
 var setupCmd = &cobra.Command{
 	Use:   "setup",
 	Short: "Set up confab (login + install hooks)",
@@ -33,39 +73,142 @@ installs are covered too).
 
 If you're already authenticated with a valid API key, the login step is
 skipped. Use --api-key to provide an API key directly (bypasses device
-auth flow).`,
+auth flow).
+
+Use --proxy to route all backend traffic through a proxy (http://,
+https://, socks5:// or socks5h://); it is saved as proxy_url in the config.
+Without it, the standard HTTP_PROXY/HTTPS_PROXY/NO_PROXY variables apply.
+
+For backends with self-signed or internal-CA certificates, use --ca-cert
+to trust a PEM bundle (saved as ca_cert_file). --tls-skip-verify disables
+certificate verification entirely and is meant for development only.
+
+Use --scope project (claude-code only) to install the hooks in the
+current directory's .claude/settings.local.json instead of
+~/.claude/settings.json, so only that project's sessions sync.
+
+Device login labels the new API key with --name (default:
+<hostname>-<username>), as 'confab login' does.
+
+Use --use-keyring to keep API keys in the OS keychain (macOS Keychain,
+Linux Secret Service) instead of config.json. If the keychain is
+unavailable, keys stay in config.json.
+
+With --json, progress (including the login prompt) goes to stderr and
+stdout gets a single JSON object describing the outcome: ok, error,
+backend_url, config_path, logged_in, the auth step ("logged_in",
+"existing" or "failed"), and per provider the hooks status ("installed",
+"unchanged" or "failed"), the skills status ("installed" or "failed")
+and the settings file written. It is printed however setup ends,
+alongside the non-zero exit on failure.
+
+With --dry-run, nothing is written: the API key (--api-key, or the saved
+one) is still validated against the backend, and for each provider setup
+prints what it would install — for Claude Code a diff of settings.json,
+"+" lines added and "-" lines removed.`,
 	RunE: runSetup,
 }
 
+// setupKeyNameOrDefault is --name, or defaultKeyName when it is unset.
+func setupKeyNameOrDefault() string {
+	if setupKeyName != "" {
+		return setupKeyName
+	}
+	return defaultKeyName()
+}
+
 func runSetup(cmd *cobra.Command, args []string) error {
-	logger.Info("Starting setup (provider=%q config-dir=%q)", setupProviderName, setupConfigDir)
+	logger.Info("Starting setup", "provider", setupProviderName, "config_dir", setupConfigDir)
+
+	out := io.Writer(os.Stdout)
+	if setupJSON {
+		// Progress, the login prompt included, goes to stderr so stdout
+		// carries only the result, which is printed however setup ends.
+		out = os.Stderr
+	}
+	res := &setupResult{Providers: []setupProviderResult{}}
+	err := runSetupSteps(cmd, out, res)
+	if setupJSON {
+		if jsonErr := writeSetupResultJSON(os.Stdout, res, err); err == nil {
+			err = jsonErr
+		}
+	}
+	return err
+}
+
+// runSetupSteps validates the flags, logs in and installs for each
+// provider, printing progress to out and recording each step in res.
+func runSetupSteps(cmd *cobra.Command, out io.Writer, res *setupResult) error {
+	res.BackendURL, _ = cmd.Flags().GetString("backend-url")
+	if path, err := config.UploadConfigPath(); err == nil {
+		res.ConfigPath = path
+	}
 
 	if setupConfigDir != "" && setupProviderName == "" {
 		return fmt.Errorf("--config-dir requires --provider (a config dir is provider-specific)")
 	}
+	if setupScope != "" {
+		scope, err := config.ParseSettingsScope(setupScope)
+		if err != nil {
+			return err
+		}
+		if scope == config.SettingsScopeProject && setupProviderName == "" {
+			return fmt.Errorf("--scope project requires --provider %s", provider.NameClaudeCode)
+		}
+		if scope == config.SettingsScopeProject && setupConfigDir != "" {
+			return fmt.Errorf("--scope project installs into the project's .claude/settings.local.json and can't be combined with --config-dir")
+		}
+	}
 
-	binding, err := resolveSetupBinding()
+	if setupDryRun {
+		if setupJSON {
+			return fmt.Errorf("--dry-run can't be combined with --json")
+		}
+		return runSetupDryRun(cmd, out)
+	}
+
+	binding, err := resolveSetupBinding(true)
 	if err != nil {
 		return err
 	}
 
-	backendURL, needsLogin, err := runSetupAuth(cmd, binding)
+	res.Auth = "failed"
+	backendURL, needsLogin, err := runSetupAuth(cmd, out, binding)
 	if err != nil {
 		return err
 	}
 
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	res.LoggedIn = needsLogin || apiKey != ""
+	res.Auth = "existing"
+	if res.LoggedIn {
+		res.Auth = "logged_in"
+	}
 	if setupProviderName != "" {
-		return runSetupSingle(backendURL, needsLogin)
+		return runSetupSingle(out, backendURL, needsLogin, res)
 	}
-	return runSetupAutoDetect(backendURL, needsLogin)
+	return runSetupAutoDetect(out, backendURL, needsLogin, res)
+}
+
+// writeSetupResultJSON prints res, with runErr (setup's outcome) folded
+// into ok/error.
+func writeSetupResultJSON(w io.Writer, res *setupResult, runErr error) error {
+	res.OK = runErr == nil
+	if runErr != nil {
+		res.Error = runErr.Error()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
 }
 
 // resolveSetupBinding builds the credential-write target for this setup run:
 // the default (top-level) binding unless --config-dir names a non-default dir.
 // It validates that the provider supports a custom config dir (claude-code
 // only for now) via GetWithDir, but resolves the binding against the DEFAULT
-// provider so BindingFor sees the true default dir.
-func resolveSetupBinding() (config.Binding, error) {
+// provider so BindingFor sees the true default dir. create is false for
+// --dry-run, which must not create the config dir.
+func resolveSetupBinding(create bool) (config.Binding, error) {
 	if setupConfigDir == "" {
 		return config.Binding{IsDefault: true}, nil
 	}
@@ -81,8 +224,10 @@ func resolveSetupBinding() (config.Binding, error) {
 	// installation would create it anyway; doing it here guarantees
 	// CanonicalDir(setupConfigDir) == CanonicalDir(runtime-derived dir) even
 	// when an ancestor is a symlink (kata hpec).
-	if err := os.MkdirAll(setupConfigDir, 0o755); err != nil {
-		return config.Binding{}, fmt.Errorf("failed to create config dir %q: %w", setupConfigDir, err)
+	if create {
+		if err := os.MkdirAll(setupConfigDir, 0o755); err != nil {
+			return config.Binding{}, fmt.Errorf("failed to create config dir %q: %w", setupConfigDir, err)
+		}
 	}
 	defaultP, err := provider.Get(name)
 	if err != nil {
@@ -92,8 +237,8 @@ func resolveSetupBinding() (config.Binding, error) {
 }
 
 // runSetupSingle installs hooks/skills for exactly the provider named
-// in --provider.
-func runSetupSingle(backendURL string, needsLogin bool) error {
+// in --provider, recording the outcome in res.
+func runSetupSingle(out io.Writer, backendURL string, needsLogin bool, res *setupResult) error {
 	providerName, err := provider.NormalizeName(setupProviderName)
 	if err != nil {
 		return err
@@ -104,18 +249,25 @@ func runSetupSingle(backendURL string, needsLogin bool) error {
 	if err != nil {
 		return err
 	}
+	scoped, err := withSettingsScope([]provider.Provider{p}, setupScope)
+	if err != nil {
+		return err
+	}
+	p = scoped[0]
 
 	if needsLogin {
-		fmt.Println("Step 2/2: Installing hooks")
+		fmt.Fprintln(out, "Step 2/2: Installing hooks")
 	}
-	fmt.Println()
+	fmt.Fprintln(out)
 
-	if err := installForProvider(p); err != nil {
-		return fmt.Errorf("failed to install %s hooks: %w", p.Name(), err)
+	pr, err := installForProvider(out, p)
+	res.Providers = append(res.Providers, pr)
+	if err != nil {
+		return err
 	}
 
-	fmt.Println()
-	fmt.Printf("✅ Setup complete. %s sessions will sync to %s\n", p.Name(), backendURL)
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "✅ Setup complete. %s sessions will sync to %s\n", p.Name(), backendURL)
 	return nil
 }
 
@@ -123,89 +275,264 @@ func runSetupSingle(backendURL string, needsLogin bool) error {
 // the full setup (hooks + skills) for each. If none are detected, auth
 // stays in place, a terse warning prints, and the process exits 0. On
 // per-provider failure mid-loop, every detected provider is still
-// attempted and the process exits non-zero if any failed.
-func runSetupAutoDetect(backendURL string, needsLogin bool) error {
+// attempted and the process exits non-zero if any failed. Each detected
+// provider's outcome is recorded in res.
+func runSetupAutoDetect(out io.Writer, backendURL string, needsLogin bool, res *setupResult) error {
 	detected := provider.DetectInstalled()
 	if len(detected) == 0 {
-		fmt.Println("Detected providers: (none)")
-		fmt.Println()
-		fmt.Println("⚠️  No supported providers (claude, codex, opencode, cursor) found on PATH or via their config dirs.")
-		fmt.Println("   Auth saved, but no hooks were installed.")
+		fmt.Fprintln(out, "Detected providers: (none)")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "⚠️  No supported providers (claude, codex, opencode, cursor) found on PATH or via their config dirs.")
+		fmt.Fprintln(out, "   Auth saved, but no hooks were installed.")
 		return nil
 	}
 
-	fmt.Printf("Detected providers: %s\n", strings.Join(detected, ", "))
-	fmt.Println()
+	fmt.Fprintf(out, "Detected providers: %s\n", strings.Join(detected, ", "))
+	fmt.Fprintln(out)
 
 	if needsLogin {
-		fmt.Println("Step 2/2: Installing hooks")
-		fmt.Println()
+		fmt.Fprintln(out, "Step 2/2: Installing hooks")
+		fmt.Fprintln(out)
 	}
 
 	results := make(map[string]error, len(detected))
 	for _, name := range detected {
 		p, err := provider.Get(name)
+		if err == nil {
+			p, err = provider.WithSettingsScope(p, config.SettingsScope(setupScope))
+		}
 		if err != nil {
 			results[name] = err
-			logger.Error("auto-detect: %v", err)
+			res.Providers = append(res.Providers, setupProviderResult{Provider: name, Hooks: "failed", Error: err.Error()})
+			logger.Errorf("auto-detect: %v", err)
 			continue
 		}
-		results[name] = installForProvider(p)
+		pr, err := installForProvider(out, p)
+		results[name] = err
+		res.Providers = append(res.Providers, pr)
 	}
 
 	var failed int
-	fmt.Println()
-	fmt.Println("Summary:")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Summary:")
 	for _, name := range detected {
 		if err := results[name]; err != nil {
 			failed++
-			fmt.Printf("  %s: failed (%v)\n", name, err)
+			fmt.Fprintf(out, "  %s: %v\n", name, err)
 		} else {
-			fmt.Printf("  %s: installed\n", name)
+			fmt.Fprintf(out, "  %s: installed\n", name)
 		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(out)
 	if failed == 0 {
-		fmt.Printf("✅ Setup complete. %s sessions will sync to %s\n",
+		fmt.Fprintf(out, "✅ Setup complete. %s sessions will sync to %s\n",
 			strings.Join(detected, ", "), backendURL)
 		return nil
 	}
-	fmt.Printf("❌ Setup complete with errors. %d of %d providers failed (see above).\n",
+	fmt.Fprintf(out, "❌ Setup complete with errors. %d of %d providers failed (see above).\n",
 		failed, len(detected))
 	return fmt.Errorf("%d of %d providers failed to install", failed, len(detected))
 }
 
-// installForProvider prints the per-provider sub-header, then installs
-// hooks (skipping if already present) and skills. Returns the first
-// failure encountered.
-func installForProvider(p provider.Provider) error {
-	fmt.Printf("▶ %s\n", p.Name())
+// installForProvider prints the per-provider sub-header to out, then
+// installs hooks (skipping if already present) and skills. Returns the
+// outcome for `setup --json` and the first failure encountered, naming
+// the step that failed.
+func installForProvider(out io.Writer, p provider.Provider) (setupProviderResult, error) {
+	fmt.Fprintf(out, "▶ %s\n", p.Name())
+	res := setupProviderResult{Provider: p.Name(), Hooks: "failed"}
+	fail := func(step string, err error) (setupProviderResult, error) {
+		res.Error = err.Error()
+		return res, fmt.Errorf("failed to install %s %s: %w", p.Name(), step, err)
+	}
 
 	already, err := p.IsHooksInstalled()
 	if err != nil {
-		fmt.Printf("  ✗ failed to check hook status: %v\n", err)
-		return err
+		fmt.Fprintf(out, "  ✗ failed to check hook status: %v\n", err)
+		return fail("hooks", err)
 	}
 	if already {
-		fmt.Println("  ✓ hooks already installed (no changes)")
+		fmt.Fprintln(out, "  ✓ hooks already installed (no changes)")
+		res.Hooks = "unchanged"
 	} else {
-		if _, err := p.InstallHooks(); err != nil {
-			fmt.Printf("  ✗ failed: %v\n", err)
-			return err
+		path, err := p.InstallHooks()
+		if err != nil {
+			fmt.Fprintf(out, "  ✗ failed: %v\n", err)
+			return fail("hooks", err)
 		}
-		fmt.Println("  ✓ hooks installed")
+		fmt.Fprintln(out, "  ✓ hooks installed")
+		res.Hooks, res.SettingsPath = "installed", path
 	}
 
+	res.Skills = "failed"
 	if err := p.InstallSkills(); err != nil {
-		fmt.Printf("  ✗ skills install failed: %v\n", err)
+		fmt.Fprintf(out, "  ✗ skills install failed: %v\n", err)
+		return fail("skills", err)
+	}
+	res.Skills = "installed"
+
+	return res, nil
+}
+
+// runSetupDryRun reports what setup would do without writing config.json
+// or any provider settings. Credentials are still checked against the
+// backend, so a bad --api-key fails here as it would for real.
+func runSetupDryRun(cmd *cobra.Command, out io.Writer) error {
+	backendURL, err := cmd.Flags().GetString("backend-url")
+	if err != nil {
+		return fmt.Errorf("failed to get backend-url flag: %w", err)
+	}
+	apiKey, err := cmd.Flags().GetString("api-key")
+	if err != nil {
+		return fmt.Errorf("failed to get api-key flag: %w", err)
+	}
+
+	fmt.Fprintln(out, "Dry run: no files will be changed.")
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Backend URL: %s\n", backendURL)
+	if setupProxyURL != "" {
+		if _, err := config.ParseProxyURL(setupProxyURL); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "Would save proxy_url")
+	}
+	if setupCACertFile != "" || setupTLSSkipVerify {
+		fmt.Fprintln(out, "Would save TLS settings (ca_cert_file / tls_skip_verify)")
+	}
+	if setupUseKeyring {
+		fmt.Fprintln(out, "Would save use_keyring")
+	}
+	fmt.Fprintln(out)
+
+	binding, err := resolveSetupBinding(false)
+	if err != nil {
+		return err
+	}
+	if err := dryRunCheckAuth(out, backendURL, apiKey, binding); err != nil {
 		return err
 	}
+	fmt.Fprintln(out)
 
+	targets, err := dryRunTargets()
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(out, "No supported providers detected; no hooks would be installed.")
+		return nil
+	}
+	for _, p := range targets {
+		if err := previewForProvider(out, p); err != nil {
+			return fmt.Errorf("failed to preview %s hooks: %w", p.Name(), err)
+		}
+	}
+	return nil
+}
+
+// dryRunCheckAuth validates the credentials setup would use: --api-key if
+// given, else the binding's saved key for this backend. Only a rejected --api-key is
+// an error; missing or stale saved credentials mean setup would log in.
+func dryRunCheckAuth(out io.Writer, backendURL, apiKey string, binding config.Binding) error {
+	fromFlag := apiKey != ""
+	if !fromFlag {
+		cfg, err := config.GetUploadConfigFor(binding)
+		if err != nil || cfg.APIKey == "" || cfg.BackendURL != backendURL {
+			fmt.Fprintln(out, "Would log in (device flow)")
+			return nil
+		}
+		apiKey = cfg.APIKey
+	}
+	cfg := withConnectionSettings(&config.UploadConfig{BackendURL: backendURL, APIKey: apiKey})
+	if setupProxyURL != "" {
+		cfg.ProxyURL = setupProxyURL
+	}
+	if setupCACertFile != "" {
+		cfg.CACertFile = setupCACertFile
+	}
+	if setupTLSSkipVerify {
+		cfg.TLSSkipVerify = true
+	}
+
+	fmt.Fprintln(out, "Validating API key...")
+	err := verifyAPIKey(cfg)
+	switch {
+	case err == nil:
+		fmt.Fprintln(out, "✓ API key is valid")
+	case fromFlag:
+		return fmt.Errorf("invalid API key: %w", err)
+	default:
+		fmt.Fprintln(out, "❌ Existing credentials invalid; would log in (device flow)")
+	}
+	return nil
+}
+
+// dryRunTargets resolves the providers setup would configure, the same
+// way runSetupSingle and runSetupAutoDetect do.
+func dryRunTargets() ([]provider.Provider, error) {
+	if setupProviderName != "" {
+		name, err := provider.NormalizeName(setupProviderName)
+		if err != nil {
+			return nil, err
+		}
+		p, err := provider.GetWithDir(name, setupConfigDir)
+		if err != nil {
+			return nil, err
+		}
+		return withSettingsScope([]provider.Provider{p}, setupScope)
+	}
+	targets, err := providersByName(provider.DetectInstalled())
+	if err != nil {
+		return nil, err
+	}
+	return withSettingsScope(targets, setupScope)
+}
+
+// hookPreviewer is implemented by providers whose hooks live in a Claude
+// settings file, so an install can be shown as a diff.
+type hookPreviewer interface {
+	PreviewHooks() (settingsPath string, before, after *config.ClaudeSettings, err error)
+}
+
+// previewForProvider prints to out what installForProvider would change
+// for p.
+func previewForProvider(out io.Writer, p provider.Provider) error {
+	fmt.Fprintf(out, "▶ %s\n", p.Name())
+	if pv, ok := p.(hookPreviewer); ok {
+		path, before, after, err := pv.PreviewHooks()
+		if err != nil {
+			return err
+		}
+		diff := config.PrettyDiff(before, after)
+		if diff == "" {
+			fmt.Fprintln(out, "  ✓ hooks already installed (no changes)")
+			return nil
+		}
+		fmt.Fprintf(out, "  Would update %s:\n", path)
+		printIndentedDiff(out, diff)
+		return nil
+	}
+	already, err := p.IsHooksInstalled()
+	if err != nil {
+		return err
+	}
+	if already {
+		fmt.Fprintln(out, "  ✓ hooks already installed (no changes)")
+	} else {
+		fmt.Fprintln(out, "  Would install hooks")
+	}
 	return nil
 }
 
-func runSetupAuth(cmd *cobra.Command, binding config.Binding) (backendURL string, needsLogin bool, err error) {
+// printIndentedDiff prints a config.PrettyDiff result to out under a
+// provider header.
+func printIndentedDiff(out io.Writer, diff string) {
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		fmt.Fprintf(out, "    %s\n", line)
+	}
+}
+
+func runSetupAuth(cmd *cobra.Command, out io.Writer, binding config.Binding) (backendURL string, needsLogin bool, err error) {
 	backendURL, err = cmd.Flags().GetString("backend-url")
 	if err != nil {
 		return "", false, fmt.Errorf("failed to get backend-url flag: %w", err)
@@ -215,15 +542,49 @@ func runSetupAuth(cmd *cobra.Command, binding config.Binding) (backendURL string
 		return "", false, fmt.Errorf("failed to get api-key flag: %w", err)
 	}
 
-	fmt.Printf("Backend URL: %s\n", backendURL)
-	fmt.Println()
+	fmt.Fprintf(out, "Backend URL: %s\n", backendURL)
+	if setupProxyURL != "" {
+		// Saved before authenticating so login requests use it too.
+		if err := config.SetProxyURL(setupProxyURL); err != nil {
+			return "", false, err
+		}
+		if u, err := config.ParseProxyURL(setupProxyURL); err == nil {
+			fmt.Fprintf(out, "Proxy: %s\n", u.Redacted())
+		}
+	}
+	if setupCACertFile != "" || setupTLSSkipVerify {
+		caCertFile := setupCACertFile
+		if caCertFile != "" {
+			// The daemon runs from a different working directory.
+			if caCertFile, err = filepath.Abs(caCertFile); err != nil {
+				return "", false, fmt.Errorf("failed to resolve CA cert path: %w", err)
+			}
+		}
+		if err := config.SetTLSSettings(caCertFile, setupTLSSkipVerify); err != nil {
+			return "", false, err
+		}
+		if caCertFile != "" {
+			fmt.Fprintf(out, "CA cert: %s\n", caCertFile)
+		}
+		if setupTLSSkipVerify {
+			fmt.Fprintln(out, "⚠️  TLS certificate verification disabled (--tls-skip-verify)")
+		}
+	}
+	if setupUseKeyring {
+		// Saved before authenticating so the new API key goes to the keyring.
+		if err := config.SetUseKeyring(true); err != nil {
+			return "", false, err
+		}
+		fmt.Fprintln(out, "API keys: OS keychain")
+	}
+	fmt.Fprintln(out)
 
 	needsLogin = true
 	if apiKey != "" {
-		if err := loginWithAPIKey(backendURL, apiKey, binding); err != nil {
+		if err := loginWithAPIKey(out, backendURL, apiKey, binding); err != nil {
 			return "", false, err
 		}
-		fmt.Println()
+		fmt.Fprintln(out)
 		needsLogin = false
 	} else {
 		// Check the binding's existing credentials (per-config-dir for a
@@ -232,39 +593,42 @@ func runSetupAuth(cmd *cobra.Command, binding config.Binding) (backendURL string
 		cfg, err := config.GetUploadConfigFor(binding)
 		if err == nil && cfg.APIKey != "" {
 			if cfg.BackendURL == backendURL {
-				fmt.Println("Checking existing authentication...")
+				fmt.Fprintln(out, "Checking existing authentication...")
 				if err := verifyAPIKey(cfg); err == nil {
 					logger.Info("Existing API key is valid, skipping login")
-					fmt.Println("Already authenticated")
-					fmt.Println()
+					fmt.Fprintln(out, "Already authenticated")
+					fmt.Fprintln(out)
 					needsLogin = false
 				} else {
-					logger.Info("Existing API key is invalid: %v", err)
-					fmt.Println("❌ Existing credentials invalid, need to re-authenticate")
-					fmt.Println()
+					logger.Infof("Existing API key is invalid: %v", err)
+					fmt.Fprintln(out, "❌ Existing credentials invalid, need to re-authenticate")
+					fmt.Fprintln(out)
 				}
 			} else {
-				logger.Info("Backend URL changed from %s to %s, need to re-login", cfg.BackendURL, backendURL)
-				fmt.Println("Backend URL changed, need to re-authenticate")
-				fmt.Println()
+				logger.Infof("Backend URL changed from %s to %s, need to re-login", cfg.BackendURL, backendURL)
+				fmt.Fprintln(out, "Backend URL changed, need to re-authenticate")
+				fmt.Fprintln(out)
 			}
 		}
 
 		if needsLogin {
-			fmt.Println("Step 1/2: Authentication")
-			fmt.Println()
-			if err := doDeviceLogin(backendURL, defaultKeyName(), binding); err != nil {
+			fmt.Fprintln(out, "Step 1/2: Authentication")
+			fmt.Fprintln(out)
+			if err := doDeviceLogin(out, backendURL, setupKeyNameOrDefault(), binding); err != nil {
 				return "", false, err
 			}
-			fmt.Println()
+			fmt.Fprintln(out)
 		}
 	}
 
+	if _, err := config.EnsureNewInstallDefaults(); err != nil {
+		logger.Warnf("Failed to apply new-install defaults: %v", err)
+	}
 	if added, err := config.EnsureDefaultRedaction(); err != nil {
-		logger.Warn("Failed to initialize redaction config: %v", err)
+		logger.Warnf("Failed to initialize redaction config: %v", err)
 	} else if added {
 		logger.Info("Initialized default redaction config")
-		fmt.Println("Redaction enabled (default patterns)")
+		fmt.Fprintln(out, "Redaction enabled (default patterns)")
 	}
 
 	return backendURL, needsLogin, nil
@@ -275,7 +639,15 @@ func init() {
 
 	setupCmd.Flags().StringVar(&setupProviderName, "provider", "", "Provider to set up (claude-code, codex, opencode, or cursor); auto-detects if unset.")
 	setupCmd.Flags().StringVar(&setupConfigDir, "config-dir", "", "Provider config dir to install into and bind to this backend (requires --provider; claude-code only). Defaults to the provider's default dir.")
+	setupCmd.Flags().StringVar(&setupScope, "scope", "", "Claude Code settings file for hooks: user or project (./.claude/settings.local.json; requires --provider claude-code). Defaults to $CONFAB_SETTINGS_SCOPE, then user.")
 	setupCmd.Flags().String("backend-url", "", "Backend API URL (required)")
 	setupCmd.MarkFlagRequired("backend-url")
 	setupCmd.Flags().String("api-key", "", "API key (bypasses device auth flow)")
+	setupCmd.Flags().StringVar(&setupKeyName, "name", "", "Name for the API key created by device login (default: <hostname>-<username>); saved as key_name")
+	setupCmd.Flags().StringVar(&setupProxyURL, "proxy", "", "Proxy URL for backend requests (http://, https://, socks5://); saved as proxy_url")
+	setupCmd.Flags().StringVar(&setupCACertFile, "ca-cert", "", "PEM CA bundle to trust for the backend's TLS certificate; saved as ca_cert_file")
+	setupCmd.Flags().BoolVar(&setupTLSSkipVerify, "tls-skip-verify", false, "Disable backend TLS certificate verification (development only)")
+	setupCmd.Flags().BoolVar(&setupUseKeyring, "use-keyring", false, "Store API keys in the OS keychain instead of config.json; saved as use_keyring")
+	setupCmd.Flags().BoolVar(&setupJSON, "json", false, "Print the outcome as JSON on stdout (progress goes to stderr)")
+	setupCmd.Flags().BoolVar(&setupDryRun, "dry-run", false, "Show what setup would change without writing config or settings files (credentials are still validated)")
 }
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -216,7 +218,7 @@ func TestRunSetup_AlreadyAuthenticated(t *testing.T) {
 
 	// Track if login was called
 	var loginCalled bool
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		loginCalled = true
 		return nil
 	}
@@ -270,7 +272,7 @@ func TestRunSetup_InvalidExistingKey(t *testing.T) {
 
 	// Track if login was called
 	var loginCalled bool
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		loginCalled = true
 		// Simulate successful login by saving new config
 		newCfg := &config.UploadConfig{
@@ -319,7 +321,7 @@ func TestRunSetup_BackendURLChanged(t *testing.T) {
 	os.WriteFile(configPath, cfgData, 0600)
 
 	var loginCalled bool
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		loginCalled = true
 		newCfg := &config.UploadConfig{
 			BackendURL: backendURL,
@@ -361,7 +363,7 @@ func TestRunSetup_NeedsLogin(t *testing.T) {
 
 	var loginCalled bool
 	var loginBackendURL string
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		loginCalled = true
 		loginBackendURL = backendURL
 		newCfg := &config.UploadConfig{
@@ -592,7 +594,7 @@ func TestRunSetup_HookInstallationFails(t *testing.T) {
 	// (CI hosts don't have the real `claude` binary).
 	stubProviderDetect(t, "claude")
 
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		t.Error("login should not be called")
 		return nil
 	}
@@ -620,7 +622,7 @@ func TestRunSetup_WithAPIKeyFlag(t *testing.T) {
 
 	// Track if login was called
 	var loginCalled bool
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		loginCalled = true
 		return nil
 	}
@@ -675,7 +677,7 @@ func TestRunSetup_WithAPIKeyFlag_InvalidKey(t *testing.T) {
 	setupSetupTestEnv(t, server.URL)
 
 	var loginCalled bool
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		loginCalled = true
 		return nil
 	}
@@ -710,7 +712,7 @@ func TestRunSetup_WithAPIKeyFlag_SavesBackendURL(t *testing.T) {
 
 	_, configPath := setupSetupTestEnv(t, server.URL)
 
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		t.Error("login should not be called")
 		return nil
 	}
@@ -738,6 +740,209 @@ func TestRunSetup_WithAPIKeyFlag_SavesBackendURL(t *testing.T) {
 	}
 }
 
+func TestRunSetup_WithProxyFlag(t *testing.T) {
+	origDoDeviceLogin := doDeviceLoginFunc
+	defer func() { doDeviceLoginFunc = origDoDeviceLogin }()
+	defer func() { setupProxyURL = "" }()
+
+	backend := &setupTestBackend{validateValid: true}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	// The fake proxy answers absolute-URI (proxied) requests by handing
+	// them straight to the backend.
+	var proxied int
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.IsAbs() {
+			proxied++
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+
+	_, configPath := setupSetupTestEnv(t, server.URL)
+
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
+		t.Error("login should not be called")
+		return nil
+	}
+
+	setupProxyURL = proxy.URL
+	cmd := &cobra.Command{}
+	cmd.Flags().String("backend-url", server.URL, "")
+	cmd.Flags().String("api-key", "cfb_test-key-12345678", "")
+
+	if err := runSetup(cmd, []string{}); err != nil {
+		t.Fatalf("runSetup failed: %v", err)
+	}
+
+	if proxied != 1 {
+		t.Errorf("expected API key validation to go through the proxy once, got %d", proxied)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	var cfg config.UploadConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if cfg.ProxyURL != proxy.URL {
+		t.Errorf("expected proxy_url %s, got %q", proxy.URL, cfg.ProxyURL)
+	}
+}
+
+func TestRunSetup_WithProxyFlag_Invalid(t *testing.T) {
+	defer func() { setupProxyURL = "" }()
+
+	server := httptest.NewServer(&setupTestBackend{validateValid: true})
+	defer server.Close()
+	setupSetupTestEnv(t, server.URL)
+
+	setupProxyURL = "ftp://proxy.corp:21"
+	cmd := &cobra.Command{}
+	cmd.Flags().String("backend-url", server.URL, "")
+	cmd.Flags().String("api-key", "cfb_test-key-12345678", "")
+
+	err := runSetup(cmd, []string{})
+	if err == nil || !strings.Contains(err.Error(), "invalid proxy URL") {
+		t.Fatalf("expected invalid proxy URL error, got %v", err)
+	}
+}
+
+func TestRunSetup_WithCACertFlag(t *testing.T) {
+	origDoDeviceLogin := doDeviceLoginFunc
+	defer func() { doDeviceLoginFunc = origDoDeviceLogin }()
+	defer func() { setupCACertFile = "" }()
+
+	// A self-signed backend: API key validation only succeeds if the CA
+	// saved by --ca-cert is trusted.
+	backend := &setupTestBackend{validateValid: true}
+	server := httptest.NewTLSServer(backend)
+	defer server.Close()
+
+	tmpDir, configPath := setupSetupTestEnv(t, server.URL)
+	caFile := filepath.Join(tmpDir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
+		t.Error("login should not be called")
+		return nil
+	}
+
+	setupCACertFile = caFile
+	cmd := &cobra.Command{}
+	cmd.Flags().String("backend-url", server.URL, "")
+	cmd.Flags().String("api-key", "cfb_test-key-12345678", "")
+
+	if err := runSetup(cmd, []string{}); err != nil {
+		t.Fatalf("runSetup failed: %v", err)
+	}
+	if backend.validateCalls != 1 {
+		t.Errorf("expected 1 validate call over TLS, got %d", backend.validateCalls)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	var cfg config.UploadConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if cfg.CACertFile != caFile || cfg.TLSSkipVerify {
+		t.Errorf("expected ca_cert_file %s without tls_skip_verify, got %q/%v", caFile, cfg.CACertFile, cfg.TLSSkipVerify)
+	}
+}
+
+func TestRunSetup_SelfSignedBackendWithoutCACert(t *testing.T) {
+	backend := &setupTestBackend{validateValid: true}
+	server := httptest.NewTLSServer(backend)
+	defer server.Close()
+	setupSetupTestEnv(t, server.URL)
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("backend-url", server.URL, "")
+	cmd.Flags().String("api-key", "cfb_test-key-12345678", "")
+
+	if err := runSetup(cmd, []string{}); err == nil {
+		t.Fatal("expected setup to fail against an untrusted self-signed backend")
+	}
+	if backend.validateCalls != 0 {
+		t.Errorf("expected the TLS handshake to fail before validate, got %d calls", backend.validateCalls)
+	}
+}
+
+// setupTestKeyring is an in-memory config.KeyringBackend.
+type setupTestKeyring map[string]string
+
+func (k setupTestKeyring) Get(service, key string) (string, error) {
+	v, ok := k[service+"/"+key]
+	if !ok {
+		return "", config.ErrKeyringNotFound
+	}
+	return v, nil
+}
+
+func (k setupTestKeyring) Set(service, key, value string) error {
+	k[service+"/"+key] = value
+	return nil
+}
+
+func (k setupTestKeyring) Delete(service, key string) error {
+	if _, ok := k[service+"/"+key]; !ok {
+		return config.ErrKeyringNotFound
+	}
+	delete(k, service+"/"+key)
+	return nil
+}
+
+func TestRunSetup_WithUseKeyringFlag(t *testing.T) {
+	origDoDeviceLogin := doDeviceLoginFunc
+	defer func() { doDeviceLoginFunc = origDoDeviceLogin }()
+	defer func() { setupUseKeyring = false }()
+	keyring := setupTestKeyring{}
+	defer config.SetKeyringBackendForTest(keyring)()
+
+	backend := &setupTestBackend{validateValid: true}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	_, configPath := setupSetupTestEnv(t, server.URL)
+
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
+		t.Error("login should not be called")
+		return nil
+	}
+
+	setupUseKeyring = true
+	cmd := &cobra.Command{}
+	cmd.Flags().String("backend-url", server.URL, "")
+	cmd.Flags().String("api-key", "cfb_test-key-12345678", "")
+
+	if err := runSetup(cmd, []string{}); err != nil {
+		t.Fatalf("runSetup failed: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	var cfg config.UploadConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if !cfg.UseKeyring || cfg.APIKey != "" {
+		t.Errorf("expected use_keyring with no api_key in the file, got %v/%q", cfg.UseKeyring, cfg.APIKey)
+	}
+	if got := keyring["confab/api_key"]; got != "cfb_test-key-12345678" {
+		t.Errorf("keyring api_key = %q, want the setup key", got)
+	}
+}
+
 // TestVerifyAPIKeyTimeout tests that verification respects timeout
 func TestVerifyAPIKeyTimeout(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -797,7 +1002,7 @@ func TestSetupWithAPIKey_PreservesCustomRedactionPatterns(t *testing.T) {
 	cfgData, _ := json.Marshal(existingCfg)
 	os.WriteFile(configPath, cfgData, 0600)
 
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		t.Error("device login should not be called when api-key is provided")
 		return nil
 	}
@@ -880,7 +1085,7 @@ func TestSetupDeviceFlow_PreservesCustomRedactionPatterns(t *testing.T) {
 	os.WriteFile(configPath, cfgData, 0600)
 
 	// Mock device login that preserves config
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		cfg, err := config.GetUploadConfig()
 		if err != nil {
 			cfg = &config.UploadConfig{}
@@ -949,7 +1154,7 @@ func TestSetupFreshInstall_AddsDefaultRedaction(t *testing.T) {
 	// Don't create any config - simulates fresh install
 	os.Remove(configPath)
 
-	doDeviceLoginFunc = func(backendURL, keyName string, _ config.Binding) error {
+	doDeviceLoginFunc = func(_ io.Writer, backendURL, keyName string, _ config.Binding) error {
 		t.Error("device login should not be called when api-key is provided")
 		return nil
 	}
@@ -993,6 +1198,10 @@ func TestSetupFreshInstall_AddsDefaultRedaction(t *testing.T) {
 	if len(savedCfg.Redaction.Patterns) != 0 {
 		t.Errorf("expected 0 patterns in config (defaults are runtime), got %d", len(savedCfg.Redaction.Patterns))
 	}
+	// And the new-install defaults by EnsureNewInstallDefaults
+	if savedCfg.MaxConcurrentUploads != config.DefaultMaxConcurrentUploads {
+		t.Errorf("max_concurrent_uploads = %d, want %d on fresh install", savedCfg.MaxConcurrentUploads, config.DefaultMaxConcurrentUploads)
+	}
 }
 
 // stubProviderDetect swaps provider.LookPath to simulate which CLIs are
@@ -1362,7 +1571,9 @@ func TestRunSetup_Idempotent_AlreadyInstalled(t *testing.T) {
     "SessionEnd":   [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook session-end"}]}],
     "PreToolUse":   [{"matcher": "Bash", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook pre-tool-use"}]}],
     "PostToolUse":  [{"matcher": "Bash", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook post-tool-use"}]}],
-    "UserPromptSubmit": [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook user-prompt-submit"}]}]
+    "UserPromptSubmit": [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook user-prompt-submit"}]}],
+    "Stop": [{"hooks": [{"type":"command","command":"/usr/local/bin/confab hook stop"}]}],
+    "PreCompact": [{"hooks": [{"type":"command","command":"/usr/local/bin/confab hook pre-compact"}]}]
   }
 }`
 	if err := os.WriteFile(claudeSettings, []byte(confabClaudeCfg), 0600); err != nil {
@@ -1421,3 +1632,192 @@ command = "/usr/local/bin/confab hook post-tool-use --provider codex"
 		t.Fatalf("expected already-installed for BOTH providers, got:\n%s", output)
 	}
 }
+
+func TestRunSetup_JSONFreshInstall(t *testing.T) {
+	origDoDeviceLogin := doDeviceLoginFunc
+	defer func() { doDeviceLoginFunc = origDoDeviceLogin }()
+	origJSON := setupJSON
+	setupJSON = true
+	defer func() { setupJSON = origJSON }()
+
+	backend := &setupTestBackend{validateValid: true}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	tmpDir, configPath := setupSetupTestEnv(t, server.URL)
+
+	doDeviceLoginFunc = func(w io.Writer, backendURL, keyName string, _ config.Binding) error {
+		fmt.Fprintln(w, "Your code: TEST-1234")
+		return config.SaveUploadConfig(&config.UploadConfig{
+			BackendURL: backendURL,
+			APIKey:     "cfb_device-login-key-12345678",
+		})
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("backend-url", server.URL, "")
+	cmd.Flags().String("api-key", "", "")
+
+	output := captureStdout(t, func() {
+		if err := runSetup(cmd, nil); err != nil {
+			t.Fatalf("runSetup failed: %v", err)
+		}
+	})
+
+	// stdout is the JSON document alone; progress went to stderr.
+	var res setupResult
+	dec := json.NewDecoder(strings.NewReader(output))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&res); err != nil {
+		t.Fatalf("stdout is not a setup result: %v\n%s", err, output)
+	}
+	if dec.More() {
+		t.Fatalf("stdout has more than the JSON result:\n%s", output)
+	}
+
+	if !res.OK || res.Error != "" {
+		t.Errorf("ok = %v, error = %q; want success", res.OK, res.Error)
+	}
+	if res.BackendURL != server.URL {
+		t.Errorf("backend_url = %q, want %q", res.BackendURL, server.URL)
+	}
+	if res.ConfigPath != configPath {
+		t.Errorf("config_path = %q, want %q", res.ConfigPath, configPath)
+	}
+	if !res.LoggedIn || res.Auth != "logged_in" {
+		t.Errorf("logged_in = %v, auth = %q after a device login", res.LoggedIn, res.Auth)
+	}
+	want := setupProviderResult{
+		Provider:     provider.NameClaudeCode,
+		Hooks:        "installed",
+		Skills:       "installed",
+		SettingsPath: filepath.Join(tmpDir, ".claude", "settings.json"),
+	}
+	if len(res.Providers) != 1 || res.Providers[0] != want {
+		t.Errorf("providers = %+v, want [%+v]", res.Providers, want)
+	}
+	verifyHooksInstalled(t)
+}
+
+func TestRunSetup_JSONOnFlagError(t *testing.T) {
+	origJSON, origDir := setupJSON, setupConfigDir
+	setupJSON, setupConfigDir = true, "/tmp/claude-work"
+	defer func() { setupJSON, setupConfigDir = origJSON, origDir }()
+
+	setupSetupTestEnv(t, "http://localhost:1")
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("backend-url", "http://localhost:1", "")
+	cmd.Flags().String("api-key", "", "")
+
+	var runErr error
+	output := captureStdout(t, func() { runErr = runSetup(cmd, nil) })
+	if runErr == nil {
+		t.Fatal("runSetup --config-dir without --provider succeeded")
+	}
+
+	var res setupResult
+	if err := json.Unmarshal([]byte(output), &res); err != nil {
+		t.Fatalf("stdout is not a setup result: %v\n%s", err, output)
+	}
+	if res.OK || res.Error != runErr.Error() || res.Auth != "" {
+		t.Errorf("result = %+v, want ok=false, error %q and no auth step", res, runErr)
+	}
+}
+
+// failingSkillsProvider installs hooks normally but fails to install
+// skills.
+type failingSkillsProvider struct {
+	provider.Provider
+}
+
+func (failingSkillsProvider) InstallSkills() error {
+	return fmt.Errorf("disk full")
+}
+
+func TestInstallForProvider_SkillsFailure(t *testing.T) {
+	setupSetupTestEnv(t, "http://localhost:1")
+	p, err := provider.Get(provider.NameClaudeCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	res, err := installForProvider(&out, failingSkillsProvider{p})
+	if err == nil || !strings.Contains(err.Error(), "failed to install claude-code skills") {
+		t.Fatalf("err = %v, want a skills failure", err)
+	}
+	if res.Hooks != "installed" || res.Skills != "failed" || res.Error != "disk full" {
+		t.Errorf("result = %+v, want hooks installed and skills failed", res)
+	}
+	if !strings.Contains(out.String(), "skills install failed") {
+		t.Errorf("progress missing the skills failure:\n%s", out.String())
+	}
+}
+
+func TestRunSetup_DryRun(t *testing.T) {
+	origDryRun := setupDryRun
+	setupDryRun = true
+	defer func() { setupDryRun = origDryRun }()
+
+	backend := &setupTestBackend{validateValid: true}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	tmpDir, configPath := setupSetupTestEnv(t, server.URL)
+	settingsPath := filepath.Join(tmpDir, ".claude", "settings.json")
+	const settings = `{"model": "opus"}`
+	if err := os.WriteFile(settingsPath, []byte(settings), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("backend-url", server.URL, "")
+	cmd.Flags().String("api-key", "cfb_direct-api-key-12345678", "")
+
+	output := captureStdout(t, func() {
+		if err := runSetup(cmd, nil); err != nil {
+			t.Fatalf("runSetup --dry-run failed: %v", err)
+		}
+	})
+
+	if backend.validateCalls != 1 {
+		t.Errorf("validate calls = %d, want 1 (dry run still checks the key)", backend.validateCalls)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote %s (stat err = %v)", configPath, err)
+	}
+	if data, err := os.ReadFile(settingsPath); err != nil || string(data) != settings {
+		t.Errorf("dry run changed settings.json: %q, %v", data, err)
+	}
+	for _, want := range []string{"Would update " + settingsPath, "+ ", "hook session-start", "hook pre-compact"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "\n    - ") {
+		t.Errorf("fresh install should only add lines:\n%s", output)
+	}
+}
+
+func TestRunSetup_DryRun_InvalidKey(t *testing.T) {
+	origDryRun := setupDryRun
+	setupDryRun = true
+	defer func() { setupDryRun = origDryRun }()
+
+	backend := &setupTestBackend{validateValid: false}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	setupSetupTestEnv(t, server.URL)
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("backend-url", server.URL, "")
+	cmd.Flags().String("api-key", "cfb_bad-api-key-12345678", "")
+
+	captureStdout(t, func() {
+		if err := runSetup(cmd, nil); err == nil || !strings.Contains(err.Error(), "invalid API key") {
+			t.Errorf("runSetup --dry-run with a bad key = %v, want invalid API key", err)
+		}
+	})
+}
@@ -39,14 +39,14 @@ Installs:
 		for _, p := range targets {
 			fmt.Printf("Installing %s skills...\n", p.Name())
 			if err := p.InstallSkills(); err != nil {
-				logger.Error("Failed to install %s skills: %v", p.Name(), err)
+				logger.Errorf("Failed to install %s skills: %v", p.Name(), err)
 				return fmt.Errorf("failed to install %s skills: %w", p.Name(), err)
 			}
 			stateDir, err := p.StateDir()
 			if err != nil {
 				return fmt.Errorf("failed to get %s state directory: %w", p.Name(), err)
 			}
-			logger.Info("%s skills installed in %s/skills/", p.Name(), stateDir)
+			logger.Infof("%s skills installed in %s/skills/", p.Name(), stateDir)
 			fmt.Printf("✓ %s skills installed in %s/skills/\n", p.Name(), stateDir)
 		}
 		fmt.Println()
@@ -71,7 +71,7 @@ var skillsRemoveCmd = &cobra.Command{
 		for _, p := range targets {
 			fmt.Printf("Removing %s skills...\n", p.Name())
 			if err := p.UninstallSkills(); err != nil {
-				logger.Error("Failed to remove %s skills: %v", p.Name(), err)
+				logger.Errorf("Failed to remove %s skills: %v", p.Name(), err)
 				return fmt.Errorf("failed to remove %s skills: %w", p.Name(), err)
 			}
 		}
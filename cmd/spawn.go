@@ -45,6 +45,15 @@ type daemonLaunchInput struct {
 	// which stamps it onto transcript chunk metadata (spm9). Empty for other
 	// providers, whose JSONL carries the model inline.
 	Model string `json:"model,omitempty"`
+	// PIDFile is the absolute --pidfile path the daemon writes its PID to
+	// for process supervisors; empty disables it.
+	PIDFile string `json:"pid_file,omitempty"`
+	// SyncIntervalMS, SyncJitterMS and Deterministic carry the
+	// --sync-interval, --sync-jitter and --deterministic flags; zero
+	// values fall back to config and env (see resolveSyncTiming).
+	SyncIntervalMS int  `json:"sync_interval_ms,omitempty"`
+	SyncJitterMS   int  `json:"sync_jitter_ms,omitempty"`
+	Deterministic  bool `json:"deterministic,omitempty"`
 }
 
 // launchAsHookInput satisfies provider.HookInput for the sole purpose
@@ -78,17 +87,17 @@ func maybeSpawnDaemon(p provider.Provider, launch *daemonLaunchInput) (bool, err
 	}
 
 	if !p.ShouldSpawnForInput(launchAsHookInput{launch}) {
-		logger.Info("Skipping %s daemon: provider gate refused (session_id=%s)",
-			p.Name(), launch.ExternalID)
+		logger.Info("Skipping daemon: provider gate refused",
+			"provider", p.Name(), "session_id", launch.ExternalID)
 		return false, nil
 	}
 
 	existingState, err := daemon.LoadStateForProvider(p.Name(), launch.ExternalID)
 	if err != nil {
-		logger.Warn("Error checking existing %s state: %v", p.Name(), err)
+		logger.Warnf("Error checking existing %s state: %v", p.Name(), err)
 	}
 	if existingState != nil && existingState.IsDaemonRunning() {
-		logger.Info("%s daemon already running: pid=%d", p.Name(), existingState.PID)
+		logger.Info("Daemon already running", "provider", p.Name(), "pid", existingState.PID)
 		p.OnAlreadyRunning(launch.ExternalID)
 		return false, nil
 	}
@@ -103,14 +112,14 @@ func maybeSpawnDaemon(p provider.Provider, launch *daemonLaunchInput) (bool, err
 	if launch.ParentPID == 0 {
 		launch.ParentPID = walkedPID
 	} else if walkedPID != 0 && walkedPID != launch.ParentPID {
-		logger.Warn("ParentPID mismatch for %s session %s: plugin=%d walked=%d; trusting plugin",
-			p.Name(), launch.ExternalID, launch.ParentPID, walkedPID)
+		logger.Warn("ParentPID mismatch; trusting plugin", "provider", p.Name(),
+			"session_id", launch.ExternalID, "plugin", launch.ParentPID, "walked", walkedPID)
 	}
 
 	if err := spawnDaemonFunc(launch); err != nil {
 		return false, fmt.Errorf("failed to spawn %s daemon: %w", p.Name(), err)
 	}
-	logger.Info("%s daemon spawned successfully", p.Name())
+	logger.Infof("%s daemon spawned successfully", p.Name())
 	return true, nil
 }
 
@@ -131,6 +140,10 @@ func spawnDaemonImpl(launch *daemonLaunchInput) error {
 	cmd := exec.Command(executable, "hook", "session-start",
 		"--provider", launch.Provider, "--bg-daemon", string(launchJSON))
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if logFormat != "" {
+		// The daemon doesn't get our flags; hand --log-format down as LOG_FORMAT.
+		cmd.Env = append(os.Environ(), logger.LogFormatEnv+"="+logFormat)
+	}
 	devNull, _ := os.OpenFile(os.DevNull, os.O_RDWR, 0)
 	cmd.Stdin = devNull
 	cmd.Stdout = devNull
@@ -148,7 +161,7 @@ func spawnDaemonImpl(launch *daemonLaunchInput) error {
 		launch.TranscriptPath, launch.CWD, launch.ParentPID)
 	state.PID = cmd.Process.Pid
 	if err := state.Save(); err != nil {
-		logger.Warn("Failed to save initial state: %v", err)
+		logger.Warnf("Failed to save initial state: %v", err)
 	}
 
 	if err := cmd.Process.Release(); err != nil {
@@ -1,39 +1,258 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/daemon"
 	"github.com/ConfabulousDev/confab/pkg/logger"
 	"github.com/ConfabulousDev/confab/pkg/provider"
 	"github.com/spf13/cobra"
 )
 
+var statusJSON bool
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show confab status",
-	Long:  `Displays backend authentication and per-provider hook/skill state for every supported provider.`,
+	Long: `Displays backend authentication, the sync daemon for the current
+directory's session, and per-provider hook/skill state for every supported
+provider.
+
+With --json, prints only the current session's sync state as JSON.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		defer NotifyIfUpdateAvailable()
 		logger.Info("Running status command")
 
+		session := currentSessionStatus()
+		if statusJSON {
+			return writeSessionStatusJSON(os.Stdout, session)
+		}
+
+		defer NotifyIfUpdateAvailable()
+
 		fmt.Println("=== Confab Status ===")
 		fmt.Println()
 
 		printBackendSection()
 
+		printSessionSection(os.Stdout, session)
+
 		printProviderSections()
 
 		return nil
 	},
 }
 
+// sessionStatus is the sync state of the daemon serving the current
+// directory, read from its state file and, while it runs, its control
+// socket.
+type sessionStatus struct {
+	Provider       string `json:"provider"`
+	SessionID      string `json:"session_id"`
+	TranscriptPath string `json:"transcript_path"`
+	PID            int    `json:"pid"`
+	// Running is false for a state file whose daemon has died (e.g. it
+	// was killed before it could clean up).
+	Running         bool      `json:"running"`
+	StartedAt       time.Time `json:"started_at"`
+	ConfabSessionID string    `json:"confab_session_id,omitempty"`
+	BackendURL      string    `json:"backend_url,omitempty"`
+	// SessionURL is the session's page on the backend; empty until the
+	// daemon's first successful init.
+	SessionURL    string         `json:"session_url,omitempty"`
+	FileLines     map[string]int `json:"file_lines"`
+	BytesUploaded int64          `json:"bytes_uploaded"`
+	LastSyncAt    *time.Time     `json:"last_sync_at"`
+}
+
+// currentSessionStatus returns sessionStatusForDir for the current
+// directory.
+func currentSessionStatus() *sessionStatus {
+	cwd, err := os.Getwd()
+	if err != nil {
+		logger.Warnf("status: cannot determine working directory: %v", err)
+		return nil
+	}
+	return sessionStatusForDir(cwd)
+}
+
+// queryDaemonStatusFunc asks a running daemon for its sync progress over
+// its control socket. Swapped out in tests.
+var queryDaemonStatusFunc = daemon.QueryStatusForProvider
+
+// sessionStatusForDir returns the daemon state whose CWD is cwd, or nil if
+// there is none. A running daemon beats a dead one's leftover state; among
+// equals (e.g. two providers), the most recently started wins. A running
+// daemon's progress comes from its control socket, falling back to the
+// state file's copy when it can't be reached.
+func sessionStatusForDir(cwd string) *sessionStatus {
+	states, err := daemon.ListAllStates()
+	if err != nil {
+		logger.Warnf("status: failed to list daemon states: %v", err)
+		return nil
+	}
+
+	var found *daemon.State
+	foundRunning := false
+	for _, st := range states {
+		if !sameDir(st.CWD, cwd) {
+			continue
+		}
+		running := st.IsDaemonRunning()
+		if found == nil || (running && !foundRunning) ||
+			(running == foundRunning && st.StartedAt.After(found.StartedAt)) {
+			found, foundRunning = st, running
+		}
+	}
+	if found == nil {
+		return nil
+	}
+
+	status := &sessionStatus{
+		Provider:        found.Provider,
+		SessionID:       found.ExternalID,
+		TranscriptPath:  found.TranscriptPath,
+		PID:             found.PID,
+		Running:         foundRunning,
+		StartedAt:       found.StartedAt,
+		ConfabSessionID: found.ConfabSessionID,
+		FileLines:       map[string]int{},
+	}
+	if p, err := provider.Get(found.Provider); err == nil {
+		if cfg, err := uploadConfigForHook(p, found.TranscriptPath); err == nil {
+			status.BackendURL = cfg.BackendURL
+		}
+	}
+	if status.ConfabSessionID != "" {
+		status.SessionURL, _ = formatSessionURL(status.ConfabSessionID, status.BackendURL)
+	}
+	progress := found.SyncProgress
+	if foundRunning {
+		if resp, err := queryDaemonStatusFunc(found.Provider, found.ExternalID); err != nil {
+			logger.Debug("status: daemon query failed, using the state file", "error", err)
+		} else if resp.SyncProgress != nil {
+			progress = resp.SyncProgress
+		}
+	}
+	if p := progress; p != nil {
+		if p.FileLines != nil {
+			status.FileLines = p.FileLines
+		}
+		status.BytesUploaded = p.BytesUploaded
+		if !p.LastSyncAt.IsZero() {
+			lastSync := p.LastSyncAt
+			status.LastSyncAt = &lastSync
+		}
+	}
+	return status
+}
+
+// sameDir compares two directory paths after cleaning and resolving
+// symlinks (macOS /tmp → /private/tmp), falling back to the cleaned path.
+func sameDir(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	resolve := func(p string) string {
+		if r, err := filepath.EvalSymlinks(p); err == nil {
+			return r
+		}
+		return filepath.Clean(p)
+	}
+	return resolve(a) == resolve(b)
+}
+
+// writeSessionStatusJSON emits {"active": false} when no daemon state
+// exists for the current directory, otherwise {"active": <running>,
+// "session": {...}}.
+func writeSessionStatusJSON(w io.Writer, session *sessionStatus) error {
+	out := struct {
+		Active  bool           `json:"active"`
+		Session *sessionStatus `json:"session,omitempty"`
+	}{Active: session != nil && session.Running, Session: session}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func printSessionSection(w io.Writer, session *sessionStatus) {
+	fmt.Fprintln(w, "Current Session:")
+	if session == nil {
+		fmt.Fprintln(w, "  sync not active (no daemon for this directory)")
+		fmt.Fprintln(w)
+		return
+	}
+
+	fmt.Fprintf(w, "  Session:    %s (%s)\n", session.SessionID, session.Provider)
+	fmt.Fprintf(w, "  Transcript: %s\n", session.TranscriptPath)
+	if session.Running {
+		fmt.Fprintf(w, "  Daemon PID: %d\n", session.PID)
+	} else {
+		fmt.Fprintf(w, "  Daemon PID: %d (not running)\n", session.PID)
+	}
+	if session.ConfabSessionID != "" {
+		fmt.Fprintf(w, "  Confab ID:  %s\n", session.ConfabSessionID)
+	} else {
+		fmt.Fprintln(w, "  Confab ID:  not yet registered with the backend")
+	}
+	if session.BackendURL != "" {
+		fmt.Fprintf(w, "  Backend:    %s\n", session.BackendURL)
+	}
+	if session.SessionURL != "" {
+		fmt.Fprintf(w, "  URL:        %s\n", session.SessionURL)
+	}
+	fmt.Fprintf(w, "  Uploaded:   %s\n", formatByteCount(session.BytesUploaded))
+	if session.LastSyncAt != nil {
+		fmt.Fprintf(w, "  Last sync:  %s\n", session.LastSyncAt.Local().Format(time.RFC3339))
+	} else {
+		fmt.Fprintln(w, "  Last sync:  never")
+	}
+
+	if len(session.FileLines) > 0 {
+		names := make([]string, 0, len(session.FileLines))
+		for name := range session.FileLines {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintln(w, "  Files:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "    FILE\tLINES SYNCED")
+		for _, name := range names {
+			fmt.Fprintf(tw, "    %s\t%d\n", name, session.FileLines[name])
+		}
+		tw.Flush()
+	}
+	fmt.Fprintln(w)
+}
+
+// formatByteCount renders n with a binary unit (B, KiB, MiB, GiB).
+func formatByteCount(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 2; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMG"[exp])
+}
+
 func printBackendSection() {
 	fmt.Println("Backend Sync:")
 	cfg, err := config.GetUploadConfig()
 	if err != nil {
-		logger.Error("Failed to get backend config: %v", err)
+		logger.Errorf("Failed to get backend config: %v", err)
 		fmt.Println("  ✗ Configuration error")
 		fmt.Println()
 		return
@@ -47,7 +266,7 @@ func printBackendSection() {
 	fmt.Printf("  Backend: %s\n", cfg.BackendURL)
 	fmt.Print("  Validating API key... ")
 	if err := verifyAPIKey(cfg); err != nil {
-		logger.Error("API key validation failed: %v", err)
+		logger.Errorf("API key validation failed: %v", err)
 		fmt.Println("✗ Invalid")
 		fmt.Printf("  Error: %v\n", err)
 		fmt.Println("  Run 'confab login' to re-authenticate")
@@ -93,7 +312,7 @@ func printProviderBlock(p provider.Provider) {
 	hooksInstalled, err := p.IsHooksInstalled()
 	switch {
 	case err != nil:
-		logger.Error("Failed to check hook status for %s: %v", p.Name(), err)
+		logger.Errorf("Failed to check hook status for %s: %v", p.Name(), err)
 		fmt.Printf("  Hooks: ? (error: %v)\n", err)
 	case hooksInstalled:
 		fmt.Println("  Hooks: ✓ Installed")
@@ -123,5 +342,6 @@ func checkmark(b bool) string {
 }
 
 func init() {
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print the current session's sync state as JSON")
 	rootCmd.AddCommand(statusCmd)
 }
@@ -3,13 +3,17 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/daemon"
 	"github.com/ConfabulousDev/confab/pkg/provider"
 )
 
@@ -258,3 +262,202 @@ func TestStatus_BackendNotConfigured(t *testing.T) {
 		t.Fatalf("expected unconfigured backend message\noutput:\n%s", output)
 	}
 }
+
+// writeRunningSessionState saves a daemon state file for a session rooted
+// at cwd, owned by the test process so IsDaemonRunning reports true.
+func writeRunningSessionState(t *testing.T, cwd string) {
+	t.Helper()
+	st := daemon.NewStateForProvider(provider.NameClaudeCode, "status-session-1", "/tmp/transcript.jsonl", cwd, 0)
+	st.SyncProgress = &daemon.SyncProgress{
+		FileLines:     map[string]int{"transcript.jsonl": 42, "agent-abc.jsonl": 7},
+		BytesUploaded: 3 * 1024,
+		LastSyncAt:    time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if err := st.Save(); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+}
+
+func TestStatus_CurrentSession(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+	writeRunningSessionState(t, workDir)
+
+	var out bytes.Buffer
+	printSessionSection(&out, currentSessionStatus())
+	got := out.String()
+
+	for _, want := range []string{
+		"Session:    status-session-1 (claude-code)",
+		"Transcript: /tmp/transcript.jsonl",
+		fmt.Sprintf("Daemon PID: %d", os.Getpid()),
+		"Uploaded:   3.0 KiB",
+		"transcript.jsonl  42",
+		"agent-abc.jsonl   7",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("session section missing %q\noutput:\n%s", want, got)
+		}
+	}
+}
+
+func TestStatus_CurrentSessionPrefersDaemonProgress(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+	writeRunningSessionState(t, workDir)
+
+	orig := queryDaemonStatusFunc
+	defer func() { queryDaemonStatusFunc = orig }()
+	queryDaemonStatusFunc = func(providerName, externalID string) (*daemon.ControlResponse, error) {
+		if externalID != "status-session-1" {
+			t.Errorf("queried session %q, want status-session-1", externalID)
+		}
+		return &daemon.ControlResponse{OK: true, SyncProgress: &daemon.SyncProgress{
+			FileLines:     map[string]int{"transcript.jsonl": 50},
+			BytesUploaded: 4 * 1024,
+		}}, nil
+	}
+
+	session := currentSessionStatus()
+	if session == nil || session.FileLines["transcript.jsonl"] != 50 || session.BytesUploaded != 4*1024 {
+		t.Errorf("session = %+v, want the daemon's progress over the state file's", session)
+	}
+
+	// An unreachable daemon falls back to the state file.
+	queryDaemonStatusFunc = func(string, string) (*daemon.ControlResponse, error) {
+		return nil, errors.New("connection refused")
+	}
+	session = currentSessionStatus()
+	if session == nil || session.FileLines["transcript.jsonl"] != 42 {
+		t.Errorf("session = %+v, want the state file's progress", session)
+	}
+}
+
+func TestStatus_NoActiveSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	// A daemon for a different directory doesn't count.
+	writeRunningSessionState(t, t.TempDir())
+
+	var out bytes.Buffer
+	printSessionSection(&out, currentSessionStatus())
+	if !strings.Contains(out.String(), "sync not active") {
+		t.Errorf("expected 'sync not active', got:\n%s", out.String())
+	}
+}
+
+func TestStatus_SessionLinkAndBackend(t *testing.T) {
+	cleanup := setupTestState(t, "claude-status-abc", "confab-status-xyz")
+	defer cleanup()
+
+	var out bytes.Buffer
+	printSessionSection(&out, sessionStatusForDir("/fake/cwd"))
+	got := out.String()
+	for _, want := range []string{
+		"Session:    claude-status-abc (claude-code)",
+		fmt.Sprintf("Daemon PID: %d\n", os.Getpid()),
+		"Confab ID:  confab-status-xyz",
+		"Backend:    " + testBackendURL,
+		"URL:        " + testBackendURL + "/sessions/confab-status-xyz",
+		"Last sync:  never",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("session section missing %q\noutput:\n%s", want, got)
+		}
+	}
+}
+
+func TestStatus_MissingStateIsNotAnError(t *testing.T) {
+	cleanup := setupTestState(t, "claude-status-abc", "confab-status-xyz")
+	defer cleanup()
+
+	if status := sessionStatusForDir("/some/other/dir"); status != nil {
+		t.Fatalf("expected no session for an unrelated dir, got %+v", status)
+	}
+	var out bytes.Buffer
+	printSessionSection(&out, nil)
+	if !strings.Contains(out.String(), "sync not active") {
+		t.Errorf("expected 'sync not active', got:\n%s", out.String())
+	}
+}
+
+func TestStatus_DeadDaemonState(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workDir := t.TempDir()
+
+	st := daemon.NewStateForProvider(provider.NameClaudeCode, "dead-session", "/tmp/t.jsonl", workDir, 0)
+	st.PID = 999999999 // no such process
+	if err := st.Save(); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+
+	status := sessionStatusForDir(workDir)
+	if status == nil || status.Running {
+		t.Fatalf("expected a non-running session, got %+v", status)
+	}
+	var out bytes.Buffer
+	printSessionSection(&out, status)
+	if !strings.Contains(out.String(), "Daemon PID: 999999999 (not running)") {
+		t.Errorf("expected dead PID to be flagged, got:\n%s", out.String())
+	}
+	var js bytes.Buffer
+	writeSessionStatusJSON(&js, status)
+	if !strings.Contains(js.String(), `"active": false`) {
+		t.Errorf("expected inactive JSON for a dead daemon, got:\n%s", js.String())
+	}
+}
+
+func TestStatus_JSON(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+
+	var inactive bytes.Buffer
+	if err := writeSessionStatusJSON(&inactive, currentSessionStatus()); err != nil {
+		t.Fatalf("writeSessionStatusJSON: %v", err)
+	}
+	if strings.TrimSpace(inactive.String()) != "{\n  \"active\": false\n}" {
+		t.Errorf("unexpected inactive JSON: %s", inactive.String())
+	}
+
+	writeRunningSessionState(t, workDir)
+	var active bytes.Buffer
+	if err := writeSessionStatusJSON(&active, currentSessionStatus()); err != nil {
+		t.Fatalf("writeSessionStatusJSON: %v", err)
+	}
+	var parsed struct {
+		Active  bool          `json:"active"`
+		Session sessionStatus `json:"session"`
+	}
+	if err := json.Unmarshal(active.Bytes(), &parsed); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, active.String())
+	}
+	if !parsed.Active || parsed.Session.SessionID != "status-session-1" {
+		t.Errorf("unexpected session: %+v", parsed)
+	}
+	if parsed.Session.FileLines["transcript.jsonl"] != 42 || parsed.Session.BytesUploaded != 3072 {
+		t.Errorf("unexpected progress: %+v", parsed.Session)
+	}
+	if parsed.Session.LastSyncAt == nil || parsed.Session.PID != os.Getpid() {
+		t.Errorf("expected last_sync_at and pid, got %+v", parsed.Session)
+	}
+}
+
+func TestFormatByteCount(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for n, want := range tests {
+		if got := formatByteCount(n); got != want {
+			t.Errorf("formatByteCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
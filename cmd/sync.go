@@ -1,7 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/daemon"
@@ -16,10 +23,19 @@ var syncCmd = &cobra.Command{
 during active provider sessions (Claude Code and Codex).
 
 The daemon watches transcript files and uploads new content
-to the backend every 30 seconds.
+to the backend every 30 seconds by default. Set sync_interval_ms and
+sync_jitter_ms (or pass --sync-interval / --sync-jitter to "sync start")
+to change the cadence, and sync_deterministic or --deterministic to sync
+exactly every interval.
 
 Note: The "sync start" and "sync stop" commands are aliases for
-"hook session-start" and "hook session-end" respectively.`,
+"hook session-start" and "hook session-end" respectively.
+
+For a one-shot sync from a script or CI step, without a daemon:
+
+  confab sync --once --provider claude-code --transcript <path> [--external-id <id>]
+
+which is the same as "confab sync once".`,
 }
 
 var syncStartCmd = &cobra.Command{
@@ -48,11 +64,28 @@ This command is an alias for 'confab hook session-end'.`,
 	},
 }
 
+var (
+	syncStatusFollow   bool
+	syncStatusInterval time.Duration
+)
+
 var syncStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show status of running sync daemons",
-	Long:  `Display information about all running sync daemons.`,
+	Long: `Display information about all running sync daemons.
+
+With --follow, redraws a live view of every daemon's progress (lines
+synced per file, last sync, backoff, errors) from its state file every
+--interval until interrupted with Ctrl-C.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if syncStatusFollow {
+			if syncStatusInterval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return followSyncStatus(ctx, os.Stdout, syncStatusInterval, stdoutIsTerminal())
+		}
 		return showSyncStatus()
 	},
 }
@@ -67,6 +100,11 @@ func init() {
 	// Old daemon processes may still call "sync start --bg-daemon".
 	syncStartCmd.Flags().StringVar(&bgDaemonData, "bg-daemon", "", "")
 	syncStartCmd.Flags().MarkHidden("bg-daemon")
+	syncStartCmd.Flags().StringVar(&daemonPIDFile, "pidfile", "", "Write the daemon's PID to this file (removed on shutdown)")
+	addDaemonTimingFlags(syncStartCmd)
+
+	syncStatusCmd.Flags().BoolVarP(&syncStatusFollow, "follow", "f", false, "Keep refreshing a live view until Ctrl-C")
+	syncStatusCmd.Flags().DurationVar(&syncStatusInterval, "interval", 2*time.Second, "Refresh interval for --follow")
 }
 
 // showSyncStatus displays all running sync daemons
@@ -84,11 +122,7 @@ func showSyncStatus() error {
 	fmt.Printf("Running sync daemons:\n\n")
 
 	for _, state := range states {
-		running := state.IsDaemonRunning()
-		status := "running"
-		if !running {
-			status = "not running (stale)"
-		}
+		status := daemonStatusLabel(state)
 
 		fmt.Printf("Session: %s\n", utils.TruncateSecret(state.ExternalID, 8, 0))
 		if state.Provider != "" {
@@ -103,3 +137,152 @@ func showSyncStatus() error {
 
 	return nil
 }
+
+// daemonStatusLabel is "running", "paused until <time>" (asked over the
+// control socket) or "not running (stale)" for a state whose process died.
+func daemonStatusLabel(state *daemon.State) string {
+	if !state.IsDaemonRunning() {
+		if state.Completed != nil {
+			return "completed"
+		}
+		return "not running (stale)"
+	}
+	if socketPath, err := daemon.GetSocketPathForProvider(state.Provider, state.ExternalID); err == nil {
+		// Daemons predating the control socket just show as running.
+		if resp, err := daemon.SendControl(socketPath, daemon.ControlStatus); err == nil && resp.Paused && resp.PausedUntil != nil {
+			return "paused until " + resp.PausedUntil.Format(time.Kitchen)
+		}
+	}
+	return "running"
+}
+
+// syncDashboard is one refresh of `sync status --follow`: every daemon's
+// state file as read at At.
+type syncDashboard struct {
+	At      time.Time
+	Daemons []syncDashboardEntry
+}
+
+// syncDashboardEntry is one daemon's progress, from the sync_progress its
+// state file carries (refreshed after every cycle).
+type syncDashboardEntry struct {
+	Provider      string
+	SessionID     string
+	Status        string
+	FileLines     map[string]int
+	TotalLines    int
+	BytesUploaded int64
+	LastSyncAt    *time.Time
+	// BackoffUntil is set only while a backend rate limit is in effect.
+	BackoffUntil      *time.Time
+	ConsecutiveErrors int
+	LastError         string
+}
+
+// collectSyncDashboard reads every daemon state file into a snapshot,
+// ordered by provider then session ID.
+func collectSyncDashboard(now time.Time) (*syncDashboard, error) {
+	states, err := daemon.ListAllStates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemon states: %w", err)
+	}
+	dash := &syncDashboard{At: now}
+	for _, state := range states {
+		entry := syncDashboardEntry{
+			Provider:  state.Provider,
+			SessionID: state.ExternalID,
+			Status:    daemonStatusLabel(state),
+			FileLines: map[string]int{},
+		}
+		if p := state.SyncProgress; p != nil {
+			for name, lines := range p.FileLines {
+				entry.FileLines[name] = lines
+				entry.TotalLines += lines
+			}
+			entry.BytesUploaded = p.BytesUploaded
+			if !p.LastSyncAt.IsZero() {
+				lastSync := p.LastSyncAt
+				entry.LastSyncAt = &lastSync
+			}
+			if p.BackoffUntil != nil && p.BackoffUntil.After(now) {
+				entry.BackoffUntil = p.BackoffUntil
+			}
+			entry.ConsecutiveErrors = p.ConsecutiveErrors
+			entry.LastError = p.LastError
+		}
+		dash.Daemons = append(dash.Daemons, entry)
+	}
+	sort.Slice(dash.Daemons, func(i, j int) bool {
+		a, b := dash.Daemons[i], dash.Daemons[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.SessionID < b.SessionID
+	})
+	return dash, nil
+}
+
+// renderSyncDashboard writes dash as one block per daemon.
+func renderSyncDashboard(w io.Writer, dash *syncDashboard) {
+	fmt.Fprintf(w, "Sync daemons at %s (Ctrl-C to exit)\n\n", dash.At.Local().Format(time.TimeOnly))
+	if len(dash.Daemons) == 0 {
+		fmt.Fprintln(w, "No sync daemons running")
+		return
+	}
+	for _, d := range dash.Daemons {
+		fmt.Fprintf(w, "Session: %s", utils.TruncateSecret(d.SessionID, 8, 0))
+		if d.Provider != "" {
+			fmt.Fprintf(w, " (%s)", d.Provider)
+		}
+		fmt.Fprintf(w, " — %s\n", d.Status)
+		if d.LastSyncAt != nil {
+			fmt.Fprintf(w, "  Last sync: %s (%s ago)\n", d.LastSyncAt.Local().Format(time.TimeOnly), dash.At.Sub(*d.LastSyncAt).Round(time.Second))
+		} else {
+			fmt.Fprintln(w, "  Last sync: never")
+		}
+		fmt.Fprintf(w, "  Uploaded:  %s, %d lines\n", formatByteCount(d.BytesUploaded), d.TotalLines)
+		if d.BackoffUntil != nil {
+			fmt.Fprintf(w, "  Backoff:   rate limited until %s\n", d.BackoffUntil.Local().Format(time.TimeOnly))
+		}
+		if d.ConsecutiveErrors > 0 {
+			fmt.Fprintf(w, "  Errors:    %d in a row, last: %s\n", d.ConsecutiveErrors, d.LastError)
+		}
+		if len(d.FileLines) > 0 {
+			names := make([]string, 0, len(d.FileLines))
+			for name := range d.FileLines {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "  FILE\tLINES SYNCED")
+			for _, name := range names {
+				fmt.Fprintf(tw, "  %s\t%d\n", name, d.FileLines[name])
+			}
+			tw.Flush()
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// followSyncStatus redraws the dashboard every interval until ctx is
+// done. On a terminal each refresh replaces the last; otherwise the
+// snapshots are appended.
+func followSyncStatus(ctx context.Context, w io.Writer, interval time.Duration, terminal bool) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		dash, err := collectSyncDashboard(time.Now())
+		if err != nil {
+			return err
+		}
+		if terminal {
+			fmt.Fprint(w, "\033[H\033[2J")
+		}
+		renderSyncDashboard(w, dash)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/ConfabulousDev/confab/pkg/sync"
+	"github.com/ConfabulousDev/confab/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncOnceProviderName string
+	syncOnceSessionID    string
+	syncOnceOutput       string
+
+	// `confab sync --once` spelling of the same command, for scripts.
+	syncOnceFlag       bool
+	syncOnceTranscript string
+)
+
+var syncOnceCmd = &cobra.Command{
+	Use:   "once <transcript-path>",
+	Short: "Run a single sync pass for a transcript",
+	Long: `Sync a transcript (and its agent files) once, the way the daemon does on
+each cycle: only lines the backend doesn't have yet are uploaded.
+
+With --output, nothing is sent to the backend. Instead every line, from
+line 1, is written as the chunk requests the daemon would send — one
+JSON ChunkRequest per line (NDJSON), after redaction — to the given file,
+or to stdout for "-". The summary then goes to stderr so stdout can be
+piped into another uploader.
+
+The session ID defaults to the transcript file name without its extension
+(the Claude Code layout); pass --session-id for other layouts.
+
+Examples:
+  confab sync once --provider claude-code ~/.claude/projects/p/abc123.jsonl
+  confab sync once --provider claude-code ~/.claude/projects/p/abc123.jsonl --output - | my-uploader`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return syncOnce(args[0])
+	},
+}
+
+// syncOnce runs `confab sync once` / `confab sync --once` for the
+// transcript at transcriptArg, with the sync once flags.
+func syncOnce(transcriptArg string) error {
+	transcriptPath, err := filepath.Abs(transcriptArg)
+	if err != nil {
+		return fmt.Errorf("invalid transcript path: %w", err)
+	}
+	if _, err := os.Stat(transcriptPath); err != nil {
+		return fmt.Errorf("cannot read transcript: %w", err)
+	}
+	sessionID := syncOnceSessionID
+	if sessionID == "" {
+		sessionID = strings.TrimSuffix(filepath.Base(transcriptPath), filepath.Ext(transcriptPath))
+	}
+	p, err := provider.Get(syncOnceProviderName)
+	if err != nil {
+		return err
+	}
+	engineCfg := sync.EngineConfig{
+		Provider:       p.Name(),
+		ExternalID:     sessionID,
+		TranscriptPath: transcriptPath,
+		CWD:            p.DefaultCWD(transcriptPath),
+	}
+
+	if syncOnceOutput != "" {
+		cfg, err := config.GetUploadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		out, err := openSyncOutput(syncOnceOutput)
+		if err != nil {
+			return err
+		}
+		err = runSyncOnceToOutput(out, os.Stderr, cfg, engineCfg)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+
+	defer NotifyIfUpdateAvailable()
+	cfg, err := config.EnsureAuthenticated()
+	if err != nil {
+		return err
+	}
+	return runSyncOnce(os.Stdout, cfg, engineCfg)
+}
+
+// syncRunOnce is the RunE of the bare `confab sync` command: with --once
+// it is `confab sync once` taking --transcript and --external-id as
+// flags; without it, it prints help.
+func syncRunOnce(cmd *cobra.Command, args []string) error {
+	if !syncOnceFlag {
+		return cmd.Help()
+	}
+	if syncOnceTranscript == "" {
+		return fmt.Errorf("--once requires --transcript")
+	}
+	if syncOnceProviderName == "" {
+		return fmt.Errorf("--once requires --provider")
+	}
+	return syncOnce(syncOnceTranscript)
+}
+
+// openSyncOutput resolves --output: "-" is stdout (left open on Close),
+// anything else a file created or truncated for the NDJSON stream.
+func openSyncOutput(output string) (io.WriteCloser, error) {
+	if output == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output: %w", err)
+	}
+	return f, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// runSyncOnce initializes against the backend and uploads whatever it is
+// missing, as one daemon cycle would.
+func runSyncOnce(w io.Writer, cfg *config.UploadConfig, engineCfg sync.EngineConfig) error {
+	engine, err := sync.New(cfg, engineCfg)
+	if err != nil {
+		return err
+	}
+	if err := engine.Init(); err != nil {
+		return fmt.Errorf("failed to initialize session: %w", err)
+	}
+	if err := setupOpencodeSaveEngine(engine, engineCfg.Provider); err != nil {
+		return err
+	}
+	chunks, err := engine.SyncAll()
+	if err != nil {
+		return fmt.Errorf("sync incomplete: %w", err)
+	}
+	fmt.Fprintf(w, "✓ Synced %d chunks to session %s\n", chunks, engine.SessionID())
+	stats := engine.GetSyncStats()
+	for _, name := range slices.Sorted(maps.Keys(stats.FileLines)) {
+		fmt.Fprintf(w, "  %s: %d lines synced\n", name, stats.FileLines[name])
+	}
+	return nil
+}
+
+// runSyncOnceToOutput drives the same engine against an NDJSON sink on
+// out, so the stream reflects redaction and chunking exactly as an upload
+// would. cfg only supplies redaction settings. The summary goes to status.
+func runSyncOnceToOutput(out, status io.Writer, cfg *config.UploadConfig, engineCfg sync.EngineConfig) error {
+	r, err := sync.NewRedactor(cfg)
+	if err != nil {
+		return err
+	}
+	engineCfg.BypassMarker = sync.BypassMarker(cfg)
+	sink := sync.NewNDJSONSink(out, engineCfg.ExternalID)
+	engine, err := sync.NewWithBackend(sink, r, engineCfg)
+	if err != nil {
+		return err
+	}
+	if err := engine.Init(); err != nil {
+		return err
+	}
+	if _, err := engine.SyncAll(); err != nil {
+		return err
+	}
+	chunks, lines := sink.Counts()
+	fmt.Fprintf(status, "Wrote %d lines in %d chunks for session %s\n", lines, chunks, utils.TruncateSecret(engineCfg.ExternalID, 8, 0))
+	return nil
+}
+
+func init() {
+	syncOnceCmd.Flags().StringVar(&syncOnceProviderName, "provider", "", "Provider the transcript belongs to (claude-code, codex, cursor, or opencode)")
+	syncOnceCmd.MarkFlagRequired("provider")
+	syncOnceCmd.Flags().StringVar(&syncOnceSessionID, "session-id", "", "Session ID to sync as (default: transcript file name without extension)")
+	syncOnceCmd.Flags().StringVar(&syncOnceOutput, "output", "", `Write chunk requests as NDJSON to this file ("-" for stdout) instead of uploading`)
+	syncCmd.AddCommand(syncOnceCmd)
+
+	syncCmd.Flags().BoolVar(&syncOnceFlag, "once", false, "Sync --transcript once and exit, without starting a daemon (same as 'sync once')")
+	syncCmd.Flags().StringVar(&syncOnceTranscript, "transcript", "", "Transcript to sync with --once")
+	syncCmd.Flags().StringVar(&syncOnceSessionID, "external-id", "", "Session ID to sync as with --once (default: transcript file name without extension)")
+	syncCmd.Flags().StringVar(&syncOnceProviderName, "provider", "", "Provider the --once transcript belongs to (claude-code, codex, cursor, or opencode)")
+	syncCmd.Flags().StringVar(&syncOnceOutput, "output", "", `With --once, write chunk requests as NDJSON to this file ("-" for stdout) instead of uploading`)
+	syncCmd.Args = cobra.NoArgs
+	syncCmd.RunE = syncRunOnce
+}
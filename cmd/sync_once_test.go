@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/ConfabulousDev/confab/pkg/sync"
+)
+
+func TestSyncOnce_OutputStdoutWritesRedactedChunks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "abc123.jsonl")
+	content := `{"type":"system"}` + "\n" + `{"type":"user","message":"token CUSTOM_SECRET"}` + "\n" + `{"type":"assistant"}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write transcript: %v", err)
+	}
+	useDefaults := false
+	cfg := &config.UploadConfig{Redaction: &config.RedactionConfig{
+		Enabled:            true,
+		UseDefaultPatterns: &useDefaults,
+		Patterns:           []config.RedactionPattern{{Name: "Custom", Pattern: `CUSTOM_[A-Z]+`, Type: "custom"}},
+	}}
+	engineCfg := sync.EngineConfig{
+		Provider:       provider.NameClaudeCode,
+		ExternalID:     "abc123",
+		TranscriptPath: path,
+		CWD:            t.TempDir(),
+	}
+
+	var status bytes.Buffer
+	stdout := captureStdout(t, func() {
+		out, err := openSyncOutput("-")
+		if err != nil {
+			t.Fatalf("openSyncOutput: %v", err)
+		}
+		if err := runSyncOnceToOutput(out, &status, cfg, engineCfg); err != nil {
+			t.Fatalf("runSyncOnceToOutput: %v", err)
+		}
+	})
+
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	for scanner.Scan() {
+		var chunk sync.ChunkRequest
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("stdout line is not a ChunkRequest: %v\n%s", err, scanner.Text())
+		}
+		if chunk.SessionID != "abc123" || chunk.FileName != "abc123.jsonl" || chunk.FirstLine != len(lines)+1 {
+			t.Errorf("unexpected chunk header: %+v", chunk)
+		}
+		lines = append(lines, chunk.Lines...)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("chunks cover %d lines, want all 3:\n%s", len(lines), stdout)
+	}
+	if strings.Contains(stdout, "CUSTOM_SECRET") {
+		t.Errorf("stdout contains unredacted secret:\n%s", stdout)
+	}
+	if !strings.Contains(status.String(), "Wrote 3 lines in 1 chunks") {
+		t.Errorf("unexpected summary: %q", status.String())
+	}
+}
+
+func TestSyncOnce_OutputFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	outPath := filepath.Join(t.TempDir(), "chunks.ndjson")
+	out, err := openSyncOutput(outPath)
+	if err != nil {
+		t.Fatalf("openSyncOutput: %v", err)
+	}
+	engineCfg := sync.EngineConfig{
+		Provider:       provider.NameClaudeCode,
+		ExternalID:     "abc123",
+		TranscriptPath: writeReplayTranscript(t),
+		CWD:            t.TempDir(),
+	}
+	if err := runSyncOnceToOutput(out, &bytes.Buffer{}, &config.UploadConfig{}, engineCfg); err != nil {
+		t.Fatalf("runSyncOnceToOutput: %v", err)
+	}
+	out.Close()
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	var chunk sync.ChunkRequest
+	if err := json.Unmarshal(bytes.TrimSpace(data), &chunk); err != nil || len(chunk.Lines) != 3 {
+		t.Errorf("output file = %s (err %v), want one 3-line chunk", data, err)
+	}
+}
+
+// syncOnceTestBackend has the transcript's first line already, and counts
+// the init and chunk requests it receives.
+type syncOnceTestBackend struct {
+	inits  int
+	chunks []sync.ChunkRequest
+}
+
+func (b *syncOnceTestBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/api/v1/sync/init":
+		b.inits++
+		json.NewEncoder(w).Encode(sync.InitResponse{
+			SessionID: "internal-once",
+			Files:     map[string]sync.FileState{"abc123.jsonl": {LastSyncedLine: 1}},
+		})
+	case "/api/v1/sync/chunk":
+		var req sync.ChunkRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		b.chunks = append(b.chunks, req)
+		json.NewEncoder(w).Encode(sync.ChunkResponse{LastSyncedLine: req.FirstLine + len(req.Lines) - 1})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSyncOnce_InitsOnceAndUploadsMissingLines(t *testing.T) {
+	backend := &syncOnceTestBackend{}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	path := writeReplayTranscript(t)
+	cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "cfb_once-test-key-12345678"}
+	engineCfg := sync.EngineConfig{
+		Provider:       provider.NameClaudeCode,
+		ExternalID:     "abc123",
+		TranscriptPath: path,
+		CWD:            t.TempDir(),
+	}
+
+	var out bytes.Buffer
+	if err := runSyncOnce(&out, cfg, engineCfg); err != nil {
+		t.Fatalf("runSyncOnce: %v", err)
+	}
+	if backend.inits != 1 {
+		t.Errorf("init requests = %d, want 1", backend.inits)
+	}
+	if len(backend.chunks) != 1 || backend.chunks[0].FirstLine != 2 || len(backend.chunks[0].Lines) != 2 {
+		t.Fatalf("chunks = %+v, want one chunk of lines 2-3", backend.chunks)
+	}
+	if !strings.Contains(out.String(), "Synced 1 chunks to session internal-once") ||
+		!strings.Contains(out.String(), "abc123.jsonl: 3 lines synced") {
+		t.Errorf("unexpected summary:\n%s", out.String())
+	}
+}
+
+func TestSyncOnceFlag_RequiresTranscript(t *testing.T) {
+	syncOnceFlag, syncOnceTranscript, syncOnceProviderName = true, "", provider.NameClaudeCode
+	defer func() { syncOnceFlag, syncOnceProviderName = false, "" }()
+
+	err := syncRunOnce(syncCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "--transcript") {
+		t.Errorf("sync --once without --transcript = %v, want an error naming it", err)
+	}
+}
+
+func TestSyncCmd_RejectsUnknownSubcommand(t *testing.T) {
+	if err := syncCmd.Args(syncCmd, []string{"strat"}); err == nil {
+		t.Error("sync strat accepted, want an unknown-command error")
+	}
+}
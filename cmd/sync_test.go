@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/ConfabulousDev/confab/pkg/daemon"
 )
 
@@ -121,6 +123,74 @@ func TestShowSyncStatus(t *testing.T) {
 	})
 }
 
+func TestCollectSyncDashboard(t *testing.T) {
+	setupSyncTestEnv(t)
+	now := time.Date(2026, 10, 1, 12, 0, 30, 0, time.UTC)
+	lastSync := now.Add(-30 * time.Second)
+	backoff := now.Add(time.Minute)
+	expired := now.Add(-time.Minute)
+
+	running := daemon.NewStateForProvider("codex", "session-b", "/tmp/b.jsonl", "/tmp", 0)
+	running.SyncProgress = &daemon.SyncProgress{
+		FileLines:         map[string]int{"transcript.jsonl": 40, "agent-x.jsonl": 2},
+		BytesUploaded:     2048,
+		LastSyncAt:        lastSync,
+		BackoffUntil:      &backoff,
+		ConsecutiveErrors: 2,
+		LastError:         "rate limited",
+	}
+	stale := daemon.NewStateForProvider("claude-code", "session-a", "/tmp/a.jsonl", "/tmp", 0)
+	stale.PID = 999999999
+	stale.SyncProgress = &daemon.SyncProgress{BackoffUntil: &expired}
+	for _, st := range []*daemon.State{running, stale} {
+		if err := st.Save(); err != nil {
+			t.Fatalf("save state: %v", err)
+		}
+	}
+
+	dash, err := collectSyncDashboard(now)
+	if err != nil {
+		t.Fatalf("collectSyncDashboard: %v", err)
+	}
+	if len(dash.Daemons) != 2 || dash.Daemons[0].SessionID != "session-a" || dash.Daemons[1].SessionID != "session-b" {
+		t.Fatalf("daemons = %+v, want session-a (claude-code) then session-b (codex)", dash.Daemons)
+	}
+	a, b := dash.Daemons[0], dash.Daemons[1]
+	if a.Status != "not running (stale)" || a.LastSyncAt != nil || a.BackoffUntil != nil || a.TotalLines != 0 {
+		t.Errorf("stale entry = %+v, want not running, never synced, expired backoff dropped", a)
+	}
+	if b.Status != "running" || b.TotalLines != 42 || b.FileLines["agent-x.jsonl"] != 2 || b.BytesUploaded != 2048 {
+		t.Errorf("running entry = %+v, want 42 lines over 2 files and 2048 bytes", b)
+	}
+	if b.LastSyncAt == nil || !b.LastSyncAt.Equal(lastSync) || b.BackoffUntil == nil || !b.BackoffUntil.Equal(backoff) {
+		t.Errorf("running entry times = last sync %v, backoff %v", b.LastSyncAt, b.BackoffUntil)
+	}
+	if b.ConsecutiveErrors != 2 || b.LastError != "rate limited" {
+		t.Errorf("running entry errors = %d %q", b.ConsecutiveErrors, b.LastError)
+	}
+
+	var out strings.Builder
+	renderSyncDashboard(&out, dash)
+	for _, want := range []string{"(30s ago)", "2.0 KiB, 42 lines", "Backoff:", "2 in a row, last: rate limited", "transcript.jsonl  40"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dashboard missing %q\n%s", want, out.String())
+		}
+	}
+}
+
+func TestFollowSyncStatusStopsOnCancel(t *testing.T) {
+	setupSyncTestEnv(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out strings.Builder
+	if err := followSyncStatus(ctx, &out, time.Hour, false); err != nil {
+		t.Fatalf("followSyncStatus: %v", err)
+	}
+	if strings.Count(out.String(), "Sync daemons at") != 1 || !strings.Contains(out.String(), "No sync daemons running") {
+		t.Errorf("expected a single snapshot before exiting, got:\n%s", out.String())
+	}
+}
+
 func TestSessionStartFromReader(t *testing.T) {
 	// Save and restore the original spawnDaemonFunc
 	origSpawnDaemon := spawnDaemonFunc
@@ -474,13 +544,15 @@ func TestRunDaemon(t *testing.T) {
 	})
 }
 
-func TestParseSyncEnvConfig(t *testing.T) {
+// TestResolveSyncTiming_Env covers the CONFAB_SYNC_* env layer on its own,
+// with no config file and no launch overrides.
+func TestResolveSyncTiming_Env(t *testing.T) {
 	t.Run("defaults when no env vars set", func(t *testing.T) {
 		// Ensure env vars are not set
 		t.Setenv("CONFAB_SYNC_INTERVAL_MS", "")
 		t.Setenv("CONFAB_SYNC_JITTER_MS", "")
 
-		interval, jitter := parseSyncEnvConfig()
+		interval, jitter := resolveSyncTiming(nil, &daemonLaunchInput{})
 
 		if interval != daemon.DefaultSyncInterval {
 			t.Errorf("expected interval %v, got %v", daemon.DefaultSyncInterval, interval)
@@ -494,7 +566,7 @@ func TestParseSyncEnvConfig(t *testing.T) {
 		t.Setenv("CONFAB_SYNC_INTERVAL_MS", "2000")
 		t.Setenv("CONFAB_SYNC_JITTER_MS", "")
 
-		interval, jitter := parseSyncEnvConfig()
+		interval, jitter := resolveSyncTiming(nil, &daemonLaunchInput{})
 
 		if interval != 2*time.Second {
 			t.Errorf("expected interval 2s, got %v", interval)
@@ -508,7 +580,7 @@ func TestParseSyncEnvConfig(t *testing.T) {
 		t.Setenv("CONFAB_SYNC_INTERVAL_MS", "")
 		t.Setenv("CONFAB_SYNC_JITTER_MS", "1000")
 
-		interval, jitter := parseSyncEnvConfig()
+		interval, jitter := resolveSyncTiming(nil, &daemonLaunchInput{})
 
 		if interval != daemon.DefaultSyncInterval {
 			t.Errorf("expected interval %v, got %v", daemon.DefaultSyncInterval, interval)
@@ -522,7 +594,7 @@ func TestParseSyncEnvConfig(t *testing.T) {
 		t.Setenv("CONFAB_SYNC_INTERVAL_MS", "500")
 		t.Setenv("CONFAB_SYNC_JITTER_MS", "100")
 
-		interval, jitter := parseSyncEnvConfig()
+		interval, jitter := resolveSyncTiming(nil, &daemonLaunchInput{})
 
 		if interval != 500*time.Millisecond {
 			t.Errorf("expected interval 500ms, got %v", interval)
@@ -536,7 +608,7 @@ func TestParseSyncEnvConfig(t *testing.T) {
 		t.Setenv("CONFAB_SYNC_INTERVAL_MS", "5000")
 		t.Setenv("CONFAB_SYNC_JITTER_MS", "0")
 
-		interval, jitter := parseSyncEnvConfig()
+		interval, jitter := resolveSyncTiming(nil, &daemonLaunchInput{})
 
 		if interval != 5*time.Second {
 			t.Errorf("expected interval 5s, got %v", interval)
@@ -550,7 +622,7 @@ func TestParseSyncEnvConfig(t *testing.T) {
 		t.Setenv("CONFAB_SYNC_INTERVAL_MS", "not-a-number")
 		t.Setenv("CONFAB_SYNC_JITTER_MS", "")
 
-		interval, jitter := parseSyncEnvConfig()
+		interval, jitter := resolveSyncTiming(nil, &daemonLaunchInput{})
 
 		if interval != daemon.DefaultSyncInterval {
 			t.Errorf("expected interval %v, got %v", daemon.DefaultSyncInterval, interval)
@@ -564,7 +636,7 @@ func TestParseSyncEnvConfig(t *testing.T) {
 		t.Setenv("CONFAB_SYNC_INTERVAL_MS", "2000")
 		t.Setenv("CONFAB_SYNC_JITTER_MS", "invalid")
 
-		interval, jitter := parseSyncEnvConfig()
+		interval, jitter := resolveSyncTiming(nil, &daemonLaunchInput{})
 
 		if interval != 2*time.Second {
 			t.Errorf("expected interval 2s, got %v", interval)
@@ -578,7 +650,7 @@ func TestParseSyncEnvConfig(t *testing.T) {
 		t.Setenv("CONFAB_SYNC_INTERVAL_MS", "-100")
 		t.Setenv("CONFAB_SYNC_JITTER_MS", "-50")
 
-		interval, jitter := parseSyncEnvConfig()
+		interval, jitter := resolveSyncTiming(nil, &daemonLaunchInput{})
 
 		if interval != daemon.DefaultSyncInterval {
 			t.Errorf("expected interval %v, got %v", daemon.DefaultSyncInterval, interval)
@@ -592,7 +664,7 @@ func TestParseSyncEnvConfig(t *testing.T) {
 		t.Setenv("CONFAB_SYNC_INTERVAL_MS", "0")
 		t.Setenv("CONFAB_SYNC_JITTER_MS", "")
 
-		interval, jitter := parseSyncEnvConfig()
+		interval, jitter := resolveSyncTiming(nil, &daemonLaunchInput{})
 
 		if interval != daemon.DefaultSyncInterval {
 			t.Errorf("expected interval %v, got %v", daemon.DefaultSyncInterval, interval)
@@ -603,6 +675,81 @@ func TestParseSyncEnvConfig(t *testing.T) {
 	})
 }
 
+func TestResolveSyncTiming(t *testing.T) {
+	cfg := &config.UploadConfig{SyncIntervalMS: 10_000, SyncJitterMS: 2_000}
+	for _, tt := range []struct {
+		name                     string
+		cfg                      *config.UploadConfig
+		envInterval, envJitter   string
+		launch                   daemonLaunchInput
+		wantInterval, wantJitter time.Duration
+	}{
+		{name: "defaults", wantInterval: daemon.DefaultSyncInterval},
+		{name: "config", cfg: cfg, wantInterval: 10 * time.Second, wantJitter: 2 * time.Second},
+		{name: "env overrides config", cfg: cfg, envInterval: "4000", envJitter: "0",
+			wantInterval: 4 * time.Second},
+		{name: "flags override env", cfg: cfg, envInterval: "4000", envJitter: "500",
+			launch:       daemonLaunchInput{SyncIntervalMS: 3000, SyncJitterMS: 1000},
+			wantInterval: 3 * time.Second, wantJitter: time.Second},
+		{name: "deterministic flag drops jitter", cfg: cfg, envJitter: "500",
+			launch:       daemonLaunchInput{SyncJitterMS: 1000, Deterministic: true},
+			wantInterval: 10 * time.Second},
+		{name: "deterministic config drops jitter",
+			cfg:          &config.UploadConfig{SyncJitterMS: 2_000, SyncDeterministic: true},
+			wantInterval: daemon.DefaultSyncInterval},
+		{name: "jitter capped at interval", cfg: cfg, envJitter: "60000",
+			wantInterval: 10 * time.Second, wantJitter: 10 * time.Second},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFAB_SYNC_INTERVAL_MS", tt.envInterval)
+			t.Setenv("CONFAB_SYNC_JITTER_MS", tt.envJitter)
+
+			interval, jitter := resolveSyncTiming(tt.cfg, &tt.launch)
+			if interval != tt.wantInterval || jitter != tt.wantJitter {
+				t.Errorf("resolveSyncTiming = (%v, %v), want (%v, %v)", interval, jitter, tt.wantInterval, tt.wantJitter)
+			}
+		})
+	}
+}
+
+func TestValidateDaemonTimingFlags(t *testing.T) {
+	defer func() { daemonSyncInterval, daemonSyncJitter = 0, 0 }()
+	for _, tt := range []struct {
+		interval, jitter time.Duration
+		wantErr          bool
+	}{
+		{0, 0, false},
+		{10 * time.Second, 2 * time.Second, false},
+		{0, 2 * time.Second, false},
+		{time.Second, 2 * time.Second, true},
+		{-time.Second, 0, true},
+		{0, -time.Second, true},
+	} {
+		daemonSyncInterval, daemonSyncJitter = tt.interval, tt.jitter
+		if err := validateDaemonTimingFlags(); (err != nil) != tt.wantErr {
+			t.Errorf("interval %v, jitter %v: err = %v, wantErr %v", tt.interval, tt.jitter, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseSyncWatchEnv(t *testing.T) {
+	for _, tt := range []struct {
+		value string
+		want  bool
+	}{
+		{"", false},
+		{"1", true},
+		{"true", true},
+		{"0", false},
+		{"yes", false},
+	} {
+		t.Setenv("CONFAB_SYNC_WATCH", tt.value)
+		if got := parseSyncWatchEnv(); got != tt.want {
+			t.Errorf("CONFAB_SYNC_WATCH=%q: parseSyncWatchEnv() = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
 // TestListAllStates verifies that daemon.ListAllStates works correctly.
 // This is an indirect test of showSyncStatus's dependency.
 func TestListAllStates(t *testing.T) {
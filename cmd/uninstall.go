@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/spf13/cobra"
+)
+
+var (
+	uninstallKeepConfig   bool
+	uninstallKeepSessions bool
+	uninstallConfirm      bool
+)
+
+// uninstallDaemonWait is how long uninstall waits for stopped daemons to
+// finish their final sync and exit before deleting their state.
+var uninstallDaemonWait = 10 * time.Second
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove confab's hooks, daemons and local data",
+	Long: `Undo everything setup did, so confab no longer runs:
+
+  - stops running sync daemons (each does a final sync first)
+  - removes confab hooks and skills from every provider (Claude Code:
+    SessionStart/End, PreToolUse, PostToolUse, UserPromptSubmit, Stop
+    and PreCompact; Codex, Cursor and OpenCode likewise)
+  - removes project-scope Claude Code hooks (.claude/settings.local.json)
+    from the current directory and every project a synced session ran in
+  - deletes the sync state directory (~/.confab/sync), unless
+    --keep-sessions
+  - deletes API keys and refresh tokens from the OS keychain and
+    ~/.confab/config.json, unless --keep-config
+
+Nothing is removed without --confirm; without it the steps are listed.
+A step that fails doesn't stop the others, and the summary says which
+steps completed. Sessions already uploaded stay on the backend. What is
+left on disk (settings backups, logs, the confab binary) is listed at
+the end.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, err := uninstallSteps(uninstallKeepConfig, uninstallKeepSessions)
+		if err != nil {
+			return err
+		}
+		if !uninstallConfirm {
+			printUninstallPlan(os.Stdout, steps)
+			return fmt.Errorf("nothing removed: re-run with --confirm to uninstall")
+		}
+		logger.Info("Running uninstall", "keep_config", uninstallKeepConfig, "keep_sessions", uninstallKeepSessions)
+		// Listed before the run: the sync state names the project dirs.
+		dirs := uninstallProjectDirs()
+		err = runUninstall(os.Stdout, steps)
+		printUninstallLeftovers(os.Stdout, uninstallLeftovers(dirs, uninstallKeepConfig, uninstallKeepSessions))
+		return err
+	},
+}
+
+// uninstallStep is one part of `confab uninstall`. run reports what it
+// removed; an error is recorded and the next step still runs.
+type uninstallStep struct {
+	name string // what the step does, e.g. "remove claude-code hooks"
+	run  func() (string, error)
+}
+
+// uninstallSteps lists the uninstall steps in order: daemons are stopped
+// before their state is deleted, and hooks removed before the config so no
+// new session starts a daemon midway.
+func uninstallSteps(keepConfig, keepSessions bool) ([]uninstallStep, error) {
+	providers, err := allOrNamedProviders("")
+	if err != nil {
+		return nil, err
+	}
+	steps := []uninstallStep{{name: "stop running sync daemons", run: stopAllDaemons}}
+	for _, p := range providers {
+		steps = append(steps,
+			uninstallStep{
+				name: fmt.Sprintf("remove %s hooks", p.Name()),
+				run: func() (string, error) {
+					path, err := p.UninstallHooks()
+					if err != nil {
+						return "", err
+					}
+					return fmt.Sprintf("removed %s hooks from %s", p.Name(), path), nil
+				},
+			},
+			uninstallStep{
+				name: fmt.Sprintf("remove %s skills", p.Name()),
+				run: func() (string, error) {
+					if err := p.UninstallSkills(); err != nil {
+						return "", err
+					}
+					return fmt.Sprintf("removed %s skills", p.Name()), nil
+				},
+			},
+		)
+	}
+	for _, path := range projectHookSettings(uninstallProjectDirs()) {
+		steps = append(steps, uninstallStep{
+			name: fmt.Sprintf("remove %s hooks from %s", provider.NameClaudeCode, path),
+			run: func() (string, error) {
+				if err := (provider.ClaudeCode{}).UninstallHooksAt(path); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("removed %s hooks from %s", provider.NameClaudeCode, path), nil
+			},
+		})
+	}
+	if !keepSessions {
+		steps = append(steps, uninstallStep{name: "delete sync state", run: deleteSyncState})
+	}
+	if !keepConfig {
+		steps = append(steps,
+			uninstallStep{name: "delete keychain entries", run: deleteKeyringSecrets},
+			uninstallStep{name: "delete config", run: deleteUploadConfig})
+	}
+	return steps, nil
+}
+
+// uninstallProjectDirs returns the directories that may hold project-scope
+// Claude Code hooks (`setup --scope project`): the working directory and
+// the project of every Claude Code session with sync state. Projects no
+// session was synced from can't be found.
+func uninstallProjectDirs() []string {
+	dirs := map[string]bool{}
+	if cwd, err := os.Getwd(); err == nil {
+		dirs[cwd] = true
+	}
+	states, err := daemon.ListAllStates()
+	if err != nil {
+		logger.Warnf("Failed to list daemon states for project hooks: %v", err)
+	}
+	for _, state := range states {
+		if state.CWD != "" && (state.Provider == "" || state.Provider == provider.NameClaudeCode) {
+			dirs[state.CWD] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(dirs))
+}
+
+// projectHookSettings returns the project settings files under dirs that
+// hold confab hooks.
+func projectHookSettings(dirs []string) []string {
+	var paths []string
+	for _, dir := range dirs {
+		path, err := config.ProjectSettingsPath(dir)
+		if err != nil {
+			continue
+		}
+		hooks, err := config.GetInstalledHooksAt(path)
+		if err != nil {
+			logger.Warnf("Failed to read project settings %s: %v", path, err)
+			continue
+		}
+		if slices.ContainsFunc(hooks, func(h config.InstalledHook) bool { return h.Confab }) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// printUninstallPlan lists what uninstall would do, for a run without
+// --confirm.
+func printUninstallPlan(w io.Writer, steps []uninstallStep) {
+	fmt.Fprintln(w, "confab uninstall would:")
+	for _, s := range steps {
+		fmt.Fprintf(w, "  - %s\n", s.name)
+	}
+	fmt.Fprintln(w)
+}
+
+// runUninstall runs every step, printing a ✓ or ✗ line for each, and
+// fails if any step did.
+func runUninstall(w io.Writer, steps []uninstallStep) error {
+	failed := 0
+	for _, s := range steps {
+		result, err := s.run()
+		if err != nil {
+			logger.Errorf("Uninstall step %q failed: %v", s.name, err)
+			fmt.Fprintf(w, "✗ %s: %v\n", s.name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(w, "✓ %s\n", result)
+	}
+	fmt.Fprintln(w)
+	if failed > 0 {
+		return fmt.Errorf("uninstall incomplete: %d of %d step(s) failed", failed, len(steps))
+	}
+	fmt.Fprintln(w, "✓ Confab uninstalled")
+	return nil
+}
+
+// stopAllDaemons signals every running sync daemon to stop and waits up to
+// uninstallDaemonWait for them to exit.
+func stopAllDaemons() (string, error) {
+	states, err := daemon.ListAllStates()
+	if err != nil {
+		return "", fmt.Errorf("failed to list daemon states: %w", err)
+	}
+	var running []*daemon.State
+	var firstErr error
+	for _, state := range states {
+		if !state.IsDaemonRunning() {
+			continue
+		}
+		if err := daemon.StopDaemonForProvider(state.Provider, state.ExternalID, nil); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("session %s: %w", state.ExternalID, err)
+			}
+			continue
+		}
+		running = append(running, state)
+	}
+
+	deadline := time.Now().Add(uninstallDaemonWait)
+	for _, state := range running {
+		for state.IsDaemonRunning() && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if state.IsDaemonRunning() && firstErr == nil {
+			firstErr = fmt.Errorf("daemon pid %d still running after %s", state.PID, uninstallDaemonWait)
+		}
+	}
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return fmt.Sprintf("stopped %d sync daemon(s)", len(running)), nil
+}
+
+// deleteSyncState removes the sync directory: daemon state files, inboxes
+// and control sockets.
+func deleteSyncState() (string, error) {
+	dir, err := daemon.GetSyncDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("failed to delete %s: %w", dir, err)
+	}
+	return fmt.Sprintf("deleted sync state in %s", dir), nil
+}
+
+// deleteKeyringSecrets removes the API keys and refresh tokens config.json
+// keeps in the OS keychain, before config.json itself goes.
+func deleteKeyringSecrets() (string, error) {
+	n, err := config.DeleteKeyringSecrets()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("deleted %d keychain secret(s)", n), nil
+}
+
+// deleteUploadConfig removes config.json (every profile in it).
+func deleteUploadConfig() (string, error) {
+	path, err := config.UploadConfigPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Sprintf("no config at %s", path), nil
+		}
+		return "", fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+	return fmt.Sprintf("deleted %s", path), nil
+}
+
+// uninstallLeftovers lists what uninstall leaves on disk: Claude settings
+// backups (user and project scope, for dirs), the log directory, the
+// config and sync state kept by --keep-config and --keep-sessions, and
+// the confab binary.
+func uninstallLeftovers(dirs []string, keepConfig, keepSessions bool) []string {
+	var items []string
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	var settingsFiles []string
+	if path, err := config.GetSettingsPath(); err == nil {
+		settingsFiles = append(settingsFiles, path)
+	}
+	for _, dir := range dirs {
+		if path, err := config.ProjectSettingsPath(dir); err == nil {
+			settingsFiles = append(settingsFiles, path)
+		}
+	}
+	for _, path := range settingsFiles {
+		if backup := config.SettingsBackupPath(path); exists(backup) {
+			items = append(items, backup+" (settings backup)")
+		}
+	}
+	if path, err := logger.FilePath(); err == nil && exists(filepath.Dir(path)) {
+		items = append(items, filepath.Dir(path)+" (logs)")
+	}
+	if path, err := config.UploadConfigPath(); err == nil && keepConfig && exists(path) {
+		items = append(items, path+" (--keep-config)")
+	}
+	if dir, err := daemon.GetSyncDir(); err == nil && keepSessions && exists(dir) {
+		items = append(items, dir+" (--keep-sessions)")
+	}
+	if path, err := os.Executable(); err == nil {
+		items = append(items, path+" (the confab binary)")
+	}
+	return items
+}
+
+// printUninstallLeftovers prints what uninstall left in place, for the
+// user to delete by hand.
+func printUninstallLeftovers(w io.Writer, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Left in place (delete by hand if you like):")
+	for _, item := range items {
+		fmt.Fprintf(w, "  - %s\n", item)
+	}
+}
+
+func init() {
+	uninstallCmd.Flags().BoolVar(&uninstallKeepConfig, "keep-config", false, "Keep ~/.confab/config.json (backend URL, API key, settings)")
+	uninstallCmd.Flags().BoolVar(&uninstallKeepSessions, "keep-sessions", false, "Keep daemon sync state in ~/.confab/sync")
+	uninstallCmd.Flags().BoolVar(&uninstallConfirm, "confirm", false, "Actually uninstall; without it the steps are only listed")
+	rootCmd.AddCommand(uninstallCmd)
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+)
+
+func TestRunUninstall_ContinuesPastFailures(t *testing.T) {
+	var ran []string
+	step := func(name string, err error) uninstallStep {
+		return uninstallStep{name: name, run: func() (string, error) {
+			ran = append(ran, name)
+			if err != nil {
+				return "", err
+			}
+			return name + " done", nil
+		}}
+	}
+	steps := []uninstallStep{
+		step("stop daemons", nil),
+		step("remove hooks", errors.New("settings.json is read-only")),
+		step("delete sync state", nil),
+		step("delete config", nil),
+	}
+
+	var out bytes.Buffer
+	err := runUninstall(&out, steps)
+	if err == nil || !strings.Contains(err.Error(), "1 of 4") {
+		t.Errorf("runUninstall error = %v, want 1 of 4 steps failed", err)
+	}
+	if len(ran) != 4 {
+		t.Errorf("ran %v, want every step despite the failure", ran)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"✓ stop daemons done",
+		"✗ remove hooks: settings.json is read-only",
+		"✓ delete sync state done",
+		"✓ delete config done",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Confab uninstalled") {
+		t.Errorf("output claims success after a failed step:\n%s", got)
+	}
+}
+
+func TestUninstallSteps_RemovesArtifacts(t *testing.T) {
+	tmpDir, configPath := setupSetupTestEnv(t, "https://confab.example.com")
+	t.Setenv(provider.CodexStateDirEnv, filepath.Join(tmpDir, ".codex"))
+	t.Setenv(provider.CursorStateDirEnv, filepath.Join(tmpDir, ".cursor"))
+	t.Setenv("CONFAB_OPENCODE_CONFIG_DIR", filepath.Join(tmpDir, ".config", "opencode"))
+	t.Setenv("CONFAB_DATA_DIR", filepath.Join(tmpDir, ".confab"))
+
+	claude, err := provider.Get(provider.NameClaudeCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := claude.InstallHooks(); err != nil {
+		t.Fatalf("InstallHooks: %v", err)
+	}
+	verifyHooksInstalled(t)
+	if err := os.WriteFile(configPath, []byte(`{"backend_url":"https://confab.example.com"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	state := daemon.NewStateForProvider(provider.NameClaudeCode, "session-1", "/tmp/transcript.jsonl", tmpDir, 0)
+	state.PID = 0 // not a running daemon: this test's own PID would get SIGTERM
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save state: %v", err)
+	}
+	syncDir, err := daemon.GetSyncDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// --keep-config --keep-sessions: only the hooks go.
+	steps, err := uninstallSteps(true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runUninstall(&bytes.Buffer{}, steps); err != nil {
+		t.Fatalf("runUninstall: %v", err)
+	}
+	settingsPath, _ := config.GetSettingsPath()
+	if data, _ := os.ReadFile(settingsPath); strings.Contains(string(data), "hook session-start") {
+		t.Errorf("hooks still installed:\n%s", data)
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		t.Errorf("--keep-config removed the config: %v", err)
+	}
+	if _, err := os.Stat(syncDir); err != nil {
+		t.Errorf("--keep-sessions removed the sync state: %v", err)
+	}
+
+	steps, err = uninstallSteps(false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := runUninstall(&out, steps); err != nil {
+		t.Fatalf("runUninstall: %v\n%s", err, out.String())
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("config not deleted: %v", err)
+	}
+	if _, err := os.Stat(syncDir); !os.IsNotExist(err) {
+		t.Errorf("sync state not deleted: %v", err)
+	}
+	if !strings.Contains(out.String(), "Confab uninstalled") {
+		t.Errorf("summary missing:\n%s", out.String())
+	}
+}
+
+func TestUninstall_ProjectHooksKeychainAndLeftovers(t *testing.T) {
+	tmpDir, configPath := setupSetupTestEnv(t, "")
+	t.Setenv("CONFAB_DATA_DIR", filepath.Join(tmpDir, ".confab"))
+	t.Setenv(provider.CodexStateDirEnv, filepath.Join(tmpDir, ".codex"))
+	t.Setenv(provider.CursorStateDirEnv, filepath.Join(tmpDir, ".cursor"))
+	t.Setenv("CONFAB_OPENCODE_CONFIG_DIR", filepath.Join(tmpDir, ".config", "opencode"))
+	keyring := setupTestKeyring{}
+	defer config.SetKeyringBackendForTest(keyring)()
+
+	// A project-scope install in a project uninstall isn't run from; a
+	// synced session there is how uninstall finds it.
+	project := filepath.Join(tmpDir, "project")
+	projectSettings, err := config.ProjectSettingsPath(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(projectSettings), 0755)
+	const hooks = `{"hooks": {"SessionStart": [{"matcher": "*", "hooks": [{"type": "command", "command": "confab hook session-start --provider claude-code"}]}]}}`
+	if err := os.WriteFile(projectSettings, []byte(hooks), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := daemon.NewStateForProvider(provider.NameClaudeCode, "session-1", "/tmp/transcript.jsonl", project, 0)
+	state.PID = 0
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save state: %v", err)
+	}
+
+	if err := config.SaveUploadConfig(&config.UploadConfig{
+		BackendURL: "https://confab.example.com",
+		APIKey:     "cfb_keychain-key-12345678",
+		UseKeyring: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if keyring["confab/api_key"] == "" {
+		t.Fatalf("keyring = %v, want the API key", keyring)
+	}
+	stored := len(keyring)
+
+	steps, err := uninstallSteps(false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := uninstallProjectDirs()
+	var out bytes.Buffer
+	if err := runUninstall(&out, steps); err != nil {
+		t.Fatalf("runUninstall: %v\n%s", err, out.String())
+	}
+	if data, _ := os.ReadFile(projectSettings); strings.Contains(string(data), "hook session-start") {
+		t.Errorf("project hooks still installed:\n%s", data)
+	}
+	if len(keyring) != 0 {
+		t.Errorf("keyring = %v, want the API key deleted", keyring)
+	}
+	if !strings.Contains(out.String(), fmt.Sprintf("deleted %d keychain secret(s)", stored)) {
+		t.Errorf("output missing the keychain step:\n%s", out.String())
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("config not deleted: %v", err)
+	}
+
+	out.Reset()
+	printUninstallLeftovers(&out, uninstallLeftovers(dirs, false, false))
+	if want := config.SettingsBackupPath(projectSettings) + " (settings backup)"; !strings.Contains(out.String(), want) {
+		t.Errorf("leftovers missing %q:\n%s", want, out.String())
+	}
+	if !strings.Contains(out.String(), "(the confab binary)") {
+		t.Errorf("leftovers missing the binary:\n%s", out.String())
+	}
+}
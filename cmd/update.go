@@ -41,16 +41,16 @@ Use --check to only check for updates without installing.`,
 }
 
 func runUpdate(cmd *cobra.Command, args []string) error {
-	logger.Info("Running update command (check=%v)", checkOnly)
+	logger.Info("Running update command", "check", checkOnly)
 
 	// Fetch latest version
 	latest, err := fetchLatestVersion()
 	if err != nil {
-		logger.Error("Failed to fetch latest version: %v", err)
+		logger.Errorf("Failed to fetch latest version: %v", err)
 		return fmt.Errorf("failed to check for updates: %w", err)
 	}
 
-	logger.Info("Current version: %s, Latest version: %s", version, latest)
+	logger.Infof("Current version: %s, Latest version: %s", version, latest)
 
 	if !isNewerVersion(cleanVersion(version), cleanVersion(latest)) {
 		fmt.Printf("confab is up to date (v%s)\n", cleanVersion(latest))
@@ -72,7 +72,7 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	if _, err := installLatestRelease(); err != nil {
-		logger.Error("Failed to install update: %v", err)
+		logger.Errorf("Failed to install update: %v", err)
 		return fmt.Errorf("update failed: %w", err)
 	}
 
@@ -321,23 +321,23 @@ func AutoUpdateIfNeeded() {
 
 	latest, err := fetchLatestVersion()
 	if err != nil {
-		logger.Debug("Auto-update check failed: %v", err)
+		logger.Debugf("Auto-update check failed: %v", err)
 		return
 	}
 
 	if !isNewerVersion(cleanVersion(version), cleanVersion(latest)) {
-		logger.Debug("No update needed (current=%s, latest=%s)", version, latest)
+		logger.Debug("No update needed", "current", version, "latest", latest)
 		writeLastCheckTime()
 		return
 	}
 
-	logger.Info("Update available: %s -> %s", version, latest)
+	logger.Infof("Update available: %s -> %s", version, latest)
 	fmt.Fprintf(os.Stderr, "Updating confab (%s -> %s)...\n", version, latest)
 
 	// Download and install new version
 	newBinary, err := installLatestRelease()
 	if err != nil {
-		logger.Error("Auto-update failed: %v", err)
+		logger.Errorf("Auto-update failed: %v", err)
 		fmt.Fprintf(os.Stderr, "Auto-update failed: %v\n", err)
 		return
 	}
@@ -346,10 +346,10 @@ func AutoUpdateIfNeeded() {
 
 	// Re-exec into new binary with same arguments
 	fmt.Fprintf(os.Stderr, "Update complete, restarting...\n\n")
-	logger.Info("Re-execing into new binary: %s", newBinary)
+	logger.Infof("Re-execing into new binary: %s", newBinary)
 
 	if err := syscall.Exec(newBinary, os.Args, os.Environ()); err != nil {
-		logger.Error("Failed to exec new binary: %v", err)
+		logger.Errorf("Failed to exec new binary: %v", err)
 		fmt.Fprintf(os.Stderr, "Failed to restart: %v\n", err)
 	}
 }
@@ -378,12 +378,12 @@ func shouldCheckForUpdate() bool {
 
 func getCheckTimePath() string {
 	// Use same directory as config in test environments
-	if testConfigPath := os.Getenv("CONFAB_CONFIG_PATH"); testConfigPath != "" {
+	if testConfigPath := os.Getenv(config.ConfigPathEnv); testConfigPath != "" {
 		return filepath.Join(filepath.Dir(testConfigPath), "last_update_check")
 	}
 	path, err := confabpath.Subpath("last_update_check")
 	if err != nil {
-		logger.Debug("Failed to get home directory for check time: %v", err)
+		logger.Debugf("Failed to get home directory for check time: %v", err)
 		return ""
 	}
 	return path
@@ -413,11 +413,11 @@ func writeLastCheckTime() {
 		return
 	}
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		logger.Debug("Failed to create check time directory: %v", err)
+		logger.Debugf("Failed to create check time directory: %v", err)
 		return
 	}
 	if err := os.WriteFile(path, []byte(time.Now().Format(time.RFC3339)), 0644); err != nil {
-		logger.Debug("Failed to write check time: %v", err)
+		logger.Debugf("Failed to write check time: %v", err)
 	}
 }
 
@@ -430,7 +430,7 @@ func NotifyIfUpdateAvailable() {
 
 	latest, err := fetchLatestVersion()
 	if err != nil {
-		logger.Debug("Update check failed: %v", err)
+		logger.Debugf("Update check failed: %v", err)
 		return
 	}
 
@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	confabhttp "github.com/ConfabulousDev/confab/pkg/http"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/ConfabulousDev/confab/pkg/sync"
+	"github.com/ConfabulousDev/confab/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyProviderName string
+	verifyConfigDir    string
+	verifyHashes       bool
+	verifyLines        bool
+	verifyFix          bool
+	verifySessionID    string
+	verifyTranscript   string
+	verifyConfabID     string
+)
+
+// verifyOptions selects the checks runVerify makes beyond line counts.
+type verifyOptions struct {
+	Hashes bool // compare SHA-256 of files whose line counts agree
+	Lines  bool // compare the transcript line by line by CRC32
+	Fix    bool // re-upload missing and mismatched lines (implies Lines)
+	// ConfabID is the backend session ID; empty looks it up in the
+	// session's daemon state. Unused with Fix, which goes through init.
+	ConfabID string
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [session-id]",
+	Short: "Check the backend holds exactly what the local transcript contains",
+	Long: `Compare a local session against the backend's sync state, per file.
+
+Line counts are compared for the transcript and every file the backend
+knows about. With --hashes, files whose line counts agree are also
+downloaded and compared by SHA-256 (the local side is redacted first, as
+it would be for an upload). Nothing is uploaded, and the backend's state is
+only read: the session's files are listed by its Confab session ID, taken
+from the local daemon state or --confab-id.
+
+With --lines, the transcript is also compared line by line: the CRC32 of
+each redacted local line is sent to the backend, and lines it lacks or
+holds with different content are listed. --fix re-uploads those lines,
+initializing the session for the upload as a sync does.
+
+The session is given by ID (as an argument or --session-id), or by
+--transcript, whose file name without extension is the session ID unless
+--session-id is also given.
+
+Exits non-zero if any file or line diverges, unless --fix repaired it.
+
+Examples:
+  confab verify --provider claude-code abc123de
+  confab verify --provider claude-code --hashes abc123de
+  confab verify --provider claude-code --lines --transcript ~/.claude/projects/p/abc123.jsonl
+  confab verify --provider claude-code --fix --session-id abc123de`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defer NotifyIfUpdateAvailable()
+		sessionArg := verifySessionID
+		if len(args) == 1 {
+			if sessionArg != "" && sessionArg != args[0] {
+				return fmt.Errorf("session ID given both as an argument and with --session-id")
+			}
+			sessionArg = args[0]
+		}
+		if sessionArg == "" && verifyTranscript == "" {
+			return fmt.Errorf("a session ID or --transcript is required")
+		}
+		cfg, p, err := resolveSaveContext(verifyProviderName, verifyConfigDir)
+		if err != nil {
+			return err
+		}
+		fullID, transcriptPath, err := resolveVerifySession(p, sessionArg, verifyTranscript)
+		if err != nil {
+			return err
+		}
+		opts := verifyOptions{Hashes: verifyHashes, Lines: verifyLines || verifyFix, Fix: verifyFix, ConfabID: verifyConfabID}
+		return runVerify(os.Stdout, cfg, p.Name(), fullID, transcriptPath, p.DefaultCWD(transcriptPath), opts)
+	},
+}
+
+// resolveVerifySession returns the session ID and transcript path to
+// verify. Without a transcript the session is looked up by (possibly
+// abbreviated) ID; with one, the ID defaults to its file name.
+func resolveVerifySession(p provider.Provider, sessionID, transcript string) (string, string, error) {
+	if transcript == "" {
+		return p.FindSessionByID(sessionID)
+	}
+	transcriptPath, err := filepath.Abs(transcript)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid transcript path: %w", err)
+	}
+	if _, err := os.Stat(transcriptPath); err != nil {
+		return "", "", fmt.Errorf("cannot read transcript: %w", err)
+	}
+	if sessionID == "" {
+		sessionID = strings.TrimSuffix(filepath.Base(transcriptPath), filepath.Ext(transcriptPath))
+	}
+	return sessionID, transcriptPath, nil
+}
+
+// runVerify loads the backend's per-file state for the session (see
+// initVerifyEngine), then prints a comparison table. With opts.Lines the
+// transcript's line-by-line comparison (and repair, with opts.Fix) comes
+// first, so the table shows the state after any repair. Returns an error
+// when anything diverges so the command exits non-zero.
+func runVerify(w io.Writer, cfg *config.UploadConfig, providerName, sessionID, transcriptPath, cwd string, opts verifyOptions) error {
+	engine, err := sync.New(cfg, sync.EngineConfig{
+		Provider:       providerName,
+		ExternalID:     sessionID,
+		TranscriptPath: transcriptPath,
+		CWD:            cwd,
+	})
+	if err != nil {
+		return err
+	}
+	client, err := confabhttp.NewClient(cfg, utils.DefaultHTTPTimeout)
+	if err != nil {
+		return err
+	}
+	if err := initVerifyEngine(engine, client, providerName, sessionID, opts); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Session %s\n\n", utils.TruncateSecret(sessionID, 8, 0))
+
+	linesDiverged := false
+	if opts.Lines {
+		if linesDiverged, err = verifyTranscriptLines(w, engine, opts.Fix); err != nil {
+			return err
+		}
+	}
+
+	var fetch sync.RemoteContentFunc
+	if opts.Hashes {
+		fetch = func(fileName string, dst io.Writer) error {
+			return client.GetRawToWriter(buildSessionFileDownloadPath(engine.SessionID(), fileName), dst)
+		}
+	}
+
+	results, err := engine.Verify(fetch)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE_NAME\tLOCAL\tBACKEND\tSTATUS")
+	diverged := 0
+	for _, v := range results {
+		local := fmt.Sprintf("%d", v.LocalLines)
+		if v.LocalMissing {
+			local = "missing"
+		}
+		if v.Diverged() {
+			diverged++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", v.FileName, local, v.BackendLines, verifyStatus(v))
+	}
+	tw.Flush()
+
+	if linesDiverged {
+		diverged++
+	}
+	if diverged > 0 {
+		return fmt.Errorf("verification failed: %d of %d files diverge", diverged, len(results))
+	}
+	fmt.Fprintf(w, "\n✓ All %d files match\n", len(results))
+	return nil
+}
+
+// initVerifyEngine loads the backend's per-file state into engine. A plain
+// check reads it from the session files endpoint, so verifying never
+// registers the session or changes its metadata; only --fix, which uploads
+// anyway, initializes it through sync/init.
+func initVerifyEngine(engine *sync.Engine, client *confabhttp.Client, providerName, sessionID string, opts verifyOptions) error {
+	if opts.Fix {
+		if err := engine.Init(); err != nil {
+			return fmt.Errorf("failed to initialize session: %w", err)
+		}
+		return nil
+	}
+
+	confabID := opts.ConfabID
+	if confabID == "" {
+		state, err := daemon.LoadStateForProvider(providerName, sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to load daemon state: %w", err)
+		}
+		if state == nil || state.ConfabSessionID == "" {
+			return fmt.Errorf("no Confab session ID recorded locally for %s; pass --confab-id (see 'confab session list')", utils.TruncateSecret(sessionID, 8, 0))
+		}
+		confabID = state.ConfabSessionID
+	}
+
+	var filesResp sessionFilesResponse
+	if err := client.Get(buildSessionFilesPath(confabID), &filesResp); err != nil {
+		return translateSessionErr(err, "list session files")
+	}
+	files := make(map[string]sync.FileState, len(filesResp.Files))
+	for _, f := range filesResp.Files {
+		files[f.FileName] = sync.FileState{LastSyncedLine: f.LastSyncedLine}
+	}
+	engine.InitFromState(&sync.InitResponse{SessionID: confabID, Files: files})
+	return nil
+}
+
+// verifyTranscriptLines prints the transcript's line-by-line comparison
+// with the backend. With fix, missing and mismatched lines are re-uploaded
+// and the engine re-reads the backend's state. Reports whether lines still
+// diverge.
+func verifyTranscriptLines(w io.Writer, engine *sync.Engine, fix bool) (bool, error) {
+	v, err := engine.VerifyLines("")
+	if err != nil {
+		return false, fmt.Errorf("line verification failed: %w", err)
+	}
+	if !v.Diverged() {
+		fmt.Fprintf(w, "%s: all %d lines match\n\n", v.FileName, v.LocalLines)
+		return false, nil
+	}
+
+	fmt.Fprintf(w, "%s: %d of %d lines diverge\n", v.FileName, len(v.Missing)+len(v.Mismatched), v.LocalLines)
+	if len(v.Missing) > 0 {
+		fmt.Fprintf(w, "  missing:    %s\n", formatLineRanges(sync.LineRanges(v.Missing)))
+	}
+	if len(v.Mismatched) > 0 {
+		fmt.Fprintf(w, "  mismatched: %s\n", formatLineRanges(sync.LineRanges(v.Mismatched)))
+	}
+	if !fix {
+		fmt.Fprintln(w)
+		return true, nil
+	}
+
+	sent, err := engine.RepairLines(v)
+	if err != nil {
+		return true, fmt.Errorf("repair failed after re-uploading %d lines: %w", sent, err)
+	}
+	fmt.Fprintf(w, "  re-uploaded %d lines\n\n", sent)
+	if err := engine.Reinit(); err != nil {
+		return false, fmt.Errorf("failed to refresh session state after repair: %w", err)
+	}
+	return false, nil
+}
+
+// formatLineRanges formats line ranges as "3, 7-9".
+func formatLineRanges(ranges [][2]int) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		if r[0] == r[1] {
+			parts[i] = fmt.Sprintf("%d", r[0])
+		} else {
+			parts[i] = fmt.Sprintf("%d-%d", r[0], r[1])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// verifyStatus describes one file's comparison result for the table.
+func verifyStatus(v sync.FileVerification) string {
+	switch {
+	case v.LocalMissing:
+		return "DIVERGED (missing locally)"
+	case v.LocalLines > v.BackendLines:
+		return fmt.Sprintf("DIVERGED (%d lines not synced)", v.LocalLines-v.BackendLines)
+	case v.LocalLines < v.BackendLines:
+		return fmt.Sprintf("DIVERGED (backend has %d extra lines)", v.BackendLines-v.LocalLines)
+	case v.LocalHash != v.BackendHash:
+		return "DIVERGED (content hash mismatch)"
+	case v.LocalHash != "":
+		return "ok (hash match)"
+	default:
+		return "ok"
+	}
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyProviderName, "provider", "", "Provider the session belongs to (claude-code, codex, cursor, or opencode)")
+	verifyCmd.MarkFlagRequired("provider")
+	verifyCmd.Flags().StringVar(&verifyConfigDir, "config-dir", "", "Verify against the backend bound to this config dir (requires --provider; claude-code only)")
+	verifyCmd.Flags().BoolVar(&verifyHashes, "hashes", false, "Also compare SHA-256 content hashes for files whose line counts match")
+	verifyCmd.Flags().BoolVar(&verifyLines, "lines", false, "Also compare the transcript line by line by CRC32 checksum")
+	verifyCmd.Flags().BoolVar(&verifyFix, "fix", false, "Re-upload missing and mismatched transcript lines (implies --lines)")
+	verifyCmd.Flags().StringVar(&verifySessionID, "session-id", "", "Session to verify (alternative to the argument)")
+	verifyCmd.Flags().StringVar(&verifyConfabID, "confab-id", "", "Backend (Confab) session ID, when the local daemon state doesn't record it")
+	verifyCmd.Flags().StringVar(&verifyTranscript, "transcript", "", "Local transcript to verify; its file name is the session ID unless --session-id is given")
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/daemon"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+	"github.com/ConfabulousDev/confab/pkg/sync"
+)
+
+// newVerifyTestServer reports lastSyncedLine for the transcript from the
+// session files endpoint (and, with allowInit, from init) and serves
+// content for the file download endpoint. Chunk uploads fail the test, as
+// does init unless allowed: verify must only read.
+func newVerifyTestServer(t *testing.T, lastSyncedLine int, content string, allowInit bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/sessions/internal-verify/files":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(sessionFilesResponse{Files: []sessionFile{
+				{FileName: "abc123.jsonl", FileType: "transcript", LastSyncedLine: lastSyncedLine},
+			}})
+		case "/api/v1/sync/init":
+			if !allowInit {
+				t.Errorf("verify must not init the session")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(sync.InitResponse{
+				SessionID: "internal-verify",
+				Files:     map[string]sync.FileState{"abc123.jsonl": {LastSyncedLine: lastSyncedLine}},
+			})
+		case "/api/v1/sessions/internal-verify/files/download":
+			w.Write([]byte(content))
+		case "/api/v1/sync/chunk":
+			t.Errorf("verify must not upload chunks")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVerify(t *testing.T) {
+	full := `{"type":"system"}` + "\n" + `{"type":"user"}` + "\n" + `{"type":"assistant"}` + "\n"
+
+	tests := []struct {
+		name           string
+		lastSyncedLine int
+		content        string
+		hashes         bool
+		wantErr        bool
+		wantOutput     string
+	}{
+		{"line counts agree", 3, "", false, false, "All 1 files match"},
+		{"hashes agree", 3, strings.TrimSuffix(full, "\n"), true, false, "ok (hash match)"},
+		{"backend behind", 2, "", false, true, "DIVERGED (1 lines not synced)"},
+		{"backend ahead", 5, "", false, true, "DIVERGED (backend has 2 extra lines)"},
+		{"content differs", 3, strings.Replace(full, "user", "USER", 1), true, true, "DIVERGED (content hash mismatch)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newVerifyTestServer(t, tt.lastSyncedLine, tt.content, false)
+			t.Setenv("HOME", t.TempDir())
+			cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "cfb_verify-test-key-12345678"}
+
+			var out bytes.Buffer
+			err := runVerify(&out, cfg, provider.NameClaudeCode, "abc123", writeReplayTranscript(t), t.TempDir(), verifyOptions{Hashes: tt.hashes, ConfabID: "internal-verify"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runVerify error = %v, wantErr %v\n%s", err, tt.wantErr, out.String())
+			}
+			if !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("output missing %q:\n%s", tt.wantOutput, out.String())
+			}
+		})
+	}
+}
+
+// newVerifyLinesTestServer serves a backend holding remote as the first
+// lines of the transcript: init reports len(remote) synced lines, the
+// checksum endpoint compares against remote, and uploaded chunks are
+// counted and applied to it.
+func newVerifyLinesTestServer(t *testing.T, remote []string) (*httptest.Server, *int) {
+	t.Helper()
+	chunks := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/sessions/internal-verify/files":
+			json.NewEncoder(w).Encode(sessionFilesResponse{Files: []sessionFile{
+				{FileName: "abc123.jsonl", FileType: "transcript", LastSyncedLine: len(remote)},
+			}})
+		case "/api/v1/sync/init":
+			json.NewEncoder(w).Encode(sync.InitResponse{
+				SessionID: "internal-verify",
+				Files:     map[string]sync.FileState{"abc123.jsonl": {LastSyncedLine: len(remote)}},
+			})
+		case "/api/v1/sessions/internal-verify/checksum":
+			var req sync.ChecksumRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decode checksum request: %v", err)
+			}
+			resp := sync.ChecksumResponse{Matches: []bool{}}
+			for i, l := range req.Lines {
+				if i < len(remote) {
+					resp.Matches = append(resp.Matches, crc32.ChecksumIEEE([]byte(remote[i])) == l.CRC32)
+				}
+			}
+			json.NewEncoder(w).Encode(resp)
+		case "/api/v1/sync/chunk":
+			var req sync.ChunkRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decode chunk request: %v", err)
+			}
+			chunks++
+			for i, line := range req.Lines {
+				n := req.FirstLine + i
+				for len(remote) < n {
+					remote = append(remote, "")
+				}
+				remote[n-1] = line
+			}
+			json.NewEncoder(w).Encode(sync.ChunkResponse{LastSyncedLine: req.FirstLine + len(req.Lines) - 1})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &chunks
+}
+
+func TestVerify_Lines(t *testing.T) {
+	remote := []string{`{"type":"system"}`, `{"type":"USER"}`}
+
+	t.Run("reports diverged lines", func(t *testing.T) {
+		server, chunks := newVerifyLinesTestServer(t, slices.Clone(remote))
+		t.Setenv("HOME", t.TempDir())
+		cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "cfb_verify-test-key-12345678"}
+
+		var out bytes.Buffer
+		err := runVerify(&out, cfg, provider.NameClaudeCode, "abc123", writeReplayTranscript(t), t.TempDir(), verifyOptions{Lines: true, ConfabID: "internal-verify"})
+		if err == nil {
+			t.Fatalf("expected divergence error\n%s", out.String())
+		}
+		for _, want := range []string{"2 of 3 lines diverge", "missing:    3", "mismatched: 2"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("output missing %q:\n%s", want, out.String())
+			}
+		}
+		if *chunks != 0 {
+			t.Errorf("uploaded %d chunks without --fix", *chunks)
+		}
+	})
+
+	t.Run("fix re-uploads them", func(t *testing.T) {
+		server, chunks := newVerifyLinesTestServer(t, slices.Clone(remote))
+		t.Setenv("HOME", t.TempDir())
+		cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "cfb_verify-test-key-12345678"}
+
+		var out bytes.Buffer
+		err := runVerify(&out, cfg, provider.NameClaudeCode, "abc123", writeReplayTranscript(t), t.TempDir(), verifyOptions{Lines: true, Fix: true})
+		if err != nil {
+			t.Fatalf("runVerify --fix: %v\n%s", err, out.String())
+		}
+		if *chunks != 1 || !strings.Contains(out.String(), "re-uploaded 2 lines") || !strings.Contains(out.String(), "All 1 files match") {
+			t.Errorf("expected lines 2-3 re-uploaded in one chunk and the session to match (chunks=%d):\n%s", *chunks, out.String())
+		}
+	})
+}
+
+func TestVerify_ConfabIDFromDaemonState(t *testing.T) {
+	server := newVerifyTestServer(t, 3, "", false)
+	t.Setenv("HOME", t.TempDir())
+	cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "cfb_verify-test-key-12345678"}
+	transcript := writeReplayTranscript(t)
+
+	var out bytes.Buffer
+	err := runVerify(&out, cfg, provider.NameClaudeCode, "abc123", transcript, t.TempDir(), verifyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "--confab-id") {
+		t.Fatalf("runVerify without daemon state = %v, want an error pointing at --confab-id", err)
+	}
+
+	st := daemon.NewStateForProvider(provider.NameClaudeCode, "abc123", transcript, t.TempDir(), 0)
+	st.ConfabSessionID = "internal-verify"
+	if err := st.Save(); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+	out.Reset()
+	if err := runVerify(&out, cfg, provider.NameClaudeCode, "abc123", transcript, t.TempDir(), verifyOptions{}); err != nil {
+		t.Fatalf("runVerify with daemon state: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "All 1 files match") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestResolveVerifySession_Transcript(t *testing.T) {
+	p, err := provider.Get(provider.NameClaudeCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript := writeReplayTranscript(t)
+
+	id, path, err := resolveVerifySession(p, "", transcript)
+	if err != nil || id != "abc123" || path != transcript {
+		t.Errorf("resolveVerifySession(transcript) = %q, %q, %v; want abc123, %q", id, path, err, transcript)
+	}
+	if id, _, _ := resolveVerifySession(p, "explicit-id", transcript); id != "explicit-id" {
+		t.Errorf("--session-id with --transcript resolved to %q, want explicit-id", id)
+	}
+	if _, _, err := resolveVerifySession(p, "", filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+		t.Error("expected an error for a missing transcript")
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/ConfabulousDev/confab/pkg/sync"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +18,7 @@ func SetVersionInfo(v, c, d string) {
 	version = v
 	commit = c
 	date = d
+	sync.SetClientVersion(v)
 }
 
 var versionCmd = &cobra.Command{
@@ -1,6 +1,7 @@
-// Package confabpath builds paths under the user's ~/.confab directory,
-// where all confab local state (config, sync state, inboxes, logs,
-// update timestamps) lives. Stdlib-only so any package can depend on it.
+// Package confabpath builds paths under confab's local directories: the
+// config directory (config.json, keyring.json) and the data directory
+// (sync state, inboxes, logs, update timestamps). Both default to
+// ~/.confab. Stdlib-only so any package can depend on it.
 package confabpath
 
 import (
@@ -9,8 +10,34 @@ import (
 	"path/filepath"
 )
 
-// Dir returns the absolute path of ~/.confab.
-func Dir() (string, error) {
+// Environment overrides, in priority order above the defaults.
+const (
+	// ConfigDirEnv overrides the config directory.
+	ConfigDirEnv = "CONFAB_CONFIG_DIR"
+	// DataDirEnv overrides the data directory, which otherwise follows
+	// the config directory.
+	DataDirEnv = "CONFAB_DATA_DIR"
+)
+
+// ConfigDir returns the directory holding confab's config, the first of:
+//
+//  1. $CONFAB_CONFIG_DIR
+//  2. $XDG_CONFIG_HOME/confab, if that directory exists
+//  3. ~/.confab
+//
+// The XDG directory is only used once it exists, so that merely having
+// XDG_CONFIG_HOME set (common on Linux desktops) doesn't move an existing
+// ~/.confab install out from under the user.
+func ConfigDir() (string, error) {
+	if dir := os.Getenv(ConfigDirEnv); dir != "" {
+		return dir, nil
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		dir := filepath.Join(xdg, "confab")
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
@@ -18,8 +45,26 @@ func Dir() (string, error) {
 	return filepath.Join(home, ".confab"), nil
 }
 
-// Subpath joins ~/.confab with one or more path segments. The first
-// segment is required — call Dir() if you need just ~/.confab itself.
+// ConfigSubpath joins ConfigDir with one or more path segments.
+func ConfigSubpath(first string, rest ...string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{dir, first}, rest...)...), nil
+}
+
+// Dir returns the data directory: $CONFAB_DATA_DIR, else ConfigDir
+// (~/.confab by default).
+func Dir() (string, error) {
+	if dir := os.Getenv(DataDirEnv); dir != "" {
+		return dir, nil
+	}
+	return ConfigDir()
+}
+
+// Subpath joins the data directory with one or more path segments. The
+// first segment is required — call Dir() if you need just the directory.
 func Subpath(first string, rest ...string) (string, error) {
 	dir, err := Dir()
 	if err != nil {
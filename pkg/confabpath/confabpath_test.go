@@ -12,6 +12,9 @@ func setHome(t *testing.T) string {
 	t.Helper()
 	tmpDir := t.TempDir()
 	t.Setenv("HOME", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(ConfigDirEnv, "")
+	t.Setenv(DataDirEnv, "")
 	return tmpDir
 }
 
@@ -72,3 +75,27 @@ func TestSubpath(t *testing.T) {
 		})
 	}
 }
+
+// Spec: CONFAB_CONFIG_DIR moves both config and data; CONFAB_DATA_DIR
+// moves only data.
+func TestConfigAndDataDirOverrides(t *testing.T) {
+	tmpDir := setHome(t)
+	configDir := filepath.Join(tmpDir, "cfg")
+	dataDir := filepath.Join(tmpDir, "data")
+
+	t.Setenv(ConfigDirEnv, configDir)
+	if got, _ := ConfigSubpath("config.json"); got != filepath.Join(configDir, "config.json") {
+		t.Errorf("ConfigSubpath = %q, want it under %q", got, configDir)
+	}
+	if got, _ := Subpath("logs"); got != filepath.Join(configDir, "logs") {
+		t.Errorf("Subpath = %q, want the data dir to follow %q", got, configDir)
+	}
+
+	t.Setenv(DataDirEnv, dataDir)
+	if got, _ := Dir(); got != dataDir {
+		t.Errorf("Dir() = %q, want %q", got, dataDir)
+	}
+	if got, _ := ConfigDir(); got != configDir {
+		t.Errorf("ConfigDir() = %q, want %q unaffected by %s", got, configDir, DataDirEnv)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/pathcanon"
 )
@@ -11,8 +12,11 @@ import (
 // Only the credentials vary per binding; redaction/log-level/auto-update are
 // read from the global top-level config.
 type BindingCreds struct {
-	BackendURL string `json:"backend_url"`
-	APIKey     string `json:"api_key"`
+	BackendURL   string    `json:"backend_url"`
+	APIKey       string    `json:"api_key"`
+	KeyName      string    `json:"key_name,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
 }
 
 // Binding identifies a (provider, config dir) backend target. The default
@@ -65,22 +69,36 @@ func GetUploadConfigFor(b Binding) (*UploadConfig, error) {
 	merged := *cfg
 	merged.BackendURL = creds.BackendURL
 	merged.APIKey = creds.APIKey
+	merged.KeyName = creds.KeyName
+	merged.RefreshToken = creds.RefreshToken
+	merged.ExpiresAt = creds.ExpiresAt
 	merged.Bindings = nil // the effective config is for a single backend
+	merged.binding = &b
 	return &merged, nil
 }
 
+// CredentialBinding returns the binding c's credentials belong to: the one
+// passed to GetUploadConfigFor, or the default binding.
+func (c *UploadConfig) CredentialBinding() Binding {
+	if c.binding == nil {
+		return Binding{IsDefault: true}
+	}
+	return *c.binding
+}
+
 // SetBindingCredentials writes backendURL/apiKey to the binding's slot: the
 // top-level fields for the default binding, or Bindings[provider][dir]
-// otherwise. Global fields are preserved.
+// otherwise. Any refresh token and key name for the old key are dropped.
+// Global fields are preserved.
 func SetBindingCredentials(b Binding, backendURL, apiKey string) error {
-	if err := validateBackendURL(backendURL); err != nil {
+	if err := ValidateBackendURL(backendURL); err != nil {
 		return fmt.Errorf("invalid backend URL: %w", err)
 	}
-	if err := validateAPIKey(apiKey); err != nil {
+	if err := ValidateAPIKey(apiKey); err != nil {
 		return fmt.Errorf("invalid API key: %w", err)
 	}
 
-	cfg, err := GetUploadConfig()
+	cfg, err := GetGlobalUploadConfig()
 	if err != nil {
 		return err
 	}
@@ -88,6 +106,9 @@ func SetBindingCredentials(b Binding, backendURL, apiKey string) error {
 	if b.IsDefault {
 		cfg.BackendURL = backendURL
 		cfg.APIKey = apiKey
+		cfg.KeyName = ""
+		cfg.RefreshToken = ""
+		cfg.ExpiresAt = time.Time{}
 	} else {
 		if cfg.Bindings == nil {
 			cfg.Bindings = map[string]map[string]BindingCreds{}
@@ -101,6 +122,59 @@ func SetBindingCredentials(b Binding, backendURL, apiKey string) error {
 	return SaveUploadConfig(cfg)
 }
 
+// SetBindingToken stores a refreshed access token in the binding's slot,
+// along with the (possibly rotated) refresh token and the access token's
+// expiry. The backend URL is kept. Fails if the binding has no credentials.
+func SetBindingToken(b Binding, accessToken, refreshToken string, expiresAt time.Time) error {
+	if err := ValidateAPIKey(accessToken); err != nil {
+		return fmt.Errorf("invalid access token: %w", err)
+	}
+
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		return err
+	}
+
+	if b.IsDefault {
+		cfg.APIKey = accessToken
+		cfg.RefreshToken = refreshToken
+		cfg.ExpiresAt = expiresAt
+	} else {
+		creds, ok := cfg.Bindings[b.Provider][b.Dir]
+		if !ok {
+			return fmt.Errorf("%w: %s at %s", ErrNoBinding, b.Provider, b.Dir)
+		}
+		creds.APIKey = accessToken
+		creds.RefreshToken = refreshToken
+		creds.ExpiresAt = expiresAt
+		cfg.Bindings[b.Provider][b.Dir] = creds
+	}
+
+	return SaveUploadConfig(cfg)
+}
+
+// SetBindingKeyName records the name the binding's API key was created
+// under. The binding must already have credentials.
+func SetBindingKeyName(b Binding, keyName string) error {
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		return err
+	}
+
+	if b.IsDefault {
+		cfg.KeyName = keyName
+	} else {
+		creds, ok := cfg.Bindings[b.Provider][b.Dir]
+		if !ok {
+			return fmt.Errorf("%w: %s at %s", ErrNoBinding, b.Provider, b.Dir)
+		}
+		creds.KeyName = keyName
+		cfg.Bindings[b.Provider][b.Dir] = creds
+	}
+
+	return SaveUploadConfig(cfg)
+}
+
 // EnsureAuthenticatedFor is GetUploadConfigFor plus a credential check,
 // mirroring EnsureAuthenticated for a specific binding.
 func EnsureAuthenticatedFor(b Binding) (*UploadConfig, error) {
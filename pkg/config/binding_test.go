@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // withTempConfig points CONFAB_CONFIG_PATH at a fresh temp file and returns
@@ -215,3 +216,75 @@ func TestHasBindingsShortCircuit(t *testing.T) {
 		t.Errorf("HasBindings(with binding) = %v,%v; want true,nil", has, err)
 	}
 }
+
+// TestSetBindingTokenAndCredentialReset: a refreshed token lands in the
+// binding's slot with its refresh token and expiry, is what
+// GetUploadConfigFor returns, and is dropped when new credentials replace it.
+func TestSetBindingTokenAndCredentialReset(t *testing.T) {
+	withTempConfig(t, &UploadConfig{BackendURL: "https://b0.example", APIKey: "cfb_default_key_000000"})
+	b := ResolveBinding("claude-code", t.TempDir(), t.TempDir())
+	if err := SetBindingCredentials(b, "https://b1.example", "cfb_custom_key_111111"); err != nil {
+		t.Fatalf("SetBindingCredentials: %v", err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := SetBindingToken(b, "cfb_refreshed_key_22222", "refresh-2", expiresAt); err != nil {
+		t.Fatalf("SetBindingToken: %v", err)
+	}
+	cfg, err := GetUploadConfigFor(b)
+	if err != nil {
+		t.Fatalf("GetUploadConfigFor: %v", err)
+	}
+	if cfg.APIKey != "cfb_refreshed_key_22222" || cfg.RefreshToken != "refresh-2" || !cfg.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("binding token = %q/%q/%v, want refreshed values", cfg.APIKey, cfg.RefreshToken, cfg.ExpiresAt)
+	}
+	if cfg.BackendURL != "https://b1.example" {
+		t.Errorf("backend_url = %q, want it kept", cfg.BackendURL)
+	}
+	if got := cfg.CredentialBinding(); got != b {
+		t.Errorf("CredentialBinding = %+v, want %+v", got, b)
+	}
+
+	if err := SetBindingToken(ResolveBinding("codex", t.TempDir(), t.TempDir()), "cfb_refreshed_key_22222", "", time.Time{}); !errors.Is(err, ErrNoBinding) {
+		t.Errorf("SetBindingToken on an unbound dir: err = %v, want ErrNoBinding", err)
+	}
+
+	if err := SetBindingCredentials(b, "https://b1.example", "cfb_custom_key_333333"); err != nil {
+		t.Fatalf("SetBindingCredentials: %v", err)
+	}
+	if cfg, _ = GetUploadConfigFor(b); cfg.RefreshToken != "" || !cfg.ExpiresAt.IsZero() {
+		t.Errorf("refresh token %q / expiry %v survived new credentials", cfg.RefreshToken, cfg.ExpiresAt)
+	}
+}
+
+func TestSetBindingKeyName(t *testing.T) {
+	withTempConfig(t, &UploadConfig{BackendURL: "https://b0.example", APIKey: "cfb_default_key_000000"})
+	def := Binding{IsDefault: true}
+	custom := ResolveBinding("claude-code", t.TempDir(), t.TempDir())
+	if err := SetBindingCredentials(custom, "https://b1.example", "cfb_custom_key_111111"); err != nil {
+		t.Fatalf("SetBindingCredentials: %v", err)
+	}
+
+	for b, name := range map[Binding]string{def: "devbox-alice", custom: "devbox-alice-work"} {
+		if err := SetBindingKeyName(b, name); err != nil {
+			t.Fatalf("SetBindingKeyName(%+v): %v", b, err)
+		}
+	}
+	for b, want := range map[Binding]string{def: "devbox-alice", custom: "devbox-alice-work"} {
+		if cfg, err := GetUploadConfigFor(b); err != nil || cfg.KeyName != want {
+			t.Errorf("key name for %+v = %q, %v; want %q", b, cfg.KeyName, err, want)
+		}
+	}
+
+	if err := SetBindingKeyName(ResolveBinding("codex", t.TempDir(), t.TempDir()), "x"); !errors.Is(err, ErrNoBinding) {
+		t.Errorf("SetBindingKeyName on an unbound dir: err = %v, want ErrNoBinding", err)
+	}
+
+	// A new key (e.g. login --api-key) drops the old key's name.
+	if err := SetBindingCredentials(def, "https://b0.example", "cfb_default_key_999999"); err != nil {
+		t.Fatalf("SetBindingCredentials: %v", err)
+	}
+	if cfg, _ := GetUploadConfig(); cfg.KeyName != "" {
+		t.Errorf("key name %q survived new credentials", cfg.KeyName)
+	}
+}
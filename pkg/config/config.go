@@ -7,7 +7,9 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/logger"
@@ -63,7 +65,7 @@ func (s *ClaudeSettings) GetEventHooks(eventName string) []any {
 	}
 	hooks, ok := hooksRaw.(map[string]any)
 	if !ok {
-		logger.Debug("settings.json: 'hooks' has unexpected type %T (expected object), skipping", hooksRaw)
+		logger.Debugf("settings.json: 'hooks' has unexpected type %T (expected object), skipping", hooksRaw)
 		return nil
 	}
 	eventHooksRaw, exists := hooks[eventName]
@@ -72,7 +74,7 @@ func (s *ClaudeSettings) GetEventHooks(eventName string) []any {
 	}
 	eventHooks, ok := eventHooksRaw.([]any)
 	if !ok {
-		logger.Debug("settings.json: hooks[%q] has unexpected type %T (expected array), skipping", eventName, eventHooksRaw)
+		logger.Debugf("settings.json: hooks[%q] has unexpected type %T (expected array), skipping", eventName, eventHooksRaw)
 		return nil
 	}
 	return eventHooks
@@ -104,12 +106,14 @@ func (s *ClaudeSettings) SetEventHooks(eventName string, matchers []any) error {
 
 // GetSettingsPath returns the path to the Claude settings file
 // (defaults to ~/.claude/settings.json, can be overridden with CONFAB_CLAUDE_DIR).
+// CONFAB_SETTINGS_SCOPE=project selects the working directory's
+// .claude/settings.local.json instead (see SettingsPathForScope).
 func GetSettingsPath() (string, error) {
-	stateDir, err := GetClaudeStateDir()
+	scope, err := SettingsScopeFromEnv()
 	if err != nil {
-		return "", fmt.Errorf("failed to get settings path: %w", err)
+		return "", err
 	}
-	return filepath.Join(stateDir, "settings.json"), nil
+	return SettingsPathForScope(scope)
 }
 
 // ReadSettings reads the default Claude settings file, preserving all fields.
@@ -139,6 +143,11 @@ func ReadSettingsAt(settingsPath string) (*ClaudeSettings, error) {
 
 	var raw map[string]any
 	if err := json.Unmarshal(data, &raw); err != nil {
+		if _, statErr := os.Stat(SettingsBackupPath(settingsPath)); statErr == nil {
+			//lint:ignore ST1005 "Claude" is a proper noun
+			return nil, fmt.Errorf("Claude settings file has invalid JSON (%s; the version before confab's last write is at %s): %w",
+				settingsPath, SettingsBackupPath(settingsPath), err)
+		}
 		//lint:ignore ST1005 "Claude" is a proper noun
 		return nil, fmt.Errorf("Claude settings file has invalid JSON (%s): %w", settingsPath, err)
 	}
@@ -154,6 +163,15 @@ func ReadSettingsAt(settingsPath string) (*ClaudeSettings, error) {
 // If expectedMtime is zero, mtime checking is skipped
 // If expectedMtime is non-zero, it checks mtime and returns error on mismatch
 func writeSettingsInternal(settingsPath string, settings *ClaudeSettings, expectedMtime time.Time) error {
+	return writeSettings(settingsPath, settings, expectedMtime, nil)
+}
+
+// writeSettings is writeSettingsInternal with afterRename, when non-nil,
+// run between the rename and the directory sync. Only tests pass one, to
+// die mid-write.
+func writeSettings(settingsPath string, settings *ClaudeSettings, expectedMtime time.Time, afterRename func()) error {
+	fsync := fsyncEnabled()
+
 	// Ensure directory exists
 	settingsDir := filepath.Dir(settingsPath)
 	if err := os.MkdirAll(settingsDir, 0700); err != nil {
@@ -174,12 +192,17 @@ func writeSettingsInternal(settingsPath string, settings *ClaudeSettings, expect
 	}
 	tempPath := tempFile.Name()
 
-	// Write data and close
+	// Write data, commit it to disk and close
 	if _, err := tempFile.Write(data); err != nil {
 		tempFile.Close()
 		os.Remove(tempPath)
 		return fmt.Errorf("failed to write temp settings: %w", err)
 	}
+	if err := syncFile(tempFile, fsync); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to sync temp settings: %w", err)
+	}
 	if err := tempFile.Close(); err != nil {
 		os.Remove(tempPath)
 		return fmt.Errorf("failed to close temp file: %w", err)
@@ -209,25 +232,189 @@ func writeSettingsInternal(settingsPath string, settings *ClaudeSettings, expect
 		}
 	}
 
+	// Keep the file we're about to replace, so a bad write (ours or a crash
+	// mid-update) can be undone with RestoreSettingsBackup. Abort rather
+	// than overwrite without one.
+	if err := backupSettings(settingsPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
 	// Atomic rename (this is where mtime gets updated by OS)
 	if err := os.Rename(tempPath, settingsPath); err != nil {
 		os.Remove(tempPath) // Clean up temp file on error
 		return fmt.Errorf("failed to rename temp settings: %w", err)
 	}
+	if afterRename != nil {
+		afterRename()
+	}
+	syncDir(settingsDir, fsync)
 
 	return nil
 }
 
+// fsyncEnabled reports the active profile's fsync option (see
+// UploadConfig.Fsync). It reads the config file directly rather than
+// through GetUploadConfig, which can itself write, and keeps fsync on when
+// the file can't be read.
+func fsyncEnabled() bool {
+	configPath, err := UploadConfigPath()
+	if err != nil {
+		return true
+	}
+	f, err := readConfigFile(configPath)
+	if err != nil {
+		return true
+	}
+	profile, err := f.activeProfile()
+	if err != nil {
+		return true
+	}
+	return f.profile(profile).IsFsyncEnabled()
+}
+
+// syncFile fsyncs f if fsync is on.
+func syncFile(f *os.File, fsync bool) error {
+	if !fsync {
+		return nil
+	}
+	return f.Sync()
+}
+
+// syncDir fsyncs dir if fsync is on, so a rename into it survives a power
+// loss. Only on Linux, where the directory entry isn't otherwise
+// guaranteed durable; a failure is logged, since the file itself is
+// already in place.
+func syncDir(dir string, fsync bool) {
+	if !fsync || runtime.GOOS != "linux" {
+		return
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		logger.Warnf("Failed to open %s to sync it: %v", dir, err)
+		return
+	}
+	defer d.Close()
+	if err := syscall.Fsync(int(d.Fd())); err != nil {
+		logger.Warnf("Failed to sync directory %s: %v", dir, err)
+	}
+}
+
+// settingsBackupSuffix names the copy of settings.json kept from before
+// confab's most recent write to it.
+const settingsBackupSuffix = ".confab-bak"
+
+// SettingsBackupPath returns where the backup of settingsPath is kept.
+func SettingsBackupPath(settingsPath string) string {
+	return settingsPath + settingsBackupSuffix
+}
+
+// backupSettings copies the current settings file to its backup path,
+// replacing any older backup. A missing settings file has nothing to back up.
+func backupSettings(settingsPath string) error {
+	data, err := os.ReadFile(settingsPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read settings for backup: %w", err)
+	}
+	if err := writeFileAtomic(SettingsBackupPath(settingsPath), data); err != nil {
+		return fmt.Errorf("failed to back up settings: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to path (mode 0600) via a temp file in the
+// same directory and a rename, so readers never see a partial file. With
+// the fsync option on, the data and the rename are flushed to disk like
+// settings writes.
+func writeFileAtomic(path string, data []byte) error {
+	fsync := fsyncEnabled()
+	tempFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	if err := syncFile(tempFile, fsync); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	if err := os.Chmod(tempPath, 0600); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	syncDir(filepath.Dir(path), fsync)
+	return nil
+}
+
+// RestoreSettingsBackup swaps the default Claude settings file with its
+// backup. See RestoreSettingsBackupAt.
+func RestoreSettingsBackup() error {
+	settingsPath, err := GetSettingsPath()
+	if err != nil {
+		return err
+	}
+	return RestoreSettingsBackupAt(settingsPath)
+}
+
+// RestoreSettingsBackupAt swaps the settings file at settingsPath with the
+// backup taken before confab's last write, e.g. when the current file no
+// longer parses. The replaced file becomes the backup, so restoring twice
+// undoes the restore. Fails without changing anything if there is no
+// backup or the backup isn't valid JSON.
+func RestoreSettingsBackupAt(settingsPath string) error {
+	backupPath := SettingsBackupPath(settingsPath)
+	backup, err := os.ReadFile(backupPath)
+	if err != nil {
+		return fmt.Errorf("no settings backup to restore: %w", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(backup, &raw); err != nil {
+		return fmt.Errorf("settings backup has invalid JSON (%s): %w", backupPath, err)
+	}
+
+	current, err := os.ReadFile(settingsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read settings: %w", err)
+	}
+	if err := writeFileAtomic(settingsPath, backup); err != nil {
+		return fmt.Errorf("failed to restore settings: %w", err)
+	}
+	if current != nil {
+		if err := writeFileAtomic(backupPath, current); err != nil {
+			return fmt.Errorf("restored settings, but failed to keep the replaced file as backup: %w", err)
+		}
+	}
+	logger.Info("Restored Claude settings from backup", "component", "config", "backup", backupPath)
+	return nil
+}
+
 // AtomicUpdateSettings performs a read-modify-write with optimistic locking.
-// It retries up to maxRetries times if the file is modified by another process.
 // The updateFn receives the current settings and should modify them in-place.
 //
-// Race condition limitation: The mtime check and rename are not truly atomic.
-// There's a small window (<1ms) between os.Stat() and os.Rename() where another
-// process could modify the file. The retry mechanism mitigates but does not
-// eliminate this race. For most use cases (CLI hook installation, infrequent
-// config changes), the retry logic provides sufficient reliability. If truly
-// atomic updates are required, file locking (flock) would be needed.
+// Concurrent confab processes are serialized by an advisory flock on
+// settings.json.lock, held across the whole read-modify-write. Writers that
+// don't take the lock (Claude Code itself, editors) are still caught by the
+// mtime check, retried up to maxRetries times. If the lock can't be used
+// (e.g. a filesystem without flock) or isn't released within
+// settingsLockTimeout, the update proceeds on the mtime check alone, whose
+// small window between os.Stat() and os.Rename() the retries mitigate but
+// don't close.
 func AtomicUpdateSettings(updateFn func(*ClaudeSettings) error) error {
 	settingsPath, err := GetSettingsPath()
 	if err != nil {
@@ -243,6 +430,12 @@ func AtomicUpdateSettingsAt(settingsPath string, updateFn func(*ClaudeSettings)
 	const maxRetries = 10
 	const baseRetryDelay = 5 * time.Millisecond
 
+	if unlock, err := lockSettings(settingsPath, settingsLockTimeout); err == nil {
+		defer unlock()
+	} else {
+		logger.Warnf("Updating %s without a lock: %v", settingsPath, err)
+	}
+
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		var mtime time.Time
 		if info, err := os.Stat(settingsPath); err == nil {
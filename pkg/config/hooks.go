@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// InstalledHook is one hook command from Claude settings.json, flattened
+// out of its event → matcher entry → hooks nesting.
+type InstalledHook struct {
+	Event string `json:"event"`
+	// Matcher is the entry's tool matcher ("Bash", ...); empty when the
+	// entry has none and so fires for every tool.
+	Matcher string `json:"matcher,omitempty"`
+	Type    string `json:"type"`
+	Command string `json:"command,omitempty"`
+	// Confab reports whether the hook runs the confab binary.
+	Confab bool `json:"confab"`
+}
+
+// IsConfabCommand reports whether a hook command invokes the confab binary,
+// by the basename of its first word (so "grep confab" doesn't count).
+func IsConfabCommand(command string) bool {
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return false
+	}
+	return filepath.Base(parts[0]) == "confab"
+}
+
+// GetInstalledHooks lists every hook in the default Claude settings file.
+func GetInstalledHooks() ([]InstalledHook, error) {
+	settingsPath, err := GetSettingsPath()
+	if err != nil {
+		return nil, err
+	}
+	return GetInstalledHooksAt(settingsPath)
+}
+
+// GetInstalledHooksAt lists every hook in the Claude settings file at
+// settingsPath, ordered by event name and then as they appear in the file.
+// A missing file has no hooks. Entries with an unexpected shape are
+// skipped, as the install path does, but a "hooks" field that isn't an
+// object is ErrHooksTypeMismatch.
+func GetInstalledHooksAt(settingsPath string) ([]InstalledHook, error) {
+	settings, err := ReadSettingsAt(settingsPath)
+	if err != nil {
+		return nil, err
+	}
+	hooksRaw, exists := settings.raw["hooks"]
+	if !exists {
+		return nil, nil
+	}
+	hooks, ok := hooksRaw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", settingsPath, ErrHooksTypeMismatch)
+	}
+
+	events := make([]string, 0, len(hooks))
+	for event := range hooks {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+
+	var installed []InstalledHook
+	for _, event := range events {
+		for _, entryAny := range settings.GetEventHooks(event) {
+			entry, ok := entryAny.(map[string]any)
+			if !ok {
+				continue
+			}
+			matcher, _ := entry["matcher"].(string)
+			list, _ := entry["hooks"].([]any)
+			for _, hookAny := range list {
+				hook, ok := hookAny.(map[string]any)
+				if !ok {
+					continue
+				}
+				hookType, _ := hook["type"].(string)
+				command, _ := hook["command"].(string)
+				installed = append(installed, InstalledHook{
+					Event:   event,
+					Matcher: matcher,
+					Type:    hookType,
+					Command: command,
+					Confab:  hookType == "command" && IsConfabCommand(command),
+				})
+			}
+		}
+	}
+	return installed, nil
+}
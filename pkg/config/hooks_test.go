@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetInstalledHooks(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CONFAB_CLAUDE_DIR", dir)
+	settings := `{
+  "model": "opus",
+  "hooks": {
+    "SessionStart": [
+      {"hooks": [{"type": "command", "command": "/usr/local/bin/confab hook session-start"}]}
+    ],
+    "PreToolUse": [
+      {"matcher": "Bash", "hooks": [
+        {"type": "command", "command": "confab hook pre-tool-use"},
+        {"type": "command", "command": "grep confab /tmp/log"}
+      ]},
+      {"matcher": "Edit", "hooks": [{"type": "prompt", "prompt": "check the edit"}]},
+      "not an entry"
+    ]
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dir, "settings.json"), []byte(settings), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := GetInstalledHooks()
+	if err != nil {
+		t.Fatalf("GetInstalledHooks: %v", err)
+	}
+	want := []InstalledHook{
+		{Event: "PreToolUse", Matcher: "Bash", Type: "command", Command: "confab hook pre-tool-use", Confab: true},
+		{Event: "PreToolUse", Matcher: "Bash", Type: "command", Command: "grep confab /tmp/log"},
+		{Event: "PreToolUse", Matcher: "Edit", Type: "prompt"},
+		{Event: "SessionStart", Type: "command", Command: "/usr/local/bin/confab hook session-start", Confab: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetInstalledHooks() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestGetInstalledHooksAt_MissingAndMalformed(t *testing.T) {
+	dir := t.TempDir()
+
+	got, err := GetInstalledHooksAt(filepath.Join(dir, "missing.json"))
+	if err != nil || got != nil {
+		t.Errorf("missing file: got %v, %v; want no hooks and no error", got, err)
+	}
+
+	path := filepath.Join(dir, "settings.json")
+	if err := os.WriteFile(path, []byte(`{"hooks": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetInstalledHooksAt(path); !errors.Is(err, ErrHooksTypeMismatch) {
+		t.Errorf("hooks array: err = %v, want ErrHooksTypeMismatch", err)
+	}
+}
@@ -0,0 +1,390 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/confabpath"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService is the service name API keys are stored under.
+const keyringService = "confab"
+
+// ErrKeyringNotFound is returned by KeyringBackend.Get when no value is
+// stored for the key.
+var ErrKeyringNotFound = errors.New("keyring: key not found")
+
+// KeyringBackend stores secrets outside config.json. Used for API keys and
+// refresh tokens when UploadConfig.UseKeyring is set.
+type KeyringBackend interface {
+	// Get returns the value stored for (service, key), or ErrKeyringNotFound.
+	Get(service, key string) (string, error)
+	// Set stores value for (service, key), replacing any existing value.
+	Set(service, key, value string) error
+	// Delete removes (service, key); ErrKeyringNotFound if there is none.
+	Delete(service, key string) error
+}
+
+// keyringBackend is the backend GetUploadConfig and SaveUploadConfig use.
+var keyringBackend = defaultKeyringBackend()
+
+// SetKeyringBackendForTest swaps the keyring backend. Returns a restore
+// function that callers should defer.
+// Intended for test code only — do not call from production.
+func SetKeyringBackendForTest(b KeyringBackend) (restore func()) {
+	prev := keyringBackend
+	keyringBackend = b
+	resetKeyringCache()
+	return func() {
+		keyringBackend = prev
+		resetKeyringCache()
+	}
+}
+
+// defaultKeyringBackend picks the OS keychain: the macOS Keychain or the
+// Secret Service over D-Bus on Linux, and a plain file elsewhere.
+func defaultKeyringBackend() KeyringBackend {
+	switch runtime.GOOS {
+	case "darwin", "linux":
+		return osKeyring{}
+	default:
+		return &fileKeyring{}
+	}
+}
+
+// osKeyring stores secrets in the OS keychain through go-keyring: generic
+// passwords in the macOS login keychain (written via `security -i` on
+// stdin, so the secret never appears in a process's argv) or the
+// freedesktop Secret Service (GNOME Keyring, KWallet) over D-Bus.
+type osKeyring struct{}
+
+func (osKeyring) Get(service, key string) (string, error) {
+	value, err := keyring.Get(service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrKeyringNotFound
+	}
+	return value, err
+}
+
+func (osKeyring) Set(service, key, value string) error {
+	return keyring.Set(service, key, value)
+}
+
+func (osKeyring) Delete(service, key string) error {
+	err := keyring.Delete(service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrKeyringNotFound
+	}
+	return err
+}
+
+// keyringCache memoizes keyring reads for the life of the process: each
+// lookup is a security subprocess or a D-Bus round trip, and a single
+// command loads the config many times. Entries are dropped whenever
+// config.json changes on disk (another confab process saving credentials
+// rewrites it) and updated by this process's own writes.
+var keyringCache struct {
+	mu      sync.Mutex
+	stamp   configStamp
+	secrets map[string]cachedSecret
+}
+
+// configStamp identifies a version of config.json.
+type configStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+type cachedSecret struct {
+	value string
+	err   error
+}
+
+func resetKeyringCache() {
+	keyringCache.mu.Lock()
+	defer keyringCache.mu.Unlock()
+	keyringCache.secrets = nil
+}
+
+// currentConfigStamp stats config.json; the zero stamp if it's missing.
+func currentConfigStamp() configStamp {
+	path, err := UploadConfigPath()
+	if err != nil {
+		return configStamp{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return configStamp{}
+	}
+	return configStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// keyringGet reads account from the keyring, through keyringCache.
+func keyringGet(account string) (string, error) {
+	stamp := currentConfigStamp()
+	keyringCache.mu.Lock()
+	defer keyringCache.mu.Unlock()
+	if keyringCache.secrets == nil || keyringCache.stamp != stamp {
+		keyringCache.secrets = map[string]cachedSecret{}
+		keyringCache.stamp = stamp
+	}
+	if cached, ok := keyringCache.secrets[account]; ok {
+		return cached.value, cached.err
+	}
+	value, err := keyringBackend.Get(keyringService, account)
+	keyringCache.secrets[account] = cachedSecret{value: value, err: err}
+	return value, err
+}
+
+// keyringSet writes account to the keyring. On failure the entry is
+// deleted instead: the secret then lives in config.json, and a stale
+// keyring copy would otherwise win over it on the next load.
+func keyringSet(account, value string) error {
+	err := keyringBackend.Set(keyringService, account, value)
+	if err != nil {
+		if delErr := keyringBackend.Delete(keyringService, account); delErr != nil && !errors.Is(delErr, ErrKeyringNotFound) {
+			logger.Warn("Could not remove stale keyring entry", "component", "config", "account", account, "error", delErr)
+		}
+	}
+	// The write changes config.json, so the stamp check would drop the
+	// cache anyway; drop it now in case the file write is skipped.
+	resetKeyringCache()
+	return err
+}
+
+// fileKeyring is the fallback backend for platforms without a supported
+// keychain: a JSON file of service -> key -> value, readable only by the
+// owner. It keeps secrets out of config.json, which users tend to share
+// when asking for help, but is not encrypted.
+type fileKeyring struct {
+	// path overrides the default ~/.confab/keyring.json (tests).
+	path string
+	mu   sync.Mutex
+}
+
+func (f *fileKeyring) filePath() (string, error) {
+	if f.path != "" {
+		return f.path, nil
+	}
+	return confabpath.ConfigSubpath("keyring.json")
+}
+
+func (f *fileKeyring) load() (map[string]map[string]string, string, error) {
+	path, err := f.filePath()
+	if err != nil {
+		return nil, "", err
+	}
+	entries := map[string]map[string]string{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return entries, path, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, "", fmt.Errorf("invalid keyring file %s: %w", path, err)
+	}
+	return entries, path, nil
+}
+
+func (f *fileKeyring) Get(service, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, _, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := entries[service][key]
+	if !ok {
+		return "", ErrKeyringNotFound
+	}
+	return value, nil
+}
+
+func (f *fileKeyring) Set(service, key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, path, err := f.load()
+	if err != nil {
+		return err
+	}
+	if entries[service] == nil {
+		entries[service] = map[string]string{}
+	}
+	entries[service][key] = value
+	return f.save(path, entries)
+}
+
+func (f *fileKeyring) Delete(service, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, path, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[service][key]; !ok {
+		return ErrKeyringNotFound
+	}
+	delete(entries[service], key)
+	return f.save(path, entries)
+}
+
+func (f *fileKeyring) save(path string, entries map[string]map[string]string) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// keyringAccount names the keyring entry for a secret ("api_key" or
+// "refresh_token") of the top-level credentials (provider == "") or of a
+// binding, in the top-level config (profile == "") or a named profile.
+func keyringAccount(profile, secret, provider, dir string) string {
+	account := secret
+	if provider != "" {
+		account += ":" + provider + ":" + dir
+	}
+	if profile != "" {
+		account = "profile:" + profile + ":" + account
+	}
+	return account
+}
+
+// loadKeyringSecrets replaces the API keys and refresh tokens read from
+// config.json for profile ("" = top-level) with the keyring's. A secret the keyring doesn't have, or
+// can't be read, keeps the file's value: that is where SaveUploadConfig
+// falls back to when the keyring is unavailable.
+func (c *UploadConfig) loadKeyringSecrets(profile string) {
+	load := func(account string, secret *string) {
+		value, err := keyringGet(account)
+		switch {
+		case err == nil:
+			*secret = value
+		case errors.Is(err, ErrKeyringNotFound):
+		default:
+			logger.Warn("Keyring unavailable, using secret from config file", "component", "config", "account", account, "error", err)
+		}
+	}
+	load(keyringAccount(profile, "api_key", "", ""), &c.APIKey)
+	load(keyringAccount(profile, "refresh_token", "", ""), &c.RefreshToken)
+	for provider, dirs := range c.Bindings {
+		for dir, creds := range dirs {
+			load(keyringAccount(profile, "api_key", provider, dir), &creds.APIKey)
+			load(keyringAccount(profile, "refresh_token", provider, dir), &creds.RefreshToken)
+			dirs[dir] = creds
+		}
+	}
+}
+
+// withoutKeyringSecrets stores c's API keys and refresh tokens in the
+// keyring under profile's accounts and returns a copy of c with them blanked, for writing to
+// config.json. A secret the keyring rejects stays in the copy, so
+// credentials are never lost, and its old keyring entry is removed so it
+// can't shadow the file's copy (see keyringSet).
+func (c *UploadConfig) withoutKeyringSecrets(profile string) *UploadConfig {
+	out := *c
+	store := func(account, secret string) string {
+		if err := keyringSet(account, secret); err != nil {
+			logger.Warn("Keyring unavailable, storing secret in config file", "component", "config", "account", account, "error", err)
+			return secret
+		}
+		return ""
+	}
+	out.APIKey = store(keyringAccount(profile, "api_key", "", ""), c.APIKey)
+	out.RefreshToken = store(keyringAccount(profile, "refresh_token", "", ""), c.RefreshToken)
+	if c.Bindings != nil {
+		out.Bindings = make(map[string]map[string]BindingCreds, len(c.Bindings))
+		for provider, dirs := range c.Bindings {
+			out.Bindings[provider] = make(map[string]BindingCreds, len(dirs))
+			for dir, creds := range dirs {
+				creds.APIKey = store(keyringAccount(profile, "api_key", provider, dir), creds.APIKey)
+				creds.RefreshToken = store(keyringAccount(profile, "refresh_token", provider, dir), creds.RefreshToken)
+				out.Bindings[provider][dir] = creds
+			}
+		}
+	}
+	return &out
+}
+
+// DeleteKeyringSecrets removes from the keyring every API key and refresh
+// token config.json can refer to, in all profiles, and returns how many
+// entries it deleted. Backs `confab uninstall`, which deletes config.json
+// afterwards. Entries that don't exist are skipped; other keyring errors
+// are returned only for profiles that use the keyring, since without it
+// the keyring may well be unavailable.
+func DeleteKeyringSecrets() (int, error) {
+	configPath, err := UploadConfigPath()
+	if err != nil {
+		return 0, err
+	}
+	f, err := readConfigFile(configPath)
+	if err != nil {
+		return 0, err
+	}
+	profiles := map[string]*UploadConfig{"": &f.UploadConfig}
+	for name, cfg := range f.Profiles {
+		if cfg != nil {
+			profiles[name] = cfg
+		}
+	}
+	defer resetKeyringCache()
+	deleted := 0
+	var firstErr error
+	for _, name := range slices.Sorted(maps.Keys(profiles)) {
+		cfg := profiles[name]
+		for _, account := range cfg.keyringAccounts(name) {
+			err := keyringBackend.Delete(keyringService, account)
+			switch {
+			case err == nil:
+				deleted++
+			case errors.Is(err, ErrKeyringNotFound):
+			case cfg.UseKeyring && firstErr == nil:
+				firstErr = fmt.Errorf("failed to delete keyring entry %s: %w", account, err)
+			}
+		}
+	}
+	return deleted, firstErr
+}
+
+// keyringAccounts lists the keyring accounts c's secrets are stored under
+// in profile: the top-level API key and refresh token and each binding's.
+func (c *UploadConfig) keyringAccounts(profile string) []string {
+	accounts := []string{
+		keyringAccount(profile, "api_key", "", ""),
+		keyringAccount(profile, "refresh_token", "", ""),
+	}
+	for _, provider := range slices.Sorted(maps.Keys(c.Bindings)) {
+		for _, dir := range slices.Sorted(maps.Keys(c.Bindings[provider])) {
+			accounts = append(accounts,
+				keyringAccount(profile, "api_key", provider, dir),
+				keyringAccount(profile, "refresh_token", provider, dir))
+		}
+	}
+	return accounts
+}
+
+// SetUseKeyring turns keyring storage for API keys on or off, moving the
+// stored keys accordingly, and preserves all other settings.
+func SetUseKeyring(useKeyring bool) error {
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.UseKeyring = useKeyring
+	return SaveUploadConfig(cfg)
+}
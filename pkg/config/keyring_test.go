@@ -0,0 +1,281 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// memKeyring is an in-memory KeyringBackend. If err is set, every call
+// fails with it, simulating an unavailable keychain; setErr fails only
+// writes. gets counts Get calls.
+type memKeyring struct {
+	items  map[string]string
+	err    error
+	setErr error
+	gets   int
+}
+
+func (m *memKeyring) Get(service, key string) (string, error) {
+	m.gets++
+	if m.err != nil {
+		return "", m.err
+	}
+	v, ok := m.items[service+"/"+key]
+	if !ok {
+		return "", ErrKeyringNotFound
+	}
+	return v, nil
+}
+
+func (m *memKeyring) Set(service, key, value string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.setErr != nil {
+		return m.setErr
+	}
+	if m.items == nil {
+		m.items = map[string]string{}
+	}
+	m.items[service+"/"+key] = value
+	return nil
+}
+
+func (m *memKeyring) Delete(service, key string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if _, ok := m.items[service+"/"+key]; !ok {
+		return ErrKeyringNotFound
+	}
+	delete(m.items, service+"/"+key)
+	return nil
+}
+
+func TestUseKeyring_RoundTrip(t *testing.T) {
+	kr := &memKeyring{}
+	defer SetKeyringBackendForTest(kr)()
+	path := withTempConfig(t, nil)
+
+	cfg := &UploadConfig{
+		BackendURL:   "https://confab.example.com",
+		APIKey:       "cfb_top-level-key-12345",
+		RefreshToken: "refresh-top-level",
+		UseKeyring:   true,
+		Bindings: map[string]map[string]BindingCreds{
+			"claude-code": {"/work/.claude": {BackendURL: "https://work.example.com", APIKey: "cfb_binding-key-12345"}},
+		},
+	}
+	if err := SaveUploadConfig(cfg); err != nil {
+		t.Fatalf("SaveUploadConfig: %v", err)
+	}
+	if cfg.APIKey != "cfb_top-level-key-12345" {
+		t.Errorf("SaveUploadConfig modified the caller's config: api_key = %q", cfg.APIKey)
+	}
+
+	raw := readRawConfig(t, path)
+	if got := raw["api_key"]; got != "" {
+		t.Errorf("config file still holds api_key: %v", got)
+	}
+	binding := raw["bindings"].(map[string]any)["claude-code"].(map[string]any)["/work/.claude"].(map[string]any)
+	if got := binding["api_key"]; got != nil && got != "" {
+		t.Errorf("config file still holds binding api_key: %v", got)
+	}
+	if got := kr.items["confab/api_key"]; got != "cfb_top-level-key-12345" {
+		t.Errorf("keyring api_key = %q", got)
+	}
+	if _, ok := raw["refresh_token"]; ok {
+		t.Errorf("config file still holds refresh_token: %v", raw["refresh_token"])
+	}
+
+	loaded, err := GetUploadConfig()
+	if err != nil {
+		t.Fatalf("GetUploadConfig: %v", err)
+	}
+	if loaded.APIKey != "cfb_top-level-key-12345" || loaded.RefreshToken != "refresh-top-level" {
+		t.Errorf("loaded api_key/refresh_token = %q/%q, want values from keyring", loaded.APIKey, loaded.RefreshToken)
+	}
+	if got := loaded.Bindings["claude-code"]["/work/.claude"].APIKey; got != "cfb_binding-key-12345" {
+		t.Errorf("loaded binding api_key = %q, want key from keyring", got)
+	}
+}
+
+func TestUseKeyring_FallsBackToFileWhenUnavailable(t *testing.T) {
+	defer SetKeyringBackendForTest(&memKeyring{err: errors.New("no secret service")})()
+	path := withTempConfig(t, nil)
+
+	if err := SaveUploadConfig(&UploadConfig{
+		BackendURL: "https://confab.example.com",
+		APIKey:     "cfb_fallback-key-12345",
+		UseKeyring: true,
+	}); err != nil {
+		t.Fatalf("SaveUploadConfig: %v", err)
+	}
+	if got := readRawConfig(t, path)["api_key"]; got != "cfb_fallback-key-12345" {
+		t.Errorf("config file api_key = %v, want fallback copy", got)
+	}
+
+	loaded, err := GetUploadConfig()
+	if err != nil {
+		t.Fatalf("GetUploadConfig: %v", err)
+	}
+	if loaded.APIKey != "cfb_fallback-key-12345" {
+		t.Errorf("loaded api_key = %q, want key from config file", loaded.APIKey)
+	}
+}
+
+// TestUseKeyring_FallbackRemovesStaleEntry verifies that when a write to
+// the keyring fails, the old keyring value is removed so the new key saved
+// to the file is the one loaded.
+func TestUseKeyring_FallbackRemovesStaleEntry(t *testing.T) {
+	kr := &memKeyring{items: map[string]string{"confab/api_key": "cfb_stale-key-12345"}, setErr: errors.New("keychain locked")}
+	defer SetKeyringBackendForTest(kr)()
+	withTempConfig(t, nil)
+
+	if err := SaveUploadConfig(&UploadConfig{
+		BackendURL: "https://confab.example.com",
+		APIKey:     "cfb_fresh-key-123456",
+		UseKeyring: true,
+	}); err != nil {
+		t.Fatalf("SaveUploadConfig: %v", err)
+	}
+	if _, ok := kr.items["confab/api_key"]; ok {
+		t.Error("stale keyring api_key was not removed")
+	}
+	loaded, err := GetUploadConfig()
+	if err != nil {
+		t.Fatalf("GetUploadConfig: %v", err)
+	}
+	if loaded.APIKey != "cfb_fresh-key-123456" {
+		t.Errorf("loaded api_key = %q, want the key saved to the file", loaded.APIKey)
+	}
+}
+
+// TestUseKeyring_ReadsKeyringOnce verifies repeated loads reuse the
+// keyring values until config.json changes.
+func TestUseKeyring_ReadsKeyringOnce(t *testing.T) {
+	kr := &memKeyring{}
+	defer SetKeyringBackendForTest(kr)()
+	withTempConfig(t, nil)
+
+	if err := SaveUploadConfig(&UploadConfig{BackendURL: "https://confab.example.com", APIKey: "cfb_cached-key-12345", UseKeyring: true}); err != nil {
+		t.Fatalf("SaveUploadConfig: %v", err)
+	}
+	for range 3 {
+		if _, err := GetUploadConfig(); err != nil {
+			t.Fatalf("GetUploadConfig: %v", err)
+		}
+	}
+	if kr.gets != 2 { // api_key and refresh_token
+		t.Errorf("keyring Get calls = %d after three loads, want 2", kr.gets)
+	}
+
+	if err := SaveUploadConfig(&UploadConfig{BackendURL: "https://confab.example.com", APIKey: "cfb_rotated-key-12345", UseKeyring: true}); err != nil {
+		t.Fatalf("SaveUploadConfig: %v", err)
+	}
+	loaded, err := GetUploadConfig()
+	if err != nil {
+		t.Fatalf("GetUploadConfig: %v", err)
+	}
+	if loaded.APIKey != "cfb_rotated-key-12345" {
+		t.Errorf("api_key after saving = %q, want the new key", loaded.APIKey)
+	}
+}
+
+func TestDeleteKeyringSecrets(t *testing.T) {
+	kr := &memKeyring{items: map[string]string{"confab/unrelated": "keep"}}
+	defer SetKeyringBackendForTest(kr)()
+	withTempConfig(t, nil)
+
+	if err := SaveUploadConfig(&UploadConfig{
+		BackendURL: "https://confab.example.com",
+		APIKey:     "cfb_top-level-key-12345",
+		UseKeyring: true,
+		Bindings: map[string]map[string]BindingCreds{
+			"claude-code": {"/work/.claude": {BackendURL: "https://work.example.com", APIKey: "cfb_binding-key-12345"}},
+		},
+	}); err != nil {
+		t.Fatalf("SaveUploadConfig: %v", err)
+	}
+	t.Setenv(ProfileEnv, "work")
+	if err := SaveUploadConfig(&UploadConfig{
+		BackendURL: "https://work.example.com",
+		APIKey:     "cfb_profile-key-123456",
+		UseKeyring: true,
+	}); err != nil {
+		t.Fatalf("SaveUploadConfig(work): %v", err)
+	}
+	stored := len(kr.items) - 1
+
+	deleted, err := DeleteKeyringSecrets()
+	if err != nil {
+		t.Fatalf("DeleteKeyringSecrets: %v", err)
+	}
+	if deleted != stored || len(kr.items) != 1 || kr.items["confab/unrelated"] != "keep" {
+		t.Errorf("deleted %d of %d; keyring left = %v, want only the unrelated entry", deleted, stored, kr.items)
+	}
+
+	// Nothing left to delete is not an error.
+	if deleted, err := DeleteKeyringSecrets(); err != nil || deleted != 0 {
+		t.Errorf("second DeleteKeyringSecrets = %d, %v; want 0, nil", deleted, err)
+	}
+}
+
+func TestSetUseKeyring_MovesExistingKey(t *testing.T) {
+	kr := &memKeyring{}
+	defer SetKeyringBackendForTest(kr)()
+	path := withTempConfig(t, &UploadConfig{BackendURL: "https://confab.example.com", APIKey: "cfb_existing-key-12345"})
+
+	if err := SetUseKeyring(true); err != nil {
+		t.Fatalf("SetUseKeyring(true): %v", err)
+	}
+	if got := readRawConfig(t, path)["api_key"]; got != "" {
+		t.Errorf("config file api_key = %v after enabling the keyring, want empty", got)
+	}
+	if got := kr.items["confab/api_key"]; got != "cfb_existing-key-12345" {
+		t.Errorf("keyring api_key = %q", got)
+	}
+
+	if err := SetUseKeyring(false); err != nil {
+		t.Fatalf("SetUseKeyring(false): %v", err)
+	}
+	if got := readRawConfig(t, path)["api_key"]; got != "cfb_existing-key-12345" {
+		t.Errorf("config file api_key = %v after disabling the keyring", got)
+	}
+}
+
+func TestFileKeyring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	kr := &fileKeyring{path: path}
+
+	if _, err := kr.Get("confab", "api_key"); !errors.Is(err, ErrKeyringNotFound) {
+		t.Fatalf("Get on missing file: err = %v, want ErrKeyringNotFound", err)
+	}
+	if err := kr.Set("confab", "api_key", "cfb_file-key-12345"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, err := kr.Get("confab", "api_key")
+	if err != nil || got != "cfb_file-key-12345" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("keyring file mode = %o, want 600", perm)
+	}
+
+	if err := kr.Delete("confab", "api_key"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := kr.Get("confab", "api_key"); !errors.Is(err, ErrKeyringNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrKeyringNotFound", err)
+	}
+	if err := kr.Delete("confab", "api_key"); !errors.Is(err, ErrKeyringNotFound) {
+		t.Errorf("second Delete: err = %v, want ErrKeyringNotFound", err)
+	}
+}
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+// configKeyType resolves a dotted key such as "redaction.enabled" against
+// UploadConfig's JSON field names and returns the named field's type. A
+// segment after a map-typed field (e.g. "static_metadata.team") names an
+// entry of that map.
+func configKeyType(key string) (reflect.Type, error) {
+	if key == "" {
+		return nil, fmt.Errorf("empty config key")
+	}
+	t := reflect.TypeOf(UploadConfig{})
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch {
+		case part == "":
+			return nil, fmt.Errorf("invalid config key %q", key)
+		case t.Kind() == reflect.Map:
+			t = t.Elem()
+		case t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()):
+			field, ok := jsonField(t, part)
+			if !ok {
+				return nil, fmt.Errorf("unknown config key %q", strings.Join(parts[:i+1], "."))
+			}
+			t = field.Type
+		default:
+			return nil, fmt.Errorf("unknown config key %q: %s is not an object", key, strings.Join(parts[:i], "."))
+		}
+	}
+	return t, nil
+}
+
+// jsonField finds the exported field of struct type t whose JSON name is
+// name.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// parseConfigValue converts the command-line value for a field of type t
+// to its JSON form: strings are taken verbatim, everything else (bools,
+// numbers, lists, objects) is parsed as JSON. "null" unsets a field that
+// may be absent, and comes back as nil.
+func parseConfigValue(t reflect.Type, value string) (any, error) {
+	v := reflect.New(t)
+	if t.Kind() == reflect.String {
+		v.Elem().SetString(value)
+	} else if err := json.Unmarshal([]byte(value), v.Interface()); err != nil {
+		return nil, fmt.Errorf("invalid value %q: want %s", value, describeConfigType(t))
+	}
+	data, err := json.Marshal(v.Elem().Interface())
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONValue(data)
+}
+
+// describeConfigType names the JSON form expected for type t, for errors.
+func describeConfigType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Slice:
+		return "a JSON array"
+	default:
+		return "a JSON object"
+	}
+}
+
+// decodeJSONValue decodes data keeping numbers as json.Number, so integers
+// round-trip exactly.
+func decodeJSONValue(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetConfigValue returns the value of a dotted key (e.g. "log_level",
+// "redaction.enabled") in the active profile, with keyring secrets filled
+// in. Strings are returned as-is and other values as compact JSON. A key
+// that is valid but unset is an error.
+func GetConfigValue(key string) (string, error) {
+	if _, err := configKeyType(key); err != nil {
+		return "", err
+	}
+	cfg, err := LoadUnvalidatedUploadConfig()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	value, err := decodeJSONValue(data)
+	if err != nil {
+		return "", err
+	}
+	for _, part := range strings.Split(key, ".") {
+		obj, _ := value.(map[string]any)
+		if value = obj[part]; value == nil {
+			return "", fmt.Errorf("%s is not set", key)
+		}
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// SetConfigValue sets a dotted key in the active profile of config.json to
+// value (see parseConfigValue), after checking that the resulting config
+// passes Validate (backend URL, API key format, redaction patterns, ...).
+// The file is edited as a generic JSON map, like ClaudeSettings, so keys
+// this version doesn't know and other profiles are written back
+// unchanged. With use_keyring, a new api_key or refresh_token goes to the
+// keyring, and toggling use_keyring moves the stored secrets.
+func SetConfigValue(key, value string) error {
+	t, err := configKeyType(key)
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(key, ".")
+	if parts[0] == "bindings" {
+		return fmt.Errorf("bindings are keyed by config directory; manage them with 'confab setup --config-dir'")
+	}
+	newValue, err := parseConfigValue(t, value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	configPath, err := UploadConfigPath()
+	if err != nil {
+		return err
+	}
+	raw, err := readRawConfigMap(configPath)
+	if err != nil {
+		return err
+	}
+	f, err := readConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	profile, err := f.activeProfile()
+	if err != nil {
+		return err
+	}
+	target := raw
+	if profile != "" {
+		target = childObject(childObject(raw, "profiles"), profile)
+	}
+	setPath(target, parts, newValue)
+
+	// Decode the edited profile to validate it, with the secrets the
+	// keyring held so the API key check sees the real values.
+	data, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	var cfg UploadConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	current := f.profile(profile)
+	if current.UseKeyring {
+		current.loadKeyringSecrets(profile)
+		if key != "api_key" {
+			cfg.APIKey = current.APIKey
+		}
+		if key != "refresh_token" {
+			cfg.RefreshToken = current.RefreshToken
+		}
+		cfg.Bindings = current.Bindings
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if key == "log_level" {
+		if _, err := ParseLogLevel(cfg.LogLevel); err != nil {
+			return err
+		}
+	}
+	if key == "log_format" {
+		if _, err := logger.ParseFormat(cfg.LogFormat); err != nil {
+			return err
+		}
+	}
+
+	if current.UseKeyring || cfg.UseKeyring {
+		placeSecrets(target, &cfg, profile)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	out, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.WriteFile(configPath, out, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// placeSecrets writes cfg's API keys and refresh tokens into the profile
+// object target: into the keyring (leaving them blank in the file) when
+// cfg.UseKeyring, else into the file itself.
+func placeSecrets(target map[string]any, cfg *UploadConfig, profile string) {
+	if cfg.UseKeyring {
+		cfg = cfg.withoutKeyringSecrets(profile)
+	}
+	target["api_key"] = cfg.APIKey
+	setOrDelete(target, "refresh_token", cfg.RefreshToken)
+	for provider, dirs := range cfg.Bindings {
+		for dir, creds := range dirs {
+			entry := childObject(childObject(childObject(target, "bindings"), provider), dir)
+			setOrDelete(entry, "api_key", creds.APIKey)
+			setOrDelete(entry, "refresh_token", creds.RefreshToken)
+		}
+	}
+}
+
+// readRawConfigMap reads config.json as a generic map; a missing file is
+// an empty one.
+func readRawConfigMap(configPath string) (map[string]any, error) {
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read confab config (%s): %w", configPath, err)
+	}
+	v, err := decodeJSONValue(data)
+	if err != nil {
+		return nil, fmt.Errorf("confab config has invalid JSON (%s): %w", configPath, err)
+	}
+	raw, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("confab config (%s) is not a JSON object", configPath)
+	}
+	return raw, nil
+}
+
+// childObject returns obj[key] as an object, replacing a missing or
+// non-object value with an empty one.
+func childObject(obj map[string]any, key string) map[string]any {
+	child, ok := obj[key].(map[string]any)
+	if !ok {
+		child = map[string]any{}
+		obj[key] = child
+	}
+	return child
+}
+
+// setPath sets the value at parts under obj, creating intermediate
+// objects; a nil value removes the key.
+func setPath(obj map[string]any, parts []string, value any) {
+	for _, part := range parts[:len(parts)-1] {
+		obj = childObject(obj, part)
+	}
+	last := parts[len(parts)-1]
+	if value == nil {
+		delete(obj, last)
+		return
+	}
+	obj[last] = value
+}
+
+// setOrDelete sets obj[key] to value, or removes it when value is empty.
+func setOrDelete(obj map[string]any, key, value string) {
+	if value == "" {
+		delete(obj, key)
+		return
+	}
+	obj[key] = value
+}
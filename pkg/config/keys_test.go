@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSetConfigValue_LogLevel(t *testing.T) {
+	path := withTempConfig(t, nil)
+	t.Setenv(ProfileEnv, "")
+	seed := `{"backend_url":"https://confab.example.com","api_key":"cfb_abcdefghijklmnopqrstuvwxyz0123456789ABCD","future_option":{"x":1}}`
+	if err := os.WriteFile(path, []byte(seed), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetConfigValue("log_level", "debug"); err != nil {
+		t.Fatalf("SetConfigValue: %v", err)
+	}
+	if got, err := GetConfigValue("log_level"); err != nil || got != "debug" {
+		t.Errorf("GetConfigValue(log_level) = %q, %v; want debug", got, err)
+	}
+	raw := readRawConfig(t, path)
+	if raw["log_level"] != "debug" {
+		t.Errorf("log_level on disk = %v", raw["log_level"])
+	}
+	if _, ok := raw["future_option"]; !ok {
+		t.Errorf("unknown field dropped: %v", raw)
+	}
+
+	if err := SetConfigValue("log_level", "loud"); err == nil {
+		t.Error("SetConfigValue accepted an invalid log level")
+	}
+	if err := SetConfigValue("backend_url", "ftp://confab.example.com"); err == nil {
+		t.Error("SetConfigValue accepted an invalid backend URL")
+	}
+	if err := SetConfigValue("api_key", "not-a-key"); err == nil {
+		t.Error("SetConfigValue accepted an invalid API key")
+	}
+	if err := SetConfigValue("no_such_key", "1"); err == nil {
+		t.Error("SetConfigValue accepted an unknown key")
+	}
+	if got, _ := GetConfigValue("log_level"); got != "debug" {
+		t.Errorf("rejected sets changed log_level to %q", got)
+	}
+	if _, err := GetConfigValue("compression"); err == nil {
+		t.Error("GetConfigValue of an unset key should fail")
+	}
+}
+
+func TestSetConfigValue_RedactionEnabledKeepsPatterns(t *testing.T) {
+	path := withTempConfig(t, &UploadConfig{
+		BackendURL: "https://confab.example.com",
+		Redaction: &RedactionConfig{
+			Enabled:  true,
+			Patterns: []RedactionPattern{{Name: "Ticket", Pattern: `TICKET-\d+`, Type: "ticket"}},
+		},
+	})
+	t.Setenv(ProfileEnv, "")
+
+	for _, enabled := range []string{"false", "true"} {
+		if err := SetConfigValue("redaction.enabled", enabled); err != nil {
+			t.Fatalf("SetConfigValue(redaction.enabled, %s): %v", enabled, err)
+		}
+		if got, err := GetConfigValue("redaction.enabled"); err != nil || got != enabled {
+			t.Errorf("redaction.enabled = %q, %v; want %s", got, err, enabled)
+		}
+		cfg, err := GetUploadConfig()
+		if err != nil {
+			t.Fatalf("GetUploadConfig: %v", err)
+		}
+		if len(cfg.Redaction.Patterns) != 1 || cfg.Redaction.Patterns[0].Name != "Ticket" {
+			t.Errorf("patterns after setting enabled=%s: %+v", enabled, cfg.Redaction.Patterns)
+		}
+	}
+	if err := SetConfigValue("redaction.enabled", "maybe"); err == nil {
+		t.Error("SetConfigValue accepted a non-bool for redaction.enabled")
+	}
+
+	// A named profile gets its own value; the top level is untouched.
+	t.Setenv(ProfileEnv, "work")
+	if err := SetConfigValue("redaction.enabled", "false"); err != nil {
+		t.Fatalf("SetConfigValue in profile: %v", err)
+	}
+	raw := readRawConfig(t, path)
+	if raw["redaction"].(map[string]any)["enabled"] != true {
+		t.Errorf("profile set changed the top-level redaction: %v", raw["redaction"])
+	}
+	work := raw["profiles"].(map[string]any)["work"].(map[string]any)
+	if work["redaction"].(map[string]any)["enabled"] != false {
+		t.Errorf("work profile redaction = %v", work["redaction"])
+	}
+}
+
+func TestSetConfigValue_APIKeyGoesToKeyring(t *testing.T) {
+	kr := &memKeyring{}
+	defer SetKeyringBackendForTest(kr)()
+	path := withTempConfig(t, &UploadConfig{BackendURL: "https://confab.example.com", UseKeyring: true})
+	t.Setenv(ProfileEnv, "")
+
+	const key = "cfb_abcdefghijklmnopqrstuvwxyz0123456789ABCD"
+	if err := SetConfigValue("api_key", key); err != nil {
+		t.Fatalf("SetConfigValue: %v", err)
+	}
+	if got := kr.items["confab/api_key"]; got != key {
+		t.Errorf("keyring api_key = %q", got)
+	}
+	if raw := readRawConfig(t, path); raw["api_key"] != "" {
+		t.Errorf("api_key written to config.json: %v", raw["api_key"])
+	}
+	if got, err := GetConfigValue("api_key"); err != nil || got != key {
+		t.Errorf("GetConfigValue(api_key) = %q, %v", got, err)
+	}
+}
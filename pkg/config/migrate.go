@@ -0,0 +1,332 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CurrentConfigVersion is the config.json schema version this build
+// writes. Bump it together with a new migrationRegistry entry.
+const CurrentConfigVersion = 1
+
+// Migration upgrades config.json, and the Claude hooks installed
+// alongside it, from one schema version to the next.
+type Migration struct {
+	From, To    int
+	Description string
+	// Config rewrites the raw config.json document in place; nil when
+	// the step only touches hooks.
+	Config func(raw map[string]any) error
+	// Hooks rewrites confab hook commands in Claude settings and returns
+	// how many it changed; nil when the step only touches the config.
+	Hooks func(settings *ClaudeSettings) (int, error)
+}
+
+// migrationRegistry maps each schema version to the migration that
+// upgrades from it.
+var migrationRegistry = map[int]Migration{
+	0: {
+		From:        0,
+		To:          1,
+		Description: `rewrite "confab save" and "confab sync start/stop" hooks as "confab hook session-start/session-end"`,
+		Hooks:       migrateLegacySyncHooks,
+	},
+}
+
+// ConfigVersion returns the schema version recorded in a raw config.json
+// document: its "version" field, 0 when absent.
+func ConfigVersion(raw map[string]any) (int, error) {
+	v, ok := raw["version"]
+	if !ok {
+		return 0, nil
+	}
+	switch n := v.(type) {
+	case int:
+		if n >= 0 {
+			return n, nil
+		}
+	case float64: // as decoded from JSON
+		if n >= 0 && n == math.Trunc(n) {
+			return int(n), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid config version %v: must be a non-negative integer", v)
+}
+
+// PendingMigrations returns, in order, the migrations that take a config
+// at schema version from up to CurrentConfigVersion. A version newer than
+// this build understands is an error.
+func PendingMigrations(from int) ([]Migration, error) {
+	if from > CurrentConfigVersion {
+		return nil, fmt.Errorf("config schema version %d is newer than this confab supports (%d); update confab", from, CurrentConfigVersion)
+	}
+	var pending []Migration
+	for v := from; v < CurrentConfigVersion; {
+		m, ok := migrationRegistry[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration registered from config schema version %d", v)
+		}
+		pending = append(pending, m)
+		v = m.To
+	}
+	return pending, nil
+}
+
+// MigrateConfig applies the Config step of every pending migration to a
+// copy of raw and stamps it with CurrentConfigVersion. Returns the
+// migrated document and the version raw was at; raw itself is left
+// unchanged.
+func MigrateConfig(raw map[string]any) (map[string]any, int, error) {
+	from, err := ConfigVersion(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+	pending, err := PendingMigrations(from)
+	if err != nil {
+		return nil, from, err
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, from, fmt.Errorf("failed to copy config: %w", err)
+	}
+	migrated := map[string]any{}
+	if err := json.Unmarshal(data, &migrated); err != nil {
+		return nil, from, fmt.Errorf("failed to copy config: %w", err)
+	}
+	for _, m := range pending {
+		if m.Config == nil {
+			continue
+		}
+		if err := m.Config(migrated); err != nil {
+			return nil, from, fmt.Errorf("migration v%d → v%d: %w", m.From, m.To, err)
+		}
+	}
+	migrated["version"] = CurrentConfigVersion
+	return migrated, from, nil
+}
+
+// MigrateHooks applies the Hooks step of every migration pending from
+// schema version from to settings, returning how many hooks changed.
+func MigrateHooks(settings *ClaudeSettings, from int) (int, error) {
+	pending, err := PendingMigrations(from)
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, m := range pending {
+		if m.Hooks == nil {
+			continue
+		}
+		n, err := m.Hooks(settings)
+		changed += n
+		if err != nil {
+			return changed, fmt.Errorf("migration v%d → v%d: %w", m.From, m.To, err)
+		}
+	}
+	return changed, nil
+}
+
+// MigrationPending reports whether a migration from schema version from
+// would change anything: a Config step, or a Hooks step that rewrites a
+// hook in the Claude settings at settingsPath.
+func MigrationPending(from int, settingsPath string) (bool, error) {
+	pending, err := PendingMigrations(from)
+	if err != nil || len(pending) == 0 {
+		return false, err
+	}
+	for _, m := range pending {
+		if m.Config != nil {
+			return true, nil
+		}
+	}
+	settings, err := ReadSettingsAt(settingsPath)
+	if err != nil {
+		return false, err
+	}
+	changed, err := MigrateHooks(settings, from)
+	return changed > 0, err
+}
+
+// ReadRawConfig reads config.json as a raw document, without keyring
+// secrets, profile selection or validation. A missing file is nil.
+func ReadRawConfig() (map[string]any, error) {
+	configPath, err := UploadConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read confab config (%s): %w", configPath, err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("confab config has invalid JSON (%s): %w", configPath, err)
+	}
+	if raw == nil {
+		raw = map[string]any{}
+	}
+	return raw, nil
+}
+
+// WriteRawConfig atomically replaces config.json with raw, which must
+// still parse as a config file.
+func WriteRawConfig(raw map[string]any) error {
+	configPath, err := UploadConfigPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := json.Unmarshal(data, &configFile{}); err != nil {
+		return fmt.Errorf("migrated config does not parse: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(configPath, data); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// sessionHookCommands are the "hook" subcommands run by each Claude
+// session event.
+var sessionHookCommands = map[string]string{
+	"SessionStart": "session-start",
+	"SessionEnd":   "session-end",
+}
+
+// legacySyncCommands maps the confab subcommands older releases installed
+// as Claude session hooks to the "hook" subcommand that replaced each.
+var legacySyncCommands = []struct {
+	event  string
+	args   []string
+	hookTo string
+}{
+	{"SessionStart", []string{"sync", "start"}, "session-start"},
+	{"SessionEnd", []string{"sync", "stop"}, "session-end"},
+	// Before the sync daemon, SessionEnd uploaded the whole session.
+	{"SessionEnd", []string{"save"}, "session-end"},
+}
+
+// migrateLegacySyncHooks rewrites the session hooks of older releases as
+// "confab hook session-start/session-end --provider claude-code", keeping
+// any further arguments. A legacy hook whose event already has the new
+// command is dropped instead. A "confab save" session-end hook had no
+// daemon to stop, so the session-start hook it needs is added as well.
+func migrateLegacySyncHooks(settings *ClaudeSettings) (int, error) {
+	changed := 0
+	binary := ""
+	for _, event := range []string{"SessionStart", "SessionEnd"} {
+		entries := settings.GetEventHooks(event)
+		if len(entries) == 0 {
+			continue
+		}
+		hasNew := hasConfabHookCommand(entries, "hook "+sessionHookCommands[event])
+		var kept []any
+		for _, entryAny := range entries {
+			entry, ok := entryAny.(map[string]any)
+			list, _ := entry["hooks"].([]any)
+			if !ok || list == nil {
+				kept = append(kept, entryAny)
+				continue
+			}
+			var hooks []any
+			for _, hookAny := range list {
+				hook, _ := hookAny.(map[string]any)
+				cmd, _ := hook["command"].(string)
+				rewritten, ok := rewriteLegacySyncCommand(event, cmd)
+				if !ok || hook["type"] != "command" {
+					hooks = append(hooks, hookAny)
+					continue
+				}
+				changed++
+				binary = strings.Fields(cmd)[0]
+				if hasNew {
+					continue
+				}
+				hook["command"] = rewritten
+				hooks = append(hooks, hook)
+				hasNew = true
+			}
+			if len(hooks) == 0 {
+				continue
+			}
+			entry["hooks"] = hooks
+			kept = append(kept, entry)
+		}
+		if err := settings.SetEventHooks(event, kept); err != nil {
+			return changed, err
+		}
+	}
+
+	if binary != "" && !hasConfabHookCommand(settings.GetEventHooks("SessionStart"), "hook session-start") {
+		entries := append(settings.GetEventHooks("SessionStart"), map[string]any{
+			"matcher": "*",
+			"hooks": []any{map[string]any{
+				"type":    "command",
+				"command": binary + " hook session-start --provider claude-code",
+			}},
+		})
+		if err := settings.SetEventHooks("SessionStart", entries); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// rewriteLegacySyncCommand returns the replacement for cmd when it is a
+// legacy confab session hook for event.
+func rewriteLegacySyncCommand(event, cmd string) (string, bool) {
+	if !IsConfabCommand(cmd) {
+		return "", false
+	}
+	fields := strings.Fields(cmd)
+	for _, legacy := range legacySyncCommands {
+		if legacy.event != event || len(fields) < 1+len(legacy.args) ||
+			!slices.Equal(fields[1:1+len(legacy.args)], legacy.args) {
+			continue
+		}
+		rest := fields[1+len(legacy.args):]
+		// "confab save <id>..." uploads specific sessions; only the bare
+		// form was a hook.
+		if legacy.args[0] == "save" && len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+			return "", false
+		}
+		parts := append([]string{fields[0], "hook", legacy.hookTo}, rest...)
+		if !slices.ContainsFunc(rest, func(arg string) bool { return strings.HasPrefix(arg, "--provider") }) {
+			parts = append(parts, "--provider", "claude-code")
+		}
+		return strings.Join(parts, " "), true
+	}
+	return "", false
+}
+
+// hasConfabHookCommand reports whether any hook in entries runs a confab
+// command containing sub.
+func hasConfabHookCommand(entries []any, sub string) bool {
+	for _, entryAny := range entries {
+		entry, _ := entryAny.(map[string]any)
+		list, _ := entry["hooks"].([]any)
+		for _, hookAny := range list {
+			hook, _ := hookAny.(map[string]any)
+			cmd, _ := hook["command"].(string)
+			if IsConfabCommand(cmd) && strings.Contains(cmd, sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
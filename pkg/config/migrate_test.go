@@ -0,0 +1,162 @@
+package config
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrateConfig_RoundTrip(t *testing.T) {
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(`{
+		"backend_url": "https://confab.example.com",
+		"api_key": "cfb_abcdefghijklmnopqrstuvwxyz12345678901234",
+		"redaction": {"enabled": true},
+		"profiles": {"work": {"backend_url": "https://work.example.com"}}
+	}`), &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, from, err := MigrateConfig(raw)
+	if err != nil {
+		t.Fatalf("MigrateConfig: %v", err)
+	}
+	if from != 0 {
+		t.Errorf("from = %d, want 0 for a config without a version", from)
+	}
+	if _, ok := raw["version"]; ok {
+		t.Error("MigrateConfig modified its input")
+	}
+	if v, _ := ConfigVersion(migrated); v != CurrentConfigVersion {
+		t.Errorf("migrated version = %d, want %d", v, CurrentConfigVersion)
+	}
+	delete(migrated, "version")
+	if !reflect.DeepEqual(migrated, raw) {
+		t.Errorf("migration changed other fields:\n got %v\nwant %v", migrated, raw)
+	}
+
+	// Written and read back, the config keeps its version and settings,
+	// and a second migration has nothing to do.
+	t.Setenv(ConfigPathEnv, filepath.Join(t.TempDir(), "config.json"))
+	migrated["version"] = CurrentConfigVersion
+	if err := WriteRawConfig(migrated); err != nil {
+		t.Fatalf("WriteRawConfig: %v", err)
+	}
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		t.Fatalf("GetGlobalUploadConfig: %v", err)
+	}
+	if cfg.Version != CurrentConfigVersion || cfg.BackendURL != "https://confab.example.com" {
+		t.Errorf("read back version %d, backend %q", cfg.Version, cfg.BackendURL)
+	}
+	reread, err := ReadRawConfig()
+	if err != nil {
+		t.Fatalf("ReadRawConfig: %v", err)
+	}
+	again, from, err := MigrateConfig(reread)
+	if err != nil || from != CurrentConfigVersion {
+		t.Fatalf("re-migration from %d, %v; want from %d", from, err, CurrentConfigVersion)
+	}
+	if a, b := mustJSON(t, again), mustJSON(t, reread); a != b {
+		t.Errorf("re-migration changed the config:\n got %s\nwant %s", a, b)
+	}
+	if pending, _ := PendingMigrations(from); len(pending) != 0 {
+		t.Errorf("pending after migration = %v, want none", pending)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMigrateConfig_RejectsNewerOrInvalidVersion(t *testing.T) {
+	for _, v := range []any{float64(CurrentConfigVersion + 1), -1.0, 1.5, "1"} {
+		if _, _, err := MigrateConfig(map[string]any{"version": v}); err == nil {
+			t.Errorf("MigrateConfig(version %v) succeeded, want an error", v)
+		}
+	}
+}
+
+func TestMigrateHooks_RewritesLegacySyncHooks(t *testing.T) {
+	command := func(cmd string) map[string]any { return makeHook("command", cmd) }
+	commands := func(s *ClaudeSettings, event string) []string {
+		var out []string
+		for _, e := range s.GetEventHooks(event) {
+			for _, h := range e.(map[string]any)["hooks"].([]any) {
+				out = append(out, h.(map[string]any)["command"].(string))
+			}
+		}
+		return out
+	}
+
+	t.Run("sync start and stop", func(t *testing.T) {
+		s := NewClaudeSettings()
+		setTestHook(s, "SessionStart", makeMatcher("*", command("/usr/local/bin/confab sync start"), command("echo hi")))
+		setTestHook(s, "SessionEnd", makeMatcher("*", command("confab sync stop --pidfile /tmp/p")))
+
+		n, err := MigrateHooks(s, 0)
+		if err != nil || n != 2 {
+			t.Fatalf("MigrateHooks = %d, %v; want 2 changes", n, err)
+		}
+		if got, want := commands(s, "SessionStart"), []string{"/usr/local/bin/confab hook session-start --provider claude-code", "echo hi"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("SessionStart = %q, want %q", got, want)
+		}
+		if got, want := commands(s, "SessionEnd"), []string{"confab hook session-end --pidfile /tmp/p --provider claude-code"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("SessionEnd = %q, want %q", got, want)
+		}
+		if n, _ := MigrateHooks(s, 0); n != 0 {
+			t.Errorf("second MigrateHooks changed %d hooks, want 0", n)
+		}
+	})
+
+	t.Run("save adds session-start", func(t *testing.T) {
+		s := NewClaudeSettings()
+		setTestHook(s, "SessionEnd", makeMatcher("*", command("confab save")))
+
+		if n, err := MigrateHooks(s, 0); err != nil || n != 2 {
+			t.Fatalf("MigrateHooks = %d, %v; want 2 changes", n, err)
+		}
+		if got := commands(s, "SessionEnd"); len(got) != 1 || got[0] != "confab hook session-end --provider claude-code" {
+			t.Errorf("SessionEnd = %q", got)
+		}
+		if got := commands(s, "SessionStart"); len(got) != 1 || got[0] != "confab hook session-start --provider claude-code" {
+			t.Errorf("SessionStart = %q", got)
+		}
+	})
+
+	t.Run("legacy beside current is dropped", func(t *testing.T) {
+		s := NewClaudeSettings()
+		setTestHook(s, "SessionStart",
+			makeMatcher("*", command("confab hook session-start --provider claude-code")),
+			makeMatcher("startup", command("confab sync start")))
+		setTestHook(s, "Stop", makeMatcher("", command("confab save abc123")))
+
+		if n, err := MigrateHooks(s, 0); err != nil || n != 1 {
+			t.Fatalf("MigrateHooks = %d, %v; want 1 change", n, err)
+		}
+		if got := commands(s, "SessionStart"); len(got) != 1 || !strings.Contains(got[0], "hook session-start") {
+			t.Errorf("SessionStart = %q, want only the current hook", got)
+		}
+		if len(s.GetEventHooks("SessionStart")) != 1 {
+			t.Error("emptied legacy entry was kept")
+		}
+		if got := commands(s, "Stop"); len(got) != 1 || got[0] != "confab save abc123" {
+			t.Errorf("Stop = %q, want other confab hooks untouched", got)
+		}
+	})
+
+	t.Run("nothing pending at the current version", func(t *testing.T) {
+		s := NewClaudeSettings()
+		setTestHook(s, "SessionStart", makeMatcher("*", command("confab sync start")))
+		if n, err := MigrateHooks(s, CurrentConfigVersion); err != nil || n != 0 {
+			t.Errorf("MigrateHooks(current) = %d, %v; want no changes", n, err)
+		}
+	})
+}
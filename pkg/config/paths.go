@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/ConfabulousDev/confab/pkg/confabpath"
 )
 
 // ClaudeStateDirEnv is the environment variable to override the default
@@ -33,3 +35,46 @@ func GetClaudeStateDir() (string, error) {
 	}
 	return filepath.Join(home, ".claude"), nil
 }
+
+// ConfigPathEnv overrides the path of config.json itself; the config
+// directory (confabpath.ConfigDirEnv) is unaffected.
+const ConfigPathEnv = "CONFAB_CONFIG_PATH"
+
+// Paths is every local directory and file location confab resolves, for
+// reporting (`confab diagnose`).
+type Paths struct {
+	// ConfigDir is confabpath.ConfigDir: CONFAB_CONFIG_DIR, else
+	// $XDG_CONFIG_HOME/confab if it exists, else ~/.confab.
+	ConfigDir string `json:"config_dir"`
+	// ConfigFile is UploadConfigPath: CONFAB_CONFIG_PATH, else
+	// ConfigDir/config.json.
+	ConfigFile string `json:"config_file"`
+	// DataDir holds sync state, inboxes, logs and OpenCode transcripts:
+	// CONFAB_DATA_DIR, else ConfigDir.
+	DataDir string `json:"data_dir"`
+	// SyncDir is DataDir/sync, where daemon state files live.
+	SyncDir string `json:"sync_dir"`
+	// ClaudeStateDir is GetClaudeStateDir: CONFAB_CLAUDE_DIR, else
+	// ~/.claude.
+	ClaudeStateDir string `json:"claude_state_dir"`
+}
+
+// ResolvePaths resolves every field of Paths from the environment.
+func ResolvePaths() (Paths, error) {
+	var p Paths
+	var err error
+	if p.ConfigDir, err = confabpath.ConfigDir(); err != nil {
+		return Paths{}, err
+	}
+	if p.ConfigFile, err = UploadConfigPath(); err != nil {
+		return Paths{}, err
+	}
+	if p.DataDir, err = confabpath.Dir(); err != nil {
+		return Paths{}, err
+	}
+	p.SyncDir = filepath.Join(p.DataDir, "sync")
+	if p.ClaudeStateDir, err = GetClaudeStateDir(); err != nil {
+		return Paths{}, err
+	}
+	return p, nil
+}
@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/confabpath"
+	"github.com/ConfabulousDev/confab/pkg/logger"
 )
 
 // TestIsLinkFromGitHubDisabled covers paths.go:20 (0% before). If the
@@ -94,3 +97,72 @@ func TestGetClaudeStateDir(t *testing.T) {
 		})
 	}
 }
+
+// TestResolvePaths_PriorityChain checks that every confab path resolver
+// (ResolvePaths, UploadConfigPath, the keyring file, the log file, and
+// confabpath.Subpath for daemon state) follows the same chain:
+// CONFAB_CONFIG_DIR > $XDG_CONFIG_HOME/confab (once it exists) > ~/.confab,
+// with CONFAB_DATA_DIR and CONFAB_CONFIG_PATH moving only their part.
+func TestResolvePaths_PriorityChain(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	xdg := filepath.Join(root, "xdg")
+	t.Setenv("HOME", home)
+	for _, env := range []string{"XDG_CONFIG_HOME", confabpath.ConfigDirEnv, confabpath.DataDirEnv, ConfigPathEnv, logger.LogDirEnv, ClaudeStateDirEnv} {
+		t.Setenv(env, "")
+	}
+
+	check := func(step, wantConfigDir, wantConfigFile, wantDataDir string) {
+		t.Helper()
+		p, err := ResolvePaths()
+		if err != nil {
+			t.Fatalf("%s: ResolvePaths: %v", step, err)
+		}
+		want := Paths{
+			ConfigDir:      wantConfigDir,
+			ConfigFile:     wantConfigFile,
+			DataDir:        wantDataDir,
+			SyncDir:        filepath.Join(wantDataDir, "sync"),
+			ClaudeStateDir: filepath.Join(home, ".claude"),
+		}
+		if p != want {
+			t.Errorf("%s: ResolvePaths() = %+v, want %+v", step, p, want)
+		}
+		if got, _ := UploadConfigPath(); got != wantConfigFile {
+			t.Errorf("%s: UploadConfigPath() = %q, want %q", step, got, wantConfigFile)
+		}
+		if got, _ := (&fileKeyring{}).filePath(); got != filepath.Join(wantConfigDir, "keyring.json") {
+			t.Errorf("%s: keyring file = %q, want it in %q", step, got, wantConfigDir)
+		}
+		if got, _ := logger.FilePath(); filepath.Dir(got) != filepath.Join(wantDataDir, "logs") {
+			t.Errorf("%s: log file = %q, want it in %q", step, got, filepath.Join(wantDataDir, "logs"))
+		}
+		if got, _ := confabpath.Subpath("sync", "x.json"); got != filepath.Join(wantDataDir, "sync", "x.json") {
+			t.Errorf("%s: confabpath.Subpath = %q, want it in %q", step, got, wantDataDir)
+		}
+	}
+
+	legacy := filepath.Join(home, ".confab")
+	check("defaults", legacy, filepath.Join(legacy, "config.json"), legacy)
+
+	// XDG_CONFIG_HOME alone doesn't move an install; the directory must exist.
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	check("xdg without dir", legacy, filepath.Join(legacy, "config.json"), legacy)
+	xdgDir := filepath.Join(xdg, "confab")
+	if err := os.MkdirAll(xdgDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	check("xdg", xdgDir, filepath.Join(xdgDir, "config.json"), xdgDir)
+
+	explicit := filepath.Join(root, "explicit")
+	t.Setenv(confabpath.ConfigDirEnv, explicit)
+	check("CONFAB_CONFIG_DIR", explicit, filepath.Join(explicit, "config.json"), explicit)
+
+	data := filepath.Join(root, "data")
+	t.Setenv(confabpath.DataDirEnv, data)
+	check("CONFAB_DATA_DIR", explicit, filepath.Join(explicit, "config.json"), data)
+
+	file := filepath.Join(root, "elsewhere", "config.json")
+	t.Setenv(ConfigPathEnv, file)
+	check("CONFAB_CONFIG_PATH", explicit, file, data)
+}
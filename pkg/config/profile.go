@@ -0,0 +1,166 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+)
+
+// ProfileEnv selects the config.json profile to use; the global --profile
+// flag sets it, so daemons spawned by the command inherit it.
+const ProfileEnv = "CONFAB_PROFILE"
+
+// DefaultProfileName names the top-level config in config.json — all of a
+// file written before profiles existed.
+const DefaultProfileName = "default"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// configFile is config.json on disk. The embedded UploadConfig is the
+// top-level (default) profile, so a flat single-profile file parses
+// unchanged; named profiles are complete configs of their own under
+// Profiles, and DefaultProfile picks the one used when CONFAB_PROFILE is
+// unset.
+type configFile struct {
+	UploadConfig
+	DefaultProfile string                   `json:"default_profile,omitempty"`
+	Profiles       map[string]*UploadConfig `json:"profiles,omitempty"`
+}
+
+// ValidateProfileName checks a profile name: letters, digits, '_' and '-'.
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '_' and '-'", name)
+	}
+	return nil
+}
+
+// activeProfile returns the named profile to use, "" for the top-level
+// config: $CONFAB_PROFILE, else the file's default_profile.
+func (f *configFile) activeProfile() (string, error) {
+	name := os.Getenv(ProfileEnv)
+	if name == "" {
+		name = f.DefaultProfile
+	}
+	if name == "" || name == DefaultProfileName {
+		return "", nil
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// profile returns the config for the named profile ("" is the top-level
+// one). A profile not in the file is empty — like a missing config.json,
+// it is created by the first save.
+func (f *configFile) profile(name string) *UploadConfig {
+	if name == "" {
+		return &f.UploadConfig
+	}
+	if cfg := f.Profiles[name]; cfg != nil {
+		return cfg
+	}
+	return &UploadConfig{}
+}
+
+// setProfile stores cfg as the named profile ("" is the top-level one).
+func (f *configFile) setProfile(name string, cfg *UploadConfig) {
+	if name == "" {
+		f.UploadConfig = *cfg
+		return
+	}
+	if f.Profiles == nil {
+		f.Profiles = make(map[string]*UploadConfig)
+	}
+	f.Profiles[name] = cfg
+}
+
+// readConfigFile parses config.json at configPath. A missing file is an
+// empty one.
+func readConfigFile(configPath string) (*configFile, error) {
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return &configFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read confab config (%s): %w", configPath, err)
+	}
+	var f configFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("confab config has invalid JSON (%s): %w", configPath, err)
+	}
+	return &f, nil
+}
+
+// ActiveProfile reports the profile GetUploadConfig reads and
+// SaveUploadConfig writes: DefaultProfileName for the top-level config.
+func ActiveProfile() (string, error) {
+	configPath, err := UploadConfigPath()
+	if err != nil {
+		return "", err
+	}
+	f, err := readConfigFile(configPath)
+	if err != nil {
+		return "", err
+	}
+	name, err := f.activeProfile()
+	if err != nil || name == "" {
+		return DefaultProfileName, err
+	}
+	return name, nil
+}
+
+// ListProfiles returns every profile in config.json, DefaultProfileName
+// (the top-level config) first and the rest sorted.
+func ListProfiles() ([]string, error) {
+	configPath, err := UploadConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := readConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return append([]string{DefaultProfileName}, names...), nil
+}
+
+// SetDefaultProfile points default_profile at name, the profile used when
+// CONFAB_PROFILE is unset. The profile must exist; DefaultProfileName
+// selects the top-level config.
+func SetDefaultProfile(name string) error {
+	configPath, err := UploadConfigPath()
+	if err != nil {
+		return err
+	}
+	f, err := readConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	if name == DefaultProfileName {
+		name = ""
+	} else if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("no profile %q in %s", name, configPath)
+	}
+	f.DefaultProfile = name
+	return writeConfigFile(configPath, f)
+}
+
+// writeConfigFile writes f to configPath, owner-only.
+func writeConfigFile(configPath string, f *configFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestProfiles_ReadWriteTwoProfiles(t *testing.T) {
+	path := withTempConfig(t, &UploadConfig{
+		BackendURL: "https://confab.example.com",
+		APIKey:     "cfb_top-level-key-12345",
+	})
+
+	save := func(profile, backendURL, apiKey string) {
+		t.Helper()
+		t.Setenv(ProfileEnv, profile)
+		cfg, err := GetGlobalUploadConfig()
+		if err != nil {
+			t.Fatalf("GetGlobalUploadConfig(%s): %v", profile, err)
+		}
+		if cfg.BackendURL != "" {
+			t.Errorf("new profile %s starts with backend %q, want empty", profile, cfg.BackendURL)
+		}
+		cfg.BackendURL, cfg.APIKey = backendURL, apiKey
+		if err := SaveUploadConfig(cfg); err != nil {
+			t.Fatalf("SaveUploadConfig(%s): %v", profile, err)
+		}
+	}
+	save("personal", "https://confabulous.dev", "cfb_personal-key-12345")
+	save("work", "https://confab.corp.example", "cfb_work-key-123456789")
+
+	load := func(profile string) *UploadConfig {
+		t.Helper()
+		t.Setenv(ProfileEnv, profile)
+		cfg, err := GetUploadConfig()
+		if err != nil {
+			t.Fatalf("GetUploadConfig(%s): %v", profile, err)
+		}
+		return cfg
+	}
+	for profile, want := range map[string]string{
+		"personal":         "https://confabulous.dev",
+		"work":             "https://confab.corp.example",
+		"":                 "https://confab.example.com",
+		DefaultProfileName: "https://confab.example.com",
+	} {
+		if got := load(profile).BackendURL; got != want {
+			t.Errorf("profile %q backend = %q, want %q", profile, got, want)
+		}
+	}
+	if got := load("work").APIKey; got != "cfb_work-key-123456789" {
+		t.Errorf("work api_key = %q", got)
+	}
+
+	// The top-level config is untouched by profile saves.
+	raw := readRawConfig(t, path)
+	if raw["backend_url"] != "https://confab.example.com" || raw["api_key"] != "cfb_top-level-key-12345" {
+		t.Errorf("top-level config changed: %v", raw)
+	}
+	if profiles, _ := raw["profiles"].(map[string]any); len(profiles) != 2 {
+		t.Errorf("profiles = %v, want personal and work", raw["profiles"])
+	}
+
+	// default_profile applies when CONFAB_PROFILE is unset, and the
+	// environment still wins over it.
+	t.Setenv(ProfileEnv, "")
+	if err := SetDefaultProfile("work"); err != nil {
+		t.Fatalf("SetDefaultProfile: %v", err)
+	}
+	if got := load("").BackendURL; got != "https://confab.corp.example" {
+		t.Errorf("default_profile=work backend = %q", got)
+	}
+	if got := load("personal").BackendURL; got != "https://confabulous.dev" {
+		t.Errorf("CONFAB_PROFILE=personal over default_profile backend = %q", got)
+	}
+	if got := load(DefaultProfileName).BackendURL; got != "https://confab.example.com" {
+		t.Errorf("CONFAB_PROFILE=default backend = %q, want the top-level config", got)
+	}
+	t.Setenv(ProfileEnv, "")
+	if active, err := ActiveProfile(); err != nil || active != "work" {
+		t.Errorf("ActiveProfile = %q, %v; want work", active, err)
+	}
+	if names, err := ListProfiles(); err != nil || !slices.Equal(names, []string{DefaultProfileName, "personal", "work"}) {
+		t.Errorf("ListProfiles = %v, %v", names, err)
+	}
+	if err := SetDefaultProfile("missing"); err == nil {
+		t.Error("SetDefaultProfile accepted a profile that doesn't exist")
+	}
+
+	t.Setenv(ProfileEnv, "no/slashes")
+	if _, err := GetUploadConfig(); err == nil {
+		t.Error("GetUploadConfig accepted an invalid profile name")
+	}
+}
+
+func TestProfiles_LegacyFlatFormat(t *testing.T) {
+	path := withTempConfig(t, nil)
+	t.Setenv(ProfileEnv, "")
+	legacy := `{"backend_url":"https://confab.example.com","api_key":"cfb_legacy-key-123456","log_level":"debug"}`
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		t.Fatalf("GetGlobalUploadConfig: %v", err)
+	}
+	if cfg.BackendURL != "https://confab.example.com" || cfg.APIKey != "cfb_legacy-key-123456" || cfg.LogLevel != "debug" {
+		t.Errorf("legacy config loaded as %+v", cfg)
+	}
+	if active, err := ActiveProfile(); err != nil || active != DefaultProfileName {
+		t.Errorf("ActiveProfile = %q, %v; want %s", active, err, DefaultProfileName)
+	}
+
+	// Saving keeps the flat format: no profile keys appear.
+	cfg.LogLevel = "warn"
+	if err := SaveUploadConfig(cfg); err != nil {
+		t.Fatalf("SaveUploadConfig: %v", err)
+	}
+	raw := readRawConfig(t, path)
+	if _, ok := raw["profiles"]; ok {
+		t.Errorf("saving a flat config added profiles: %v", raw)
+	}
+	if _, ok := raw["default_profile"]; ok {
+		t.Errorf("saving a flat config added default_profile: %v", raw)
+	}
+	if raw["log_level"] != "warn" {
+		t.Errorf("log_level = %v, want warn", raw["log_level"])
+	}
+}
+
+func TestProfiles_KeyringAccountsPerProfile(t *testing.T) {
+	kr := &memKeyring{}
+	defer SetKeyringBackendForTest(kr)()
+	withTempConfig(t, nil)
+
+	for _, profile := range []string{"", "work"} {
+		t.Setenv(ProfileEnv, profile)
+		if err := SaveUploadConfig(&UploadConfig{
+			BackendURL: "https://confab.example.com",
+			APIKey:     "cfb_key-for-" + profile + "-profile",
+			UseKeyring: true,
+		}); err != nil {
+			t.Fatalf("SaveUploadConfig(%q): %v", profile, err)
+		}
+	}
+	if got := kr.items["confab/api_key"]; got != "cfb_key-for--profile" {
+		t.Errorf("top-level keyring api_key = %q", got)
+	}
+	if got := kr.items["confab/profile:work:api_key"]; got != "cfb_key-for-work-profile" {
+		t.Errorf("work keyring api_key = %q", got)
+	}
+
+	t.Setenv(ProfileEnv, "work")
+	cfg, err := GetUploadConfig()
+	if err != nil {
+		t.Fatalf("GetUploadConfig: %v", err)
+	}
+	if cfg.APIKey != "cfb_key-for-work-profile" {
+		t.Errorf("work api_key from keyring = %q", cfg.APIKey)
+	}
+}
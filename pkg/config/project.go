@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ProjectConfigDir and ProjectConfigFile locate a per-project config:
+// <project>/.confab/config.json.
+const (
+	ProjectConfigDir  = ".confab"
+	ProjectConfigFile = "config.json"
+)
+
+// ProjectConfig is the subset of UploadConfig a project may override. A
+// field left at its zero value (or null) keeps the global value.
+//
+// Everything else is global-only and rejected in a project file:
+// credentials (api_key, refresh_token, expires_at, use_keyring, bindings)
+// so secrets never live in a repository; where sessions go and what is
+// scrubbed from them (backend_url, redaction), since a cloned repository
+// must not be able to send transcripts and the global API key elsewhere
+// or switch redaction off; connection trust (proxy_url, ca_cert_file,
+// tls_skip_verify); and per-installation behavior (auto_update, retries,
+// concurrency).
+//
+// The fields have no omitempty so `confab config init` writes every one of
+// them as a template.
+type ProjectConfig struct {
+	LogLevel                string        `json:"log_level"`
+	Compression             string        `json:"compression"`
+	CompressionLevel        int           `json:"compression_level"`
+	MaxUploadBytesPerSecond int64         `json:"max_upload_bps"`
+	SyncSchedule            *SyncSchedule `json:"sync_schedule"`
+
+	// ActiveRedactionProfile picks one of the global config's
+	// redaction_profiles for this project, in place of the global active
+	// profile. The profiles themselves are global-only.
+	ActiveRedactionProfile string `json:"active_redaction_profile"`
+}
+
+// FindProjectConfig walks up from dir looking for .confab/config.json and
+// returns the first one found, or "" if there is none. The walk stops at
+// the home directory, whose .confab/config.json is the global config, and
+// otherwise at the filesystem root.
+func FindProjectConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	home, _ := os.UserHomeDir()
+	globalPath, _ := UploadConfigPath()
+	for {
+		if home != "" && dir == home {
+			return "", nil
+		}
+		candidate := filepath.Join(dir, ProjectConfigDir, ProjectConfigFile)
+		if candidate != globalPath {
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProjectConfig reads and validates the project config at path. Keys
+// that aren't project-overridable are an error, so a misplaced api_key is
+// reported rather than silently ignored.
+func LoadProjectConfig(path string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project config (%s): %w", path, err)
+	}
+	var pc ProjectConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&pc); err != nil {
+		return nil, fmt.Errorf("invalid project config (%s): %w (global-only settings such as api_key, backend_url and redaction belong in ~/.confab/config.json)", path, err)
+	}
+	if err := pc.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid project config (%s): %w", path, err)
+	}
+	return &pc, nil
+}
+
+// Validate checks each field that is set.
+func (pc *ProjectConfig) Validate() error {
+	if pc.LogLevel != "" {
+		if _, err := ParseLogLevel(pc.LogLevel); err != nil {
+			return err
+		}
+	}
+	if pc.ActiveRedactionProfile != "" {
+		if err := ValidateProfileName(pc.ActiveRedactionProfile); err != nil {
+			return fmt.Errorf("invalid active_redaction_profile: %w", err)
+		}
+	}
+	if err := ValidateCompression(pc.Compression); err != nil {
+		return fmt.Errorf("invalid compression: %w", err)
+	}
+	if err := ValidateCompressionLevel(pc.CompressionLevel); err != nil {
+		return fmt.Errorf("invalid compression_level: %w", err)
+	}
+	if pc.MaxUploadBytesPerSecond < 0 {
+		return errors.New("max_upload_bps must not be negative")
+	}
+	if pc.SyncSchedule != nil {
+		if err := pc.SyncSchedule.Validate(); err != nil {
+			return fmt.Errorf("invalid sync schedule: %w", err)
+		}
+	}
+	return nil
+}
+
+// ApplyTo overrides cfg's fields with every field set in pc.
+func (pc *ProjectConfig) ApplyTo(cfg *UploadConfig) {
+	if pc.LogLevel != "" {
+		cfg.LogLevel = pc.LogLevel
+	}
+	if pc.ActiveRedactionProfile != "" {
+		cfg.ActiveRedactionProfile = pc.ActiveRedactionProfile
+	}
+	if pc.Compression != "" {
+		cfg.Compression = pc.Compression
+	}
+	if pc.CompressionLevel != 0 {
+		cfg.CompressionLevel = pc.CompressionLevel
+	}
+	if pc.MaxUploadBytesPerSecond != 0 {
+		cfg.MaxUploadBytesPerSecond = pc.MaxUploadBytesPerSecond
+	}
+	if pc.SyncSchedule != nil {
+		cfg.SyncSchedule = pc.SyncSchedule
+	}
+}
+
+// applyProjectConfig merges the project config found from the working
+// directory, if any, into cfg.
+func applyProjectConfig(cfg *UploadConfig) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil // no working directory, no project
+	}
+	path, err := FindProjectConfig(cwd)
+	if err != nil || path == "" {
+		return nil
+	}
+	pc, err := LoadProjectConfig(path)
+	if err != nil {
+		return err
+	}
+	pc.ApplyTo(cfg)
+	return nil
+}
+
+// WriteProjectConfigTemplate creates dir/.confab/config.json listing every
+// overridable field, unset. It refuses to replace an existing file.
+func WriteProjectConfigTemplate(dir string) (string, error) {
+	path := filepath.Join(dir, ProjectConfigDir, ProjectConfigFile)
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("project config already exists: %s", path)
+	}
+	data, err := json.MarshalIndent(ProjectConfig{}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal project config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", ProjectConfigDir, err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write project config: %w", err)
+	}
+	return path, nil
+}
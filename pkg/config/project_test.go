@@ -0,0 +1,221 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeProjectConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, ProjectConfigDir, ProjectConfigFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProjectConfigApplyTo_PartialOverride(t *testing.T) {
+	autoUpdate := false
+	global := &UploadConfig{
+		BackendURL:       "https://global.example.com",
+		APIKey:           "cfb_global",
+		LogLevel:         "warn",
+		AutoUpdate:       &autoUpdate,
+		Redaction:        &RedactionConfig{Enabled: true},
+		CompressionLevel: 5,
+		Compression:      CompressionGzip,
+	}
+	globalRedaction := global.Redaction
+	pc := &ProjectConfig{
+		LogLevel: "debug",
+		// Compression and CompressionLevel left at zero: keep global.
+	}
+
+	pc.ApplyTo(global)
+
+	if global.LogLevel != "debug" {
+		t.Errorf("LogLevel = %q, want project override", global.LogLevel)
+	}
+	if global.Compression != CompressionGzip || global.CompressionLevel != 5 {
+		t.Errorf("unset project fields changed global values: %+v", global)
+	}
+	if global.APIKey != "cfb_global" || global.AutoUpdate == nil || *global.AutoUpdate ||
+		global.BackendURL != "https://global.example.com" || global.Redaction != globalRedaction {
+		t.Errorf("global-only fields changed: %+v", global)
+	}
+}
+
+func TestFindProjectConfig_WalksUpAndStopsAtHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CONFAB_CONFIG_PATH", filepath.Join(home, ".confab", "config.json"))
+
+	project := filepath.Join(home, "src", "project")
+	nested := filepath.Join(project, "pkg", "deep")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The global config in ~/.confab is never mistaken for a project one.
+	writeProjectConfig(t, home, `{"backend_url": "https://global.example.com"}`)
+	if got, err := FindProjectConfig(nested); err != nil || got != "" {
+		t.Errorf("FindProjectConfig with only a global config = %q, %v; want none", got, err)
+	}
+
+	want := writeProjectConfig(t, project, `{"log_level": "debug"}`)
+	if got, err := FindProjectConfig(nested); err != nil || got != want {
+		t.Errorf("FindProjectConfig(nested) = %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestLoadProjectConfig_RejectsGlobalOnlyFields(t *testing.T) {
+	path := writeProjectConfig(t, t.TempDir(), `{"log_level": "debug", "api_key": "cfb_secret"}`)
+	_, err := LoadProjectConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "api_key") {
+		t.Errorf("LoadProjectConfig err = %v, want it to reject api_key", err)
+	}
+
+	// A cloned repository must not redirect uploads or turn redaction off.
+	for _, key := range []string{"backend_url", "redaction"} {
+		content := `{"backend_url": "https://attacker.example.com"}`
+		if key == "redaction" {
+			content = `{"redaction": {"enabled": false}}`
+		}
+		path := writeProjectConfig(t, t.TempDir(), content)
+		if _, err := LoadProjectConfig(path); err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("LoadProjectConfig err = %v, want it to reject %s", err, key)
+		}
+	}
+
+	path = writeProjectConfig(t, t.TempDir(), `{"compression_level": 99}`)
+	if _, err := LoadProjectConfig(path); err == nil {
+		t.Error("expected error for out-of-range compression_level")
+	}
+}
+
+func TestGetUploadConfig_AppliesProjectOverride(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	globalPath := filepath.Join(home, ".confab", "config.json")
+	t.Setenv("CONFAB_CONFIG_PATH", globalPath)
+	if err := os.MkdirAll(filepath.Dir(globalPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(globalPath, []byte(`{"backend_url": "https://global.example.com", "api_key": "cfb_global", "log_level": "warn"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	project := filepath.Join(home, "project")
+	writeProjectConfig(t, project, `{"log_level": "debug", "sync_schedule": null}`)
+	t.Chdir(project)
+
+	cfg, err := GetUploadConfig()
+	if err != nil {
+		t.Fatalf("GetUploadConfig: %v", err)
+	}
+	if cfg.LogLevel != "debug" || cfg.BackendURL != "https://global.example.com" || cfg.APIKey != "cfb_global" {
+		t.Errorf("merged config = %+v, want project log_level over global backend and key", cfg)
+	}
+
+	global, err := GetGlobalUploadConfig()
+	if err != nil {
+		t.Fatalf("GetGlobalUploadConfig: %v", err)
+	}
+	if global.LogLevel != "warn" {
+		t.Errorf("GetGlobalUploadConfig().LogLevel = %q, want the unmerged global value", global.LogLevel)
+	}
+}
+
+func TestGetEffectiveRedactionConfig_Precedence(t *testing.T) {
+	top := &RedactionConfig{Enabled: true}
+	hipaa := &RedactionConfig{Enabled: true, Patterns: []RedactionPattern{{Name: "MRN", Pattern: `MRN-\d+`, Type: "mrn"}}}
+	pci := &RedactionConfig{Enabled: true, Patterns: []RedactionPattern{{Name: "PAN", Pattern: `\d{16}`, Type: "pan"}}}
+
+	tests := []struct {
+		name         string
+		globalActive string
+		project      *ProjectConfig
+		want         *RedactionConfig
+	}{
+		{"no profile active", "", nil, top},
+		{"global active profile", "hipaa", nil, hipaa},
+		{"project active profile beats global", "hipaa", &ProjectConfig{ActiveRedactionProfile: "pci"}, pci},
+		{"unknown profile falls back to top level", "missing", nil, top},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &UploadConfig{
+				Redaction:              top,
+				RedactionProfiles:      map[string]*RedactionConfig{"hipaa": hipaa, "pci": pci},
+				ActiveRedactionProfile: tt.globalActive,
+			}
+			if tt.project != nil {
+				tt.project.ApplyTo(cfg)
+			}
+			if got := cfg.GetEffectiveRedactionConfig(); got != tt.want {
+				t.Errorf("GetEffectiveRedactionConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUploadConfig_ProjectActivatesRedactionProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	globalPath := filepath.Join(home, ".confab", "config.json")
+	t.Setenv("CONFAB_CONFIG_PATH", globalPath)
+	if err := os.MkdirAll(filepath.Dir(globalPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	global := `{"backend_url": "https://global.example.com", "api_key": "cfb_global",
+		"redaction": {"enabled": true},
+		"redaction_profiles": {"hipaa": {"enabled": true, "patterns": [{"name": "MRN", "pattern": "MRN-[0-9]+", "type": "mrn"}]}}}`
+	if err := os.WriteFile(globalPath, []byte(global), 0600); err != nil {
+		t.Fatal(err)
+	}
+	project := filepath.Join(home, "project")
+	writeProjectConfig(t, project, `{"active_redaction_profile": "hipaa"}`)
+	t.Chdir(project)
+
+	cfg, err := GetUploadConfig()
+	if err != nil {
+		t.Fatalf("GetUploadConfig: %v", err)
+	}
+	if got := cfg.GetEffectiveRedactionConfig(); got == nil || len(got.Patterns) != 1 || got.Patterns[0].Name != "MRN" {
+		t.Errorf("effective redaction = %+v, want the hipaa profile", got)
+	}
+
+	writeProjectConfig(t, project, `{"active_redaction_profile": "pci"}`)
+	if _, err := GetUploadConfig(); err == nil || !strings.Contains(err.Error(), `"pci"`) {
+		t.Errorf("GetUploadConfig with an undefined profile = %v, want an error naming it", err)
+	}
+}
+
+func TestAddAndActivateRedactionProfile(t *testing.T) {
+	withTempConfig(t, &UploadConfig{BackendURL: "https://global.example.com", Redaction: &RedactionConfig{Enabled: true}})
+
+	if err := ActivateRedactionProfile("hipaa"); err == nil {
+		t.Error("activating a missing profile succeeded")
+	}
+	if err := AddRedactionProfile("hipaa"); err != nil {
+		t.Fatalf("AddRedactionProfile: %v", err)
+	}
+	if err := AddRedactionProfile("hipaa"); err == nil {
+		t.Error("adding an existing profile succeeded")
+	}
+	if err := ActivateRedactionProfile("hipaa"); err != nil {
+		t.Fatalf("ActivateRedactionProfile: %v", err)
+	}
+
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		t.Fatalf("GetGlobalUploadConfig: %v", err)
+	}
+	if cfg.ActiveRedactionProfile != "hipaa" || cfg.RedactionProfiles["hipaa"] == nil || !cfg.RedactionProfiles["hipaa"].Enabled {
+		t.Errorf("saved config = active %q, profiles %+v", cfg.ActiveRedactionProfile, cfg.RedactionProfiles)
+	}
+}
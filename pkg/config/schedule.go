@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SyncSchedule restricts when the daemon uploads. Outside the allowed times
+// the daemon doesn't contact the backend at all; the transcript on disk
+// acts as the buffer, and everything accumulated is flushed on the first
+// cycle after the window opens, or at daemon shutdown.
+type SyncSchedule struct {
+	// QuietHours are daily windows, in local time, during which nothing is
+	// uploaded.
+	QuietHours []QuietWindow `json:"quiet_hours,omitempty"`
+}
+
+// QuietWindow is a daily local-time range [Start, End) in "HH:MM" form.
+// End before Start wraps past midnight (e.g. 22:00–07:00).
+type QuietWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Validate checks every window parses and is non-empty.
+func (s *SyncSchedule) Validate() error {
+	for i, w := range s.QuietHours {
+		start, end, err := w.minutes()
+		if err != nil {
+			return fmt.Errorf("quiet_hours[%d]: %w", i, err)
+		}
+		if start == end {
+			return fmt.Errorf("quiet_hours[%d]: start and end are both %s", i, w.Start)
+		}
+	}
+	return nil
+}
+
+// Allows reports whether uploads are allowed at t, i.e. t falls outside
+// every quiet window. A nil schedule always allows. Windows that fail to
+// parse are ignored (Validate rejects them when the config is loaded).
+func (s *SyncSchedule) Allows(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	m := t.Hour()*60 + t.Minute()
+	for _, w := range s.QuietHours {
+		start, end, err := w.minutes()
+		if err != nil {
+			continue
+		}
+		if start < end && m >= start && m < end {
+			return false
+		}
+		if start > end && (m >= start || m < end) {
+			return false
+		}
+	}
+	return true
+}
+
+// minutes returns Start and End as minutes past midnight.
+func (w QuietWindow) minutes() (start, end int, err error) {
+	if start, err = parseClock(w.Start); err != nil {
+		return 0, 0, fmt.Errorf("invalid start: %w", err)
+	}
+	if end, err = parseClock(w.End); err != nil {
+		return 0, 0, fmt.Errorf("invalid end: %w", err)
+	}
+	return start, end, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncSchedule_Allows(t *testing.T) {
+	at := func(clock string) time.Time {
+		tm, _ := time.Parse("15:04", clock)
+		return time.Date(2026, 3, 1, tm.Hour(), tm.Minute(), 0, 0, time.Local)
+	}
+	daytime := &SyncSchedule{QuietHours: []QuietWindow{{Start: "09:00", End: "17:30"}}}
+	overnight := &SyncSchedule{QuietHours: []QuietWindow{{Start: "22:00", End: "07:00"}}}
+
+	tests := []struct {
+		name     string
+		schedule *SyncSchedule
+		clock    string
+		want     bool
+	}{
+		{"nil schedule", nil, "12:00", true},
+		{"no windows", &SyncSchedule{}, "12:00", true},
+		{"before window", daytime, "08:59", true},
+		{"window start is quiet", daytime, "09:00", false},
+		{"inside window", daytime, "12:00", false},
+		{"window end is allowed", daytime, "17:30", true},
+		{"overnight before midnight", overnight, "23:15", false},
+		{"overnight after midnight", overnight, "03:00", false},
+		{"overnight end is allowed", overnight, "07:00", true},
+		{"overnight daytime", overnight, "12:00", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Allows(at(tt.clock)); got != tt.want {
+				t.Errorf("Allows(%s) = %v, want %v", tt.clock, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncSchedule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  QuietWindow
+		wantErr string
+	}{
+		{"valid", QuietWindow{Start: "22:00", End: "07:00"}, ""},
+		{"bad start", QuietWindow{Start: "10pm", End: "07:00"}, "invalid start"},
+		{"bad end", QuietWindow{Start: "22:00", End: "25:00"}, "invalid end"},
+		{"empty window", QuietWindow{Start: "08:00", End: "08:00"}, "start and end are both"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&SyncSchedule{QuietHours: []QuietWindow{tt.window}}).Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetUploadConfig_RejectsInvalidSyncSchedule(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("CONFAB_CONFIG_PATH", configPath)
+	os.WriteFile(configPath, []byte(`{"sync_schedule":{"quiet_hours":[{"start":"9am","end":"17:00"}]}}`), 0600)
+
+	if _, err := GetUploadConfig(); err == nil || !strings.Contains(err.Error(), "invalid sync schedule") {
+		t.Errorf("GetUploadConfig() error = %v, want invalid sync schedule", err)
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// prettyDiffContext is how many unchanged lines PrettyDiff keeps around
+// each change.
+const prettyDiffContext = 2
+
+// Clone returns a deep copy of s, so a caller can apply changes in memory
+// (e.g. `setup --dry-run`) and compare against the original.
+func (s *ClaudeSettings) Clone() (*ClaudeSettings, error) {
+	data, err := json.Marshal(s.raw)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		raw = make(map[string]any)
+	}
+	return &ClaudeSettings{raw: raw}, nil
+}
+
+// PrettyDiff renders the change from before to after as a line diff of
+// their indented JSON: removed lines start with "- ", added lines with
+// "+ ", and a little unchanged context with "  ". Runs of unchanged lines
+// beyond the context collapse to "  ...". Returns "" when the two
+// serialize identically; a nil side counts as empty settings.
+func PrettyDiff(before, after *ClaudeSettings) string {
+	a, b := settingsLines(before), settingsLines(after)
+
+	// Longest common subsequence table; settings files are small enough
+	// that the quadratic table is fine.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type diffLine struct {
+		op   byte // ' ', '-' or '+'
+		text string
+	}
+	var lines []diffLine
+	changed := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			// Removals first, so a changed line reads "-" then "+".
+			lines = append(lines, diffLine{'-', a[i]})
+			changed = true
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			changed = true
+			j++
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	// Keep unchanged lines only within prettyDiffContext of a change.
+	keep := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for c := max(0, k-prettyDiffContext); c <= min(len(lines)-1, k+prettyDiffContext); c++ {
+			keep[c] = true
+		}
+	}
+
+	var sb strings.Builder
+	skipped := false
+	for k, l := range lines {
+		if !keep[k] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			sb.WriteString("  ...\n")
+			skipped = false
+		}
+		sb.WriteByte(l.op)
+		sb.WriteByte(' ')
+		sb.WriteString(l.text)
+		sb.WriteByte('\n')
+	}
+	if skipped {
+		sb.WriteString("  ...\n")
+	}
+	return sb.String()
+}
+
+// settingsLines serializes s as indented JSON split into lines.
+func settingsLines(s *ClaudeSettings) []string {
+	raw := map[string]any{}
+	if s != nil && s.raw != nil {
+		raw = s.raw
+	}
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(data), "\n")
+}
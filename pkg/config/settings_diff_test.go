@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrettyDiff(t *testing.T) {
+	before := NewClaudeSettings()
+	before.raw["model"] = "opus"
+	before.SetEventHooks("Stop", []any{
+		map[string]any{"hooks": []any{map[string]any{"type": "command", "command": "confab hook stop-old"}}},
+	})
+
+	after, err := before.Clone()
+	if err != nil {
+		t.Fatalf("Clone: %v", err)
+	}
+	if got := PrettyDiff(before, after); got != "" {
+		t.Errorf("PrettyDiff of identical settings = %q, want empty", got)
+	}
+
+	after.SetEventHooks("Stop", []any{
+		map[string]any{"hooks": []any{map[string]any{"type": "command", "command": "confab hook stop"}}},
+	})
+	if before.GetEventHooks("Stop")[0].(map[string]any)["hooks"].([]any)[0].(map[string]any)["command"] != "confab hook stop-old" {
+		t.Fatal("changing the clone modified the original")
+	}
+
+	got := PrettyDiff(before, after)
+	removed := strings.Index(got, "\n- "+strings.Repeat(" ", 12)+`"command": "confab hook stop-old",`)
+	added := strings.Index(got, "\n+ "+strings.Repeat(" ", 12)+`"command": "confab hook stop",`)
+	if removed < 0 || added < 0 || removed > added {
+		t.Errorf("diff should remove the old hook, then add the new one:\n%s", got)
+	}
+	if strings.Contains(got, `"model"`) {
+		t.Errorf("diff kept an unchanged line outside the context:\n%s", got)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if !strings.HasPrefix(line, "+ ") && !strings.HasPrefix(line, "- ") && !strings.HasPrefix(line, "  ") {
+			t.Errorf("line %q lacks a diff prefix", line)
+		}
+	}
+
+	// A nil side is empty settings: everything is added.
+	got = PrettyDiff(nil, before)
+	if !strings.Contains(got, "\n+   \"model\": \"opus\"") {
+		t.Errorf("PrettyDiff(nil, settings) missing added line:\n%s", got)
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// settingsLockTimeout bounds how long AtomicUpdateSettingsAt waits for
+// another process's read-modify-write before falling back to the
+// optimistic mtime check. Hook installs hold the lock for milliseconds.
+var settingsLockTimeout = 5 * time.Second
+
+const settingsLockPollInterval = 10 * time.Millisecond
+
+// errSettingsLockUnavailable means the lock couldn't be used at all (no
+// lock file, or a filesystem without flock), as opposed to being held.
+var errSettingsLockUnavailable = errors.New("settings lock unavailable")
+
+// settingsLockPath returns the advisory lock file for settingsPath. The
+// settings file itself can't be locked: each write renames a new file
+// over it, so a lock on the old inode would protect nothing. The lock file
+// is left in place; removing it would let two processes lock different
+// inodes under the same name.
+func settingsLockPath(settingsPath string) string {
+	return settingsPath + ".lock"
+}
+
+// lockSettings takes an exclusive flock on settingsPath's lock file,
+// polling until timeout. The returned func releases it. Errors wrap
+// errSettingsLockUnavailable when locking isn't possible here, and report
+// a timeout when another holder kept it too long.
+func lockSettings(settingsPath string, timeout time.Duration) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(settingsPath), 0700); err != nil {
+		return nil, fmt.Errorf("%w: %v", errSettingsLockUnavailable, err)
+	}
+	f, err := os.OpenFile(settingsLockPath(settingsPath), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errSettingsLockUnavailable, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return func() {
+				syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+				f.Close()
+			}, nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) && !errors.Is(err, syscall.EINTR) {
+			f.Close()
+			return nil, fmt.Errorf("%w: %v", errSettingsLockUnavailable, err)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out after %v waiting for %s", timeout, settingsLockPath(settingsPath))
+		}
+		time.Sleep(settingsLockPollInterval)
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SettingsScope selects which Claude settings file confab reads and
+// writes hooks in.
+type SettingsScope string
+
+const (
+	// SettingsScopeUser is the user-wide settings.json in the Claude state
+	// dir (~/.claude, or CONFAB_CLAUDE_DIR). The default.
+	SettingsScopeUser SettingsScope = "user"
+	// SettingsScopeProject is <cwd>/.claude/settings.local.json, which
+	// Claude Code reads for sessions in that project only, so confab syncs
+	// just that repo's sessions.
+	SettingsScopeProject SettingsScope = "project"
+)
+
+// SettingsScopeEnv selects the settings scope when no explicit scope is
+// given ("user" or "project").
+const SettingsScopeEnv = "CONFAB_SETTINGS_SCOPE"
+
+// ProjectSettingsFile is the project-local settings file, relative to the
+// project directory.
+var ProjectSettingsFile = filepath.Join(".claude", "settings.local.json")
+
+// ParseSettingsScope validates a scope name. "" is the user scope.
+func ParseSettingsScope(s string) (SettingsScope, error) {
+	switch SettingsScope(s) {
+	case "", SettingsScopeUser:
+		return SettingsScopeUser, nil
+	case SettingsScopeProject:
+		return SettingsScopeProject, nil
+	}
+	return "", fmt.Errorf("invalid settings scope %q (must be %q or %q)", s, SettingsScopeUser, SettingsScopeProject)
+}
+
+// SettingsScopeFromEnv returns the scope named by CONFAB_SETTINGS_SCOPE,
+// defaulting to the user scope.
+func SettingsScopeFromEnv() (SettingsScope, error) {
+	scope, err := ParseSettingsScope(os.Getenv(SettingsScopeEnv))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", SettingsScopeEnv, err)
+	}
+	return scope, nil
+}
+
+// ProjectSettingsPath returns the project-local settings file for the
+// project at dir.
+func ProjectSettingsPath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+	return filepath.Join(dir, ProjectSettingsFile), nil
+}
+
+// SettingsPathForScope returns the settings file for scope: the user
+// settings.json, or the working directory's .claude/settings.local.json.
+func SettingsPathForScope(scope SettingsScope) (string, error) {
+	switch scope {
+	case SettingsScopeProject:
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get working directory: %w", err)
+		}
+		return ProjectSettingsPath(cwd)
+	case "", SettingsScopeUser:
+		stateDir, err := GetClaudeStateDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get settings path: %w", err)
+		}
+		return filepath.Join(stateDir, "settings.json"), nil
+	}
+	return "", fmt.Errorf("invalid settings scope %q", scope)
+}
+
+// ReadSettingsForScope is ReadSettings against the settings file for scope.
+func ReadSettingsForScope(scope SettingsScope) (*ClaudeSettings, error) {
+	settingsPath, err := SettingsPathForScope(scope)
+	if err != nil {
+		return nil, err
+	}
+	return ReadSettingsAt(settingsPath)
+}
+
+// AtomicUpdateSettingsForScope is AtomicUpdateSettings against the
+// settings file for scope.
+func AtomicUpdateSettingsForScope(scope SettingsScope, updateFn func(*ClaudeSettings) error) error {
+	settingsPath, err := SettingsPathForScope(scope)
+	if err != nil {
+		return err
+	}
+	return AtomicUpdateSettingsAt(settingsPath, updateFn)
+}
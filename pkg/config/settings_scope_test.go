@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSettingsScope(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SettingsScope
+		wantErr bool
+	}{
+		{"", SettingsScopeUser, false},
+		{"user", SettingsScopeUser, false},
+		{"project", SettingsScopeProject, false},
+		{"global", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSettingsScope(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSettingsScope(%q) = %q, %v; want %q (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestProjectScopeLeavesUserSettingsAlone routes ReadSettings and
+// AtomicUpdateSettings through CONFAB_SETTINGS_SCOPE=project and checks the
+// update lands in the project's settings.local.json only.
+func TestProjectScopeLeavesUserSettingsAlone(t *testing.T) {
+	userDir := t.TempDir()
+	t.Setenv("CONFAB_CLAUDE_DIR", userDir)
+	userPath := filepath.Join(userDir, "settings.json")
+	if err := os.WriteFile(userPath, []byte(`{"model": "opus"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	projectDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(projectDir)
+	t.Setenv(SettingsScopeEnv, "project")
+
+	projectPath := filepath.Join(projectDir, ".claude", "settings.local.json")
+	if got, err := GetSettingsPath(); err != nil || got != projectPath {
+		t.Fatalf("GetSettingsPath() = %q, %v; want %q", got, err, projectPath)
+	}
+	err = AtomicUpdateSettings(func(s *ClaudeSettings) error {
+		return s.SetEventHooks("SessionStart", []any{map[string]any{"hooks": []any{}}})
+	})
+	if err != nil {
+		t.Fatalf("AtomicUpdateSettings: %v", err)
+	}
+	settings, err := ReadSettings()
+	if err != nil {
+		t.Fatalf("ReadSettings: %v", err)
+	}
+	if len(settings.GetEventHooks("SessionStart")) != 1 {
+		t.Errorf("project settings missing the update")
+	}
+
+	if data, _ := os.ReadFile(userPath); string(data) != `{"model": "opus"}` {
+		t.Errorf("user settings.json changed by a project-scope update: %s", data)
+	}
+	user, err := ReadSettingsForScope(SettingsScopeUser)
+	if err != nil || len(user.GetEventHooks("SessionStart")) != 0 {
+		t.Errorf("ReadSettingsForScope(user) = %v, %v; want no hooks", user, err)
+	}
+
+	t.Setenv(SettingsScopeEnv, "bogus")
+	if _, err := GetSettingsPath(); err == nil {
+		t.Error("GetSettingsPath() with an invalid scope succeeded, want an error")
+	}
+}
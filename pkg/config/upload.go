@@ -1,12 +1,22 @@
 package config
 
 import (
-	"encoding/json"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"maps"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/ConfabulousDev/confab/pkg/confabpath"
 	"github.com/ConfabulousDev/confab/pkg/logger"
@@ -17,16 +27,159 @@ import (
 // The top-level BackendURL/APIKey are the DEFAULT binding (the provider's
 // default config dir). Per-(provider, config dir) bindings live under
 // Bindings (kata hpec); only backend_url/api_key vary per binding — Redaction,
-// LogLevel and AutoUpdate stay global. Bindings is omitempty so a pure
-// single-dir install's config.json is byte-identical to before this feature.
+// LogLevel, LogFormat, log rotation and AutoUpdate stay global. Bindings is
+// omitempty so a pure single-dir install's config.json is byte-identical to
+// before this feature.
 type UploadConfig struct {
-	BackendURL string           `json:"backend_url"`
-	APIKey     string           `json:"api_key"`
+	BackendURL string `json:"backend_url"`
+	APIKey     string `json:"api_key"`
+	// KeyName is the label APIKey was created under by device login
+	// (`login --name`), shown by `confab diagnose`. Empty for keys pasted
+	// with --api-key.
+	KeyName    string           `json:"key_name,omitempty"`
 	LogLevel   string           `json:"log_level,omitempty"`   // debug, info, warn, error (default: info)
+	LogFormat  string           `json:"log_format,omitempty"`  // text (default) or json
 	AutoUpdate *bool            `json:"auto_update,omitempty"` // nil = enabled (default), false = disabled
 	Redaction  *RedactionConfig `json:"redaction,omitempty"`
+	// LogMaxSizeMB is the size in megabytes at which the log file is
+	// rotated, and LogMaxBackups how many rotated files are kept. 0 (unset)
+	// keeps the logger's defaults (1 MB, 20 backups).
+	LogMaxSizeMB  int `json:"log_max_size_mb,omitempty"`
+	LogMaxBackups int `json:"log_max_backups,omitempty"`
+	// RedactionProfiles is a library of named redaction sections (e.g.
+	// "hipaa", "pci"). ActiveRedactionProfile, when set, names the one
+	// used instead of Redaction (see GetEffectiveRedactionConfig); a
+	// project config may set it to pick a profile for that project.
+	RedactionProfiles      map[string]*RedactionConfig `json:"redaction_profiles,omitempty"`
+	ActiveRedactionProfile string                      `json:"active_redaction_profile,omitempty"`
+	// RefreshToken, when set, lets an expired APIKey (a device-flow access
+	// token) be exchanged for a new one at /auth/token/refresh. ExpiresAt
+	// is when APIKey expires; zero means unknown or never expires.
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
+	// CompressionLevel selects the zstd level for chunk uploads, 1 (fastest)
+	// to 11 (smallest). 0 (unset) keeps the default level.
+	CompressionLevel int `json:"compression_level,omitempty"`
+	// Compression is the request body codec: "zstd" (default when empty),
+	// "gzip" for reverse proxies that only pass gzip, or "none".
+	// CompressionLevel applies to zstd only.
+	Compression string `json:"compression,omitempty"`
+	// SendFileLineCounts opts in to sending local per-file line counts with
+	// the sync init request so the backend can detect mismatches early.
+	SendFileLineCounts bool `json:"send_file_line_counts,omitempty"`
+	// SendSourceModTime opts in to sending each chunk's source file
+	// modification time in the chunk metadata.
+	SendSourceModTime bool `json:"send_source_mod_time,omitempty"`
+	// UploadPartialTail uploads a transcript's last line even while it is
+	// not yet valid JSON, instead of waiting for the write to finish.
+	UploadPartialTail bool `json:"upload_partial_tail,omitempty"`
+	// SkipOversizeLines uploads a placeholder for a line too large for any
+	// chunk instead of stopping the file's sync at that line.
+	SkipOversizeLines bool `json:"skip_oversize_lines,omitempty"`
+	// SyncAttachments opts in to uploading files referenced as document
+	// attachments in transcript tool results (up to 512 KB each).
+	SyncAttachments bool `json:"sync_attachments,omitempty"`
+	// HoldTailOnExit keeps holding back an incomplete last line during the
+	// daemon's final sync, instead of uploading it as the session ends.
+	HoldTailOnExit bool `json:"hold_tail_on_exit,omitempty"`
+	// MaxUploadBytesPerSecond caps the rate at which a sync client sends
+	// chunk request bodies (compressed), across concurrent uploads. 0
+	// (unset) means unlimited.
+	MaxUploadBytesPerSecond int64 `json:"max_upload_bps,omitempty"`
+	// MaxInFlightBytes bounds the total size of chunk bodies being uploaded
+	// concurrently; new uploads wait until capacity frees. 0 = unlimited.
+	MaxInFlightBytes int64 `json:"max_in_flight_bytes,omitempty"`
+	// MaxConcurrentUploads is how many agent files a sync cycle uploads in
+	// parallel. 0 (unset, e.g. configs from before this option) means 1;
+	// new installs get DefaultMaxConcurrentUploads.
+	MaxConcurrentUploads int `json:"max_concurrent_uploads,omitempty"`
+	// MaxAgentDepth is how deeply nested an agent file may be (the
+	// transcript is depth 0) and still be synced. 0 (unset) means
+	// pkg/sync's default of 5.
+	MaxAgentDepth int `json:"max_agent_depth,omitempty"`
+	// MaxTotalAgentFiles caps how many agent files a session syncs. 0
+	// (unset) means pkg/sync's default of 100.
+	MaxTotalAgentFiles int `json:"max_total_agent_files,omitempty"`
+	// MultipartUpload sends chunk bodies larger than UploadPartSize in
+	// parts, so an interrupted upload resumes from the first part the
+	// backend is missing instead of starting over.
+	MultipartUpload bool `json:"multipart_upload,omitempty"`
+	// UploadPartSize is the part size in bytes for multipart uploads. 0
+	// (unset) means pkg/sync's default of 1 MB.
+	UploadPartSize int `json:"upload_part_size,omitempty"`
+	// ProxyURL routes backend requests through an explicit proxy (http://,
+	// https://, socks5:// or socks5h://). Empty falls back to the
+	// HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables.
+	ProxyURL string `json:"proxy_url,omitempty"`
+	// CACertFile is a PEM bundle trusted in addition to the system roots,
+	// for backends with self-signed or internal-CA certificates.
+	CACertFile string `json:"ca_cert_file,omitempty"`
+	// TLSSkipVerify disables backend certificate verification. For
+	// development only; a warning is logged whenever it is in effect.
+	TLSSkipVerify bool `json:"tls_skip_verify,omitempty"`
+	// MaxConsecutive404 is how many consecutive "session not found" sync
+	// cycles the daemon tolerates before stopping (0 = daemon default, 3).
+	MaxConsecutive404 int `json:"max_consecutive_404,omitempty"`
+	// MaxConsecutive400 is how many times in a row the backend may reject
+	// the same chunk with 400 Bad Request before the chunk is split to
+	// isolate the offending line (0 = sync default, 3).
+	MaxConsecutive400 int `json:"max_consecutive_400,omitempty"`
+	// MaxSessions caps how many sessions one daemon manages at once (its
+	// own plus OpenCode child sessions; 0 = daemon default, 64).
+	MaxSessions int `json:"max_sessions,omitempty"`
+	// QuarantineRejectedLines replaces a single line the backend keeps
+	// rejecting with a placeholder so the rest of the file can sync.
+	// Defaults to true when nil; false leaves the file stuck at that line.
+	QuarantineRejectedLines *bool `json:"quarantine_rejected_lines,omitempty"`
+	// Fsync flushes settings and config writes to disk before renaming
+	// them into place, and (on Linux) their directory after, so a power
+	// loss can't leave a partial settings.json. Defaults to true when nil;
+	// false trades that for speed, e.g. in tests.
+	Fsync *bool `json:"fsync,omitempty"`
+	// TargetChunkDurationMS is how long a chunk upload should take; the
+	// daemon shrinks chunks when uploads run longer (0 = sync default, 5s).
+	TargetChunkDurationMS int `json:"target_chunk_duration_ms,omitempty"`
+	// MinChunkBytes is the smallest chunk size that shrinking goes down to
+	// (0 = sync default, 256 KiB).
+	MinChunkBytes int `json:"min_chunk_bytes,omitempty"`
+	// MaxRetries is how many times pkg/sync retries an init or chunk
+	// request that failed with a 5xx or network error (0 = no retries).
+	MaxRetries int `json:"max_retries,omitempty"`
+	// BaseBackoffMS is the first retry delay in milliseconds, doubled per
+	// attempt with jitter (0 = DefaultBaseBackoffMS).
+	BaseBackoffMS int `json:"base_backoff_ms,omitempty"`
+	// RequestTimeoutMS bounds each sync request attempt, from connecting
+	// to reading the whole response (0 = DefaultRequestTimeoutMS).
+	RequestTimeoutMS int `json:"request_timeout_ms,omitempty"`
+	// SyncIntervalMS is the daemon's base sync interval in milliseconds
+	// (0 = DefaultSyncIntervalMS).
+	SyncIntervalMS int `json:"sync_interval_ms,omitempty"`
+	// SyncJitterMS is the most random delay added to each sync interval
+	// (0 = none). Must not exceed the sync interval.
+	SyncJitterMS int `json:"sync_jitter_ms,omitempty"`
+	// SyncDeterministic pins the daemon to an exact sync cadence by forcing
+	// jitter to 0, whatever sync_jitter_ms or CONFAB_SYNC_JITTER_MS say.
+	SyncDeterministic bool `json:"sync_deterministic,omitempty"`
+	// Version is the config.json schema version (0 = written before
+	// versioning); `confab migrate` upgrades it to CurrentConfigVersion.
+	// Only the top-level value counts, not a profile's.
+	Version int `json:"version,omitempty"`
+	// UseKeyring stores API keys and refresh tokens in the OS keychain
+	// instead of this file; see keyring.go. A secret the keychain can't
+	// take stays in the file.
+	UseKeyring bool `json:"use_keyring,omitempty"`
+	// SyncSchedule limits when the daemon uploads (nil = any time).
+	SyncSchedule *SyncSchedule `json:"sync_schedule,omitempty"`
+	// StaticMetadata is fixed key/value metadata (e.g. department,
+	// cost_center) sent with every session's init and chunk requests.
+	// Bounded by ValidateStaticMetadata.
+	StaticMetadata map[string]string `json:"static_metadata,omitempty"`
 	// Bindings maps provider -> canonical config dir -> credentials.
 	Bindings map[string]map[string]BindingCreds `json:"bindings,omitempty"`
+
+	// binding is the binding GetUploadConfigFor resolved this config's
+	// credentials from; nil for the default binding. See CredentialBinding.
+	binding *Binding
 }
 
 // IsAutoUpdateEnabled returns whether auto-update is enabled.
@@ -35,11 +188,87 @@ func (c *UploadConfig) IsAutoUpdateEnabled() bool {
 	return c.AutoUpdate == nil || *c.AutoUpdate
 }
 
+// IsQuarantineEnabled returns whether rejected lines are quarantined.
+// Defaults to true when QuarantineRejectedLines is nil.
+func (c *UploadConfig) IsQuarantineEnabled() bool {
+	return c.QuarantineRejectedLines == nil || *c.QuarantineRejectedLines
+}
+
+// IsFsyncEnabled returns whether settings and config writes are flushed
+// to disk. Defaults to true when Fsync is nil.
+func (c *UploadConfig) IsFsyncEnabled() bool {
+	return c.Fsync == nil || *c.Fsync
+}
+
 // RedactionConfig holds redaction settings
 type RedactionConfig struct {
 	Enabled            bool               `json:"enabled"`
 	UseDefaultPatterns *bool              `json:"use_default_patterns,omitempty"` // defaults to true if nil
 	Patterns           []RedactionPattern `json:"patterns,omitempty"`
+	// DisabledDefaults lists default patterns (by exact Name) to skip while
+	// keeping the rest of the defaults enabled.
+	DisabledDefaults []string `json:"disabled_defaults,omitempty"`
+	// BypassMarker names a top-level JSON field (e.g. "_confab_noredact")
+	// that, set to true on a transcript line, uploads that line unredacted.
+	// The field itself is always stripped before upload. Empty disables the
+	// bypass.
+	BypassMarker string `json:"bypass_marker,omitempty"`
+	// Entropy opts in to masking standalone high-entropy tokens that no
+	// pattern matches (nil = disabled).
+	Entropy *EntropyConfig `json:"entropy,omitempty"`
+}
+
+// Defaults for EntropyConfig fields left at zero.
+const (
+	DefaultEntropyThreshold = 4.0
+	DefaultEntropyMinLength = 24
+	DefaultEntropyMaxLength = 256
+)
+
+// EntropyConfig configures high-entropy token redaction. A token is a run
+// of base64/base64url characters; it is masked when its length is within
+// [MinLength, MaxLength], it mixes upper case, lower case and digits, and
+// its Shannon entropy is at least Threshold bits per character.
+type EntropyConfig struct {
+	Enabled bool `json:"enabled"`
+	// Threshold is the minimum Shannon entropy in bits per character
+	// (0 = DefaultEntropyThreshold).
+	Threshold float64 `json:"threshold,omitempty"`
+	// MinLength is the shortest token considered (0 = DefaultEntropyMinLength).
+	MinLength int `json:"min_length,omitempty"`
+	// MaxLength is the longest token considered; longer runs are treated as
+	// encoded data such as base64 images and left alone
+	// (0 = DefaultEntropyMaxLength).
+	MaxLength int `json:"max_length,omitempty"`
+}
+
+// WithDefaults returns a copy with zero fields replaced by the defaults.
+func (c EntropyConfig) WithDefaults() EntropyConfig {
+	if c.Threshold == 0 {
+		c.Threshold = DefaultEntropyThreshold
+	}
+	if c.MinLength == 0 {
+		c.MinLength = DefaultEntropyMinLength
+	}
+	if c.MaxLength == 0 {
+		c.MaxLength = DefaultEntropyMaxLength
+	}
+	return c
+}
+
+// Validate checks the entropy settings are usable. Thresholds above 8 bits
+// per character can never be reached by byte strings.
+func (c *EntropyConfig) Validate() error {
+	if c.Threshold < 0 || c.Threshold > 8 {
+		return fmt.Errorf("threshold must be between 0 and 8 bits per character, got %g", c.Threshold)
+	}
+	if c.MinLength < 0 || c.MaxLength < 0 {
+		return fmt.Errorf("min_length and max_length must not be negative")
+	}
+	if d := c.WithDefaults(); d.MaxLength < d.MinLength {
+		return fmt.Errorf("max_length (%d) must be at least min_length (%d)", d.MaxLength, d.MinLength)
+	}
+	return nil
 }
 
 // ShouldUseDefaultPatterns returns true if default patterns should be used.
@@ -48,6 +277,30 @@ func (c *RedactionConfig) ShouldUseDefaultPatterns() bool {
 	return c.UseDefaultPatterns == nil || *c.UseDefaultPatterns
 }
 
+// EnabledDefaultPatterns returns the default patterns that apply under this
+// config: none if defaults are off, otherwise GetDefaultRedactionPatterns
+// minus any pattern whose Name appears in DisabledDefaults.
+func (c *RedactionConfig) EnabledDefaultPatterns() []RedactionPattern {
+	if !c.ShouldUseDefaultPatterns() {
+		return nil
+	}
+	defaults := GetDefaultRedactionPatterns()
+	if len(c.DisabledDefaults) == 0 {
+		return defaults
+	}
+	disabled := make(map[string]bool, len(c.DisabledDefaults))
+	for _, name := range c.DisabledDefaults {
+		disabled[name] = true
+	}
+	enabled := defaults[:0]
+	for _, p := range defaults {
+		if !disabled[p.Name] {
+			enabled = append(enabled, p)
+		}
+	}
+	return enabled
+}
+
 // RedactionPattern represents a single redaction pattern
 type RedactionPattern struct {
 	Name         string `json:"name"`
@@ -55,6 +308,218 @@ type RedactionPattern struct {
 	Type         string `json:"type"`
 	CaptureGroup int    `json:"capture_group,omitempty"`
 	FieldPattern string `json:"field_pattern,omitempty"`
+	// FieldNames redacts the whole string value of matching JSON keys,
+	// after the regex patterns ran. A plain name ("password") matches the
+	// key at any depth; a dotted one ("auth.password") matches that key
+	// path from the top-level object, with arrays transparent. Values are
+	// replaced with Replacement, "[REDACTED:TYPE]", or "[REDACTED]" when
+	// Type is empty (Type is optional for field_names-only patterns).
+	FieldNames []string `json:"field_names,omitempty"`
+	// Replacement overrides the default "[REDACTED:TYPE]" marker. It may
+	// reference capture groups ($1, ${name}) of the value pattern, or of the
+	// field pattern when the pattern has no value pattern.
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Validate checks that no custom pattern's Replacement itself looks like a
+// secret (i.e. matches one of the default value patterns) or references
+// the text being redacted (see checkReplacementRefs), either of which
+// would defeat the point of redacting, and that the entropy settings are
+// usable. It gates writes (SaveUploadConfig, confab config set); loading
+// only warns (see warnRedactionSettings).
+func (c *RedactionConfig) Validate() error {
+	if c.Entropy != nil {
+		if err := c.Entropy.Validate(); err != nil {
+			return fmt.Errorf("entropy: %w", err)
+		}
+	}
+
+	for _, p := range c.Patterns {
+		if p.Replacement == "" {
+			continue
+		}
+		for _, sp := range defaultSecretRegexps() {
+			if sp.re.MatchString(p.Replacement) {
+				return fmt.Errorf("pattern %q: replacement looks like a secret (matches %q)", p.Name, sp.name)
+			}
+		}
+		if err := checkReplacementRefs(p); err != nil {
+			return fmt.Errorf("pattern %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+type namedRegexp struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// defaultSecretRegexps is the compiled value patterns of the defaults,
+// compiled on first use.
+var defaultSecretRegexps = sync.OnceValue(func() []namedRegexp {
+	var compiled []namedRegexp
+	for _, p := range GetDefaultRedactionPatterns() {
+		if p.Pattern != "" {
+			compiled = append(compiled, namedRegexp{name: p.Name, re: regexp.MustCompile(p.Pattern)})
+		}
+	}
+	return compiled
+})
+
+// checkReplacementRefs rejects a replacement that references the text the
+// pattern redacts: $0, the capture_group being redacted, or a group
+// nested inside it, any of which would write the secret straight back.
+// Other groups (a "password=" prefix kept with $1) are fine. Field-only
+// patterns expand against the field name, which isn't secret.
+func checkReplacementRefs(p RedactionPattern) error {
+	if p.Pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(p.Pattern)
+	if err != nil {
+		return nil // reported by ValidateRedactionConfig
+	}
+	secret := secretGroups(p.Pattern, p.CaptureGroup)
+	for _, ref := range replacementRefs(p.Replacement) {
+		group, err := strconv.Atoi(ref)
+		if err != nil {
+			group = re.SubexpIndex(ref)
+		}
+		if secret[group] {
+			return fmt.Errorf("replacement references $%s, which would put the redacted text back", ref)
+		}
+	}
+	return nil
+}
+
+// secretGroups returns the group numbers whose text is redacted: 0, and
+// with a capture group, that group and every group inside it.
+func secretGroups(pattern string, captureGroup int) map[int]bool {
+	secret := map[int]bool{0: true}
+	if captureGroup == 0 {
+		return secret
+	}
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return secret
+	}
+	var walk func(r *syntax.Regexp, inside bool)
+	walk = func(r *syntax.Regexp, inside bool) {
+		if r.Op == syntax.OpCapture {
+			inside = inside || r.Cap == captureGroup
+			if inside {
+				secret[r.Cap] = true
+			}
+		}
+		for _, sub := range r.Sub {
+			walk(sub, inside)
+		}
+	}
+	walk(parsed, false)
+	return secret
+}
+
+// replacementRefs returns the group references in a replacement as
+// regexp.Expand reads them: the name or number of each $name or ${name},
+// skipping $$ escapes.
+func replacementRefs(replacement string) []string {
+	var refs []string
+	for i := 0; i < len(replacement); i++ {
+		if replacement[i] != '$' || i+1 == len(replacement) {
+			continue
+		}
+		rest := replacement[i+1:]
+		switch {
+		case rest[0] == '$':
+			i++
+		case rest[0] == '{':
+			if end := strings.IndexByte(rest, '}'); end > 1 {
+				refs = append(refs, rest[1:end])
+				i += end + 1
+			}
+		default:
+			n := 0
+			for n < len(rest) && (rest[n] == '_' || unicode.IsLetter(rune(rest[n])) || unicode.IsDigit(rune(rest[n]))) {
+				n++
+			}
+			if n > 0 {
+				refs = append(refs, rest[:n])
+				i += n
+			}
+		}
+	}
+	return refs
+}
+
+// GetEffectiveRedactionConfig returns the redaction settings uploads use:
+// the active redaction profile when one is set, otherwise the top-level
+// Redaction section. Nil means redaction is not configured.
+func (c *UploadConfig) GetEffectiveRedactionConfig() *RedactionConfig {
+	if c.ActiveRedactionProfile != "" {
+		if profile, ok := c.RedactionProfiles[c.ActiveRedactionProfile]; ok {
+			return profile
+		}
+	}
+	return c.Redaction
+}
+
+// validateRedactionProfiles checks each redaction profile and that the
+// active one exists, so a typo never silently falls back to Redaction.
+func (c *UploadConfig) validateRedactionProfiles() error {
+	if err := c.checkRedactionProfileRefs(); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.RedactionProfiles)) {
+		if err := c.RedactionProfiles[name].Validate(); err != nil {
+			return fmt.Errorf("redaction profile %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// checkRedactionProfileRefs is the part of validateRedactionProfiles that
+// loading enforces: valid, non-empty profiles and an active profile that
+// exists.
+func (c *UploadConfig) checkRedactionProfileRefs() error {
+	for _, name := range slices.Sorted(maps.Keys(c.RedactionProfiles)) {
+		if err := ValidateProfileName(name); err != nil {
+			return fmt.Errorf("redaction profile %q: %w", name, err)
+		}
+		if c.RedactionProfiles[name] == nil {
+			return fmt.Errorf("redaction profile %q is empty", name)
+		}
+	}
+	if c.ActiveRedactionProfile != "" {
+		if _, ok := c.RedactionProfiles[c.ActiveRedactionProfile]; !ok {
+			return fmt.Errorf("active_redaction_profile %q is not defined in redaction_profiles", c.ActiveRedactionProfile)
+		}
+	}
+	return nil
+}
+
+// ValidateRedactionConfig compiles every custom pattern's Pattern and
+// FieldPattern, so a bad regex is caught when the config is saved or a
+// daemon starts rather than when the redactor is first built mid-sync. The
+// error lists each pattern that fails, by name. A nil config is valid.
+func ValidateRedactionConfig(c *RedactionConfig) error {
+	if c == nil {
+		return nil
+	}
+	var errs []error
+	for _, p := range c.Patterns {
+		if p.Pattern != "" {
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				errs = append(errs, fmt.Errorf("pattern %q: invalid pattern: %w", p.Name, err))
+			}
+		}
+		if p.FieldPattern != "" {
+			if _, err := regexp.Compile(p.FieldPattern); err != nil {
+				errs = append(errs, fmt.Errorf("pattern %q: invalid field_pattern: %w", p.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // GetUploadConfig reads upload configuration from ~/.confab/config.json.
@@ -64,42 +529,124 @@ type RedactionPattern struct {
 // GetUploadConfigFor(provider.BindingFor(p, configDir)) instead — calling this
 // directly for a custom-config-dir session silently yields the wrong backend
 // (kata hpec).
+//
+// Overrides from a per-project .confab/config.json found above the working
+// directory are applied (see ProjectConfig). Code that modifies and saves
+// the config must start from GetGlobalUploadConfig instead, so project
+// values aren't written into the global file.
 func GetUploadConfig() (*UploadConfig, error) {
-	configPath, err := getConfigPath()
+	cfg, err := GetGlobalUploadConfig()
 	if err != nil {
 		return nil, err
 	}
-
-	// Return default config if file doesn't exist
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return &UploadConfig{
-			BackendURL: "",
-			APIKey:     "",
-		}, nil
+	if err := applyProjectConfig(cfg); err != nil {
+		return nil, err
+	}
+	// A project may name a profile the global library lacks.
+	if err := cfg.checkRedactionProfileRefs(); err != nil {
+		return nil, fmt.Errorf("invalid redaction profiles: %w", err)
 	}
+	return cfg, nil
+}
 
-	// Read and parse config file
-	data, err := os.ReadFile(configPath)
+// GetGlobalUploadConfig reads ~/.confab/config.json alone, without project
+// overrides. Use it for read-modify-write with SaveUploadConfig.
+func GetGlobalUploadConfig() (*UploadConfig, error) {
+	configPath, err := UploadConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	config, err := readUploadConfigFile(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read confab config (%s): %w", configPath, err)
+		return nil, err
+	}
+
+	config.warnRedactionSettings(configPath)
+	if err := config.checkRedactionProfileRefs(); err != nil {
+		return nil, fmt.Errorf("confab config has invalid redaction profiles (%s): %w", configPath, err)
+	}
+
+	if config.SyncSchedule != nil {
+		if err := config.SyncSchedule.Validate(); err != nil {
+			return nil, fmt.Errorf("confab config has invalid sync schedule (%s): %w", configPath, err)
+		}
+	}
+	if _, err := ParseProxyURL(config.ProxyURL); err != nil {
+		return nil, fmt.Errorf("confab config has invalid proxy_url (%s): %w", configPath, err)
+	}
+
+	return config, nil
+}
+
+// warnedRedaction holds the redaction warnings already logged, so a config
+// loaded on every sync cycle warns once per process.
+var warnedRedaction sync.Map
+
+// warnRedactionSettings logs, rather than fails on, redaction and profile
+// settings that RedactionConfig.Validate rejects. Writes refuse them, so
+// only a hand-edited file has them, and it shouldn't stop every command
+// from loading the config; `confab config validate` reports them in full.
+func (c *UploadConfig) warnRedactionSettings(configPath string) {
+	warn := func(section string, err error) {
+		if _, seen := warnedRedaction.LoadOrStore(section+": "+err.Error(), true); seen {
+			return
+		}
+		logger.Warn("Invalid redaction settings in config; fix them with confab config set", "component", "config", "path", configPath, "section", section, "error", err)
 	}
+	if c.Redaction != nil {
+		if err := c.Redaction.Validate(); err != nil {
+			warn("redaction", err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.RedactionProfiles)) {
+		if profile := c.RedactionProfiles[name]; profile != nil {
+			if err := profile.Validate(); err != nil {
+				warn("redaction_profiles."+name, err)
+			}
+		}
+	}
+}
 
-	var config UploadConfig
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("confab config has invalid JSON (%s): %w", configPath, err)
+// LoadUnvalidatedUploadConfig reads ~/.confab/config.json like
+// GetGlobalUploadConfig but skips its validation, so `confab config
+// validate` can report every problem (see ValidateConfig) instead of
+// stopping at the first. Only unreadable or malformed JSON is an error.
+func LoadUnvalidatedUploadConfig() (*UploadConfig, error) {
+	configPath, err := UploadConfigPath()
+	if err != nil {
+		return nil, err
 	}
+	return readUploadConfigFile(configPath)
+}
 
-	return &config, nil
+// readUploadConfigFile parses the active profile (see ActiveProfile) of
+// the config at configPath, with keyring secrets filled in. A missing file
+// or profile is the empty default config.
+func readUploadConfigFile(configPath string) (*UploadConfig, error) {
+	f, err := readConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := f.activeProfile()
+	if err != nil {
+		return nil, err
+	}
+	config := f.profile(profile)
+	if config.UseKeyring {
+		config.loadKeyringSecrets(profile)
+	}
+	return config, nil
 }
 
-// SaveUploadConfig writes upload configuration to ~/.confab/config.json
+// SaveUploadConfig writes upload configuration to ~/.confab/config.json,
+// as the active profile (see ActiveProfile); other profiles are kept.
 func SaveUploadConfig(config *UploadConfig) error {
 	// Validate before saving
 	if err := config.Validate(); err != nil {
 		return err
 	}
 
-	configPath, err := getConfigPath()
+	configPath, err := UploadConfigPath()
 	if err != nil {
 		return err
 	}
@@ -111,30 +658,37 @@ func SaveUploadConfig(config *UploadConfig) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Marshal config to JSON
-	data, err := json.MarshalIndent(config, "", "  ")
+	// An unreadable existing file is replaced, as before profiles.
+	f, err := readConfigFile(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		f = &configFile{}
 	}
-
-	// Write to file
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
+	profile, err := f.activeProfile()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	// With UseKeyring, API keys go to the keyring, not the file
+	if config.UseKeyring {
+		config = config.withoutKeyringSecrets(profile)
+	}
+	f.setProfile(profile, config)
+	return writeConfigFile(configPath, f)
 }
 
-func getConfigPath() (string, error) {
+// UploadConfigPath returns the path of config.json: CONFAB_CONFIG_PATH
+// when set, else config.json in confabpath.ConfigDir (~/.confab by default).
+func UploadConfigPath() (string, error) {
 	// Allow overriding config path for testing
-	if testConfigPath := os.Getenv("CONFAB_CONFIG_PATH"); testConfigPath != "" {
+	if testConfigPath := os.Getenv(ConfigPathEnv); testConfigPath != "" {
 		return testConfigPath, nil
 	}
-	return confabpath.Subpath("config.json")
+	return confabpath.ConfigSubpath("config.json")
 }
 
-// validateBackendURL checks if the backend URL is valid
-func validateBackendURL(backendURL string) error {
+// ValidateBackendURL checks if the backend URL is valid. Empty is allowed
+// (not configured).
+func ValidateBackendURL(backendURL string) error {
 	if backendURL == "" {
 		return nil // Empty is allowed (not configured)
 	}
@@ -162,10 +716,118 @@ func validateBackendURL(backendURL string) error {
 	return nil
 }
 
-// validateAPIKey checks if the API key format is valid.
+// ParseProxyURL parses a proxy_url value. Empty returns (nil, nil), meaning
+// no explicit proxy. Only http, https, socks5 and socks5h schemes are
+// accepted, since those are what net/http's Transport can dial.
+func ParseProxyURL(proxyURL string) (*url.URL, error) {
+	if proxyURL == "" {
+		return nil, nil
+	}
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL format: %w", err)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, socks5 or socks5h)", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("proxy URL must include a host")
+	}
+	return parsed, nil
+}
+
+// SetProxyURL saves proxy_url to the global config, preserving all other
+// settings. Empty clears it.
+func SetProxyURL(proxyURL string) error {
+	if _, err := ParseProxyURL(proxyURL); err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.ProxyURL = proxyURL
+	return SaveUploadConfig(cfg)
+}
+
+// LoadCACertPool returns the system cert pool extended with the PEM
+// certificates in path. It fails if the file holds no certificates, so a
+// wrong path or format is caught rather than silently trusting nothing new.
+func LoadCACertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// SetTLSSettings saves ca_cert_file and tls_skip_verify to the global
+// config, preserving all other settings. caCertFile is stored as given, so
+// callers should pass an absolute path.
+func SetTLSSettings(caCertFile string, skipVerify bool) error {
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.CACertFile = caCertFile
+	cfg.TLSSkipVerify = skipVerify
+	return SaveUploadConfig(cfg)
+}
+
+// ActivateRedactionProfile saves active_redaction_profile to the global
+// config. The profile must exist in redaction_profiles.
+func ActivateRedactionProfile(name string) error {
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.RedactionProfiles[name]; !ok {
+		return fmt.Errorf("no redaction profile named %q (add one with 'confab redaction add-profile %s')", name, name)
+	}
+	cfg.ActiveRedactionProfile = name
+	return SaveUploadConfig(cfg)
+}
+
+// AddRedactionProfile saves a new redaction profile to the global config,
+// starting as a copy of the top-level redaction section (default patterns,
+// enabled, when there is none) for the user to edit. An existing profile
+// is not replaced.
+func AddRedactionProfile(name string) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.RedactionProfiles[name]; ok {
+		return fmt.Errorf("redaction profile %q already exists", name)
+	}
+	profile := &RedactionConfig{Enabled: true}
+	if cfg.Redaction != nil {
+		copied := *cfg.Redaction
+		profile = &copied
+	}
+	if cfg.RedactionProfiles == nil {
+		cfg.RedactionProfiles = make(map[string]*RedactionConfig)
+	}
+	cfg.RedactionProfiles[name] = profile
+	return SaveUploadConfig(cfg)
+}
+
+// ValidateAPIKey checks if the API key format is valid.
 // Confab API keys have the format: cfb_<40 alphanumeric chars>
 // Returns nil for empty string (not configured), but empty is not a valid key.
-func validateAPIKey(apiKey string) error {
+func ValidateAPIKey(apiKey string) error {
 	// Empty means not configured - skip validation but callers should
 	// check separately if authentication is required
 	if apiKey == "" {
@@ -193,16 +855,202 @@ func validateAPIKey(apiKey string) error {
 	return nil
 }
 
+// Compression level bounds for UploadConfig.CompressionLevel. The range
+// follows zstd's numeric levels, which the encoder maps onto its speed
+// presets (fastest, default, better, best).
+const (
+	MinCompressionLevel = 1
+	MaxCompressionLevel = 11
+)
+
+// Request body codecs for UploadConfig.Compression.
+const (
+	CompressionZstd = "zstd"
+	CompressionGzip = "gzip"
+	CompressionNone = "none"
+)
+
+// ValidateCompression checks that codec is empty (zstd) or one of
+// CompressionZstd, CompressionGzip and CompressionNone.
+func ValidateCompression(codec string) error {
+	switch codec {
+	case "", CompressionZstd, CompressionGzip, CompressionNone:
+		return nil
+	default:
+		return fmt.Errorf("must be %q, %q or %q, got %q", CompressionZstd, CompressionGzip, CompressionNone, codec)
+	}
+}
+
+// Limits for UploadConfig.StaticMetadata, which rides along on every
+// chunk upload and so must stay small.
+const (
+	MaxStaticMetadataKeys        = 32
+	MaxStaticMetadataKeyLength   = 64
+	MaxStaticMetadataValueLength = 256
+)
+
+// staticMetadataKeyPattern limits static metadata keys to identifier-like
+// names the backend can index.
+var staticMetadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ValidateStaticMetadata checks that metadata has at most
+// MaxStaticMetadataKeys entries, keys of letters, digits, '_', '-' and '.'
+// up to MaxStaticMetadataKeyLength, and values up to
+// MaxStaticMetadataValueLength bytes. Keys are checked in sorted order, so
+// the error reported is stable.
+func ValidateStaticMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxStaticMetadataKeys {
+		return fmt.Errorf("at most %d keys allowed, got %d", MaxStaticMetadataKeys, len(metadata))
+	}
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if len(k) > MaxStaticMetadataKeyLength {
+			return fmt.Errorf("key %q is longer than %d characters", k, MaxStaticMetadataKeyLength)
+		}
+		if !staticMetadataKeyPattern.MatchString(k) {
+			return fmt.Errorf("key %q may only contain letters, digits, '_', '-' and '.'", k)
+		}
+		if len(metadata[k]) > MaxStaticMetadataValueLength {
+			return fmt.Errorf("value of %q is longer than %d bytes", k, MaxStaticMetadataValueLength)
+		}
+	}
+	return nil
+}
+
+// ValidateCompressionLevel checks that level is 0 (default) or within
+// MinCompressionLevel..MaxCompressionLevel.
+func ValidateCompressionLevel(level int) error {
+	if level == 0 {
+		return nil
+	}
+	if level < MinCompressionLevel || level > MaxCompressionLevel {
+		return fmt.Errorf("must be between %d and %d, got %d", MinCompressionLevel, MaxCompressionLevel, level)
+	}
+	return nil
+}
+
 // Validate checks if the upload config is valid
 func (c *UploadConfig) Validate() error {
-	if err := validateBackendURL(c.BackendURL); err != nil {
+	if err := ValidateBackendURL(c.BackendURL); err != nil {
 		return fmt.Errorf("invalid backend URL: %w", err)
 	}
 
-	if err := validateAPIKey(c.APIKey); err != nil {
+	if err := ValidateAPIKey(c.APIKey); err != nil {
 		return fmt.Errorf("invalid API key: %w", err)
 	}
 
+	if err := ValidateCompressionLevel(c.CompressionLevel); err != nil {
+		return fmt.Errorf("invalid compression level: %w", err)
+	}
+
+	if err := ValidateCompression(c.Compression); err != nil {
+		return fmt.Errorf("invalid compression: %w", err)
+	}
+
+	if c.MaxUploadBytesPerSecond < 0 {
+		return fmt.Errorf("invalid max upload rate: must not be negative, got %d", c.MaxUploadBytesPerSecond)
+	}
+
+	if c.MaxConcurrentUploads < 0 {
+		return fmt.Errorf("invalid max concurrent uploads: must not be negative, got %d", c.MaxConcurrentUploads)
+	}
+
+	if c.MaxAgentDepth < 0 {
+		return fmt.Errorf("invalid max agent depth: must not be negative, got %d", c.MaxAgentDepth)
+	}
+
+	if c.MaxTotalAgentFiles < 0 {
+		return fmt.Errorf("invalid max total agent files: must not be negative, got %d", c.MaxTotalAgentFiles)
+	}
+
+	if c.MaxInFlightBytes < 0 {
+		return fmt.Errorf("invalid max in-flight bytes: must not be negative, got %d", c.MaxInFlightBytes)
+	}
+
+	if c.UploadPartSize < 0 {
+		return fmt.Errorf("invalid upload part size: must not be negative, got %d", c.UploadPartSize)
+	}
+
+	if c.MaxConsecutive404 < 0 {
+		return fmt.Errorf("invalid max consecutive 404s: must be at least 1 (or 0 for the default), got %d", c.MaxConsecutive404)
+	}
+
+	if c.MaxConsecutive400 < 0 {
+		return fmt.Errorf("invalid max consecutive 400s: must be at least 1 (or 0 for the default), got %d", c.MaxConsecutive400)
+	}
+
+	if c.MaxSessions < 0 {
+		return fmt.Errorf("invalid max sessions: must be at least 1 (or 0 for the default), got %d", c.MaxSessions)
+	}
+
+	if c.TargetChunkDurationMS < 0 || c.MinChunkBytes < 0 {
+		return fmt.Errorf("invalid adaptive chunk sizing: target_chunk_duration_ms and min_chunk_bytes must not be negative")
+	}
+
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("invalid max retries: must not be negative, got %d", c.MaxRetries)
+	}
+
+	if c.BaseBackoffMS < 0 {
+		return fmt.Errorf("invalid base backoff: must not be negative, got %d", c.BaseBackoffMS)
+	}
+
+	if c.RequestTimeoutMS < 0 {
+		return fmt.Errorf("invalid request timeout: must not be negative, got %d", c.RequestTimeoutMS)
+	}
+
+	if c.SyncIntervalMS < 0 || c.SyncJitterMS < 0 {
+		return fmt.Errorf("invalid sync timing: sync_interval_ms and sync_jitter_ms must not be negative")
+	}
+	if err := c.validateSyncJitter(); err != nil {
+		return fmt.Errorf("invalid sync timing: %w", err)
+	}
+
+	if c.LogMaxSizeMB < 0 || c.LogMaxBackups < 0 {
+		return fmt.Errorf("invalid log rotation: log_max_size_mb and log_max_backups must not be negative")
+	}
+
+	if _, err := ParseProxyURL(c.ProxyURL); err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	if c.CACertFile != "" {
+		if _, err := LoadCACertPool(c.CACertFile); err != nil {
+			return fmt.Errorf("invalid CA cert file: %w", err)
+		}
+	}
+
+	if c.Redaction != nil {
+		if err := c.Redaction.Validate(); err != nil {
+			return fmt.Errorf("invalid redaction config: %w", err)
+		}
+		if err := ValidateRedactionConfig(c.Redaction); err != nil {
+			return fmt.Errorf("invalid redaction config: %w", err)
+		}
+	}
+	if err := c.validateRedactionProfiles(); err != nil {
+		return fmt.Errorf("invalid redaction profiles: %w", err)
+	}
+	for _, profile := range c.RedactionProfiles {
+		if err := ValidateRedactionConfig(profile); err != nil {
+			return fmt.Errorf("invalid redaction config: %w", err)
+		}
+	}
+
+	if c.SyncSchedule != nil {
+		if err := c.SyncSchedule.Validate(); err != nil {
+			return fmt.Errorf("invalid sync schedule: %w", err)
+		}
+	}
+
+	if err := ValidateStaticMetadata(c.StaticMetadata); err != nil {
+		return fmt.Errorf("invalid static metadata: %w", err)
+	}
+
 	return nil
 }
 
@@ -426,11 +1274,106 @@ func GetDefaultRedactionPatterns() []RedactionPattern {
 	}
 }
 
+// DefaultMaxConcurrentUploads is the max_concurrent_uploads written for new
+// installs by EnsureNewInstallDefaults.
+const DefaultMaxConcurrentUploads = 4
+
+// DefaultBaseBackoffMS is the first sync retry delay when base_backoff_ms
+// is unset.
+const DefaultBaseBackoffMS = 500
+
+// DefaultRequestTimeoutMS is the sync request timeout when
+// request_timeout_ms is unset.
+const DefaultRequestTimeoutMS = 30_000
+
+// DefaultSyncIntervalMS is the daemon's sync interval when
+// sync_interval_ms is unset.
+const DefaultSyncIntervalMS = 30_000
+
+// SyncInterval returns sync_interval_ms as a duration, or the default when
+// it is unset.
+func (c *UploadConfig) SyncInterval() time.Duration {
+	if c.SyncIntervalMS > 0 {
+		return time.Duration(c.SyncIntervalMS) * time.Millisecond
+	}
+	return DefaultSyncIntervalMS * time.Millisecond
+}
+
+// SyncJitter returns sync_jitter_ms as a duration, or 0 in deterministic
+// mode.
+func (c *UploadConfig) SyncJitter() time.Duration {
+	if c.SyncDeterministic || c.SyncJitterMS <= 0 {
+		return 0
+	}
+	return time.Duration(c.SyncJitterMS) * time.Millisecond
+}
+
+// validateSyncJitter rejects a sync_jitter_ms larger than the effective
+// sync interval.
+func (c *UploadConfig) validateSyncJitter() error {
+	if c.SyncJitterMS > 0 && time.Duration(c.SyncJitterMS)*time.Millisecond > c.SyncInterval() {
+		return fmt.Errorf("sync_jitter_ms (%d) must not exceed the sync interval (%s)", c.SyncJitterMS, c.SyncInterval())
+	}
+	return nil
+}
+
+// RequestTimeout returns request_timeout_ms as a duration, or the default
+// when it is unset.
+func (c *UploadConfig) RequestTimeout() time.Duration {
+	if c.RequestTimeoutMS > 0 {
+		return time.Duration(c.RequestTimeoutMS) * time.Millisecond
+	}
+	return DefaultRequestTimeoutMS * time.Millisecond
+}
+
+// EnsureNewInstallDefaults applies the defaults setup writes only for a new
+// install: max_concurrent_uploads, which existing installs leave at
+// sequential uploads. An install setup hasn't completed before has no
+// redaction section, so call this before EnsureDefaultRedaction adds one.
+// A config without a schema version is stamped with CurrentConfigVersion
+// when there is nothing to migrate (MigrationPending for the default
+// Claude settings), whether or not the install is new.
+// Returns true if the config was changed.
+func EnsureNewInstallDefaults() (bool, error) {
+	cfg, err := GetGlobalUploadConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	changed := false
+	if cfg.Redaction == nil && cfg.MaxConcurrentUploads == 0 {
+		cfg.MaxConcurrentUploads = DefaultMaxConcurrentUploads
+		changed = true
+	}
+	if cfg.Version == 0 {
+		settingsPath, err := GetSettingsPath()
+		if err != nil {
+			return false, err
+		}
+		pending, err := MigrationPending(0, settingsPath)
+		if err != nil {
+			return false, fmt.Errorf("failed to check pending migrations: %w", err)
+		}
+		if !pending {
+			cfg.Version = CurrentConfigVersion
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	if err := SaveUploadConfig(cfg); err != nil {
+		return false, fmt.Errorf("failed to save config: %w", err)
+	}
+	return true, nil
+}
+
 // EnsureDefaultRedaction ensures the config has a redaction section with defaults.
 // If redaction config already exists (even if disabled), it's left unchanged.
 // Returns true if defaults were added, false if config already had redaction settings.
 func EnsureDefaultRedaction() (bool, error) {
-	cfg, err := GetUploadConfig()
+	cfg, err := GetGlobalUploadConfig()
 	if err != nil {
 		return false, fmt.Errorf("failed to get config: %w", err)
 	}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+// ValidationError is one problem ValidateConfig found. Field is the JSON
+// path of the setting, e.g. "redaction.patterns[2].type".
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// patternTypePattern limits a redaction pattern's type to what reads well
+// inside its "[REDACTED:TYPE]" marker.
+var patternTypePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateConfig checks cfg field by field and returns every problem found,
+// in a stable order, or nil when the config is usable. Unlike
+// UploadConfig.Validate, which stops at the first problem, it is meant for
+// reporting, e.g. by `confab config validate`. An unset backend_url or
+// api_key is reported too, since nothing can sync without them.
+func ValidateConfig(cfg *UploadConfig) []ValidationError {
+	var errs []ValidationError
+	add := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, ValidationError{Field: field, Message: err.Error()})
+		}
+	}
+
+	if cfg.BackendURL == "" {
+		add("backend_url", fmt.Errorf("not set (run 'confab login')"))
+	} else {
+		add("backend_url", ValidateBackendURL(cfg.BackendURL))
+	}
+	if cfg.APIKey == "" {
+		add("api_key", fmt.Errorf("not set (run 'confab login')"))
+	} else {
+		add("api_key", ValidateAPIKey(cfg.APIKey))
+	}
+
+	providers := make([]string, 0, len(cfg.Bindings))
+	for p := range cfg.Bindings {
+		providers = append(providers, p)
+	}
+	sort.Strings(providers)
+	for _, p := range providers {
+		dirs := make([]string, 0, len(cfg.Bindings[p]))
+		for dir := range cfg.Bindings[p] {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		for _, dir := range dirs {
+			creds := cfg.Bindings[p][dir]
+			prefix := fmt.Sprintf("bindings.%s[%q].", p, dir)
+			add(prefix+"backend_url", ValidateBackendURL(creds.BackendURL))
+			add(prefix+"api_key", ValidateAPIKey(creds.APIKey))
+		}
+	}
+
+	if cfg.LogLevel != "" {
+		_, err := ParseLogLevel(cfg.LogLevel)
+		add("log_level", err)
+	}
+	if _, err := logger.ParseFormat(cfg.LogFormat); err != nil {
+		add("log_format", err)
+	}
+	if _, err := ParseProxyURL(cfg.ProxyURL); err != nil {
+		add("proxy_url", err)
+	}
+	if cfg.CACertFile != "" {
+		_, err := LoadCACertPool(cfg.CACertFile)
+		add("ca_cert_file", err)
+	}
+	add("compression", ValidateCompression(cfg.Compression))
+	add("compression_level", ValidateCompressionLevel(cfg.CompressionLevel))
+	for _, n := range []struct {
+		field string
+		value int64
+	}{
+		{"max_upload_bps", cfg.MaxUploadBytesPerSecond},
+		{"max_concurrent_uploads", int64(cfg.MaxConcurrentUploads)},
+		{"max_agent_depth", int64(cfg.MaxAgentDepth)},
+		{"max_total_agent_files", int64(cfg.MaxTotalAgentFiles)},
+		{"max_in_flight_bytes", cfg.MaxInFlightBytes},
+		{"upload_part_size", int64(cfg.UploadPartSize)},
+		{"max_consecutive_404", int64(cfg.MaxConsecutive404)},
+		{"max_consecutive_400", int64(cfg.MaxConsecutive400)},
+		{"max_sessions", int64(cfg.MaxSessions)},
+		{"target_chunk_duration_ms", int64(cfg.TargetChunkDurationMS)},
+		{"min_chunk_bytes", int64(cfg.MinChunkBytes)},
+		{"max_retries", int64(cfg.MaxRetries)},
+		{"base_backoff_ms", int64(cfg.BaseBackoffMS)},
+		{"request_timeout_ms", int64(cfg.RequestTimeoutMS)},
+		{"sync_interval_ms", int64(cfg.SyncIntervalMS)},
+		{"sync_jitter_ms", int64(cfg.SyncJitterMS)},
+		{"log_max_size_mb", int64(cfg.LogMaxSizeMB)},
+		{"log_max_backups", int64(cfg.LogMaxBackups)},
+	} {
+		if n.value < 0 {
+			add(n.field, fmt.Errorf("must not be negative, got %d", n.value))
+		}
+	}
+
+	if cfg.Redaction != nil {
+		for i, p := range cfg.Redaction.Patterns {
+			for _, err := range validateRedactionPattern(p) {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("redaction.patterns[%d].%s", i, err.Field),
+					Message: err.Message,
+				})
+			}
+		}
+		add("redaction", cfg.Redaction.Validate())
+	}
+	add("redaction_profiles", cfg.validateRedactionProfiles())
+	add("sync_jitter_ms", cfg.validateSyncJitter())
+	if cfg.SyncSchedule != nil {
+		add("sync_schedule", cfg.SyncSchedule.Validate())
+	}
+	add("static_metadata", ValidateStaticMetadata(cfg.StaticMetadata))
+	return errs
+}
+
+// validateRedactionPattern checks one custom redaction pattern; Field in
+// the results is relative to the pattern. A pattern needs a name, a type,
+// and a value pattern, a field pattern or both (a field pattern narrowed
+// by a value pattern), each of which must compile, and/or field names.
+// A pattern with only field names may omit the type.
+func validateRedactionPattern(p RedactionPattern) []ValidationError {
+	var errs []ValidationError
+	if p.Name == "" {
+		errs = append(errs, ValidationError{"name", "must not be empty"})
+	}
+	onlyFieldNames := p.Pattern == "" && p.FieldPattern == "" && len(p.FieldNames) > 0
+	switch {
+	case p.Type == "":
+		if !onlyFieldNames {
+			errs = append(errs, ValidationError{"type", "must not be empty"})
+		}
+	case !patternTypePattern.MatchString(p.Type):
+		errs = append(errs, ValidationError{"type", fmt.Sprintf("%q may only contain letters, digits, '_' and '-'", p.Type)})
+	}
+	if p.Pattern == "" && p.FieldPattern == "" && len(p.FieldNames) == 0 {
+		errs = append(errs, ValidationError{"pattern", "one of pattern, field_pattern or field_names is required"})
+	}
+	for i, name := range p.FieldNames {
+		if name == "" || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+			errs = append(errs, ValidationError{fmt.Sprintf("field_names[%d]", i), fmt.Sprintf("%q is not a key name or dotted key path", name)})
+		}
+	}
+	var re *regexp.Regexp
+	if p.Pattern != "" {
+		var err error
+		if re, err = regexp.Compile(p.Pattern); err != nil {
+			errs = append(errs, ValidationError{"pattern", err.Error()})
+		}
+	}
+	if p.FieldPattern != "" {
+		if _, err := regexp.Compile(p.FieldPattern); err != nil {
+			errs = append(errs, ValidationError{"field_pattern", err.Error()})
+		}
+	}
+	if p.CaptureGroup < 0 || (re != nil && p.CaptureGroup > re.NumSubexp()) {
+		errs = append(errs, ValidationError{"capture_group", fmt.Sprintf("%d is not a group of the pattern", p.CaptureGroup)})
+	}
+	return errs
+}
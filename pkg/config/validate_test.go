@@ -0,0 +1,127 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func validTestConfig() *UploadConfig {
+	return &UploadConfig{
+		BackendURL: "https://confab.example.com",
+		APIKey:     "cfb_abcdefghijklmnopqrstuvwxyz12345678901234",
+		Redaction: &RedactionConfig{
+			Enabled: true,
+			Patterns: []RedactionPattern{
+				{Name: "Ticket", Pattern: `TICKET-\d+`, Type: "ticket"},
+				// A field pattern narrowed by a value pattern is valid.
+				{Name: "Internal host", Pattern: `\.corp$`, FieldPattern: `^host$`, Type: "host"},
+			},
+		},
+	}
+}
+
+func TestValidateConfig_Valid(t *testing.T) {
+	if errs := ValidateConfig(validTestConfig()); errs != nil {
+		t.Errorf("ValidateConfig() = %v, want nil", errs)
+	}
+}
+
+func TestValidateConfig_Unset(t *testing.T) {
+	errs := ValidateConfig(&UploadConfig{})
+	if len(errs) != 2 || errs[0].Field != "backend_url" || errs[1].Field != "api_key" {
+		t.Fatalf("ValidateConfig(empty) = %v, want backend_url and api_key errors", errs)
+	}
+	if !strings.Contains(errs[0].Message, "confab login") {
+		t.Errorf("unset backend_url message %q should point at confab login", errs[0].Message)
+	}
+}
+
+func TestValidateConfig_ReportsEveryProblem(t *testing.T) {
+	cfg := validTestConfig()
+	cfg.BackendURL = "ftp://confab.example.com"
+	cfg.APIKey = "not-a-key"
+	cfg.MaxRetries = -1
+	cfg.StaticMetadata = map[string]string{"cost center": "cc-42"}
+	cfg.Redaction.Patterns = append(cfg.Redaction.Patterns,
+		RedactionPattern{},
+		RedactionPattern{Name: "Broken", Pattern: `(unclosed`, Type: "has space"},
+		RedactionPattern{Name: "Group", Pattern: `(a)(b)`, Type: "ab", CaptureGroup: 3},
+	)
+
+	got := map[string]bool{}
+	for _, e := range ValidateConfig(cfg) {
+		got[e.Field] = true
+	}
+	want := []string{
+		"backend_url",
+		"api_key",
+		"max_retries",
+		"static_metadata",
+		"redaction.patterns[2].name",
+		"redaction.patterns[2].type",
+		"redaction.patterns[2].pattern",
+		"redaction.patterns[3].pattern",
+		"redaction.patterns[3].type",
+		"redaction.patterns[4].capture_group",
+	}
+	for _, field := range want {
+		if !got[field] {
+			t.Errorf("no error reported for %s (got %v)", field, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got errors for %v, want exactly %v", got, want)
+	}
+	for _, ok := range []string{"redaction.patterns[0]", "redaction.patterns[1]"} {
+		for field := range got {
+			if strings.HasPrefix(field, ok+".") {
+				t.Errorf("valid pattern reported invalid: %s", field)
+			}
+		}
+	}
+}
+
+func TestValidateConfig_BadFieldPattern(t *testing.T) {
+	cfg := validTestConfig()
+	cfg.Redaction.Patterns = []RedactionPattern{{Name: "Field", FieldPattern: `[`, Type: "field"}}
+	errs := ValidateConfig(cfg)
+	if len(errs) != 1 || errs[0].Field != "redaction.patterns[0].field_pattern" {
+		t.Errorf("ValidateConfig() = %v, want one field_pattern error", errs)
+	}
+}
+
+func TestValidateConfig_FieldNames(t *testing.T) {
+	cfg := validTestConfig()
+	cfg.Redaction.Patterns = []RedactionPattern{{Name: "Passwords", FieldNames: []string{"password", "auth.token"}}}
+	if errs := ValidateConfig(cfg); len(errs) != 0 {
+		t.Errorf("ValidateConfig() = %v, want a field_names-only pattern without a type accepted", errs)
+	}
+
+	cfg.Redaction.Patterns = []RedactionPattern{{Name: "Bad", FieldNames: []string{"ok", "", "a..b"}}}
+	errs := ValidateConfig(cfg)
+	if len(errs) != 2 || errs[0].Field != "redaction.patterns[0].field_names[1]" || errs[1].Field != "redaction.patterns[0].field_names[2]" {
+		t.Errorf("ValidateConfig() = %v, want errors for the empty and malformed names", errs)
+	}
+}
+
+func TestValidateConfig_SyncJitterWithinInterval(t *testing.T) {
+	cfg := validTestConfig()
+	cfg.SyncJitterMS = 5_000
+	if errs := ValidateConfig(cfg); errs != nil {
+		t.Errorf("ValidateConfig() = %v, want jitter under the default interval accepted", errs)
+	}
+
+	cfg.SyncIntervalMS = 2_000
+	errs := ValidateConfig(cfg)
+	if len(errs) != 1 || errs[0].Field != "sync_jitter_ms" {
+		t.Errorf("ValidateConfig() = %v, want one sync_jitter_ms error", errs)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "sync_jitter_ms") {
+		t.Errorf("Validate() = %v, want a sync_jitter_ms error", err)
+	}
+
+	cfg.SyncJitterMS = 2_000
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want jitter equal to the interval accepted", err)
+	}
+}
@@ -1,8 +1,15 @@
 package daemon
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/ConfabulousDev/confab/pkg/provider"
 )
 
@@ -24,3 +31,50 @@ func TestDaemonBinding(t *testing.T) {
 		t.Errorf("custom ConfigDir: IsDefault=true, want false")
 	}
 }
+
+// TestScheduleAllowsSync_BindingAndReload: the sync_schedule is read through
+// the daemon's binding, reloaded only when the config file changes, and an
+// unreadable config allows syncing.
+func TestScheduleAllowsSync_BindingAndReload(t *testing.T) {
+	t.Setenv(provider.ClaudeStateDirEnv, t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(config.ConfigPathEnv, configPath)
+
+	d := New(Config{Provider: provider.NameClaudeCode, ConfigDir: t.TempDir()})
+	b := d.binding()
+	now := time.Now()
+	schedule := fmt.Sprintf(`"sync_schedule":{"quiet_hours":[{"start":%q,"end":%q}]}`,
+		now.Add(-time.Hour).Format("15:04"), now.Add(time.Hour).Format("15:04"))
+	bindings := fmt.Sprintf(`"bindings":{%q:{%q:{"backend_url":"https://confab.example.com","api_key":"cfb_binding-key-1234567890"}}}`, b.Provider, b.Dir)
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("{" + bindings + "," + schedule + "}")
+	if d.scheduleAllowsSync() {
+		t.Fatal("sync allowed inside the quiet window")
+	}
+
+	// Same size and mtime: the cached schedule stands, without a re-read.
+	info, _ := os.Stat(configPath)
+	write(string(bytes.Repeat([]byte("x"), int(info.Size()))))
+	os.Chtimes(configPath, info.ModTime(), info.ModTime())
+	if d.scheduleAllowsSync() {
+		t.Error("unchanged config was re-read")
+	}
+
+	write("{not json")
+	if !d.scheduleAllowsSync() || d.scheduleErr == nil {
+		t.Errorf("unreadable config: want sync allowed and the error kept, got err %v", d.scheduleErr)
+	}
+
+	// Without this daemon's binding the config can't be used, even though
+	// the default one would read the quiet window.
+	write("{" + schedule + "}")
+	if !d.scheduleAllowsSync() || !errors.Is(d.scheduleErr, config.ErrNoBinding) {
+		t.Errorf("missing binding: want sync allowed and ErrNoBinding, got %v", d.scheduleErr)
+	}
+}
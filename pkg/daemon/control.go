@@ -0,0 +1,279 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/confabpath"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+// DefaultPauseMaxDuration is how long a pause lasts before the daemon
+// resumes syncing on its own, so a forgotten `confab pause` doesn't leave
+// a session unsynced indefinitely.
+const DefaultPauseMaxDuration = time.Hour
+
+// controlIOTimeout bounds a single request/response exchange on the
+// control socket, on both ends.
+const controlIOTimeout = 5 * time.Second
+
+// Control socket commands.
+const (
+	ControlPause  = "pause"
+	ControlResume = "resume"
+	ControlStatus = "status"
+	// ControlSync asks the daemon to run a sync cycle now rather than at
+	// its next interval (see `confab hook stop`).
+	ControlSync = "sync"
+	// ControlPreCompact runs a sync cycle, waiting for it, and records the
+	// transcript's pre-compaction size in the state file (see
+	// `confab hook pre-compact` and State.PreCompact).
+	ControlPreCompact = "pre-compact"
+)
+
+// preCompactWait bounds how long a pre-compact request waits for its sync
+// cycle, leaving time to reply within controlIOTimeout.
+const preCompactWait = controlIOTimeout - time.Second
+
+// ControlRequest is one line of JSON sent to a daemon's control socket.
+type ControlRequest struct {
+	Cmd string `json:"cmd"`
+}
+
+// ControlResponse is the daemon's one-line JSON reply.
+type ControlResponse struct {
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	Paused bool   `json:"paused"`
+	// PausedUntil is when the daemon resumes on its own; nil unless paused.
+	PausedUntil *time.Time `json:"paused_until,omitempty"`
+	// SyncProgress answers the status command: the daemon's progress as of
+	// its last sync cycle, fresher than the state file's copy, which is
+	// only saved when it changes. Nil before the first cycle.
+	SyncProgress *SyncProgress `json:"sync_progress,omitempty"`
+}
+
+func legacySocketPath(externalID string) (string, error) {
+	return confabpath.Subpath("sync", externalID+".sock")
+}
+
+// GetSocketPathForProvider returns the namespaced control socket path.
+func GetSocketPathForProvider(provider, externalID string) (string, error) {
+	if provider == "" {
+		return legacySocketPath(externalID)
+	}
+	return confabpath.Subpath("sync", provider, externalID+".sock")
+}
+
+// startControlSocket listens on the daemon's control socket and serves
+// requests in the background. The returned listener is closed (and the
+// socket file removed) by stopControlSocket.
+func (d *Daemon) startControlSocket(path string) (net.Listener, error) {
+	// A socket file left by a daemon that was killed would make Listen
+	// fail; this daemon owns the session now (see the state file lock).
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to restrict control socket: %w", err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					logger.Warnf("Control socket stopped: %v", err)
+				}
+				return
+			}
+			go d.serveControl(conn)
+		}
+	}()
+	return ln, nil
+}
+
+func stopControlSocket(ln net.Listener, path string) {
+	ln.Close()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.Warnf("Failed to remove control socket %s: %v", path, err)
+	}
+}
+
+// serveControl answers a single request on conn.
+func (d *Daemon) serveControl(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlIOTimeout))
+
+	var req ControlRequest
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil || len(line) > 0 {
+		err = json.Unmarshal(line, &req)
+	}
+
+	var resp ControlResponse
+	switch {
+	case err != nil:
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	case req.Cmd == ControlPause:
+		d.pause()
+		resp.OK = true
+	case req.Cmd == ControlResume:
+		d.resume()
+		resp.OK = true
+	case req.Cmd == ControlStatus:
+		resp.SyncProgress = d.progress.Load()
+		resp.OK = true
+	case req.Cmd == ControlSync:
+		d.requestSync()
+		resp.OK = true
+	case req.Cmd == ControlPreCompact:
+		if err := d.requestPreCompact(preCompactWait); err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.OK = true
+		}
+	default:
+		resp.Error = fmt.Sprintf("unknown command %q", req.Cmd)
+	}
+	if d.isPaused() {
+		until := time.Unix(0, d.pausedUntil.Load())
+		resp.Paused, resp.PausedUntil = true, &until
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// pause stops sync cycles until resume or pauseMaxDuration from now,
+// whichever comes first. Pausing an already paused daemon extends it.
+func (d *Daemon) pause() {
+	d.pausedUntil.Store(time.Now().Add(d.pauseMaxDuration).UnixNano())
+	d.paused.Store(true)
+	logger.Infof("Sync paused: auto-resume in %v", d.pauseMaxDuration)
+}
+
+func (d *Daemon) resume() {
+	if d.paused.Swap(false) {
+		logger.Info("Sync resumed")
+	}
+}
+
+// requestSync wakes the main loop for an immediate sync cycle. Requests
+// that arrive while one is already pending are coalesced.
+func (d *Daemon) requestSync() {
+	select {
+	case d.syncNowCh <- struct{}{}:
+	default:
+	}
+}
+
+// requestPreCompact asks the main loop for a sync cycle followed by a
+// compaction mark and waits up to timeout for both.
+func (d *Daemon) requestPreCompact(timeout time.Duration) error {
+	done := make(chan struct{})
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case d.preCompactCh <- done:
+	case <-timer.C:
+		return errors.New("daemon busy, sync not started")
+	}
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return errors.New("timed out waiting for sync")
+	}
+}
+
+// isPaused reports whether sync is paused, resuming first if the pause
+// has outlasted pauseMaxDuration.
+func (d *Daemon) isPaused() bool {
+	if !d.paused.Load() {
+		return false
+	}
+	if time.Now().UnixNano() >= d.pausedUntil.Load() {
+		if d.paused.CompareAndSwap(true, false) {
+			logger.Infof("Sync auto-resumed after %v", d.pauseMaxDuration)
+		}
+		return false
+	}
+	return true
+}
+
+// SendControl sends cmd to the daemon listening on socketPath and returns
+// its reply.
+func SendControl(socketPath, cmd string) (*ControlResponse, error) {
+	conn, err := net.DialTimeout("unix", socketPath, controlIOTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlIOTimeout))
+
+	if err := json.NewEncoder(conn).Encode(ControlRequest{Cmd: cmd}); err != nil {
+		return nil, fmt.Errorf("failed to send %s: %w", cmd, err)
+	}
+	var resp ControlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read daemon reply: %w", err)
+	}
+	if !resp.OK {
+		return &resp, fmt.Errorf("daemon rejected %s: %s", cmd, resp.Error)
+	}
+	return &resp, nil
+}
+
+// PreCompactForProvider asks the running daemon for a session to sync now
+// and mark the transcript's pre-compaction size, returning once it has.
+func PreCompactForProvider(providerName, externalID string) error {
+	return sendControlForSession(providerName, externalID, ControlPreCompact)
+}
+
+// RequestSyncForProvider asks the running daemon for a session to sync
+// now. It fails if no daemon is running for the session or its control
+// socket can't be reached.
+func RequestSyncForProvider(providerName, externalID string) error {
+	return sendControlForSession(providerName, externalID, ControlSync)
+}
+
+// QueryStatusForProvider asks the running daemon for a session for its
+// pause state and current sync progress (ControlResponse.SyncProgress).
+func QueryStatusForProvider(providerName, externalID string) (*ControlResponse, error) {
+	socketPath, err := runningDaemonSocket(providerName, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return SendControl(socketPath, ControlStatus)
+}
+
+// sendControlForSession sends cmd to the running daemon for a session.
+func sendControlForSession(providerName, externalID, cmd string) error {
+	socketPath, err := runningDaemonSocket(providerName, externalID)
+	if err != nil {
+		return err
+	}
+	_, err = SendControl(socketPath, cmd)
+	return err
+}
+
+// runningDaemonSocket returns the control socket path of the running
+// daemon for a session.
+func runningDaemonSocket(providerName, externalID string) (string, error) {
+	state, err := LoadStateForProvider(providerName, externalID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load state: %w", err)
+	}
+	if state == nil || !state.IsDaemonRunning() {
+		return "", fmt.Errorf("no running daemon for session %s", externalID)
+	}
+	return GetSocketPathForProvider(state.Provider, state.ExternalID)
+}
@@ -0,0 +1,260 @@
+package daemon
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// syncedLines is the highest line the mock backend has received.
+func syncedLines(mock *mockBackend) int {
+	last := 0
+	for _, c := range mock.getChunkRequests() {
+		last = max(last, c.FirstLine+len(c.Lines)-1)
+	}
+	return last
+}
+
+func waitForSyncedLines(t *testing.T, mock *mockBackend, want int) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for syncedLines(mock) < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("backend has %d lines, want %d", syncedLines(mock), want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestDaemonControlSocket_PauseResume(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "pause-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   50 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+	waitForSyncedLines(t, mock, 1)
+
+	socketPath, err := GetSocketPathForProvider("claude-code", "pause-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := SendControl(socketPath, ControlPause)
+	if err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if !resp.Paused || resp.PausedUntil == nil || time.Until(*resp.PausedUntil) < 59*time.Minute {
+		t.Errorf("pause reply = %+v, want paused for the default hour", resp)
+	}
+	if resp, err := SendControl(socketPath, ControlStatus); err != nil || !resp.Paused {
+		t.Errorf("status = %+v, %v; want paused", resp, err)
+	}
+
+	// Lines written while paused stay local for several intervals.
+	f, _ := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"user"}` + "\n")
+	f.Close()
+	time.Sleep(300 * time.Millisecond)
+	if got := syncedLines(mock); got != 1 {
+		t.Fatalf("backend has %d lines while paused, want 1", got)
+	}
+
+	if resp, err := SendControl(socketPath, ControlResume); err != nil || resp.Paused {
+		t.Errorf("resume = %+v, %v; want not paused", resp, err)
+	}
+	waitForSyncedLines(t, mock, 2)
+
+	if _, err := SendControl(socketPath, "reboot"); err == nil {
+		t.Error("expected an unknown command to be rejected")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("control socket still present after shutdown: %v", err)
+	}
+}
+
+func TestDaemonControlSocket_SyncNow(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "sync-now-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   time.Hour,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+	waitForSyncedLines(t, mock, 1)
+
+	// With an hour-long interval, only the sync request uploads the line.
+	f, _ := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"assistant"}` + "\n")
+	f.Close()
+	if err := RequestSyncForProvider("claude-code", "sync-now-test"); err != nil {
+		t.Fatalf("RequestSyncForProvider: %v", err)
+	}
+	waitForSyncedLines(t, mock, 2)
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := RequestSyncForProvider("claude-code", "sync-now-test"); err == nil {
+		t.Error("expected RequestSyncForProvider to fail once the daemon exited")
+	}
+}
+
+func TestDaemonControlSocket_StatusReportsProgress(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"+`{"type":"user"}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "status-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   50 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+	waitForSyncedLines(t, mock, 2)
+
+	// The snapshot is taken just after the cycle that uploaded the lines.
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		resp, err := QueryStatusForProvider("claude-code", "status-test")
+		if err != nil {
+			t.Fatalf("QueryStatusForProvider: %v", err)
+		}
+		if p := resp.SyncProgress; p != nil && p.FileLines["transcript.jsonl"] == 2 {
+			if p.BytesUploaded == 0 {
+				t.Errorf("status progress = %+v, want bytes uploaded", p)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status progress = %+v, want 2 transcript lines", resp.SyncProgress)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func TestDaemonControlSocket_PreCompact(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "pre-compact-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   time.Hour,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+	waitForSyncedLines(t, mock, 1)
+
+	// PreCompact returns only once the pending line is synced and marked.
+	f, _ := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"assistant"}` + "\n")
+	f.Close()
+	if err := PreCompactForProvider("claude-code", "pre-compact-test"); err != nil {
+		t.Fatalf("PreCompactForProvider: %v", err)
+	}
+	if n := syncedLines(mock); n != 2 {
+		t.Errorf("backend has %d lines after PreCompact, want 2", n)
+	}
+	state, err := LoadStateForProvider("claude-code", "pre-compact-test")
+	if err != nil || state.PreCompact == nil {
+		t.Fatalf("state after PreCompact = %+v, %v; want a pre-compact mark", state, err)
+	}
+	if state.PreCompact.Lines != 2 {
+		t.Errorf("PreCompact.Lines = %d, want 2", state.PreCompact.Lines)
+	}
+
+	// Compaction rewrites the transcript shorter; the next cycle re-inits
+	// and clears the mark.
+	inits := len(mock.getInitRequests())
+	os.WriteFile(transcriptPath, []byte(`{"type":"summary"}`+"\n"), 0644)
+	if err := RequestSyncForProvider("claude-code", "pre-compact-test"); err != nil {
+		t.Fatalf("RequestSyncForProvider: %v", err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		state, _ = LoadStateForProvider("claude-code", "pre-compact-test")
+		if state != nil && state.PreCompact == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pre-compact mark never cleared after compaction")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := len(mock.getInitRequests()); got <= inits {
+		t.Errorf("init requests = %d after compaction, want more than %d (re-init)", got, inits)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func TestDaemonPause_AutoResumes(t *testing.T) {
+	d := New(Config{ExternalID: "auto-resume-test", PauseMaxDuration: 50 * time.Millisecond})
+	d.pause()
+	if !d.isPaused() {
+		t.Fatal("expected daemon to be paused")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if d.isPaused() {
+		t.Error("expected pause to lapse after PauseMaxDuration")
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"math/rand"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -24,10 +26,16 @@ import (
 
 const (
 	// DefaultSyncInterval is the base interval for syncing files
-	DefaultSyncInterval = 30 * time.Second
+	DefaultSyncInterval = config.DefaultSyncIntervalMS * time.Millisecond
 
-	// syncIntervalJitter is random jitter added to sync interval (0 to this value)
-	syncIntervalJitter = 5 * time.Second
+	// DefaultSyncIntervalJitter is random jitter added to the default sync
+	// interval (0 to this value); an explicit SyncInterval gets none unless
+	// SyncIntervalJitter is set.
+	DefaultSyncIntervalJitter = 5 * time.Second
+
+	// DefaultMaxBackoff caps the wait between sync cycles while the
+	// backend keeps failing, unless Config.MaxBackoff overrides it.
+	DefaultMaxBackoff = 5 * time.Minute
 
 	// initialWaitTimeout is how long to wait for transcript file to appear
 	initialWaitTimeout = 60 * time.Second
@@ -35,9 +43,15 @@ const (
 	// initialWaitPollInterval is how often to check for transcript file
 	initialWaitPollInterval = 2 * time.Second
 
-	// maxConsecutiveNotFound is how many consecutive 404 errors before stopping.
-	// This handles the case where a session is deleted from the backend.
-	maxConsecutiveNotFound = 3
+	// DefaultMaxConsecutive404 is how many consecutive 404 errors before
+	// stopping, unless Config.MaxConsecutive404 overrides it. This handles
+	// the case where a session is deleted from the backend.
+	DefaultMaxConsecutive404 = 3
+
+	// DefaultMaxSessions is how many sessions (the root plus OpenCode child
+	// sessions) one daemon manages at once, unless Config.MaxSessions
+	// overrides it. Children discovered beyond it are deferred.
+	DefaultMaxSessions = 64
 
 	// collectorShutdownTimeout is the single ceiling for waiting on the root
 	// OpenCode collector plus every child collector to finish during shutdown
@@ -52,6 +66,28 @@ const (
 // never modify it.
 var parentCheckInterval = 5 * time.Second
 
+// progressLogInterval is the least time between the daemon's progress logs
+// for a file still catching up (e.g. the first sync of a large transcript).
+// Var (not const) so tests can shorten it.
+var progressLogInterval = 10 * time.Second
+
+// progressLogger is the daemon's EngineConfig.OnProgress: it logs how far a
+// backfill has got, at most once per progressLogInterval, and only while a
+// file is short of its estimated length. The engine never calls it
+// concurrently.
+type progressLogger struct {
+	last time.Time
+}
+
+func (p *progressLogger) observe(file string, linesSynced, totalLinesEstimate int) {
+	if linesSynced >= totalLinesEstimate || time.Since(p.last) < progressLogInterval {
+		return
+	}
+	p.last = time.Now()
+	logger.Infof("Sync progress: %s line %d of ~%d (%d%%)",
+		file, linesSynced, totalLinesEstimate, linesSynced*100/totalLinesEstimate)
+}
+
 // shutdownTimeout is the maximum time to wait for final sync during shutdown.
 // If the backend is slow or unresponsive, we give up and clean up anyway.
 // This is a var (not const) to allow tests to override it.
@@ -81,13 +117,68 @@ type Daemon struct {
 	parentPID      int
 	syncInterval   time.Duration
 	syncJitter     time.Duration
+	maxBackoff     time.Duration // cap on the failure backoff (see nextSyncDelay)
+	watchMode      bool
+	metricsAddr    string
+	metricsPort    int
+	pidFile        string
+	maxNotFound    int // consecutive 404s before stopping
+
+	// watcher delivers filesystem-driven sync triggers in WatchMode; nil
+	// otherwise (see watchC).
+	watcher *transcriptWatcher
 
 	state               *State
 	engine              *pkgsync.Engine
 	stopCh              chan struct{}
+	syncNowCh           chan struct{} // buffered(1); see requestSync
+	preCompactCh        chan chan struct{}
 	stopOnce            sync.Once
 	doneCh              chan struct{}
-	consecutiveNotFound int // tracks consecutive 404 errors for session deletion detection
+	consecutiveNotFound int    // tracks consecutive 404 errors for session deletion detection
+	consecutiveErrors   int    // cycles in a row whose init or sync failed (reported via metrics)
+	lastSyncError       string // error of the last failed cycle, cleared by a clean one (persisted for status)
+
+	// tokenEngine mirrors engine for the refreshTokens goroutine, which
+	// must not read the engine field the main loop reassigns.
+	tokenEngine atomic.Pointer[pkgsync.Engine]
+
+	// metrics serves /healthz and /metrics when Config.MetricsAddr is set;
+	// nil otherwise.
+	metrics *metricsServer
+	// prom holds the Prometheus collectors served when Config.MetricsPort
+	// is set; nil otherwise.
+	prom *promMetrics
+	// heldBySchedule is set while the config's sync_schedule is holding
+	// uploads back, so the transition is logged once each way and shutdown
+	// knows to flush even if the engine never initialized.
+	heldBySchedule bool
+	// schedule is the sync_schedule as of scheduleStamp, the config files'
+	// mtimes when it was loaded, so scheduleAllowsSync re-reads the config
+	// (and any keyring secrets with it) only after a file changes.
+	// scheduleErr is the error that load returned, logged once.
+	schedule      *config.SyncSchedule
+	scheduleStamp configStamp
+	scheduleErr   error
+	// rateLimitedUntil is when the backend's last 429 Retry-After window
+	// ends. The main loop neither syncs nor reacts to watch triggers
+	// before then.
+	rateLimitedUntil time.Time
+
+	// paused is set by `confab pause` over the control socket (see
+	// control.go) and cleared by resume or once pausedUntil (unix nanos)
+	// passes. Written from socket goroutines, read by the main loop.
+	paused           atomic.Bool
+	pausedUntil      atomic.Int64
+	pauseMaxDuration time.Duration
+
+	// progress is the SyncProgress last computed by persistSyncState,
+	// which the control socket's status command replies with. Written by
+	// the main loop, read from socket goroutines.
+	progress atomic.Pointer[SyncProgress]
+
+	chunkTarget   time.Duration // Config.TargetChunkDuration
+	minChunkBytes int           // Config.MinChunkBytes
 
 	// collectorCancel stops the OpenCode collector goroutine (nil for
 	// Claude/Codex); collectorDone closes when that goroutine has exited.
@@ -121,6 +212,11 @@ type Daemon struct {
 	// childCollectorsMu. Nil until startChildCollector first runs.
 	childCollectors   map[string]*opencodeChildCollector
 	childCollectorsMu sync.Mutex
+	// maxSessions caps the root plus running child collectors (see
+	// admitChildLocked); deferredChildren holds the children turned away, so
+	// each is logged once. Guarded by childCollectorsMu.
+	maxSessions      int
+	deferredChildren map[string]bool
 }
 
 // Config holds daemon configuration
@@ -134,6 +230,49 @@ type Config struct {
 	ParentPID          int    // Claude Code process ID to monitor (0 to disable)
 	SyncInterval       time.Duration
 	SyncIntervalJitter time.Duration // 0 to disable jitter (for testing)
+	// MaxBackoff caps the wait between cycles while syncs keep failing:
+	// after the first failed cycle in a row the wait doubles with each
+	// further failure, up to MaxBackoff, and is back to SyncInterval after
+	// the first clean cycle. 0 uses DefaultMaxBackoff; a value at or
+	// below SyncInterval turns the backoff off.
+	MaxBackoff time.Duration
+	// WatchMode triggers syncs from filesystem events on the transcript
+	// (and its subagent files) instead of waiting for the next tick.
+	// SyncInterval still applies as the fallback between event-driven
+	// syncs. Ignored for OpenCode, which has no upstream file to watch.
+	WatchMode bool
+	// MetricsAddr, when non-empty, is the address (e.g. "127.0.0.1:9464")
+	// of a local HTTP server exposing /healthz and /metrics for debugging
+	// or scraping a running daemon: JSON by default, the Prometheus
+	// registry (as served on MetricsPort) when the scraper asks for the
+	// text format. Shut down when Run returns.
+	MetricsAddr string
+	// MetricsPort, when non-zero, starts an HTTP server on
+	// 127.0.0.1:MetricsPort exposing /metrics in the Prometheus text format
+	// (see prometheus.go). Independent of MetricsAddr. Shut down when Run
+	// returns.
+	MetricsPort int
+	// PIDFile, when non-empty, is a path the daemon writes its PID to on
+	// startup for process supervisors, and removes when Run returns.
+	PIDFile string
+	// MaxConsecutive404 is how many sync cycles in a row may fail with 404
+	// (session not found) before the daemon concludes the session was
+	// deleted and stops. 0 uses DefaultMaxConsecutive404. Raise it for
+	// backends behind proxies that return spurious 404s.
+	MaxConsecutive404 int
+	// PauseMaxDuration is how long a `confab pause` holds off syncing
+	// before the daemon resumes on its own. 0 uses DefaultPauseMaxDuration.
+	PauseMaxDuration time.Duration
+	// TargetChunkDuration and MinChunkBytes tune the engine's adaptive
+	// chunk sizing (see pkgsync.EngineConfig); 0 uses the sync defaults.
+	// The learned size is kept in State.ChunkSizing across restarts.
+	TargetChunkDuration time.Duration
+	MinChunkBytes       int
+	// MaxSessions caps how many sessions this daemon manages at once: its
+	// own session plus the OpenCode child sessions it collects. Children
+	// discovered beyond the cap are deferred (retried each cycle) while
+	// the sessions already managed keep syncing. 0 uses DefaultMaxSessions.
+	MaxSessions int
 }
 
 // New creates a new daemon instance
@@ -146,7 +285,27 @@ func New(cfg Config) *Daemon {
 	jitter := cfg.SyncIntervalJitter
 	if jitter == 0 && cfg.SyncInterval == 0 {
 		// Only use default jitter if using default interval
-		jitter = syncIntervalJitter
+		jitter = DefaultSyncIntervalJitter
+	}
+
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	maxNotFound := cfg.MaxConsecutive404
+	if maxNotFound <= 0 {
+		maxNotFound = DefaultMaxConsecutive404
+	}
+
+	maxSessions := cfg.MaxSessions
+	if maxSessions <= 0 {
+		maxSessions = DefaultMaxSessions
+	}
+
+	pauseMax := cfg.PauseMaxDuration
+	if pauseMax <= 0 {
+		pauseMax = DefaultPauseMaxDuration
 	}
 
 	providerName := cfg.Provider
@@ -155,18 +314,30 @@ func New(cfg Config) *Daemon {
 	}
 
 	return &Daemon{
-		providerName:   providerName,
-		externalID:     cfg.ExternalID,
-		transcriptPath: cfg.TranscriptPath,
-		cwd:            cfg.CWD,
-		configDir:      cfg.ConfigDir,
-		model:          cfg.Model,
-		parentPID:      cfg.ParentPID,
-		syncInterval:   interval,
-		syncJitter:     jitter,
-		stopCh:         make(chan struct{}),
-		doneCh:         make(chan struct{}),
-		parentDeathCh:  make(chan struct{}),
+		providerName:     providerName,
+		externalID:       cfg.ExternalID,
+		transcriptPath:   cfg.TranscriptPath,
+		cwd:              cfg.CWD,
+		configDir:        cfg.ConfigDir,
+		model:            cfg.Model,
+		parentPID:        cfg.ParentPID,
+		syncInterval:     interval,
+		syncJitter:       jitter,
+		maxBackoff:       maxBackoff,
+		watchMode:        cfg.WatchMode,
+		metricsAddr:      cfg.MetricsAddr,
+		metricsPort:      cfg.MetricsPort,
+		pidFile:          cfg.PIDFile,
+		maxNotFound:      maxNotFound,
+		pauseMaxDuration: pauseMax,
+		chunkTarget:      cfg.TargetChunkDuration,
+		minChunkBytes:    cfg.MinChunkBytes,
+		maxSessions:      maxSessions,
+		stopCh:           make(chan struct{}),
+		syncNowCh:        make(chan struct{}, 1),
+		preCompactCh:     make(chan chan struct{}),
+		doneCh:           make(chan struct{}),
+		parentDeathCh:    make(chan struct{}),
 	}
 }
 
@@ -186,7 +357,7 @@ func (d *Daemon) monitorParent(ctx context.Context) {
 			return
 		case <-ticker.C:
 			if !isProcessRunning(d.parentPID) {
-				logger.Info("Parent process %d exited; signaling shutdown", d.parentPID)
+				logger.Infof("Parent process %d exited; signaling shutdown", d.parentPID)
 				close(d.parentDeathCh)
 				return
 			}
@@ -199,7 +370,7 @@ func (d *Daemon) Run(ctx context.Context) error {
 	// Set session context for all log lines
 	logger.SetSession(d.externalID, "")
 
-	logger.Info("Daemon starting: transcript=%s interval=%v", d.transcriptPath, d.syncInterval)
+	logger.Info("Daemon starting", "component", "daemon", "transcript", d.transcriptPath, "interval", d.syncInterval.String())
 
 	// Setup signal handling as early as possible to catch signals during
 	// initialization (waiting for transcript, backend init).
@@ -207,6 +378,58 @@ func (d *Daemon) Run(ctx context.Context) error {
 	sigCh := make(chan os.Signal, 2)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
 
+	if d.pidFile != "" {
+		if err := writePIDFile(d.pidFile); err != nil {
+			return err
+		}
+		defer removePIDFile(d.pidFile)
+	}
+	d.logStartupInfo()
+
+	// A redaction pattern that doesn't compile would make every engine
+	// build in tryInit fail, retried each cycle. Refuse to start instead,
+	// before anything is uploaded.
+	if err := d.checkRedactionConfig(); err != nil {
+		logger.Error("Invalid redaction config, not starting", "component", "daemon", "error", err)
+		return err
+	}
+
+	// Both metrics servers expose the same Prometheus registry.
+	if d.metricsAddr != "" || d.metricsPort != 0 {
+		d.prom = newPromMetrics()
+	}
+	if d.metricsAddr != "" {
+		m, err := startMetricsServer(d.metricsAddr, d.externalID, d.prom.handler())
+		if err != nil {
+			logger.Warnf("Metrics server unavailable: %v", err)
+		} else {
+			d.metrics = m
+			defer m.Close()
+			logger.Info("Metrics server listening", "addr", m.Addr())
+		}
+	}
+	if d.metricsPort != 0 {
+		if err := d.prom.serve(d.metricsPort); err != nil {
+			logger.Warnf("Prometheus metrics server unavailable: %v", err)
+		} else {
+			defer d.prom.Close()
+			logger.Info("Prometheus metrics server listening", "addr", d.prom.Addr())
+		}
+	}
+
+	// In watch mode, start watching before waiting so a transcript that
+	// appears late is noticed on its Create event rather than the next poll.
+	if d.watchMode && d.transcriptPath != "" {
+		w, err := newTranscriptWatcher(d.transcriptPath)
+		if err != nil {
+			logger.Warnf("Watch mode unavailable, falling back to polling: %v", err)
+		} else {
+			d.watcher = w
+			defer w.Close()
+			logger.Info("Watch mode enabled: syncing on transcript changes", "fallback_interval", d.syncInterval.String())
+		}
+	}
+
 	// Wait for transcript file to exist before doing anything else.
 	// Don't save state or set up panic handlers until we have a transcript.
 	if err := d.waitForTranscript(ctx, sigCh); err != nil {
@@ -215,9 +438,36 @@ func (d *Daemon) Run(ctx context.Context) error {
 
 	// Save state for duplicate detection. Done after transcript exists so we
 	// don't leave stale state files for sessions that never produced transcripts.
+	// A state file left behind by a previous daemon for this session (e.g. one
+	// that was killed) carries its discovered agent IDs, file read
+	// positions and unfinished multipart uploads forward.
+	previous := d.loadPreviousState()
 	d.state = NewStateForProvider(d.providerName, d.externalID, d.transcriptPath, d.cwd, d.parentPID)
+	if previous != nil {
+		d.state.KnownAgentIDs = previous.KnownAgentIDs
+		d.state.StartEventSessionID = previous.StartEventSessionID
+		d.state.FileOffsets = previous.FileOffsets
+		d.state.PendingUploads = previous.PendingUploads
+		d.state.DirtyTail = previous.DirtyTail
+		d.state.Completed = previous.Completed
+		if previous.Completed != nil {
+			// Hooks reading the state (commit linking) keep the backend
+			// session until this daemon initializes.
+			d.state.ConfabSessionID = previous.ConfabSessionID
+		}
+	}
 	if err := d.state.Save(); err != nil {
-		logger.Warn("Failed to save initial state: %v", err)
+		logger.Warnf("Failed to save initial state: %v", err)
+	}
+
+	// The control socket lets `confab pause`/`confab resume` reach this
+	// daemon. Optional: without it the daemon just can't be paused.
+	if socketPath, err := GetSocketPathForProvider(d.providerName, d.externalID); err == nil {
+		if ln, err := d.startControlSocket(socketPath); err != nil {
+			logger.Warnf("Control socket unavailable: %v", err)
+		} else {
+			defer stopControlSocket(ln, socketPath)
+		}
 	}
 
 	// OpenCode has no upstream file to tail: derive a local materialized
@@ -254,7 +504,7 @@ func (d *Daemon) Run(ctx context.Context) error {
 		go func() {
 			defer close(d.collectorDone)
 			if err := collector.Run(collectorCtx); err != nil && !errors.Is(err, context.Canceled) {
-				logger.Warn("OpenCode collector exited: %v", err)
+				logger.Warnf("OpenCode collector exited: %v", err)
 			}
 		}()
 	}
@@ -265,7 +515,7 @@ func (d *Daemon) Run(ctx context.Context) error {
 	// and we need the logs for debugging.
 	defer func() {
 		if r := recover(); r != nil {
-			logger.Error("Daemon panic: %v", r)
+			logger.Errorf("Daemon panic: %v", r)
 			if d.state != nil {
 				d.state.Delete()
 			}
@@ -274,7 +524,7 @@ func (d *Daemon) Run(ctx context.Context) error {
 	}()
 
 	if d.parentPID > 0 {
-		logger.Info("Daemon running: pid=%d parent_pid=%d", os.Getpid(), d.parentPID)
+		logger.Info("Daemon running", "pid", os.Getpid(), "parent_pid", d.parentPID)
 		// CF-549 R6: parent-PID monitoring runs in its own goroutine so a
 		// hung SyncAll cannot delay shutdown after parent death. The
 		// monitor closes parentDeathCh on detection; the main loop drains
@@ -286,11 +536,19 @@ func (d *Daemon) Run(ctx context.Context) error {
 		defer monitorCancel()
 		go d.monitorParent(monitorCtx)
 	} else {
-		logger.Info("Daemon running: pid=%d (no parent monitoring)", os.Getpid())
+		logger.Info("Daemon running (no parent monitoring)", "pid", os.Getpid())
 	}
 
+	// Expiring access tokens are refreshed in the background; see token.go.
+	refreshCtx, refreshCancel := context.WithCancel(ctx)
+	defer refreshCancel()
+	go d.refreshTokens(refreshCtx)
+
 	// Main loop with jittered interval to avoid thundering herd.
 	// First iteration fires immediately (0 duration), then uses normal interval.
+	// In watch mode a filesystem trigger syncs early and restarts the interval.
+	// Shutdown (stop, signal, parent exit) is selected on whatever the delay,
+	// so the final sync never waits out a backoff.
 	firstSync := true
 	for {
 		var delay time.Duration
@@ -298,11 +556,25 @@ func (d *Daemon) Run(ctx context.Context) error {
 			delay = 0
 			firstSync = false
 		} else {
-			delay = d.syncInterval
-			if d.syncJitter > 0 {
-				delay += time.Duration(rand.Int63n(int64(d.syncJitter)))
+			delay = d.nextSyncDelay()
+			if d.backingOff() {
+				logger.Info("Backing off after failed sync cycles", "component", "daemon", "consecutive_errors", d.consecutiveErrors, "delay", delay.String())
 			}
 		}
+		// A rate-limited backend said how long to back off; honor it even
+		// when that's longer than the interval, and ignore watch triggers
+		// until it has passed. Watch triggers are ignored while backing
+		// off after failures too, so an outage isn't retried on every
+		// transcript write.
+		watchC := d.watchC()
+		syncNowC := d.syncNowCh
+		if d.isPaused() || d.backingOff() {
+			watchC = nil
+		}
+		if wait := time.Until(d.rateLimitedUntil); wait > 0 {
+			delay = max(delay, wait)
+			watchC, syncNowC = nil, nil
+		}
 		timer := time.NewTimer(delay)
 
 		select {
@@ -326,50 +598,236 @@ func (d *Daemon) Run(ctx context.Context) error {
 			return d.shutdown("parent process exited")
 
 		case <-timer.C:
-			// For OpenCode, the collector materializes the transcript file
-			// asynchronously. Stay lifecycle-only — monitor the parent but
-			// never contact the backend — until at least one complete
-			// message exists, so we don't create empty backend sessions.
-			if !d.backendSyncEnabled() {
-				continue
+			if reason := d.syncCycle(); reason != "" {
+				return d.shutdown(reason)
 			}
 
-			// If not initialized yet, try to connect to backend
-			if d.engine == nil || !d.engine.IsInitialized() {
-				if err := d.tryInit(); err != nil {
-					logger.Warn("Backend init failed (will retry): %v", err)
-					if errors.Is(err, http.ErrUnauthorized) {
-						d.resetEngineOnAuthFailure()
-					}
-					continue
-				}
+		case <-watchC:
+			timer.Stop()
+			if reason := d.syncCycle(); reason != "" {
+				return d.shutdown(reason)
 			}
 
-			// Sync
-			if chunks, err := d.engine.SyncAll(); err != nil {
-				logger.Warn("Sync cycle had errors: %v", err)
-				if errors.Is(err, http.ErrUnauthorized) {
-					d.resetEngineOnAuthFailure()
-				}
-				// Track consecutive 404 errors for session deletion detection.
-				// Stop after maxConsecutiveNotFound to avoid infinite retries.
-				if errors.Is(err, http.ErrSessionNotFound) {
-					d.consecutiveNotFound++
-					logger.Warn("Session not found (404): count=%d/%d", d.consecutiveNotFound, maxConsecutiveNotFound)
-					if d.consecutiveNotFound >= maxConsecutiveNotFound {
-						return d.shutdown("session deleted from backend")
-					}
-				} else {
-					d.consecutiveNotFound = 0
-				}
-			} else {
-				d.consecutiveNotFound = 0
-				if chunks > 0 {
-					logger.Debug("Sync cycle complete: chunks=%d", chunks)
-				}
+		case <-syncNowC:
+			timer.Stop()
+			if reason := d.syncCycle(); reason != "" {
+				return d.shutdown(reason)
+			}
+
+		case done := <-d.preCompactCh:
+			// Not gated on the rate limit: compaction won't wait, and the
+			// mark matters even if the flush fails.
+			timer.Stop()
+			reason := d.syncCycle()
+			d.markPreCompact()
+			close(done)
+			if reason != "" {
+				return d.shutdown(reason)
+			}
+		}
+	}
+}
+
+// nextSyncDelay is the wait before the next sync cycle: SyncInterval plus
+// jitter, or while cycles keep failing, the interval doubled for each
+// failure after the first, capped at maxBackoff (jitter still added).
+func (d *Daemon) nextSyncDelay() time.Duration {
+	delay := d.syncInterval
+	for i := 1; i < d.consecutiveErrors && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = max(d.syncInterval, min(delay, d.maxBackoff))
+	if d.syncJitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(d.syncJitter)))
+	}
+	return delay
+}
+
+// backingOff reports whether failed cycles have stretched the wait beyond
+// the sync interval.
+func (d *Daemon) backingOff() bool {
+	return d.consecutiveErrors > 1 && d.maxBackoff > d.syncInterval
+}
+
+// syncCycle runs one sync pass: backend init if needed, SyncAll, then state
+// persistence. Returns a non-empty shutdown reason when the daemon should
+// stop (the session was deleted from the backend).
+func (d *Daemon) syncCycle() (shutdownReason string) {
+	defer d.updateMetrics()
+
+	// For OpenCode, the collector materializes the transcript file
+	// asynchronously. Stay lifecycle-only — monitor the parent but
+	// never contact the backend — until at least one complete
+	// message exists, so we don't create empty backend sessions.
+	if !d.backendSyncEnabled() {
+		return ""
+	}
+
+	// Outside the configured sync schedule, don't contact the backend at
+	// all. Nothing is lost: unsynced lines stay on disk and the tracker's
+	// offsets pick them up when the window opens (or at shutdown).
+	if !d.scheduleAllowsSync() {
+		return ""
+	}
+
+	// Paused via `confab pause`: same as outside the schedule, lines wait
+	// on disk until resume (or the auto-resume) picks them up.
+	if d.isPaused() {
+		logger.Info("Sync paused")
+		return ""
+	}
+
+	// A completed session stays off the backend until its transcript
+	// changes: everything up to the completion mark is already uploaded.
+	if d.awaitingNewTail() {
+		return ""
+	}
+
+	// If not initialized yet, try to connect to backend
+	if d.engine == nil || !d.engine.IsInitialized() {
+		if err := d.tryInit(); err != nil {
+			logger.Warn("Backend init failed (will retry)", "component", "daemon", "error", err)
+			d.observeCycle(err)
+			d.consecutiveErrors++
+			d.lastSyncError = err.Error()
+			d.noteRateLimit(err)
+			if errors.Is(err, http.ErrUnauthorized) {
+				d.resetEngineOnAuthFailure()
+			}
+			d.persistSyncState()
+			return ""
+		}
+	}
+
+	d.checkCompaction()
+
+	// Sync
+	chunks, err := d.engine.SyncAll()
+	d.observeCycle(err)
+	if err != nil {
+		logger.Warn("Sync cycle had errors", "component", "daemon", "error", err, "consecutive_errors", d.consecutiveErrors+1)
+		d.consecutiveErrors++
+		d.lastSyncError = err.Error()
+		d.noteRateLimit(err)
+		if errors.Is(err, http.ErrUnauthorized) {
+			d.resetEngineOnAuthFailure()
+		}
+		// Track consecutive 404 errors for session deletion detection.
+		// Stop after maxNotFound to avoid infinite retries.
+		if errors.Is(err, http.ErrSessionNotFound) {
+			d.consecutiveNotFound++
+			logger.Warn("Session not found (404)", "count", d.consecutiveNotFound, "max", d.maxNotFound)
+			if d.consecutiveNotFound >= d.maxNotFound {
+				return "session deleted from backend"
 			}
+		} else if !errors.Is(err, http.ErrCircuitOpen) {
+			// A refused call says nothing about the session, so an open
+			// breaker doesn't restart the count.
+			d.consecutiveNotFound = 0
+		}
+	} else {
+		d.consecutiveNotFound = 0
+		d.consecutiveErrors = 0
+		d.lastSyncError = ""
+		if chunks > 0 {
+			logger.Debug("Sync cycle complete", "component", "daemon", "chunks", chunks)
 		}
 	}
+	d.persistSyncState()
+	return ""
+}
+
+// awaitingNewTail reports whether this daemon resumed a completed session
+// whose transcript hasn't changed since completion. Once it has, the
+// completion mark is cleared and syncing resumes from the persisted
+// offsets, so only the new tail is uploaded.
+func (d *Daemon) awaitingNewTail() bool {
+	if d.state == nil || d.state.Completed == nil {
+		return false
+	}
+	mark := d.state.Completed
+	info, err := os.Stat(d.transcriptPath)
+	if err != nil || (info.Size() == mark.Size && info.ModTime().Equal(mark.ModTime)) {
+		return true
+	}
+	logger.Info("Completed session's transcript changed, resuming sync", "component", "daemon", "completed_bytes", mark.Size, "bytes", info.Size())
+	d.state.Completed = nil
+	if err := d.state.Save(); err != nil {
+		logger.Warn("Failed to clear completion mark", "component", "daemon", "error", err)
+	}
+	return false
+}
+
+// markPreCompact records the transcript's current size in the state file
+// (State.PreCompact) for checkCompaction to compare against.
+func (d *Daemon) markPreCompact() {
+	if d.state == nil {
+		return
+	}
+	info, err := os.Stat(d.transcriptPath)
+	if err != nil {
+		logger.Warn("PreCompact: cannot stat transcript", "component", "daemon", "error", err)
+		return
+	}
+	lines, err := pkgsync.CountLines(d.transcriptPath)
+	if err != nil {
+		logger.Warn("PreCompact: cannot count transcript lines", "component", "daemon", "error", err)
+		return
+	}
+	d.state.PreCompact = &CompactMark{
+		ByteOffset: info.Size(),
+		Lines:      lines,
+		ModTime:    info.ModTime(),
+		At:         time.Now(),
+	}
+	if err := d.state.Save(); err != nil {
+		logger.Warn("Failed to save pre-compact mark", "component", "daemon", "error", err)
+		return
+	}
+	logger.Info("Recorded transcript size before compaction", "component", "daemon", "byte_offset", info.Size(), "lines", lines)
+}
+
+// checkCompaction re-inits the engine once the transcript differs from a
+// pending pre-compact mark: compaction may have rewritten lines that were
+// already uploaded, so the engine's byte offsets can't be trusted. The mark
+// is kept until the re-init succeeds. Caller ensures the engine is
+// initialized.
+func (d *Daemon) checkCompaction() {
+	if d.state == nil || d.state.PreCompact == nil {
+		return
+	}
+	mark := d.state.PreCompact
+	info, err := os.Stat(d.transcriptPath)
+	if err != nil || (info.Size() == mark.ByteOffset && info.ModTime().Equal(mark.ModTime)) {
+		return // not compacted yet
+	}
+	log := logger.With("component", "daemon", "pre_compact_bytes", mark.ByteOffset, "bytes", info.Size())
+	if err := d.engine.Reinit(); err != nil {
+		log.Warn("Re-init after compaction failed (will retry)", "error", err)
+		return
+	}
+	log.Info("Transcript changed since PreCompact, re-initialized sync state")
+	d.state.PreCompact = nil
+	if err := d.state.Save(); err != nil {
+		logger.Warn("Failed to clear pre-compact mark", "component", "daemon", "error", err)
+	}
+}
+
+// observeCycle reports a cycle's init or sync outcome to the Prometheus
+// metrics, if enabled.
+func (d *Daemon) observeCycle(err error) {
+	if d.prom != nil {
+		d.prom.observeCycle(err)
+	}
+}
+
+// noteRateLimit records the Retry-After of a rate-limited (429) error so
+// the main loop waits at least that long before the next sync.
+func (d *Daemon) noteRateLimit(err error) {
+	if wait := http.RetryAfter(err); wait > 0 {
+		d.rateLimitedUntil = time.Now().Add(wait)
+		logger.Warn("Backend rate limited", "component", "daemon", "retry_in", wait.String(), "error", err)
+	}
 }
 
 // waitForTranscript waits for the transcript file to exist before proceeding.
@@ -405,12 +863,97 @@ func (d *Daemon) waitForTranscript(ctx context.Context, sigCh chan os.Signal) er
 		case <-timeout:
 			return fmt.Errorf("timeout waiting for transcript file after %v", initialWaitTimeout)
 		case <-ticker.C:
-			if _, err := os.Stat(d.transcriptPath); err == nil {
-				logger.Info("Transcript file appeared")
-				return nil
-			}
+		case <-d.watchC():
+		}
+		if _, err := os.Stat(d.transcriptPath); err == nil {
+			logger.Info("Transcript file appeared")
+			return nil
+		}
+	}
+}
+
+// scheduleAllowsSync reports whether the config's sync_schedule allows
+// uploading now, logging transitions in and out of a quiet window. The
+// schedule is reloaded when the config changes, so edits take effect
+// without restarting the daemon; an unreadable config allows syncing
+// (tryInit will report the problem).
+func (d *Daemon) scheduleAllowsSync() bool {
+	d.loadSchedule()
+	allowed := d.schedule.Allows(time.Now())
+	switch {
+	case !allowed && !d.heldBySchedule:
+		logger.Info("Sync paused: inside a sync_schedule quiet window; buffering until it ends")
+	case allowed && d.heldBySchedule:
+		logger.Info("Sync resumed: sync_schedule window open, flushing buffered changes")
+	}
+	d.heldBySchedule = !allowed
+	return allowed
+}
+
+// configStamp identifies the state of the config files a schedule was read
+// from: the global config and the working directory's project config, if
+// any. ok is false when either couldn't be stat'ed.
+type configStamp struct {
+	ok                            bool
+	projectPath                   string
+	globalSize, projectSize       int64
+	globalModTime, projectModTime time.Time
+}
+
+// currentConfigStamp stats the config files.
+func currentConfigStamp() configStamp {
+	path, err := config.UploadConfigPath()
+	if err != nil {
+		return configStamp{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return configStamp{}
+	}
+	stamp := configStamp{ok: true, globalSize: info.Size(), globalModTime: info.ModTime()}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return configStamp{}
+	}
+	if stamp.projectPath, err = config.FindProjectConfig(cwd); err != nil {
+		return configStamp{}
+	}
+	if stamp.projectPath != "" {
+		info, err := os.Stat(stamp.projectPath)
+		if err != nil {
+			return configStamp{}
+		}
+		stamp.projectSize, stamp.projectModTime = info.Size(), info.ModTime()
+	}
+	return stamp
+}
+
+// loadSchedule reloads d.schedule from this daemon's binding's config
+// unless the config files are unchanged since the last load.
+func (d *Daemon) loadSchedule() {
+	stamp := currentConfigStamp()
+	if stamp.ok && stamp == d.scheduleStamp {
+		return
+	}
+	d.scheduleStamp = stamp
+	cfg, err := config.GetUploadConfigFor(d.binding())
+	if err != nil {
+		if d.scheduleErr == nil {
+			logger.Warn("Cannot read sync_schedule from config; syncing without it", "error", err)
 		}
+		d.schedule, d.scheduleErr = nil, err
+		return
 	}
+	d.schedule, d.scheduleErr = cfg.SyncSchedule, nil
+}
+
+// watchC returns the watch-mode trigger channel, or nil (which blocks
+// forever in a select) when not watching.
+func (d *Daemon) watchC() <-chan struct{} {
+	if d.watcher == nil {
+		return nil
+	}
+	return d.watcher.C()
 }
 
 // backendSyncEnabled reports whether this daemon has a data source to sync.
@@ -448,6 +991,20 @@ func (d *Daemon) binding() config.Binding {
 	return provider.BindingFor(p, d.configDir)
 }
 
+// checkRedactionConfig validates the redaction patterns of the config this
+// daemon will sync with. A config that can't be loaded yet (e.g. not logged
+// in) is left for tryInit to report and retry.
+func (d *Daemon) checkRedactionConfig() error {
+	cfg, err := config.GetUploadConfigFor(d.binding())
+	if err != nil {
+		return nil
+	}
+	if err := config.ValidateRedactionConfig(cfg.GetEffectiveRedactionConfig()); err != nil {
+		return fmt.Errorf("invalid redaction config: %w", err)
+	}
+	return nil
+}
+
 // tryInit attempts to initialize the sync engine and session with the backend.
 // Auth is checked here lazily, not at daemon startup.
 func (d *Daemon) tryInit() error {
@@ -459,6 +1016,15 @@ func (d *Daemon) tryInit() error {
 			TranscriptPath: d.transcriptPath,
 			CWD:            d.cwd,
 			Model:          d.model,
+
+			TargetChunkDuration: d.chunkTarget,
+			MinChunkBytes:       d.minChunkBytes,
+
+			OnProgress: (&progressLogger{}).observe,
+		}
+		if d.prom != nil {
+			engineCfg.OnChunkStats = d.prom.observeChunk
+			engineCfg.OnBackendRequest = d.prom.observeRequest
 		}
 
 		// Get authenticated config lazily, only when we need to talk to backend.
@@ -475,6 +1041,16 @@ func (d *Daemon) tryInit() error {
 			return fmt.Errorf("failed to create sync engine: %w", err)
 		}
 		d.engine = engine
+		d.tokenEngine.Store(engine)
+		if d.state != nil {
+			engine.SeedKnownAgentIDs(d.state.KnownAgentIDs)
+			engine.SeedOffsetHints(d.state.FileOffsets)
+			if d.state.ChunkSizing != nil {
+				engine.SeedChunkSizing(*d.state.ChunkSizing)
+			}
+			engine.SeedPendingUploads(d.state.PendingUploads)
+			engine.SeedStartEventSent(d.state.StartEventSessionID)
+		}
 
 		// CF-538: wrap the engine's tracker so OpenCode's DiscoverDescendants
 		// drives per-child collector spawn (and capability gating) through
@@ -494,17 +1070,124 @@ func (d *Daemon) tryInit() error {
 	// Update session context now that we have the backend session ID
 	logger.SetSession(d.externalID, d.engine.SessionID())
 
+	// Once per backend session, across restarts via the state file; the
+	// backend only uses it to record session context.
+	if err := d.engine.SendStartEvent(); err != nil {
+		logger.Warn("Failed to send session_start event", "component", "daemon", "error", err)
+	}
+
 	// Persist the Confab session ID so other hooks (e.g., PreToolUse) can access it
 	if d.state != nil {
 		d.state.ConfabSessionID = d.engine.SessionID()
+		d.state.StartEventSessionID = d.engine.StartEventSessionID()
 		if err := d.state.Save(); err != nil {
-			logger.Warn("Failed to save Confab session ID to state: %v", err)
+			logger.Warn("Failed to save Confab session ID to state", "component", "daemon", "error", err)
 		}
 	}
 
 	return nil
 }
 
+// loadPreviousState returns the state recorded by a previous daemon for this
+// session, if its state file is still on disk and that daemon is no longer
+// running. Best-effort: any load error yields nil.
+func (d *Daemon) loadPreviousState() *State {
+	prev, err := LoadStateForProvider(d.providerName, d.externalID)
+	if err != nil || prev == nil || prev.IsDaemonRunning() {
+		return nil
+	}
+	if len(prev.KnownAgentIDs) > 0 {
+		logger.Infof("Restored %d known agent ID(s) from previous daemon state", len(prev.KnownAgentIDs))
+	}
+	if len(prev.FileOffsets) > 0 {
+		logger.Infof("Restored read offsets for %d file(s) from previous daemon state", len(prev.FileOffsets))
+	}
+	if prev.DirtyTail != nil {
+		logger.Infof("Previous daemon exited with unsynced data at %s; resuming", prev.DirtyTail.Format(time.RFC3339))
+	}
+	if prev.Completed != nil {
+		logger.Infof("Session completed at %s; waiting for new transcript lines before syncing", prev.Completed.At.Format(time.RFC3339))
+	}
+	return prev
+}
+
+// persistSyncState writes the engine's discovered agent IDs, upload progress
+// and file read offsets, plus the daemon's error and backoff state, into
+// the state file when any has changed since the last save. Without an
+// engine (init keeps failing) only the error and backoff state is updated.
+// The agent-ID set only ever grows, so a length comparison is enough to
+// detect a change; progress changes whenever a cycle uploads or completes.
+func (d *Daemon) persistSyncState() {
+	if d.state == nil || (d.engine == nil && d.state.SyncProgress == nil && d.lastSyncError == "") {
+		return
+	}
+	changed := false
+
+	progress := &SyncProgress{}
+	if d.state.SyncProgress != nil {
+		*progress = *d.state.SyncProgress
+	}
+	progress.ConsecutiveErrors = d.consecutiveErrors
+	progress.LastError = d.lastSyncError
+	progress.BackoffUntil = nil
+	if d.rateLimitedUntil.After(time.Now()) {
+		until := d.rateLimitedUntil
+		progress.BackoffUntil = &until
+	}
+
+	if d.engine != nil {
+		ids := d.engine.KnownAgentIDs()
+		if len(ids) != len(d.state.KnownAgentIDs) {
+			d.state.KnownAgentIDs = ids
+			changed = true
+		}
+
+		stats := d.engine.GetSyncStats()
+		progress.FileLines = stats.FileLines
+		progress.BytesUploaded = stats.BytesUploaded
+		progress.LastSyncAt = stats.LastSyncAt
+
+		if offsets := d.engine.FileOffsets(); !fileOffsetsEqual(offsets, d.state.FileOffsets) {
+			d.state.FileOffsets = offsets
+			changed = true
+		}
+
+		if paths := d.engine.FilePaths(); !maps.Equal(paths, d.state.FilePaths) {
+			d.state.FilePaths = paths
+			changed = true
+		}
+
+		if sizing := d.engine.ChunkSizing(); d.state.ChunkSizing == nil || sizing != *d.state.ChunkSizing {
+			d.state.ChunkSizing = &sizing
+			changed = true
+		}
+
+		if pending := d.engine.PendingUploads(); !maps.Equal(pending, d.state.PendingUploads) {
+			d.state.PendingUploads = pending
+			changed = true
+		}
+
+		if d.state.DirtyTail != nil && !stats.LastSyncAt.IsZero() {
+			logger.Info("Data left unsynced by a previous daemon is now synced")
+			d.state.DirtyTail = nil
+			changed = true
+		}
+	}
+
+	d.progress.Store(progress)
+	if !progress.equal(d.state.SyncProgress) {
+		d.state.SyncProgress = progress
+		changed = true
+	}
+
+	if !changed {
+		return
+	}
+	if err := d.state.Save(); err != nil {
+		logger.Warnf("Failed to save sync state: %v", err)
+	}
+}
+
 // resetEngineOnAuthFailure clears the sync engine to force a config re-read
 // on the next cycle. The user may have re-authenticated with a new API key.
 func (d *Daemon) resetEngineOnAuthFailure() {
@@ -513,6 +1196,7 @@ func (d *Daemon) resetEngineOnAuthFailure() {
 		d.engine.Reset()
 	}
 	d.engine = nil
+	d.tokenEngine.Store(nil)
 }
 
 // Stop signals the daemon to stop. Safe to call multiple times.
@@ -526,7 +1210,7 @@ func (d *Daemon) Stop() {
 func (d *Daemon) shutdown(reason string) error {
 	defer close(d.doneCh)
 
-	logger.Info("Daemon shutting down: reason=%s", reason)
+	logger.Info("Daemon shutting down", "reason", reason)
 
 	// Stop the OpenCode collectors (root + every CF-538 descendant) and wait
 	// for them to exit before the final sync, so no append races the final
@@ -554,60 +1238,96 @@ func (d *Daemon) shutdown(reason string) error {
 	events := d.readInboxEvents()
 	var sessionEndEvent *types.InboxEvent
 	for _, event := range events {
-		logger.Info("Processing inbox event: type=%s", event.Type)
+		logger.Info("Processing inbox event", "type", event.Type)
 		if event.Type == "session_end" && event.HookInput != nil {
-			logger.Debug("SessionEnd event: reason=%s", event.HookInput.Reason)
+			logger.Debug("SessionEnd event", "reason", event.HookInput.Reason)
 			sessionEndEvent = &event
 		}
 	}
 
+	// Changes held back by the sync schedule are flushed on shutdown
+	// regardless of the schedule, which may mean initializing first.
+	initialized := d.engine != nil && d.engine.IsInitialized()
+	flushHeld := d.heldBySchedule && !initialized && d.backendSyncEnabled()
+
 	// Final sync with timeout - if backend is slow/unresponsive, don't hang forever
-	if d.engine != nil && d.engine.IsInitialized() {
+	finalSyncFailed := false
+	completed := false
+	if initialized || flushHeld {
 		done := make(chan struct{})
+		synced := false // written by the goroutine before close(done)
+		ended := false  // likewise: session_end reached the backend
 		go func() {
 			defer close(done)
 			defer func() {
 				if r := recover(); r != nil {
-					logger.Error("Panic during final sync: %v", r)
+					logger.Error("Panic during final sync", "component", "daemon", "panic", r)
 				}
 			}()
 
+			if flushHeld {
+				logger.Info("Flushing changes held by sync_schedule before exit")
+				if err := d.tryInit(); err != nil {
+					logger.Error("Backend init for final flush failed", "component", "daemon", "error", err)
+					return
+				}
+			}
+
 			logger.Info("Performing final sync...")
-			if chunks, err := d.engine.SyncAll(); err != nil {
-				logger.Error("Final sync had errors: %v", err)
+			chunks, err := d.engine.SyncAllFinal()
+			if err != nil {
+				logger.Error("Final sync had errors", "component", "daemon", "error", err)
 			} else if chunks > 0 {
-				logger.Info("Final sync complete: chunks=%d", chunks)
+				logger.Info("Final sync complete", "component", "daemon", "chunks", chunks)
 			} else {
 				logger.Info("Final sync complete: already up to date")
 			}
+			synced = err == nil
 
 			// Log final stats
 			stats := d.engine.GetSyncStats()
-			for file, lines := range stats {
-				logger.Info("Final state: file=%s lines_synced=%d", file, lines)
+			for file, lines := range stats.FileLines {
+				logger.Info("Final state", "component", "daemon", "file", file, "lines_synced", lines)
 			}
 
 			// Send session_end event to backend (after final sync completes)
 			if sessionEndEvent != nil {
 				if err := d.engine.SendSessionEnd(sessionEndEvent.HookInput, sessionEndEvent.Timestamp); err != nil {
-					logger.Error("Failed to send session_end event: %v", err)
+					logger.Error("Failed to send session_end event", "component", "daemon", "error", err)
 					// Don't fail shutdown for this - the sync already completed
+				} else {
+					ended = true
 				}
 			}
 		}()
 
 		select {
 		case <-done:
-			// Sync completed normally
+			// Sync finished; the engine is quiescent, so its progress can
+			// be persisted in case it failed.
+			finalSyncFailed = !synced
+			completed = synced && ended
+			if finalSyncFailed || completed {
+				d.persistSyncState()
+			}
 		case <-time.After(shutdownTimeout):
-			logger.Warn("Shutdown timed out after %v, skipping final sync", shutdownTimeout)
+			// The sync goroutine may still be using the engine, so the
+			// progress persisted by the last cycle is what we keep.
+			logger.Warnf("Shutdown timed out after %v, skipping final sync", shutdownTimeout)
+			finalSyncFailed = true
 		}
 	}
 
+	// A daemon that resumed a completed session and never saw it change
+	// leaves the completion mark in place.
+	if d.state != nil && d.state.Completed != nil && !initialized {
+		completed = true
+	}
+
 	// Clean up state and inbox files
-	if d.state != nil {
+	if d.state != nil && !(finalSyncFailed && d.keepDirtyState()) && !(completed && d.keepCompletedState()) {
 		if err := d.state.DeleteWithInbox(); err != nil {
-			logger.Warn("Failed to delete state/inbox files: %v", err)
+			logger.Warnf("Failed to delete state/inbox files: %v", err)
 		}
 	}
 
@@ -615,6 +1335,58 @@ func (d *Daemon) shutdown(reason string) error {
 	return nil
 }
 
+// keepDirtyState saves the state file with a dirty-tail marker instead of
+// deleting it, so the next daemon for this session resumes from the
+// persisted offsets and known agent IDs rather than losing track of data
+// that was never uploaded. The inbox is still removed: a replayed
+// session_end would be stale. The PID is cleared: the state outlives this
+// process by days, and a reused PID must not pass for a running daemon.
+// Reports false if the state couldn't be saved.
+func (d *Daemon) keepDirtyState() bool {
+	now := time.Now()
+	d.state.DirtyTail = &now
+	d.state.PID = 0
+	if err := d.state.Save(); err != nil {
+		logger.Warnf("Failed to save state with unsynced data: %v", err)
+		return false
+	}
+	logger.Warn("Final sync failed; kept state for the next daemon to resume unsynced data")
+	if d.state.InboxPath != "" {
+		if err := os.Remove(d.state.InboxPath); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("Failed to delete inbox file: %v", err)
+		}
+	}
+	return true
+}
+
+// keepCompletedState saves the state file with a completion mark instead of
+// deleting it (see State.Completed). Like keepDirtyState, the PID is
+// cleared and the inbox removed. Reports false if the state couldn't be
+// saved.
+func (d *Daemon) keepCompletedState() bool {
+	if d.state.Completed == nil {
+		info, err := os.Stat(d.transcriptPath)
+		if err != nil {
+			logger.Warnf("Cannot stat transcript to mark session completed: %v", err)
+			return false
+		}
+		d.state.Completed = &CompletionMark{At: time.Now(), Size: info.Size(), ModTime: info.ModTime()}
+	}
+	d.state.DirtyTail = nil
+	d.state.PID = 0
+	if err := d.state.Save(); err != nil {
+		logger.Warnf("Failed to save completed state: %v", err)
+		return false
+	}
+	logger.Info("Session completed; kept state so a restarted daemon skips synced lines")
+	if d.state.InboxPath != "" {
+		if err := os.Remove(d.state.InboxPath); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("Failed to delete inbox file: %v", err)
+		}
+	}
+	return true
+}
+
 // readInboxEvents reads all events from the inbox file
 func (d *Daemon) readInboxEvents() []types.InboxEvent {
 	if d.state == nil || d.state.InboxPath == "" {
@@ -624,7 +1396,7 @@ func (d *Daemon) readInboxEvents() []types.InboxEvent {
 	f, err := os.Open(d.state.InboxPath)
 	if err != nil {
 		if !os.IsNotExist(err) {
-			logger.Warn("Failed to open inbox file: %v", err)
+			logger.Warnf("Failed to open inbox file: %v", err)
 		}
 		return nil
 	}
@@ -635,14 +1407,14 @@ func (d *Daemon) readInboxEvents() []types.InboxEvent {
 	for scanner.Scan() {
 		var event types.InboxEvent
 		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
-			logger.Warn("Failed to parse inbox event: %v", err)
+			logger.Warnf("Failed to parse inbox event: %v", err)
 			continue
 		}
 		events = append(events, event)
 	}
 
 	if err := scanner.Err(); err != nil {
-		logger.Warn("Error reading inbox file: %v", err)
+		logger.Warnf("Error reading inbox file: %v", err)
 	}
 
 	return events
@@ -666,6 +1438,13 @@ func StopDaemonForProvider(providerName, externalID string, hookInput *types.Cla
 	}
 
 	if !state.IsDaemonRunning() {
+		if state.Completed != nil {
+			return fmt.Errorf("daemon not running (session already completed)")
+		}
+		if state.DirtyTail != nil {
+			// Kept for the next daemon to resume unsynced data.
+			return fmt.Errorf("daemon not running (unsynced data kept for the next daemon)")
+		}
 		// Clean up stale state file
 		state.Delete()
 		return fmt.Errorf("daemon not running (stale state cleaned up)")
@@ -674,7 +1453,7 @@ func StopDaemonForProvider(providerName, externalID string, hookInput *types.Cla
 	// Write event to inbox before signaling (daemon reads on shutdown)
 	if hookInput != nil && state.InboxPath != "" {
 		if err := writeInboxEvent(state.InboxPath, "session_end", hookInput); err != nil {
-			logger.Warn("Failed to write inbox event: %v", err)
+			logger.Warnf("Failed to write inbox event: %v", err)
 			// Continue anyway - daemon can still do final sync without the event
 		}
 	}
@@ -689,7 +1468,7 @@ func StopDaemonForProvider(providerName, externalID string, hookInput *types.Cla
 		return fmt.Errorf("failed to send SIGTERM: %w", err)
 	}
 
-	logger.Info("Sent SIGTERM to daemon: pid=%d", state.PID)
+	logger.Info("Sent SIGTERM to daemon", "pid", state.PID)
 	return nil
 }
 
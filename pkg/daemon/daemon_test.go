@@ -810,3 +810,56 @@ func TestNewDaemonHasParentDeathChannel(t *testing.T) {
 	default:
 	}
 }
+
+// TestKeepDirtyState_NeverRunning verifies a state kept after a failed final
+// sync doesn't carry the exited daemon's PID, so a reused PID can't make it
+// look running to the spawn check or get signaled by the stop paths.
+func TestKeepDirtyState_NeverRunning(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// PID is this (live) process, as for a daemon about to exit.
+	d := &Daemon{providerName: "claude-code", externalID: "dirty-pid-test"}
+	d.state = NewStateForProvider(d.providerName, d.externalID, "/path", "/cwd", 0)
+	if !d.keepDirtyState() {
+		t.Fatal("keepDirtyState failed")
+	}
+
+	state, err := LoadStateForProvider(d.providerName, d.externalID)
+	if err != nil || state == nil {
+		t.Fatalf("load kept state: %v (state=%v)", err, state)
+	}
+	if state.IsDaemonRunning() {
+		t.Errorf("dirty state counts as running (pid %d)", state.PID)
+	}
+	if err := StopDaemonForProvider(d.providerName, d.externalID, nil); err == nil {
+		t.Error("StopDaemonForProvider succeeded for a kept dirty state")
+	}
+	if state, _ := LoadStateForProvider(d.providerName, d.externalID); state == nil || state.DirtyTail == nil {
+		t.Errorf("stop deleted the dirty state: %+v", state)
+	}
+}
+
+// TestKeepCompletedState_NeverRunning is TestKeepDirtyState_NeverRunning
+// for a session that ended cleanly.
+func TestKeepCompletedState_NeverRunning(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	transcriptPath := filepath.Join(t.TempDir(), "transcript.jsonl")
+	os.WriteFile(transcriptPath, []byte(`{"type":"user"}`+"\n"), 0644)
+
+	d := &Daemon{providerName: "claude-code", externalID: "completed-pid-test", transcriptPath: transcriptPath}
+	d.state = NewStateForProvider(d.providerName, d.externalID, transcriptPath, "/cwd", 0)
+	if !d.keepCompletedState() {
+		t.Fatal("keepCompletedState failed")
+	}
+
+	state, err := LoadStateForProvider(d.providerName, d.externalID)
+	if err != nil || state == nil || state.Completed == nil {
+		t.Fatalf("load kept state: %v (state=%+v)", err, state)
+	}
+	if state.IsDaemonRunning() {
+		t.Errorf("completed state counts as running (pid %d)", state.PID)
+	}
+	if err := StopDaemonForProvider(d.providerName, d.externalID, nil); err == nil {
+		t.Error("StopDaemonForProvider succeeded for a completed state")
+	}
+}
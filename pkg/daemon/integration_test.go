@@ -1,6 +1,8 @@
 package daemon
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -16,8 +18,10 @@ import (
 	"testing"
 	"time"
 
+	confabhttp "github.com/ConfabulousDev/confab/pkg/http"
 	"github.com/ConfabulousDev/confab/pkg/provider"
 	"github.com/ConfabulousDev/confab/pkg/sync"
+	"github.com/ConfabulousDev/confab/pkg/types"
 	"github.com/klauspost/compress/zstd"
 )
 
@@ -30,10 +34,21 @@ func readRequestBody(r *http.Request) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	return decodeBody(body, r.Header.Get("Content-Encoding"))
+}
 
-	// Decompress if zstd encoded
-	if r.Header.Get("Content-Encoding") == "zstd" {
+// decodeBody decompresses body per its Content-Encoding (zstd by default,
+// gzip if configured).
+func decodeBody(body []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "zstd":
 		return zstdDecoder.DecodeAll(body, nil)
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(zr)
 	}
 
 	return body, nil
@@ -42,9 +57,10 @@ func readRequestBody(r *http.Request) ([]byte, error) {
 // mockBackend tracks requests and provides configurable responses
 type mockBackend struct {
 	t              *testing.T
-	mu             stdsync.Mutex // protects initRequests and chunkRequests
+	mu             stdsync.Mutex // protects initRequests, chunkRequests and eventRequests
 	initRequests   []sync.InitRequest
 	chunkRequests  []sync.ChunkRequest
+	eventRequests  []sync.EventRequest
 	initResponse   *sync.InitResponse
 	initError      bool
 	chunkError     bool
@@ -57,8 +73,49 @@ type mockBackend struct {
 	// keeps workflow discovery off; the route exists to avoid the
 	// default-case t.Errorf if a probe ever fires.
 	caps *sync.Capabilities
+
+	// Token refresh. When accessToken is set, /api/v1 requests bearing any
+	// other token get 401, and sync.TokenRefreshPath exchanges refreshToken
+	// for refreshedAccessToken (valid for an hour). Guarded by mu.
+	accessToken  string
+	refreshToken string
+	refreshCalls int
+
+	// Backend-side chunk bookkeeping, when enforceContiguity is set:
+	// stored is the last line stored per file (also reported by init),
+	// and a chunk not starting right after it gets 400 "contiguity error"
+	// — unless its idempotency key was seen, in which case the cached
+	// response is replayed. dropResponses loses that many responses after
+	// storing the chunk (the connection is closed), as a network drop
+	// would. Guarded by mu.
+	enforceContiguity bool
+	dropResponses     int
+	stored            map[string]int
+	idempotent        map[string]sync.ChunkResponse
+	contiguityErrors  int
+
+	// Multipart chunk uploads. Without multipart the start endpoint 404s,
+	// as on a backend that predates it. uploads holds each started
+	// upload's parts; a part at failPart's index gets 503 while failPart
+	// is >= 0, and partUploads counts the part uploads per index. Guarded
+	// by mu.
+	multipart     bool
+	failPart      int
+	uploads       map[string]*mockUpload
+	partUploads   map[int]int
+	statusQueries int
+	startRequests int
+}
+
+// mockUpload is a multipart upload mockBackend has started.
+type mockUpload struct {
+	start sync.MultipartStartRequest
+	parts map[int][]byte
 }
 
+// refreshedAccessToken is the access token mockBackend issues on refresh.
+const refreshedAccessToken = "cfb_refreshed-access-token-1234"
+
 // getInitRequests returns a snapshot of the init requests received so far.
 func (m *mockBackend) getInitRequests() []sync.InitRequest {
 	m.mu.Lock()
@@ -75,7 +132,8 @@ func (m *mockBackend) getChunkRequests() []sync.ChunkRequest {
 
 func newMockBackend(t *testing.T) *mockBackend {
 	return &mockBackend{
-		t: t,
+		t:        t,
+		failPart: -1,
 		initResponse: &sync.InitResponse{
 			SessionID: "test-session-id",
 			Files:     make(map[string]sync.FileState),
@@ -103,7 +161,30 @@ func (m *mockBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.HasPrefix(r.URL.Path, "/api/v1/") {
+		m.mu.Lock()
+		rejected := m.accessToken != "" && r.Header.Get("Authorization") != "Bearer "+m.accessToken
+		m.mu.Unlock()
+		if rejected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
 	switch r.URL.Path {
+	case sync.TokenRefreshPath:
+		var req sync.TokenRefreshRequest
+		json.Unmarshal(body, &req)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.refreshCalls++
+		if req.RefreshToken != m.refreshToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		m.accessToken = refreshedAccessToken
+		json.NewEncoder(w).Encode(sync.TokenResponse{AccessToken: refreshedAccessToken, ExpiresIn: 3600})
+
 	case "/api/v1/capabilities":
 		if m.caps == nil {
 			w.WriteHeader(http.StatusNotFound) // old backend: no endpoint
@@ -126,8 +207,27 @@ func (m *mockBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		m.mu.Lock()
 		m.initRequests = append(m.initRequests, req)
+		resp := *m.initResponse
+		if m.enforceContiguity {
+			resp.Files = make(map[string]sync.FileState, len(m.stored))
+			for name, line := range m.stored {
+				resp.Files[name] = sync.FileState{LastSyncedLine: line}
+			}
+		}
+		m.mu.Unlock()
+		json.NewEncoder(w).Encode(&resp)
+
+	case "/api/v1/sync/event":
+		var req sync.EventRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			m.t.Errorf("Failed to decode event request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		m.mu.Lock()
+		m.eventRequests = append(m.eventRequests, req)
 		m.mu.Unlock()
-		json.NewEncoder(w).Encode(m.initResponse)
+		json.NewEncoder(w).Encode(sync.EventResponse{Success: true})
 
 	case "/api/v1/sync/chunk":
 		if m.chunkStatus != 0 {
@@ -156,6 +256,24 @@ func (m *mockBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		m.mu.Lock()
 		m.chunkRequests = append(m.chunkRequests, req)
+		if m.enforceContiguity {
+			resp, status := m.storeChunk(req, r.Header.Get(sync.IdempotencyKeyHeader))
+			drop := status == http.StatusOK && m.dropResponses > 0
+			if drop {
+				m.dropResponses--
+			}
+			m.mu.Unlock()
+			switch {
+			case drop:
+				dropConnection(w)
+			case status != http.StatusOK:
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(map[string]string{"error": "contiguity error"})
+			default:
+				json.NewEncoder(w).Encode(resp)
+			}
+			return
+		}
 		m.mu.Unlock()
 
 		// Return last synced line as first + len(lines) - 1
@@ -164,12 +282,121 @@ func (m *mockBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			LastSyncedLine: lastLine,
 		})
 
+	case "/api/v1/sync/chunk/start":
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.startRequests++
+		if !m.multipart {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var req sync.MultipartStartRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			m.t.Errorf("Failed to decode multipart start request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if m.uploads == nil {
+			m.uploads = make(map[string]*mockUpload)
+			m.partUploads = make(map[int]int)
+		}
+		id := fmt.Sprintf("upload-%d", len(m.uploads)+1)
+		m.uploads[id] = &mockUpload{start: req, parts: make(map[int][]byte)}
+		json.NewEncoder(w).Encode(sync.MultipartStartResponse{UploadID: id})
+
+	case "/api/v1/sync/chunk/status":
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.statusQueries++
+		upload := m.uploads[r.URL.Query().Get("upload_id")]
+		if upload == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp := sync.MultipartStatusResponse{ReceivedParts: []int{}}
+		for i := range upload.parts {
+			resp.ReceivedParts = append(resp.ReceivedParts, i)
+		}
+		json.NewEncoder(w).Encode(resp)
+
+	case "/api/v1/sync/chunk/part":
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		upload := m.uploads[r.URL.Query().Get("upload_id")]
+		var from, to int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &from, &to); err != nil || upload == nil || to-from+1 != len(body) {
+			m.t.Errorf("Bad multipart part: range=%q body=%d bytes", r.Header.Get("Range"), len(body))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		index := from / upload.start.PartSize
+		m.partUploads[index]++
+		if index == m.failPart {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		upload.parts[index] = body
+		var assembled []byte
+		for i := 0; i*upload.start.PartSize < upload.start.TotalSize; i++ {
+			part, ok := upload.parts[i]
+			if !ok {
+				json.NewEncoder(w).Encode(sync.MultipartPartResponse{})
+				return
+			}
+			assembled = append(assembled, part...)
+		}
+		decoded, err := decodeBody(assembled, upload.start.ContentEncoding)
+		var req sync.ChunkRequest
+		if err == nil {
+			err = json.Unmarshal(decoded, &req)
+		}
+		if err != nil {
+			m.t.Errorf("Failed to decode assembled multipart chunk: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		m.chunkRequests = append(m.chunkRequests, req)
+		json.NewEncoder(w).Encode(sync.MultipartPartResponse{Complete: true, LastSyncedLine: req.FirstLine + len(req.Lines) - 1})
+
 	default:
 		m.t.Errorf("Unexpected request to %s", r.URL.Path)
 		w.WriteHeader(http.StatusNotFound)
 	}
 }
 
+// storeChunk applies req to the backend-side file state as a backend with
+// idempotency support would: a known key replays its cached response, a
+// chunk that doesn't continue the file is rejected with 400, anything else
+// is stored. Caller holds mu.
+func (m *mockBackend) storeChunk(req sync.ChunkRequest, key string) (sync.ChunkResponse, int) {
+	if resp, ok := m.idempotent[key]; ok && key != "" {
+		return resp, http.StatusOK
+	}
+	if m.stored == nil {
+		m.stored = make(map[string]int)
+		m.idempotent = make(map[string]sync.ChunkResponse)
+	}
+	if req.FirstLine != m.stored[req.FileName]+1 {
+		m.contiguityErrors++
+		return sync.ChunkResponse{}, http.StatusBadRequest
+	}
+	m.stored[req.FileName] = req.FirstLine + len(req.Lines) - 1
+	resp := sync.ChunkResponse{LastSyncedLine: m.stored[req.FileName]}
+	if key != "" {
+		m.idempotent[key] = resp
+	}
+	return resp, http.StatusOK
+}
+
+// dropConnection closes the connection without a response, so the client
+// sees a transport error after the backend already acted on the request.
+func dropConnection(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
 // setupTestEnv creates a temporary environment for daemon testing
 func setupTestEnv(t *testing.T, serverURL string) (tmpDir string, transcriptPath string) {
 	tmpDir = t.TempDir()
@@ -191,6 +418,62 @@ func setupTestEnv(t *testing.T, serverURL string) (tmpDir string, transcriptPath
 	return tmpDir, transcriptPath
 }
 
+// TestDaemonSyncCycle_GzipCompression runs a sync cycle with
+// compression=gzip and checks the mock backend decodes every chunk.
+func TestDaemonSyncCycle_GzipCompression(t *testing.T) {
+	var encodings stdsync.Map
+	mock := newMockBackend(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if enc := r.Header.Get("Content-Encoding"); enc != "" {
+			encodings.Store(enc, true)
+		}
+		mock.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	configJSON := fmt.Sprintf(`{"backend_url":"%s","api_key":"test-api-key-12345678","compression":"gzip"}`, server.URL)
+	os.WriteFile(os.Getenv("CONFAB_CONFIG_PATH"), []byte(configJSON), 0600)
+
+	// Lines long enough that the chunk crosses the compression threshold.
+	var content strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&content, `{"type":"user","n":%d,"message":"%s"}`+"\n", i, strings.Repeat("x", 100))
+	}
+	os.WriteFile(transcriptPath, []byte(content.String()), 0644)
+
+	d := New(Config{
+		ExternalID:     "gzip-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   time.Hour,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(mock.getChunkRequests()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-errCh
+
+	chunks := mock.getChunkRequests()
+	if len(chunks) == 0 || len(chunks[0].Lines) != 20 {
+		t.Fatalf("expected one decoded 20-line chunk, got %d chunks", len(chunks))
+	}
+	if _, ok := encodings.Load("gzip"); !ok {
+		t.Error("expected a gzip-encoded request")
+	}
+	if _, ok := encodings.Load("zstd"); ok {
+		t.Error("got a zstd-encoded request with compression=gzip")
+	}
+}
+
 // TestDaemonSyncCycle tests a full init + sync cycle with mock server
 func TestDaemonSyncCycle(t *testing.T) {
 	mock := newMockBackend(t)
@@ -267,6 +550,56 @@ func TestDaemonSyncCycle(t *testing.T) {
 	}
 }
 
+// TestDaemonIdempotentChunkResend covers a chunk whose response is lost
+// after the backend stored it: the in-client retry re-sends it with the
+// same idempotency key and gets the cached response instead of a
+// contiguity error, and every line is stored exactly once.
+func TestDaemonIdempotentChunkResend(t *testing.T) {
+	mock := newMockBackend(t)
+	mock.enforceContiguity = true
+	mock.dropResponses = 1
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	configJSON := fmt.Sprintf(`{"backend_url":"%s","api_key":"test-api-key-12345678","max_retries":2,"base_backoff_ms":10}`, server.URL)
+	os.WriteFile(os.Getenv("CONFAB_CONFIG_PATH"), []byte(configJSON), 0600)
+	os.WriteFile(transcriptPath, []byte(`{"type":"user","message":"a"}`+"\n"+`{"type":"assistant","message":"b"}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "test-external-id",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   50 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- d.Run(ctx) }()
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	select {
+	case <-errCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Daemon did not exit")
+	}
+
+	chunks := mock.getChunkRequests()
+	if len(chunks) < 2 || chunks[0].FirstLine != 1 || chunks[1].FirstLine != 1 {
+		t.Fatalf("chunk requests = %+v, want the first chunk re-sent", chunks)
+	}
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if mock.contiguityErrors != 0 {
+		t.Errorf("backend returned %d contiguity errors, want the re-send answered from the idempotency cache", mock.contiguityErrors)
+	}
+	if got := mock.stored["transcript.jsonl"]; got != 2 {
+		t.Errorf("backend stored %d lines, want 2", got)
+	}
+	if len(mock.idempotent) != 1 {
+		t.Errorf("backend saw %d idempotency keys, want 1 shared by the original and the re-send", len(mock.idempotent))
+	}
+}
+
 // TestDaemonRetryOnBackendError tests that daemon retries when backend is unavailable
 func TestDaemonRetryOnBackendError(t *testing.T) {
 	mock := newMockBackend(t)
@@ -650,6 +983,493 @@ func TestDaemonAgentFileNotExistYet(t *testing.T) {
 	}
 }
 
+// TestDaemonRestoresKnownAgentIDsAfterRestart simulates a daemon that was
+// killed after discovering an agent ID but before the agent's file existed.
+// The restarted daemon must not re-read the (already synced) referencing
+// transcript line, yet still pick up the agent once its file appears.
+func TestDaemonRestoresKnownAgentIDsAfterRestart(t *testing.T) {
+	mock := newMockBackend(t)
+	// Backend already has both transcript lines from the previous daemon.
+	mock.initResponse.Files = map[string]sync.FileState{
+		"transcript.jsonl": {LastSyncedLine: 2},
+	}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+
+	transcriptContent := `{"type":"system","message":"start"}
+{"type":"user","toolUseResult":{"agentId":"fed98765","result":"pending"}}
+`
+	os.WriteFile(transcriptPath, []byte(transcriptContent), 0644)
+
+	// State file left behind by the killed daemon (PID 0 = not running).
+	stale := NewStateForProvider(provider.NameClaudeCode, "restart-agents-test", transcriptPath, tmpDir, 0)
+	stale.PID = 0
+	stale.KnownAgentIDs = []string{"fed98765"}
+	if err := stale.Save(); err != nil {
+		t.Fatalf("save stale state: %v", err)
+	}
+
+	d := New(Config{
+		ExternalID:     "restart-agents-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   50 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	time.Sleep(150 * time.Millisecond)
+
+	// The restored ID is persisted into the new daemon's state file.
+	loaded, err := LoadStateForProvider(provider.NameClaudeCode, "restart-agents-test")
+	if err != nil || loaded == nil {
+		t.Fatalf("load state: %v (state=%v)", err, loaded)
+	}
+	if len(loaded.KnownAgentIDs) != 1 || loaded.KnownAgentIDs[0] != "fed98765" {
+		t.Errorf("KnownAgentIDs = %v, want [fed98765]", loaded.KnownAgentIDs)
+	}
+
+	// The agent file appears only after the restart.
+	subagentsDir := filepath.Join(filepath.Dir(transcriptPath), "transcript", "subagents")
+	os.MkdirAll(subagentsDir, 0755)
+	agentPath := filepath.Join(subagentsDir, "agent-fed98765.jsonl")
+	os.WriteFile(agentPath, []byte(`{"type":"agent","message":"late"}`+"\n"), 0644)
+
+	time.Sleep(150 * time.Millisecond)
+	cancel()
+	<-errCh
+
+	agentUploads := 0
+	for _, req := range mock.getChunkRequests() {
+		if req.FileType == "transcript" {
+			t.Errorf("transcript should not be re-uploaded, got first_line=%d", req.FirstLine)
+		}
+		if req.FileName == "agent-fed98765.jsonl" {
+			agentUploads++
+		}
+	}
+	if agentUploads == 0 {
+		t.Error("Expected agent referenced before restart to be uploaded after it appeared")
+	}
+}
+
+// TestDaemonShutdownWithBackendDown_ResumesUnsyncedData covers a daemon
+// whose backend becomes unreachable before shutdown: the final sync fails,
+// so the state file must be kept with a dirty tail (offsets and the agent
+// ID discovered from an already-synced line), and a later daemon run must
+// upload everything that was left behind.
+func TestDaemonShutdownWithBackendDown_ResumesUnsyncedData(t *testing.T) {
+	const externalID = "backend-down-shutdown-test"
+	mock1 := newMockBackend(t)
+	server1 := httptest.NewServer(mock1)
+
+	tmpDir, transcriptPath := setupTestEnv(t, server1.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","message":"start"}
+{"type":"user","toolUseResult":{"agentId":"abc24680","result":"pending"}}
+`), 0644)
+
+	// run starts a daemon, lets it sync, calls beforeStop, then stops it.
+	run := func(beforeStop func()) {
+		d := New(Config{
+			ExternalID:     externalID,
+			TranscriptPath: transcriptPath,
+			CWD:            tmpDir,
+			SyncInterval:   50 * time.Millisecond,
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() { errCh <- d.Run(ctx) }()
+		time.Sleep(200 * time.Millisecond)
+		beforeStop()
+		cancel()
+		if err := <-errCh; err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	}
+
+	// First run: take the backend down, then write data that only the
+	// final sync could upload.
+	run(func() {
+		server1.Close()
+		f, _ := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+		f.WriteString(`{"type":"assistant","message":"written while backend down"}` + "\n")
+		f.Close()
+		subagentsDir := filepath.Join(filepath.Dir(transcriptPath), "transcript", "subagents")
+		os.MkdirAll(subagentsDir, 0755)
+		os.WriteFile(filepath.Join(subagentsDir, "agent-abc24680.jsonl"), []byte(`{"type":"agent","message":"late"}`+"\n"), 0644)
+	})
+	if got := len(mock1.getChunkRequests()); got == 0 {
+		t.Fatal("expected the first run to upload before the backend went down")
+	}
+
+	state, err := LoadStateForProvider(provider.NameClaudeCode, externalID)
+	if err != nil || state == nil {
+		t.Fatalf("state file should be kept after a failed final sync: %v (state=%v)", err, state)
+	}
+	if state.DirtyTail == nil {
+		t.Error("expected dirty_tail to be set after a failed final sync")
+	}
+	// The daemon ran in this process, so its PID is alive; a kept state
+	// must not pass for a running daemon regardless.
+	if state.PID != 0 || state.IsDaemonRunning() {
+		t.Errorf("dirty state counts as running (pid %d)", state.PID)
+	}
+	if len(state.KnownAgentIDs) != 1 || state.KnownAgentIDs[0] != "abc24680" {
+		t.Errorf("KnownAgentIDs = %v, want [abc24680]", state.KnownAgentIDs)
+	}
+
+	// The backend is back (at a new address) with what the first run uploaded.
+	mock2 := newMockBackend(t)
+	mock2.initResponse.Files = map[string]sync.FileState{
+		"transcript.jsonl": {LastSyncedLine: 2},
+	}
+	server2 := httptest.NewServer(mock2)
+	defer server2.Close()
+	writeScheduleConfig(t, server2.URL, false)
+
+	run(func() {})
+
+	var transcriptFirstLines []int
+	agentUploads := 0
+	for _, req := range mock2.getChunkRequests() {
+		switch req.FileName {
+		case "transcript.jsonl":
+			transcriptFirstLines = append(transcriptFirstLines, req.FirstLine)
+		case "agent-abc24680.jsonl":
+			agentUploads++
+		}
+	}
+	if len(transcriptFirstLines) == 0 || transcriptFirstLines[0] != 3 {
+		t.Errorf("transcript uploads after recovery start at %v, want line 3", transcriptFirstLines)
+	}
+	if agentUploads == 0 {
+		t.Error("agent file referenced before the outage was never uploaded")
+	}
+	if state, _ := LoadStateForProvider(provider.NameClaudeCode, externalID); state != nil {
+		t.Errorf("state file should be deleted after a clean shutdown, got dirty_tail=%v", state.DirtyTail)
+	}
+}
+
+// TestDaemonRestartAfterCompletion verifies that a session which ended
+// cleanly keeps its state marked completed, and that a daemon restarted
+// for it neither re-inits nor re-uploads until the transcript grows, then
+// uploads only the new lines without resending session_start.
+func TestDaemonRestartAfterCompletion(t *testing.T) {
+	const externalID = "completed-restart-test"
+	mock := newMockBackend(t)
+	mock.enforceContiguity = true
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"user","message":"one"}
+{"type":"assistant","message":"two"}
+`), 0644)
+
+	start := func() (context.CancelFunc, chan error) {
+		d := New(Config{
+			ExternalID:     externalID,
+			TranscriptPath: transcriptPath,
+			CWD:            tmpDir,
+			SyncInterval:   50 * time.Millisecond,
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() { errCh <- d.Run(ctx) }()
+		return cancel, errCh
+	}
+
+	// First run ends the session: final sync plus session_end.
+	cancel, errCh := start()
+	time.Sleep(200 * time.Millisecond)
+	inboxPath, err := GetInboxPathForProvider(provider.NameClaudeCode, externalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeInboxEvent(inboxPath, "session_end", &types.ClaudeHookInput{SessionID: externalID, Reason: "exit"}); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	state, err := LoadStateForProvider(provider.NameClaudeCode, externalID)
+	if err != nil || state == nil || state.Completed == nil {
+		t.Fatalf("state should be kept and marked completed: %v (state=%+v)", err, state)
+	}
+	// The daemon ran in this process, so its PID is alive; a kept state
+	// must not pass for a running daemon regardless.
+	if state.PID != 0 || state.IsDaemonRunning() {
+		t.Errorf("completed state counts as running (pid %d)", state.PID)
+	}
+	mock.mu.Lock()
+	events := 0
+	for _, req := range mock.eventRequests {
+		if req.EventType == "session_end" {
+			events++
+		}
+	}
+	mock.mu.Unlock()
+	if n := events; n != 1 {
+		t.Fatalf("session_end events = %d, want 1", n)
+	}
+	inits, chunks := len(mock.getInitRequests()), len(mock.getChunkRequests())
+
+	// Restart with the transcript untouched: nothing reaches the backend.
+	cancel, errCh = start()
+	time.Sleep(200 * time.Millisecond)
+	if got := len(mock.getInitRequests()); got != inits {
+		t.Errorf("restart re-initialized a completed session (%d init requests, want %d)", got, inits)
+	}
+	if got := len(mock.getChunkRequests()); got != chunks {
+		t.Errorf("restart re-uploaded a completed session (%d chunk requests, want %d)", got, chunks)
+	}
+
+	// New tail lines resume syncing, from where the session left off.
+	f, _ := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"user","message":"three"}` + "\n")
+	f.Close()
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	newChunks := mock.getChunkRequests()[chunks:]
+	if len(newChunks) != 1 || newChunks[0].FirstLine != 3 || len(newChunks[0].Lines) != 1 {
+		t.Fatalf("after resuming, chunks = %+v, want one chunk of line 3", newChunks)
+	}
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if mock.contiguityErrors != 0 {
+		t.Errorf("contiguity errors = %d, want 0", mock.contiguityErrors)
+	}
+	starts := 0
+	for _, req := range mock.eventRequests {
+		if req.EventType == "session_start" {
+			starts++
+		}
+	}
+	if starts != 1 {
+		t.Errorf("session_start events = %d, want 1 across the restart", starts)
+	}
+}
+
+// writeScheduleConfig rewrites the test config with a sync_schedule whose
+// quiet window is the two hours around now when quiet is true, and no
+// schedule otherwise.
+func writeScheduleConfig(t *testing.T, serverURL string, quiet bool) {
+	t.Helper()
+	schedule := ""
+	if quiet {
+		now := time.Now()
+		schedule = fmt.Sprintf(`,"sync_schedule":{"quiet_hours":[{"start":%q,"end":%q}]}`,
+			now.Add(-time.Hour).Format("15:04"), now.Add(time.Hour).Format("15:04"))
+	}
+	configJSON := fmt.Sprintf(`{"backend_url":"%s","api_key":"test-api-key-12345678"%s}`, serverURL, schedule)
+	if err := os.WriteFile(os.Getenv("CONFAB_CONFIG_PATH"), []byte(configJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDaemonSyncSchedule_BuffersThenFlushes verifies that inside a quiet
+// window the daemon neither initializes nor uploads, and that once the
+// schedule allows syncing everything written meanwhile goes up in order.
+func TestDaemonSyncSchedule_BuffersThenFlushes(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	writeScheduleConfig(t, server.URL, true)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","line":1}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "schedule-buffer-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   50 * time.Millisecond,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	time.Sleep(150 * time.Millisecond)
+	f, _ := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"user","line":2}` + "\n")
+	f.Close()
+	time.Sleep(150 * time.Millisecond)
+
+	if n := len(mock.getInitRequests()) + len(mock.getChunkRequests()); n != 0 {
+		t.Fatalf("backend saw %d requests during quiet hours, want 0", n)
+	}
+
+	// Open the window: the next cycle flushes both buffered lines.
+	writeScheduleConfig(t, server.URL, false)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(mock.getChunkRequests()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-errCh
+
+	chunks := mock.getChunkRequests()
+	if len(chunks) == 0 {
+		t.Fatal("expected buffered lines to be flushed once the schedule allowed syncing")
+	}
+	if chunks[0].FirstLine != 1 || len(chunks[0].Lines) != 2 {
+		t.Errorf("first chunk = first_line %d with %d lines, want line 1 with both buffered lines", chunks[0].FirstLine, len(chunks[0].Lines))
+	}
+}
+
+// TestDaemonSyncSchedule_FlushesOnShutdown verifies that lines held back by
+// a quiet window are still uploaded when the daemon stops, even though the
+// engine never initialized during the window.
+func TestDaemonSyncSchedule_FlushesOnShutdown(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	writeScheduleConfig(t, server.URL, true)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","line":1}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "schedule-shutdown-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   50 * time.Millisecond,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	time.Sleep(150 * time.Millisecond)
+	if len(mock.getChunkRequests()) != 0 {
+		t.Fatal("expected no uploads during quiet hours")
+	}
+	cancel()
+	<-errCh
+
+	if len(mock.getChunkRequests()) != 1 {
+		t.Errorf("expected the held line to be flushed at shutdown, got %d chunks", len(mock.getChunkRequests()))
+	}
+}
+
+// TestDaemonPersistsSyncProgress verifies the daemon writes per-file line
+// counts, bytes uploaded, and last sync time into its state file after a
+// sync cycle, which is what `confab status` reads.
+func TestDaemonPersistsSyncProgress(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	line1 := `{"type":"system","message":"start"}`
+	line2 := `{"type":"user","message":"hello"}`
+	os.WriteFile(transcriptPath, []byte(line1+"\n"+line2+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "progress-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   50 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+
+	loaded, err := LoadStateForProvider(provider.NameClaudeCode, "progress-test")
+	if err != nil || loaded == nil {
+		t.Fatalf("load state: %v (state=%v)", err, loaded)
+	}
+	cancel()
+	<-errCh
+
+	p := loaded.SyncProgress
+	if p == nil {
+		t.Fatal("expected sync_progress in state file")
+	}
+	if got := p.FileLines["transcript.jsonl"]; got != 2 {
+		t.Errorf("FileLines[transcript.jsonl] = %d, want 2", got)
+	}
+	if want := int64(len(line1) + len(line2) + 2); p.BytesUploaded != want {
+		t.Errorf("BytesUploaded = %d, want %d", p.BytesUploaded, want)
+	}
+	if p.LastSyncAt.IsZero() {
+		t.Error("expected LastSyncAt to be set after a clean cycle")
+	}
+
+	off, ok := loaded.FileOffsets["transcript.jsonl"]
+	if !ok {
+		t.Fatal("expected file_offsets for transcript.jsonl in state file")
+	}
+	if want := int64(len(line1) + len(line2) + 2); off.LastSyncedLine != 2 || off.ByteOffset != want {
+		t.Errorf("FileOffsets[transcript.jsonl] = %+v, want line 2 at byte %d", off, want)
+	}
+}
+
+// TestDaemonPersistsSyncErrors verifies failing init cycles are recorded
+// in the state file (consecutive errors, last error) for
+// `confab sync status --follow`, even though no engine exists yet.
+func TestDaemonPersistsSyncErrors(t *testing.T) {
+	mock := newMockBackend(t)
+	mock.initError = true
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"user","message":"hello"}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "errors-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   50 * time.Millisecond,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() { errCh <- d.Run(ctx) }()
+	time.Sleep(300 * time.Millisecond)
+
+	loaded, err := LoadStateForProvider(provider.NameClaudeCode, "errors-test")
+	cancel()
+	<-errCh
+	if err != nil || loaded == nil {
+		t.Fatalf("load state: %v (state=%v)", err, loaded)
+	}
+	p := loaded.SyncProgress
+	if p == nil || p.ConsecutiveErrors < 2 || p.LastError == "" {
+		t.Fatalf("sync_progress = %+v, want repeated init errors recorded", p)
+	}
+	if !p.LastSyncAt.IsZero() {
+		t.Errorf("LastSyncAt = %v, want zero without a clean cycle", p.LastSyncAt)
+	}
+}
+
 // TestDaemonBackendHasMoreLines tests resuming when backend has more lines than expected
 func TestDaemonBackendHasMoreLines(t *testing.T) {
 	mock := newMockBackend(t)
@@ -798,6 +1618,53 @@ func TestDaemonShutdownFinalSync(t *testing.T) {
 	}
 }
 
+// TestDaemonShutdownFinalSync_UnterminatedLastLine verifies that a last
+// line Claude never newline-terminated is held back while the session runs
+// (it may still be mid-write) but uploaded by the final sync on shutdown.
+func TestDaemonShutdownFinalSync_UnterminatedLastLine(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	last := `{"type":"assistant","line":2}`
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","line":1}`+"\n"+last), 0644)
+
+	d := New(Config{
+		ExternalID:     "unterminated-tail-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   10 * time.Second, // Very long - won't trigger during test
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	// Wait for initial sync (happens immediately on start)
+	time.Sleep(100 * time.Millisecond)
+	var lines []string
+	for _, req := range mock.getChunkRequests() {
+		lines = append(lines, req.Lines...)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("initial sync uploaded %q, want only the terminated line 1", lines)
+	}
+
+	cancel()
+	<-errCh
+
+	lines = nil
+	for _, req := range mock.getChunkRequests() {
+		lines = append(lines, req.Lines...)
+	}
+	if len(lines) != 2 || lines[1] != last {
+		t.Errorf("uploaded lines after shutdown = %q, want the unterminated last line as line 2", lines)
+	}
+}
+
 // TestDaemonMultipleAgentFiles tests discovery and sync of multiple agent files
 func TestDaemonMultipleAgentFiles(t *testing.T) {
 	mock := newMockBackend(t)
@@ -1194,6 +2061,152 @@ func TestDaemonHTTPErrors(t *testing.T) {
 		elapsed.Seconds(), finalCount)
 }
 
+// TestDaemonHonorsRetryAfter checks that a 429 with Retry-After holds the
+// daemon off for that long, even though its sync interval is much shorter.
+func TestDaemonHonorsRetryAfter(t *testing.T) {
+	defer confabhttp.SetMaxRetriesForTest(0)()
+
+	var mu stdsync.Mutex
+	var requestTimes []time.Time
+	mock := newMockBackend(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requestTimes = append(requestTimes, time.Now())
+		first := len(requestTimes) == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		mock.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","message":"rate limit test"}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "retry-after-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   100 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := d.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requestTimes) < 2 {
+		t.Fatalf("expected a request after the Retry-After window, got %d requests", len(requestTimes))
+	}
+	if gap := requestTimes[1].Sub(requestTimes[0]); gap < 2*time.Second {
+		t.Errorf("second request came %v after the 429, want >= 2s (Retry-After)", gap)
+	}
+	if len(mock.getChunkRequests()) == 0 {
+		t.Error("expected the daemon to sync once the window passed")
+	}
+}
+
+// TestDaemonBackoff checks that the wait between cycles doubles while the
+// backend keeps failing, up to MaxBackoff, and is back to SyncInterval
+// after the first clean cycle; and that Stop still runs the final sync at
+// once mid-backoff. Each daemon fails fewer times than it takes to open
+// the client's circuit breaker, which would refuse the recovering request.
+func TestDaemonBackoff(t *testing.T) {
+	defer confabhttp.SetMaxRetriesForTest(0)()
+
+	var failing atomic.Bool
+	failing.Store(true)
+	mock := newMockBackend(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only uploads fail, so the engine initializes and Stop has a
+		// final sync to run.
+		if failing.Load() && r.URL.Path == "/api/v1/sync/chunk" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		mock.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","message":"backoff test"}`+"\n"), 0644)
+
+	t.Run("grows and resets", func(t *testing.T) {
+		interval := 100 * time.Millisecond
+		d := New(Config{
+			ExternalID:     "backoff-test",
+			TranscriptPath: transcriptPath,
+			CWD:            tmpDir,
+			SyncInterval:   interval,
+			MaxBackoff:     500 * time.Millisecond,
+		})
+		if got := d.nextSyncDelay(); got != interval {
+			t.Errorf("delay before any failure = %v, want %v", got, interval)
+		}
+		want := []time.Duration{interval, 2 * interval, 4 * interval, 500 * time.Millisecond}
+		for i, w := range want {
+			d.syncCycle()
+			if got := d.nextSyncDelay(); got != w {
+				t.Errorf("delay after %d failures = %v, want %v", i+1, got, w)
+			}
+		}
+
+		failing.Store(false)
+		d.syncCycle()
+		if d.consecutiveErrors != 0 {
+			t.Fatalf("consecutiveErrors = %d after a clean cycle, want 0 (last error %q)", d.consecutiveErrors, d.lastSyncError)
+		}
+		if got := d.nextSyncDelay(); got != interval {
+			t.Errorf("delay after recovery = %v, want %v", got, interval)
+		}
+	})
+
+	t.Run("stop skips the backoff", func(t *testing.T) {
+		failing.Store(true)
+		// New content, since the backend already has the first line.
+		f, err := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(`{"type":"system","message":"after backoff"}` + "\n")
+		f.Close()
+		d := New(Config{
+			ExternalID:     "backoff-stop-test",
+			TranscriptPath: transcriptPath,
+			CWD:            tmpDir,
+			SyncInterval:   time.Second,
+			MaxBackoff:     time.Minute,
+		})
+		errCh := make(chan error, 1)
+		go func() { errCh <- d.Run(context.Background()) }()
+
+		// Two failures (at 0s and 1s), so the next cycle is 2s after the
+		// second.
+		time.Sleep(1200 * time.Millisecond)
+		failing.Store(false)
+		before := len(mock.getChunkRequests())
+		start := time.Now()
+		d.Stop()
+		select {
+		case <-errCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run didn't return after Stop while backing off")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("shutdown took %v, want the final sync without waiting out the backoff", elapsed)
+		}
+		if len(mock.getChunkRequests()) == before {
+			t.Error("expected the final sync to upload the transcript")
+		}
+	})
+}
+
 // TestDaemonLargeFile tests that daemon can handle large transcript files (~100MB).
 // This tests memory efficiency and streaming behavior.
 func TestDaemonLargeFile(t *testing.T) {
@@ -1515,24 +2528,74 @@ func TestDaemonLineTooLarge(t *testing.T) {
 
 	// Wait for daemon to attempt syncing
 	time.Sleep(1500 * time.Millisecond)
-
+
+	cancel()
+	<-errCh
+
+	// Verify results
+	chunks := atomic.LoadInt32(&chunkCount)
+	received := atomic.LoadInt32(&linesReceived)
+
+	t.Logf("Line too large test: received %d lines in %d chunks", received, chunks)
+
+	// Should have synced exactly 1 line (line 1), then failed on line 2
+	if received != 1 {
+		t.Errorf("Expected 1 line synced (before the too-large line), got %d", received)
+	}
+
+	// Should have made exactly 1 chunk request (for line 1)
+	if chunks != 1 {
+		t.Errorf("Expected 1 chunk request, got %d", chunks)
+	}
+}
+
+// TestDaemonLineTooLarge_SkipOversizeLines covers the same file with
+// skip_oversize_lines set: line 2 is replaced by a placeholder and line 3
+// still syncs.
+func TestDaemonLineTooLarge_SkipOversizeLines(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	configJSON := fmt.Sprintf(`{"backend_url":"%s","api_key":"test-api-key-12345678","skip_oversize_lines":true}`, server.URL)
+	os.WriteFile(os.Getenv("CONFAB_CONFIG_PATH"), []byte(configJSON), 0600)
+
+	hugePadding := strings.Repeat("x", 15*1024*1024)
+	content := `{"type":"msg","line":1}` + "\n" +
+		fmt.Sprintf(`{"type":"msg","line":2,"padding":"%s"}`, hugePadding) + "\n" +
+		`{"type":"msg","line":3}` + "\n"
+	os.WriteFile(transcriptPath, []byte(content), 0644)
+
+	d := New(Config{
+		ExternalID:     "line-too-large-skip-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   100 * time.Millisecond,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() { errCh <- d.Run(ctx) }()
+	time.Sleep(1500 * time.Millisecond)
 	cancel()
 	<-errCh
 
-	// Verify results
-	chunks := atomic.LoadInt32(&chunkCount)
-	received := atomic.LoadInt32(&linesReceived)
-
-	t.Logf("Line too large test: received %d lines in %d chunks", received, chunks)
-
-	// Should have synced exactly 1 line (line 1), then failed on line 2
-	if received != 1 {
-		t.Errorf("Expected 1 line synced (before the too-large line), got %d", received)
+	var lines []string
+	for _, req := range mock.getChunkRequests() {
+		if req.FileName != "transcript.jsonl" || req.FirstLine != len(lines)+1 {
+			t.Fatalf("unexpected chunk %s starting at line %d after %d lines", req.FileName, req.FirstLine, len(lines))
+		}
+		lines = append(lines, req.Lines...)
 	}
-
-	// Should have made exactly 1 chunk request (for line 1)
-	if chunks != 1 {
-		t.Errorf("Expected 1 chunk request, got %d", chunks)
+	if len(lines) != 3 {
+		t.Fatalf("synced %d lines, want 3", len(lines))
+	}
+	if lines[0] != `{"type":"msg","line":1}` || lines[2] != `{"type":"msg","line":3}` {
+		t.Errorf("lines 1 and 3 = %q, %q; want them unchanged", lines[0], lines[2])
+	}
+	if !strings.Contains(lines[1], `"type":"confab_oversize_line"`) || !strings.Contains(lines[1], `"line":2`) {
+		t.Errorf("line 2 = %.200q, want the oversize placeholder", lines[1])
 	}
 }
 
@@ -1663,6 +2726,120 @@ func TestDaemonBadRequestRecovery(t *testing.T) {
 	t.Logf("400 Bad Request recovery test: %d total requests, %d successful chunks", totalRequests, successfulChunks)
 }
 
+// TestDaemonBadRequestQuarantine tests that a line the backend always
+// rejects with 400 doesn't stall the file: after the configured number of
+// identical rejections the chunk is split down to that line, which is
+// uploaded as a placeholder, and the lines around it sync.
+func TestDaemonBadRequestQuarantine(t *testing.T) {
+	var mu stdsync.Mutex
+	synced := map[int]string{} // line number -> accepted content
+	lastLine := 0
+	rejected := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := readRequestBody(r)
+
+		switch r.URL.Path {
+		case "/api/v1/sync/init":
+			mu.Lock()
+			files := map[string]sync.FileState{}
+			if lastLine > 0 {
+				files["transcript.jsonl"] = sync.FileState{LastSyncedLine: lastLine}
+			}
+			mu.Unlock()
+			json.NewEncoder(w).Encode(sync.InitResponse{SessionID: "quarantine-session", Files: files})
+
+		case "/api/v1/sync/chunk":
+			var req sync.ChunkRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, l := range req.Lines {
+				if strings.Contains(l, "poison") {
+					rejected++
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error": "invalid content"}`))
+					return
+				}
+			}
+			if req.FirstLine != lastLine+1 {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "non-contiguous"}`))
+				return
+			}
+			for i, l := range req.Lines {
+				synced[req.FirstLine+i] = l
+			}
+			lastLine = req.FirstLine + len(req.Lines) - 1
+			json.NewEncoder(w).Encode(sync.ChunkResponse{LastSyncedLine: lastLine})
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	var content strings.Builder
+	for i := 1; i <= 6; i++ {
+		if i == 4 {
+			content.WriteString(`{"type":"user","poison":true}` + "\n")
+			continue
+		}
+		fmt.Fprintf(&content, `{"type":"user","line":%d}`+"\n", i)
+	}
+	os.WriteFile(transcriptPath, []byte(content.String()), 0644)
+
+	d := New(Config{
+		ExternalID:     "quarantine-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   20 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		mu.Lock()
+		done := lastLine == 6
+		mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			mu.Lock()
+			t.Fatalf("backend stuck at line %d after %d rejections", lastLine, rejected)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-errCh
+
+	mu.Lock()
+	defer mu.Unlock()
+	if rejected < sync.DefaultMaxConsecutive400 {
+		t.Errorf("backend rejected %d chunks, want at least %d before the split", rejected, sync.DefaultMaxConsecutive400)
+	}
+	for i := 1; i <= 6; i++ {
+		want := fmt.Sprintf(`"line":%d`, i)
+		if i == 4 {
+			want = "confab_quarantined"
+		}
+		if !strings.Contains(synced[i], want) {
+			t.Errorf("line %d = %q, want it to contain %q", i, synced[i], want)
+		}
+	}
+}
+
 // TestDaemonSIGTERMFinalSync tests that daemon performs final sync when receiving SIGTERM.
 // This is critical: if final sync breaks, users lose the last ~30s of transcript data.
 func TestDaemonSIGTERMFinalSync(t *testing.T) {
@@ -2169,6 +3346,97 @@ func TestDaemonSessionDeletedRecovery(t *testing.T) {
 	t.Logf("404 recovery test: daemon survived %d chunk requests (first 2 were 404s)", chunks)
 }
 
+// TestDaemonSessionDeleted_CustomThreshold verifies MaxConsecutive404: with
+// a threshold of 5 the daemon rides out 4 consecutive 404s and then
+// recovers, where the default of 3 would have stopped it.
+func TestDaemonSessionDeleted_CustomThreshold(t *testing.T) {
+	var chunkCount int32
+	const failCount = 4
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		body, _ := readRequestBody(r)
+
+		switch r.URL.Path {
+		case "/api/v1/sync/init":
+			json.NewEncoder(w).Encode(sync.InitResponse{
+				SessionID: "threshold-test-session",
+				Files:     make(map[string]sync.FileState),
+			})
+
+		case "/api/v1/sync/chunk":
+			if atomic.AddInt32(&chunkCount, 1) <= failCount {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error": "session not found"}`))
+				return
+			}
+
+			var req sync.ChunkRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(sync.ChunkResponse{
+				LastSyncedLine: req.FirstLine + len(req.Lines) - 1,
+			})
+
+		case "/api/v1/sync/event":
+			json.NewEncoder(w).Encode(sync.EventResponse{Success: true})
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","line":1}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:        "404-threshold-test",
+		TranscriptPath:    transcriptPath,
+		CWD:               tmpDir,
+		SyncInterval:      50 * time.Millisecond,
+		MaxConsecutive404: 5,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&chunkCount) <= failCount {
+		select {
+		case err := <-errCh:
+			t.Fatalf("daemon exited after %d chunk requests: %v", atomic.LoadInt32(&chunkCount), err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d chunk requests before deadline", atomic.LoadInt32(&chunkCount))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Give the recovering cycle a moment, then confirm the daemon is alive.
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case err := <-errCh:
+		t.Fatalf("daemon should have survived %d consecutive 404s: %v", failCount, err)
+	default:
+	}
+
+	cancel()
+	<-errCh
+	if d.consecutiveNotFound != 0 {
+		t.Errorf("consecutiveNotFound = %d after recovery, want 0", d.consecutiveNotFound)
+	}
+}
+
 // =================================================================================================
 // Codex daemon integration tests (CF-387)
 //
@@ -2527,8 +3795,8 @@ func TestDaemonIntegration_Codex_ShutdownPath_FinalSyncIncludesChildren(t *testi
 }
 
 // TestDaemon_ExitsAfter3Consecutive404s guards the
-// maxConsecutiveNotFound shutdown path at daemon.go (constant defined
-// at daemon.go:37, branch at daemon.go:216-220). If the user deletes
+// DefaultMaxConsecutive404 shutdown path (the 404 branch in
+// syncCycle). If the user deletes
 // their backend session, the daemon's chunk uploads will 404 forever;
 // without this exit the daemon would consume resources indefinitely.
 //
@@ -2777,3 +4045,242 @@ func TestDaemonShutsDownWithin6sOfParentDeath(t *testing.T) {
 		t.Fatalf("daemon did not exit within %v after parent died; monitorParent goroutine not driving shutdown", deadline)
 	}
 }
+
+// TestDaemonRefusesInvalidRedactionPattern: a redaction pattern that
+// doesn't compile stops the daemon at startup, before any backend request.
+func TestDaemonRefusesInvalidRedactionPattern(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	configJSON := fmt.Sprintf(`{"backend_url":"%s","api_key":"test-api-key-12345678","redaction":{"enabled":true,"patterns":[{"name":"Broken","pattern":"(unclosed","type":"x"}]}}`, server.URL)
+	os.WriteFile(os.Getenv("CONFAB_CONFIG_PATH"), []byte(configJSON), 0600)
+	os.WriteFile(transcriptPath, []byte(`{"type":"user","message":"hello"}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:     "bad-redaction",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   50 * time.Millisecond,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := d.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), `"Broken"`) {
+		t.Fatalf("Run error = %v, want one naming the Broken pattern", err)
+	}
+	if n := len(mock.getInitRequests()); n != 0 {
+		t.Errorf("daemon sent %d init request(s) despite the invalid pattern", n)
+	}
+}
+
+// TestDaemonResumesMultipartUploadAfterRestart: a chunk sent in parts
+// whose third part keeps failing leaves its upload ID in the state file,
+// and the next daemon asks the backend which parts it has and sends only
+// the rest.
+func TestDaemonResumesMultipartUploadAfterRestart(t *testing.T) {
+	const externalID = "multipart-resume-test"
+	mock := newMockBackend(t)
+	mock.multipart = true
+	mock.failPart = 2
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	configJSON := fmt.Sprintf(`{"backend_url":"%s","api_key":"test-api-key-12345678","compression":"none","multipart_upload":true,"upload_part_size":1024}`, server.URL)
+	os.WriteFile(os.Getenv("CONFAB_CONFIG_PATH"), []byte(configJSON), 0600)
+	var content strings.Builder
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&content, `{"type":"user","n":%d,"message":"%s"}`+"\n", i, strings.Repeat("x", 150))
+	}
+	os.WriteFile(transcriptPath, []byte(content.String()), 0644)
+
+	run := func() {
+		d := New(Config{
+			ExternalID:     externalID,
+			TranscriptPath: transcriptPath,
+			CWD:            tmpDir,
+			SyncInterval:   50 * time.Millisecond,
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() { errCh <- d.Run(ctx) }()
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+		if err := <-errCh; err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	}
+
+	run()
+	state, err := LoadStateForProvider(provider.NameClaudeCode, externalID)
+	if err != nil || state == nil {
+		t.Fatalf("state file should be kept after a failed final sync: %v (state=%v)", err, state)
+	}
+	if len(state.PendingUploads) != 1 {
+		t.Fatalf("PendingUploads = %v, want the interrupted upload", state.PendingUploads)
+	}
+	if n := len(mock.getChunkRequests()); n != 0 {
+		t.Fatalf("backend completed %d chunk(s) while a part was failing", n)
+	}
+
+	// The kept state has no PID, so the next daemon restores it.
+	mock.mu.Lock()
+	mock.failPart = -1
+	mock.mu.Unlock()
+	run()
+
+	chunks := mock.getChunkRequests()
+	if len(chunks) != 1 || chunks[0].FirstLine != 1 || len(chunks[0].Lines) != 30 {
+		t.Fatalf("expected one assembled 30-line chunk, got %d chunks", len(chunks))
+	}
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if mock.startRequests != 1 {
+		t.Errorf("start requests = %d, want 1 (later attempts resume)", mock.startRequests)
+	}
+	if mock.statusQueries == 0 {
+		t.Error("restarted daemon never asked the backend for the upload's status")
+	}
+	for _, i := range []int{0, 1} {
+		if mock.partUploads[i] != 1 {
+			t.Errorf("part %d uploaded %d times, want once", i, mock.partUploads[i])
+		}
+	}
+	if state, _ := LoadStateForProvider(provider.NameClaudeCode, externalID); state != nil {
+		t.Errorf("state file should be deleted after a clean shutdown, got pending_uploads=%v", state.PendingUploads)
+	}
+}
+
+// TestDaemonMultipartFallsBackToSinglePart: a backend without the
+// multipart endpoints (404 on start) gets the chunk in one request, and
+// isn't asked to start a multipart upload again.
+func TestDaemonMultipartFallsBackToSinglePart(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	configJSON := fmt.Sprintf(`{"backend_url":"%s","api_key":"test-api-key-12345678","compression":"none","multipart_upload":true,"upload_part_size":1024}`, server.URL)
+	os.WriteFile(os.Getenv("CONFAB_CONFIG_PATH"), []byte(configJSON), 0600)
+	var content strings.Builder
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&content, `{"type":"user","n":%d,"message":"%s"}`+"\n", i, strings.Repeat("x", 150))
+	}
+	os.WriteFile(transcriptPath, []byte(content.String()), 0644)
+
+	d := New(Config{
+		ExternalID:     "multipart-fallback-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   50 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- d.Run(ctx) }()
+	time.Sleep(100 * time.Millisecond)
+	f, _ := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(content.String())
+	f.Close()
+	time.Sleep(150 * time.Millisecond)
+	cancel()
+	<-errCh
+
+	chunks := mock.getChunkRequests()
+	if len(chunks) < 2 || chunks[0].FirstLine != 1 || len(chunks[0].Lines) != 30 || chunks[1].FirstLine != 31 {
+		t.Fatalf("expected single-part chunks for lines 1-30 and 31-60, got %d chunks", len(chunks))
+	}
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if mock.startRequests != 1 {
+		t.Errorf("start requests = %d, want 1 (the 404 turns multipart off)", mock.startRequests)
+	}
+}
+
+// TestDaemonZeroJitterKeepsFixedCadence verifies that with jitter 0 the
+// gap between consecutive syncs stays within a tight tolerance of
+// SyncInterval.
+func TestDaemonZeroJitterKeepsFixedCadence(t *testing.T) {
+	const interval = 200 * time.Millisecond
+	const tolerance = 75 * time.Millisecond
+
+	mock := newMockBackend(t)
+	var mu stdsync.Mutex
+	var chunkTimes []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/sync/chunk" {
+			mu.Lock()
+			chunkTimes = append(chunkTimes, time.Now())
+			mu.Unlock()
+		}
+		mock.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","line":1}`+"\n"), 0644)
+
+	d := New(Config{
+		ExternalID:         "fixed-cadence-test",
+		TranscriptPath:     transcriptPath,
+		CWD:                tmpDir,
+		SyncInterval:       interval,
+		SyncIntervalJitter: 0,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	// Keep the transcript growing so every cycle uploads a chunk.
+	stop := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		for line := 2; ; line++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			f, err := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(f, `{"type":"user","line":%d}`+"\n", line)
+			f.Close()
+		}
+	}()
+
+	time.Sleep(6*interval + interval/2)
+	close(stop)
+	<-writerDone
+	cancel()
+	<-errCh
+
+	// A cycle may upload more than one chunk; only the first upload of
+	// each cycle marks when it ran.
+	mu.Lock()
+	var cycles []time.Time
+	for _, at := range chunkTimes {
+		if len(cycles) == 0 || at.Sub(cycles[len(cycles)-1]) > interval/2 {
+			cycles = append(cycles, at)
+		}
+	}
+	mu.Unlock()
+	if len(cycles) < 4 {
+		t.Fatalf("sync cycles = %d, want at least 4", len(cycles))
+	}
+	// Skip the first cycle: it fires right after init rather than an
+	// interval later. The last one may be the shutdown sync.
+	for i := 2; i < len(cycles)-1; i++ {
+		gap := cycles[i].Sub(cycles[i-1])
+		if gap < interval-10*time.Millisecond || gap > interval+tolerance {
+			t.Errorf("gap between syncs %d and %d = %v, want %v (+%v)", i-1, i, gap, interval, tolerance)
+		}
+	}
+}
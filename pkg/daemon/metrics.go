@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+// metricsShutdownTimeout bounds how long Run waits for in-flight metrics
+// requests when it returns.
+const metricsShutdownTimeout = 2 * time.Second
+
+// Metrics is the JSON document served at /metrics.
+type Metrics struct {
+	ExternalID string `json:"external_id"`
+	// SessionID is the backend (Confab) session ID; empty until the first
+	// successful init.
+	SessionID string `json:"session_id,omitempty"`
+	// LastSyncAt is when a sync cycle last completed without errors.
+	LastSyncAt *time.Time `json:"last_sync_at,omitempty"`
+	// FileLines is lines synced per backend file name.
+	FileLines map[string]int `json:"file_lines"`
+	// ConsecutiveErrors counts cycles in a row whose init or sync failed.
+	ConsecutiveErrors int `json:"consecutive_errors"`
+	// ConsecutiveNotFound is the session-deleted (404) counter; the daemon
+	// stops when it reaches its limit.
+	ConsecutiveNotFound int `json:"consecutive_not_found"`
+}
+
+// metricsServer serves /healthz and /metrics: JSON from a snapshot the
+// daemon refreshes after every sync cycle, so handlers never touch the
+// engine concurrently with the main loop, or, for scrapers (see
+// wantsPrometheusText), the daemon's Prometheus registry.
+type metricsServer struct {
+	srv  *http.Server
+	ln   net.Listener
+	prom http.Handler
+
+	mu       sync.Mutex
+	snapshot Metrics
+}
+
+// startMetricsServer listens on addr and serves in the background.
+func startMetricsServer(addr, externalID string, prom http.Handler) (*metricsServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	m := &metricsServer{ln: ln, prom: prom, snapshot: Metrics{ExternalID: externalID, FileLines: map[string]int{}}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /metrics", m.serveMetrics)
+	m.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := m.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warnf("Metrics server stopped: %v", err)
+		}
+	}()
+	return m, nil
+}
+
+// Addr returns the address the server is listening on.
+func (m *metricsServer) Addr() string {
+	return m.ln.Addr().String()
+}
+
+func (m *metricsServer) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if wantsPrometheusText(r) {
+		m.prom.ServeHTTP(w, r)
+		return
+	}
+	m.mu.Lock()
+	snapshot := m.snapshot
+	m.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// wantsPrometheusText reports whether a /metrics request asks for the
+// Prometheus text format: ?format=prometheus, or an Accept header naming
+// text/plain or OpenMetrics, as Prometheus scrapers send. Anything else
+// (curl's */*, no Accept) keeps getting JSON.
+func wantsPrometheusText(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "prometheus" {
+		return true
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/plain") || strings.Contains(accept, "application/openmetrics-text")
+}
+
+func (m *metricsServer) update(snapshot Metrics) {
+	m.mu.Lock()
+	m.snapshot = snapshot
+	m.mu.Unlock()
+}
+
+// Close shuts the server down, waiting briefly for in-flight requests.
+func (m *metricsServer) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+	defer cancel()
+	if err := m.srv.Shutdown(ctx); err != nil {
+		logger.Warnf("Metrics server shutdown: %v", err)
+	}
+}
+
+// updateMetrics refreshes the metrics snapshot from the daemon's current
+// state. Called from the main loop only.
+func (d *Daemon) updateMetrics() {
+	if d.metrics == nil {
+		return
+	}
+	snapshot := Metrics{
+		ExternalID:          d.externalID,
+		FileLines:           map[string]int{},
+		ConsecutiveErrors:   d.consecutiveErrors,
+		ConsecutiveNotFound: d.consecutiveNotFound,
+	}
+	if d.engine != nil && d.engine.IsInitialized() {
+		snapshot.SessionID = d.engine.SessionID()
+		stats := d.engine.GetSyncStats()
+		snapshot.FileLines = stats.FileLines
+		if !stats.LastSyncAt.IsZero() {
+			snapshot.LastSyncAt = &stats.LastSyncAt
+		}
+	}
+	d.metrics.update(snapshot)
+}
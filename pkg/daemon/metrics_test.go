@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// freeAddr returns a loopback address with a currently unused port.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func TestDaemonMetricsEndpoint(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"+`{"type":"user"}`+"\n"+`{"type":"assistant"}`+"\n"), 0644)
+
+	addr := freeAddr(t)
+	d := New(Config{
+		ExternalID:     "metrics-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   time.Hour,
+		MetricsAddr:    addr,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	// The first cycle runs immediately; poll until it's reflected.
+	var m Metrics
+	deadline := time.Now().Add(3 * time.Second)
+	for m.FileLines["transcript.jsonl"] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("/metrics never reported the first sync cycle: %+v", m)
+		}
+		time.Sleep(20 * time.Millisecond)
+		resp, err := http.Get("http://" + addr + "/metrics")
+		if err != nil {
+			continue
+		}
+		json.NewDecoder(resp.Body).Decode(&m)
+		resp.Body.Close()
+	}
+
+	if got := m.FileLines["transcript.jsonl"]; got != 3 {
+		t.Errorf("file_lines[transcript.jsonl] = %d, want 3", got)
+	}
+	if m.ExternalID != "metrics-test" || m.SessionID == "" {
+		t.Errorf("external_id = %q, session_id = %q; want metrics-test and a backend session", m.ExternalID, m.SessionID)
+	}
+	if m.LastSyncAt == nil {
+		t.Error("last_sync_at not set after a clean sync cycle")
+	}
+	if m.ConsecutiveErrors != 0 || m.ConsecutiveNotFound != 0 {
+		t.Errorf("error counters = %d/%d, want 0/0", m.ConsecutiveErrors, m.ConsecutiveNotFound)
+	}
+
+	resp, err := http.Get("http://" + addr + "/healthz")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("/healthz: resp=%v err=%v", resp, err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	<-errCh
+	if _, err := http.Get("http://" + addr + "/healthz"); err == nil {
+		t.Error("metrics server still serving after Run returned")
+	}
+}
+
+// TestDaemonMetricsEndpoint_PrometheusText: a scraper asking for the text
+// format gets the daemon's Prometheus registry, with the chunk and line
+// counters and last sync time from the first cycle.
+func TestDaemonMetricsEndpoint_PrometheusText(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"+`{"type":"user"}`+"\n"), 0644)
+
+	addr := freeAddr(t)
+	d := New(Config{
+		ExternalID:     "prom-text-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   time.Hour,
+		MetricsAddr:    addr,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	scrape := func() (map[string]*dto.MetricFamily, error) {
+		req, _ := http.NewRequest("GET", "http://"+addr+"/metrics", nil)
+		req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0;q=0.75,text/plain;version=0.0.4;q=0.5,*/*;q=0.1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			return nil, fmt.Errorf("content type %q", ct)
+		}
+		parser := expfmt.NewTextParser(model.UTF8Validation)
+		return parser.TextToMetricFamilies(resp.Body)
+	}
+	var families map[string]*dto.MetricFamily
+	deadline := time.Now().Add(3 * time.Second)
+	// Chunks are counted mid-cycle and the last sync time at its end.
+	for promValue(families, "confab_last_sync_timestamp_seconds", nil) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("/metrics never reported a synced chunk: %v", families)
+		}
+		time.Sleep(20 * time.Millisecond)
+		var err error
+		if families, err = scrape(); err != nil && !strings.Contains(err.Error(), "connection refused") {
+			t.Fatalf("scrape: %v", err)
+		}
+	}
+
+	if got := promValue(families, "confab_chunks_uploaded_total", nil); got != 1 {
+		t.Errorf("confab_chunks_uploaded_total = %v, want 1", got)
+	}
+	if got := promValue(families, "confab_lines_synced_total", map[string]string{"file_type": "transcript"}); got != 2 {
+		t.Errorf("confab_lines_synced_total{file_type=transcript} = %v, want 2", got)
+	}
+	if got := promValue(families, "confab_last_sync_timestamp_seconds", nil); got < float64(time.Now().Add(-time.Minute).Unix()) {
+		t.Errorf("confab_last_sync_timestamp_seconds = %v, want about now", got)
+	}
+
+	cancel()
+	<-errCh
+}
@@ -70,58 +70,109 @@ func (r *opencodeRegistrar) RegisterCodexRollout(string, string, bool, provider.
 // AND ensures a collector goroutine is running for it (CF-538).
 //
 // Idempotent on all three layers: capability check is constant-time
-// against the engine's cache, RegisterSidechainFile returns false (and
-// preserves the sync position) when the name is already tracked, and
-// startChildCollector is a no-op if a goroutine is already running for
-// the child id.
+// against the engine's cache, startChildCollector is a no-op if a
+// goroutine is already running for the child id, and
+// RegisterSidechainFile returns false (and preserves the sync position)
+// when the name is already tracked.
 //
 // When the capability flag is off, both register and spawn no-op silently
 // — the engine has already logged the capability state once via
-// resolveCaps.
+// resolveCaps. A child the daemon's session cap turns away is neither
+// registered nor spawned; discovery offers it again next cycle.
 func (r *opencodeRegistrar) RegisterOpencodeChild(childID, localPath string) {
 	if !r.engine.OpencodeChildFilesAllowed() {
 		return
 	}
+	if !r.daemon.startChildCollector(childID, localPath) {
+		return
+	}
 	name := provider.OpencodeChildBackendName(childID)
 	r.tracker.RegisterSidechainFile(localPath, name, "agent")
-	r.daemon.startChildCollector(childID, localPath)
 }
 
-// startChildCollector spawns a per-child OpenCode collector goroutine.
-// Idempotent: a no-op if one is already running for childID. Logs Info
-// on first spawn.
+// admitChildLocked reports whether childID may be managed under the
+// daemon's session cap: true if its collector is already running or the
+// root plus running children leave room for it. A child turned away is
+// deferred, logged once, and admitted on a later call once there is room.
+// Caller holds childCollectorsMu, so admission and the collector's
+// insertion are one step and concurrent discoveries can't overshoot the
+// cap.
+func (d *Daemon) admitChildLocked(childID string) bool {
+	if _, ok := d.childCollectors[childID]; ok {
+		return true
+	}
+	if 1+len(d.childCollectors) >= d.maxSessions {
+		if !d.deferredChildren[childID] {
+			if d.deferredChildren == nil {
+				d.deferredChildren = make(map[string]bool)
+			}
+			d.deferredChildren[childID] = true
+			logger.Warnf("Session cap reached (%d sessions): deferring OpenCode child %s", d.maxSessions, childID)
+		}
+		return false
+	}
+	if d.deferredChildren[childID] {
+		delete(d.deferredChildren, childID)
+		logger.Infof("Admitting deferred OpenCode child %s", childID)
+	}
+	return true
+}
+
+// startChildCollector admits childID under the session cap (see
+// admitChildLocked) and spawns its OpenCode collector goroutine. Returns
+// false if the cap turned it away. Idempotent: true and a no-op if one is
+// already running for childID. Logs Info on first spawn.
 //
 // Each child collector shares the daemon's *OpenCodeDBReader (one *sql.DB
 // per ReadSession call, no shared state), and uses the same poll cadence
 // as the root. Lifetime is daemon-scoped: shutdown() cancels every
-// collector and waits for done before the final backend sync.
-func (d *Daemon) startChildCollector(childID, localPath string) {
+// collector and waits for done before the final backend sync. A collector
+// that exits removes its own entry, freeing its slot under the cap.
+func (d *Daemon) startChildCollector(childID, localPath string) bool {
 	d.childCollectorsMu.Lock()
 	defer d.childCollectorsMu.Unlock()
+	if !d.admitChildLocked(childID) {
+		return false
+	}
 	if d.childCollectors == nil {
 		d.childCollectors = make(map[string]*opencodeChildCollector)
 	}
 	if _, ok := d.childCollectors[childID]; ok {
-		return
+		return true
 	}
 	if d.dbReader == nil {
 		// Pre-condition violation: child-collector spawn requires the
 		// shared reader set up at daemon Run start. Log and skip rather
 		// than panic.
-		logger.Warn("startChildCollector(%s): dbReader not initialized; skipping", childID)
-		return
+		logger.Warnf("startChildCollector(%s): dbReader not initialized; skipping", childID)
+		return true
 	}
 	ctx, cancel := context.WithCancel(d.childCollectorBase)
 	done := make(chan struct{})
-	d.childCollectors[childID] = &opencodeChildCollector{cancel: cancel, done: done}
+	cc := &opencodeChildCollector{cancel: cancel, done: done}
+	d.childCollectors[childID] = cc
 	collector := provider.NewOpenCodeCollector(d.dbReader, childID, localPath, d.syncInterval)
-	logger.Info("Discovered OpenCode child: session=%s path=%s", childID, localPath)
+	log := logger.With("component", "daemon", "child_session_id", childID, "file", localPath)
+	log.Info("Discovered OpenCode child")
 	go func() {
 		defer close(done)
+		defer d.removeChildCollector(childID, cc)
 		if err := collector.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
-			logger.Warn("OpenCode child collector exited: session=%s err=%v", childID, err)
+			log.Warn("OpenCode child collector exited", "error", err)
 		}
 	}()
+	return true
+}
+
+// removeChildCollector drops childID's entry if it is still cc, so a
+// collector that exited no longer counts against the session cap and is
+// started again if discovery offers the child.
+func (d *Daemon) removeChildCollector(childID string, cc *opencodeChildCollector) {
+	d.childCollectorsMu.Lock()
+	defer d.childCollectorsMu.Unlock()
+	if d.childCollectors[childID] == cc {
+		delete(d.childCollectors, childID)
+	}
 }
 
 // waitForCollectors waits for the root collector + every child collector
@@ -139,7 +190,7 @@ func waitForCollectors(rootDone chan struct{}, childDones []chan struct{}, timeo
 		select {
 		case <-done:
 		case <-deadline:
-			logger.Warn("OpenCode collector did not stop within %v; proceeding with final sync", timeout)
+			logger.Warnf("OpenCode collector did not stop within %v; proceeding with final sync", timeout)
 			return
 		}
 	}
@@ -158,4 +209,3 @@ func (d *Daemon) childCollectorDones() []chan struct{} {
 	}
 	return dones
 }
-
@@ -2,10 +2,13 @@ package daemon
 
 import (
 	"context"
+	"fmt"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	stdsync "sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -313,6 +316,129 @@ func TestDaemonOpenCodeChildShutdownCancelsAllCollectors(t *testing.T) {
 	}
 }
 
+// TestDaemonOpenCodeSessionCapDefersChildren asserts that with
+// MaxSessions=2 (the root plus one child) a second child is deferred —
+// never materialized or uploaded — while the root and the admitted child
+// keep syncing messages that arrive later.
+func TestDaemonOpenCodeSessionCapDefersChildren(t *testing.T) {
+	const rootID = "ses_root_cap"
+	children := []string{"ses_child_A_cap", "ses_child_B_cap"}
+	mock := newMockBackend(t)
+	mock.caps = &sync.Capabilities{OpencodeSubagentFiles: true}
+	backend := httptest.NewServer(mock)
+	defer backend.Close()
+
+	db := opencodetest.NewDB(t)
+	db.AddSession(rootID, "").AddSession(children[0], rootID).AddSession(children[1], rootID)
+	addUserMessage := func(session, mid string) {
+		db.AddMessage(session, mid, opencodetest.UserTextMessage("u "+mid))
+		db.AddPart(mid, "prt_"+mid, opencodetest.TextPart("u "+mid))
+	}
+	addUserMessage(rootID, "msg_001_root")
+	for _, ch := range children {
+		addUserMessage(ch, "msg_001_"+ch)
+	}
+	t.Setenv(provider.OpenCodeDBEnv, db.Path())
+
+	tmpDir, _ := setupTestEnv(t, backend.URL)
+	dm := New(Config{
+		Provider:     provider.NameOpencode,
+		ExternalID:   rootID,
+		CWD:          t.TempDir(),
+		SyncInterval: 50 * time.Millisecond,
+		MaxSessions:  2,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- dm.Run(ctx) }()
+	time.Sleep(300 * time.Millisecond)
+
+	// Whichever child was discovered first holds the one child slot.
+	childPath := func(ch string) string {
+		return filepath.Join(tmpDir, ".confab", "opencode", rootID, "children", ch, "messages.jsonl")
+	}
+	admitted, deferred := children[0], children[1]
+	if _, err := os.Stat(childPath(admitted)); err != nil {
+		admitted, deferred = deferred, admitted
+	}
+
+	// Later messages for the managed sessions must still be synced.
+	addUserMessage(rootID, "msg_002_root")
+	addUserMessage(admitted, "msg_002_"+admitted)
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	select {
+	case <-errCh:
+	case <-time.After(3 * time.Second):
+		t.Fatal("daemon did not exit")
+	}
+
+	if _, err := os.Stat(childPath(deferred)); err == nil {
+		t.Errorf("deferred child %s was materialized despite the session cap", deferred)
+	}
+	lines := make(map[string]int)
+	for _, c := range mock.getChunkRequests() {
+		lines[c.FileName] += len(c.Lines)
+	}
+	if got := lines["opencode/"+deferred+"/messages.jsonl"]; got != 0 {
+		t.Errorf("deferred child uploaded %d lines, want 0", got)
+	}
+	if got := lines["opencode/"+admitted+"/messages.jsonl"]; got != 2 {
+		t.Errorf("admitted child uploaded %d lines, want 2 (uploads: %v)", got, lines)
+	}
+	rootLines := 0
+	for name, n := range lines {
+		if !strings.HasPrefix(name, "opencode/") {
+			rootLines += n
+		}
+	}
+	if rootLines != 2 {
+		t.Errorf("root uploaded %d lines, want 2 (uploads: %v)", rootLines, lines)
+	}
+}
+
+// TestStartChildCollectorCapIsAtomic asserts that concurrent discoveries
+// can't admit more children than the session cap allows, and that a
+// collector that exits frees its slot.
+func TestStartChildCollectorCapIsAtomic(t *testing.T) {
+	db := opencodetest.NewDB(t)
+	base, cancel := context.WithCancel(context.Background())
+	d := &Daemon{
+		maxSessions:        2,
+		dbReader:           provider.NewOpenCodeDBReader(db.Path()),
+		childCollectorBase: base,
+		syncInterval:       time.Hour,
+	}
+	dir := t.TempDir()
+
+	var admitted atomic.Int32
+	var wg stdsync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			childID := fmt.Sprintf("ses_child_%d", i)
+			if d.startChildCollector(childID, filepath.Join(dir, childID, "messages.jsonl")) {
+				admitted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := admitted.Load(); got != 1 {
+		t.Errorf("admitted %d children under a cap of 2 sessions, want 1", got)
+	}
+
+	dones := d.childCollectorDones()
+	cancel()
+	waitForCollectors(nil, dones, 3*time.Second)
+	d.childCollectorsMu.Lock()
+	remaining := len(d.childCollectors)
+	d.childCollectorsMu.Unlock()
+	if remaining != 0 {
+		t.Errorf("%d collectors still registered after exiting, want 0", remaining)
+	}
+}
+
 // TestDaemonOpenCodeChildSidechainResume asserts that a daemon restart
 // against an OpenCode session with an existing per-child materialized
 // file does not re-upload already-synced lines, and does upload any
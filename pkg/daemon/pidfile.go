@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+// writePIDFile writes the current process ID to path for process
+// supervisors, creating parent directories as needed.
+func writePIDFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create pidfile directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write pidfile: %w", err)
+	}
+	return nil
+}
+
+// removePIDFile deletes path if it still holds this process's PID, so a
+// daemon that outlived its pidfile (e.g. a replacement was started with
+// the same path) doesn't delete its successor's.
+func removePIDFile(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warnf("Failed to read pidfile %s: %v", path, err)
+		}
+		return
+	}
+	if strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		logger.Infof("Pidfile %s now belongs to another process, leaving it", path)
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		logger.Warnf("Failed to remove pidfile %s: %v", path, err)
+	}
+}
+
+// logStartupInfo logs where supervisors and health checks can find this
+// daemon: its PID, state file (which doubles as the duplicate-daemon lock),
+// inbox and pidfile.
+func (d *Daemon) logStartupInfo() {
+	statePath, _ := GetStatePathForProvider(d.providerName, d.externalID)
+	inboxPath, _ := GetInboxPathForProvider(d.providerName, d.externalID)
+	logger.Info("Daemon process info",
+		"pid", os.Getpid(), "state", statePath, "inbox", inboxPath, "pidfile", d.pidFile)
+}
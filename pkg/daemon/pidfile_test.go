@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDaemonPIDFile(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"), 0644)
+
+	pidFile := filepath.Join(tmpDir, "run", "confab.pid")
+	d := New(Config{
+		ExternalID:     "pidfile-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   time.Hour,
+		PIDFile:        pidFile,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	var data []byte
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		var err error
+		if data, err = os.ReadFile(pidFile); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pidfile never written: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, want := strings.TrimSpace(string(data)), strconv.Itoa(os.Getpid()); got != want {
+		t.Errorf("pidfile contains %q, want %q", got, want)
+	}
+
+	d.Stop()
+	select {
+	case <-errCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+	if _, err := os.Stat(pidFile); !os.IsNotExist(err) {
+		t.Errorf("pidfile still present after shutdown (stat err = %v)", err)
+	}
+}
+
+func TestRemovePIDFile_LeavesOtherProcessFile(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "confab.pid")
+	os.WriteFile(pidFile, []byte("1\n"), 0644)
+
+	removePIDFile(pidFile)
+
+	if _, err := os.Stat(pidFile); err != nil {
+		t.Errorf("pidfile owned by another PID was removed: %v", err)
+	}
+}
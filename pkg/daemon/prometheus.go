@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	confabhttp "github.com/ConfabulousDev/confab/pkg/http"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	pkgsync "github.com/ConfabulousDev/confab/pkg/sync"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// promMetrics holds the daemon's Prometheus collectors and the server that
+// exposes them at /metrics when Config.MetricsPort is set (the
+// Config.MetricsAddr server serves them too, see handler). Each daemon has
+// its own registry, so nothing is registered globally. The collectors are
+// safe for concurrent use, so the engine's callbacks update them directly.
+type promMetrics struct {
+	registry *prometheus.Registry
+
+	linesSynced     *prometheus.CounterVec
+	bytesUploaded   prometheus.Counter
+	chunksUploaded  prometheus.Counter
+	syncErrors      *prometheus.CounterVec
+	lastSync        prometheus.Gauge
+	requestDuration *prometheus.HistogramVec
+
+	srv *http.Server
+	ln  net.Listener
+}
+
+func newPromMetrics() *promMetrics {
+	p := &promMetrics{
+		registry: prometheus.NewRegistry(),
+		linesSynced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "confab_lines_synced_total",
+			Help: "Lines accepted by the backend, by file type.",
+		}, []string{"file_type"}),
+		bytesUploaded: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "confab_bytes_uploaded_total",
+			Help: "Uncompressed bytes of lines accepted by the backend.",
+		}),
+		chunksUploaded: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "confab_chunks_uploaded_total",
+			Help: "Chunks accepted by the backend.",
+		}),
+		syncErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "confab_sync_errors_total",
+			Help: "Sync cycles whose init or sync failed, by error type.",
+		}, []string{"error_type"}),
+		lastSync: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "confab_last_sync_timestamp_seconds",
+			Help: "Unix time of the last sync cycle that completed without errors.",
+		}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "confab_backend_request_duration_seconds",
+			Help:    "Backend HTTP request duration, by endpoint and status code (0 when no response arrived).",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"endpoint", "status_code"}),
+	}
+	p.registry.MustRegister(p.linesSynced, p.bytesUploaded, p.chunksUploaded,
+		p.syncErrors, p.lastSync, p.requestDuration)
+	return p
+}
+
+// serve listens on 127.0.0.1:port and serves /metrics in the background.
+func (p *promMetrics) serve(port int) error {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	p.ln = ln
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", p.handler())
+	p.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := p.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warnf("Prometheus metrics server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// handler serves the registry in the Prometheus text format.
+func (p *promMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+// Addr returns the address the server is listening on.
+func (p *promMetrics) Addr() string {
+	return p.ln.Addr().String()
+}
+
+// Close shuts the server down, waiting briefly for in-flight scrapes.
+func (p *promMetrics) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+	defer cancel()
+	if err := p.srv.Shutdown(ctx); err != nil {
+		logger.Warnf("Prometheus metrics server shutdown: %v", err)
+	}
+}
+
+// observeChunk is the engine's OnChunkStats callback.
+func (p *promMetrics) observeChunk(c pkgsync.ChunkStats) {
+	p.linesSynced.WithLabelValues(c.FileType).Add(float64(c.Lines))
+	p.bytesUploaded.Add(float64(c.Bytes))
+	p.chunksUploaded.Inc()
+}
+
+// observeRequest is the engine's OnBackendRequest callback.
+func (p *promMetrics) observeRequest(endpoint string, statusCode int, elapsed time.Duration) {
+	p.requestDuration.WithLabelValues(endpoint, strconv.Itoa(statusCode)).Observe(elapsed.Seconds())
+}
+
+// observeCycle records the outcome of a sync cycle that reached the
+// backend: a failed init or sync counts an error, a clean sync moves the
+// last-sync timestamp.
+func (p *promMetrics) observeCycle(err error) {
+	if err != nil {
+		p.syncErrors.WithLabelValues(syncErrorType(err)).Inc()
+		return
+	}
+	p.lastSync.SetToCurrentTime()
+}
+
+// syncErrorType classifies a cycle error for the error_type label.
+func syncErrorType(err error) string {
+	switch {
+	case errors.Is(err, confabhttp.ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, confabhttp.ErrSessionNotFound):
+		return "not_found"
+	case errors.Is(err, confabhttp.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, confabhttp.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, confabhttp.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	}
+	var statusErr *confabhttp.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode >= 500 {
+		return "server_error"
+	}
+	return "other"
+}
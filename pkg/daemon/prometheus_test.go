@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	confabhttp "github.com/ConfabulousDev/confab/pkg/http"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// scrapeProm fetches and parses the Prometheus endpoint at addr.
+func scrapeProm(addr string) (map[string]*dto.MetricFamily, error) {
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	return parser.TextToMetricFamilies(resp.Body)
+}
+
+// promValue returns the counter or gauge value of the series in family
+// name whose labels include all of labels, or the histogram's sample count.
+func promValue(families map[string]*dto.MetricFamily, name string, labels map[string]string) float64 {
+	mf := families[name]
+	if mf == nil {
+		return 0
+	}
+	var total float64
+	for _, m := range mf.GetMetric() {
+		match := true
+		for k, v := range labels {
+			found := false
+			for _, lp := range m.GetLabel() {
+				if lp.GetName() == k && lp.GetValue() == v {
+					found = true
+				}
+			}
+			match = match && found
+		}
+		if !match {
+			continue
+		}
+		switch {
+		case m.Counter != nil:
+			total += m.GetCounter().GetValue()
+		case m.Gauge != nil:
+			total += m.GetGauge().GetValue()
+		case m.Histogram != nil:
+			total += float64(m.GetHistogram().GetSampleCount())
+		}
+	}
+	return total
+}
+
+func TestDaemonPrometheusMetrics(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"+`{"type":"user"}`+"\n"+`{"type":"assistant"}`+"\n"), 0644)
+
+	_, portStr, _ := net.SplitHostPort(freeAddr(t))
+	port, _ := strconv.Atoi(portStr)
+	addr := net.JoinHostPort("127.0.0.1", portStr)
+	d := New(Config{
+		ExternalID:     "prom-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		SyncInterval:   time.Hour,
+		MetricsPort:    port,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+
+	// The first cycle runs immediately; poll until it's reflected.
+	var families map[string]*dto.MetricFamily
+	deadline := time.Now().Add(3 * time.Second)
+	for promValue(families, "confab_last_sync_timestamp_seconds", nil) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("/metrics never reported the first sync cycle: %v", families)
+		}
+		time.Sleep(20 * time.Millisecond)
+		families, _ = scrapeProm(addr)
+	}
+
+	chunks := mock.getChunkRequests()
+	var lines, bytes float64
+	for _, c := range chunks {
+		lines += float64(len(c.Lines))
+		for _, l := range c.Lines {
+			bytes += float64(len(l) + 1)
+		}
+	}
+	if lines != 3 {
+		t.Fatalf("mock backend received %v lines, want 3", lines)
+	}
+	checks := []struct {
+		name   string
+		labels map[string]string
+		want   float64
+	}{
+		{"confab_chunks_uploaded_total", nil, float64(len(chunks))},
+		{"confab_lines_synced_total", map[string]string{"file_type": "transcript"}, lines},
+		{"confab_bytes_uploaded_total", nil, bytes},
+		{"confab_backend_request_duration_seconds", map[string]string{"endpoint": "/api/v1/sync/chunk", "status_code": "200"}, float64(len(chunks))},
+		{"confab_backend_request_duration_seconds", map[string]string{"endpoint": "/api/v1/sync/init", "status_code": "200"}, float64(len(mock.getInitRequests()))},
+		{"confab_sync_errors_total", nil, 0},
+	}
+	for _, c := range checks {
+		if got := promValue(families, c.name, c.labels); got != c.want {
+			t.Errorf("%s%v = %v, want %v", c.name, c.labels, got, c.want)
+		}
+	}
+
+	cancel()
+	<-errCh
+	if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		t.Error("Prometheus metrics server still serving after Run returned")
+	}
+}
+
+func TestSyncErrorType(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("init: %w", confabhttp.ErrUnauthorized), "unauthorized"},
+		{confabhttp.ErrSessionNotFound, "not_found"},
+		{confabhttp.ErrRateLimited, "rate_limited"},
+		{confabhttp.ErrCircuitOpen, "circuit_open"},
+		{context.DeadlineExceeded, "timeout"},
+		{&confabhttp.StatusError{StatusCode: 503}, "server_error"},
+		{errors.New("disk full"), "other"},
+	}
+	for _, tt := range tests {
+		if got := syncErrorType(tt.err); got != tt.want {
+			t.Errorf("syncErrorType(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
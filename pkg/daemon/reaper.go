@@ -13,8 +13,19 @@ import (
 // realistic "long-dead daemon" age, so it's safe to skip younger files.
 const reapMinAge = 5 * time.Second
 
+// dirtyTailMaxAge is how long a state file with a dirty tail (a daemon that
+// exited with unsynced data) is kept for the next daemon of the session to
+// resume from. After that the session is presumed abandoned and reaped.
+const dirtyTailMaxAge = 7 * 24 * time.Hour
+
+// completedMaxAge is how long the state file of a completed session is
+// kept, so a daemon restarted for it doesn't re-sync from scratch.
+const completedMaxAge = 7 * 24 * time.Hour
+
 // ReapStaleStates walks every provider subdirectory under ~/.confab/sync
-// and removes state + inbox files whose daemon PID is no longer alive.
+// and removes state + inbox files whose daemon PID is no longer alive,
+// except those holding a recent dirty tail (see State.DirtyTail) or a
+// recent completion (see State.Completed).
 // Provider-agnostic: the signal-0 liveness check is OS-level, not
 // provider-specific, so one pass covers Claude / Codex / OpenCode.
 //
@@ -34,12 +45,18 @@ func ReapStaleStates() (reaped int, err error) {
 		if state.IsDaemonRunning() {
 			continue
 		}
+		if state.DirtyTail != nil && time.Since(*state.DirtyTail) < dirtyTailMaxAge {
+			continue
+		}
+		if state.Completed != nil && time.Since(state.Completed.At) < completedMaxAge {
+			continue
+		}
 		if err := state.DeleteWithInbox(); err != nil {
-			logger.Debug("reap: failed to fully delete %s: %v", state.ExternalID, err)
+			logger.Debugf("reap: failed to fully delete %s: %v", state.ExternalID, err)
 			continue
 		}
 		reaped++
-		logger.Debug("reap: removed stale state for %s/%s", state.Provider, state.ExternalID)
+		logger.Debugf("reap: removed stale state for %s/%s", state.Provider, state.ExternalID)
 	}
 	return reaped, nil
 }
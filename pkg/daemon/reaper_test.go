@@ -135,3 +135,62 @@ func TestReapStaleStatesDeletesInboxToo(t *testing.T) {
 		t.Errorf("inbox file %s should be removed alongside state; stat err=%v", s.InboxPath, err)
 	}
 }
+
+// TestReapStaleStatesKeepsRecentDirtyTail asserts a dead daemon's state
+// with unsynced data is kept for the next daemon to resume, until the
+// dirty tail is older than dirtyTailMaxAge.
+func TestReapStaleStatesKeepsRecentDirtyTail(t *testing.T) {
+	setupReaperEnv(t)
+	old := time.Now().Add(-1 * time.Minute)
+	recent := seedState(t, provider.NameClaudeCode, "ses_dirty_recent", 999995, old)
+	recentTail := time.Now().Add(-time.Hour)
+	recent.DirtyTail = &recentTail
+	expired := seedState(t, provider.NameClaudeCode, "ses_dirty_expired", 999994, old)
+	expiredTail := time.Now().Add(-dirtyTailMaxAge - time.Hour)
+	expired.DirtyTail = &expiredTail
+	for _, s := range []*State{recent, expired} {
+		if err := s.Save(); err != nil {
+			t.Fatalf("save state: %v", err)
+		}
+	}
+
+	if _, err := ReapStaleStates(); err != nil {
+		t.Fatalf("ReapStaleStates: %v", err)
+	}
+	recentPath, _ := GetStatePathForProvider(recent.Provider, recent.ExternalID)
+	if _, err := os.Stat(recentPath); err != nil {
+		t.Errorf("state with a recent dirty tail should not be reaped; stat err=%v", err)
+	}
+	expiredPath, _ := GetStatePathForProvider(expired.Provider, expired.ExternalID)
+	if _, err := os.Stat(expiredPath); !os.IsNotExist(err) {
+		t.Errorf("state with an expired dirty tail should be reaped; stat err=%v", err)
+	}
+}
+
+// TestReapStaleStatesKeepsRecentCompletion asserts a completed session's
+// state is kept until the completion is older than completedMaxAge.
+func TestReapStaleStatesKeepsRecentCompletion(t *testing.T) {
+	setupReaperEnv(t)
+	old := time.Now().Add(-1 * time.Minute)
+	recent := seedState(t, provider.NameClaudeCode, "ses_done_recent", 999993, old)
+	recent.Completed = &CompletionMark{At: time.Now().Add(-time.Hour)}
+	expired := seedState(t, provider.NameClaudeCode, "ses_done_expired", 999992, old)
+	expired.Completed = &CompletionMark{At: time.Now().Add(-completedMaxAge - time.Hour)}
+	for _, s := range []*State{recent, expired} {
+		if err := s.Save(); err != nil {
+			t.Fatalf("save state: %v", err)
+		}
+	}
+
+	if _, err := ReapStaleStates(); err != nil {
+		t.Fatalf("ReapStaleStates: %v", err)
+	}
+	recentPath, _ := GetStatePathForProvider(recent.Provider, recent.ExternalID)
+	if _, err := os.Stat(recentPath); err != nil {
+		t.Errorf("recently completed state should not be reaped; stat err=%v", err)
+	}
+	expiredPath, _ := GetStatePathForProvider(expired.Provider, expired.ExternalID)
+	if _, err := os.Stat(expiredPath); !os.IsNotExist(err) {
+		t.Errorf("long-completed state should be reaped; stat err=%v", err)
+	}
+}
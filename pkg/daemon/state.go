@@ -3,6 +3,7 @@ package daemon
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"github.com/ConfabulousDev/confab/pkg/confabpath"
 	"github.com/ConfabulousDev/confab/pkg/logger"
 	providerpkg "github.com/ConfabulousDev/confab/pkg/provider"
+	pkgsync "github.com/ConfabulousDev/confab/pkg/sync"
 )
 
 // State represents the daemon's persistent state
@@ -25,6 +27,157 @@ type State struct {
 	InboxPath       string    `json:"inbox_path"`           // Path to event inbox (JSONL)
 	StartedAt       time.Time `json:"started_at"`
 	ConfabSessionID string    `json:"confab_session_id,omitempty"` // Backend session ID (set after Init)
+
+	// KnownAgentIDs are the agent IDs the engine has discovered from
+	// transcript content. Persisted so a restarted daemon keeps checking
+	// disk for agents referenced before the restart, even if their files
+	// only appear afterwards (the referencing lines are already synced and
+	// won't be re-read).
+	KnownAgentIDs []string `json:"known_agent_ids,omitempty"`
+
+	// StartEventSessionID is the backend session the session_start event
+	// was sent for, carried forward so a restarted daemon resuming the
+	// same backend session doesn't send it again.
+	StartEventSessionID string `json:"start_event_session_id,omitempty"`
+
+	// SyncProgress is refreshed after each sync cycle so `confab status` can
+	// report progress from the state file alone. Nil until the first cycle.
+	SyncProgress *SyncProgress `json:"sync_progress,omitempty"`
+
+	// FileOffsets are the engine's local read positions (backend file name
+	// → offset), persisted so a restarted daemon can seek straight past
+	// already-synced lines instead of re-scanning large files.
+	FileOffsets map[string]pkgsync.FileOffset `json:"file_offsets,omitempty"`
+
+	// FilePaths maps each tracked file's backend name to its local path,
+	// so tools reading the state (`confab export`) find agent and sidechain
+	// files without re-running discovery.
+	FilePaths map[string]string `json:"file_paths,omitempty"`
+
+	// DirtyTail is set when a daemon exited without its final sync
+	// succeeding (backend unreachable or shutdown timeout), so local data
+	// past the persisted progress was never uploaded. The state file is
+	// kept instead of deleted, the reaper spares it for dirtyTailMaxAge,
+	// and the next daemon for the session carries it forward until a sync
+	// cycle completes without errors.
+	DirtyTail *time.Time `json:"dirty_tail,omitempty"`
+
+	// PreCompact is the transcript as it stood when Claude Code's
+	// PreCompact hook fired, after the flush that preceded compaction. The
+	// first sync cycle that finds the transcript different re-inits the
+	// engine, since compaction may have rewritten lines already uploaded,
+	// and clears it.
+	PreCompact *CompactMark `json:"pre_compact,omitempty"`
+
+	// Completed is set when the session ended cleanly: the final sync
+	// succeeded and the backend got the session_end event. The state file
+	// is kept instead of deleted, so a daemon started later for the same
+	// session (e.g. Claude re-opening a finished transcript) doesn't
+	// contact the backend until the transcript changes, then resumes from
+	// the persisted offsets.
+	Completed *CompletionMark `json:"completed,omitempty"`
+
+	// ChunkSizing is the engine's adaptive chunk size estimate, seeded into
+	// the next engine for this session so it starts at a size that suits
+	// the connection.
+	ChunkSizing *pkgsync.ChunkSizing `json:"chunk_sizing,omitempty"`
+
+	// PendingUploads are the engine's unfinished multipart chunk uploads,
+	// keyed by chunk idempotency key, so the next engine resumes them from
+	// the first part the backend is missing.
+	PendingUploads map[string]pkgsync.PendingUpload `json:"pending_uploads,omitempty"`
+}
+
+// CompactMark records the transcript's size, line count and mtime just
+// before a compaction.
+type CompactMark struct {
+	ByteOffset int64     `json:"byte_offset"`
+	Lines      int       `json:"lines"`
+	ModTime    time.Time `json:"mod_time"`
+	At         time.Time `json:"at"`
+}
+
+// CompletionMark records when a session completed and its transcript's
+// size and mtime at that point.
+type CompletionMark struct {
+	At      time.Time `json:"at"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// SyncProgress is the upload progress snapshot persisted in State.
+type SyncProgress struct {
+	FileLines     map[string]int `json:"file_lines"`     // backend file name → last synced line
+	BytesUploaded int64          `json:"bytes_uploaded"` // uncompressed, since the engine started
+	LastSyncAt    time.Time      `json:"last_sync_at"`   // zero until a cycle completes without errors
+
+	// ConsecutiveErrors and LastError describe the cycles failing since
+	// the last clean one; BackoffUntil is when a backend rate limit
+	// (429 Retry-After) lets the daemon sync again.
+	ConsecutiveErrors int        `json:"consecutive_errors,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+	BackoffUntil      *time.Time `json:"backoff_until,omitempty"`
+}
+
+func (p *SyncProgress) equal(other *SyncProgress) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	if p.BytesUploaded != other.BytesUploaded || !p.LastSyncAt.Equal(other.LastSyncAt) ||
+		len(p.FileLines) != len(other.FileLines) ||
+		p.ConsecutiveErrors != other.ConsecutiveErrors || p.LastError != other.LastError {
+		return false
+	}
+	if (p.BackoffUntil == nil) != (other.BackoffUntil == nil) ||
+		(p.BackoffUntil != nil && !p.BackoffUntil.Equal(*other.BackoffUntil)) {
+		return false
+	}
+	for name, line := range p.FileLines {
+		if other.FileLines[name] != line {
+			return false
+		}
+	}
+	return true
+}
+
+// fileOffsetsEqual reports whether two persisted offset maps are identical.
+func fileOffsetsEqual(a, b map[string]pkgsync.FileOffset) bool {
+	return maps.EqualFunc(a, b, func(x, y pkgsync.FileOffset) bool {
+		return x.LastSyncedLine == y.LastSyncedLine && x.ByteOffset == y.ByteOffset &&
+			x.LastSize == y.LastSize && x.LastModTime.Equal(y.LastModTime)
+	})
+}
+
+// LocalFiles returns the session's files as backend file name → local
+// path: the transcript, every file in FilePaths, and, for state written
+// before FilePaths existed, files named in SyncProgress or FileOffsets,
+// placed where the sync engine looks for them: plain agent names in the
+// transcript's subagents directory, path-encoded names ("subagents/...")
+// under the transcript's session directory. Paths are not checked for
+// existence.
+func (s *State) LocalFiles() map[string]string {
+	files := map[string]string{filepath.Base(s.TranscriptPath): s.TranscriptPath}
+	maps.Copy(files, s.FilePaths)
+	subagentsDir := pkgsync.NewFileTracker(s.TranscriptPath).SubagentsDir()
+	addDerived := func(name string) {
+		if _, ok := files[name]; ok {
+			return
+		}
+		if strings.Contains(name, "/") {
+			files[name] = filepath.Join(filepath.Dir(subagentsDir), filepath.FromSlash(name))
+		} else {
+			files[name] = filepath.Join(subagentsDir, name)
+		}
+	}
+	if s.SyncProgress != nil {
+		for name := range s.SyncProgress.FileLines {
+			addDerived(name)
+		}
+	}
+	for name := range s.FileOffsets {
+		addDerived(name)
+	}
+	return files
 }
 
 // NewStateForProvider creates a daemon state under a provider namespace.
@@ -200,7 +353,9 @@ func (s *State) DeleteWithInbox() error {
 	return firstErr
 }
 
-// IsDaemonRunning checks if the daemon process is still alive
+// IsDaemonRunning checks if the daemon process is still alive. A state
+// kept after its daemon exited (DirtyTail, Completed) has PID 0 and never
+// counts as running.
 func (s *State) IsDaemonRunning() bool {
 	return isProcessRunning(s.PID)
 }
@@ -248,7 +403,7 @@ func ListAllStates() ([]*State, error) {
 			providerDir := filepath.Join(syncDir, provider)
 			providerStates, err := listStatesInDir(providerDir, provider)
 			if err != nil {
-				logger.Debug("Skipping provider state dir %s: %v", provider, err)
+				logger.Debugf("Skipping provider state dir %s: %v", provider, err)
 				continue
 			}
 			states = append(states, providerStates...)
@@ -262,7 +417,7 @@ func ListAllStates() ([]*State, error) {
 		externalID := strings.TrimSuffix(entry.Name(), ".json")
 		state, err := LoadStateForProvider("", externalID)
 		if err != nil {
-			logger.Debug("Skipping invalid state file %s: %v", externalID, err)
+			logger.Debugf("Skipping invalid state file %s: %v", externalID, err)
 			continue
 		}
 		if state != nil && state.Provider == "" {
@@ -290,7 +445,7 @@ func listStatesInDir(dir, provider string) ([]*State, error) {
 		path := filepath.Join(dir, entry.Name())
 		state, err := loadStateAt(path)
 		if err != nil {
-			logger.Debug("Skipping invalid state file %s: %v", path, err)
+			logger.Debugf("Skipping invalid state file %s: %v", path, err)
 			continue
 		}
 		if state != nil {
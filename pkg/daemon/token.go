@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+// tokenRefreshLeeway is how long before its expiry an access token is
+// proactively refreshed, so uploads never hit an expired token.
+const tokenRefreshLeeway = 5 * time.Minute
+
+// tokenRefreshCheckInterval is how often refreshTokens checks the access
+// token's expiry. Var (not const) so tests can shorten it.
+var tokenRefreshCheckInterval = time.Minute
+
+// refreshTokens refreshes the engine's access token shortly before it
+// expires. It runs beside the main loop so a token expiring mid-sync (or
+// while the schedule holds uploads back) is replaced in time; a token that
+// expires anyway is refreshed on the backend's 401 by the sync client.
+// Returns when ctx is cancelled or stopCh is closed.
+func (d *Daemon) refreshTokens(ctx context.Context) {
+	ticker := time.NewTicker(tokenRefreshCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+			engine := d.tokenEngine.Load()
+			if engine == nil {
+				continue
+			}
+			if _, err := engine.RefreshTokenIfExpiring(tokenRefreshLeeway); err != nil {
+				logger.Warnf("Proactive token refresh failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+)
+
+// writeTokenConfig rewrites the test config with an access token that
+// expires at expiresAt and a refresh token.
+func writeTokenConfig(t *testing.T, serverURL, accessToken string, expiresAt time.Time) {
+	t.Helper()
+	configJSON := fmt.Sprintf(`{"backend_url":%q,"api_key":%q,"refresh_token":"refresh-1","expires_at":%q}`,
+		serverURL, accessToken, expiresAt.Format(time.RFC3339))
+	if err := os.WriteFile(os.Getenv("CONFAB_CONFIG_PATH"), []byte(configJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runDaemonFor runs a daemon with a short sync interval for d, then stops it.
+func runDaemonFor(t *testing.T, externalID, transcriptPath, cwd string, d time.Duration) {
+	t.Helper()
+	daemon := New(Config{
+		ExternalID:     externalID,
+		TranscriptPath: transcriptPath,
+		CWD:            cwd,
+		SyncInterval:   50 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- daemon.Run(ctx) }()
+	time.Sleep(d)
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+// TestDaemonRefreshesExpiredToken checks that a daemon whose access token
+// the backend rejects refreshes it and keeps syncing, instead of waiting
+// for the user to log in again.
+func TestDaemonRefreshesExpiredToken(t *testing.T) {
+	mock := newMockBackend(t)
+	mock.accessToken = "cfb_server-current-token-0000"
+	mock.refreshToken = "refresh-1"
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	writeTokenConfig(t, server.URL, "cfb_expired-access-token-1234", time.Now().Add(-time.Minute))
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","message":"start"}`+"\n"), 0644)
+
+	runDaemonFor(t, "token-refresh-401-test", transcriptPath, tmpDir, 300*time.Millisecond)
+
+	if len(mock.getChunkRequests()) == 0 {
+		t.Error("expected chunks to upload after the token was refreshed")
+	}
+	cfg, err := config.GetUploadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.APIKey != refreshedAccessToken {
+		t.Errorf("saved api_key = %q, want the refreshed token", cfg.APIKey)
+	}
+}
+
+// TestDaemonRefreshesTokenBeforeExpiry checks the background refresh: a
+// token expiring within tokenRefreshLeeway is replaced without any request
+// failing first.
+func TestDaemonRefreshesTokenBeforeExpiry(t *testing.T) {
+	originalInterval := tokenRefreshCheckInterval
+	tokenRefreshCheckInterval = 20 * time.Millisecond
+	defer func() { tokenRefreshCheckInterval = originalInterval }()
+
+	const expiringToken = "cfb_expiring-access-token-1234"
+	mock := newMockBackend(t)
+	mock.accessToken = expiringToken
+	mock.refreshToken = "refresh-1"
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	writeTokenConfig(t, server.URL, expiringToken, time.Now().Add(2*time.Minute))
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","message":"start"}`+"\n"), 0644)
+
+	runDaemonFor(t, "token-refresh-proactive-test", transcriptPath, tmpDir, 300*time.Millisecond)
+
+	mock.mu.Lock()
+	refreshCalls := mock.refreshCalls
+	mock.mu.Unlock()
+	if refreshCalls != 1 {
+		t.Errorf("refresh calls = %d, want exactly 1 (the new token is valid for an hour)", refreshCalls)
+	}
+	cfg, err := config.GetUploadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.APIKey != refreshedAccessToken {
+		t.Errorf("saved api_key = %q, want the refreshed token", cfg.APIKey)
+	}
+	if until := time.Until(cfg.ExpiresAt); until < 59*time.Minute {
+		t.Errorf("saved expires_at is %v from now, want ~1h", until)
+	}
+}
@@ -0,0 +1,167 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long a watcher waits after the first write event
+// before signalling a sync, so a burst of appends costs one sync cycle.
+// Later events don't extend the wait, so a constantly-written transcript
+// still syncs every watchDebounce. Var (not const) so tests can shorten it.
+var watchDebounce = 250 * time.Millisecond
+
+// transcriptWatcher turns filesystem events on a transcript (and its
+// subagent files) into sync triggers for WatchMode.
+//
+// It watches directories rather than files: a watch on the file itself is
+// lost when an editor replaces it via write-to-temp + rename, and can't be
+// placed before the file exists. A directory watch sees the rename's Create
+// event for the transcript name in both cases.
+type transcriptWatcher struct {
+	w              *fsnotify.Watcher
+	transcriptPath string
+	// dirs are watched once they exist: the transcript's directory, the
+	// Claude session dir (so its subagents dir is seen being created), and
+	// subagentDirs.
+	dirs []string
+	// subagentDirs hold agent files; any .jsonl event inside them
+	// triggers a sync. Claude nests them under <session>/subagents,
+	// Cursor beside the transcript.
+	subagentDirs []string
+
+	notify chan struct{}
+	done   chan struct{}
+
+	mu      sync.Mutex
+	watched map[string]bool
+	pending bool
+}
+
+// newTranscriptWatcher starts watching transcriptPath's directory. Fails
+// only if the watcher can't be created; missing directories are retried
+// on later events.
+func newTranscriptWatcher(transcriptPath string) (*transcriptWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	sessionDir := strings.TrimSuffix(transcriptPath, ".jsonl")
+	subagentDirs := []string{
+		filepath.Join(sessionDir, "subagents"),
+		filepath.Join(filepath.Dir(transcriptPath), "subagents"),
+	}
+	tw := &transcriptWatcher{
+		w:              w,
+		transcriptPath: transcriptPath,
+		dirs:           append([]string{filepath.Dir(transcriptPath), sessionDir}, subagentDirs...),
+		subagentDirs:   subagentDirs,
+		notify:         make(chan struct{}, 1),
+		done:           make(chan struct{}),
+		watched:        make(map[string]bool),
+	}
+	tw.addDirs()
+	go tw.loop()
+	return tw, nil
+}
+
+// C receives a value whenever the watched files changed since the last
+// receive. Bursts of events are coalesced into one value.
+func (tw *transcriptWatcher) C() <-chan struct{} {
+	return tw.notify
+}
+
+// Close stops the watcher.
+func (tw *transcriptWatcher) Close() error {
+	err := tw.w.Close()
+	<-tw.done
+	return err
+}
+
+// addDirs adds watches for any watched directory that now exists.
+func (tw *transcriptWatcher) addDirs() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	for _, dir := range tw.dirs {
+		if tw.watched[dir] {
+			continue
+		}
+		if err := tw.w.Add(dir); err != nil {
+			if !os.IsNotExist(err) {
+				logger.Debugf("Watch %s failed: %v", dir, err)
+			}
+			continue
+		}
+		tw.watched[dir] = true
+	}
+}
+
+func (tw *transcriptWatcher) loop() {
+	defer close(tw.done)
+	for {
+		select {
+		case ev, ok := <-tw.w.Events:
+			if !ok {
+				return
+			}
+			if ev.Has(fsnotify.Create) {
+				// A session or subagents dir may just have appeared.
+				tw.addDirs()
+			}
+			if tw.relevant(ev) {
+				tw.trigger()
+			}
+		case err, ok := <-tw.w.Errors:
+			if !ok {
+				return
+			}
+			logger.Warnf("Transcript watcher error: %v", err)
+		}
+	}
+}
+
+// relevant reports whether ev may have changed a file the engine syncs.
+func (tw *transcriptWatcher) relevant(ev fsnotify.Event) bool {
+	if !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Rename) {
+		return false
+	}
+	if ev.Name == tw.transcriptPath {
+		return true
+	}
+	if filepath.Ext(ev.Name) != ".jsonl" {
+		return false
+	}
+	dir := filepath.Dir(ev.Name)
+	for _, d := range tw.subagentDirs {
+		if dir == d {
+			return true
+		}
+	}
+	return false
+}
+
+// trigger schedules a notification after watchDebounce unless one is
+// already pending.
+func (tw *transcriptWatcher) trigger() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.pending {
+		return
+	}
+	tw.pending = true
+	time.AfterFunc(watchDebounce, func() {
+		tw.mu.Lock()
+		tw.pending = false
+		tw.mu.Unlock()
+		select {
+		case tw.notify <- struct{}{}:
+		default:
+		}
+	})
+}
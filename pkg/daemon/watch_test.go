@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// runWatchDaemon starts a WatchMode daemon whose fallback interval is far
+// longer than any test, so every sync after the first must come from a
+// filesystem event. The returned stop function cancels it and waits.
+func runWatchDaemon(t *testing.T, externalID, transcriptPath, cwd string) (stop func()) {
+	t.Helper()
+	d := New(Config{
+		ExternalID:         externalID,
+		TranscriptPath:     transcriptPath,
+		CWD:                cwd,
+		SyncInterval:       time.Hour,
+		SyncIntervalJitter: 0,
+		WatchMode:          true,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-errCh
+	}
+}
+
+// waitForChunks polls until the mock has received n chunk uploads.
+func waitForChunks(t *testing.T, mock *mockBackend, n int, within time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(within)
+	for len(mock.getChunkRequests()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d chunk uploads within %v, want %d", len(mock.getChunkRequests()), within, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDaemonWatchMode_SyncsOnAppend(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","line":1}`+"\n"), 0644)
+
+	stop := runWatchDaemon(t, "watch-append-test", transcriptPath, tmpDir)
+	defer stop()
+	waitForChunks(t, mock, 1, 2*time.Second)
+
+	f, _ := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"user","line":2}` + "\n")
+	f.Close()
+
+	waitForChunks(t, mock, 2, 2*time.Second)
+	if got := mock.getChunkRequests()[1].FirstLine; got != 2 {
+		t.Errorf("second chunk FirstLine = %d, want 2", got)
+	}
+}
+
+func TestDaemonWatchMode_RenameReplace(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	line1 := `{"type":"system","line":1}` + "\n"
+	os.WriteFile(transcriptPath, []byte(line1), 0644)
+
+	stop := runWatchDaemon(t, "watch-rename-test", transcriptPath, tmpDir)
+	defer stop()
+	waitForChunks(t, mock, 1, 2*time.Second)
+
+	// Editor-style save: write a temp file, then rename it over the original.
+	tmp := transcriptPath + ".tmp"
+	os.WriteFile(tmp, []byte(line1+`{"type":"user","line":2}`+"\n"), 0644)
+	if err := os.Rename(tmp, transcriptPath); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForChunks(t, mock, 2, 2*time.Second)
+
+	// The replaced file must still be watched.
+	f, _ := os.OpenFile(transcriptPath, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"assistant","line":3}` + "\n")
+	f.Close()
+	waitForChunks(t, mock, 3, 2*time.Second)
+}
+
+func TestDaemonWatchMode_TranscriptAppearsLate(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+
+	stop := runWatchDaemon(t, "watch-late-test", transcriptPath, tmpDir)
+	defer stop()
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","message":"late"}`+"\n"), 0644)
+
+	// Well under initialWaitPollInterval: the Create event ends the wait.
+	waitForChunks(t, mock, 1, initialWaitPollInterval/2)
+	t.Logf("late transcript synced after %v", time.Since(start))
+}
+
+func TestDaemonWatchMode_SubagentFile(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	agentID := "abcd1234"
+	os.WriteFile(transcriptPath, []byte(`{"type":"user","toolUseResult":{"agentId":"`+agentID+`"}}`+"\n"), 0644)
+	subagentsDir := filepath.Join(filepath.Dir(transcriptPath), "transcript", "subagents")
+
+	stop := runWatchDaemon(t, "watch-subagent-test", transcriptPath, tmpDir)
+	defer stop()
+	waitForChunks(t, mock, 1, 2*time.Second)
+
+	// The subagents dir doesn't exist yet; its creation is picked up and
+	// the agent file written inside it triggers a sync.
+	os.MkdirAll(subagentsDir, 0755)
+	time.Sleep(50 * time.Millisecond)
+	os.WriteFile(filepath.Join(subagentsDir, "agent-"+agentID+".jsonl"), []byte(`{"type":"assistant"}`+"\n"), 0644)
+
+	waitForChunks(t, mock, 2, 2*time.Second)
+	if got := mock.getChunkRequests()[1].FileName; got != "agent-"+agentID+".jsonl" {
+		t.Errorf("second chunk FileName = %q, want the agent file", got)
+	}
+}
@@ -2,9 +2,12 @@ package git
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/types"
 )
@@ -236,6 +239,49 @@ func GetHeadSHA(cwd string) (string, error) {
 	return strings.TrimSpace(sha), nil
 }
 
+// HeadCommit describes the HEAD commit.
+type HeadCommit struct {
+	// Message is the full message: subject, body and trailers.
+	Message string
+	// CommitTime is the committer date, to the second.
+	CommitTime time.Time
+	// Merge is set when the commit has more than one parent.
+	Merge bool
+}
+
+// GetHeadCommit returns the HEAD commit. Returns nil and nil if not in a
+// git repo.
+func GetHeadCommit(cwd string) (*HeadCommit, error) {
+	if !isGitRepo(cwd) {
+		return nil, nil
+	}
+	out, err := gitCommand(cwd, "log", "--format=%ct %P%n%B", "-n", "1")
+	if err != nil {
+		return nil, err
+	}
+	header, message, _ := strings.Cut(out, "\n")
+	fields := strings.Fields(header)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("unexpected git log output %q", header)
+	}
+	seconds, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected commit time %q: %w", fields[0], err)
+	}
+	return &HeadCommit{
+		Message:    message,
+		CommitTime: time.Unix(seconds, 0),
+		Merge:      len(fields) > 2,
+	}, nil
+}
+
+// HeadOnUpstream reports whether HEAD is already contained in the current
+// branch's upstream, i.e. pushed. False when there is no upstream.
+func HeadOnUpstream(cwd string) bool {
+	_, err := gitCommand(cwd, "merge-base", "--is-ancestor", "HEAD", "@{u}")
+	return err == nil
+}
+
 // ToGitHubURL converts a git remote URL to a GitHub HTTPS URL.
 // Handles: git@github.com:owner/repo.git, https://github.com/owner/repo.git,
 // ssh://git@github.com/owner/repo.git
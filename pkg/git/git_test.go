@@ -7,6 +7,7 @@ import (
 	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 // initGitRepoWithCommit initialises a git repo in a fresh temp dir, sets
@@ -211,6 +212,64 @@ func runGit(t *testing.T, dir string, args ...string) {
 	}
 }
 
+func TestGetHeadCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available in PATH")
+	}
+
+	head, err := GetHeadCommit(t.TempDir())
+	if err != nil || head != nil {
+		t.Errorf("GetHeadCommit(non-git dir) = %+v, %v; want nil", head, err)
+	}
+
+	dir := initGitRepoWithCommit(t)
+	before := time.Now().Add(-time.Second)
+	runGit(t, dir, "commit", "--amend", "-m", "Subject\n\nBody", "--trailer", "Confab-Link: https://example.com/sessions/1")
+	head, err = GetHeadCommit(dir)
+	if err != nil {
+		t.Fatalf("GetHeadCommit() error: %v", err)
+	}
+	for _, want := range []string{"Subject", "Body", "Confab-Link: https://example.com/sessions/1"} {
+		if !strings.Contains(head.Message, want) {
+			t.Errorf("GetHeadCommit().Message = %q, want it to contain %q", head.Message, want)
+		}
+	}
+	if head.CommitTime.Before(before) || head.Merge {
+		t.Errorf("GetHeadCommit() = %+v, want a recent non-merge commit", head)
+	}
+
+	runGit(t, dir, "checkout", "-b", "side")
+	runGit(t, dir, "commit", "--allow-empty", "-m", "side")
+	runGit(t, dir, "checkout", "main")
+	runGit(t, dir, "commit", "--allow-empty", "-m", "main")
+	runGit(t, dir, "merge", "--no-edit", "side")
+	if head, err = GetHeadCommit(dir); err != nil || !head.Merge {
+		t.Errorf("GetHeadCommit() after merge = %+v, %v; want a merge", head, err)
+	}
+}
+
+func TestHeadOnUpstream(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available in PATH")
+	}
+
+	remote := t.TempDir()
+	runGit(t, remote, "init", "--bare")
+	dir := initGitRepoWithCommit(t)
+	if HeadOnUpstream(dir) {
+		t.Error("HeadOnUpstream() = true without an upstream")
+	}
+	runGit(t, dir, "remote", "add", "origin", remote)
+	runGit(t, dir, "push", "-u", "origin", "main")
+	if !HeadOnUpstream(dir) {
+		t.Error("HeadOnUpstream() = false right after pushing")
+	}
+	runGit(t, dir, "commit", "--allow-empty", "-m", "unpushed")
+	if HeadOnUpstream(dir) {
+		t.Error("HeadOnUpstream() = true with an unpushed commit")
+	}
+}
+
 func TestGetHeadSHA(t *testing.T) {
 	// Skip if git is not available
 	if _, err := exec.LookPath("git"); err != nil {
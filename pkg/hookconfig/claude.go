@@ -2,7 +2,6 @@ package hookconfig
 
 import (
 	"fmt"
-	"path/filepath"
 	"strings"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
@@ -19,11 +18,7 @@ var toolUseMatchers = []string{
 // isConfabCommand checks if a command string invokes the confab binary.
 // More precise than substring contains to avoid false positives.
 func isConfabCommand(command string) bool {
-	parts := strings.Fields(command)
-	if len(parts) == 0 {
-		return false
-	}
-	return filepath.Base(parts[0]) == "confab"
+	return config.IsConfabCommand(command)
 }
 
 // isConfabHookEntry returns true if a hook entry is a confab command hook.
@@ -40,7 +35,7 @@ func getHooksList(entry map[string]any, eventName string, entryIdx int) []any {
 	}
 	hooksList, ok := hooksListRaw.([]any)
 	if !ok {
-		logger.Debug("settings.json: hooks[%q][%d].hooks has unexpected type %T (expected array)", eventName, entryIdx, hooksListRaw)
+		logger.Debugf("settings.json: hooks[%q][%d].hooks has unexpected type %T (expected array)", eventName, entryIdx, hooksListRaw)
 		return nil
 	}
 	return hooksList
@@ -55,7 +50,7 @@ func installHook(settings *config.ClaudeSettings, hook map[string]any, eventName
 	for i, entryAny := range eventHooks {
 		entry, ok := entryAny.(map[string]any)
 		if !ok {
-			logger.Debug("settings.json: hooks[%q][%d] has unexpected type %T (expected object), skipping", eventName, i, entryAny)
+			logger.Debugf("settings.json: hooks[%q][%d] has unexpected type %T (expected object), skipping", eventName, i, entryAny)
 			continue
 		}
 
@@ -73,7 +68,7 @@ func installHook(settings *config.ClaudeSettings, hook map[string]any, eventName
 		for j, existingHookAny := range hooksList {
 			existingHook, ok := existingHookAny.(map[string]any)
 			if !ok {
-				logger.Debug("settings.json: hooks[%q][%d].hooks[%d] has unexpected type %T (expected object), skipping", eventName, i, j, existingHookAny)
+				logger.Debugf("settings.json: hooks[%q][%d].hooks[%d] has unexpected type %T (expected object), skipping", eventName, i, j, existingHookAny)
 				continue
 			}
 			if isConfabHookEntry(existingHook) {
@@ -112,7 +107,7 @@ func removeHooksFromEvent(settings *config.ClaudeSettings, eventName string, sho
 	for i, matcherAny := range eventHooks {
 		matcher, ok := matcherAny.(map[string]any)
 		if !ok {
-			logger.Debug("settings.json: hooks[%q][%d] has unexpected type %T (expected object), preserving as-is", eventName, i, matcherAny)
+			logger.Debugf("settings.json: hooks[%q][%d] has unexpected type %T (expected object), preserving as-is", eventName, i, matcherAny)
 			updatedMatchers = append(updatedMatchers, matcherAny)
 			continue
 		}
@@ -127,7 +122,7 @@ func removeHooksFromEvent(settings *config.ClaudeSettings, eventName string, sho
 		for j, hookAny := range hooksList {
 			hook, ok := hookAny.(map[string]any)
 			if !ok {
-				logger.Debug("settings.json: hooks[%q][%d].hooks[%d] has unexpected type %T (expected object), preserving as-is", eventName, i, j, hookAny)
+				logger.Debugf("settings.json: hooks[%q][%d].hooks[%d] has unexpected type %T (expected object), preserving as-is", eventName, i, j, hookAny)
 				remainingHooks = append(remainingHooks, hookAny)
 				continue
 			}
@@ -151,13 +146,13 @@ func findHookInEvent(settings *config.ClaudeSettings, eventName string, matches
 	for i, matcherAny := range eventHooks {
 		matcher, ok := matcherAny.(map[string]any)
 		if !ok {
-			logger.Debug("settings.json: hooks[%q][%d] has unexpected type %T (expected object), skipping", eventName, i, matcherAny)
+			logger.Debugf("settings.json: hooks[%q][%d] has unexpected type %T (expected object), skipping", eventName, i, matcherAny)
 			continue
 		}
 		for j, hookAny := range getHooksList(matcher, eventName, i) {
 			hook, ok := hookAny.(map[string]any)
 			if !ok {
-				logger.Debug("settings.json: hooks[%q][%d].hooks[%d] has unexpected type %T (expected object), skipping", eventName, i, j, hookAny)
+				logger.Debugf("settings.json: hooks[%q][%d].hooks[%d] has unexpected type %T (expected object), skipping", eventName, i, j, hookAny)
 				continue
 			}
 			if matches(hook) {
@@ -177,13 +172,53 @@ func hasHookWithCommand(settings *config.ClaudeSettings, eventName, cmdSubstring
 	})
 }
 
-// InstallSyncHooks installs SessionStart + SessionEnd hooks for the
-// incremental sync daemon.
-func InstallSyncHooks(settingsPath string) error {
+// claudeHookBundles are the in-memory installers behind the Install*
+// functions, in the order ClaudeCode.InstallHooks runs them.
+var claudeHookBundles = []func(*config.ClaudeSettings, string) error{
+	applySyncHooks,
+	applyPreToolUseHooks,
+	applyPostToolUseHooks,
+	applyUserPromptSubmitHook,
+	applyStopHook,
+	applyPreCompactHook,
+}
+
+// installClaudeBundle resolves the confab binary path and applies one
+// hook bundle to the settings file at settingsPath.
+func installClaudeBundle(settingsPath string, apply func(*config.ClaudeSettings, string) error) error {
+	binaryPath, err := config.GetBinaryPath()
+	if err != nil {
+		return fmt.Errorf("failed to get binary path: %w", err)
+	}
+	return config.AtomicUpdateSettingsAt(settingsPath, func(settings *config.ClaudeSettings) error {
+		return apply(settings, binaryPath)
+	})
+}
+
+// ApplyClaudeHooks adds every Claude hook bundle to settings in memory,
+// without touching disk — what the Install* functions together would
+// write. Used to preview an install (`confab setup --dry-run`).
+func ApplyClaudeHooks(settings *config.ClaudeSettings) error {
 	binaryPath, err := config.GetBinaryPath()
 	if err != nil {
 		return fmt.Errorf("failed to get binary path: %w", err)
 	}
+	for _, apply := range claudeHookBundles {
+		if err := apply(settings, binaryPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InstallSyncHooks installs SessionStart + SessionEnd hooks for the
+// incremental sync daemon.
+func InstallSyncHooks(settingsPath string) error {
+	return installClaudeBundle(settingsPath, applySyncHooks)
+}
+
+// applySyncHooks adds the sync hooks to settings in memory.
+func applySyncHooks(settings *config.ClaudeSettings, binaryPath string) error {
 	// Installed strings carry an explicit `--provider claude-code` (m9mb), like
 	// codex/cursor already do. The idempotency/uninstall matchers below use
 	// Contains "hook session-start"/"session-end", so they still match both this
@@ -199,12 +234,10 @@ func InstallSyncHooks(settingsPath string) error {
 		"type":    "command",
 		"command": fmt.Sprintf("%s hook session-end --provider claude-code", binaryPath),
 	}
-	return config.AtomicUpdateSettingsAt(settingsPath, func(settings *config.ClaudeSettings) error {
-		if err := installHook(settings, sessionStartHook, "SessionStart", "*", true); err != nil {
-			return err
-		}
-		return installHook(settings, sessionEndHook, "SessionEnd", "*", true)
-	})
+	if err := installHook(settings, sessionStartHook, "SessionStart", "*", true); err != nil {
+		return err
+	}
+	return installHook(settings, sessionEndHook, "SessionEnd", "*", true)
 }
 
 // UninstallSyncHooks removes the sync daemon hooks. Handles both old
@@ -244,22 +277,21 @@ func IsSyncHooksInstalled(settingsPath string) (bool, error) {
 // InstallPreToolUseHooks installs the PreToolUse hook for git commit
 // validation. Installs with a "Bash" matcher to intercept git commits.
 func InstallPreToolUseHooks(settingsPath string) error {
-	binaryPath, err := config.GetBinaryPath()
-	if err != nil {
-		return fmt.Errorf("failed to get binary path: %w", err)
-	}
+	return installClaudeBundle(settingsPath, applyPreToolUseHooks)
+}
+
+// applyPreToolUseHooks adds the PreToolUse hook to settings in memory.
+func applyPreToolUseHooks(settings *config.ClaudeSettings, binaryPath string) error {
 	preToolUseHook := map[string]any{
 		"type":    "command",
 		"command": fmt.Sprintf("%s hook pre-tool-use", binaryPath),
 	}
-	return config.AtomicUpdateSettingsAt(settingsPath, func(settings *config.ClaudeSettings) error {
-		for _, matcher := range toolUseMatchers {
-			if err := installHook(settings, preToolUseHook, "PreToolUse", matcher, true); err != nil {
-				return err
-			}
+	for _, matcher := range toolUseMatchers {
+		if err := installHook(settings, preToolUseHook, "PreToolUse", matcher, true); err != nil {
+			return err
 		}
-		return nil
-	})
+	}
+	return nil
 }
 
 // UninstallPreToolUseHooks removes the PreToolUse hook.
@@ -281,22 +313,21 @@ func IsPreToolUseHooksInstalled(settingsPath string) (bool, error) {
 // InstallPostToolUseHooks installs the PostToolUse hook for GitHub
 // link tracking.
 func InstallPostToolUseHooks(settingsPath string) error {
-	binaryPath, err := config.GetBinaryPath()
-	if err != nil {
-		return fmt.Errorf("failed to get binary path: %w", err)
-	}
+	return installClaudeBundle(settingsPath, applyPostToolUseHooks)
+}
+
+// applyPostToolUseHooks adds the PostToolUse hook to settings in memory.
+func applyPostToolUseHooks(settings *config.ClaudeSettings, binaryPath string) error {
 	postToolUseHook := map[string]any{
 		"type":    "command",
 		"command": fmt.Sprintf("%s hook post-tool-use", binaryPath),
 	}
-	return config.AtomicUpdateSettingsAt(settingsPath, func(settings *config.ClaudeSettings) error {
-		for _, matcher := range toolUseMatchers {
-			if err := installHook(settings, postToolUseHook, "PostToolUse", matcher, true); err != nil {
-				return err
-			}
+	for _, matcher := range toolUseMatchers {
+		if err := installHook(settings, postToolUseHook, "PostToolUse", matcher, true); err != nil {
+			return err
 		}
-		return nil
-	})
+	}
+	return nil
 }
 
 // UninstallPostToolUseHooks removes the PostToolUse hook.
@@ -315,20 +346,23 @@ func IsPostToolUseHooksInstalled(settingsPath string) (bool, error) {
 	return hasHookWithCommand(settings, "PostToolUse", "hook post-tool-use"), nil
 }
 
+// HookTypeUserPromptSubmit is the `confab hook` handler the UserPromptSubmit
+// hook runs (cmd/hook_userpromptsubmit.go).
+const HookTypeUserPromptSubmit = "user-prompt-submit"
+
 // InstallUserPromptSubmitHook installs the UserPromptSubmit hook.
 // Unlike other hooks, UserPromptSubmit doesn't use matchers.
 func InstallUserPromptSubmitHook(settingsPath string) error {
-	binaryPath, err := config.GetBinaryPath()
-	if err != nil {
-		return fmt.Errorf("failed to get binary path: %w", err)
-	}
+	return installClaudeBundle(settingsPath, applyUserPromptSubmitHook)
+}
+
+// applyUserPromptSubmitHook adds the UserPromptSubmit hook to settings in memory.
+func applyUserPromptSubmitHook(settings *config.ClaudeSettings, binaryPath string) error {
 	hook := map[string]any{
 		"type":    "command",
-		"command": fmt.Sprintf("%s hook user-prompt-submit", binaryPath),
+		"command": fmt.Sprintf("%s hook %s", binaryPath, HookTypeUserPromptSubmit),
 	}
-	return config.AtomicUpdateSettingsAt(settingsPath, func(settings *config.ClaudeSettings) error {
-		return installHook(settings, hook, "UserPromptSubmit", "", false)
-	})
+	return installHook(settings, hook, "UserPromptSubmit", "", false)
 }
 
 // UninstallUserPromptSubmitHook removes the UserPromptSubmit hook.
@@ -345,5 +379,70 @@ func IsUserPromptSubmitHookInstalled(settingsPath string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to read settings: %w", err)
 	}
-	return hasHookWithCommand(settings, "UserPromptSubmit", "hook user-prompt-submit"), nil
+	return hasHookWithCommand(settings, "UserPromptSubmit", "hook "+HookTypeUserPromptSubmit), nil
+}
+
+// InstallStopHook installs the Stop hook, which flushes the session's
+// sync daemon each time Claude finishes responding. Like
+// UserPromptSubmit, Stop doesn't use matchers.
+func InstallStopHook(settingsPath string) error {
+	return installClaudeBundle(settingsPath, applyStopHook)
+}
+
+// applyStopHook adds the Stop hook to settings in memory.
+func applyStopHook(settings *config.ClaudeSettings, binaryPath string) error {
+	hook := map[string]any{
+		"type":    "command",
+		"command": fmt.Sprintf("%s hook stop", binaryPath),
+	}
+	return installHook(settings, hook, "Stop", "", false)
+}
+
+// UninstallStopHook removes the Stop hook.
+func UninstallStopHook(settingsPath string) error {
+	return config.AtomicUpdateSettingsAt(settingsPath, func(settings *config.ClaudeSettings) error {
+		return removeHooksFromEvent(settings, "Stop", isConfabHookEntry)
+	})
+}
+
+// IsStopHookInstalled checks if the Stop hook is installed.
+func IsStopHookInstalled(settingsPath string) (bool, error) {
+	settings, err := config.ReadSettingsAt(settingsPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read settings: %w", err)
+	}
+	return hasHookWithCommand(settings, "Stop", "hook stop"), nil
+}
+
+// InstallPreCompactHook installs the PreCompact hook, which flushes the
+// session's sync daemon before Claude compacts the transcript and marks its
+// size so the daemon re-inits once compaction rewrites it. Fires for both
+// manual and auto compaction, so no matcher is used.
+func InstallPreCompactHook(settingsPath string) error {
+	return installClaudeBundle(settingsPath, applyPreCompactHook)
+}
+
+// applyPreCompactHook adds the PreCompact hook to settings in memory.
+func applyPreCompactHook(settings *config.ClaudeSettings, binaryPath string) error {
+	hook := map[string]any{
+		"type":    "command",
+		"command": fmt.Sprintf("%s hook pre-compact", binaryPath),
+	}
+	return installHook(settings, hook, "PreCompact", "", false)
+}
+
+// UninstallPreCompactHook removes the PreCompact hook.
+func UninstallPreCompactHook(settingsPath string) error {
+	return config.AtomicUpdateSettingsAt(settingsPath, func(settings *config.ClaudeSettings) error {
+		return removeHooksFromEvent(settings, "PreCompact", isConfabHookEntry)
+	})
+}
+
+// IsPreCompactHookInstalled checks if the PreCompact hook is installed.
+func IsPreCompactHookInstalled(settingsPath string) (bool, error) {
+	settings, err := config.ReadSettingsAt(settingsPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read settings: %w", err)
+	}
+	return hasHookWithCommand(settings, "PreCompact", "hook pre-compact"), nil
 }
@@ -0,0 +1,232 @@
+package hookconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+)
+
+// hookSlot identifies a matcher entry of an event: its "matcher" value,
+// or has=false for entries without the key.
+type hookSlot struct {
+	matcher string
+	has     bool
+}
+
+// confabEventSlots lists, per event, the matcher entries the installers
+// put confab hooks under. Confab hooks found under any other matcher of a
+// single-slot event are stray (e.g. hand-copied into a "" matcher) and
+// are merged into the canonical entry. Events not listed here, and
+// unlisted matchers of multi-slot events, are only de-duplicated within
+// their own matcher.
+var confabEventSlots = map[string][]hookSlot{
+	"SessionStart":     {{"*", true}},
+	"SessionEnd":       {{"*", true}},
+	"UserPromptSubmit": {{}},
+	"Stop":             {{}},
+	"PreCompact":       {{}},
+	"PreToolUse":       toolUseSlots(),
+	"PostToolUse":      toolUseSlots(),
+}
+
+func toolUseSlots() []hookSlot {
+	slots := make([]hookSlot, len(toolUseMatchers))
+	for i, m := range toolUseMatchers {
+		slots[i] = hookSlot{m, true}
+	}
+	return slots
+}
+
+// entrySlot returns the slot a matcher entry occupies.
+func entrySlot(entry map[string]any) hookSlot {
+	m, has := entry["matcher"]
+	if !has {
+		return hookSlot{}
+	}
+	s, _ := m.(string)
+	return hookSlot{s, true}
+}
+
+// confabHookKey identifies what a confab command does regardless of where
+// the binary lives, e.g. "hook session-end" for both
+// "/usr/local/bin/confab hook session-end" and
+// "~/.local/bin/confab hook session-end --provider claude-code".
+func confabHookKey(command string) string {
+	parts := strings.Fields(command)
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+	return strings.Join(parts[1:], " ")
+}
+
+// RepairHooks removes duplicate confab command hooks from every event in
+// the Claude settings file at settingsPath, keeping one per command and
+// matcher, and merges confab hooks under stray matchers into the entry
+// the installers use (see confabEventSlots). Non-confab hooks and unknown
+// fields are left untouched; matcher entries left without hooks are
+// dropped. Returns the number of hooks removed or moved; the file is only
+// rewritten when that is non-zero.
+func RepairHooks(settingsPath string) (int, error) {
+	n, err := CountHookRepairs(settingsPath)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	err = config.AtomicUpdateSettingsAt(settingsPath, func(settings *config.ClaudeSettings) error {
+		n, err = repairHooks(settings)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// CountHookRepairs reports how many hooks RepairHooks would remove or
+// move, without changing the file.
+func CountHookRepairs(settingsPath string) (int, error) {
+	settings, err := config.ReadSettingsAt(settingsPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read settings: %w", err)
+	}
+	return repairHooks(settings)
+}
+
+// repairHooks applies RepairHooks to settings in memory.
+func repairHooks(settings *config.ClaudeSettings) (int, error) {
+	hooks, err := settings.GetHooksMap()
+	if err != nil {
+		return 0, err
+	}
+	events := make([]string, 0, len(hooks))
+	for event := range hooks {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+
+	total := 0
+	for _, event := range events {
+		n, err := repairEvent(settings, event)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// repairEvent de-duplicates and merges the confab hooks of one event.
+// Each slot's first entry is canonical: its hooks win over copies
+// elsewhere, and confab hooks from the slot's other entries (or from
+// stray matchers) are moved into it, so a repaired file matches what
+// installHook updates.
+func repairEvent(settings *config.ClaudeSettings, eventName string) (int, error) {
+	eventHooks := settings.GetEventHooks(eventName)
+	slots, known := confabEventSlots[eventName]
+
+	// Entries that aren't objects or whose hooks aren't an array are left
+	// exactly as they are (nil here).
+	entries := make([]map[string]any, len(eventHooks))
+	targets := make([]hookSlot, len(eventHooks))
+	canonical := make(map[hookSlot]int)
+	for i, entryAny := range eventHooks {
+		entry, ok := entryAny.(map[string]any)
+		if !ok {
+			continue
+		}
+		if hooksRaw, has := entry["hooks"]; has {
+			if _, ok := hooksRaw.([]any); !ok {
+				continue
+			}
+		}
+		entries[i] = entry
+		slot := entrySlot(entry)
+		targets[i] = slot
+		if known && len(slots) == 1 && slot != slots[0] {
+			targets[i] = slots[0]
+		} else if _, seen := canonical[slot]; !seen {
+			canonical[slot] = i
+		}
+	}
+	isCanonical := func(i int) bool {
+		c, ok := canonical[targets[i]]
+		return ok && c == i
+	}
+
+	seen := make(map[hookSlot]map[string]bool)
+	moved := make(map[hookSlot][]any)
+	kept := make([][]any, len(eventHooks))
+	changes := 0
+	filter := func(i int) {
+		target := targets[i]
+		if seen[target] == nil {
+			seen[target] = make(map[string]bool)
+		}
+		for _, hookAny := range getHooksList(entries[i], eventName, i) {
+			hook, ok := hookAny.(map[string]any)
+			if !ok || !isConfabHookEntry(hook) {
+				kept[i] = append(kept[i], hookAny)
+				continue
+			}
+			key := confabHookKey(hook["command"].(string))
+			switch {
+			case seen[target][key]:
+				changes++
+			case isCanonical(i):
+				kept[i] = append(kept[i], hook)
+			default:
+				moved[target] = append(moved[target], hook)
+				changes++
+			}
+			seen[target][key] = true
+		}
+	}
+	for i := range entries {
+		if entries[i] != nil && isCanonical(i) {
+			filter(i)
+		}
+	}
+	for i := range entries {
+		if entries[i] != nil && !isCanonical(i) {
+			filter(i)
+		}
+	}
+	if changes == 0 {
+		return 0, nil
+	}
+
+	var updated []any
+	for i, entryAny := range eventHooks {
+		entry := entries[i]
+		if entry == nil {
+			updated = append(updated, entryAny)
+			continue
+		}
+		hooksList := kept[i]
+		if isCanonical(i) {
+			hooksList = append(hooksList, moved[targets[i]]...)
+			delete(moved, targets[i])
+		}
+		if len(hooksList) == 0 {
+			if _, has := entry["hooks"]; has {
+				continue
+			}
+			updated = append(updated, entry)
+			continue
+		}
+		entry["hooks"] = hooksList
+		updated = append(updated, entry)
+	}
+	// Stray hooks of an event without a canonical entry get a new one.
+	for _, slot := range slots {
+		if hooksList, ok := moved[slot]; ok {
+			newEntry := map[string]any{"hooks": hooksList}
+			if slot.has {
+				newEntry["matcher"] = slot.matcher
+			}
+			updated = append(updated, newEntry)
+		}
+	}
+	return changes, settings.SetEventHooks(eventName, updated)
+}
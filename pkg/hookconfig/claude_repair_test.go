@@ -0,0 +1,140 @@
+package hookconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// duplicateSessionEndSettings has three confab SessionEnd hooks: two in
+// the "*" matcher (one with a different binary path) and one under a stray
+// "" matcher that also holds a user hook. A user hook, a per-entry field
+// and a top-level key confab doesn't know about must survive the repair.
+const duplicateSessionEndSettings = `{
+  "model": "opus",
+  "hooks": {
+    "SessionEnd": [
+      {"matcher": "*", "timeout": 30, "hooks": [
+        {"type":"command","command":"/usr/local/bin/confab hook session-end --provider claude-code"},
+        {"type":"command","command":"/home/me/.local/bin/confab hook session-end"}
+      ]},
+      {"matcher": "", "hooks": [
+        {"type":"command","command":"notify-send bye"},
+        {"type":"command","command":"/usr/local/bin/confab hook session-end"}
+      ]}
+    ],
+    "SessionStart": [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook session-start"}]}]
+  }
+}`
+
+func TestRepairHooksCollapsesDuplicateSessionEnd(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(settingsPath, []byte(duplicateSessionEndSettings), 0600); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+
+	if n, err := CountHookRepairs(settingsPath); err != nil || n != 2 {
+		t.Fatalf("CountHookRepairs() = %d, %v; want 2", n, err)
+	}
+	n, err := RepairHooks(settingsPath)
+	if err != nil {
+		t.Fatalf("RepairHooks() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("RepairHooks() = %d, want 2 duplicates removed", n)
+	}
+
+	data, err := os.ReadFile(settingsPath)
+	if err != nil {
+		t.Fatalf("read settings: %v", err)
+	}
+	var got struct {
+		Model string `json:"model"`
+		Hooks map[string][]struct {
+			Matcher *string `json:"matcher"`
+			Timeout int     `json:"timeout"`
+			Hooks   []struct {
+				Command string `json:"command"`
+			} `json:"hooks"`
+		} `json:"hooks"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid settings after repair: %v\n%s", err, data)
+	}
+	if got.Model != "opus" {
+		t.Errorf("top-level model = %q, want it preserved", got.Model)
+	}
+
+	var confab []string
+	for _, entry := range got.Hooks["SessionEnd"] {
+		for _, h := range entry.Hooks {
+			if strings.Contains(h.Command, "confab hook session-end") {
+				confab = append(confab, h.Command)
+				if entry.Matcher == nil || *entry.Matcher != "*" || entry.Timeout != 30 {
+					t.Errorf("confab hook kept under matcher %v (timeout %d), want the canonical \"*\" entry", entry.Matcher, entry.Timeout)
+				}
+			}
+		}
+	}
+	if len(confab) != 1 || !strings.HasPrefix(confab[0], "/usr/local/bin/confab") {
+		t.Errorf("confab SessionEnd hooks = %q, want only the first one\n%s", confab, data)
+	}
+	if !strings.Contains(string(data), "notify-send bye") {
+		t.Errorf("user hook dropped by repair\n%s", data)
+	}
+	if len(got.Hooks["SessionStart"]) != 1 {
+		t.Errorf("SessionStart changed: %+v", got.Hooks["SessionStart"])
+	}
+
+	// Repaired settings need no further repair, and aren't rewritten.
+	info, _ := os.Stat(settingsPath)
+	if n, err := RepairHooks(settingsPath); err != nil || n != 0 {
+		t.Errorf("second RepairHooks() = %d, %v; want 0", n, err)
+	}
+	if after, _ := os.Stat(settingsPath); !after.ModTime().Equal(info.ModTime()) {
+		t.Error("RepairHooks rewrote settings with nothing to repair")
+	}
+}
+
+func TestRepairHooksMergesStrayMatcherAndKeepsToolUseSlots(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+	const settings = `{
+  "hooks": {
+    "Stop": [
+      {"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook stop"}]}
+    ],
+    "PreToolUse": [
+      {"matcher": "Bash", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook pre-tool-use"}]},
+      {"matcher": "mcp__github__create_pull_request", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook pre-tool-use"}]},
+      {"matcher": "Bash", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook pre-tool-use"}]}
+    ]
+  }
+}`
+	if err := os.WriteFile(settingsPath, []byte(settings), 0600); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+	// The stray Stop hook moves to a matcher-less entry; the second Bash
+	// entry is a duplicate. The MCP matcher is a separate slot.
+	if n, err := RepairHooks(settingsPath); err != nil || n != 2 {
+		t.Fatalf("RepairHooks() = %d, %v; want 2", n, err)
+	}
+	installed, err := IsStopHookInstalled(settingsPath)
+	if err != nil || !installed {
+		t.Errorf("IsStopHookInstalled() = %v, %v after repair; want true", installed, err)
+	}
+	data, _ := os.ReadFile(settingsPath)
+	var got struct {
+		Hooks map[string][]map[string]any `json:"hooks"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid settings: %v", err)
+	}
+	if stop := got.Hooks["Stop"]; len(stop) != 1 || stop[0]["matcher"] != nil {
+		t.Errorf("Stop entries = %v, want one without a matcher", stop)
+	}
+	if pre := got.Hooks["PreToolUse"]; len(pre) != 2 {
+		t.Errorf("PreToolUse entries = %v, want Bash and the MCP matcher", pre)
+	}
+}
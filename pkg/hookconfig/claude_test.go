@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
 )
 
 // claudeStateDirEnv mirrors pkg/config.ClaudeStateDirEnv and
@@ -131,3 +133,149 @@ func TestIsSyncHooksInstalledRoundTrip(t *testing.T) {
 		t.Fatal("IsSyncHooksInstalled() = false; want true")
 	}
 }
+
+// stopHookSettings has a confab Stop hook next to a user's own Stop hook
+// and a top-level key confab doesn't know about.
+const stopHookSettings = `{
+  "model": "opus",
+  "hooks": {
+    "Stop": [{"hooks": [
+      {"type":"command","command":"/usr/local/bin/confab hook stop"},
+      {"type":"command","command":"notify-send done"}
+    ]}],
+    "SessionStart": [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook session-start"}]}]
+  }
+}`
+
+func TestInstallStopHookWritesSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv(claudeStateDirEnv, tmpDir)
+
+	if err := InstallStopHook(testSettingsPath(t)); err != nil {
+		t.Fatalf("InstallStopHook() error = %v", err)
+	}
+	settings, err := config.ReadSettingsAt(testSettingsPath(t))
+	if err != nil {
+		t.Fatalf("ReadSettingsAt() error = %v", err)
+	}
+	entries := settings.GetEventHooks("Stop")
+	if len(entries) != 1 {
+		t.Fatalf("Stop has %d entries, want 1", len(entries))
+	}
+	entry := entries[0].(map[string]any)
+	if _, has := entry["matcher"]; has {
+		t.Errorf("Stop entry has a matcher: %v", entry)
+	}
+	hook := entry["hooks"].([]any)[0].(map[string]any)
+	if cmd, _ := hook["command"].(string); !strings.HasSuffix(cmd, " hook stop") {
+		t.Errorf("Stop hook command = %q, want it to invoke 'hook stop'", cmd)
+	}
+}
+
+func TestPreCompactHookInstallAndUninstall(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv(claudeStateDirEnv, tmpDir)
+
+	if err := InstallPreCompactHook(testSettingsPath(t)); err != nil {
+		t.Fatalf("InstallPreCompactHook() error = %v", err)
+	}
+	settings, err := config.ReadSettingsAt(testSettingsPath(t))
+	if err != nil {
+		t.Fatalf("ReadSettingsAt() error = %v", err)
+	}
+	entries := settings.GetEventHooks("PreCompact")
+	if len(entries) != 1 {
+		t.Fatalf("PreCompact has %d entries, want 1", len(entries))
+	}
+	entry := entries[0].(map[string]any)
+	if _, has := entry["matcher"]; has {
+		t.Errorf("PreCompact entry has a matcher: %v", entry)
+	}
+	hook := entry["hooks"].([]any)[0].(map[string]any)
+	if cmd, _ := hook["command"].(string); !strings.HasSuffix(cmd, " hook pre-compact") {
+		t.Errorf("PreCompact hook command = %q, want it to invoke 'hook pre-compact'", cmd)
+	}
+
+	// The test binary isn't named confab, so seed a recognizable entry for
+	// uninstall to find.
+	seeded := `{"hooks": {"PreCompact": [{"hooks": [{"type":"command","command":"/usr/local/bin/confab hook pre-compact"}]}]}}`
+	if err := os.WriteFile(testSettingsPath(t), []byte(seeded), 0600); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+	if ok, err := IsPreCompactHookInstalled(testSettingsPath(t)); err != nil || !ok {
+		t.Fatalf("IsPreCompactHookInstalled() = %v, %v; want true", ok, err)
+	}
+	if err := UninstallPreCompactHook(testSettingsPath(t)); err != nil {
+		t.Fatalf("UninstallPreCompactHook() error = %v", err)
+	}
+	if ok, err := IsPreCompactHookInstalled(testSettingsPath(t)); err != nil || ok {
+		t.Errorf("IsPreCompactHookInstalled() = %v, %v after uninstall; want false", ok, err)
+	}
+}
+
+func TestStopHookReinstallIsIdempotent(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv(claudeStateDirEnv, tmpDir)
+	if err := os.WriteFile(testSettingsPath(t), []byte(stopHookSettings), 0600); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+	if ok, err := IsStopHookInstalled(testSettingsPath(t)); err != nil || !ok {
+		t.Fatalf("IsStopHookInstalled() = %v, %v; want true", ok, err)
+	}
+
+	// Re-installing replaces confab's entry in place instead of adding one.
+	if err := InstallStopHook(testSettingsPath(t)); err != nil {
+		t.Fatalf("InstallStopHook() error = %v", err)
+	}
+	data, err := os.ReadFile(testSettingsPath(t))
+	if err != nil {
+		t.Fatalf("read settings: %v", err)
+	}
+	if n := strings.Count(string(data), "hook stop"); n != 1 {
+		t.Errorf("settings.json has %d 'hook stop' entries after re-install, want 1\n%s", n, data)
+	}
+	for _, want := range []string{"notify-send done", `"model": "opus"`, "hook session-start"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("settings.json lost %q after re-install\n%s", want, data)
+		}
+	}
+}
+
+func TestUninstallStopHookRemovesEntry(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv(claudeStateDirEnv, tmpDir)
+	if err := os.WriteFile(testSettingsPath(t), []byte(stopHookSettings), 0600); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+
+	if err := UninstallStopHook(testSettingsPath(t)); err != nil {
+		t.Fatalf("UninstallStopHook() error = %v", err)
+	}
+	if ok, err := IsStopHookInstalled(testSettingsPath(t)); err != nil || ok {
+		t.Errorf("IsStopHookInstalled() = %v, %v after uninstall; want false", ok, err)
+	}
+	data, err := os.ReadFile(testSettingsPath(t))
+	if err != nil {
+		t.Fatalf("read settings: %v", err)
+	}
+	for _, want := range []string{"notify-send done", `"model": "opus"`, "hook session-start"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("settings.json lost %q after uninstall\n%s", want, data)
+		}
+	}
+
+	// With only confab's hook present, the Stop event is dropped entirely.
+	if err := os.WriteFile(testSettingsPath(t), []byte(`{"hooks":{"Stop":[{"hooks":[{"type":"command","command":"/usr/local/bin/confab hook stop"}]}]}}`), 0600); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+	if err := UninstallStopHook(testSettingsPath(t)); err != nil {
+		t.Fatalf("UninstallStopHook() error = %v", err)
+	}
+	settings, err := config.ReadSettingsAt(testSettingsPath(t))
+	if err != nil {
+		t.Fatalf("ReadSettingsAt() error = %v", err)
+	}
+	if entries := settings.GetEventHooks("Stop"); len(entries) != 0 {
+		t.Errorf("Stop still has %d entries after uninstall: %v", len(entries), entries)
+	}
+}
@@ -0,0 +1,163 @@
+package http
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+// ErrCircuitOpen is returned by CircuitBreaker.Allow while the breaker is
+// open: the request is refused without touching the network.
+var ErrCircuitOpen = errors.New("circuit breaker open: backend is failing")
+
+// IsBackendFailure reports whether err means the backend itself is failing
+// — a 5xx response or no response at all — rather than answering this
+// request with a 4xx. Only such errors should count towards opening a
+// CircuitBreaker: a 404 for a deleted session or a 400 for one bad chunk
+// says nothing about the backend's health.
+func IsBackendFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	return true
+}
+
+const (
+	// DefaultCircuitFailureThreshold is how many consecutive failures open
+	// the circuit.
+	DefaultCircuitFailureThreshold = 5
+	// DefaultCircuitOpenDuration is how long an open circuit refuses
+	// requests before allowing a probe.
+	DefaultCircuitOpenDuration = 30 * time.Second
+)
+
+// CircuitBreakerConfig tunes a CircuitBreaker. Zero fields use the defaults.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed requests that
+	// opens the circuit.
+	FailureThreshold int
+	// OpenDuration is how long the circuit stays open before a single
+	// half-open probe is let through.
+	OpenDuration time.Duration
+}
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen refuses every request with ErrCircuitOpen.
+	CircuitOpen
+	// CircuitHalfOpen lets one probe request through; its outcome closes or
+	// re-opens the circuit.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops a client from hammering a backend that keeps failing.
+// After FailureThreshold consecutive failures it opens for OpenDuration,
+// during which Allow returns ErrCircuitOpen. It then goes half-open and
+// allows exactly one probe: success closes the circuit, failure re-opens it.
+// Safe for concurrent use.
+type CircuitBreaker struct {
+	threshold    int
+	openDuration time.Duration
+	now          func() time.Time
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a closed breaker, filling zero config fields
+// with the defaults.
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultCircuitFailureThreshold
+	}
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = DefaultCircuitOpenDuration
+	}
+	return &CircuitBreaker{
+		threshold:    cfg.FailureThreshold,
+		openDuration: cfg.OpenDuration,
+		now:          time.Now,
+	}
+}
+
+// Allow reports whether a request may be sent. It returns ErrCircuitOpen
+// while the circuit is open, and while a half-open probe is already in
+// flight. Every nil return must be followed by exactly one Record call.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.openDuration {
+		b.state = CircuitHalfOpen
+	}
+	switch b.state {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record reports the outcome of a request admitted by Allow.
+func (b *CircuitBreaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = CircuitClosed
+		b.failures = 0
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		if b.state != CircuitOpen {
+			logger.Warnf("Circuit breaker open after %d consecutive failures; pausing requests for %s", b.failures, b.openDuration)
+		}
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+		b.probing = false
+	}
+}
+
+// State returns the current state, reporting an open circuit whose
+// OpenDuration has elapsed as half-open.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.openDuration {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a breaker driven by a fake clock the test advances.
+func newTestBreaker(cfg CircuitBreakerConfig) (*CircuitBreaker, *time.Time) {
+	now := time.Unix(1_700_000_000, 0)
+	b := NewCircuitBreaker(cfg)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestCircuitBreaker_StateMachine(t *testing.T) {
+	b, now := newTestBreaker(CircuitBreakerConfig{OpenDuration: 10 * time.Second})
+
+	fail := func() {
+		t.Helper()
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow() = %v, want nil in state %s", err, b.State())
+		}
+		b.Record(false)
+	}
+
+	// Closed: four failures are tolerated.
+	for i := 0; i < DefaultCircuitFailureThreshold-1; i++ {
+		fail()
+	}
+	if got := b.State(); got != CircuitClosed {
+		t.Fatalf("state after %d failures = %s, want closed", DefaultCircuitFailureThreshold-1, got)
+	}
+
+	// The fifth consecutive failure opens it.
+	fail()
+	if got := b.State(); got != CircuitOpen {
+		t.Fatalf("state after %d failures = %s, want open", DefaultCircuitFailureThreshold, got)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow() while open = %v, want ErrCircuitOpen", err)
+	}
+
+	// After OpenDuration: one probe only.
+	*now = now.Add(10 * time.Second)
+	if got := b.State(); got != CircuitHalfOpen {
+		t.Fatalf("state after OpenDuration = %s, want half-open", got)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe Allow() = %v, want nil", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second Allow() during probe = %v, want ErrCircuitOpen", err)
+	}
+
+	// Failed probe re-opens for a full OpenDuration.
+	b.Record(false)
+	if got := b.State(); got != CircuitOpen {
+		t.Fatalf("state after failed probe = %s, want open", got)
+	}
+	*now = now.Add(9 * time.Second)
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow() before re-open elapsed = %v, want ErrCircuitOpen", err)
+	}
+
+	// Successful probe closes it and resets the failure count.
+	*now = now.Add(time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe Allow() = %v, want nil", err)
+	}
+	b.Record(true)
+	if got := b.State(); got != CircuitClosed {
+		t.Fatalf("state after successful probe = %s, want closed", got)
+	}
+	fail()
+	if got := b.State(); got != CircuitClosed {
+		t.Errorf("one failure after closing reopened the circuit")
+	}
+}
+
+func TestCircuitBreaker_SuccessResetsFailureCount(t *testing.T) {
+	b, _ := newTestBreaker(CircuitBreakerConfig{FailureThreshold: 3})
+	for i := 0; i < 10; i++ {
+		b.Allow()
+		b.Record(i%2 == 0) // never two failures in a row
+	}
+	if got := b.State(); got != CircuitClosed {
+		t.Errorf("state = %s, want closed with no consecutive failures", got)
+	}
+}
+
+func TestNewCircuitBreaker_Defaults(t *testing.T) {
+	b := NewCircuitBreaker(CircuitBreakerConfig{})
+	if b.threshold != DefaultCircuitFailureThreshold {
+		t.Errorf("threshold = %d, want %d", b.threshold, DefaultCircuitFailureThreshold)
+	}
+	if b.openDuration != DefaultCircuitOpenDuration {
+		t.Errorf("openDuration = %s, want %s", b.openDuration, DefaultCircuitOpenDuration)
+	}
+}
+
+func TestIsBackendFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"success", nil, false},
+		{"server error", &StatusError{StatusCode: 502}, true},
+		{"not found", &StatusError{StatusCode: 404, sentinel: ErrSessionNotFound}, false},
+		{"bad request", fmt.Errorf("chunk upload failed: %w", &StatusError{StatusCode: 400}), false},
+		{"transport error", errors.New("connection refused"), true},
+	}
+	for _, tt := range tests {
+		if got := IsBackendFailure(tt.err); got != tt.want {
+			t.Errorf("%s: IsBackendFailure = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
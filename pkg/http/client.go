@@ -2,15 +2,19 @@ package http
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
@@ -28,12 +32,26 @@ const (
 	maxRetryAfterSeconds = 3600
 
 	// Retry settings for rate limiting
-	maxRetries        = 5
 	initialBackoff    = 1 * time.Second
 	maxBackoff        = 60 * time.Second
 	backoffMultiplier = 2.0
 )
 
+// maxRetries is how many times a 429 response is retried before the
+// request gives up with ErrRateLimited. A var (not const) so tests can
+// lower it via SetMaxRetriesForTest.
+var maxRetries = 5
+
+// SetMaxRetriesForTest temporarily changes how many times 429 responses are
+// retried, so tests can see ErrRateLimited without sitting through the
+// backoff. Returns a restore function that callers should defer.
+// Intended for test code only — do not call from production.
+func SetMaxRetriesForTest(n int) (restore func()) {
+	prev := maxRetries
+	maxRetries = n
+	return func() { maxRetries = prev }
+}
+
 // maxResponseSize is the maximum size of an HTTP response body we'll read.
 // Prevents OOM from malicious or buggy servers sending unbounded responses.
 // It is a var (not const) so tests can lower it via
@@ -71,9 +89,10 @@ func BuildUserAgent(version string) string {
 // This typically means the API key is invalid or expired.
 var ErrUnauthorized = errors.New("unauthorized")
 
-// errRateLimited is returned when retries are exhausted on 429 responses.
-// Internal only — no callers currently check for this sentinel.
-var errRateLimited = errors.New("rate limited")
+// ErrRateLimited is returned when the server still answers 429 after the
+// client's own retries. It arrives wrapped in a *StatusError whose
+// RetryAfter says how long the server asked us to wait; see RetryAfter.
+var ErrRateLimited = errors.New("rate limited")
 
 // ErrSessionNotFound is returned when the server returns 404.
 // This typically means the session was deleted from the backend.
@@ -83,37 +102,102 @@ var ErrSessionNotFound = errors.New("session not found")
 // This typically means the resource already exists (e.g., duplicate link).
 var ErrConflict = errors.New("conflict")
 
+// ErrTimeout is returned when a request attempt runs past the client's
+// timeout, e.g. a backend that accepts the connection but never answers.
+// It wraps the transport error, so the failure stays retryable.
+var ErrTimeout = errors.New("request timed out")
+
+// wrapTimeout marks err with ErrTimeout when it is a deadline or network
+// timeout; other errors are returned unchanged.
+func wrapTimeout(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
+
 // Client is a configured HTTP client for making authenticated requests to the backend
 type Client struct {
 	cfg        *config.UploadConfig
 	httpClient *http.Client
-	encoder    *zstd.Encoder
+	// apiKey is the bearer token, initially cfg.APIKey; SetAPIKey replaces
+	// it when a token is refreshed. Guarded by apiKeyMu.
+	apiKeyMu sync.RWMutex
+	apiKey   string
+	// compression is the request body codec (config.CompressionZstd,
+	// CompressionGzip or CompressionNone).
+	compression string
+	encoder     *zstd.Encoder // set only for zstd
+	// observe, if set, is told about every request sent (see
+	// SetRequestObserver).
+	observe RequestObserver
 }
 
-// NewClient creates a new authenticated HTTP client
+// RequestObserver is called after each HTTP request a Client sends, retries
+// included, with the request's method and path, the response status code
+// (0 when no response arrived) and the time until the response headers.
+type RequestObserver func(method, path string, statusCode int, elapsed time.Duration)
+
+// SetRequestObserver installs fn to be told about every request (nil
+// removes it). Call it before the client is shared; it is not guarded.
+func (c *Client) SetRequestObserver(fn RequestObserver) {
+	c.observe = fn
+}
+
+// send executes req and reports it to the request observer, if any.
+func (c *Client) send(req *http.Request, path string) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if c.observe != nil {
+		statusCode := 0
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		c.observe(req.Method, path, statusCode, time.Since(start))
+	}
+	return resp, err
+}
+
+// NewClient creates a new authenticated HTTP client using the default zstd
+// compression level.
 func NewClient(cfg *config.UploadConfig, timeout time.Duration) (*Client, error) {
-	// Create zstd encoder with default compression level (good balance of speed/ratio)
-	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	return NewClientWithCompressionLevel(cfg, timeout, 0)
+}
+
+// NewClientWithCompressionLevel is NewClient with an explicit zstd level for
+// request bodies: 0 keeps the default, 1–11 map onto zstd's speed presets
+// via zstd.EncoderLevelFromZstd (1–2 fastest, 3–5 default, 6–9 better,
+// 10–11 best). Out-of-range levels are rejected. The codec itself comes
+// from cfg.Compression; the level is ignored unless it is zstd.
+func NewClientWithCompressionLevel(cfg *config.UploadConfig, timeout time.Duration, compressionLevel int) (*Client, error) {
+	if err := config.ValidateCompressionLevel(compressionLevel); err != nil {
+		return nil, fmt.Errorf("invalid compression level: %w", err)
+	}
+	if err := config.ValidateCompression(cfg.Compression); err != nil {
+		return nil, fmt.Errorf("invalid compression: %w", err)
+	}
+	compression := cfg.Compression
+	if compression == "" {
+		compression = config.CompressionZstd
 	}
 
-	// Configure transport with TLS minimum version for non-localhost URLs.
-	//
-	// Security note: For localhost URLs (http://localhost, http://127.0.0.1,
-	// http://[::1]), we use the default transport without TLS enforcement.
-	// This is intentional for local development where developers run a local
-	// backend server. Production traffic always goes through HTTPS with TLS 1.2+.
-	// Localhost connections stay on the local machine and don't traverse networks.
-	var transport http.RoundTripper
-	if !isLocalhost(cfg.BackendURL) {
-		transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				MinVersion: tls.VersionTLS12,
-			},
+	var encoder *zstd.Encoder
+	if compression == config.CompressionZstd {
+		level := zstd.SpeedDefault // good balance of speed/ratio
+		if compressionLevel != 0 {
+			level = zstd.EncoderLevelFromZstd(compressionLevel)
 		}
-	} else {
-		logger.Debug("Using localhost backend URL - TLS not enforced")
+		var err error
+		encoder, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(level))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+	}
+
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Client{
@@ -122,10 +206,94 @@ func NewClient(cfg *config.UploadConfig, timeout time.Duration) (*Client, error)
 			Timeout:   timeout,
 			Transport: transport,
 		},
-		encoder: encoder,
+		apiKey:      cfg.APIKey,
+		compression: compression,
+		encoder:     encoder,
 	}, nil
 }
 
+// APIKey returns the bearer token sent with requests.
+func (c *Client) APIKey() string {
+	c.apiKeyMu.RLock()
+	defer c.apiKeyMu.RUnlock()
+	return c.apiKey
+}
+
+// SetAPIKey replaces the bearer token for subsequent requests, e.g. after
+// a token refresh. Safe to call while requests are in flight.
+func (c *Client) SetAPIKey(apiKey string) {
+	c.apiKeyMu.Lock()
+	c.apiKey = apiKey
+	c.apiKeyMu.Unlock()
+}
+
+// NewTransport builds the transport for backend requests: a clone of
+// http.DefaultTransport with TLS 1.2+ enforced for non-localhost backends,
+// cfg.CACertFile trusted alongside the system roots, and the proxy chosen by
+// cfg.ProxyURL, or by HTTP_PROXY/HTTPS_PROXY/NO_PROXY when that is empty.
+//
+// Security note: For localhost URLs (http://localhost, http://127.0.0.1,
+// http://[::1]), TLS is not enforced. This is intentional for local
+// development where developers run a local backend server. Production traffic
+// always goes through HTTPS with TLS 1.2+. Localhost connections stay on the
+// local machine and don't traverse networks.
+func NewTransport(cfg *config.UploadConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{}
+	if !isLocalhost(cfg.BackendURL) {
+		tlsConfig.MinVersion = tls.VersionTLS12
+	} else {
+		logger.Debug("Using localhost backend URL - TLS not enforced")
+	}
+	if cfg.CACertFile != "" {
+		pool, err := config.LoadCACertPool(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CA cert file: %w", err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.TLSSkipVerify {
+		logger.Warn("tls_skip_verify is set: backend TLS certificates are NOT verified")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	// An explicit proxy applies to every request (including localhost);
+	// the environment fallback follows NO_PROXY and skips localhost.
+	proxyURL, err := config.ParseProxyURL(cfg.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	} else {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	return transport, nil
+}
+
+// compress encodes payload with the client's codec, returning the encoded
+// bytes and the Content-Encoding to send ("" when uncompressed).
+func (c *Client) compress(payload []byte) ([]byte, string, error) {
+	switch c.compression {
+	case config.CompressionGzip:
+		var buf bytes.Buffer
+		buf.Grow(len(payload) / 2)
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			return nil, "", err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "gzip", nil
+	case config.CompressionNone:
+		return payload, "", nil
+	default:
+		return c.encoder.EncodeAll(payload, make([]byte, 0, len(payload)/2)), "zstd", nil
+	}
+}
+
 // isLocalhost checks if the URL points to localhost.
 // Used to determine if TLS enforcement should be skipped for local development.
 func isLocalhost(url string) bool {
@@ -136,30 +304,73 @@ func isLocalhost(url string) bool {
 
 // DoJSON performs an HTTP request with JSON body and parses JSON response
 // Automatically sets Content-Type, Authorization, and handles error responses.
-// Payloads larger than 1KB are compressed with zstd.
+// Payloads larger than 1KB are compressed with the configured codec (zstd
+// by default).
 // Retries with exponential backoff on 429 (rate limited) responses.
 func (c *Client) DoJSON(method, path string, reqBody, respBody interface{}) error {
+	return c.doJSON(method, path, reqBody, respBody, nil, nil)
+}
+
+// PostWithBodyWrapper is Post with the (possibly compressed) request body
+// passed through wrapBody on every attempt, e.g. to rate-limit the upload.
+func (c *Client) PostWithBodyWrapper(path string, reqBody, respBody interface{}, wrapBody func(io.Reader) io.Reader) error {
+	return c.doJSON("POST", path, reqBody, respBody, wrapBody, nil)
+}
+
+// PostWithHeaders is PostWithBodyWrapper with extra request headers, set
+// on every attempt (e.g. an idempotency key that must survive retries).
+func (c *Client) PostWithHeaders(path string, headers map[string]string, reqBody, respBody interface{}, wrapBody func(io.Reader) io.Reader) error {
+	return c.doJSON("POST", path, reqBody, respBody, wrapBody, headers)
+}
+
+// EncodeJSON marshals reqBody and compresses it as doJSON would, returning
+// the body bytes and their Content-Encoding ("" when uncompressed). Used
+// to split one request body across several PostBytes requests.
+func (c *Client) EncodeJSON(reqBody interface{}) ([]byte, string, error) {
+	payload, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if len(payload) < compressionThreshold {
+		return payload, "", nil
+	}
+	payload, contentEncoding, err := c.compress(payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to compress request: %w", err)
+	}
+	return payload, contentEncoding, nil
+}
+
+// PostBytes POSTs body as-is (Content-Type application/octet-stream) and
+// parses a JSON response, with doJSON's headers, 429 retries and error
+// mapping. body is passed through wrapBody on every attempt.
+func (c *Client) PostBytes(path string, headers map[string]string, body []byte, respBody interface{}, wrapBody func(io.Reader) io.Reader) error {
+	logger.Debug("HTTP POST", "path", path, "body_bytes", len(body))
+	return c.do("POST", path, body, "application/octet-stream", "", respBody, wrapBody, headers)
+}
+
+func (c *Client) doJSON(method, path string, reqBody, respBody interface{}, wrapBody func(io.Reader) io.Reader, headers map[string]string) error {
 	// Marshal and compress request body once (for retries)
 	var payload []byte
-	var contentEncoding string
+	var contentType, contentEncoding string
 
 	if reqBody != nil {
 		var err error
-		payload, err = json.Marshal(reqBody)
+		payload, contentEncoding, err = c.EncodeJSON(reqBody)
 		if err != nil {
-			return fmt.Errorf("failed to marshal request: %w", err)
+			return err
 		}
+		contentType = "application/json"
 
 		// Log request metadata at debug level (never log payload — it contains transcript content)
-		logger.Debug("HTTP %s %s payload_bytes=%d", method, path, len(payload))
-
-		// Compress if payload is large enough
-		if len(payload) >= compressionThreshold {
-			payload = c.encoder.EncodeAll(payload, make([]byte, 0, len(payload)/2))
-			contentEncoding = "zstd"
-		}
+		logger.Debug("HTTP request", "method", method, "path", path, "payload_bytes", len(payload))
 	}
+	return c.do(method, path, payload, contentType, contentEncoding, respBody, wrapBody, headers)
+}
 
+// do sends payload (nil for no body), retrying 429s with backoff, and
+// parses a JSON response into respBody.
+func (c *Client) do(method, path string, payload []byte, contentType, contentEncoding string, respBody interface{}, wrapBody func(io.Reader) io.Reader, headers map[string]string) error {
 	url := c.cfg.BackendURL + path
 	backoff := initialBackoff
 
@@ -168,51 +379,66 @@ func (c *Client) DoJSON(method, path string, reqBody, respBody interface{}) erro
 		var bodyReader io.Reader
 		if payload != nil {
 			bodyReader = bytes.NewReader(payload)
+			if wrapBody != nil {
+				bodyReader = wrapBody(bodyReader)
+			}
 		}
 
-		// Create request
-		req, err := http.NewRequest(method, url, bodyReader)
+		// Create request. Each attempt gets its own deadline (alongside
+		// http.Client.Timeout), so a hung connection can't hold up a sync
+		// cycle past the timeout.
+		ctx, cancel := c.attemptContext()
+		req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 		if err != nil {
+			cancel()
 			return fmt.Errorf("failed to create request: %w", err)
 		}
+		// A wrapped body hides its length from NewRequest; keep a fixed
+		// Content-Length rather than falling back to chunked encoding.
+		if payload != nil {
+			req.ContentLength = int64(len(payload))
+		}
 
 		// Set headers
-		if reqBody != nil {
-			req.Header.Set("Content-Type", "application/json")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
 			if contentEncoding != "" {
 				req.Header.Set("Content-Encoding", contentEncoding)
 			}
 		}
-		req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+		req.Header.Set("Authorization", "Bearer "+c.APIKey())
 		if userAgent != "" {
 			req.Header.Set("User-Agent", userAgent)
 		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
 
 		// Execute request
-		resp, err := c.httpClient.Do(req)
+		resp, err := c.send(req, path)
 		if err != nil {
-			return fmt.Errorf("failed to send request: %w", err)
+			cancel()
+			return fmt.Errorf("failed to send request: %w", wrapTimeout(err))
 		}
 
 		// Read response body (bounded to prevent OOM from malicious servers)
 		body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
 		resp.Body.Close()
+		cancel()
 		if err != nil {
-			return fmt.Errorf("failed to read response body: %w", err)
+			return fmt.Errorf("failed to read response body: %w", wrapTimeout(err))
 		}
 
 		// Handle rate limiting with retry
 		if resp.StatusCode == http.StatusTooManyRequests {
 			if attempt == maxRetries {
-				return fmt.Errorf("%w: exceeded %d retries", errRateLimited, maxRetries)
+				return mapStatusToError(resp, fmt.Sprintf("exceeded %d retries", maxRetries))
 			}
 
 			// Use Retry-After header if provided, otherwise use exponential backoff
 			waitTime := backoff
-			if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
-				if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 && seconds <= maxRetryAfterSeconds {
-					waitTime = time.Duration(seconds) * time.Second
-				}
+			if retryAfter := parseRetryAfter(resp.Header); retryAfter > 0 {
+				waitTime = retryAfter
 			}
 
 			time.Sleep(waitTime)
@@ -227,7 +453,7 @@ func (c *Client) DoJSON(method, path string, reqBody, respBody interface{}) erro
 
 		// Accept any 2xx status code as success
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return mapStatusToError(resp.StatusCode, truncateBody(body, 256))
+			return mapStatusToError(resp, truncateBody(body, 256))
 		}
 
 		// Parse response if requested
@@ -242,6 +468,15 @@ func (c *Client) DoJSON(method, path string, reqBody, respBody interface{}) erro
 	panic("unreachable: retry loop exited without returning")
 }
 
+// attemptContext returns the context for one request attempt: bounded by
+// the client's timeout, when it has one.
+func (c *Client) attemptContext() (context.Context, context.CancelFunc) {
+	if c.httpClient.Timeout > 0 {
+		return context.WithTimeout(context.Background(), c.httpClient.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
 // Get performs a GET request with JSON response parsing
 func (c *Client) Get(path string, respBody interface{}) error {
 	return c.DoJSON("GET", path, nil, respBody)
@@ -257,6 +492,11 @@ func (c *Client) Patch(path string, reqBody, respBody interface{}) error {
 	return c.DoJSON("PATCH", path, reqBody, respBody)
 }
 
+// Delete performs a DELETE request with JSON response parsing
+func (c *Client) Delete(path string, respBody interface{}) error {
+	return c.DoJSON("DELETE", path, nil, respBody)
+}
+
 // GetRawToWriter performs a GET request and streams the response body to w.
 // Unlike Get/DoJSON, this does not attempt JSON unmarshaling — it copies the
 // raw response bytes directly. Handles auth and error status mapping, but does
@@ -270,12 +510,12 @@ func (c *Client) GetRawToWriter(path string, w io.Writer) error {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	req.Header.Set("Authorization", "Bearer "+c.APIKey())
 	if userAgent != "" {
 		req.Header.Set("User-Agent", userAgent)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.send(req, path)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -301,26 +541,81 @@ func checkStreamResponseStatus(resp *http.Response) error {
 
 	// 429 returns immediately without reading body — the caller may retry elsewhere
 	if resp.StatusCode == http.StatusTooManyRequests {
-		return fmt.Errorf("%w: status %d", errRateLimited, resp.StatusCode)
+		return mapStatusToError(resp, "")
 	}
 
 	// Read a snippet of the body for error context
 	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
 
-	return mapStatusToError(resp.StatusCode, string(snippet))
+	return mapStatusToError(resp, string(snippet))
+}
+
+// StatusError is a non-2xx response. It unwraps to ErrUnauthorized,
+// ErrSessionNotFound, ErrConflict or ErrRateLimited when one applies, so errors.Is checks
+// work unchanged; callers that retry can inspect StatusCode and RetryAfter.
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the response's Retry-After delay (0 if absent or
+	// invalid), capped at maxRetryAfterSeconds.
+	RetryAfter time.Duration
+	sentinel   error
+	body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.sentinel != nil {
+		if e.body == "" {
+			return fmt.Sprintf("%v: status %d", e.sentinel, e.StatusCode)
+		}
+		return fmt.Sprintf("%v: status %d: %s", e.sentinel, e.StatusCode, e.body)
+	}
+	return fmt.Sprintf("http request failed with status %d: %s", e.StatusCode, e.body)
 }
 
-func mapStatusToError(status int, body string) error {
-	switch status {
+func (e *StatusError) Unwrap() error { return e.sentinel }
+
+// mapStatusToError wraps a non-2xx response in a StatusError.
+func mapStatusToError(resp *http.Response, body string) error {
+	err := &StatusError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header), body: body}
+	switch resp.StatusCode {
 	case http.StatusUnauthorized, http.StatusForbidden:
-		return fmt.Errorf("%w: status %d: %s", ErrUnauthorized, status, body)
+		err.sentinel = ErrUnauthorized
 	case http.StatusNotFound:
-		return fmt.Errorf("%w: status %d: %s", ErrSessionNotFound, status, body)
+		err.sentinel = ErrSessionNotFound
 	case http.StatusConflict:
-		return fmt.Errorf("%w: status %d: %s", ErrConflict, status, body)
-	default:
-		return fmt.Errorf("http request failed with status %d: %s", status, body)
+		err.sentinel = ErrConflict
+	case http.StatusTooManyRequests:
+		err.sentinel = ErrRateLimited
+	}
+	return err
+}
+
+// RetryAfter returns the Retry-After delay carried by err when it is a
+// rate-limited (429) response, or 0 when it isn't one or the server didn't
+// say how long to wait.
+func RetryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as
+// an HTTP-date. Returns 0 when it is absent, invalid, not in the future, or
+// more than maxRetryAfterSeconds away.
+func parseRetryAfter(h http.Header) time.Duration {
+	value := h.Get("Retry-After")
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = time.Until(at)
+	}
+	if wait <= 0 || wait > maxRetryAfterSeconds*time.Second {
+		return 0
 	}
+	return wait
 }
 
 // truncateBody truncates a response body for safe inclusion in error messages.
@@ -2,13 +2,22 @@ package http
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/klauspost/compress/zstd"
@@ -144,6 +153,53 @@ func TestClient_CompressionRatio(t *testing.T) {
 	}
 }
 
+func TestClient_CompressionLevel(t *testing.T) {
+	var receivedBody []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "test-key"}
+
+	lines := make([]string, 200)
+	for i := range lines {
+		lines[i] = strings.Repeat(`{"type":"user","message":"compress me"}`, 3)
+	}
+	payload := map[string]interface{}{"lines": lines}
+	originalJSON, _ := json.Marshal(payload)
+
+	for _, level := range []int{1, 3, 9, 11} {
+		client, err := NewClientWithCompressionLevel(cfg, 0, level)
+		if err != nil {
+			t.Fatalf("level %d: failed to create client: %v", level, err)
+		}
+		var resp struct{ Ok bool }
+		if err := client.Post("/test", payload, &resp); err != nil {
+			t.Fatalf("level %d: request failed: %v", level, err)
+		}
+
+		decoder, _ := zstd.NewReader(nil)
+		decompressed, err := decoder.DecodeAll(receivedBody, nil)
+		decoder.Close()
+		if err != nil {
+			t.Fatalf("level %d: failed to decompress: %v", level, err)
+		}
+		if !bytes.Equal(decompressed, originalJSON) {
+			t.Errorf("level %d: decompressed body does not match original", level)
+		}
+	}
+
+	for _, level := range []int{-1, 12} {
+		if _, err := NewClientWithCompressionLevel(cfg, 0, level); err == nil {
+			t.Errorf("expected error for compression level %d", level)
+		}
+	}
+}
+
 func TestBuildUserAgent(t *testing.T) {
 	t.Run("with version", func(t *testing.T) {
 		ua := BuildUserAgent("1.2.3")
@@ -197,6 +253,35 @@ func TestClient_RetryOn429(t *testing.T) {
 	}
 }
 
+func TestClient_RequestObserverSeesEveryAttempt(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.UploadConfig{BackendURL: server.URL, APIKey: "test-key"}, 0)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	var seen []string
+	client.SetRequestObserver(func(method, path string, statusCode int, elapsed time.Duration) {
+		seen = append(seen, fmt.Sprintf("%s %s %d", method, path, statusCode))
+	})
+	if err := client.Post("/test", map[string]string{}, nil); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if got, want := strings.Join(seen, ", "), "POST /test 429, POST /test 200"; got != want {
+		t.Errorf("observed %q, want %q", got, want)
+	}
+}
+
 func TestClient_RetryExhausted(t *testing.T) {
 	attempts := 0
 
@@ -221,8 +306,8 @@ func TestClient_RetryExhausted(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error after exhausted retries")
 	}
-	if !errors.Is(err, errRateLimited) {
-		t.Errorf("expected errRateLimited, got: %v", err)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got: %v", err)
 	}
 	// maxRetries+1 attempts total (0..maxRetries inclusive)
 	if attempts != maxRetries+1 {
@@ -230,6 +315,64 @@ func TestClient_RetryExhausted(t *testing.T) {
 	}
 }
 
+func TestClient_RateLimitedCarriesRetryAfter(t *testing.T) {
+	defer SetMaxRetriesForTest(0)()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "42")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.UploadConfig{
+		BackendURL: server.URL,
+		APIKey:     "test-key",
+	}, 0)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	err = client.Get("/test", nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got: %v", err)
+	}
+	if got := RetryAfter(err); got != 42*time.Second {
+		t.Errorf("RetryAfter = %v, want 42s", got)
+	}
+	if got := RetryAfter(errors.New("other")); got != 0 {
+		t.Errorf("RetryAfter(non-429) = %v, want 0", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"absent", "", 0, 0},
+		{"seconds", "30", 30 * time.Second, 30 * time.Second},
+		{"zero", "0", 0, 0},
+		{"negative", "-5", 0, 0},
+		{"above cap", "7200", 0, 0},
+		{"garbage", "soon", 0, 0},
+		{"http-date", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{"http-date in the past", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.value != "" {
+				h.Set("Retry-After", tt.value)
+			}
+			if got := parseRetryAfter(h); got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
 func TestClient_GetRawToWriter(t *testing.T) {
 	t.Run("streams response to writer", func(t *testing.T) {
 		want := "line1\nline2\nline3\n"
@@ -313,7 +456,7 @@ func TestClient_GetRawToWriter(t *testing.T) {
 		}
 	})
 
-	t.Run("returns errRateLimited on 429 without retry", func(t *testing.T) {
+	t.Run("returns ErrRateLimited on 429 without retry", func(t *testing.T) {
 		attempts := 0
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			attempts++
@@ -335,8 +478,8 @@ func TestClient_GetRawToWriter(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected error for 429")
 		}
-		if !errors.Is(err, errRateLimited) {
-			t.Errorf("expected errRateLimited, got: %v", err)
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("expected ErrRateLimited, got: %v", err)
 		}
 		if attempts != 1 {
 			t.Errorf("expected exactly 1 attempt (no retry), got %d", attempts)
@@ -436,6 +579,61 @@ func TestClient_Patch(t *testing.T) {
 	}
 }
 
+func TestClient_PostWithHeaders(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Idempotency-Key")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.UploadConfig{BackendURL: server.URL, APIKey: "k"}, 0)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := client.PostWithHeaders("/api/v1/things", map[string]string{"X-Idempotency-Key": "abc"}, map[string]string{"k": "v"}, nil, nil); err != nil {
+		t.Fatalf("PostWithHeaders: %v", err)
+	}
+	if got != "abc" {
+		t.Errorf("X-Idempotency-Key = %q, want abc", got)
+	}
+}
+
+func TestClient_PostWithBodyWrapper(t *testing.T) {
+	var contentLength int64
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.UploadConfig{BackendURL: server.URL, APIKey: "k"}, 0)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	wrapped := 0
+	wrap := func(r io.Reader) io.Reader {
+		wrapped++
+		return io.MultiReader(r) // hides the concrete reader's length
+	}
+	if err := client.PostWithBodyWrapper("/api/v1/things", map[string]string{"k": "v"}, nil, wrap); err != nil {
+		t.Fatalf("PostWithBodyWrapper: %v", err)
+	}
+	if wrapped != 1 {
+		t.Errorf("wrapper called %d times, want 1", wrapped)
+	}
+	if received["k"] != "v" {
+		t.Errorf("body[k] = %q, want v", received["k"])
+	}
+	if contentLength != int64(len(`{"k":"v"}`)) {
+		t.Errorf("Content-Length = %d, want %d", contentLength, len(`{"k":"v"}`))
+	}
+}
+
 func TestClient_SetUserAgent(t *testing.T) {
 	var receivedUA string
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -514,3 +712,239 @@ func TestClient_MaxResponseSize(t *testing.T) {
 		t.Errorf("expected error decoding truncated response, got nil; resp = %+v", resp)
 	}
 }
+
+// newForwardProxy starts a plain HTTP forward proxy that relays
+// absolute-URI requests to their target and counts them.
+func newForwardProxy(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.IsAbs() {
+			http.Error(w, "not a proxy request", http.StatusBadRequest)
+			return
+		}
+		hits.Add(1)
+		out := r.Clone(r.Context())
+		out.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(out)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, &hits
+}
+
+// newSOCKS5Proxy starts a minimal no-auth SOCKS5 server supporting CONNECT
+// and counts the connections it relays.
+func newSOCKS5Proxy(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var hits atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				target, err := socks5Handshake(conn)
+				if err != nil {
+					return
+				}
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer upstream.Close()
+				hits.Add(1)
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return "socks5://" + ln.Addr().String(), &hits
+}
+
+// socks5Handshake reads the greeting and CONNECT request and returns the
+// target address.
+func socks5Handshake(conn net.Conn) (string, error) {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return "", err
+	}
+	if _, err := io.ReadFull(conn, make([]byte, hdr[1])); err != nil {
+		return "", err
+	}
+	conn.Write([]byte{5, 0}) // no auth
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return "", err
+	}
+	var host string
+	switch req[3] {
+	case 1: // IPv4
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case 3: // domain
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return "", err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		return "", errors.New("unsupported address type")
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1]))), nil
+}
+
+func TestClient_ProxyURL(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer backend.Close()
+
+	httpProxy, httpHits := newForwardProxy(t)
+	socksProxy, socksHits := newSOCKS5Proxy(t)
+
+	for _, tt := range []struct {
+		name     string
+		proxyURL string
+		hits     *atomic.Int32
+	}{
+		{"http", httpProxy.URL, httpHits},
+		{"socks5", socksProxy, socksHits},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(&config.UploadConfig{
+				BackendURL: backend.URL,
+				APIKey:     "test-key",
+				ProxyURL:   tt.proxyURL,
+			}, 0)
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			var resp struct{ Ok bool }
+			if err := client.Get("/test", &resp); err != nil {
+				t.Fatalf("Get through proxy: %v", err)
+			}
+			if !resp.Ok {
+				t.Error("expected backend response through proxy")
+			}
+			if got := tt.hits.Load(); got != 1 {
+				t.Errorf("proxy relayed %d requests, want 1", got)
+			}
+		})
+	}
+}
+
+func TestClient_InvalidProxyURL(t *testing.T) {
+	_, err := NewClient(&config.UploadConfig{
+		BackendURL: "https://confab.dev",
+		ProxyURL:   "ftp://proxy.corp:21",
+	}, 0)
+	if err == nil {
+		t.Fatal("expected NewClient to reject an ftp:// proxy")
+	}
+}
+
+func TestNewTransport_DefaultsToEnvironmentProxy(t *testing.T) {
+	transport, err := NewTransport(&config.UploadConfig{BackendURL: "https://confab.dev"})
+	if err != nil {
+		t.Fatalf("NewTransport: %v", err)
+	}
+	if transport.Proxy == nil {
+		t.Fatal("expected Proxy to fall back to http.ProxyFromEnvironment, got nil")
+	}
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Error("expected TLS 1.2 minimum for a non-localhost backend")
+	}
+}
+
+// writeServerCA writes server's self-signed certificate to a PEM file and
+// returns its path.
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestClient_CACertFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+	caFile := writeServerCA(t, server)
+
+	for _, tt := range []struct {
+		name    string
+		cfg     config.UploadConfig
+		wantErr bool
+	}{
+		{"system roots reject self-signed", config.UploadConfig{}, true},
+		{"ca_cert_file trusts self-signed", config.UploadConfig{CACertFile: caFile}, false},
+		{"tls_skip_verify", config.UploadConfig{TLSSkipVerify: true}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.BackendURL = server.URL
+			cfg.APIKey = "test-key"
+			client, err := NewClient(&cfg, 0)
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			var resp struct{ Ok bool }
+			err = client.Get("/test", &resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !resp.Ok {
+				t.Error("expected backend response")
+			}
+		})
+	}
+}
+
+func TestClient_InvalidCACertFile(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0600)
+
+	for _, path := range []string{notPEM, filepath.Join(t.TempDir(), "missing.pem")} {
+		_, err := NewClient(&config.UploadConfig{BackendURL: "https://confab.dev", CACertFile: path}, 0)
+		if err == nil {
+			t.Errorf("expected NewClient to reject CA cert file %s", path)
+		}
+	}
+}
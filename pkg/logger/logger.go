@@ -1,11 +1,14 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -16,12 +19,15 @@ import (
 
 const (
 	// LogDirEnv is the environment variable to override the default log directory
-	LogDirEnv   = "CONFAB_LOG_DIR"
-	logFileName = "confab.log"
-	maxSizeMB   = 1    // 1MB per file
-	maxAgeDays  = 14   // Keep 2 weeks
-	maxBackups  = 20   // Max old log files (safety limit)
-	compressOld = true // Compress rotated logs
+	LogDirEnv = "CONFAB_LOG_DIR"
+	// LogFormatEnv selects the output format ("text" or "json") when the
+	// --log-format flag isn't given.
+	LogFormatEnv = "LOG_FORMAT"
+	logFileName  = "confab.log"
+	maxSizeMB    = 1    // 1MB per file (default; see SetRotation)
+	maxAgeDays   = 14   // Keep 2 weeks
+	maxBackups   = 20   // Max old log files (default; see SetRotation)
+	compressOld  = true // Compress rotated logs
 )
 
 // Level represents the log level
@@ -49,14 +55,47 @@ func (l Level) String() string {
 	}
 }
 
+// Format is the log line encoding.
+type Format int
+
+const (
+	// FormatText is "[time] [ctx] LEVEL: msg key=value ...", for humans.
+	FormatText Format = iota
+	// FormatJSON is one JSON object per line with time, level, msg and any
+	// fields (component and error among them), for log ingestion.
+	FormatJSON
+)
+
+// ParseFormat parses "text" or "json" (case-insensitive); empty is text.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "text":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return FormatText, fmt.Errorf("invalid log format %q: must be text or json", s)
+	}
+}
+
 // Logger manages logging to file and optionally stderr
 type Logger struct {
 	file       io.WriteCloser
 	logger     *log.Logger
 	level      Level
+	format     Format
 	mu         sync.Mutex
 	alsoStderr bool   // Also write to stderr
 	sessionCtx string // Session context prefix (e.g., "session=abc123")
+	// externalID and sessionID are the full IDs behind sessionCtx, emitted
+	// as separate fields in JSON format.
+	externalID string
+	sessionID  string
+
+	// parent and fields are set on loggers returned by With: they
+	// write through parent, adding fields to every line.
+	parent *Logger
+	fields map[string]any
 }
 
 var (
@@ -150,6 +189,20 @@ func Init() error {
 	return err
 }
 
+// FilePath returns the path of the current log file: confab.log under
+// CONFAB_LOG_DIR, or ~/.confab/logs. Rotated backups sit alongside it.
+func FilePath() (string, error) {
+	logDir := os.Getenv(LogDirEnv)
+	if logDir == "" {
+		dir, err := confabpath.Subpath("logs")
+		if err != nil {
+			return "", err
+		}
+		logDir = dir
+	}
+	return filepath.Join(logDir, logFileName), nil
+}
+
 // Get returns the logger instance (initializes if needed)
 func Get() *Logger {
 	if instance == nil {
@@ -173,8 +226,57 @@ func Close() error {
 	return nil
 }
 
+// root returns the logger that owns the output: l itself, or the logger
+// With was called on.
+func (l *Logger) root() *Logger {
+	if l.parent != nil {
+		return l.parent
+	}
+	return l
+}
+
+// With returns a logger that adds the key/value pairs in keyvals (as for
+// Info) to every line it writes, on top of any fields l already carries.
+// Output, level, format and session context stay shared with l.
+//
+// Derive loggers where they're used rather than caching them in package
+// variables: tests reset the singleton they write through.
+func (l *Logger) With(keyvals ...any) *Logger {
+	return &Logger{parent: l.root(), fields: mergeKV(l.fields, keyvals)}
+}
+
+// SetFormat sets the log line encoding.
+func (l *Logger) SetFormat(format Format) {
+	l = l.root()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = format
+}
+
+// SetRotation sets the size in megabytes at which the log file rotates and
+// how many rotated files are kept; 0 keeps the default for either. A no-op
+// when logging doesn't go to a file (tests, the stderr fallback).
+func (l *Logger) SetRotation(sizeMB, backups int) {
+	l = l.root()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rotator, ok := l.file.(*lumberjack.Logger)
+	if !ok {
+		return
+	}
+	if sizeMB <= 0 {
+		sizeMB = maxSizeMB
+	}
+	if backups <= 0 {
+		backups = maxBackups
+	}
+	rotator.MaxSize = sizeMB
+	rotator.MaxBackups = backups
+}
+
 // SetLevel sets the minimum log level
 func (l *Logger) SetLevel(level Level) {
+	l = l.root()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.level = level
@@ -182,6 +284,7 @@ func (l *Logger) SetLevel(level Level) {
 
 // SetAlsoStderr sets whether to also write to stderr
 func (l *Logger) SetAlsoStderr(enabled bool) {
+	l = l.root()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.alsoStderr = enabled
@@ -189,10 +292,13 @@ func (l *Logger) SetAlsoStderr(enabled bool) {
 
 // setSessionContext sets a session context that will be included in all log lines.
 // Pass empty string to clear the context.
-func (l *Logger) setSessionContext(ctx string) {
+func (l *Logger) setSessionContext(ctx, externalID, sessionID string) {
+	l = l.root()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.sessionCtx = ctx
+	l.externalID = externalID
+	l.sessionID = sessionID
 }
 
 // SetSession sets session IDs that will be included in all log lines.
@@ -207,7 +313,7 @@ func (l *Logger) SetSession(externalID, sessionID string) {
 	} else if sessionID != "" {
 		ctx = fmt.Sprintf("[sess=%s]", shortID(sessionID))
 	}
-	l.setSessionContext(ctx)
+	l.setSessionContext(ctx, externalID, sessionID)
 }
 
 // shortID returns first 8 chars of an ID for brevity in logs
@@ -218,8 +324,48 @@ func shortID(id string) string {
 	return id
 }
 
-// log writes a log message at the specified level
+// log writes a printf-formatted message at the specified level
 func (l *Logger) log(level Level, format string, args ...interface{}) {
+	l.write(level, fmt.Sprintf(format, args...), l.fields)
+}
+
+// logKV writes msg at the specified level with keyvals added to l's
+// fields; see (*Logger).Info.
+func (l *Logger) logKV(level Level, msg string, keyvals []any) {
+	l.write(level, msg, mergeKV(l.fields, keyvals))
+}
+
+// mergeKV returns fields with the key/value pairs in keyvals added, or
+// fields itself when there are none.
+func mergeKV(fields map[string]any, keyvals []any) map[string]any {
+	if len(keyvals) == 0 {
+		return fields
+	}
+	merged := make(map[string]any, len(fields)+len(keyvals)/2)
+	for k, v := range fields {
+		merged[k] = v
+	}
+	for len(keyvals) > 0 {
+		key, ok := keyvals[0].(string)
+		if !ok || len(keyvals) == 1 {
+			// Like slog: a value without a key is kept under !BADKEY.
+			merged[badKey] = keyvals[0]
+			keyvals = keyvals[1:]
+			continue
+		}
+		merged[key] = keyvals[1]
+		keyvals = keyvals[2:]
+	}
+	return merged
+}
+
+// badKey holds a key/value argument that isn't a string key followed by
+// its value.
+const badKey = "!BADKEY"
+
+// write emits one log line at level, if level is enabled.
+func (l *Logger) write(level Level, message string, fields map[string]any) {
+	l = l.root()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -227,14 +373,19 @@ func (l *Logger) log(level Level, format string, args ...interface{}) {
 		return
 	}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	message := fmt.Sprintf(format, args...)
+	now := time.Now()
 
 	var logLine string
-	if l.sessionCtx != "" {
-		logLine = fmt.Sprintf("[%s] %s %s: %s\n", timestamp, l.sessionCtx, level, message)
+	if l.format == FormatJSON {
+		logLine = l.jsonLine(now, level, message, fields)
 	} else {
-		logLine = fmt.Sprintf("[%s] %s: %s\n", timestamp, level, message)
+		timestamp := now.Format("2006-01-02 15:04:05")
+		message += textFields(fields)
+		if l.sessionCtx != "" {
+			logLine = fmt.Sprintf("[%s] %s %s: %s\n", timestamp, l.sessionCtx, level, message)
+		} else {
+			logLine = fmt.Sprintf("[%s] %s: %s\n", timestamp, level, message)
+		}
 	}
 
 	// Write to log file
@@ -248,23 +399,112 @@ func (l *Logger) log(level Level, format string, args ...interface{}) {
 	}
 }
 
-// Debug logs a debug message
-func (l *Logger) Debug(format string, args ...interface{}) {
+// fieldValue makes v JSON-friendly: errors become their message, and
+// values encoding/json can't encode fall back to their %v form.
+func fieldValue(v any) any {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+// textFields renders fields as " key=value" pairs in key order, quoting
+// values that contain spaces.
+func textFields(fields map[string]any) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		v := fmt.Sprint(fieldValue(fields[k]))
+		if strings.ContainsAny(v, " \t\"") || v == "" {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(&b, " %s=%s", k, v)
+	}
+	return b.String()
+}
+
+// jsonLine encodes one JSON-format log line. The time, level and msg keys
+// can't be overridden by fields. Session IDs appear shortened as ext and
+// sess, like the text prefix, and in full as external_id and session_id
+// (unless a field of that name was given) for filtering in aggregators.
+func (l *Logger) jsonLine(now time.Time, level Level, message string, fields map[string]any) string {
+	entry := make(map[string]any, len(fields)+7)
+	if l.externalID != "" {
+		entry["external_id"] = l.externalID
+	}
+	if l.sessionID != "" {
+		entry["session_id"] = l.sessionID
+	}
+	for k, v := range fields {
+		entry[k] = fieldValue(v)
+	}
+	if l.externalID != "" {
+		entry["ext"] = shortID(l.externalID)
+	}
+	if l.sessionID != "" {
+		entry["sess"] = shortID(l.sessionID)
+	}
+	entry["time"] = now.Format("2006-01-02T15:04:05.000Z07:00")
+	entry["level"] = level.String()
+	entry["msg"] = message
+	data, err := json.Marshal(entry)
+	if err != nil {
+		// Unreachable: every value went through fieldValue.
+		data, _ = json.Marshal(map[string]string{"time": entry["time"].(string), "level": level.String(), "msg": message})
+	}
+	return string(data) + "\n"
+}
+
+// Debug logs a debug message with alternating key/value pairs, like
+// slog: Debug("chunk uploaded", "file", name, "lines", n).
+func (l *Logger) Debug(msg string, keyvals ...any) {
+	l.logKV(DEBUG, msg, keyvals)
+}
+
+// Info logs an info message with alternating key/value pairs, like slog.
+// The pairs become fields of that line only, unlike With; a
+// dangling value or non-string key is logged under "!BADKEY".
+func (l *Logger) Info(msg string, keyvals ...any) {
+	l.logKV(INFO, msg, keyvals)
+}
+
+// Warn logs a warning message with alternating key/value pairs, like slog.
+func (l *Logger) Warn(msg string, keyvals ...any) {
+	l.logKV(WARN, msg, keyvals)
+}
+
+// Error logs an error message with alternating key/value pairs, like slog.
+func (l *Logger) Error(msg string, keyvals ...any) {
+	l.logKV(ERROR, msg, keyvals)
+}
+
+// Debugf logs a printf-formatted debug message
+func (l *Logger) Debugf(format string, args ...interface{}) {
 	l.log(DEBUG, format, args...)
 }
 
-// Info logs an info message
-func (l *Logger) Info(format string, args ...interface{}) {
+// Infof logs a printf-formatted info message
+func (l *Logger) Infof(format string, args ...interface{}) {
 	l.log(INFO, format, args...)
 }
 
-// Warn logs a warning message
-func (l *Logger) Warn(format string, args ...interface{}) {
+// Warnf logs a printf-formatted warning message
+func (l *Logger) Warnf(format string, args ...interface{}) {
 	l.log(WARN, format, args...)
 }
 
-// Error logs an error message
-func (l *Logger) Error(format string, args ...interface{}) {
+// Errorf logs a printf-formatted error message
+func (l *Logger) Errorf(format string, args ...interface{}) {
 	l.log(ERROR, format, args...)
 }
 
@@ -286,24 +526,44 @@ func (l *Logger) ErrorPrint(format string, args ...interface{}) {
 
 // Package-level convenience functions
 
-// Debug logs a debug message (file only, not shown to user)
-func Debug(format string, args ...interface{}) {
-	Get().Debug(format, args...)
+// Debug logs a debug message with key/value pairs (file only, not shown to user)
+func Debug(msg string, keyvals ...any) {
+	Get().Debug(msg, keyvals...)
 }
 
-// Info logs an info message (file only, not shown to user)
-func Info(format string, args ...interface{}) {
-	Get().Info(format, args...)
+// Info logs an info message with key/value pairs (file only, not shown to user)
+func Info(msg string, keyvals ...any) {
+	Get().Info(msg, keyvals...)
 }
 
-// Warn logs a warning (file only, not shown to user)
-func Warn(format string, args ...interface{}) {
-	Get().Warn(format, args...)
+// Warn logs a warning with key/value pairs (file only, not shown to user)
+func Warn(msg string, keyvals ...any) {
+	Get().Warn(msg, keyvals...)
 }
 
-// Error logs an error (file only, not shown to user)
-func Error(format string, args ...interface{}) {
-	Get().Error(format, args...)
+// Error logs an error with key/value pairs (file only, not shown to user)
+func Error(msg string, keyvals ...any) {
+	Get().Error(msg, keyvals...)
+}
+
+// Debugf logs a printf-formatted debug message (file only, not shown to user)
+func Debugf(format string, args ...interface{}) {
+	Get().Debugf(format, args...)
+}
+
+// Infof logs a printf-formatted info message (file only, not shown to user)
+func Infof(format string, args ...interface{}) {
+	Get().Infof(format, args...)
+}
+
+// Warnf logs a printf-formatted warning (file only, not shown to user)
+func Warnf(format string, args ...interface{}) {
+	Get().Warnf(format, args...)
+}
+
+// Errorf logs a printf-formatted error (file only, not shown to user)
+func Errorf(format string, args ...interface{}) {
+	Get().Errorf(format, args...)
 }
 
 // ErrorPrint logs an error AND prints to stderr for user visibility
@@ -311,6 +571,11 @@ func ErrorPrint(format string, args ...interface{}) {
 	Get().ErrorPrint(format, args...)
 }
 
+// With returns a logger adding keyvals to every line; see (*Logger).With.
+func With(keyvals ...any) *Logger {
+	return Get().With(keyvals...)
+}
+
 // SetSession sets session IDs that will be included in all log lines
 func SetSession(externalID, sessionID string) {
 	Get().SetSession(externalID, sessionID)
@@ -2,6 +2,8 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
@@ -126,7 +128,7 @@ func TestLogFormat(t *testing.T) {
 	}
 
 	// Log a message
-	logger.Info("test message with %s", "args")
+	logger.Infof("test message with %s", "args")
 
 	// Read log file
 	tmpFile.Sync()
@@ -152,6 +154,117 @@ func TestLogFormat(t *testing.T) {
 	}
 }
 
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{logger: log.New(&buf, "", 0), level: INFO, format: FormatJSON}
+	logger.SetSession("external-session-id", "backend-session-id")
+
+	logger.With("component", "sync").
+		With("error", errors.New("upload \"failed\""), "lines", 3).
+		Warnf("Chunk %s", "rejected")
+	logger.Info("plain")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("log line is not JSON: %v\n%s", err, lines[0])
+	}
+	want := map[string]any{
+		"level": "WARN", "msg": "Chunk rejected", "component": "sync",
+		"error": `upload "failed"`, "lines": float64(3), "ext": "external", "sess": "backend-",
+		"external_id": "external-session-id", "session_id": "backend-session-id",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, entry[k], v)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, entry["time"].(string)); err != nil {
+		t.Errorf("time %q is not RFC 3339: %v", entry["time"], err)
+	}
+
+	// Fields belong to the derived logger only.
+	entry = nil
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("log line is not JSON: %v\n%s", err, lines[1])
+	}
+	if _, ok := entry["component"]; ok || entry["msg"] != "plain" {
+		t.Errorf("plain line = %v, want no fields", entry)
+	}
+}
+
+func TestWith_TextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{logger: log.New(&buf, "", 0), level: INFO}
+	derived := logger.With("component", "daemon", "error", errors.New("boom now"))
+
+	// Level changes on either logger apply to both.
+	derived.SetLevel(WARN)
+	derived.Info("hidden")
+	derived.Warn("Sync cycle had errors")
+
+	got := buf.String()
+	if strings.Contains(got, "hidden") {
+		t.Errorf("INFO line written after SetLevel(WARN) on derived logger: %q", got)
+	}
+	if !strings.Contains(got, `WARN: Sync cycle had errors component=daemon error="boom now"`) {
+		t.Errorf("unexpected text line: %q", got)
+	}
+}
+
+func TestKeyValues(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{logger: log.New(&buf, "", 0), level: INFO, format: FormatJSON}
+	derived := logger.With("component", "sync")
+
+	derived.Info("Discovered new file", "path", "/tmp/a.jsonl", "lines", 3, "error", errors.New("boom"))
+	derived.Warn("Odd pairs", "file")
+	derived.Info("No pairs")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %q", len(lines), buf.String())
+	}
+	var entries []map[string]any
+	for _, line := range lines {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line is not JSON: %v\n%s", err, line)
+		}
+		entries = append(entries, entry)
+	}
+	want := map[string]any{
+		"msg": "Discovered new file", "component": "sync",
+		"path": "/tmp/a.jsonl", "lines": float64(3), "error": "boom",
+	}
+	for k, v := range want {
+		if entries[0][k] != v {
+			t.Errorf("%s = %#v, want %#v", k, entries[0][k], v)
+		}
+	}
+	if entries[1][badKey] != "file" {
+		t.Errorf("dangling value = %v, want it under %s", entries[1], badKey)
+	}
+	// Pairs belong to their own line, not to the logger.
+	if _, ok := entries[2]["path"]; ok || entries[2]["component"] != "sync" {
+		t.Errorf("plain line = %v, want only the derived logger's fields", entries[2])
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatText, "text": FormatText, "JSON": FormatJSON} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
 func TestSetAlsoStderr(t *testing.T) {
 	// Create temp file for logger
 	tmpFile, err := os.CreateTemp("", "test-log-*.log")
@@ -258,7 +371,7 @@ func TestConcurrentLogging(t *testing.T) {
 		go func(id int) {
 			defer wg.Done()
 			for j := 0; j < messagesPerGoroutine; j++ {
-				logger.Info("goroutine %d message %d", id, j)
+				logger.Infof("goroutine %d message %d", id, j)
 			}
 		}(i)
 	}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/natefinch/lumberjack.v2"
 )
@@ -155,3 +156,50 @@ func TestLoggerWriteAfterRotation(t *testing.T) {
 		t.Error("Current log doesn't contain recent writes")
 	}
 }
+
+func TestSetRotation_RotatesAtConfiguredSize(t *testing.T) {
+	logDir := SetupForTesting(t)
+	Get().SetRotation(1, 1)
+
+	// 1100 lines of ~1KB: just over the 1MB limit, so one rotation.
+	line := strings.Repeat("x", 1000)
+	for i := 0; i < 1100; i++ {
+		Info("line %d %s", i, line)
+	}
+	Info("after rotation")
+	Close()
+
+	// Rotated files are compressed in the background; wait for the backup.
+	var backups []string
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		backups, _ = filepath.Glob(filepath.Join(logDir, "confab-*.log.gz"))
+		if len(backups) > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one rotated file", backups)
+	}
+
+	content, err := os.ReadFile(filepath.Join(logDir, logFileName))
+	if err != nil {
+		t.Fatalf("read active log: %v", err)
+	}
+	if len(content) >= 1024*1024 {
+		t.Errorf("active log is %d bytes, want it truncated by the rotation", len(content))
+	}
+	if !strings.Contains(string(content), "after rotation") {
+		t.Error("active log is missing the line written after rotation")
+	}
+}
+
+func TestSetRotation_ZeroKeepsDefaults(t *testing.T) {
+	SetupForTesting(t)
+	Get().SetRotation(0, 0)
+	rotator := Get().file.(*lumberjack.Logger)
+	if rotator.MaxSize != maxSizeMB || rotator.MaxBackups != maxBackups {
+		t.Errorf("SetRotation(0, 0): MaxSize=%d MaxBackups=%d, want %d and %d", rotator.MaxSize, rotator.MaxBackups, maxSizeMB, maxBackups)
+	}
+}
@@ -4,6 +4,8 @@
 package loginit
 
 import (
+	"os"
+
 	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/ConfabulousDev/confab/pkg/logger"
 )
@@ -19,9 +21,41 @@ func ApplyLogLevel() {
 
 	level, err := config.ParseLogLevel(cfg.LogLevel)
 	if err != nil {
-		logger.Warn("Invalid log_level in config: %v", err)
+		logger.Warnf("Invalid log_level in config: %v", err)
 		return
 	}
 
 	logger.Get().SetLevel(level)
 }
+
+// ApplyLogRotation applies the upload config's log_max_size_mb and
+// log_max_backups to the log file. No-ops if the config can't be read.
+func ApplyLogRotation() {
+	cfg, err := config.GetUploadConfig()
+	if err != nil {
+		return
+	}
+	logger.Get().SetRotation(cfg.LogMaxSizeMB, cfg.LogMaxBackups)
+}
+
+// ApplyLogFormat sets the log line format from flagValue (the root
+// --log-format flag), or from LOG_FORMAT when the flag is empty, or else
+// from the upload config's log_format. An unrecognized value logs a
+// warning and keeps the text format.
+func ApplyLogFormat(flagValue string) {
+	value := flagValue
+	if value == "" {
+		value = os.Getenv(logger.LogFormatEnv)
+	}
+	if value == "" {
+		if cfg, err := config.GetUploadConfig(); err == nil {
+			value = cfg.LogFormat
+		}
+	}
+	format, err := logger.ParseFormat(value)
+	if err != nil {
+		logger.Warnf("Invalid log format: %v", err)
+		return
+	}
+	logger.Get().SetFormat(format)
+}
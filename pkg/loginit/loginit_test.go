@@ -70,6 +70,71 @@ func TestApplyLogLevel_MissingConfig(t *testing.T) {
 	}
 }
 
+// Spec: LOG_FORMAT=json switches the log file to JSON lines; the flag
+// value, when given, wins over the environment.
+func TestApplyLogFormat(t *testing.T) {
+	logDir := setupLogger(t)
+	t.Setenv(logger.LogFormatEnv, "json")
+
+	ApplyLogFormat("")
+	logger.Info("probe-json-line")
+	ApplyLogFormat("text")
+	logger.Info("probe-text-line")
+
+	data, err := os.ReadFile(filepath.Join(logDir, "confab.log"))
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %q", data)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil || entry["msg"] != "probe-json-line" {
+		t.Errorf("LOG_FORMAT=json line = %q (err %v), want JSON", lines[0], err)
+	}
+	if !strings.Contains(lines[1], "INFO: probe-text-line") {
+		t.Errorf("--log-format text line = %q, want text", lines[1])
+	}
+}
+
+// Spec: with neither flag nor LOG_FORMAT, the config's log_format applies;
+// JSON lines carry the level and the session's IDs as fields.
+func TestApplyLogFormat_FromConfig(t *testing.T) {
+	logDir := setupLogger(t)
+	t.Setenv(logger.LogFormatEnv, "")
+	configPath := writeTestConfig(t, map[string]any{
+		"backend_url": "https://example.test",
+		"api_key":     "cfb_aaaaaaaaaaaaaaaaaaaa",
+		"log_format":  "json",
+	})
+	t.Setenv("CONFAB_CONFIG_PATH", configPath)
+
+	ApplyLogFormat("")
+	logger.SetSession("external-session-id", "backend-session-id")
+	defer logger.SetSession("", "")
+	logger.Warn("probe-config-json", "file", "transcript.jsonl")
+
+	data, err := os.ReadFile(filepath.Join(logDir, "confab.log"))
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+		t.Fatalf("log_format=json line is not JSON: %v\n%s", err, data)
+	}
+	want := map[string]any{
+		"level": "WARN", "msg": "probe-config-json",
+		"session_id": "backend-session-id", "file": "transcript.jsonl",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, entry[k], v)
+		}
+	}
+}
+
 // setupLogger thin wrapper preserved so existing call sites read the
 // same. Delegates to logger.SetupForTesting.
 func setupLogger(t *testing.T) string {
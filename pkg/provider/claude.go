@@ -26,8 +26,12 @@ const ClaudeStateDirEnv = "CONFAB_CLAUDE_DIR"
 // StateDir() — it is how `confab setup --provider claude-code --config-dir
 // <dir>` retargets installation into a non-default config dir (kata hpec). The
 // zero value (ClaudeCode{}) keeps today's behavior.
+//
+// settingsScope selects the settings file hooks go in (see SettingsPath);
+// empty defers to CONFAB_SETTINGS_SCOPE. Set via WithSettingsScope.
 type ClaudeCode struct {
 	configDirOverride string
+	settingsScope     config.SettingsScope
 }
 
 var _ Provider = ClaudeCode{}
@@ -82,8 +86,8 @@ func (ClaudeCode) WalkUpToRoot(sessionID string) (string, string, error) {
 // ShouldSpawnForInput is unconditional for Claude Code.
 func (ClaudeCode) ShouldSpawnForInput(HookInput) bool { return true }
 
-// InstallHooks installs all four Confab hook bundles (sync, PreToolUse,
-// PostToolUse, UserPromptSubmit). Returns the settings.json path.
+// InstallHooks installs all six Confab hook bundles (sync, PreToolUse,
+// PostToolUse, UserPromptSubmit, Stop, PreCompact). Returns the settings.json path.
 func (p ClaudeCode) InstallHooks() (string, error) {
 	settingsPath, err := p.SettingsPath()
 	if err != nil {
@@ -94,6 +98,8 @@ func (p ClaudeCode) InstallHooks() (string, error) {
 		hookconfig.InstallPreToolUseHooks,
 		hookconfig.InstallPostToolUseHooks,
 		hookconfig.InstallUserPromptSubmitHook,
+		hookconfig.InstallStopHook,
+		hookconfig.InstallPreCompactHook,
 	}
 	for _, install := range installers {
 		if err := install(settingsPath); err != nil {
@@ -103,25 +109,80 @@ func (p ClaudeCode) InstallHooks() (string, error) {
 	return settingsPath, nil
 }
 
-// UninstallHooks removes all four Confab hook bundles. Returns the
+// PreviewHooks returns the settings file InstallHooks would write, with
+// its current contents and the contents after installing — without
+// writing anything. Backs `confab setup --dry-run`.
+func (p ClaudeCode) PreviewHooks() (settingsPath string, before, after *config.ClaudeSettings, err error) {
+	settingsPath, err = p.SettingsPath()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	before, err = config.ReadSettingsAt(settingsPath)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	after, err = before.Clone()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if err := hookconfig.ApplyClaudeHooks(after); err != nil {
+		return "", nil, nil, err
+	}
+	return settingsPath, before, after, nil
+}
+
+// UninstallHooks removes all six Confab hook bundles. Returns the
 // settings.json path even if no hooks were present.
 func (p ClaudeCode) UninstallHooks() (string, error) {
 	settingsPath, err := p.SettingsPath()
 	if err != nil {
 		return "", err
 	}
+	if err := p.UninstallHooksAt(settingsPath); err != nil {
+		return "", err
+	}
+	return settingsPath, nil
+}
+
+// UninstallHooksAt removes all six Confab hook bundles from the Claude
+// settings file at settingsPath, whichever scope it belongs to. Backs
+// `confab uninstall` for project-scope installs outside the working
+// directory.
+func (ClaudeCode) UninstallHooksAt(settingsPath string) error {
 	uninstallers := []func(string) error{
 		hookconfig.UninstallSyncHooks,
 		hookconfig.UninstallPreToolUseHooks,
 		hookconfig.UninstallPostToolUseHooks,
 		hookconfig.UninstallUserPromptSubmitHook,
+		hookconfig.UninstallStopHook,
+		hookconfig.UninstallPreCompactHook,
 	}
 	for _, uninstall := range uninstallers {
 		if err := uninstall(settingsPath); err != nil {
-			return "", err
+			return err
 		}
 	}
-	return settingsPath, nil
+	return nil
+}
+
+// RepairHooks removes duplicate confab hooks from settings.json and merges
+// ones under stray matchers (see hookconfig.RepairHooks). Returns the
+// number of hooks removed or moved.
+func (p ClaudeCode) RepairHooks() (int, error) {
+	settingsPath, err := p.SettingsPath()
+	if err != nil {
+		return 0, err
+	}
+	return hookconfig.RepairHooks(settingsPath)
+}
+
+// HookRepairs reports how many hooks RepairHooks would remove or move.
+func (p ClaudeCode) HookRepairs() (int, error) {
+	settingsPath, err := p.SettingsPath()
+	if err != nil {
+		return 0, err
+	}
+	return hookconfig.CountHookRepairs(settingsPath)
 }
 
 // InstallSkills installs the Claude Code skills shipped with confab (/retro)
@@ -211,7 +272,7 @@ func (p ClaudeCode) DefaultCWD(transcriptPath string) string {
 	return filepath.Dir(transcriptPath)
 }
 
-// IsHooksInstalled reports whether all four Confab hook bundles for
+// IsHooksInstalled reports whether all five Confab hook bundles for
 // Claude Code are installed. Mirrors InstallHooks: true only when every
 // bundle is present.
 func (p ClaudeCode) IsHooksInstalled() (bool, error) {
@@ -224,6 +285,8 @@ func (p ClaudeCode) IsHooksInstalled() (bool, error) {
 		hookconfig.IsPreToolUseHooksInstalled,
 		hookconfig.IsPostToolUseHooksInstalled,
 		hookconfig.IsUserPromptSubmitHookInstalled,
+		hookconfig.IsStopHookInstalled,
+		hookconfig.IsPreCompactHookInstalled,
 	}
 	for _, check := range checks {
 		ok, err := check(settingsPath)
@@ -265,8 +328,27 @@ func (p ClaudeCode) ProjectsDir() (string, error) {
 	return filepath.Join(stateDir, "projects"), nil
 }
 
-// SettingsPath returns the Claude settings file path.
+// WithSettingsScope returns p installing into / checking the settings
+// file for scope instead of the one CONFAB_SETTINGS_SCOPE selects.
+func (p ClaudeCode) WithSettingsScope(scope config.SettingsScope) ClaudeCode {
+	p.settingsScope = scope
+	return p
+}
+
+// SettingsPath returns the Claude settings file path: settings.json in the
+// state dir, or for the project scope the working directory's
+// .claude/settings.local.json (which ignores any config dir override).
 func (p ClaudeCode) SettingsPath() (string, error) {
+	scope := p.settingsScope
+	if scope == "" {
+		var err error
+		if scope, err = config.SettingsScopeFromEnv(); err != nil {
+			return "", err
+		}
+	}
+	if scope == config.SettingsScopeProject {
+		return config.SettingsPathForScope(scope)
+	}
 	stateDir, err := p.StateDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get claude state directory: %w", err)
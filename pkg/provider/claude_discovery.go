@@ -40,7 +40,7 @@ func (p ClaudeCode) ScanSessions() ([]SessionInfo, error) {
 
 	err = filepath.WalkDir(projectsDir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
-			logger.Warn("Failed to access path during scan: %s: %v", path, err)
+			logger.Warnf("Failed to access path during scan: %s: %v", path, err)
 			skippedPaths = append(skippedPaths, path)
 			return nil
 		}
@@ -78,7 +78,7 @@ func (p ClaudeCode) FindSessionByID(partialID string) (string, string, error) {
 
 	err = filepath.WalkDir(projectsDir, func(path string, d os.DirEntry, walkErr error) error {
 		if walkErr != nil {
-			logger.Warn("Failed to access path during search: %s: %v", path, walkErr)
+			logger.Warnf("Failed to access path during search: %s: %v", path, walkErr)
 			skippedPaths = append(skippedPaths, path)
 			return nil
 		}
@@ -93,7 +93,7 @@ func (p ClaudeCode) FindSessionByID(partialID string) (string, string, error) {
 		return nil
 	})
 	if err != nil {
-		logger.Warn("Failed to walk projects directory: %v", err)
+		logger.Warnf("Failed to walk projects directory: %v", err)
 	}
 
 	reportSkippedPaths(skippedPaths, "search")
@@ -131,7 +131,7 @@ func extractClaudeSessionMetadataFromFile(transcriptPath string) SessionMetadata
 	// Open failures (lines==nil) degrade silently; scan errors log a
 	// warning but we still process whatever was collected.
 	if err != nil && lines != nil {
-		logger.Warn("Error reading transcript %s during metadata extraction: %v", transcriptPath, err)
+		logger.Warnf("Error reading transcript %s during metadata extraction: %v", transcriptPath, err)
 	}
 	return extractClaudeMetadata(lines)
 }
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/ConfabulousDev/confab/pkg/types"
 )
 
@@ -306,12 +307,77 @@ func TestClaudeCodeUninstallHooksClearsSettings(t *testing.T) {
 	}
 }
 
+// TestClaudeCodeProjectScopeInstallsLocally verifies the project settings
+// scope installs into <cwd>/.claude/settings.local.json and leaves the user
+// settings.json alone, whether chosen explicitly or via the environment.
+func TestClaudeCodeProjectScopeInstallsLocally(t *testing.T) {
+	userDir := t.TempDir()
+	t.Setenv(ClaudeStateDirEnv, userDir)
+	projectDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(projectDir)
+	wantPath := filepath.Join(projectDir, ".claude", "settings.local.json")
+
+	p := ClaudeCode{}.WithSettingsScope(config.SettingsScopeProject)
+	path, err := p.InstallHooks()
+	if err != nil {
+		t.Fatalf("InstallHooks() error = %v", err)
+	}
+	if path != wantPath {
+		t.Errorf("InstallHooks() path = %q, want %q", path, wantPath)
+	}
+	data, err := os.ReadFile(wantPath)
+	if err != nil || !strings.Contains(string(data), "hook session-start") {
+		t.Fatalf("project settings missing hooks (err %v):\n%s", err, data)
+	}
+	if _, err := os.Stat(filepath.Join(userDir, "settings.json")); !os.IsNotExist(err) {
+		t.Errorf("user settings.json touched by a project-scope install (stat err %v)", err)
+	}
+
+	t.Setenv(config.SettingsScopeEnv, "project")
+	if got, err := (ClaudeCode{}).SettingsPath(); err != nil || got != wantPath {
+		t.Errorf("SettingsPath() with %s=project = %q, %v; want %q", config.SettingsScopeEnv, got, err, wantPath)
+	}
+	if got, _ := (ClaudeCode{}).WithSettingsScope(config.SettingsScopeUser).SettingsPath(); got != filepath.Join(userDir, "settings.json") {
+		t.Errorf("explicit user scope SettingsPath() = %q, want the user settings.json", got)
+	}
+
+	if _, err := WithSettingsScope(Codex{}, config.SettingsScopeProject); err == nil {
+		t.Error("WithSettingsScope(codex, project) succeeded, want an error")
+	}
+}
+
 // TestClaudeCodeIsHooksInstalled exercises the AND-aggregation across
 // all four hook bundles. We hand-roll settings.json with confab-named
 // commands so the underlying isConfabCommand check (which is binary-
 // path-sensitive) returns true under test.
 func TestClaudeCodeIsHooksInstalled(t *testing.T) {
-	const allFour = `{
+	const allSix = `{
+  "hooks": {
+    "SessionStart": [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook session-start"}]}],
+    "SessionEnd":   [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook session-end"}]}],
+    "PreToolUse":   [{"matcher": "Bash", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook pre-tool-use"}]}],
+    "PostToolUse":  [{"matcher": "Bash", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook post-tool-use"}]}],
+    "UserPromptSubmit": [{"hooks": [{"type":"command","command":"/usr/local/bin/confab hook user-prompt-submit"}]}],
+    "Stop": [{"hooks": [{"type":"command","command":"/usr/local/bin/confab hook stop"}]}],
+    "PreCompact": [{"hooks": [{"type":"command","command":"/usr/local/bin/confab hook pre-compact"}]}]
+  }
+}`
+	// Installed by a confab that predates the PreCompact hook.
+	const noPreCompact = `{
+  "hooks": {
+    "SessionStart": [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook session-start"}]}],
+    "SessionEnd":   [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook session-end"}]}],
+    "PreToolUse":   [{"matcher": "Bash", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook pre-tool-use"}]}],
+    "PostToolUse":  [{"matcher": "Bash", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook post-tool-use"}]}],
+    "UserPromptSubmit": [{"hooks": [{"type":"command","command":"/usr/local/bin/confab hook user-prompt-submit"}]}],
+    "Stop": [{"hooks": [{"type":"command","command":"/usr/local/bin/confab hook stop"}]}]
+  }
+}`
+	// Installed by a confab that predates the Stop hook.
+	const noStop = `{
   "hooks": {
     "SessionStart": [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook session-start"}]}],
     "SessionEnd":   [{"matcher": "*", "hooks": [{"type":"command","command":"/usr/local/bin/confab hook session-end"}]}],
@@ -334,8 +400,10 @@ func TestClaudeCodeIsHooksInstalled(t *testing.T) {
 		want     bool
 	}{
 		{"no settings file", "", false},
-		{"all four bundles", allFour, true},
-		{"missing one bundle", onlyThree, false},
+		{"all six bundles", allSix, true},
+		{"missing the PreCompact hook", noPreCompact, false},
+		{"missing the Stop hook", noStop, false},
+		{"missing two bundles", onlyThree, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
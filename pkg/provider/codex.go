@@ -54,7 +54,7 @@ func (p Codex) ShouldSpawnForInput(in HookInput) bool {
 		if os.IsNotExist(err) {
 			return true
 		}
-		logger.Warn("Codex ShouldSpawnForInput: failed to inspect rollout %s: %v", in.TranscriptPath(), err)
+		logger.Warnf("Codex ShouldSpawnForInput: failed to inspect rollout %s: %v", in.TranscriptPath(), err)
 		return false
 	}
 	return info.IsUserSession()
@@ -98,7 +98,7 @@ func (p Codex) IsSkillInstalled(name string) bool {
 func (p Codex) InitTranscript(target TranscriptRegistrar, transcriptPath, externalID string) error {
 	info, err := p.ReadSessionInfo(transcriptPath)
 	if err != nil {
-		logger.Warn("Codex root session_meta read failed: %v", err)
+		logger.Warnf("Codex root session_meta read failed: %v", err)
 		// Fall through with zero CodexSessionInfo so the partial metadata
 		// still goes out. Backend treats missing fields as "unknown".
 	}
@@ -137,20 +137,20 @@ func (p Codex) DiscoverDescendants(reg DescendantRegistrar, rootThreadUUID strin
 			continue
 		}
 		if err := p.ValidateRolloutPath(row.RolloutPath); err != nil {
-			logger.Warn("Codex descendant %s: invalid rollout path %q: %v",
+			logger.Warnf("Codex descendant %s: invalid rollout path %q: %v",
 				row.ThreadUUID, row.RolloutPath, err)
 			continue
 		}
 		info, err := p.ReadSessionInfo(row.RolloutPath)
 		if err != nil {
-			logger.Warn("Codex descendant %s: failed to read session_meta: %v",
+			logger.Warnf("Codex descendant %s: failed to read session_meta: %v",
 				row.ThreadUUID, err)
 			continue
 		}
 		// The DB says this is a descendant, but only trust the row if the
 		// rollout itself confirms it's a subagent. Symmetric to provider.IsUserSession.
 		if info.IsUserSession() {
-			logger.Warn("Codex descendant %s: session_meta says user-session, skipping",
+			logger.Warnf("Codex descendant %s: session_meta says user-session, skipping",
 				row.ThreadUUID)
 			continue
 		}
@@ -171,8 +171,8 @@ func (p Codex) DiscoverDescendants(reg DescendantRegistrar, rootThreadUUID strin
 			AgentNickname:    row.AgentNickname,
 		}
 		reg.RegisterCodexRollout(row.RolloutPath, fileName, false, meta)
-		logger.Info("Discovered Codex descendant: thread=%s path=%s",
-			row.ThreadUUID, row.RolloutPath)
+		logger.Info("Discovered Codex descendant",
+			"thread", row.ThreadUUID, "path", row.RolloutPath)
 	}
 	return nil
 }
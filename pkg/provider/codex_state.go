@@ -228,8 +228,8 @@ func (p Codex) WalkUpToRoot(threadUUID string) (rootUUID, rootRolloutPath string
 	for depth := 0; depth < maxWalkDepth; depth++ {
 		parent, found, attempts, lookupErr := p.lookupParentWithRetry(ctx, db, current, depth == 0)
 		if depth == 0 {
-			logger.Info("codex walk_up_to_root: thread=%s parent_found=%t attempts=%d elapsed=%s",
-				threadUUID, found, attempts, time.Since(start))
+			logger.Info("codex walk_up_to_root", "thread", threadUUID,
+				"parent_found", found, "attempts", attempts, "elapsed", time.Since(start).String())
 		}
 		if lookupErr != nil {
 			// Schema/IO failure mid-walk: degrade to "current is the root".
@@ -378,7 +378,7 @@ JOIN threads t ON t.id = d.thread_uuid
 `
 	rows, err := db.QueryContext(ctx, q, rootThreadUUID)
 	if err != nil {
-		logger.Warn("codex list_subtree: query failed (schema mismatch?): %v", err)
+		logger.Warnf("codex list_subtree: query failed (schema mismatch?): %v", err)
 		return nil, nil
 	}
 	defer rows.Close()
@@ -391,13 +391,13 @@ JOIN threads t ON t.id = d.thread_uuid
 			&r.CWD, &r.Model, &r.Source, &r.ThreadSource,
 			&r.AgentPath, &r.AgentRole, &r.AgentNickname,
 		); err != nil {
-			logger.Warn("codex list_subtree: row scan failed: %v", err)
+			logger.Warnf("codex list_subtree: row scan failed: %v", err)
 			return nil, nil
 		}
 		out = append(out, r)
 	}
 	if err := rows.Err(); err != nil {
-		logger.Warn("codex list_subtree: row iteration failed: %v", err)
+		logger.Warnf("codex list_subtree: row iteration failed: %v", err)
 		return nil, nil
 	}
 	return out, nil
@@ -380,7 +380,7 @@ func (p Cursor) ScanSessions() ([]SessionInfo, error) {
 	var skippedPaths []string
 	err = filepath.WalkDir(projectsDir, func(path string, d os.DirEntry, walkErr error) error {
 		if walkErr != nil {
-			logger.Warn("Failed to access path during scan: %s: %v", path, walkErr)
+			logger.Warnf("Failed to access path during scan: %s: %v", path, walkErr)
 			skippedPaths = append(skippedPaths, path)
 			return nil
 		}
@@ -414,7 +414,7 @@ func (p Cursor) FindSessionByID(partialID string) (string, string, error) {
 	var skippedPaths []string
 	err = filepath.WalkDir(projectsDir, func(path string, d os.DirEntry, walkErr error) error {
 		if walkErr != nil {
-			logger.Warn("Failed to access path during search: %s: %v", path, walkErr)
+			logger.Warnf("Failed to access path during search: %s: %v", path, walkErr)
 			skippedPaths = append(skippedPaths, path)
 			return nil
 		}
@@ -425,7 +425,7 @@ func (p Cursor) FindSessionByID(partialID string) (string, string, error) {
 		return nil
 	})
 	if err != nil {
-		logger.Warn("Failed to walk projects directory: %v", err)
+		logger.Warnf("Failed to walk projects directory: %v", err)
 	}
 	reportSkippedPaths(skippedPaths, "search")
 
@@ -472,7 +472,7 @@ func parseCursorSessionFromPath(path string, d os.DirEntry) *SessionInfo {
 func extractCursorSessionMetadataFromFile(transcriptPath string) SessionMetadata {
 	lines, err := readHeadLines(transcriptPath)
 	if err != nil && lines != nil {
-		logger.Warn("Error reading transcript %s during metadata extraction: %v", transcriptPath, err)
+		logger.Warnf("Error reading transcript %s during metadata extraction: %v", transcriptPath, err)
 	}
 	return extractCursorMetadata(lines)
 }
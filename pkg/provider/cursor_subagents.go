@@ -66,7 +66,7 @@ func (Cursor) DiscoverDescendants(reg DescendantRegistrar, _ string) error {
 		// Claude/OpenCode sidechains); absolute path for local reads.
 		name := path.Join(cursorSubagentsDir, base)
 		if wreg.RegisterSidechainFile(filepath.Join(subagentsDir, base), name, FileTypeAgent) {
-			logger.Info("Discovered Cursor subagent sidechain: %s", name)
+			logger.Infof("Discovered Cursor subagent sidechain: %s", name)
 		}
 	}
 	return nil
@@ -161,13 +161,13 @@ const opencodeListDescendantsTimeout = 10 * time.Second
 func (Opencode) DiscoverDescendants(reg DescendantRegistrar, externalID string) error {
 	oreg, ok := reg.(OpencodeDescendantRegistrar)
 	if !ok {
-		logger.Warn("OpenCode descendant discovery requires the daemon-supplied registrar; subagent capture disabled for session %s", externalID)
+		logger.Warnf("OpenCode descendant discovery requires the daemon-supplied registrar; subagent capture disabled for session %s", externalID)
 		return nil
 	}
 
 	dbPath, err := OpenCodeDBPath()
 	if err != nil {
-		logger.Warn("OpenCode DB path resolve failed: %v", err)
+		logger.Warnf("OpenCode DB path resolve failed: %v", err)
 		return nil
 	}
 	reader := NewOpenCodeDBReader(dbPath)
@@ -176,14 +176,14 @@ func (Opencode) DiscoverDescendants(reg DescendantRegistrar, externalID string)
 	defer cancel()
 	descendants, err := reader.ListDescendants(ctx, externalID)
 	if err != nil {
-		logger.Warn("OpenCode ListDescendants failed for %s: %v", externalID, err)
+		logger.Warnf("OpenCode ListDescendants failed for %s: %v", externalID, err)
 		return nil
 	}
 
 	for _, childID := range descendants {
 		localPath, err := opencodeChildLocalPath(externalID, childID)
 		if err != nil {
-			logger.Warn("OpenCode child path derive failed for %s: %v", childID, err)
+			logger.Warnf("OpenCode child path derive failed for %s: %v", childID, err)
 			continue
 		}
 		oreg.RegisterOpencodeChild(childID, localPath)
@@ -231,7 +231,7 @@ func (Opencode) AnnotateChunk(c ChunkView, sentFirstUserMessage bool, redact fun
 	}
 	msg, err := ocFirstUserMessageText(c.Lines())
 	if err != nil {
-		logger.Debug("opencode: failed to extract first user message: %v", err)
+		logger.Debugf("opencode: failed to extract first user message: %v", err)
 		return result
 	}
 	if msg == "" {
@@ -255,7 +255,7 @@ func (Opencode) DefaultCWD(transcriptPath string) string {
 // not currently support reliably (CF-549, M2). The log goes to the confab
 // log file only, not to opencode's stderr.
 func (Opencode) OnAlreadyRunning(externalID string) {
-	logger.Warn("opencode session %s has an existing daemon; multi-process resume is not supported and sync may be unreliable",
+	logger.Warnf("opencode session %s has an existing daemon; multi-process resume is not supported and sync may be unreliable",
 		externalID)
 }
 
@@ -347,7 +347,7 @@ func (Opencode) ScanSessions() ([]SessionInfo, error) {
 		if err != nil {
 			// A per-session preview failure shouldn't drop the session from
 			// the list; degrade to a blank TITLE.
-			logger.Debug("opencode: first user message read failed for %s: %v", row.ID, err)
+			logger.Debugf("opencode: first user message read failed for %s: %v", row.ID, err)
 			fum = ""
 		}
 		sessions = append(sessions, SessionInfo{
@@ -185,7 +185,7 @@ func MaterializeOpenCodeSession(ctx context.Context, source ocSource, sessionID,
 // poll is the only liveness mechanism.
 func (c *OpenCodeCollector) Run(ctx context.Context) error {
 	if err := c.seed(); err != nil {
-		logger.Warn("opencode collector seed failed (continuing): %v", err)
+		logger.Warnf("opencode collector seed failed (continuing): %v", err)
 	}
 	c.tryReconcile(ctx)
 
@@ -212,13 +212,13 @@ func (c *OpenCodeCollector) tryReconcile(ctx context.Context) {
 		return
 	}
 	if c.consecutiveErr > 0 {
-		logger.Info("opencode collector recovered after %d failed cycle(s) for %s",
+		logger.Infof("opencode collector recovered after %d failed cycle(s) for %s",
 			c.consecutiveErr, c.sessionID)
 		c.consecutiveErr = 0
 		c.lastErrKind = ""
 	}
 	if n > 0 {
-		logger.Debug("opencode collector appended %d message(s) for %s", n, c.sessionID)
+		logger.Debugf("opencode collector appended %d message(s) for %s", n, c.sessionID)
 	}
 }
 
@@ -234,12 +234,12 @@ func (c *OpenCodeCollector) warnReconcileError(err error) {
 	if kind != c.lastErrKind {
 		c.lastErrKind = kind
 		c.consecutiveErr = 1
-		logger.Warn("opencode collector reconcile failed for %s: %v", c.sessionID, err)
+		logger.Warnf("opencode collector reconcile failed for %s: %v", c.sessionID, err)
 		return
 	}
 	c.consecutiveErr++
 	if c.consecutiveErr%n == 0 {
-		logger.Warn("opencode collector reconcile still failing for %s (%d consecutive): %v",
+		logger.Warnf("opencode collector reconcile still failing for %s (%d consecutive): %v",
 			c.sessionID, c.consecutiveErr, err)
 	}
 }
@@ -58,10 +58,15 @@ type DescendantRegistrar interface {
 // journal.jsonl (CF-533). The backend (CF-532) accepts and stores it but
 // never Claude-parses it; it is excluded from token/transcript analytics.
 // Workflow subagent transcripts use the ordinary FileTypeAgent file_type.
+//
+// FileTypeAttachment is a non-JSONL file a transcript references as a
+// document attachment (e.g. a PDF given to a tool); pkg/sync uploads it
+// whole, base64-encoded, instead of as lines.
 const (
 	FileTypeTranscript      = "transcript"
 	FileTypeAgent           = "agent"
 	FileTypeWorkflowJournal = "workflow_journal"
+	FileTypeAttachment      = "attachment"
 )
 
 // WorkflowRegistrar is the surface DiscoverWorkflowFiles uses to register
@@ -314,6 +319,19 @@ func GetWithDir(name, dir string) (Provider, error) {
 	}
 }
 
+// WithSettingsScope returns p retargeted at the settings file for scope.
+// Only Claude Code has settings scopes; for any other provider the user
+// scope returns p unchanged and the project scope is an error.
+func WithSettingsScope(p Provider, scope config.SettingsScope) (Provider, error) {
+	if c, ok := p.(ClaudeCode); ok {
+		return c.WithSettingsScope(scope), nil
+	}
+	if scope == config.SettingsScopeProject {
+		return nil, fmt.Errorf("project settings scope is not supported for provider %q", p.Name())
+	}
+	return p, nil
+}
+
 // NormalizeName returns the canonical provider name. Backed by the
 // registry so it can't drift from the Provider list. An empty name returns
 // ErrNoProvider via Get (no implicit claude-code fallback; kata frm7).
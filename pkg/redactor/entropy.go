@@ -0,0 +1,131 @@
+package redactor
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+)
+
+const (
+	// entropyPatternName labels entropy matches in FindMatches.
+	entropyPatternName = "High Entropy Token"
+	// entropyMarker replaces a high-entropy token.
+	entropyMarker = "[REDACTED:HIGH_ENTROPY]"
+)
+
+// entropyRunRegex matches runs of base64/base64url characters, with up to
+// two trailing '=' padding characters. '.' is excluded so file names and
+// hostnames split into short pieces. Runs are then split on '/' into
+// tokens (see findSpans).
+var entropyRunRegex = regexp.MustCompile(`[A-Za-z0-9+/_-]+={0,2}`)
+
+// entropyDetector masks standalone tokens that look random enough to be
+// secrets no named pattern knows about.
+type entropyDetector struct {
+	threshold float64
+	minLength int
+	maxLength int
+}
+
+func newEntropyDetector(cfg *config.EntropyConfig) *entropyDetector {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	c := cfg.WithDefaults()
+	return &entropyDetector{threshold: c.Threshold, minLength: c.MinLength, maxLength: c.MaxLength}
+}
+
+// redact replaces every high-entropy token in s with entropyMarker.
+func (d *entropyDetector) redact(s string) string {
+	spans := d.findSpans(s)
+	if len(spans) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(s[last:span[0]])
+		b.WriteString(entropyMarker)
+		last = span[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// findSpans returns the [start, end) byte offsets of high-entropy tokens
+// in s.
+//
+// A whole run longer than maxLength is encoded data (a base64 image, file
+// contents), not a credential, and is skipped — checked before splitting
+// on '/', since base64 blobs contain slashes. So is a data URI payload.
+// The remaining runs are split on '/' so paths and URLs are judged segment
+// by segment; the cost is that a secret containing '/' is judged in pieces.
+func (d *entropyDetector) findSpans(s string) [][2]int {
+	var spans [][2]int
+	for _, loc := range entropyRunRegex.FindAllStringIndex(s, -1) {
+		start, end := loc[0], loc[1]
+		if end-start > d.maxLength || strings.HasSuffix(s[:start], "base64,") {
+			continue
+		}
+		for start < end {
+			segEnd := start + strings.IndexByte(s[start:end], '/')
+			if segEnd < start {
+				segEnd = end
+			}
+			if d.isSecret(s[start:segEnd]) {
+				spans = append(spans, [2]int{start, segEnd})
+			}
+			start = segEnd + 1
+		}
+	}
+	return spans
+}
+
+// isSecret applies the length, character-class and entropy rules to token.
+func (d *entropyDetector) isSecret(token string) bool {
+	if len(token) < d.minLength || len(token) > d.maxLength {
+		return false
+	}
+	if !hasMixedCharClasses(token) {
+		return false
+	}
+	return shannonEntropy(token) >= d.threshold
+}
+
+// hasMixedCharClasses reports whether token contains an upper-case letter,
+// a lower-case letter and a digit. Random tokens almost always do; prose,
+// identifiers and hex hashes rarely do.
+func hasMixedCharClasses(token string) bool {
+	var upper, lower, digit bool
+	for i := 0; i < len(token); i++ {
+		switch c := token[i]; {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per byte.
+func shannonEntropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	n := float64(len(s))
+	var h float64
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
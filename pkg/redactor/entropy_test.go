@@ -0,0 +1,124 @@
+package redactor
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+)
+
+// entropyOnlyRedactor returns a redactor with no patterns and default
+// entropy settings, so only the entropy detector can fire.
+func entropyOnlyRedactor(t *testing.T) *Redactor {
+	t.Helper()
+	useDefaults := false
+	r, err := NewFromConfig(&config.RedactionConfig{
+		Enabled:            true,
+		UseDefaultPatterns: &useDefaults,
+		Entropy:            &config.EntropyConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	if r == nil {
+		t.Fatal("expected a redactor when only entropy redaction is enabled")
+	}
+	return r
+}
+
+func TestEntropy_RedactsHighEntropyToken(t *testing.T) {
+	r := entropyOnlyRedactor(t)
+
+	const token = "Zx9Qm2Lr7Tk4Wv8Bn3Hc6Jp1Yd5Fs0Ga"
+	line := `{"type":"user","message":"use token ` + token + ` for the staging deploy"}`
+
+	got := r.RedactJSONLine(line)
+	if strings.Contains(got, token) {
+		t.Errorf("high-entropy token not redacted: %s", got)
+	}
+	if !strings.Contains(got, "use token [REDACTED:HIGH_ENTROPY] for the staging deploy") {
+		t.Errorf("surrounding text not preserved: %s", got)
+	}
+}
+
+func TestEntropy_LeavesNormalContentAlone(t *testing.T) {
+	r := entropyOnlyRedactor(t)
+
+	for _, s := range []string{
+		"The quick brown fox jumps over the lazy dog while internationalization and characterization tests run.",
+		"Edit /Users/alice/Projects/Confab2025/internal/handlers/SessionHandler.go then rerun",
+		"commit 3f786850e387550fdab836ed7e6dc881de23001b and uuid 123e4567-e89b-12d3-a456-426614174000",
+		"getUserProfileByIdentifier2 calls TestEngine_SeedOffsetHints_SkipsSyncedLines",
+		"see https://github.com/ConfabulousDev/confab/pull/1234/files?diff=split",
+	} {
+		if got := r.Redact(s); got != s {
+			t.Errorf("Redact(%q) = %q, want unchanged", s, got)
+		}
+	}
+}
+
+func TestEntropy_SkipsBase64Images(t *testing.T) {
+	r := entropyOnlyRedactor(t)
+
+	// A realistic chunk of image data: long, high-entropy, with '/' and '+'.
+	var b strings.Builder
+	for b.Len() < 4096 {
+		b.WriteString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9Qz0AEYBxVSF+FAAhKDveksOjmAAAAAElFTkSuQmCC/")
+	}
+	data := b.String()
+
+	image := map[string]interface{}{
+		"type":   "image",
+		"source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": data},
+	}
+	line, _ := json.Marshal(image)
+	if got := r.RedactJSONLine(string(line)); strings.Contains(got, "REDACTED") {
+		t.Error("base64 image data was redacted")
+	}
+
+	uri := "data:image/png;base64,Zx9Qm2Lr7Tk4Wv8Bn3Hc6Jp1Yd5Fs0Ga"
+	if got := r.Redact(uri); got != uri {
+		t.Errorf("data URI was redacted: %q", got)
+	}
+}
+
+func TestEntropy_NamedPatternWins(t *testing.T) {
+	r, err := NewFromConfig(&config.RedactionConfig{
+		Enabled: true,
+		Entropy: &config.EntropyConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	got := r.Redact("key sk-ant-REDACTED")
+	if strings.Contains(got, "HIGH_ENTROPY") || !strings.Contains(got, "[REDACTED:") {
+		t.Errorf("expected the named pattern's marker, got %q", got)
+	}
+}
+
+func TestEntropy_FindMatches(t *testing.T) {
+	r := entropyOnlyRedactor(t)
+
+	const token = "Zx9Qm2Lr7Tk4Wv8Bn3Hc6Jp1Yd5Fs0Ga"
+	line := `{"message":"token ` + token + `"}`
+	got := r.FindMatches(line)
+	if len(got) != 1 || got[0].PatternName != entropyPatternName || line[got[0].Start:got[0].End] != token {
+		t.Errorf("FindMatches = %+v, want one span over the token", got)
+	}
+}
+
+func TestEntropy_DisabledByDefault(t *testing.T) {
+	useDefaults := false
+	r, err := NewFromConfig(&config.RedactionConfig{
+		Enabled:            true,
+		UseDefaultPatterns: &useDefaults,
+		Entropy:            &config.EntropyConfig{Enabled: false},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	if r != nil {
+		t.Error("expected nil redactor with no patterns and entropy disabled")
+	}
+}
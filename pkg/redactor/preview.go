@@ -0,0 +1,107 @@
+package redactor
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// Match is a span of a raw line that a redaction pattern would replace.
+// Start and End are byte offsets into the line as it appears on disk.
+type Match struct {
+	PatternName string
+	Start       int
+	End         int
+}
+
+// jsonStringPairRegex matches a `"key": "value"` pair in raw JSON text. Both
+// submatches are the still-escaped string contents (no surrounding quotes).
+var jsonStringPairRegex = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*"((?:[^"\\]|\\.)*)"`)
+
+// FindMatches reports the spans of line that the configured patterns would
+// redact, without modifying anything. It powers `confab redact --preview`.
+//
+// Spans are located on the raw line text so they can be highlighted in
+// place. Value-based patterns are matched against the whole line; field-based
+// patterns are matched against the values of `"key": "value"` pairs whose key
+// matches FieldPattern, or equals a plain (undotted) entry of FieldNames;
+// dotted field names aren't located in raw text. Because the real redaction walks parsed JSON, spans
+// are a close approximation for values that contain JSON escapes. Results are
+// ordered by Start, then End.
+// Lines carrying the bypass marker report no matches, since they would be
+// uploaded unredacted.
+func (r *Redactor) FindMatches(line string) []Match {
+	if r.bypassMarker != "" {
+		var data interface{}
+		if json.Unmarshal([]byte(line), &data) == nil && r.stripBypassMarker(data) {
+			return nil
+		}
+	}
+
+	var matches []Match
+
+	for _, p := range r.patterns {
+		if p.fieldRegex != nil || p.regex == nil {
+			continue
+		}
+		matches = append(matches, p.findSpans(line, 0)...)
+	}
+	if r.entropy != nil {
+		for _, span := range r.entropy.findSpans(line) {
+			matches = append(matches, Match{PatternName: entropyPatternName, Start: span[0], End: span[1]})
+		}
+	}
+
+	for _, pair := range jsonStringPairRegex.FindAllStringSubmatchIndex(line, -1) {
+		key, err := strconv.Unquote(`"` + line[pair[2]:pair[3]] + `"`)
+		if err != nil {
+			continue
+		}
+		valueStart, valueEnd := pair[4], pair[5]
+		for _, p := range r.patterns {
+			if valueEnd > valueStart && slices.Contains(p.fieldNames, key) {
+				matches = append(matches, Match{PatternName: p.name, Start: valueStart, End: valueEnd})
+			}
+			if p.fieldRegex == nil || !p.fieldRegex.MatchString(key) {
+				continue
+			}
+			if p.regex == nil {
+				if valueEnd > valueStart {
+					matches = append(matches, Match{PatternName: p.name, Start: valueStart, End: valueEnd})
+				}
+				continue
+			}
+			matches = append(matches, p.findSpans(line[valueStart:valueEnd], valueStart)...)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End < matches[j].End
+	})
+	return matches
+}
+
+// findSpans returns the spans this pattern's value regex would replace in s,
+// shifted by offset. Honors captureGroup the same way redactCaptureGroup does.
+func (p compiledPattern) findSpans(s string, offset int) []Match {
+	var spans []Match
+	for _, idx := range p.regex.FindAllStringSubmatchIndex(s, -1) {
+		start, end := idx[0], idx[1]
+		if p.captureGroup > 0 {
+			if len(idx) <= p.captureGroup*2+1 {
+				continue
+			}
+			start, end = idx[p.captureGroup*2], idx[p.captureGroup*2+1]
+			if start == -1 || end == -1 {
+				continue
+			}
+		}
+		spans = append(spans, Match{PatternName: p.name, Start: start + offset, End: end + offset})
+	}
+	return spans
+}
@@ -0,0 +1,79 @@
+package redactor
+
+import (
+	"testing"
+)
+
+func TestFindMatches_ValuePattern(t *testing.T) {
+	r, err := compilePatterns([]Pattern{
+		{Name: "API Key", Pattern: `sk-[A-Za-z0-9]{10}`, Type: "api_key"},
+	})
+	if err != nil {
+		t.Fatalf("compilePatterns: %v", err)
+	}
+
+	line := `{"text":"keys sk-1234567890 and sk-abcdefghij"}`
+	got := r.FindMatches(line)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 matches, got %d: %+v", len(got), got)
+	}
+	for _, m := range got {
+		if m.PatternName != "API Key" {
+			t.Errorf("PatternName = %q, want %q", m.PatternName, "API Key")
+		}
+		if span := line[m.Start:m.End]; len(span) != 13 || span[:3] != "sk-" {
+			t.Errorf("unexpected span %q", span)
+		}
+	}
+	if got[0].Start > got[1].Start {
+		t.Error("matches should be ordered by start offset")
+	}
+}
+
+func TestFindMatches_CaptureGroup(t *testing.T) {
+	r, err := compilePatterns([]Pattern{
+		{Name: "Password", Pattern: `(://[^:/@\s]+:)([^@\s]+)(@)`, Type: "password", CaptureGroup: 2},
+	})
+	if err != nil {
+		t.Fatalf("compilePatterns: %v", err)
+	}
+
+	line := `{"url":"postgres://user:hunter2@db"}`
+	got := r.FindMatches(line)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(got))
+	}
+	if span := line[got[0].Start:got[0].End]; span != "hunter2" {
+		t.Errorf("span = %q, want %q", span, "hunter2")
+	}
+}
+
+func TestFindMatches_FieldPattern(t *testing.T) {
+	r, err := compilePatterns([]Pattern{
+		{Name: "Sensitive Field", FieldPattern: `^password$`, Type: "sensitive_field"},
+	})
+	if err != nil {
+		t.Fatalf("compilePatterns: %v", err)
+	}
+
+	line := `{"user":"alice","password":"s3cret!"}`
+	got := r.FindMatches(line)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(got))
+	}
+	if span := line[got[0].Start:got[0].End]; span != "s3cret!" {
+		t.Errorf("span = %q, want %q", span, "s3cret!")
+	}
+}
+
+func TestFindMatches_NoMatches(t *testing.T) {
+	r, err := compilePatterns([]Pattern{
+		{Name: "API Key", Pattern: `sk-[A-Za-z0-9]{10}`, Type: "api_key"},
+	})
+	if err != nil {
+		t.Fatalf("compilePatterns: %v", err)
+	}
+	if got := r.FindMatches(`{"text":"nothing here"}`); len(got) != 0 {
+		t.Errorf("expected no matches, got %+v", got)
+	}
+}
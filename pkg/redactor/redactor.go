@@ -15,20 +15,31 @@ import (
 // Redactor handles redaction of sensitive data
 type Redactor struct {
 	patterns []compiledPattern
+	// bypassMarker is the top-level field that exempts a JSON line from
+	// redaction (empty = no bypass); see config.RedactionConfig.BypassMarker.
+	bypassMarker string
+	// entropy masks high-entropy tokens after the patterns run (nil =
+	// disabled); see config.RedactionConfig.Entropy.
+	entropy *entropyDetector
 }
 
 // compiledPattern represents a compiled regex pattern with metadata
 type compiledPattern struct {
+	name         string
 	regex        *regexp.Regexp
 	fieldRegex   *regexp.Regexp // nil means apply to all string values
+	fieldNames   []string       // keys or dotted key paths redacted whole
 	patternType  string
 	captureGroup int
+	replacement  string // empty means the default "[REDACTED:TYPE]" marker
 }
 
 // NewFromConfig creates a new Redactor from a config.RedactionConfig.
-// Returns nil if cfg is nil or if no patterns are configured.
+// Returns nil if cfg is nil or if no patterns are configured and entropy
+// redaction is off.
 // Note: This function does NOT check cfg.Enabled - callers should check that.
-// If UseDefaultPatterns is true (default), default patterns are included.
+// If UseDefaultPatterns is true (default), default patterns are included,
+// except those named in DisabledDefaults.
 // Custom patterns from cfg.Patterns are added after default patterns.
 func NewFromConfig(cfg *config.RedactionConfig) (*Redactor, error) {
 	if cfg == nil {
@@ -37,20 +48,27 @@ func NewFromConfig(cfg *config.RedactionConfig) (*Redactor, error) {
 
 	var patterns []Pattern
 
-	// Add default patterns if enabled (default behavior)
-	if cfg.ShouldUseDefaultPatterns() {
-		patterns = append(patterns, convertPatterns(config.GetDefaultRedactionPatterns())...)
-	}
+	// Add default patterns if enabled (default behavior), minus any the user
+	// disabled by name via DisabledDefaults
+	patterns = append(patterns, convertPatterns(cfg.EnabledDefaultPatterns())...)
 
 	// Add custom patterns from config
 	patterns = append(patterns, convertPatterns(cfg.Patterns)...)
 
-	// Return nil if no patterns to apply
-	if len(patterns) == 0 {
+	entropy := newEntropyDetector(cfg.Entropy)
+
+	// Return nil if nothing to apply
+	if len(patterns) == 0 && entropy == nil {
 		return nil, nil
 	}
 
-	return compilePatterns(patterns)
+	r, err := compilePatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	r.bypassMarker = cfg.BypassMarker
+	r.entropy = entropy
+	return r, nil
 }
 
 // convertPatterns converts config.RedactionPattern slice to redactor.Pattern slice.
@@ -63,6 +81,8 @@ func convertPatterns(src []config.RedactionPattern) []Pattern {
 			Type:         p.Type,
 			CaptureGroup: p.CaptureGroup,
 			FieldPattern: p.FieldPattern,
+			FieldNames:   p.FieldNames,
+			Replacement:  p.Replacement,
 		}
 	}
 	return patterns
@@ -74,8 +94,11 @@ func compilePatterns(patterns []Pattern) (*Redactor, error) {
 
 	for _, p := range patterns {
 		cp := compiledPattern{
+			name:         p.Name,
 			patternType:  p.Type,
 			captureGroup: p.CaptureGroup,
+			fieldNames:   p.FieldNames,
+			replacement:  p.Replacement,
 		}
 
 		// Compile value pattern if provided
@@ -96,9 +119,9 @@ func compilePatterns(patterns []Pattern) (*Redactor, error) {
 			cp.fieldRegex = fieldRegex
 		}
 
-		// Validate: must have at least one of Pattern or FieldPattern
-		if cp.regex == nil && cp.fieldRegex == nil {
-			return nil, fmt.Errorf("pattern '%s' must have either pattern or field_pattern", p.Name)
+		// Validate: must have at least one of Pattern, FieldPattern or FieldNames
+		if cp.regex == nil && cp.fieldRegex == nil && len(cp.fieldNames) == 0 {
+			return nil, fmt.Errorf("pattern '%s' must have pattern, field_pattern or field_names", p.Name)
 		}
 
 		compiled = append(compiled, cp)
@@ -115,7 +138,9 @@ func (r *Redactor) Redact(input string) string {
 	return r.applyValuePatterns(input)
 }
 
-// applyValuePatterns applies all value-based patterns (no field context) to the input.
+// applyValuePatterns applies all value-based patterns (no field context) to the input,
+// then entropy redaction if enabled, so tokens a named pattern already caught keep
+// their specific marker.
 // Field-based patterns are skipped since this operates on plain text without field context.
 func (r *Redactor) applyValuePatterns(input string) string {
 	result := input
@@ -125,6 +150,9 @@ func (r *Redactor) applyValuePatterns(input string) string {
 		}
 		result = r.applyRegex(result, p)
 	}
+	if r.entropy != nil {
+		result = r.entropy.redact(result)
+	}
 	return result
 }
 
@@ -174,7 +202,10 @@ func (r *Redactor) RedactJSONL(input []byte) []byte {
 		}
 
 		// Recursively redact string values and re-serialize
-		redacted := r.redactValueWithFieldContext(data, "")
+		redacted := data
+		if !r.stripBypassMarker(data) {
+			redacted = r.redactParsed(data)
+		}
 		output, err := json.Marshal(redacted)
 		if err != nil {
 			// Shouldn't happen, but fall back to original if it does
@@ -199,8 +230,11 @@ func (r *Redactor) RedactJSONLine(line string) string {
 		return r.Redact(line)
 	}
 
-	// Recursively redact string values
-	redacted := r.redactValueWithFieldContext(data, "")
+	// Recursively redact string values, unless the line opts out
+	redacted := data
+	if !r.stripBypassMarker(data) {
+		redacted = r.redactParsed(data)
+	}
 
 	// Re-serialize
 	output, err := json.Marshal(redacted)
@@ -212,6 +246,64 @@ func (r *Redactor) RedactJSONLine(line string) string {
 	return string(output)
 }
 
+// stripBypassMarker removes the bypass marker field from a parsed top-level
+// JSON object and reports whether it was set to true, i.e. whether the line
+// should skip redaction. The marker is removed even when not true, so it
+// never reaches the backend.
+func (r *Redactor) stripBypassMarker(data interface{}) bool {
+	_, bypass := stripMarker(data, r.bypassMarker)
+	return bypass
+}
+
+// stripMarker removes marker from a parsed top-level JSON object, reporting
+// whether it was present and whether it was set to true.
+func stripMarker(data interface{}, marker string) (present, bypass bool) {
+	obj, ok := data.(map[string]interface{})
+	if marker == "" || !ok {
+		return false, false
+	}
+	value, present := obj[marker]
+	if !present {
+		return false, false
+	}
+	delete(obj, marker)
+	return true, value == true
+}
+
+// StripBypassMarker removes the bypass marker field from a JSON line that
+// is uploaded without a Redactor, i.e. with redaction disabled, so the
+// marker never reaches the backend either way. Lines without the marker,
+// and lines that aren't a JSON object, are returned unchanged.
+func StripBypassMarker(line, marker string) string {
+	if marker == "" || !strings.Contains(line, marker) {
+		return line
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(line), &data); err != nil {
+		return line
+	}
+	if present, _ := stripMarker(data, marker); !present {
+		return line
+	}
+	output, err := json.Marshal(data)
+	if err != nil {
+		return line
+	}
+	return string(output)
+}
+
+// redactParsed redacts a parsed JSON line: the regex patterns on every
+// string value, then field-name redaction on the result.
+func (r *Redactor) redactParsed(data interface{}) interface{} {
+	redacted := r.redactValueWithFieldContext(data, "")
+	for _, p := range r.patterns {
+		if len(p.fieldNames) > 0 {
+			redacted = p.redactFieldNames(redacted, nil)
+		}
+	}
+	return redacted
+}
+
 // redactValueWithFieldContext recursively redacts string values in a JSON structure,
 // tracking the current field name for field-based pattern matching.
 func (r *Redactor) redactValueWithFieldContext(v interface{}, fieldName string) interface{} {
@@ -253,7 +345,7 @@ func (r *Redactor) redactStringValue(value, fieldName string) string {
 		if p.regex != nil {
 			result = r.applyRegex(result, p)
 		} else {
-			result = p.redactionMarker()
+			result = p.fieldReplacement(fieldName)
 		}
 	}
 
@@ -261,20 +353,92 @@ func (r *Redactor) redactStringValue(value, fieldName string) string {
 	return r.applyValuePatterns(result)
 }
 
+// redactFieldNames replaces the string values under keys matching the
+// pattern's field names in v, whose key path from the top-level object is
+// path. Array elements share their parent's path. v is modified in place
+// (it is redactValueWithFieldContext's fresh copy).
+func (p compiledPattern) redactFieldNames(v interface{}, path []string) interface{} {
+	switch val := v.(type) {
+	case string:
+		if len(path) > 0 && p.matchesFieldPath(path) {
+			return p.fieldNameReplacement()
+		}
+		return val
+	case map[string]interface{}:
+		for k, child := range val {
+			val[k] = p.redactFieldNames(child, append(path[:len(path):len(path)], k))
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = p.redactFieldNames(child, path)
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+// matchesFieldPath reports whether a value at key path matches one of the
+// pattern's field names: a plain name matches the last key, a dotted name
+// the whole path.
+func (p compiledPattern) matchesFieldPath(path []string) bool {
+	for _, name := range p.fieldNames {
+		if strings.Contains(name, ".") {
+			if name == strings.Join(path, ".") {
+				return true
+			}
+		} else if name == path[len(path)-1] {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldNameReplacement is the value substituted for a field-name match:
+// Replacement used literally, else "[REDACTED:TYPE]", else "[REDACTED]".
+func (p compiledPattern) fieldNameReplacement() string {
+	switch {
+	case p.replacement != "":
+		return p.replacement
+	case p.patternType != "":
+		return p.redactionMarker()
+	default:
+		return "[REDACTED]"
+	}
+}
+
 // redactionMarker returns the redaction placeholder for this pattern, e.g. "[REDACTED:API_KEY]".
 func (p compiledPattern) redactionMarker() string {
 	return fmt.Sprintf("[REDACTED:%s]", strings.ToUpper(p.patternType))
 }
 
+// expandReplacement returns the text substituted for a match of re in src,
+// given the match's submatch indices. Custom replacements have $1-style
+// references expanded; the default marker is used literally.
+func (p compiledPattern) expandReplacement(re *regexp.Regexp, src string, submatches []int) string {
+	if p.replacement == "" {
+		return p.redactionMarker()
+	}
+	return string(re.ExpandString(nil, p.replacement, src, submatches))
+}
+
+// fieldReplacement returns the value that replaces a whole field matched by a
+// field-only pattern, expanding references against the field name match.
+func (p compiledPattern) fieldReplacement(fieldName string) string {
+	return p.expandReplacement(p.fieldRegex, fieldName, p.fieldRegex.FindStringSubmatchIndex(fieldName))
+}
+
 // redactFullMatch replaces the entire match with a redaction marker
 func (r *Redactor) redactFullMatch(input string, p compiledPattern) string {
-	return p.regex.ReplaceAllString(input, p.redactionMarker())
+	if p.replacement == "" {
+		return p.regex.ReplaceAllLiteralString(input, p.redactionMarker())
+	}
+	return p.regex.ReplaceAllString(input, p.replacement)
 }
 
 // redactCaptureGroup replaces only the specified capture group
 func (r *Redactor) redactCaptureGroup(input string, p compiledPattern) string {
-	marker := p.redactionMarker()
-
 	return p.regex.ReplaceAllStringFunc(input, func(match string) string {
 		// Use FindStringSubmatchIndex to get exact positions of capture groups.
 		// This is more reliable than strings.Index, which would find the first
@@ -293,6 +457,6 @@ func (r *Redactor) redactCaptureGroup(input string, p compiledPattern) string {
 			return match
 		}
 
-		return match[:start] + marker + match[end:]
+		return match[:start] + p.expandReplacement(p.regex, match, indices) + match[end:]
 	})
 }
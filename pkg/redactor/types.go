@@ -2,7 +2,7 @@ package redactor
 
 // Pattern represents a single redaction pattern.
 //
-// There are two modes of operation:
+// There are two regex modes of operation, plus FieldNames:
 //
 //  1. Value-based (FieldPattern empty): The Pattern regex is applied to all string
 //     values in the JSON. Use this for secrets with distinctive formats that can
@@ -19,4 +19,11 @@ type Pattern struct {
 	// FieldPattern is a regex that matches JSON field names. When set, only
 	// values of matching fields are considered for redaction.
 	FieldPattern string `json:"field_pattern,omitempty"`
+	// FieldNames lists JSON keys ("password") or dotted key paths
+	// ("auth.password") whose string values are replaced whole, after the
+	// regex patterns ran. Independent of Pattern and FieldPattern.
+	FieldNames []string `json:"field_names,omitempty"`
+	// Replacement overrides the default "[REDACTED:TYPE]" marker and may
+	// reference capture groups with $1 / ${name} syntax.
+	Replacement string `json:"replacement,omitempty"`
 }
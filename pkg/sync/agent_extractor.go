@@ -0,0 +1,82 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ConfabulousDev/confab/pkg/provider"
+)
+
+// AgentExtractor finds the child agent files a transcript or agent file
+// refers to, for agent frameworks whose child files aren't Claude's
+// agent-<id>.jsonl. ReadChunk passes it every transcript and agent line;
+// DiscoverNewFiles looks for ChildFileName(id) under the subagents
+// directory, both for the IDs found and when scanning the directory.
+//
+// IDs still have to pass isValidAgentID, and a ChildFileName containing a
+// path separator is ignored, so a crafted ID can't name a file elsewhere.
+type AgentExtractor interface {
+	// ExtractChildIDs returns the child IDs referenced by one JSONL line
+	// (before redaction). Lines that aren't JSON or reference no children
+	// return nil.
+	ExtractChildIDs(line []byte) []string
+	// ChildFileName is the base name of the child file for id.
+	ChildFileName(id string) string
+}
+
+// messageAgentExtractor is implemented by an AgentExtractor that can work
+// on the message ReadChunk has already decoded, saving a second decode of
+// every line.
+type messageAgentExtractor interface {
+	ExtractChildIDsFromMessage(msg map[string]interface{}) []string
+}
+
+// ClaudeAgentExtractor is the default AgentExtractor: Claude Code's
+// toolUseResult.agentId references to agent-<id>.jsonl files.
+type ClaudeAgentExtractor struct{}
+
+// ExtractChildIDs implements AgentExtractor.
+func (e ClaudeAgentExtractor) ExtractChildIDs(line []byte) []string {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return nil
+	}
+	return e.ExtractChildIDsFromMessage(msg)
+}
+
+// ExtractChildIDsFromMessage extracts agent IDs from a decoded Claude
+// transcript message. Hard-binding to ClaudeCode here is the explicit
+// "this is Claude's transcript schema" signal; see
+// pkg/provider/claude_agentids.go.
+func (ClaudeAgentExtractor) ExtractChildIDsFromMessage(msg map[string]interface{}) []string {
+	return (provider.ClaudeCode{}).ExtractAgentIDsFromMessage(msg)
+}
+
+// ChildFileName implements AgentExtractor.
+func (ClaudeAgentExtractor) ChildFileName(id string) string {
+	return fmt.Sprintf("agent-%s.jsonl", id)
+}
+
+// childFileName is ChildFileName of the tracker's extractor, or "" if the
+// result isn't a plain file name.
+func (t *FileTracker) childFileName(id string) string {
+	name := t.agentExtractor.ChildFileName(id)
+	if name == "" || filepath.Base(name) != name || strings.ContainsAny(name, `/\`) {
+		return ""
+	}
+	return name
+}
+
+// isChildFileName reports whether a file name in the subagents directory
+// follows the extractor's naming, by splitting ChildFileName of a
+// placeholder ID into the prefix and suffix around it.
+func (t *FileTracker) isChildFileName(name string) bool {
+	const placeholder = "\x00"
+	prefix, suffix, ok := strings.Cut(t.agentExtractor.ChildFileName(placeholder), placeholder)
+	if !ok {
+		return false
+	}
+	return len(name) > len(prefix)+len(suffix) && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, suffix)
+}
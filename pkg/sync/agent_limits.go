@@ -0,0 +1,192 @@
+package sync
+
+import (
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+)
+
+// DefaultMaxAgentDepth and DefaultMaxTotalAgentFiles bound agent discovery
+// when neither EngineConfig nor the upload config sets a limit.
+const (
+	DefaultMaxAgentDepth      = 5
+	DefaultMaxTotalAgentFiles = 100
+)
+
+// agentDepth is an agent file's nesting depth: one more than the file
+// whose line referenced it (the transcript is depth 0). guessed marks a
+// depth that doesn't come from such a reference.
+type agentDepth struct {
+	n       int
+	guessed bool
+}
+
+// shallowerThan reports whether d should replace other: a referenced depth
+// beats a guessed one, then the shallower wins.
+func (d agentDepth) shallowerThan(other agentDepth) bool {
+	if d.guessed != other.guessed {
+		return !d.guessed
+	}
+	return d.n < other.n
+}
+
+// admitAgentFiles sorts a SyncAll queue by the agent limits. Files other
+// than agents are ready. An agent nothing has referenced yet — a running
+// subagent found by the subagents directory scan, a provider descendant,
+// a file from backend state — waits while other files might still
+// reference it, then counts as depth 1 until a reference gives its real
+// depth. Agents at maxAgentDepth or deeper are capped: never uploaded, but
+// still read for the agents they reference, so those get their real
+// depth too rather than passing as unreferenced. New agents past
+// maxTotalAgentFiles are dropped. Both are warned about once.
+func (e *Engine) admitAgentFiles(files []*TrackedFile) (ready, capped, waiting []*TrackedFile) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	admit := func(f *TrackedFile) {
+		switch {
+		case e.agentCapped(f.Name):
+			capped = append(capped, f)
+		case e.agentAllowed(f.Name):
+			ready = append(ready, f)
+		}
+	}
+	for _, f := range files {
+		switch {
+		case f.Type != provider.FileTypeAgent:
+			ready = append(ready, f)
+		case e.cappedAgents[f.Name]:
+			capped = append(capped, f)
+		default:
+			if _, ok := e.agentDepths[f.Name]; ok {
+				admit(f)
+			} else {
+				waiting = append(waiting, f)
+			}
+		}
+	}
+	if len(ready) > 0 || len(capped) > 0 {
+		return ready, capped, waiting
+	}
+
+	for _, f := range waiting {
+		e.setAgentDepth(f.Name, agentDepth{n: 1, guessed: true})
+		admit(f)
+	}
+	return ready, capped, nil
+}
+
+// recordAgentDepths applies the depths of the agent files referenced in a
+// BFS iteration. A capped agent stays capped: its lines have been read
+// without being uploaded.
+func (e *Engine) recordAgentDepths(depths map[string]agentDepth) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for name, depth := range depths {
+		if e.cappedAgents[name] {
+			continue
+		}
+		if known, ok := e.agentDepths[name]; !ok || depth.shallowerThan(known) {
+			e.setAgentDepth(name, depth)
+		}
+	}
+}
+
+// setAgentDepth records an agent file's depth. Caller holds e.mu.
+func (e *Engine) setAgentDepth(name string, depth agentDepth) {
+	if e.agentDepths == nil {
+		e.agentDepths = make(map[string]agentDepth)
+	}
+	e.agentDepths[name] = depth
+}
+
+// agentCapped reports whether an agent file of known depth is at
+// maxAgentDepth or deeper, marking it capped for good if so. Caller holds
+// e.mu.
+func (e *Engine) agentCapped(name string) bool {
+	depth := e.agentDepths[name].n
+	if depth < e.maxAgentDepth {
+		return false
+	}
+	if e.cappedAgents == nil {
+		e.cappedAgents = make(map[string]bool)
+	}
+	e.cappedAgents[name] = true
+	e.warnAgentLimit(name, "Agent file is nested too deeply, not syncing it",
+		"depth", depth, "max_agent_depth", e.maxAgentDepth)
+	return true
+}
+
+// agentAllowed reports whether an agent file is within maxTotalAgentFiles,
+// counting it the first time it is. Caller holds e.mu.
+func (e *Engine) agentAllowed(name string) bool {
+	if e.admittedAgents[name] {
+		return true
+	}
+	if len(e.admittedAgents) >= e.maxTotalAgentFiles {
+		e.warnAgentLimit(name, "Too many agent files in this session, not syncing another",
+			"max_total_agent_files", e.maxTotalAgentFiles)
+		return false
+	}
+	if e.admittedAgents == nil {
+		e.admittedAgents = make(map[string]bool)
+	}
+	e.admittedAgents[name] = true
+	return true
+}
+
+// referencedAgentDepth is the depth of the agents file's lines reference,
+// and whether it is only a guess. Caller holds e.mu.
+func (e *Engine) referencedAgentDepth(file *TrackedFile) agentDepth {
+	parent := e.agentDepths[file.Name] // zero for the transcript
+	return agentDepth{n: parent.n + 1, guessed: parent.guessed}
+}
+
+// scanCappedAgent reads a capped agent's new lines for the agents they
+// reference, without uploading them, and moves the local read position
+// past them.
+func (e *Engine) scanCappedAgent(file *TrackedFile, cycle *syncCycle) {
+	if !e.tracker.BeginSync(file) {
+		return
+	}
+	defer e.tracker.EndSync(file)
+	if !e.tracker.HasFileChanged(file) {
+		return
+	}
+	for {
+		chunk, err := e.tracker.readChunk(file, nil, DefaultMaxChunkBytes, DefaultMaxChunkBytes)
+		if err != nil {
+			logger.Warn("Failed to read capped agent file", "component", "sync", "file", file.Name, "error", err)
+			return
+		}
+		if chunk == nil {
+			return
+		}
+		e.mu.Lock()
+		e.noteReferencedAgents(file, chunk, cycle)
+		e.mu.Unlock()
+		e.tracker.UpdateAfterSync(file, chunk.FirstLine+len(chunk.Lines)-1, chunk.NewOffset)
+	}
+}
+
+// noteReferencedAgents records the agents a chunk references for
+// discovery. Caller holds e.mu.
+func (e *Engine) noteReferencedAgents(file *TrackedFile, chunk *Chunk, cycle *syncCycle) {
+	cycle.agentIDs = append(cycle.agentIDs, chunk.AgentIDs...)
+	depth := e.referencedAgentDepth(file)
+	for _, id := range chunk.AgentIDs {
+		cycle.noteAgentDepth(e.tracker.childFileName(id), depth)
+	}
+}
+
+// warnAgentLimit logs a skipped agent file once per engine rather than on
+// every cycle. Caller holds e.mu.
+func (e *Engine) warnAgentLimit(name, msg string, keyvals ...any) {
+	if e.agentLimitWarned[name] {
+		return
+	}
+	if e.agentLimitWarned == nil {
+		e.agentLimitWarned = make(map[string]bool)
+	}
+	e.agentLimitWarned[name] = true
+	logger.With("component", "sync", "file", name).Warn(msg, keyvals...)
+}
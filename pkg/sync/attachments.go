@@ -0,0 +1,248 @@
+package sync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+)
+
+// MaxAttachmentBytes is the largest attachment file uploaded; larger ones
+// are skipped with a warning.
+const MaxAttachmentBytes = 512 * 1024
+
+// attachmentNamePrefix starts the backend file name of every attachment
+// (see attachmentFileName).
+const attachmentNamePrefix = "attachment-"
+
+// ChunkAttachment is the content of an attachment file, sent in place of
+// lines (ChunkRequest.Attachment). Attachments aren't JSONL, so the whole
+// file goes in one request, base64-encoded.
+type ChunkAttachment struct {
+	Encoding string `json:"encoding"` // always "base64"
+	Size     int    `json:"size"`     // decoded size in bytes
+	Data     string `json:"data"`
+}
+
+// attachmentBackend is implemented by backends that can store attachment
+// files (the HTTP client). With other backends attachments are skipped.
+type attachmentBackend interface {
+	UploadAttachment(sessionID, fileName string, data []byte) (int, error)
+}
+
+// UploadAttachment uploads an attachment file's content as a chunk of file
+// type "attachment": FirstLine 1, no lines, and the content in
+// ChunkRequest.Attachment. Returns the backend's last synced line (1 once
+// stored).
+func (c *Client) UploadAttachment(sessionID, fileName string, data []byte) (int, error) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	req := ChunkRequest{
+		SessionID: sessionID,
+		FileName:  fileName,
+		FileType:  provider.FileTypeAttachment,
+		FirstLine: 1,
+		Lines:     []string{},
+		Attachment: &ChunkAttachment{
+			Encoding: "base64",
+			Size:     len(data),
+			Data:     encoded,
+		},
+	}
+
+	if c.inFlight != nil {
+		size := int64(len(encoded))
+		c.inFlight.acquire(size)
+		defer c.inFlight.release(size)
+	}
+
+	var resp ChunkResponse
+	headers := map[string]string{IdempotencyKeyHeader: ChunkIdempotencyKey(sessionID, fileName, 1, []string{encoded})}
+	err := c.do(c.withRetries(func() error {
+		return c.httpClient.PostWithHeaders("/api/v1/sync/chunk", headers, req, &resp, c.throttleBody)
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("attachment upload failed: %w", err)
+	}
+	return resp.LastSyncedLine, nil
+}
+
+// attachmentPathsFromMessage returns the file paths of document
+// attachments in a transcript line's tool results:
+//
+//	{"type":"tool_result","content":[{"type":"document","source":{"type":"file","path":"/abs/report.pdf"}}]}
+//
+// The tool_result may be the line itself or nested anywhere in it (Claude
+// puts them in message.content). Only absolute paths are returned.
+func attachmentPathsFromMessage(msg map[string]any) []string {
+	var paths []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if v["type"] == "tool_result" {
+				paths = append(paths, documentPaths(v["content"])...)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(msg)
+	return paths
+}
+
+// documentPaths returns the source paths of the file documents in a
+// tool_result's content array.
+func documentPaths(content any) []string {
+	items, _ := content.([]any)
+	var paths []string
+	for _, item := range items {
+		doc, _ := item.(map[string]any)
+		if doc["type"] != "document" {
+			continue
+		}
+		source, _ := doc["source"].(map[string]any)
+		path, _ := source["path"].(string)
+		if source["type"] != "file" || path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			logger.Debugf("Skipping attachment with relative path: %s", path)
+			continue
+		}
+		paths = append(paths, filepath.Clean(path))
+	}
+	return paths
+}
+
+// attachmentFileName is the backend file name for the attachment at path:
+// the base name, prefixed with a hash of the full path so two files with
+// the same name don't collide.
+func attachmentFileName(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return attachmentNamePrefix + hex.EncodeToString(sum[:6]) + "-" + filepath.Base(path)
+}
+
+// isAttachmentName reports whether a backend file name is an attachment's.
+func isAttachmentName(name string) bool {
+	return strings.HasPrefix(name, attachmentNamePrefix)
+}
+
+// DiscoverAttachments starts tracking the attachment files at paths that
+// aren't tracked yet, and returns them. Each path is considered once: a
+// missing file, a directory or one over MaxAttachmentBytes is skipped (the
+// last with a warning) and not retried.
+func (t *FileTracker) DiscoverAttachments(paths []string) []*TrackedFile {
+	t.discoverMu.Lock()
+	defer t.discoverMu.Unlock()
+	var newFiles []*TrackedFile
+	for _, path := range paths {
+		if t.knownAttachments[path] {
+			continue
+		}
+		t.knownAttachments[path] = true
+
+		name := attachmentFileName(path)
+		if t.IsTracked(name) {
+			t.mu.Lock()
+			if f := t.files[name]; f.Path == "" {
+				// Known from backend state only; now we know where it is.
+				f.Path = path
+			}
+			t.mu.Unlock()
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			logger.Debugf("Skipping attachment that isn't a readable file: %s", path)
+			continue
+		}
+		if info.Size() > MaxAttachmentBytes {
+			logger.Warn("Skipping attachment larger than the attachment size limit",
+				"component", "sync", "file", path, "size", info.Size(), "max", MaxAttachmentBytes)
+			continue
+		}
+
+		tracked := &TrackedFile{Path: path, Name: name, Type: provider.FileTypeAttachment}
+		t.mu.Lock()
+		t.files[name] = tracked
+		t.mu.Unlock()
+		newFiles = append(newFiles, tracked)
+	}
+	return newFiles
+}
+
+// isTextAttachment reports whether an attachment is text the redactor can
+// scrub: valid UTF-8 without NUL bytes.
+func isTextAttachment(data []byte) bool {
+	return utf8.Valid(data) && !bytes.Contains(data, []byte{0})
+}
+
+// syncAttachment uploads an attachment file whole. Attachments are sent
+// once: after a successful upload (LastSyncedLine 1) a changed file is
+// not re-sent. A file grown past MaxAttachmentBytes, or a backend that
+// can't store attachments, skips it until the file changes again.
+//
+// With redaction on, a text attachment is run through the redactor like a
+// transcript line, and a binary one (a PDF, an image) is skipped, since
+// secrets in it can't be found or scrubbed.
+func (e *Engine) syncAttachment(file *TrackedFile, cycle *syncCycle) {
+	if file.LastSyncedLine > 0 {
+		return
+	}
+	log := logger.With("component", "sync", "file", file.Name, "path", file.Path)
+	backend, ok := e.backend.(attachmentBackend)
+	if !ok {
+		log.Debug("Backend doesn't store attachments; skipping")
+		e.tracker.UpdateAfterSync(file, 0, 0)
+		return
+	}
+	data, err := os.ReadFile(file.Path)
+	if err != nil {
+		log.Error("Failed to read attachment", "error", err)
+		e.mu.Lock()
+		cycle.fail(err)
+		e.mu.Unlock()
+		return
+	}
+	if len(data) > MaxAttachmentBytes {
+		log.Warn("Skipping attachment larger than the attachment size limit", "size", len(data), "max", MaxAttachmentBytes)
+		e.tracker.UpdateAfterSync(file, 0, 0)
+		return
+	}
+	if e.redactor != nil {
+		if !isTextAttachment(data) {
+			log.Warn("Skipping binary attachment: redaction is enabled and it can't be redacted")
+			e.tracker.UpdateAfterSync(file, 0, 0)
+			return
+		}
+		data = []byte(e.redactor.Redact(string(data)))
+	}
+
+	if _, err := backend.UploadAttachment(e.sessionID, file.Name, data); err != nil {
+		log.Error("Failed to upload attachment", "error", err)
+		e.mu.Lock()
+		cycle.fail(err)
+		e.mu.Unlock()
+		return
+	}
+	e.tracker.UpdateAfterSync(file, 1, int64(len(data)))
+
+	e.mu.Lock()
+	cycle.chunks++
+	e.bytesUploaded += int64(len(data))
+	e.mu.Unlock()
+	log.Debug("Attachment uploaded", "size", len(data))
+}
@@ -0,0 +1,110 @@
+package sync
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults for adaptive chunk sizing (see adaptiveChunker).
+const (
+	DefaultTargetChunkDuration = 5 * time.Second
+	DefaultMinChunkBytes       = 256 * 1024
+)
+
+// chunkerAlpha weights the newest upload in the rolling averages.
+const chunkerAlpha = 0.5
+
+// ChunkSizing is the adaptive chunker's learned state, persisted by the
+// daemon so a restarted engine starts from the last estimate instead of
+// the full DefaultMaxChunkBytes.
+type ChunkSizing struct {
+	ChunkBytes  int           `json:"chunk_bytes"`
+	AvgDuration time.Duration `json:"avg_duration_ns"`
+	AvgBytes    float64       `json:"avg_bytes"`
+}
+
+// adaptiveChunker picks the byte limit for the next chunk from how long
+// recent uploads took. It keeps rolling averages of upload duration and
+// chunk size; when the average duration exceeds target the limit is
+// halved, and when it is under 40% of target (2s for the 5s default) it
+// grows by a quarter, within [minBytes, maxBytes]. The averages restart
+// whenever the limit changes, since samples taken at the old size no
+// longer describe the new one. Safe for concurrent use.
+type adaptiveChunker struct {
+	target   time.Duration
+	minBytes int
+	maxBytes int
+
+	mu          sync.Mutex
+	limit       int
+	avgDuration time.Duration
+	avgBytes    float64
+	samples     int
+}
+
+func newAdaptiveChunker(target time.Duration, minBytes, maxBytes int) *adaptiveChunker {
+	if target <= 0 {
+		target = DefaultTargetChunkDuration
+	}
+	if minBytes <= 0 {
+		minBytes = DefaultMinChunkBytes
+	}
+	minBytes = min(minBytes, maxBytes)
+	return &adaptiveChunker{target: target, minBytes: minBytes, maxBytes: maxBytes, limit: maxBytes}
+}
+
+// Limit returns the byte limit for the next chunk.
+func (c *adaptiveChunker) Limit() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit
+}
+
+// Observe records an accepted chunk of n bytes that took d to upload and
+// adjusts the limit.
+func (c *adaptiveChunker) Observe(n int64, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.samples == 0 {
+		c.avgDuration, c.avgBytes = d, float64(n)
+	} else {
+		c.avgDuration = time.Duration(chunkerAlpha*float64(d) + (1-chunkerAlpha)*float64(c.avgDuration))
+		c.avgBytes = chunkerAlpha*float64(n) + (1-chunkerAlpha)*c.avgBytes
+	}
+	c.samples++
+
+	next := c.limit
+	switch {
+	case c.avgDuration > c.target:
+		next = max(c.limit/2, c.minBytes)
+	case c.avgDuration < c.target*2/5 && c.avgBytes >= float64(c.limit)/2:
+		// Only grow when chunks actually fill the limit; small chunks are
+		// fast because there was little to send, not because the link is.
+		next = min(c.limit+c.limit/4, c.maxBytes)
+	}
+	if next != c.limit {
+		c.limit = next
+		c.samples = 0
+	}
+}
+
+// Sizing snapshots the chunker's state for persistence.
+func (c *adaptiveChunker) Sizing() ChunkSizing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ChunkSizing{ChunkBytes: c.limit, AvgDuration: c.avgDuration, AvgBytes: c.avgBytes}
+}
+
+// Seed restores persisted state, clamping the limit to the current bounds.
+func (c *adaptiveChunker) Seed(s ChunkSizing) {
+	if s.ChunkBytes <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limit = min(max(s.ChunkBytes, c.minBytes), c.maxBytes)
+	c.avgDuration, c.avgBytes = s.AvgDuration, s.AvgBytes
+	if s.AvgDuration > 0 {
+		c.samples = 1
+	}
+}
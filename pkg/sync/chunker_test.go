@@ -0,0 +1,126 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// simulateLink feeds c uploads over a link of bps bytes per second, each
+// chunk filling the current limit, and returns the upload durations.
+func simulateLink(c *adaptiveChunker, bps float64, uploads int) []time.Duration {
+	durations := make([]time.Duration, uploads)
+	for i := range durations {
+		n := c.Limit()
+		durations[i] = time.Duration(float64(n) / bps * float64(time.Second))
+		c.Observe(int64(n), durations[i])
+	}
+	return durations
+}
+
+func TestAdaptiveChunkerConvergesToTarget(t *testing.T) {
+	const mb = 1024 * 1024
+	for _, bps := range []float64{0.5 * mb, 1 * mb, 2 * mb} {
+		t.Run(fmt.Sprintf("%.1fMBps", bps/mb), func(t *testing.T) {
+			c := newAdaptiveChunker(0, 0, DefaultMaxChunkBytes)
+			durations := simulateLink(c, bps, 10)
+			// From the 6th upload on, every upload is within target.
+			for i, d := range durations[5:] {
+				if d > DefaultTargetChunkDuration {
+					t.Errorf("upload %d took %v, want at most %v (durations %v)", i+6, d, DefaultTargetChunkDuration, durations)
+				}
+			}
+			if c.Limit() < DefaultMinChunkBytes {
+				t.Errorf("limit %d fell below the minimum %d", c.Limit(), DefaultMinChunkBytes)
+			}
+		})
+	}
+}
+
+func TestAdaptiveChunkerGrowsBackWhenFast(t *testing.T) {
+	c := newAdaptiveChunker(0, 0, DefaultMaxChunkBytes)
+	c.Seed(ChunkSizing{ChunkBytes: 1024 * 1024})
+	simulateLink(c, 100*1024*1024, 20) // fast link: every upload is well under 2s
+	if c.Limit() != DefaultMaxChunkBytes {
+		t.Errorf("limit = %d after fast uploads, want back at the max %d", c.Limit(), DefaultMaxChunkBytes)
+	}
+
+	// Small chunks are fast because there was little to send; they don't
+	// grow the limit.
+	c.Seed(ChunkSizing{ChunkBytes: 1024 * 1024})
+	for range 20 {
+		c.Observe(100, time.Millisecond)
+	}
+	if c.Limit() != 1024*1024 {
+		t.Errorf("limit = %d after tiny chunks, want it unchanged", c.Limit())
+	}
+}
+
+func TestAdaptiveChunkerSeedClampsAndRestores(t *testing.T) {
+	c := newAdaptiveChunker(time.Second, 4096, 1<<20)
+	c.Seed(ChunkSizing{ChunkBytes: 10})
+	if c.Limit() != 4096 {
+		t.Errorf("seeded limit = %d, want clamped to min 4096", c.Limit())
+	}
+	c.Seed(ChunkSizing{ChunkBytes: 1 << 30})
+	if c.Limit() != 1<<20 {
+		t.Errorf("seeded limit = %d, want clamped to max", c.Limit())
+	}
+
+	c.Observe(1<<20, 3*time.Second)
+	restored := newAdaptiveChunker(time.Second, 4096, 1<<20)
+	restored.Seed(c.Sizing())
+	if restored.Sizing() != c.Sizing() {
+		t.Errorf("restored sizing = %+v, want %+v", restored.Sizing(), c.Sizing())
+	}
+}
+
+func TestReadChunk_LineOverSoftLimitSentAlone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.jsonl")
+	big := `{"type":"user","text":"` + strings.Repeat("x", 200) + `"}`
+	os.WriteFile(path, []byte(big+"\n"+`{"type":"assistant"}`+"\n"), 0644)
+
+	tracker := NewFileTracker(path)
+	tracker.InitFromBackendState(nil)
+	file := tracker.GetTrackedFiles()[0]
+
+	chunk, err := tracker.readChunk(file, nil, 64, DefaultMaxChunkBytes)
+	if err != nil {
+		t.Fatalf("readChunk: %v", err)
+	}
+	if len(chunk.Lines) != 1 || chunk.Lines[0] != big {
+		t.Fatalf("chunk lines = %q, want just the oversized line", chunk.Lines)
+	}
+	if _, err := tracker.ReadChunk(file, nil, 64); err == nil {
+		t.Error("ReadChunk with a hard limit below the line succeeded, want an error")
+	}
+}
+
+// BenchmarkAdaptiveChunkerConvergence reports how many uploads the chunker
+// needs before upload durations stay within target, across link speeds.
+// Run with:
+//
+//	go test ./pkg/sync -run '^$' -bench AdaptiveChunkerConvergence
+func BenchmarkAdaptiveChunkerConvergence(b *testing.B) {
+	const mb = 1024 * 1024
+	for _, bps := range []float64{0.5 * mb, 1 * mb, 2 * mb} {
+		b.Run(fmt.Sprintf("%.1fMBps", bps/mb), func(b *testing.B) {
+			var converged int
+			for i := 0; i < b.N; i++ {
+				c := newAdaptiveChunker(0, 0, DefaultMaxChunkBytes)
+				durations := simulateLink(c, bps, 20)
+				converged = len(durations)
+				for j := len(durations) - 1; j >= 0 && durations[j] <= DefaultTargetChunkDuration; j-- {
+					converged = j
+				}
+			}
+			if converged > 5 {
+				b.Fatalf("took %d uploads to converge, want at most 5", converged)
+			}
+			b.ReportMetric(float64(converged), "uploads-to-target")
+		})
+	}
+}
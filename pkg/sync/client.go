@@ -1,31 +1,116 @@
 package sync
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/ConfabulousDev/confab/pkg/git"
 	"github.com/ConfabulousDev/confab/pkg/http"
+	"github.com/ConfabulousDev/confab/pkg/logger"
 	"github.com/ConfabulousDev/confab/pkg/provider"
-	"github.com/ConfabulousDev/confab/pkg/utils"
+	"golang.org/x/time/rate"
 )
 
 // Client handles communication with the sync API endpoints
 type Client struct {
 	httpClient *http.Client
+	// uploadLimiter caps the combined body rate of this client's chunk
+	// uploads (nil = unlimited).
+	uploadLimiter *rate.Limiter
+	// inFlight bounds the total size of chunk bodies being uploaded at
+	// once (nil = unlimited).
+	inFlight *inFlightLimiter
+	// multipart sends large chunk bodies in resumable parts (nil = off;
+	// see multipart.go).
+	multipart *multipartUploads
+	// breaker short-circuits requests while the backend keeps failing.
+	breaker *http.CircuitBreaker
+	// maxRetries and baseBackoff configure in-client retries of init and
+	// chunk requests (see retry.go).
+	maxRetries  int
+	baseBackoff time.Duration
+
+	// Token refresh state (see refresh.go). binding is where refreshed
+	// tokens are saved; refreshToken is empty for plain API keys, which
+	// are never refreshed. tokenMu serializes refreshes.
+	binding      config.Binding
+	tokenMu      sync.Mutex
+	refreshToken string
+	expiresAt    time.Time
 }
 
-// NewClient creates a new sync API client
-func NewClient(cfg *config.UploadConfig) (*Client, error) {
-	httpClient, err := http.NewClient(cfg, utils.DefaultHTTPTimeout)
+// NewClient creates a new sync API client. compressionLevel selects the zstd
+// level for uploads (1–11); 0 falls back to cfg.CompressionLevel, and then to
+// the default level.
+func NewClient(cfg *config.UploadConfig, compressionLevel int) (*Client, error) {
+	if compressionLevel == 0 {
+		compressionLevel = cfg.CompressionLevel
+	}
+	httpClient, err := http.NewClientWithCompressionLevel(cfg, cfg.RequestTimeout(), compressionLevel)
 	if err != nil {
 		return nil, err
 	}
-	return &Client{
-		httpClient: httpClient,
-	}, nil
+	c := &Client{
+		httpClient:    httpClient,
+		uploadLimiter: newUploadLimiter(cfg.MaxUploadBytesPerSecond),
+		breaker:       http.NewCircuitBreaker(http.CircuitBreakerConfig{}),
+		maxRetries:    cfg.MaxRetries,
+		baseBackoff:   time.Duration(cfg.BaseBackoffMS) * time.Millisecond,
+		binding:       cfg.CredentialBinding(),
+		refreshToken:  cfg.RefreshToken,
+		expiresAt:     cfg.ExpiresAt,
+	}
+	if c.baseBackoff == 0 {
+		c.baseBackoff = config.DefaultBaseBackoffMS * time.Millisecond
+	}
+	if cfg.MaxInFlightBytes > 0 {
+		c.inFlight = newInFlightLimiter(cfg.MaxInFlightBytes)
+	}
+	return c, nil
+}
+
+// do runs one backend call through the circuit breaker: it returns
+// http.ErrCircuitOpen without calling when the circuit is open, and records
+// the call's outcome otherwise, where only 5xx and transport errors count
+// as failures (http.IsBackendFailure). A call rejected as unauthorized is retried
+// once if the access token could be refreshed.
+func (c *Client) do(call func() error) error {
+	if err := c.breaker.Allow(); err != nil {
+		return err
+	}
+	usedKey := c.httpClient.APIKey()
+	err := call()
+	if errors.Is(err, http.ErrUnauthorized) && c.refreshAfterUnauthorized(usedKey) {
+		err = call()
+	}
+	c.breaker.Record(!http.IsBackendFailure(err))
+	return err
+}
+
+// endpointLabel turns a backend request path into a metrics label by
+// replacing the session ID in /api/v1/sessions/{id}/... with "{id}", so
+// per-session paths don't each become their own series.
+func endpointLabel(path string) string {
+	const prefix = "/api/v1/sessions/"
+	rest, ok := strings.CutPrefix(path, prefix)
+	if !ok {
+		return path
+	}
+	if _, tail, found := strings.Cut(rest, "/"); found {
+		return prefix + "{id}/" + tail
+	}
+	return prefix + "{id}"
 }
 
 // InitMetadata contains optional metadata for session initialization
@@ -34,6 +119,9 @@ type InitMetadata struct {
 	GitInfo  json.RawMessage `json:"git_info,omitempty"`
 	Hostname string          `json:"hostname,omitempty"`
 	Username string          `json:"username,omitempty"`
+	// Static is the configured static_metadata (org-wide tags such as
+	// department or cost_center); omitted when none is configured.
+	Static map[string]string `json:"static,omitempty"`
 }
 
 // InitRequest is the request body for POST /api/v1/sync/init
@@ -42,6 +130,25 @@ type InitRequest struct {
 	ExternalID     string        `json:"external_id"`
 	TranscriptPath string        `json:"transcript_path"`
 	Metadata       *InitMetadata `json:"metadata,omitempty"`
+	// FileLineCounts is the local line count per known file (backend file
+	// name → lines), sent only when enabled so the backend can spot
+	// mismatches with its own sync state early.
+	FileLineCounts map[string]int `json:"file_line_counts,omitempty"`
+	// TotalLineCount is the transcript's line count at init (estimated for
+	// very large files, see EstimateLines), so the backend knows the
+	// session's size before the first chunk arrives. 0 when unknown.
+	TotalLineCount int `json:"total_line_count,omitempty"`
+	// EstimatedDurationSeconds is TotalLineCount × SecondsPerLine, a rough
+	// guide for prioritizing sync jobs and estimating completion.
+	EstimatedDurationSeconds float64 `json:"estimated_duration_seconds,omitempty"`
+}
+
+// InitOptions are the optional fields of an init request. A nil
+// *InitOptions sends none of them.
+type InitOptions struct {
+	FileLineCounts           map[string]int
+	TotalLineCount           int
+	EstimatedDurationSeconds float64
 }
 
 // InitResponse is the response for POST /api/v1/sync/init
@@ -63,6 +170,12 @@ type ChunkRequest struct {
 	FirstLine int            `json:"first_line"`
 	Lines     []string       `json:"lines"`
 	Metadata  *ChunkMetadata `json:"metadata,omitempty"`
+	// Checksum is ChunkChecksum of Lines, so the backend can reject a
+	// body corrupted on the way. Older backends ignore it.
+	Checksum string `json:"checksum,omitempty"`
+	// Attachment carries the content of an attachment file (file type
+	// "attachment"), which is sent whole with no lines.
+	Attachment *ChunkAttachment `json:"attachment,omitempty"`
 }
 
 // ChunkMetadata contains metadata sent to the backend with a chunk
@@ -87,6 +200,41 @@ type ChunkMetadata struct {
 	// NOTE: accepted on the wire but not yet persisted by the backend (pending
 	// a confab-web migration), so it is currently forward-looking/inert.
 	Model string `json:"model,omitempty"`
+
+	// SourceModTime is the modification time (UTC) of the file the chunk
+	// was read from, as of the read, so the backend can spot out-of-order
+	// or stale uploads. Only sent when EngineConfig.SendSourceModTime (or
+	// config send_source_mod_time) is on.
+	SourceModTime *time.Time `json:"source_mod_time,omitempty"`
+
+	// ModelName is the model of the chunk's last assistant message and
+	// TokenUsage the sum of their usage objects, read from Claude
+	// transcript and agent lines by ReadChunk. Unlike Model, which the
+	// engine sets for Cursor from the hook payload, these come from the
+	// file content. An assistant message split over several lines (one
+	// per content block, each repeating the usage) is counted once per
+	// chunk. Omitted when the chunk has no assistant lines.
+	ModelName  string      `json:"model_name,omitempty"`
+	TokenUsage *TokenUsage `json:"token_usage,omitempty"`
+
+	// Static repeats InitMetadata.Static on every chunk, so the tags reach
+	// the backend even for sessions it initialized before they were set.
+	Static map[string]string `json:"static,omitempty"`
+
+	// AgentDepth is an agent file's nesting depth below the transcript (1
+	// for an agent the transcript references), so the backend can check
+	// it against its own limit. Omitted for other files.
+	AgentDepth int `json:"agent_depth,omitempty"`
+}
+
+// TokenUsage is the summed token usage of a chunk's assistant messages.
+// TotalTokens is the sum of the other four.
+type TokenUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens,omitempty"`
+	TotalTokens              int64 `json:"total_tokens"`
 }
 
 // CodexRolloutMetadata is the per-rollout metadata transmitted on the FIRST
@@ -117,17 +265,23 @@ type EventResponse struct {
 // Init initializes or resumes a sync session
 // Returns the session ID and current sync state for all files. The
 // providerName must be a canonical provider name (callers via Engine.Init
-// pass e.provider.Name(), which is always non-empty).
-func (c *Client) Init(providerName, externalID, transcriptPath string, metadata *InitMetadata) (*InitResponse, error) {
+// pass e.provider.Name(), which is always non-empty). opts is optional
+// (nil omits its fields).
+func (c *Client) Init(providerName, externalID, transcriptPath string, metadata *InitMetadata, opts *InitOptions) (*InitResponse, error) {
 	req := InitRequest{
 		Provider:       providerName,
 		ExternalID:     externalID,
 		TranscriptPath: transcriptPath,
 		Metadata:       metadata,
 	}
+	if opts != nil {
+		req.FileLineCounts = opts.FileLineCounts
+		req.TotalLineCount = opts.TotalLineCount
+		req.EstimatedDurationSeconds = opts.EstimatedDurationSeconds
+	}
 
 	var resp InitResponse
-	if err := c.httpClient.Post("/api/v1/sync/init", req, &resp); err != nil {
+	if err := c.do(c.withRetries(func() error { return c.httpClient.Post("/api/v1/sync/init", req, &resp) })); err != nil {
 		return nil, fmt.Errorf("sync init failed: %w", err)
 	}
 
@@ -157,14 +311,53 @@ type Capabilities struct {
 // sentinel (http.ErrSessionNotFound) is preserved via %w for errors.Is.
 func (c *Client) Capabilities() (Capabilities, error) {
 	var caps Capabilities
-	if err := c.httpClient.Get("/api/v1/capabilities", &caps); err != nil {
+	if err := c.do(func() error { return c.httpClient.Get("/api/v1/capabilities", &caps) }); err != nil {
 		return Capabilities{}, fmt.Errorf("capabilities probe failed: %w", err)
 	}
 	return caps, nil
 }
 
+// IdempotencyKeyHeader carries ChunkIdempotencyKey on chunk uploads.
+const IdempotencyKeyHeader = "X-Idempotency-Key"
+
+// ChunkIdempotencyKey identifies a chunk upload so a backend that stored
+// it but whose response was lost can answer the re-send with the stored
+// response instead of a contiguity error: the SHA-256 of the session ID,
+// file name, first line number and first line (NUL-separated, so fields
+// can't run into each other), as 32 hex characters. It doesn't cover the
+// later lines, so a re-send cut at a different length gets the original
+// response, whose last_synced_line is still where the file stands.
+func ChunkIdempotencyKey(sessionID, fileName string, firstLine int, lines []string) string {
+	var first string
+	if len(lines) > 0 {
+		first = lines[0]
+	}
+	sum := sha256.Sum256([]byte(sessionID + "\x00" + fileName + "\x00" + strconv.Itoa(firstLine) + "\x00" + first))
+	return hex.EncodeToString(sum[:])[:32]
+}
+
+// ChunkChecksum is the SHA-256, as 64 hex characters, of a chunk's lines
+// concatenated with each one followed by a newline (the JSONL they came
+// from, after redaction), so a line boundary can't move without changing
+// it. An empty chunk has no checksum.
+func ChunkChecksum(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, line := range lines {
+		io.WriteString(h, line)
+		io.WriteString(h, "\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // UploadChunk uploads a chunk of lines for a file with optional metadata
-// Returns the new last synced line number
+// Returns the new last synced line number. Each request carries
+// IdempotencyKeyHeader, identical across retries and re-sends, and the
+// lines' ChunkChecksum. With
+// multipart uploads on, a body larger than the part size goes in parts
+// (see uploadMultipart), or in one request if the backend lacks them.
 func (c *Client) UploadChunk(sessionID, fileName, fileType string, firstLine int, lines []string, metadata *ChunkMetadata) (int, error) {
 	req := ChunkRequest{
 		SessionID: sessionID,
@@ -172,17 +365,89 @@ func (c *Client) UploadChunk(sessionID, fileName, fileType string, firstLine int
 		FileType:  fileType,
 		FirstLine: firstLine,
 		Lines:     lines,
+		Checksum:  ChunkChecksum(lines),
 		Metadata:  metadata,
 	}
 
+	if c.inFlight != nil {
+		size := chunkBodySize(lines)
+		c.inFlight.acquire(size)
+		defer c.inFlight.release(size)
+	}
+
+	key := ChunkIdempotencyKey(sessionID, fileName, firstLine, lines)
+	if c.multipart != nil {
+		body, contentEncoding, err := c.httpClient.EncodeJSON(req)
+		if err != nil {
+			return 0, fmt.Errorf("chunk upload failed: %w", err)
+		}
+		if c.multipart.applies(len(body)) {
+			lastLine, err := c.uploadMultipart(key, &req, body, contentEncoding)
+			if !errors.Is(err, errMultipartUnsupported) {
+				return lastLine, err
+			}
+			logger.Info("Backend has no multipart upload endpoint, uploading chunks in one request", "component", "sync", "file", fileName)
+		}
+	}
+
 	var resp ChunkResponse
-	if err := c.httpClient.Post("/api/v1/sync/chunk", req, &resp); err != nil {
+	headers := map[string]string{IdempotencyKeyHeader: key}
+	err := c.do(c.withRetries(func() error {
+		return c.httpClient.PostWithHeaders("/api/v1/sync/chunk", headers, req, &resp, c.throttleBody)
+	}))
+	if err != nil {
 		return 0, fmt.Errorf("chunk upload failed: %w", err)
 	}
 
 	return resp.LastSyncedLine, nil
 }
 
+// throttleBody wraps a chunk request body (already compressed, so the
+// limit applies to the bytes actually sent) so it draws on the client's
+// uploadLimiter. Every upload and retry shares the limiter, so concurrent
+// uploads together stay under max_upload_bps.
+func (c *Client) throttleBody(body io.Reader) io.Reader {
+	if c.uploadLimiter == nil {
+		return body
+	}
+	return &throttledReader{r: body, limiter: c.uploadLimiter}
+}
+
+// newUploadLimiter returns a limiter for bytesPerSecond with a burst of
+// ~100ms worth of bytes, or nil (unlimited) when it is 0 or negative.
+func newUploadLimiter(bytesPerSecond int64) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(max(bytesPerSecond/10, 1)))
+}
+
+// throttledReader paces reads through a limiter. Reads are capped at the
+// limiter's burst, so a single read never waits for more than ~100ms of
+// data and WaitN never rejects it.
+type throttledReader struct {
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+// newThrottledReader paces r to bytesPerSecond on a limiter of its own.
+func newThrottledReader(r io.Reader, bytesPerSecond int64) *throttledReader {
+	return &throttledReader{r: r, limiter: newUploadLimiter(bytesPerSecond)}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if burst := t.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if waitErr := t.limiter.WaitN(context.Background(), n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
 // SendEvent sends a session lifecycle event to the backend
 func (c *Client) SendEvent(sessionID, eventType string, timestamp time.Time, payload json.RawMessage) error {
 	req := EventRequest{
@@ -193,7 +458,7 @@ func (c *Client) SendEvent(sessionID, eventType string, timestamp time.Time, pay
 	}
 
 	var resp EventResponse
-	if err := c.httpClient.Post("/api/v1/sync/event", req, &resp); err != nil {
+	if err := c.do(func() error { return c.httpClient.Post("/api/v1/sync/event", req, &resp) }); err != nil {
 		return fmt.Errorf("send event failed: %w", err)
 	}
 
@@ -218,7 +483,7 @@ func (c *Client) UpdateSessionSummary(externalID, summary string) error {
 
 	var resp UpdateSummaryResponse
 	path := fmt.Sprintf("/api/v1/sessions/%s/summary", externalID)
-	if err := c.httpClient.Patch(path, req, &resp); err != nil {
+	if err := c.do(func() error { return c.httpClient.Patch(path, req, &resp) }); err != nil {
 		return fmt.Errorf("update summary failed: %w", err)
 	}
 
@@ -246,9 +511,101 @@ type GitHubLinkResponse struct {
 func (c *Client) LinkGitHub(sessionID string, req *GitHubLinkRequest) (*GitHubLinkResponse, error) {
 	var resp GitHubLinkResponse
 	path := fmt.Sprintf("/api/v1/sessions/%s/github-links", sessionID)
-	if err := c.httpClient.Post(path, req, &resp); err != nil {
+	if err := c.do(func() error { return c.httpClient.Post(path, req, &resp) }); err != nil {
 		return nil, fmt.Errorf("link github failed: %w", err)
 	}
 
 	return &resp, nil
 }
+
+// SessionListItem is one session in the GET /api/v1/sessions response
+type SessionListItem struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSyncAt time.Time `json:"last_sync_at"`
+	LineCount  int       `json:"line_count"`
+	GitBranch  string    `json:"git_branch,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+}
+
+// ListSessionsResponse is one page of GET /api/v1/sessions. NextCursor is
+// empty on the last page.
+type ListSessionsResponse struct {
+	Sessions   []SessionListItem `json:"sessions"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// ListSessions fetches one page of the user's sessions, newest first: up to
+// limit sessions, starting at cursor (empty for the first page).
+func (c *Client) ListSessions(limit int, cursor string) (*ListSessionsResponse, error) {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(limit))
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
+	var resp ListSessionsResponse
+	path := "/api/v1/sessions?" + params.Encode()
+	if err := c.do(func() error { return c.httpClient.Get(path, &resp) }); err != nil {
+		return nil, fmt.Errorf("list sessions failed: %w", err)
+	}
+
+	return &resp, nil
+}
+
+// GetSession fetches a session's full metadata (GET /api/v1/sessions/{id}).
+// The document is returned as-is, since the backend adds fields over time.
+func (c *Client) GetSession(sessionID string) (json.RawMessage, error) {
+	var resp json.RawMessage
+	path := "/api/v1/sessions/" + url.PathEscape(sessionID)
+	if err := c.do(func() error { return c.httpClient.Get(path, &resp) }); err != nil {
+		return nil, fmt.Errorf("get session failed: %w", err)
+	}
+
+	return resp, nil
+}
+
+// DeleteSession deletes a session and its synced files from the backend
+func (c *Client) DeleteSession(sessionID string) error {
+	path := "/api/v1/sessions/" + url.PathEscape(sessionID)
+	if err := c.do(func() error { return c.httpClient.Delete(path, nil) }); err != nil {
+		return fmt.Errorf("delete session failed: %w", err)
+	}
+
+	return nil
+}
+
+// LineChecksum is the CRC32 (IEEE) of one file line as uploaded: redacted,
+// without its trailing newline. Line is 1-based.
+type LineChecksum struct {
+	Line  int    `json:"line"`
+	CRC32 uint32 `json:"crc32"`
+}
+
+// ChecksumRequest asks the backend to compare its copy of one session file
+// against local line checksums.
+type ChecksumRequest struct {
+	SessionID string         `json:"session_id"`
+	FileName  string         `json:"file_name"`
+	Lines     []LineChecksum `json:"lines"`
+}
+
+// ChecksumResponse holds one entry per requested line, in request order:
+// true when the backend's line has the same checksum. The list may stop
+// short at the end of the backend's copy.
+type ChecksumResponse struct {
+	Matches []bool `json:"matches"`
+}
+
+// Checksum compares line checksums with the backend's stored copy of a
+// file (GET /api/v1/sessions/{id}/checksum, with the request as its JSON
+// body).
+func (c *Client) Checksum(req *ChecksumRequest) (*ChecksumResponse, error) {
+	var resp ChecksumResponse
+	path := "/api/v1/sessions/" + url.PathEscape(req.SessionID) + "/checksum"
+	if err := c.do(func() error { return c.httpClient.DoJSON("GET", path, req, &resp) }); err != nil {
+		return nil, fmt.Errorf("checksum failed: %w", err)
+	}
+
+	return &resp, nil
+}
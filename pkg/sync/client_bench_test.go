@@ -0,0 +1,88 @@
+package sync
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+)
+
+// BenchmarkUploadChunk_CompressionLevel compares upload throughput and wire
+// size across zstd levels for a ~10MB transcript chunk. Run with:
+//
+//	go test ./pkg/sync -run '^$' -bench CompressionLevel -benchmem
+func BenchmarkUploadChunk_CompressionLevel(b *testing.B) {
+	var wireBytes atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		wireBytes.Store(n)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"last_synced_line":1}`))
+	}))
+	defer server.Close()
+
+	lines := generateBenchTranscript(10 * 1024 * 1024)
+	var rawBytes int64
+	for _, l := range lines {
+		rawBytes += int64(len(l)) + 1
+	}
+
+	cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "test-api-key-12345678"}
+	for _, level := range []int{1, 3, 9} {
+		b.Run(fmt.Sprintf("level=%d", level), func(b *testing.B) {
+			client, err := NewClient(cfg, level)
+			if err != nil {
+				b.Fatalf("failed to create client: %v", err)
+			}
+			b.SetBytes(rawBytes)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.UploadChunk("bench-session", "transcript.jsonl", "transcript", 1, lines, nil); err != nil {
+					b.Fatalf("upload failed: %v", err)
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(wireBytes.Load())/float64(rawBytes)*100, "%wire")
+		})
+	}
+}
+
+// generateBenchTranscript builds transcript-like JSONL lines totalling about
+// size bytes. Content mixes repeated structure with varying IDs, timestamps,
+// and text so the levels produce meaningfully different ratios.
+func generateBenchTranscript(size int) []string {
+	rng := rand.New(rand.NewSource(1))
+	words := strings.Fields("the a function returns error nil context file path test build handler request response config value struct interface package import go run check update line chunk sync daemon backend")
+	sentence := func(n int) string {
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = words[rng.Intn(len(words))]
+		}
+		return strings.Join(parts, " ")
+	}
+
+	var lines []string
+	total := 0
+	for i := 0; total < size; i++ {
+		uuid := fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", rng.Uint32(), rng.Intn(1<<16), rng.Intn(1<<16), rng.Intn(1<<16), rng.Int63n(1<<48))
+		ts := fmt.Sprintf("2026-01-15T10:%02d:%02d.%03dZ", (i/60)%60, i%60, rng.Intn(1000))
+		var line string
+		switch i % 3 {
+		case 0:
+			line = fmt.Sprintf(`{"type":"user","uuid":"%s","timestamp":"%s","message":{"role":"user","content":"%s"}}`, uuid, ts, sentence(20+rng.Intn(40)))
+		case 1:
+			line = fmt.Sprintf(`{"type":"assistant","uuid":"%s","timestamp":"%s","message":{"role":"assistant","content":[{"type":"text","text":"%s"}],"usage":{"input_tokens":%d,"output_tokens":%d}}}`, uuid, ts, sentence(50+rng.Intn(150)), rng.Intn(100000), rng.Intn(4000))
+		default:
+			line = fmt.Sprintf(`{"type":"user","uuid":"%s","timestamp":"%s","toolUseResult":{"stdout":"%s","stderr":"","interrupted":false}}`, uuid, ts, sentence(100+rng.Intn(300)))
+		}
+		lines = append(lines, line)
+		total += len(line) + 1
+	}
+	return lines
+}
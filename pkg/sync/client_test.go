@@ -1,11 +1,18 @@
 package sync
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	stdsync "sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -13,6 +20,20 @@ import (
 	pkghttp "github.com/ConfabulousDev/confab/pkg/http"
 )
 
+func TestEndpointLabel(t *testing.T) {
+	tests := map[string]string{
+		"/api/v1/sync/chunk":                   "/api/v1/sync/chunk",
+		"/api/v1/sessions/abc123/summary":      "/api/v1/sessions/{id}/summary",
+		"/api/v1/sessions/abc123/github-links": "/api/v1/sessions/{id}/github-links",
+		"/api/v1/sessions/abc123":              "/api/v1/sessions/{id}",
+	}
+	for path, want := range tests {
+		if got := endpointLabel(path); got != want {
+			t.Errorf("endpointLabel(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
 func TestClient_LinkGitHub_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Verify request
@@ -232,6 +253,179 @@ func TestClient_SendEvent_5xxError(t *testing.T) {
 	}
 }
 
+// TestClient_CircuitBreakerStopsRequests verifies that once the backend has
+// failed enough times in a row, the client returns ErrCircuitOpen without
+// making further network calls.
+func TestClient_CircuitBreakerStopsRequests(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := mustNewTestClient(t, server.URL)
+	for i := 0; i < 10; i++ {
+		client.SendEvent("sess-1", "session_end", time.Now(), json.RawMessage(`{}`))
+	}
+	if calls != pkghttp.DefaultCircuitFailureThreshold {
+		t.Errorf("backend calls = %d, want %d before the circuit opened", calls, pkghttp.DefaultCircuitFailureThreshold)
+	}
+
+	_, err := client.UploadChunk("sess-1", "transcript.jsonl", "transcript", 1, []string{"{}"}, nil)
+	if !errors.Is(err, pkghttp.ErrCircuitOpen) {
+		t.Errorf("UploadChunk error = %v, want ErrCircuitOpen", err)
+	}
+}
+
+// TestClient_CircuitBreakerIgnoresClientErrors verifies 4xx responses don't
+// open the circuit: the backend is answering, just not favorably.
+func TestClient_CircuitBreakerIgnoresClientErrors(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls%2 == 0 {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	client := mustNewTestClient(t, server.URL)
+	for i := 0; i < 10; i++ {
+		_, err := client.UploadChunk("sess-1", "transcript.jsonl", "transcript", 1, []string{"{}"}, nil)
+		if errors.Is(err, pkghttp.ErrCircuitOpen) {
+			t.Fatalf("call %d: circuit opened on 4xx responses", i+1)
+		}
+	}
+	if calls != 10 {
+		t.Errorf("backend calls = %d, want 10", calls)
+	}
+}
+
+// TestNewWithBackend_AppliesCircuitBreakerConfig verifies
+// EngineConfig.CircuitBreaker reaches a Client passed to NewWithBackend.
+func TestNewWithBackend_AppliesCircuitBreakerConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := mustNewTestClient(t, server.URL)
+	newEngineWithBackend(t, client, nil, EngineConfig{CircuitBreaker: pkghttp.CircuitBreakerConfig{FailureThreshold: 1}})
+	client.SendEvent("sess-1", "session_end", time.Now(), json.RawMessage(`{}`))
+	if err := client.SendEvent("sess-1", "session_end", time.Now(), json.RawMessage(`{}`)); !errors.Is(err, pkghttp.ErrCircuitOpen) {
+		t.Errorf("second call error = %v, want ErrCircuitOpen after one failure", err)
+	}
+}
+
+// TestClient_RetriesServerErrors verifies init and chunk requests are
+// retried in-client on 5xx, waiting out a Retry-After before the next try.
+func TestClient_RetriesServerErrors(t *testing.T) {
+	var attempts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts = append(attempts, time.Now())
+		switch len(attempts) {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			json.NewEncoder(w).Encode(ChunkResponse{LastSyncedLine: 1})
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.UploadConfig{
+		BackendURL:    server.URL,
+		APIKey:        "test-api-key-12345678",
+		MaxRetries:    3,
+		BaseBackoffMS: 10,
+	}, 0)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	lastLine, err := client.UploadChunk("sess-1", "transcript.jsonl", "transcript", 1, []string{"{}"}, nil)
+	if err != nil {
+		t.Fatalf("UploadChunk failed: %v", err)
+	}
+	if lastLine != 1 {
+		t.Errorf("lastLine = %d, want 1", lastLine)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("attempts = %d, want 3", len(attempts))
+	}
+	if gap := attempts[1].Sub(attempts[0]); gap < time.Second {
+		t.Errorf("second attempt came %s after the first, want >= 1s (Retry-After)", gap)
+	}
+}
+
+func TestClient_RequestTimeout(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.UploadConfig{
+		BackendURL:       server.URL,
+		APIKey:           "test-api-key-12345678",
+		MaxRetries:       1,
+		BaseBackoffMS:    1,
+		RequestTimeoutMS: 100,
+	}, 0)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	start := time.Now()
+	_, err = client.Init("claude-code", "ext-1", "/tmp/t.jsonl", nil, nil)
+	elapsed := time.Since(start)
+	if !errors.Is(err, pkghttp.ErrTimeout) {
+		t.Fatalf("Init error = %v, want ErrTimeout", err)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("Init took %s, want it bounded by the 100ms timeout per attempt", elapsed)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2 (a timeout is retried)", got)
+	}
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound} {
+		var calls int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(status)
+		}))
+
+		client, err := NewClient(&config.UploadConfig{
+			BackendURL:    server.URL,
+			APIKey:        "test-api-key-12345678",
+			MaxRetries:    3,
+			BaseBackoffMS: 1,
+		}, 0)
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		if _, err := client.Init("claude-code", "ext-1", "/tmp/t.jsonl", nil, nil); err == nil {
+			t.Errorf("status %d: expected error", status)
+		}
+		if calls != 1 {
+			t.Errorf("status %d: calls = %d, want 1 (no retry)", status, calls)
+		}
+		server.Close()
+	}
+}
+
 func TestClient_UpdateSessionSummary_Success(t *testing.T) {
 	var receivedReq UpdateSummaryRequest
 	var receivedPath, receivedMethod string
@@ -284,7 +478,7 @@ func mustNewTestClient(t *testing.T, serverURL string) *Client {
 		BackendURL: serverURL,
 		APIKey:     "test-api-key-12345678",
 	}
-	client, err := NewClient(cfg)
+	client, err := NewClient(cfg, 0)
 	if err != nil {
 		t.Fatalf("failed to create client: %v", err)
 	}
@@ -484,3 +678,332 @@ func TestChunkMetadata_CodexRollout_JSONUnmarshal_RoundTrips(t *testing.T) {
 		t.Errorf("decoded payload doesn't match original: %+v", decoded.CodexRollout)
 	}
 }
+
+// TestThrottledReader paces a reader to its rate. The 64KB case keeps the
+// default run under a second; the 1MB/10s case runs only without -short.
+func TestThrottledReader(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		size int64
+		rate int64
+		long bool
+	}{
+		{name: "64KB at 64KB/s", size: 64 * 1024, rate: 64 * 1024},
+		{name: "1MB at 100KB/s", size: 1 << 20, rate: 100 * 1024, long: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.long && testing.Short() {
+				t.Skip("wall-clock throttle test takes ~10s")
+			}
+			start := time.Now()
+			n, err := io.Copy(io.Discard, newThrottledReader(bytes.NewReader(make([]byte, tt.size)), tt.rate))
+			elapsed := time.Since(start)
+			if err != nil || n != tt.size {
+				t.Fatalf("copy: n=%d err=%v", n, err)
+			}
+
+			// The initial burst covers the first ~10%.
+			expected := time.Duration(float64(tt.size) / float64(tt.rate) * float64(time.Second))
+			if elapsed < expected*85/100 {
+				t.Errorf("%d bytes at %d B/s took %v, want >= %v", tt.size, tt.rate, elapsed, expected*85/100)
+			}
+			if elapsed > 2*expected {
+				t.Errorf("%d bytes at %d B/s took %v, want <= %v", tt.size, tt.rate, elapsed, 2*expected)
+			}
+		})
+	}
+}
+
+func TestClient_UploadChunk_Throttled(t *testing.T) {
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+		json.NewEncoder(w).Encode(ChunkResponse{LastSyncedLine: 1})
+	}))
+	defer server.Close()
+
+	// Random bytes barely compress, so the wire body stays ~40KB.
+	raw := make([]byte, 30*1024)
+	rand.Read(raw)
+	line := `{"data":"` + base64.StdEncoding.EncodeToString(raw) + `"}`
+
+	cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "test-key", MaxUploadBytesPerSecond: 40 * 1024}
+	client, err := NewClient(cfg, 0)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := client.UploadChunk("s", "f.jsonl", "transcript", 1, []string{line}, nil); err != nil {
+		t.Fatalf("UploadChunk: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if received < 30*1024 {
+		t.Fatalf("expected ~40KB body on the wire, got %d bytes", received)
+	}
+	if want := time.Duration(float64(received)/float64(cfg.MaxUploadBytesPerSecond)*float64(time.Second)) - 200*time.Millisecond; elapsed < want {
+		t.Errorf("upload of %d bytes at %d B/s took %v, want >= %v", received, cfg.MaxUploadBytesPerSecond, elapsed, want)
+	}
+}
+
+// TestClient_UploadChunk_ThrottleSharedAcrossUploads: concurrent uploads
+// draw on one budget, so ~1MB split over two chunks at 256KB/s still takes
+// about 4s (per-upload limits would finish in about 2s).
+func TestClient_UploadChunk_ThrottleSharedAcrossUploads(t *testing.T) {
+	if testing.Short() {
+		t.Skip("wall-clock throttle test takes ~4s")
+	}
+	var received atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received.Add(int64(len(body)))
+		json.NewEncoder(w).Encode(ChunkResponse{LastSyncedLine: 1})
+	}))
+	defer server.Close()
+
+	const rate = 256 * 1024
+	cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "test-key", MaxUploadBytesPerSecond: rate}
+	client, err := NewClient(cfg, 0)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// Random bytes barely compress: each ~512KB line stays ~512KB on the wire.
+	start := time.Now()
+	var wg stdsync.WaitGroup
+	for i := range 2 {
+		raw := make([]byte, 384*1024)
+		rand.Read(raw)
+		line := `{"data":"` + base64.StdEncoding.EncodeToString(raw) + `"}`
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.UploadChunk("s", fmt.Sprintf("f%d.jsonl", i), "transcript", 1, []string{line}, nil); err != nil {
+				t.Errorf("UploadChunk: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	total := received.Load()
+	if total < 900*1024 {
+		t.Fatalf("expected ~1MB on the wire, got %d bytes", total)
+	}
+	if elapsed < 3*time.Second {
+		t.Errorf("%d bytes over two concurrent uploads at %d B/s took %v, want >= 3s", total, rate, elapsed)
+	}
+}
+
+func TestClient_UploadChunk_Compression(t *testing.T) {
+	// Large enough to cross the compression threshold.
+	lines := []string{
+		`{"type":"user","message":"` + strings.Repeat("hello ", 300) + `"}`,
+		`{"type":"assistant","message":"` + strings.Repeat("world ", 300) + `"}`,
+	}
+
+	for _, tt := range []struct {
+		compression  string
+		wantEncoding string
+	}{
+		{"", "zstd"},
+		{config.CompressionZstd, "zstd"},
+		{config.CompressionGzip, "gzip"},
+		{config.CompressionNone, ""},
+	} {
+		t.Run("compression="+tt.compression, func(t *testing.T) {
+			var gotEncoding string
+			var got ChunkRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotEncoding = r.Header.Get("Content-Encoding")
+				body, err := readRequestBody(r)
+				if err != nil {
+					t.Errorf("decode %q body: %v", gotEncoding, err)
+				}
+				if err := json.Unmarshal(body, &got); err != nil {
+					t.Errorf("unmarshal chunk: %v", err)
+				}
+				json.NewEncoder(w).Encode(ChunkResponse{LastSyncedLine: 2})
+			}))
+			defer server.Close()
+
+			cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "test-key", Compression: tt.compression}
+			client, err := NewClient(cfg, 0)
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			if _, err := client.UploadChunk("s", "f.jsonl", "transcript", 1, lines, nil); err != nil {
+				t.Fatalf("UploadChunk: %v", err)
+			}
+
+			if gotEncoding != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", gotEncoding, tt.wantEncoding)
+			}
+			if len(got.Lines) != 2 || got.Lines[0] != lines[0] || got.Lines[1] != lines[1] {
+				t.Errorf("chunk lines did not round-trip through %q", tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestNewClient_InvalidCompression(t *testing.T) {
+	cfg := &config.UploadConfig{BackendURL: "https://confab.dev", APIKey: "test-key", Compression: "brotli"}
+	if _, err := NewClient(cfg, 0); err == nil {
+		t.Fatal("expected NewClient to reject compression=brotli")
+	}
+}
+
+func TestClient_UploadChunk_IdempotencyKey(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(ChunkResponse{LastSyncedLine: 2})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.UploadConfig{BackendURL: server.URL, APIKey: "test-key", MaxRetries: 1, BaseBackoffMS: 1}, 0)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	lines := []string{`{"a":1}`, `{"b":2}`}
+	if _, err := client.UploadChunk("s1", "f.jsonl", "transcript", 1, lines, nil); err != nil {
+		t.Fatalf("UploadChunk: %v", err)
+	}
+
+	want := ChunkIdempotencyKey("s1", "f.jsonl", 1, lines)
+	if len(keys) != 2 || keys[0] != want || keys[1] != want {
+		t.Errorf("idempotency keys = %q, want %q on the request and its retry", keys, want)
+	}
+	if len(want) != 32 {
+		t.Errorf("key %q has %d chars, want 32", want, len(want))
+	}
+	// Only the session, file, first line number and first line count.
+	if ChunkIdempotencyKey("s1", "f.jsonl", 1, lines[:1]) != want {
+		t.Error("key changed with the later lines")
+	}
+	for _, other := range []string{
+		ChunkIdempotencyKey("s2", "f.jsonl", 1, lines),
+		ChunkIdempotencyKey("s1", "g.jsonl", 1, lines),
+		ChunkIdempotencyKey("s1", "f.jsonl", 3, lines),
+		ChunkIdempotencyKey("s1", "f.jsonl", 1, lines[1:]),
+		ChunkIdempotencyKey("s1", "f.jsonl1", 1, nil),
+	} {
+		if other == want {
+			t.Errorf("distinct chunk got the same key %q", want)
+		}
+	}
+}
+
+// TestClient_UploadChunk_MultipartRanges: a compressed body over the part
+// size goes out as contiguous Range parts that reassemble into the chunk
+// request, and the completed upload is no longer pending.
+func TestClient_UploadChunk_MultipartRanges(t *testing.T) {
+	var start MultipartStartRequest
+	var ranges []string
+	var assembled []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/api/v1/sync/chunk/start":
+			json.Unmarshal(body, &start)
+			json.NewEncoder(w).Encode(MultipartStartResponse{UploadID: "u1"})
+		case "/api/v1/sync/chunk/part":
+			if r.URL.Query().Get("upload_id") != "u1" {
+				t.Errorf("part for upload %q", r.URL.Query().Get("upload_id"))
+			}
+			ranges = append(ranges, r.Header.Get("Range"))
+			assembled = append(assembled, body...)
+			json.NewEncoder(w).Encode(MultipartPartResponse{Complete: len(assembled) == start.TotalSize, LastSyncedLine: 1})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.UploadConfig{BackendURL: server.URL, APIKey: "test-key"}, 0)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	client.multipart = newMultipartUploads(1024)
+
+	// Random bytes barely compress: the ~4KB body needs 4+ parts.
+	raw := make([]byte, 3*1024)
+	rand.Read(raw)
+	line := `{"data":"` + base64.StdEncoding.EncodeToString(raw) + `"}`
+	if last, err := client.UploadChunk("s", "f.jsonl", "transcript", 1, []string{line}, nil); err != nil || last != 1 {
+		t.Fatalf("UploadChunk = %d, %v", last, err)
+	}
+
+	if start.ContentEncoding != "zstd" || start.PartSize != 1024 || start.TotalSize != len(assembled) {
+		t.Errorf("start request = %+v, assembled %d bytes", start, len(assembled))
+	}
+	for i, got := range ranges {
+		want := fmt.Sprintf("bytes=%d-%d", i*1024, min((i+1)*1024, start.TotalSize)-1)
+		if got != want {
+			t.Errorf("part %d Range = %q, want %q", i, got, want)
+		}
+	}
+	decoded, err := zstdDecoder.DecodeAll(assembled, nil)
+	if err != nil {
+		t.Fatalf("decode assembled body: %v", err)
+	}
+	var req ChunkRequest
+	if err := json.Unmarshal(decoded, &req); err != nil || len(req.Lines) != 1 || req.Lines[0] != line {
+		t.Errorf("assembled body doesn't decode to the chunk request: %v", err)
+	}
+	if pending := client.PendingUploads(); len(pending) != 0 {
+		t.Errorf("PendingUploads = %v after the upload completed", pending)
+	}
+}
+
+func TestClient_Sessions_ListShowDelete(t *testing.T) {
+	mock := newMockBackend(t)
+	created := time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)
+	for _, id := range []string{"s1", "s2", "s3"} {
+		mock.sessions = append(mock.sessions, SessionListItem{ID: id, CreatedAt: created, LineCount: 10, GitBranch: "main"})
+	}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	client := mustNewTestClient(t, server.URL)
+
+	page, err := client.ListSessions(2, "")
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(page.Sessions) != 2 || page.NextCursor == "" {
+		t.Fatalf("first page = %d sessions, cursor %q; want 2 and a cursor", len(page.Sessions), page.NextCursor)
+	}
+	page, err = client.ListSessions(2, page.NextCursor)
+	if err != nil {
+		t.Fatalf("ListSessions(cursor): %v", err)
+	}
+	if len(page.Sessions) != 1 || page.Sessions[0].ID != "s3" || page.NextCursor != "" {
+		t.Errorf("last page = %+v, want just s3 and no cursor", page)
+	}
+
+	raw, err := client.GetSession("s2")
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	var got SessionListItem
+	if err := json.Unmarshal(raw, &got); err != nil || got.ID != "s2" || !got.CreatedAt.Equal(created) {
+		t.Errorf("GetSession = %s (%v), want s2's metadata", raw, err)
+	}
+
+	if err := client.DeleteSession("s2"); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if len(mock.deletedSessions) != 1 || mock.deletedSessions[0] != "s2" {
+		t.Errorf("deleted = %v, want [s2]", mock.deletedSessions)
+	}
+	if _, err := client.GetSession("s2"); !errors.Is(err, pkghttp.ErrSessionNotFound) {
+		t.Errorf("GetSession after delete = %v, want ErrSessionNotFound", err)
+	}
+}
@@ -0,0 +1,116 @@
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/ConfabulousDev/confab/pkg/types"
+)
+
+// LineDiff is one line position where the local and backend copies of a
+// file disagree. A side that has no line at that position is marked
+// missing rather than compared as an empty string.
+type LineDiff struct {
+	FileName       string
+	Line           int    // 1-based
+	Local          string // redacted, as an upload would send it
+	Backend        string
+	LocalMissing   bool
+	BackendMissing bool
+}
+
+// Diff compares each tracked file line by line against the backend's
+// stored copy, fetched with fetch. Local lines are redacted first, since
+// the backend only ever holds redacted content. Transcripts are append-only,
+// so lines are compared by position. An empty fileName diffs every file;
+// otherwise only that one. Files the backend reports no lines for are not
+// fetched. Must be called after Init. Results are sorted by file then line.
+func (e *Engine) Diff(fileName string, fetch RemoteContentFunc) ([]LineDiff, error) {
+	if !e.initialized {
+		return nil, fmt.Errorf("engine not initialized")
+	}
+
+	files := e.tracker.GetTrackedFiles()
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	var diffs []LineDiff
+	found := false
+	for _, f := range files {
+		if fileName != "" && f.Name != fileName {
+			continue
+		}
+		found = true
+
+		local, err := e.redactedLines(f.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		var backend []string
+		if f.LastSyncedLine > 0 {
+			var buf bytes.Buffer
+			if err := fetch(f.Name, &buf); err != nil {
+				return nil, fmt.Errorf("failed to fetch %s: %w", f.Name, err)
+			}
+			if backend, err = splitLines(&buf); err != nil {
+				return nil, fmt.Errorf("failed to read backend %s: %w", f.Name, err)
+			}
+		}
+		diffs = append(diffs, diffLines(f.Name, local, backend)...)
+	}
+	if fileName != "" && !found {
+		return nil, fmt.Errorf("file %q is not tracked in this session", fileName)
+	}
+	return diffs, nil
+}
+
+// diffLines compares local and backend positionally.
+func diffLines(fileName string, local, backend []string) []LineDiff {
+	var diffs []LineDiff
+	for i := 0; i < max(len(local), len(backend)); i++ {
+		d := LineDiff{FileName: fileName, Line: i + 1}
+		switch {
+		case i >= len(local):
+			d.LocalMissing, d.Backend = true, backend[i]
+		case i >= len(backend):
+			d.BackendMissing, d.Local = true, local[i]
+		case local[i] == backend[i]:
+			continue
+		default:
+			d.Local, d.Backend = local[i], backend[i]
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// redactedLines reads every line of path through the engine's redactor.
+func (e *Engine) redactedLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines, err := splitLines(f)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range lines {
+		lines[i] = e.tracker.redactLine(e.redactor, line)
+	}
+	return lines, nil
+}
+
+func splitLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, bufio.MaxScanTokenSize), DefaultMaxChunkBytes+types.MaxJSONLLineSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
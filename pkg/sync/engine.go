@@ -1,12 +1,15 @@
 package sync
 
 import (
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/user"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
@@ -16,6 +19,7 @@ import (
 	"github.com/ConfabulousDev/confab/pkg/provider"
 	"github.com/ConfabulousDev/confab/pkg/redactor"
 	"github.com/ConfabulousDev/confab/pkg/types"
+	"golang.org/x/sync/errgroup"
 )
 
 // Compile-time assertions that the tracker types satisfy the provider
@@ -40,6 +44,7 @@ type Engine struct {
 	cwd                  string
 	initialized          bool
 	sentFirstUserMessage bool
+	startEventSessionID  string // backend session session_start was sent for
 
 	// model is the session-constant LLM model name (Cursor only; sourced from
 	// the sessionStart hook). When non-empty, the engine stamps it onto every
@@ -62,6 +67,56 @@ type Engine struct {
 	// per-child collector spawn through the same provider seam Codex uses.
 	// See SetDescendantRegistrar.
 	descendantReg provider.DescendantRegistrar
+
+	initOverride    *InitResponse                    // replaces backend file state from Init (replay)
+	onChunk         func(fileName string, lines int) // optional per-chunk progress callback
+	onChunkStats    func(ChunkStats)                 // optional per-chunk metrics callback
+	onProgress      func(string, int, int)           // optional per-file progress callback
+	sendLineCounts  bool                             // include local per-file line counts in Init
+	secondsPerLine  float64                          // InitRequest.EstimatedDurationSeconds per transcript line
+	sendModTime     bool                             // stamp ChunkMetadata.SourceModTime on every chunk
+	staticMetadata  map[string]string                // configured static_metadata for init and every chunk
+	holdTailOnFinal bool                             // SyncAllFinal keeps deferring incomplete last lines
+
+	// lastInitAppliedAt is when an InitResponse was last seeded into the
+	// tracker. Within dedupWindow of it, a refresh whose file states the
+	// tracker already holds leaves the tracker (and its read offsets)
+	// alone; see applyBackendFiles. Zero forces the next one to apply.
+	lastInitAppliedAt time.Time
+	dedupWindow       time.Duration
+
+	// maxConcurrentUploads bounds parallel sidechain (agent) file uploads
+	// in SyncAll; 1 keeps uploads sequential.
+	maxConcurrentUploads int
+
+	// maxAgentDepth and maxTotalAgentFiles bound agent discovery in SyncAll
+	// (see admitAgentFiles). agentDepths is each agent file's nesting depth,
+	// admittedAgents the agent files allowed to sync, cappedAgents those
+	// too deep to, and agentLimitWarned those already warned about as
+	// skipped. Guarded by mu.
+	maxAgentDepth      int
+	maxTotalAgentFiles int
+	agentDepths        map[string]agentDepth
+	admittedAgents     map[string]bool
+	cappedAgents       map[string]bool
+	agentLimitWarned   map[string]bool
+
+	// maxConsecutive400 and quarantine control how a chunk the backend
+	// keeps rejecting is split and its offending line skipped (see
+	// handleRejectedChunk).
+	maxConsecutive400 int
+	quarantine        bool
+
+	// chunker sizes chunks from recent upload durations.
+	chunker *adaptiveChunker
+
+	// mu guards the fields below, plus sentFirstUserMessage and onChunk
+	// calls, while SyncAll uploads sidechain files concurrently.
+	mu sync.Mutex
+
+	// Cumulative upload progress for status reporting (see Stats).
+	bytesUploaded int64     // uncompressed line bytes accepted by the backend
+	lastSyncAt    time.Time // end of the last SyncAll cycle with no errors
 }
 
 // setProviderForTest substitutes the engine's resolved Provider with a stub.
@@ -71,7 +126,7 @@ func (e *Engine) setProviderForTest(p provider.Provider) { e.provider = p }
 // Backend is the sync transport used by Engine. The HTTP client implements this
 // for provider-aware backend sync.
 type Backend interface {
-	Init(providerName, externalID, transcriptPath string, metadata *InitMetadata) (*InitResponse, error)
+	Init(providerName, externalID, transcriptPath string, metadata *InitMetadata, opts *InitOptions) (*InitResponse, error)
 	UploadChunk(sessionID, fileName, fileType string, firstLine int, lines []string, metadata *ChunkMetadata) (int, error)
 	SendEvent(sessionID, eventType string, timestamp time.Time, payload json.RawMessage) error
 	UpdateSessionSummary(externalID, summary string) error
@@ -82,6 +137,19 @@ type Backend interface {
 	Capabilities() (Capabilities, error)
 }
 
+// multipartBackend is implemented by backends that can resume multipart
+// chunk uploads across restarts (the HTTP client).
+type multipartBackend interface {
+	PendingUploads() map[string]PendingUpload
+	SeedPendingUploads(map[string]PendingUpload)
+}
+
+// tokenRefresher is implemented by backends whose access token can expire
+// and be refreshed (the HTTP client).
+type tokenRefresher interface {
+	RefreshTokenIfExpiring(within time.Duration) (bool, error)
+}
+
 // EngineConfig holds configuration for creating an Engine
 type EngineConfig struct {
 	Provider       string
@@ -92,24 +160,150 @@ type EngineConfig struct {
 	// the sessionStart hook payload). Empty for other providers. The engine
 	// stamps it onto transcript chunk metadata when non-empty.
 	Model string
+	// CompressionLevel is the zstd level (1–11) for chunk uploads. 0 uses
+	// the upload config's compression_level, or the default level if unset.
+	CompressionLevel int
+	// InitOverride, when non-nil, replaces the file state the backend reports
+	// from Init: the engine syncs as if the backend held only
+	// InitOverride.Files, so an empty map re-uploads every file from line 1.
+	// A non-empty InitOverride.SessionID also replaces the backend's session
+	// ID. Init still calls the backend to register the session. Used by
+	// `confab replay`.
+	InitOverride *InitResponse
+	// OnChunkUploaded, if set, is called after each chunk the backend
+	// accepts, with the chunk's file name and line count. Called from
+	// SyncAll, possibly from concurrent upload goroutines, but never
+	// concurrently with itself.
+	OnChunkUploaded func(fileName string, lines int)
+	// OnChunkStats is OnChunkUploaded with the chunk's file type and size
+	// too, for metrics. Called under the same conditions.
+	OnChunkStats func(ChunkStats)
+	// OnProgress, if set, is called after each accepted chunk with the
+	// file's name, its last synced line and an estimate of its total line
+	// count (file size over the average synced line length, never below
+	// linesSynced), so a long backfill can show progress. Called under the
+	// same conditions as OnChunkUploaded.
+	OnProgress func(file string, linesSynced, totalLinesEstimate int)
+	// OnBackendRequest, if set, is called after every HTTP request to the
+	// backend (retries included) with the endpoint path, IDs replaced by
+	// placeholders, the response status code (0 when none arrived) and the
+	// request's duration. Ignored by NewWithBackend.
+	OnBackendRequest func(endpoint string, statusCode int, elapsed time.Duration)
+	// SendFileLineCounts adds the local line count of each known file to the
+	// init request (InitRequest.FileLineCounts). Also enabled by the upload
+	// config's send_file_line_counts.
+	SendFileLineCounts bool
+	// SecondsPerLine converts the transcript's line count into the init
+	// request's EstimatedDurationSeconds. 0 uses DefaultSecondsPerLine.
+	SecondsPerLine float64
+	// InitDedupWindow is how long an applied init response stays current:
+	// a state refresh after an upload failure within it whose last synced
+	// lines match the tracker's keeps the tracker's read positions. 0 uses
+	// DefaultInitDedupWindow.
+	InitDedupWindow time.Duration
+	// SendSourceModTime stamps each chunk's metadata with its source file's
+	// modification time (ChunkMetadata.SourceModTime). Also enabled by the
+	// upload config's send_source_mod_time.
+	SendSourceModTime bool
+	// StaticMetadata is sent in the init metadata and on every chunk
+	// (InitMetadata.Static, ChunkMetadata.Static). New uses the upload
+	// config's static_metadata when this is nil.
+	StaticMetadata map[string]string
+	// UploadPartialTail uploads a file's last line even when it isn't valid
+	// JSON yet. By default such a line is assumed to be mid-write and held
+	// back until it parses or another line follows it, so it isn't sent
+	// truncated and then again complete. Also enabled by the upload
+	// config's upload_partial_tail.
+	UploadPartialTail bool
+	// SkipOversizeLines uploads a placeholder in place of a line larger
+	// than DefaultMaxChunkBytes, logging a warning, so the lines after it
+	// still sync. By default such a line is an error and the file can't
+	// sync past it. Lines beyond the read buffer (the chunk limit plus
+	// types.MaxJSONLLineSize) still fail. Also enabled by the upload
+	// config's skip_oversize_lines.
+	SkipOversizeLines bool
+	// SyncAttachments uploads files that transcript tool results reference
+	// as document attachments (FileTypeAttachment), each whole and only
+	// once, up to MaxAttachmentBytes. Also enabled by the upload config's
+	// sync_attachments.
+	SyncAttachments bool
+	// AgentExtractor finds child agent files in transcript lines, for agent
+	// frameworks other than Claude Code. Nil means ClaudeAgentExtractor
+	// (toolUseResult.agentId → agent-<id>.jsonl).
+	AgentExtractor AgentExtractor
+	// BypassMarker is the redaction bypass marker field stripped from
+	// every line uploaded without a redactor, so it never reaches the
+	// backend with redaction disabled (a redactor strips its own). New
+	// takes it from the upload config; see BypassMarker.
+	BypassMarker string
+	// HoldTailOnFinalSync keeps the deferral in SyncAllFinal too, so a last
+	// line still unterminated or unparseable at shutdown is left for a later
+	// daemon instead of being uploaded as-is. Also enabled by the upload
+	// config's hold_tail_on_exit.
+	HoldTailOnFinalSync bool
+	// MaxConcurrentUploads is how many agent/sidechain files SyncAll uploads
+	// in parallel once the transcript is done. 0 uses the upload config's
+	// max_concurrent_uploads; anything below 1 means sequential.
+	MaxConcurrentUploads int
+	// MaxAgentDepth is how deeply nested an agent file may be and still be
+	// synced: the transcript is depth 0, an agent it references depth 1, and
+	// so on; agents at this depth or deeper are skipped with a warning. 0
+	// uses the upload config's max_agent_depth, or DefaultMaxAgentDepth.
+	MaxAgentDepth int
+	// MaxTotalAgentFiles caps how many agent files the engine syncs; agents
+	// discovered past it are skipped with a warning. 0 uses the upload
+	// config's max_total_agent_files, or DefaultMaxTotalAgentFiles.
+	MaxTotalAgentFiles int
+	// MaxConsecutive400 is how many 400 Bad Request responses in a row the
+	// same chunk may get before it is split to isolate the rejected line. 0
+	// uses the upload config's max_consecutive_400, or
+	// DefaultMaxConsecutive400.
+	MaxConsecutive400 int
+	// QuarantineRejectedLines, when non-nil, overrides the upload config's
+	// quarantine_rejected_lines (default true): an isolated line the
+	// backend still rejects is uploaded as a placeholder so the file can
+	// advance past it.
+	QuarantineRejectedLines *bool
+	// TargetChunkDuration is how long a chunk upload should take. When
+	// uploads average longer, the engine halves its chunk size (down to
+	// MinChunkBytes); when they are well under it, the size grows back
+	// toward DefaultMaxChunkBytes. 0 uses DefaultTargetChunkDuration.
+	TargetChunkDuration time.Duration
+	// MinChunkBytes is the smallest chunk size adaptive sizing shrinks to.
+	// 0 uses DefaultMinChunkBytes. A single line larger than the current
+	// size is still sent, alone.
+	MinChunkBytes int
+	// CircuitBreaker tunes the breaker that stops backend calls after
+	// repeated failures (see http.CircuitBreaker). Zero fields use the
+	// defaults: open after 5 consecutive failures, for 30s.
+	CircuitBreaker http.CircuitBreakerConfig
+	// MultipartUpload sends chunk bodies larger than its PartSize in
+	// resumable parts (see MultipartUploadConfig). Also enabled by the
+	// upload config's multipart_upload. Ignored by NewWithBackend.
+	MultipartUpload MultipartUploadConfig
 }
 
 // New creates a new sync engine with the given configuration.
 // The engine is not connected to the backend until Init() is called.
 func New(uploadCfg *config.UploadConfig, engineCfg EngineConfig) (*Engine, error) {
-	client, err := NewClient(uploadCfg)
+	client, err := NewClient(uploadCfg, engineCfg.CompressionLevel)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sync client: %w", err)
 	}
+	client.breaker = http.NewCircuitBreaker(engineCfg.CircuitBreaker)
+	if engineCfg.MultipartUpload.Enabled || uploadCfg.MultipartUpload {
+		client.multipart = newMultipartUploads(cmp.Or(engineCfg.MultipartUpload.PartSize, uploadCfg.UploadPartSize))
+	}
+	if fn := engineCfg.OnBackendRequest; fn != nil {
+		client.httpClient.SetRequestObserver(func(_, path string, statusCode int, elapsed time.Duration) {
+			fn(endpointLabel(path), statusCode, elapsed)
+		})
+	}
 
 	// Initialize redactor if enabled in config
-	var r *redactor.Redactor
-	if uploadCfg.Redaction != nil && uploadCfg.Redaction.Enabled {
-		var err error
-		r, err = redactor.NewFromConfig(uploadCfg.Redaction)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create redactor: %w", err)
-		}
+	r, err := NewRedactor(uploadCfg)
+	if err != nil {
+		return nil, err
 	}
 
 	p, err := provider.Get(engineCfg.Provider)
@@ -117,18 +311,61 @@ func New(uploadCfg *config.UploadConfig, engineCfg EngineConfig) (*Engine, error
 		return nil, fmt.Errorf("invalid provider %q: %w", engineCfg.Provider, err)
 	}
 
+	tracker := NewFileTracker(engineCfg.TranscriptPath)
+	tracker.uploadPartialTail = engineCfg.UploadPartialTail || uploadCfg.UploadPartialTail
+	tracker.skipOversizeLines = engineCfg.SkipOversizeLines || uploadCfg.SkipOversizeLines
+	tracker.syncAttachments = engineCfg.SyncAttachments || uploadCfg.SyncAttachments
+	tracker.bypassMarker = cmp.Or(engineCfg.BypassMarker, BypassMarker(uploadCfg))
+	if engineCfg.AgentExtractor != nil {
+		tracker.agentExtractor = engineCfg.AgentExtractor
+	}
+
+	staticMetadata := engineCfg.StaticMetadata
+	if staticMetadata == nil {
+		staticMetadata = uploadCfg.StaticMetadata
+	}
+
 	return &Engine{
 		backend:        client,
 		redactor:       r,
-		tracker:        NewFileTracker(engineCfg.TranscriptPath),
+		tracker:        tracker,
 		provider:       p,
 		externalID:     engineCfg.ExternalID,
 		transcriptPath: engineCfg.TranscriptPath,
 		cwd:            engineCfg.CWD,
 		model:          engineCfg.Model,
+		initOverride:   engineCfg.InitOverride,
+		onChunk:        engineCfg.OnChunkUploaded,
+		onChunkStats:   engineCfg.OnChunkStats,
+		onProgress:     engineCfg.OnProgress,
+		sendLineCounts: engineCfg.SendFileLineCounts || uploadCfg.SendFileLineCounts,
+		secondsPerLine: cmp.Or(engineCfg.SecondsPerLine, DefaultSecondsPerLine),
+		dedupWindow:    cmp.Or(engineCfg.InitDedupWindow, DefaultInitDedupWindow),
+		sendModTime:    engineCfg.SendSourceModTime || uploadCfg.SendSourceModTime,
+		staticMetadata: staticMetadata,
+
+		holdTailOnFinal: engineCfg.HoldTailOnFinalSync || uploadCfg.HoldTailOnExit,
+
+		maxConcurrentUploads: cmp.Or(engineCfg.MaxConcurrentUploads, uploadCfg.MaxConcurrentUploads),
+
+		maxAgentDepth:      cmp.Or(engineCfg.MaxAgentDepth, uploadCfg.MaxAgentDepth, DefaultMaxAgentDepth),
+		maxTotalAgentFiles: cmp.Or(engineCfg.MaxTotalAgentFiles, uploadCfg.MaxTotalAgentFiles, DefaultMaxTotalAgentFiles),
+
+		maxConsecutive400: cmp.Or(engineCfg.MaxConsecutive400, uploadCfg.MaxConsecutive400, DefaultMaxConsecutive400),
+		quarantine:        boolOr(engineCfg.QuarantineRejectedLines, uploadCfg.IsQuarantineEnabled()),
+
+		chunker: newAdaptiveChunker(engineCfg.TargetChunkDuration, engineCfg.MinChunkBytes, DefaultMaxChunkBytes),
 	}, nil
 }
 
+// boolOr returns *b, or def when b is nil.
+func boolOr(b *bool, def bool) bool {
+	if b == nil {
+		return def
+	}
+	return *b
+}
+
 // NewWithBackend creates an engine with a preconfigured backend.
 // Test-facing; returns an error if the provider name is invalid.
 func NewWithBackend(backend Backend, r *redactor.Redactor, engineCfg EngineConfig) (*Engine, error) {
@@ -136,15 +373,49 @@ func NewWithBackend(backend Backend, r *redactor.Redactor, engineCfg EngineConfi
 	if err != nil {
 		return nil, fmt.Errorf("invalid provider %q: %w", engineCfg.Provider, err)
 	}
+	tracker := NewFileTracker(engineCfg.TranscriptPath)
+	tracker.uploadPartialTail = engineCfg.UploadPartialTail
+	tracker.skipOversizeLines = engineCfg.SkipOversizeLines
+	tracker.syncAttachments = engineCfg.SyncAttachments
+	tracker.bypassMarker = engineCfg.BypassMarker
+	if engineCfg.AgentExtractor != nil {
+		tracker.agentExtractor = engineCfg.AgentExtractor
+	}
+
+	if client, ok := backend.(*Client); ok {
+		client.breaker = http.NewCircuitBreaker(engineCfg.CircuitBreaker)
+	}
+
 	return &Engine{
 		backend:        backend,
 		redactor:       r,
-		tracker:        NewFileTracker(engineCfg.TranscriptPath),
+		tracker:        tracker,
 		provider:       p,
 		externalID:     engineCfg.ExternalID,
 		transcriptPath: engineCfg.TranscriptPath,
 		cwd:            engineCfg.CWD,
 		model:          engineCfg.Model,
+		initOverride:   engineCfg.InitOverride,
+		onChunk:        engineCfg.OnChunkUploaded,
+		onChunkStats:   engineCfg.OnChunkStats,
+		onProgress:     engineCfg.OnProgress,
+		sendLineCounts: engineCfg.SendFileLineCounts,
+		secondsPerLine: cmp.Or(engineCfg.SecondsPerLine, DefaultSecondsPerLine),
+		dedupWindow:    cmp.Or(engineCfg.InitDedupWindow, DefaultInitDedupWindow),
+		sendModTime:    engineCfg.SendSourceModTime,
+		staticMetadata: engineCfg.StaticMetadata,
+
+		holdTailOnFinal: engineCfg.HoldTailOnFinalSync,
+
+		maxConcurrentUploads: engineCfg.MaxConcurrentUploads,
+
+		maxAgentDepth:      cmp.Or(engineCfg.MaxAgentDepth, DefaultMaxAgentDepth),
+		maxTotalAgentFiles: cmp.Or(engineCfg.MaxTotalAgentFiles, DefaultMaxTotalAgentFiles),
+
+		maxConsecutive400: cmp.Or(engineCfg.MaxConsecutive400, DefaultMaxConsecutive400),
+		quarantine:        boolOr(engineCfg.QuarantineRejectedLines, true),
+
+		chunker: newAdaptiveChunker(engineCfg.TargetChunkDuration, engineCfg.MinChunkBytes, DefaultMaxChunkBytes),
 	}, nil
 }
 
@@ -203,6 +474,15 @@ func (cv *chunkView) SetLatestMessageAt(t time.Time) {
 	ensureChunkMetadata(cv.chunk).LatestMessageAt = &t
 }
 
+// DefaultSecondsPerLine is the session time assumed per transcript line
+// for InitRequest.EstimatedDurationSeconds: a user prompt, an assistant
+// reply or a tool call each take a few seconds on average.
+const DefaultSecondsPerLine = 3.0
+
+// DefaultInitDedupWindow is how long an applied init response is
+// considered current when EngineConfig.InitDedupWindow is unset.
+const DefaultInitDedupWindow = 30 * time.Second
+
 // Init initializes the sync session with the backend.
 // - Creates session if not exists, or resumes existing
 // - Gets last_synced_line for all known files
@@ -232,13 +512,35 @@ func (e *Engine) Init() error {
 		GitInfo:  gitInfoJSON,
 		Hostname: hostname,
 		Username: username,
+		Static:   e.staticMetadata,
 	}
 
-	resp, err := e.backend.Init(e.provider.Name(), e.externalID, e.transcriptPath, metadata)
+	opts := &InitOptions{}
+	if e.sendLineCounts {
+		opts.FileLineCounts = e.localLineCounts()
+	}
+	if n, estimated, err := EstimateLines(e.transcriptPath); err == nil {
+		opts.TotalLineCount = n
+		opts.EstimatedDurationSeconds = float64(n) * e.secondsPerLine
+		if estimated {
+			logger.Debugf("Estimated transcript line count by sampling: %d", n)
+		}
+	}
+
+	resp, err := e.backend.Init(e.provider.Name(), e.externalID, e.transcriptPath, metadata, opts)
 	if err != nil {
 		return err
 	}
 
+	if e.initOverride != nil {
+		logger.Info("Applying init override", "backend_files", len(resp.Files), "override_files", len(e.initOverride.Files))
+		override := *e.initOverride
+		if override.SessionID == "" {
+			override.SessionID = resp.SessionID
+		}
+		resp = &override
+	}
+
 	e.sessionID = resp.SessionID
 	e.initialized = true
 
@@ -250,16 +552,56 @@ func (e *Engine) Init() error {
 	// metadata attached during provider.DiscoverDescendants.
 	if transcript := e.tracker.GetTranscriptFile(); transcript != nil {
 		if err := e.provider.InitTranscript(transcript, e.transcriptPath, e.externalID); err != nil {
-			logger.Warn("provider InitTranscript failed: %v", err)
+			logger.Warn("provider InitTranscript failed", "component", "sync", "error", err)
 		}
 	}
 
-	logger.Info("Sync session initialized: session_id=%s existing_files=%d", e.sessionID, len(resp.Files))
+	logger.Info("Sync session initialized", "component", "sync", "session_id", e.sessionID, "existing_files", len(resp.Files))
 
 	return nil
 }
 
+// InitFromState initializes the engine from backend file state the caller
+// fetched through a read-only endpoint, instead of Init's POST to
+// sync/init, which registers the session and updates its metadata. For
+// checks such as `confab verify` that must leave the backend untouched.
+func (e *Engine) InitFromState(resp *InitResponse) {
+	e.sessionID = resp.SessionID
+	e.initialized = true
+	e.applyBackendFiles(resp)
+}
+
+// localLineCounts counts lines in the transcript and every file already
+// tracked, keyed by backend file name. Files that can't be read (not yet
+// written, remote-only) are omitted rather than reported as zero.
+func (e *Engine) localLineCounts() map[string]int {
+	paths := map[string]string{filepath.Base(e.transcriptPath): e.transcriptPath}
+	for _, f := range e.tracker.GetTrackedFiles() {
+		paths[f.Name] = f.Path
+	}
+	counts := make(map[string]int, len(paths))
+	for name, path := range paths {
+		n, err := CountLines(path)
+		if err != nil {
+			logger.Debugf("Skipping line count for %s: %v", name, err)
+			continue
+		}
+		counts[name] = n
+	}
+	return counts
+}
+
+// applyBackendFiles seeds the tracker from an init response. Within the
+// dedup window of the last seed, a response whose last synced lines all
+// match the tracker's current ones is skipped: the tracker already agrees
+// with the backend, and re-seeding would only drop its read offsets.
 func (e *Engine) applyBackendFiles(resp *InitResponse) {
+	if time.Since(e.lastInitAppliedAt) < e.dedupWindow && e.trackerMatches(resp.Files) {
+		logger.Debug("Init response matches tracker state; keeping read offsets")
+		return
+	}
+	e.lastInitAppliedAt = time.Now()
+
 	backendState := make(map[string]FileState)
 	for fileName, state := range resp.Files {
 		backendState[fileName] = FileState{LastSyncedLine: state.LastSyncedLine}
@@ -267,6 +609,24 @@ func (e *Engine) applyBackendFiles(resp *InitResponse) {
 	e.tracker.InitFromBackendState(backendState)
 }
 
+// trackerMatches reports whether every tracked file's LastSyncedLine
+// equals the backend's in files (0 for a file the backend doesn't list),
+// and every file the backend lists is tracked.
+func (e *Engine) trackerMatches(files map[string]FileState) bool {
+	tracked := e.tracker.GetTrackedFiles()
+	seen := 0
+	for _, f := range tracked {
+		state, ok := files[f.Name]
+		if ok {
+			seen++
+		}
+		if f.LastSyncedLine != state.LastSyncedLine {
+			return false
+		}
+	}
+	return seen == len(files)
+}
+
 // IsInitialized returns true if Init() has been called successfully
 func (e *Engine) IsInitialized() bool {
 	return e.initialized
@@ -292,13 +652,42 @@ const maxSyncIterations = 10
 //  4. Add only NEW files to the queue for next iteration
 //  5. Repeat until queue is empty (or max iterations reached)
 //
+// Agent files beyond EngineConfig.MaxAgentDepth or MaxTotalAgentFiles are
+// skipped (see admitAgentFiles).
+//
 // Returns number of chunks uploaded and the first error encountered (if any).
 // Continues syncing other files even if one file fails.
 func (e *Engine) SyncAll() (int, error) {
+	return e.syncAll()
+}
+
+// SyncAllFinal is SyncAll for the last sync before the daemon exits. Each
+// file's last line is uploaded even if it lacks its trailing newline or
+// isn't valid JSON yet — there is no next cycle to wait for — unless
+// EngineConfig.HoldTailOnFinalSync (config hold_tail_on_exit) is set.
+func (e *Engine) SyncAllFinal() (int, error) {
+	if !e.holdTailOnFinal {
+		e.tracker.flushTail.Store(true)
+		defer e.tracker.flushTail.Store(false)
+	}
+	return e.syncAll()
+}
+
+func (e *Engine) syncAll() (int, error) {
 	if !e.initialized {
 		return 0, fmt.Errorf("engine not initialized: call Init() first")
 	}
 
+	// A file rewritten shorter than what was synced (a compacted
+	// transcript) can't be appended to from its old offset; re-read the
+	// backend's positions and rescan from the start instead.
+	if shrunk := e.tracker.ShrunkFiles(); len(shrunk) > 0 {
+		logger.Warn("Files shrank since last sync, re-initializing", "component", "sync", "files", shrunk)
+		if err := e.Reinit(); err != nil {
+			return 0, fmt.Errorf("re-init after files shrank: %w", err)
+		}
+	}
+
 	totalChunks := 0
 	var firstErr error
 
@@ -315,7 +704,7 @@ func (e *Engine) SyncAll() (int, error) {
 		reg = e.descendantReg
 	}
 	if err := e.provider.DiscoverDescendants(reg, e.externalID); err != nil {
-		logger.Warn("provider DiscoverDescendants failed: %v", err)
+		logger.Warn("provider DiscoverDescendants failed", "component", "sync", "error", err)
 	}
 
 	// Provider-owned workflow-file discovery (CF-533), gated per-file-type on
@@ -327,9 +716,9 @@ func (e *Engine) SyncAll() (int, error) {
 	e.capsProbedThisRun = false
 	if !e.workflowUploadsRuledOut() {
 		if n, err := e.provider.DiscoverWorkflowFiles(e.tracker, e.workflowFileTypeAllowed); err != nil {
-			logger.Warn("provider DiscoverWorkflowFiles failed: %v", err)
+			logger.Warn("provider DiscoverWorkflowFiles failed", "component", "sync", "error", err)
 		} else if n > 0 {
-			logger.Info("Discovered %d workflow subagent file(s)", n)
+			logger.Infof("Discovered %d workflow subagent file(s)", n)
 		}
 	}
 
@@ -338,109 +727,349 @@ func (e *Engine) SyncAll() (int, error) {
 
 	// BFS loop: process files in queue, discover new ones, add to queue
 	for iteration := 0; iteration < maxSyncIterations && len(filesToProcess) > 0; iteration++ {
-		var newAgentIDs []string
+		ready, capped, waiting := e.admitAgentFiles(filesToProcess)
+
+		// Transcripts go first and sequentially; agent and other sidechain
+		// files are then uploaded up to maxConcurrentUploads at a time.
+		var primary, sidechains []*TrackedFile
+		for _, file := range ready {
+			if file.Type == provider.FileTypeTranscript {
+				primary = append(primary, file)
+			} else {
+				sidechains = append(sidechains, file)
+			}
+		}
 
-		// Process each file in the current queue
-		for _, file := range filesToProcess {
-			// Check if file has changed (skip if not)
-			if !e.tracker.HasFileChanged(file) {
-				continue
+		var cycle syncCycle
+		for _, file := range primary {
+			e.syncFile(file, &cycle)
+		}
+		if e.maxConcurrentUploads <= 1 {
+			for _, file := range sidechains {
+				e.syncFile(file, &cycle)
+			}
+		} else {
+			var g errgroup.Group
+			sem := make(chan struct{}, e.maxConcurrentUploads)
+			for _, file := range sidechains {
+				sem <- struct{}{}
+				g.Go(func() error {
+					defer func() { <-sem }()
+					e.syncFile(file, &cycle)
+					return nil
+				})
 			}
+			g.Wait()
+		}
+		for _, file := range capped {
+			e.scanCappedAgent(file, &cycle)
+		}
 
-			// Read and upload chunks until no more data (handles byte-limited chunks)
-			for {
-				// Read new lines
-				chunk, err := e.tracker.ReadChunk(file, e.redactor, DefaultMaxChunkBytes)
-				if err != nil {
-					logger.Error("Failed to read chunk: file=%s error=%v", file.Path, err)
-					if firstErr == nil {
-						firstErr = err
-					}
-					break
-				}
+		totalChunks += cycle.chunks
+		if firstErr == nil {
+			firstErr = cycle.err
+		}
 
-				if chunk == nil {
-					break // No more lines
+		// Discover new files based on agent IDs found in this iteration.
+		// DiscoverNewFiles only returns files not already tracked (cycle-safe).
+		newFiles := e.tracker.DiscoverNewFiles(cycle.agentIDs)
+		newFiles = append(newFiles, e.tracker.DiscoverAttachments(cycle.attachmentPaths)...)
+		for _, f := range newFiles {
+			logger.Info("Discovered new file", "path", f.Path, "type", f.Type)
+		}
+		e.recordAgentDepths(cycle.agentDepths)
+
+		// Re-read the backend's positions after a failed upload, so the next
+		// sync resumes from them. Only now, with no upload in flight: the
+		// refresh replaces the tracked files, and progress recorded on the
+		// old ones would be lost. The queued files are among those replaced,
+		// so the rest waits for the next sync.
+		if cycle.refresh {
+			if err := e.refreshStateFromBackend(); err != nil {
+				logger.Error("Failed to refresh state from backend", "error", err)
+				// Auth errors from refresh should be propagated so daemon can handle them
+				if errors.Is(err, http.ErrUnauthorized) {
+					firstErr = err
 				}
+			}
+			break
+		}
 
-				// Collect agent IDs for discovery (local use only)
-				if len(chunk.AgentIDs) > 0 {
-					newAgentIDs = append(newAgentIDs, chunk.AgentIDs...)
-				}
+		// Queue only the newly discovered files for next iteration, plus
+		// agents still waiting for their depth
+		filesToProcess = append(newFiles, waiting...)
+	}
 
-				// Provider-owned chunk metadata. AnnotateChunk runs on every
-				// chunk regardless of file type; each provider internally
-				// gates its extraction (Codex first_user_message gated on
-				// transcript, codex_rollout gated on FirstLine==1; Claude
-				// extracts only from transcript files).
-				annotation := e.provider.AnnotateChunk(
-					&chunkView{chunk: chunk, file: file},
-					e.sentFirstUserMessage,
-					e.redactFn(),
-				)
-				for _, link := range annotation.SummaryLinks {
-					e.linkSummaryToPreviousSession(link.Summary, link.LeafUUID)
-				}
+	if firstErr == nil {
+		e.mu.Lock()
+		e.lastSyncAt = time.Now()
+		e.mu.Unlock()
+	}
+	return totalChunks, firstErr
+}
 
-				// Stamp the session-constant model onto transcript chunks
-				// (Cursor only — its model comes from the sessionStart hook,
-				// not the transcript). Generic + omitempty: providers with an
-				// empty model send nothing, so no provider branch lives here.
-				if e.model != "" && chunk.FileType == provider.FileTypeTranscript {
-					ensureChunkMetadata(chunk).Model = e.model
-				}
+// syncCycle accumulates one BFS iteration's results across the (possibly
+// concurrent) per-file uploads. Guarded by Engine.mu.
+type syncCycle struct {
+	chunks          int
+	agentIDs        []string
+	agentDepths     map[string]agentDepth // referenced agent file name → depth
+	attachmentPaths []string
+	err             error // first error encountered
+	refresh         bool  // an upload failed; re-read the backend's state
+}
 
-				// Upload chunk
-				lastLine, err := e.backend.UploadChunk(e.sessionID, chunk.FileName, chunk.FileType, chunk.FirstLine, chunk.Lines, chunk.Metadata)
-				if err != nil {
-					logger.Error("Failed to upload chunk: file=%s first_line=%d lines=%d error=%v",
-						chunk.FileName, chunk.FirstLine, len(chunk.Lines), err)
-					if firstErr == nil {
-						firstErr = err
-					}
-
-					// Refresh state from backend to handle partial success (e.g., timeout where
-					// server stored data but response didn't reach us). This ensures we resume
-					// from the correct position on the next sync attempt.
-					// Skip for auth errors (handled at daemon level) or session not found (can't recover).
-					if !errors.Is(err, http.ErrUnauthorized) && !errors.Is(err, http.ErrSessionNotFound) {
-						if refreshErr := e.refreshStateFromBackend(); refreshErr != nil {
-							logger.Error("Failed to refresh state from backend: %v", refreshErr)
-							// Auth errors from refresh should be propagated so daemon can handle them
-							if errors.Is(refreshErr, http.ErrUnauthorized) {
-								firstErr = refreshErr
-							}
-						}
-					}
-
-					break
-				}
+// noteAgentDepth records the depth of an agent file referenced this
+// iteration, keeping the shallowest. Caller holds Engine.mu.
+func (c *syncCycle) noteAgentDepth(name string, depth agentDepth) {
+	if name == "" {
+		return
+	}
+	if known, ok := c.agentDepths[name]; ok && !depth.shallowerThan(known) {
+		return
+	}
+	if c.agentDepths == nil {
+		c.agentDepths = make(map[string]agentDepth)
+	}
+	c.agentDepths[name] = depth
+}
 
-				// Update tracking state
-				if annotation.IncludedFirstUserMessage {
-					e.sentFirstUserMessage = true
-				}
-				e.tracker.UpdateAfterSync(file, lastLine, chunk.NewOffset)
+func (c *syncCycle) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// syncFile reads and uploads chunks of one file until it has no new lines
+// (handles byte-limited chunks), recording results in cycle. Safe to call
+// for different files concurrently: shared engine state is only touched
+// under e.mu, and the tracker serializes its own updates.
+func (e *Engine) syncFile(file *TrackedFile, cycle *syncCycle) {
+	// A SyncAll that overran its interval may still be uploading this
+	// file; leave it to that one.
+	if !e.tracker.BeginSync(file) {
+		return
+	}
+	defer e.tracker.EndSync(file)
+
+	// Check if file has changed (skip if not)
+	if !e.tracker.HasFileChanged(file) {
+		return
+	}
+	if file.Type == provider.FileTypeAttachment {
+		e.syncAttachment(file, cycle)
+		return
+	}
+
+	for {
+		// Read new lines
+		chunk, err := e.tracker.readChunk(file, e.redactor, e.chunker.Limit(), DefaultMaxChunkBytes)
+		if err != nil {
+			logger.Error("Failed to read chunk", "file", file.Path, "error", err)
+			e.mu.Lock()
+			cycle.fail(err)
+			e.mu.Unlock()
+			return
+		}
+
+		if chunk == nil {
+			file.clearRejections()
+			return // No more lines
+		}
+		if file.quarantineNext {
+			chunk.Lines[0] = quarantinePlaceholder(chunk.FirstLine)
+		}
+
+		// Provider-owned chunk metadata. AnnotateChunk runs on every
+		// chunk regardless of file type; each provider internally
+		// gates its extraction (Codex first_user_message gated on
+		// transcript, codex_rollout gated on FirstLine==1; Claude
+		// extracts only from transcript files).
+		e.mu.Lock()
+		sentFirst := e.sentFirstUserMessage
+		agentDepth := e.agentDepths[file.Name]
+		e.mu.Unlock()
+		annotation := e.provider.AnnotateChunk(
+			&chunkView{chunk: chunk, file: file},
+			sentFirst,
+			e.redactFn(),
+		)
+		for _, link := range annotation.SummaryLinks {
+			e.linkSummaryToPreviousSession(link.Summary, link.LeafUUID)
+		}
 
-				logger.Debug("Synced file: file=%s first_line=%d last_line=%d lines=%d",
-					chunk.FileName, chunk.FirstLine, lastLine, len(chunk.Lines))
+		// Stamp the session-constant model onto transcript chunks
+		// (Cursor only — its model comes from the sessionStart hook,
+		// not the transcript). Generic + omitempty: providers with an
+		// empty model send nothing, so no provider branch lives here.
+		if e.model != "" && chunk.FileType == provider.FileTypeTranscript {
+			ensureChunkMetadata(chunk).Model = e.model
+		}
+		if agentDepth.n > 0 && !agentDepth.guessed && chunk.FileType == provider.FileTypeAgent {
+			ensureChunkMetadata(chunk).AgentDepth = agentDepth.n
+		}
+		if e.sendModTime && !chunk.ModTime.IsZero() {
+			modTime := chunk.ModTime.UTC()
+			ensureChunkMetadata(chunk).SourceModTime = &modTime
+		}
+		if len(e.staticMetadata) > 0 {
+			ensureChunkMetadata(chunk).Static = e.staticMetadata
+		}
 
-				totalChunks++
+		// Upload chunk
+		uploadStart := time.Now()
+		lastLine, err := e.backend.UploadChunk(e.sessionID, chunk.FileName, chunk.FileType, chunk.FirstLine, chunk.Lines, chunk.Metadata)
+		if err != nil && isBadRequest(err) && e.handleRejectedChunk(file, chunk) {
+			continue // retry now with a smaller chunk
+		}
+		if err != nil {
+			logger.Error("Failed to upload chunk",
+				"component", "sync", "file", chunk.FileName, "first_line", chunk.FirstLine,
+				"lines", len(chunk.Lines), "error", err)
+
+			e.mu.Lock()
+			cycle.fail(err)
+			// Refresh state from backend to handle partial success (e.g., timeout where
+			// server stored data but response didn't reach us), once this iteration's
+			// uploads are done (see syncAll).
+			// Skip for auth errors (handled at daemon level) or session not found (can't recover).
+			if !errors.Is(err, http.ErrUnauthorized) && !errors.Is(err, http.ErrSessionNotFound) {
+				cycle.refresh = true
 			}
+			e.mu.Unlock()
+			return
 		}
 
-		// Discover new files based on agent IDs found in this iteration.
-		// DiscoverNewFiles only returns files not already tracked (cycle-safe).
-		newFiles := e.tracker.DiscoverNewFiles(newAgentIDs)
-		for _, f := range newFiles {
-			logger.Info("Discovered new file: path=%s type=%s", f.Path, f.Type)
+		e.chunker.Observe(chunkBytes(chunk.Lines), time.Since(uploadStart))
+
+		// Update tracking state
+		e.tracker.UpdateAfterSync(file, lastLine, chunk.NewOffset)
+		if file.quarantineNext {
+			logger.Warn("Quarantined a line the backend keeps rejecting; uploaded a placeholder in its place", "component", "sync", "file", chunk.FileName, "line", chunk.FirstLine)
+			file.clearRejections()
+		} else {
+			file.rejections = 0
 		}
 
-		// Queue only the newly discovered files for next iteration
-		filesToProcess = newFiles
+		e.mu.Lock()
+		if annotation.IncludedFirstUserMessage {
+			e.sentFirstUserMessage = true
+		}
+		// Collect agent IDs for discovery (local use only)
+		e.noteReferencedAgents(file, chunk, cycle)
+		cycle.attachmentPaths = append(cycle.attachmentPaths, chunk.AttachmentPaths...)
+		cycle.chunks++
+		e.bytesUploaded += chunkBytes(chunk.Lines)
+		e.reportChunk(file, chunk)
+		e.mu.Unlock()
+
+		logger.Debug("Chunk uploaded",
+			"component", "sync", "file", chunk.FileName, "first_line", chunk.FirstLine,
+			"last_line", lastLine, "lines", len(chunk.Lines))
 	}
+}
 
-	return totalChunks, firstErr
+// DefaultMaxConsecutive400 is how many 400s in a row the same chunk may get
+// before SyncAll splits it to isolate the rejected line.
+const DefaultMaxConsecutive400 = 3
+
+// isBadRequest reports whether err is a 400 Bad Request response.
+func isBadRequest(err error) bool {
+	var statusErr *http.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == 400
+}
+
+// handleRejectedChunk is called when the backend rejects chunk with 400 and
+// reports whether syncFile should retry right away with a smaller chunk.
+// A 400 usually means content the backend won't accept, so retrying the
+// same chunk every cycle never makes progress. Once the chunk at the same
+// first line has been rejected maxConsecutive400 times, it is halved on
+// each further 400 until the offending line is alone; that line is then
+// quarantined (uploaded as a placeholder, keeping line numbers contiguous)
+// if enabled, or left to fail every cycle otherwise. Runs in the goroutine
+// syncing file, which owns its rejection state.
+func (e *Engine) handleRejectedChunk(file *TrackedFile, chunk *Chunk) bool {
+	if chunk.FirstLine != file.rejectedLine {
+		file.rejectedLine, file.rejections = chunk.FirstLine, 0
+	}
+	file.rejections++
+	if file.chunkLineLimit == 0 && file.rejections < e.maxConsecutive400 {
+		return false
+	}
+	log := logger.With("component", "sync", "file", chunk.FileName, "first_line", chunk.FirstLine, "rejections", file.rejections)
+	switch {
+	case file.quarantineNext:
+		return false // the placeholder was rejected too
+	case len(chunk.Lines) > 1:
+		file.chunkLineLimit = len(chunk.Lines) / 2
+		log.Warn("Backend keeps rejecting chunk, retrying a smaller one", "lines", file.chunkLineLimit)
+		return true
+	case e.quarantine:
+		file.chunkLineLimit = 1
+		file.quarantineNext = true
+		return true
+	default:
+		file.chunkLineLimit = 1
+		log.Error("Backend keeps rejecting this line and quarantine is disabled; the file can't sync past it")
+		return false
+	}
+}
+
+// quarantinePlaceholder replaces a quarantined line in the upload.
+func quarantinePlaceholder(line int) string {
+	return fmt.Sprintf(`{"type":"confab_quarantined","line":%d,"reason":"rejected by the backend (400 Bad Request)"}`, line)
+}
+
+// ChunkStats describes one chunk the backend accepted, for
+// EngineConfig.OnChunkStats.
+type ChunkStats struct {
+	FileName string
+	FileType string
+	Lines    int
+	Bytes    int64 // uncompressed, newlines included
+}
+
+// chunkBytes is the uncompressed size of lines, one newline each.
+func chunkBytes(lines []string) int64 {
+	var n int64
+	for _, line := range lines {
+		n += int64(len(line)) + 1
+	}
+	return n
+}
+
+// reportChunk calls the per-chunk callbacks for an accepted chunk of file,
+// after UpdateAfterSync. Callers must not run it concurrently; SyncAll holds
+// e.mu.
+func (e *Engine) reportChunk(file *TrackedFile, chunk *Chunk) {
+	if e.onChunk != nil {
+		e.onChunk(chunk.FileName, len(chunk.Lines))
+	}
+	if e.onChunkStats != nil {
+		e.onChunkStats(ChunkStats{
+			FileName: chunk.FileName,
+			FileType: chunk.FileType,
+			Lines:    len(chunk.Lines),
+			Bytes:    chunkBytes(chunk.Lines),
+		})
+	}
+	if e.onProgress != nil {
+		e.onProgress(chunk.FileName, file.LastSyncedLine, estimateTotalLines(file))
+	}
+}
+
+// estimateTotalLines extrapolates a file's line count from its synced
+// position: the file size (as of UpdateAfterSync) divided by the average
+// length of the lines up to ByteOffset. Compressed files, whose offsets
+// count decompressed bytes, and files without an offset report their
+// synced line count.
+func estimateTotalLines(file *TrackedFile) int {
+	synced := file.LastSyncedLine
+	if synced <= 0 || file.ByteOffset <= 0 || isCompressed(file.Path) {
+		return synced
+	}
+	total := int(float64(file.LastSize) * float64(synced) / float64(file.ByteOffset))
+	return max(total, synced)
 }
 
 // resolveCaps lazily probes and caches the backend's workflow capabilities
@@ -474,7 +1103,7 @@ func (e *Engine) resolveCaps() (Capabilities, bool) {
 		// workflow uploads for the rest of the session.
 		if !e.loggedProbeError {
 			e.loggedProbeError = true
-			logger.Info("Backend capability probe failed (%v); will retry next sync cycle", err)
+			logger.Infof("Backend capability probe failed (%v); will retry next sync cycle", err)
 		}
 		return Capabilities{}, false
 	}
@@ -482,8 +1111,8 @@ func (e *Engine) resolveCaps() (Capabilities, bool) {
 	e.caps = caps
 	e.capsResolved = true
 	if caps.WorkflowFiles || caps.WorkflowJournal {
-		logger.Info("Backend workflow capabilities: workflow_files=%v workflow_journal=%v",
-			caps.WorkflowFiles, caps.WorkflowJournal)
+		logger.Info("Backend workflow capabilities",
+			"workflow_files", caps.WorkflowFiles, "workflow_journal", caps.WorkflowJournal)
 	} else {
 		logger.Info("Backend advertises no workflow file support; skipping workflow subagent uploads")
 	}
@@ -574,7 +1203,7 @@ func logGitRemotes(info *git.GitInfo) {
 	if tracking == "" {
 		tracking = "<none>"
 	}
-	logger.Info("Git remotes detected: %s (tracking: %s)",
+	logger.Infof("Git remotes detected: %s (tracking: %s)",
 		strings.Join(names, ", "), tracking)
 }
 
@@ -597,17 +1226,104 @@ func (e *Engine) SendSessionEnd(hookInput *types.ClaudeHookInput, timestamp time
 		return fmt.Errorf("failed to send session_end event: %w", err)
 	}
 
-	logger.Info("Sent session_end event: session_id=%s", e.sessionID)
+	logger.Info("Sent session_end event", "session_id", e.sessionID)
+	return nil
+}
+
+// Stats is a snapshot of the engine's sync progress, used by the daemon to
+// report status for `confab status`.
+type Stats struct {
+	FileLines     map[string]int // backend file name → last synced line
+	BytesUploaded int64          // uncompressed bytes uploaded by this engine
+	LastSyncAt    time.Time      // zero until a SyncAll cycle completes cleanly
+}
+
+// GetSyncStats returns current sync statistics: lines synced per file,
+// bytes uploaded and the last clean SyncAll.
+func (e *Engine) GetSyncStats() Stats {
+	files := e.tracker.GetTrackedFiles()
+	lines := make(map[string]int, len(files))
+	for _, file := range files {
+		lines[file.Name] = file.LastSyncedLine
+	}
+	return Stats{
+		FileLines:     lines,
+		BytesUploaded: e.bytesUploaded,
+		LastSyncAt:    e.lastSyncAt,
+	}
+}
+
+// KnownAgentIDs returns the agent IDs discovered so far (sorted). See
+// FileTracker.KnownAgentIDs.
+func (e *Engine) KnownAgentIDs() []string {
+	return e.tracker.KnownAgentIDs()
+}
+
+// SeedKnownAgentIDs restores agent IDs discovered by a previous daemon run so
+// agents referenced before a restart are still picked up when their files
+// appear afterwards. See FileTracker.SeedKnownAgentIDs.
+func (e *Engine) SeedKnownAgentIDs(ids []string) {
+	e.tracker.SeedKnownAgentIDs(ids)
+}
+
+// SeedOffsetHints restores file read positions persisted by a previous
+// daemon run; must be called before Init. See FileTracker.SeedOffsetHints.
+func (e *Engine) SeedOffsetHints(hints map[string]FileOffset) {
+	e.tracker.SeedOffsetHints(hints)
+}
+
+// FileOffsets returns the current read position of each tracked file, for
+// persisting across restarts. See FileTracker.Offsets.
+func (e *Engine) FileOffsets() map[string]FileOffset {
+	return e.tracker.Offsets()
+}
+
+// FilePaths returns the local path of each tracked file, for persisting so
+// tools like `confab export` can find a session's files. See
+// FileTracker.Paths.
+func (e *Engine) FilePaths() map[string]string {
+	return e.tracker.Paths()
+}
+
+// ChunkSizing snapshots adaptive chunk sizing for persistence.
+func (e *Engine) ChunkSizing() ChunkSizing {
+	return e.chunker.Sizing()
+}
+
+// SeedChunkSizing restores adaptive chunk sizing persisted by an earlier
+// engine (see ChunkSizing), so it needn't relearn a slow connection.
+func (e *Engine) SeedChunkSizing(s ChunkSizing) {
+	e.chunker.Seed(s)
+}
+
+// PendingUploads returns the multipart chunk uploads started but not yet
+// completed, for persisting across restarts. Nil when the backend doesn't
+// do multipart uploads.
+func (e *Engine) PendingUploads() map[string]PendingUpload {
+	if b, ok := e.backend.(multipartBackend); ok {
+		return b.PendingUploads()
+	}
 	return nil
 }
 
-// GetSyncStats returns current sync statistics (lines synced per file)
-func (e *Engine) GetSyncStats() map[string]int {
-	stats := make(map[string]int)
-	for _, file := range e.tracker.GetTrackedFiles() {
-		stats[file.Name] = file.LastSyncedLine
+// SeedPendingUploads restores uploads persisted by an earlier engine (see
+// PendingUploads), so a chunk interrupted mid-upload resumes from the
+// first part the backend is missing.
+func (e *Engine) SeedPendingUploads(pending map[string]PendingUpload) {
+	if b, ok := e.backend.(multipartBackend); ok {
+		b.SeedPendingUploads(pending)
 	}
-	return stats
+}
+
+// RefreshTokenIfExpiring refreshes the backend access token if it expires
+// within the given window, reporting whether it did. Safe to call
+// concurrently with SyncAll. A no-op for backends without expiring tokens.
+func (e *Engine) RefreshTokenIfExpiring(within time.Duration) (bool, error) {
+	r, ok := e.backend.(tokenRefresher)
+	if !ok {
+		return false, nil
+	}
+	return r.RefreshTokenIfExpiring(within)
 }
 
 // Reset clears the initialized state, allowing Init to be called again.
@@ -616,6 +1332,22 @@ func (e *Engine) GetSyncStats() map[string]int {
 func (e *Engine) Reset() {
 	e.initialized = false
 	e.sessionID = ""
+	e.lastInitAppliedAt = time.Time{}
+}
+
+// Reinit re-reads every file's last synced line from the backend and drops
+// the local read offsets, so the next sync rescans each file from the start
+// and resumes after the lines the backend already has. Used when a file may
+// have been rewritten rather than appended to (see ShrunkFiles and the
+// daemon's PreCompact handling).
+func (e *Engine) Reinit() error {
+	if !e.initialized {
+		return fmt.Errorf("engine not initialized: call Init() first")
+	}
+	// Dropping the offsets is the point, even when the backend's state
+	// hasn't changed.
+	e.lastInitAppliedAt = time.Time{}
+	return e.refreshStateFromBackend()
 }
 
 // refreshStateFromBackend calls Init to get current backend state and updates tracker.
@@ -623,13 +1355,13 @@ func (e *Engine) Reset() {
 // received data but we didn't get a response (e.g., timeout).
 func (e *Engine) refreshStateFromBackend() error {
 	// Call Init without metadata - we just want to refresh file states
-	resp, err := e.backend.Init(e.provider.Name(), e.externalID, e.transcriptPath, nil)
+	resp, err := e.backend.Init(e.provider.Name(), e.externalID, e.transcriptPath, nil, nil)
 	if err != nil {
 		return err
 	}
 
 	e.applyBackendFiles(resp)
 
-	logger.Info("Refreshed sync state from backend: files=%d", len(resp.Files))
+	logger.Info("Refreshed sync state from backend", "files", len(resp.Files))
 	return nil
 }
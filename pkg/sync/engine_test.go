@@ -1,14 +1,22 @@
 package sync
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"testing"
@@ -34,9 +42,16 @@ func readRequestBody(r *http.Request) ([]byte, error) {
 		return nil, err
 	}
 
-	// Decompress if zstd encoded
-	if r.Header.Get("Content-Encoding") == "zstd" {
+	// Decompress per Content-Encoding (zstd by default, gzip if configured)
+	switch r.Header.Get("Content-Encoding") {
+	case "zstd":
 		return zstdDecoder.DecodeAll(body, nil)
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(zr)
 	}
 
 	return body, nil
@@ -61,6 +76,17 @@ type mockBackend struct {
 	caps             *Capabilities
 	capsStatus       int
 	capsRequestCount int32
+
+	// Session listing. GET /api/v1/sessions pages through sessions (the
+	// cursor is the next index); GET and DELETE /api/v1/sessions/{id} find
+	// and remove entries, recording deletes in deletedSessions.
+	sessions        []SessionListItem
+	deletedSessions []string
+
+	// Line checksums. GET /api/v1/sessions/{id}/checksum compares the
+	// requested checksums with remoteLines, the backend's stored copy.
+	remoteLines      []string
+	checksumRequests []ChecksumRequest
 }
 
 // summaryRequest captures a PATCH to /api/v1/sessions/{externalID}/summary.
@@ -143,8 +169,12 @@ func (m *mockBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		m.chunkRequests = append(m.chunkRequests, req)
 
-		// Return last synced line as first + len(lines) - 1
+		// Return last synced line as first + len(lines) - 1; an
+		// attachment counts as one line
 		lastLine := req.FirstLine + len(req.Lines) - 1
+		if req.Attachment != nil {
+			lastLine = req.FirstLine
+		}
 		json.NewEncoder(w).Encode(ChunkResponse{
 			LastSyncedLine: lastLine,
 		})
@@ -159,7 +189,55 @@ func (m *mockBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		m.eventRequests = append(m.eventRequests, req)
 		json.NewEncoder(w).Encode(EventResponse{Success: true})
 
+	case "/api/v1/sessions":
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		end := min(start+limit, len(m.sessions))
+		resp := ListSessionsResponse{Sessions: m.sessions[start:end]}
+		if end < len(m.sessions) {
+			resp.NextCursor = strconv.Itoa(end)
+		}
+		json.NewEncoder(w).Encode(resp)
+
 	default:
+		if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/sessions/") &&
+			strings.HasSuffix(r.URL.Path, "/checksum") {
+			var req ChecksumRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				m.t.Errorf("Failed to decode checksum request: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			m.checksumRequests = append(m.checksumRequests, req)
+			resp := ChecksumResponse{Matches: []bool{}}
+			for i, l := range req.Lines {
+				if i >= len(m.remoteLines) {
+					break
+				}
+				resp.Matches = append(resp.Matches, crc32.ChecksumIEEE([]byte(m.remoteLines[i])) == l.CRC32)
+			}
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+		// GET / DELETE /api/v1/sessions/{id}
+		if id, ok := strings.CutPrefix(r.URL.Path, "/api/v1/sessions/"); ok && !strings.Contains(id, "/") &&
+			(r.Method == http.MethodGet || r.Method == http.MethodDelete) {
+			for i, s := range m.sessions {
+				if s.ID != id {
+					continue
+				}
+				if r.Method == http.MethodDelete {
+					m.deletedSessions = append(m.deletedSessions, id)
+					m.sessions = append(m.sessions[:i], m.sessions[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				json.NewEncoder(w).Encode(s)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
 		// PATCH /api/v1/sessions/{external_id}/summary — used by
 		// linkSummaryToPreviousSession. Record the request so dispatch
 		// tests can assert it fired.
@@ -254,6 +332,396 @@ func TestEngine_Init_NewSession(t *testing.T) {
 	}
 }
 
+// TestEngine_Init_FileLineCounts verifies the opt-in per-file line counts in
+// the init request match the transcript's actual lines, and are omitted when
+// the option is off.
+func TestEngine_Init_FileLineCounts(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"n":1}`+"\n"+`{"n":2}`+"\n"+`{"n":3}`), 0644)
+
+	for _, enabled := range []bool{true, false} {
+		engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+			ExternalID:         "line-counts-test",
+			TranscriptPath:     transcriptPath,
+			CWD:                tmpDir,
+			SendFileLineCounts: enabled,
+		})
+		if err := engine.Init(); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+	}
+
+	if len(mock.initRequests) != 2 {
+		t.Fatalf("expected 2 init requests, got %d", len(mock.initRequests))
+	}
+	if got := mock.initRequests[0].FileLineCounts; len(got) != 1 || got["transcript.jsonl"] != 3 {
+		t.Errorf("FileLineCounts = %v, want map[transcript.jsonl:3]", got)
+	}
+	if got := mock.initRequests[1].FileLineCounts; got != nil {
+		t.Errorf("expected no FileLineCounts when disabled, got %v", got)
+	}
+	// The transcript's total is sent either way.
+	for i, req := range mock.initRequests {
+		if req.TotalLineCount != 3 {
+			t.Errorf("init %d: TotalLineCount = %d, want 3", i, req.TotalLineCount)
+		}
+		if req.EstimatedDurationSeconds != 3*DefaultSecondsPerLine {
+			t.Errorf("init %d: EstimatedDurationSeconds = %v, want %v", i, req.EstimatedDurationSeconds, 3*DefaultSecondsPerLine)
+		}
+	}
+}
+
+// TestEngine_SyncAll_SourceModTime verifies the opt-in source_mod_time in
+// chunk metadata is the file's actual modification time, and is omitted
+// when the option is off.
+func TestEngine_SyncAll_SourceModTime(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"user"}`+"\n"), 0644)
+	mtime := time.Date(2026, 3, 14, 15, 9, 26, 0, time.FixedZone("PDT", -7*3600))
+	if err := os.Chtimes(transcriptPath, mtime, mtime); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	for _, enabled := range []bool{true, false} {
+		engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+			ExternalID:        "mod-time-test",
+			TranscriptPath:    transcriptPath,
+			CWD:               tmpDir,
+			SendSourceModTime: enabled,
+		})
+		if err := engine.Init(); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+		if _, err := engine.SyncAll(); err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+	}
+
+	if len(mock.chunkRequests) != 2 {
+		t.Fatalf("expected 2 chunk requests, got %d", len(mock.chunkRequests))
+	}
+	meta := mock.chunkRequests[0].Metadata
+	if meta == nil || meta.SourceModTime == nil {
+		t.Fatalf("chunk metadata = %+v, want source_mod_time", meta)
+	}
+	if !meta.SourceModTime.Equal(mtime) || meta.SourceModTime.Location() != time.UTC {
+		t.Errorf("source_mod_time = %v, want %v in UTC", meta.SourceModTime, mtime.UTC())
+	}
+	if meta := mock.chunkRequests[1].Metadata; meta != nil && meta.SourceModTime != nil {
+		t.Errorf("expected no source_mod_time when disabled, got %v", meta.SourceModTime)
+	}
+}
+
+// TestEngine_SyncAll_StaticMetadata verifies the upload config's
+// static_metadata is sent in the init metadata and on every chunk.
+func TestEngine_SyncAll_StaticMetadata(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"user"}`+"\n"), 0644)
+
+	static := map[string]string{"department": "research", "cost_center": "cc-42"}
+	engine, err := New(&config.UploadConfig{
+		BackendURL:     server.URL,
+		APIKey:         "test-api-key-12345678",
+		StaticMetadata: static,
+	}, EngineConfig{
+		Provider:       provider.NameClaudeCode,
+		ExternalID:     "static-metadata-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+
+	if len(mock.initRequests) != 1 || mock.initRequests[0].Metadata == nil {
+		t.Fatalf("init requests = %+v, want one with metadata", mock.initRequests)
+	}
+	if got := mock.initRequests[0].Metadata.Static; !maps.Equal(got, static) {
+		t.Errorf("init static metadata = %v, want %v", got, static)
+	}
+	if len(mock.chunkRequests) != 1 || mock.chunkRequests[0].Metadata == nil {
+		t.Fatalf("chunk requests = %+v, want one with metadata", mock.chunkRequests)
+	}
+	if got := mock.chunkRequests[0].Metadata.Static; !maps.Equal(got, static) {
+		t.Errorf("chunk static metadata = %v, want %v", got, static)
+	}
+}
+
+func TestEngine_Verify_RedactsBeforeHashing(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"password":"hunter2"}`+"\n"), 0644)
+	mock.initResponse.Files = map[string]FileState{
+		"transcript.jsonl": {LastSyncedLine: 1},
+		"agent-gone.jsonl": {LastSyncedLine: 4},
+	}
+
+	r, err := redactor.NewFromConfig(&config.RedactionConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), r, EngineConfig{
+		ExternalID:     "verify-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	results, err := engine.Verify(func(fileName string, w io.Writer) error {
+		_, err := io.WriteString(w, `{"password":"[REDACTED:SENSITIVE_FIELD]"}`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if agent := results[0]; agent.FileName != "agent-gone.jsonl" || !agent.LocalMissing || !agent.Diverged() {
+		t.Errorf("expected missing agent file to diverge, got %+v", agent)
+	}
+	if tr := results[1]; tr.Diverged() || tr.LocalHash == "" {
+		t.Errorf("expected redacted transcript to match backend hash, got %+v", tr)
+	}
+}
+
+func TestEngine_VerifyLines_ReportsAndRepairsDivergedLines(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"n":1}`+"\n"+`{"password":"hunter2"}`+"\n"+`{"n":3}`+"\n"+`{"n":4}`+"\n"+`{"n":5}`+"\n"), 0644)
+	mock.initResponse.Files = map[string]FileState{"transcript.jsonl": {LastSyncedLine: 4}}
+	mock.remoteLines = []string{`{"n":1}`, `{"password":"[REDACTED:SENSITIVE_FIELD]"}`, `{"n":"three"}`, `{"n":4}`}
+
+	r, err := redactor.NewFromConfig(&config.RedactionConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), r, EngineConfig{
+		ExternalID:     "verify-lines-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	v, err := engine.VerifyLines("")
+	if err != nil {
+		t.Fatalf("VerifyLines failed: %v", err)
+	}
+	if len(mock.checksumRequests) != 1 || mock.checksumRequests[0].SessionID != "test-session-id" ||
+		mock.checksumRequests[0].FileName != "transcript.jsonl" || len(mock.checksumRequests[0].Lines) != 5 {
+		t.Fatalf("unexpected checksum requests: %+v", mock.checksumRequests)
+	}
+	if v.LocalLines != 5 || !slices.Equal(v.Mismatched, []int{3}) || !slices.Equal(v.Missing, []int{5}) {
+		t.Fatalf("VerifyLines = %+v, want line 3 mismatched and line 5 missing", v)
+	}
+
+	mock.chunkRequests = nil
+	sent, err := engine.RepairLines(v)
+	if err != nil {
+		t.Fatalf("RepairLines failed: %v", err)
+	}
+	if sent != 2 || len(mock.chunkRequests) != 2 {
+		t.Fatalf("RepairLines sent %d lines in %d chunks, want 2 in 2", sent, len(mock.chunkRequests))
+	}
+	for i, want := range []int{3, 5} {
+		if c := mock.chunkRequests[i]; c.FirstLine != want || len(c.Lines) != 1 {
+			t.Errorf("repair chunk %d = first line %d, %d lines; want line %d alone", i, c.FirstLine, len(c.Lines), want)
+		}
+	}
+}
+
+func TestLineRanges(t *testing.T) {
+	got := LineVerification{Missing: []int{9, 10}, Mismatched: []int{7, 2, 3, 8}}.Ranges()
+	want := [][2]int{{2, 3}, {7, 10}}
+	if !slices.Equal(got, want) {
+		t.Errorf("Ranges() = %v, want %v", got, want)
+	}
+}
+
+func TestEngine_RefreshSkipsIdenticalInitResponse(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"n":1}`+"\n"+`{"n":2}`+"\n"+`{"n":3}`+"\n"), 0644)
+	mock.initResponse.Files = map[string]FileState{"transcript.jsonl": {LastSyncedLine: 2}}
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "dedup-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	// Stand in for a read position reached by an earlier sync.
+	engine.Tracker().GetTranscriptFile().ByteOffset = 16
+
+	refresh := func() *TrackedFile {
+		t.Helper()
+		if err := engine.refreshStateFromBackend(); err != nil {
+			t.Fatalf("refreshStateFromBackend: %v", err)
+		}
+		return engine.Tracker().GetTranscriptFile()
+	}
+
+	// The same response within the window keeps the read position.
+	if f := refresh(); f.ByteOffset != 16 || f.LastSyncedLine != 2 {
+		t.Errorf("identical response: offset=%d line=%d, want 16 and 2", f.ByteOffset, f.LastSyncedLine)
+	}
+
+	// A tracker that moved past the backend (an upload it didn't keep) is
+	// re-seeded even though the response hasn't changed.
+	engine.Tracker().UpdateAfterSync(engine.Tracker().GetTranscriptFile(), 3, 24)
+	if f := refresh(); f.ByteOffset != 0 || f.LastSyncedLine != 2 {
+		t.Errorf("tracker ahead of backend: offset=%d line=%d, want 0 and 2", f.ByteOffset, f.LastSyncedLine)
+	}
+
+	// Changed backend state re-seeds the tracker.
+	mock.initResponse.Files = map[string]FileState{"transcript.jsonl": {LastSyncedLine: 3}}
+	if f := refresh(); f.ByteOffset != 0 || f.LastSyncedLine != 3 {
+		t.Errorf("changed response: offset=%d line=%d, want 0 and 3", f.ByteOffset, f.LastSyncedLine)
+	}
+
+	// Past the window, the same response is applied again.
+	engine.Tracker().GetTranscriptFile().ByteOffset = 24
+	engine.lastInitAppliedAt = time.Now().Add(-2 * DefaultInitDedupWindow)
+	if f := refresh(); f.ByteOffset != 0 {
+		t.Errorf("stale cached response: offset=%d, want 0", f.ByteOffset)
+	}
+
+	// Reinit always drops the read positions.
+	engine.Tracker().GetTranscriptFile().ByteOffset = 24
+	if err := engine.Reinit(); err != nil {
+		t.Fatalf("Reinit: %v", err)
+	}
+	if f := engine.Tracker().GetTranscriptFile(); f.ByteOffset != 0 {
+		t.Errorf("Reinit kept offset %d, want 0", f.ByteOffset)
+	}
+	if got := len(mock.initRequests); got != 6 {
+		t.Errorf("init requests = %d, want 6 (every refresh asks the backend)", got)
+	}
+}
+
+func TestEngine_Diff_RedactsLocalLines(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"password":"hunter2"}`+"\n"+`{"n":2}`+"\n"), 0644)
+	mock.initResponse.Files = map[string]FileState{"transcript.jsonl": {LastSyncedLine: 2}}
+
+	r, err := redactor.NewFromConfig(&config.RedactionConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), r, EngineConfig{
+		ExternalID:     "diff-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	diffs, err := engine.Diff("", func(fileName string, w io.Writer) error {
+		_, err := io.WriteString(w, `{"password":"[REDACTED:SENSITIVE_FIELD]"}`+"\n"+`{"n":3}`+"\n")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(diffs) != 1 {
+		t.Fatalf("expected only line 2 to differ, got %+v", diffs)
+	}
+	if d := diffs[0]; d.Line != 2 || d.Local != `{"n":2}` || d.Backend != `{"n":3}` {
+		t.Errorf("unexpected diff: %+v", d)
+	}
+}
+
+func TestEngine_ReplayRange(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	var content string
+	for i := 1; i <= 5; i++ {
+		content += fmt.Sprintf(`{"n":%d}`, i) + "\n"
+	}
+	os.WriteFile(transcriptPath, []byte(content), 0644)
+	mock.initResponse.Files = map[string]FileState{
+		"transcript.jsonl": {LastSyncedLine: 5},
+	}
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "replay-range-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	sent, err := engine.ReplayRange("", 2, 3)
+	if err != nil {
+		t.Fatalf("ReplayRange failed: %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("sent = %d, want 2", sent)
+	}
+	if len(mock.chunkRequests) != 1 {
+		t.Fatalf("expected 1 chunk request, got %d", len(mock.chunkRequests))
+	}
+	req := mock.chunkRequests[0]
+	if req.FirstLine != 2 || len(req.Lines) != 2 || req.Lines[1] != `{"n":3}` {
+		t.Errorf("unexpected chunk: first_line=%d lines=%v", req.FirstLine, req.Lines)
+	}
+	if got := engine.GetSyncStats().FileLines["transcript.jsonl"]; got != 5 {
+		t.Errorf("replay changed sync state: last synced line = %d, want 5", got)
+	}
+
+	if sent, err := engine.ReplayRange("transcript.jsonl", 4, 0); err != nil || sent != 2 {
+		t.Errorf("ReplayRange to EOF = (%d, %v), want (2, nil)", sent, err)
+	}
+	if _, err := engine.ReplayRange("", 6, 0); err == nil {
+		t.Error("expected error for from line beyond EOF")
+	}
+	if _, err := engine.ReplayRange("agent-missing.jsonl", 1, 0); err == nil {
+		t.Error("expected error for untracked file")
+	}
+}
+
 // TestEngine_SendSessionEnd_DispatchesEvent verifies SendSessionEnd
 // marshals the hook payload and dispatches a "session_end" event with
 // the engine's externalID. Covers engine.go:381 — entirely 0% prior.
@@ -299,118 +767,535 @@ func TestEngine_SendSessionEnd_DispatchesEvent(t *testing.T) {
 	if !got.Timestamp.Equal(ts) {
 		t.Errorf("Timestamp = %v, want %v", got.Timestamp, ts)
 	}
-	// Payload must round-trip back to the original hook input.
-	var decoded types.ClaudeHookInput
-	if err := json.Unmarshal(got.Payload, &decoded); err != nil {
-		t.Fatalf("payload not valid JSON: %v", err)
+	// Payload must round-trip back to the original hook input.
+	var decoded types.ClaudeHookInput
+	if err := json.Unmarshal(got.Payload, &decoded); err != nil {
+		t.Fatalf("payload not valid JSON: %v", err)
+	}
+	if decoded.SessionID != hookInput.SessionID ||
+		decoded.HookEventName != hookInput.HookEventName ||
+		decoded.Reason != hookInput.Reason {
+		t.Errorf("payload round-trip mismatch: got %+v, want %+v", decoded, hookInput)
+	}
+}
+
+// TestEngine_SendSessionEnd_NotInitialized verifies SendSessionEnd is a
+// no-op (no error, no backend call) when the engine isn't initialized.
+// Protects the early-return at engine.go:382.
+func TestEngine_SendSessionEnd_NotInitialized(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"), 0644)
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{ExternalID: "not-init", TranscriptPath: transcriptPath, CWD: tmpDir})
+	// No Init() call.
+	if err := engine.SendSessionEnd(&types.ClaudeHookInput{}, time.Now()); err != nil {
+		t.Errorf("SendSessionEnd on uninitialized engine returned %v, want nil (no-op)", err)
+	}
+	if len(mock.eventRequests) != 0 {
+		t.Errorf("uninitialized engine made %d event requests, want 0", len(mock.eventRequests))
+	}
+}
+
+// TestEngine_Init_RecordsProviderField asserts that the provider name
+// supplied via EngineConfig propagates to the InitRequest payload. It
+// does NOT exercise any Codex-specific provider behavior — for that,
+// see TestEngine_SyncAll_CodexRoot_FirstChunk_EmitsCodexRolloutMeta and
+// related Codex dispatch tests at the bottom of this file.
+func TestEngine_Init_RecordsProviderField(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"session_meta","payload":{}}`+"\n"), 0644)
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		Provider:       "codex",
+		ExternalID:     "codex-external-id",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	if len(mock.initRequests) != 1 {
+		t.Fatalf("expected 1 init request, got %d", len(mock.initRequests))
+	}
+	if got := mock.initRequests[0].Provider; got != "codex" {
+		t.Fatalf("provider = %q, want codex", got)
+	}
+}
+
+func TestEngine_Init_ResumeSession(t *testing.T) {
+	mock := newMockBackend(t)
+	// Backend already has some lines synced
+	mock.initResponse.Files = map[string]FileState{
+		"transcript.jsonl": {LastSyncedLine: 5},
+	}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+
+	// Create transcript with more lines
+	content := ""
+	for i := 1; i <= 10; i++ {
+		content += fmt.Sprintf(`{"line":%d}`, i) + "\n"
+	}
+	os.WriteFile(transcriptPath, []byte(content), 0644)
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "resume-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	// Sync should only upload new lines
+	chunks, err := engine.SyncAll()
+	if err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+
+	if chunks != 1 {
+		t.Errorf("expected 1 chunk, got %d", chunks)
+	}
+
+	// Verify chunk starts at line 6
+	if len(mock.chunkRequests) != 1 {
+		t.Fatalf("expected 1 chunk request, got %d", len(mock.chunkRequests))
+	}
+
+	chunkReq := mock.chunkRequests[0]
+	if chunkReq.FirstLine != 6 {
+		t.Errorf("expected FirstLine 6, got %d", chunkReq.FirstLine)
+	}
+	if len(chunkReq.Lines) != 5 {
+		t.Errorf("expected 5 lines (6-10), got %d", len(chunkReq.Lines))
+	}
+}
+
+// TestEngine_PartialTailLine_UploadedOnceComplete verifies that a last
+// line caught mid-write (newline present, JSON truncated) is held back
+// while the lines before it upload, and is sent exactly once after the
+// writer completes it.
+func TestEngine_PartialTailLine_UploadedOnceComplete(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	complete := `{"type":"user","n":1}` + "\n" + `{"type":"assistant","n":2}` + "\n"
+	if err := os.WriteFile(transcriptPath, []byte(complete+`{"type":"assis`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "partial-tail",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("first SyncAll failed: %v", err)
+	}
+	if len(mock.chunkRequests) != 1 || len(mock.chunkRequests[0].Lines) != 2 {
+		t.Fatalf("first cycle chunks = %+v, want lines 1-2 only", mock.chunkRequests)
+	}
+
+	// The writer finishes the line (rewriting the file changes its size
+	// and mtime, as the append would).
+	tail := `{"type":"assistant","n":3}`
+	if err := os.WriteFile(transcriptPath, []byte(complete+tail+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(2 * time.Second)
+	os.Chtimes(transcriptPath, future, future)
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("second SyncAll failed: %v", err)
+	}
+
+	var uploaded []string
+	for _, req := range mock.chunkRequests {
+		uploaded = append(uploaded, req.Lines...)
+	}
+	if len(uploaded) != 3 || uploaded[2] != tail {
+		t.Fatalf("uploaded lines = %q, want the completed tail once as line 3", uploaded)
+	}
+	if got := mock.chunkRequests[len(mock.chunkRequests)-1].FirstLine; got != 3 {
+		t.Errorf("tail chunk FirstLine = %d, want 3", got)
+	}
+}
+
+// TestEngine_SyncAll_CompactedTranscriptReinits verifies that a transcript
+// rewritten shorter than what was synced (Claude Code compacting it) makes
+// the engine re-init and rescan rather than reading on from its stale byte
+// offset, and that later lines then upload contiguously.
+func TestEngine_SyncAll_CompactedTranscriptReinits(t *testing.T) {
+	var initCount int32
+	serverLastSyncedLine := 0
+	var uploaded []ChunkRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := readRequestBody(r)
+		switch r.URL.Path {
+		case "/api/v1/sync/init":
+			atomic.AddInt32(&initCount, 1)
+			json.NewEncoder(w).Encode(InitResponse{
+				SessionID: "test-session-id",
+				Files: map[string]FileState{
+					"transcript.jsonl": {LastSyncedLine: serverLastSyncedLine},
+				},
+			})
+		case "/api/v1/sync/chunk":
+			var req ChunkRequest
+			json.Unmarshal(body, &req)
+			if req.FirstLine != serverLastSyncedLine+1 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error": fmt.Sprintf("first_line must be %d (got %d) - chunks must be contiguous",
+						serverLastSyncedLine+1, req.FirstLine),
+				})
+				return
+			}
+			uploaded = append(uploaded, req)
+			serverLastSyncedLine = req.FirstLine + len(req.Lines) - 1
+			json.NewEncoder(w).Encode(ChunkResponse{LastSyncedLine: serverLastSyncedLine})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	lines := func(from, to int, pad string) string {
+		var b strings.Builder
+		for i := from; i <= to; i++ {
+			fmt.Fprintf(&b, `{"line":%d,"text":"%s"}`+"\n", i, pad)
+		}
+		return b.String()
+	}
+	long := strings.Repeat("x", 200)
+	os.WriteFile(transcriptPath, []byte(lines(1, 6, long)), 0644)
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "compaction-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("first SyncAll failed: %v", err)
+	}
+	if serverLastSyncedLine != 6 {
+		t.Fatalf("backend has %d lines, want 6", serverLastSyncedLine)
+	}
+
+	// Compaction rewrites the transcript to two short lines.
+	compacted := lines(1, 2, "summary")
+	os.WriteFile(transcriptPath, []byte(compacted), 0644)
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("SyncAll after compaction failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&initCount); got != 2 {
+		t.Fatalf("init calls = %d, want 2 (initial + re-init after shrink)", got)
+	}
+	if len(uploaded) != 1 {
+		t.Fatalf("chunks after compaction = %d, want none new", len(uploaded)-1)
+	}
+
+	// New lines grow the file past its pre-compaction size; they must be
+	// read from the rewritten file, numbered after the backend's line 6.
+	grown := compacted + lines(3, 8, long)
+	os.WriteFile(transcriptPath, []byte(grown), 0644)
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("SyncAll after growth failed: %v", err)
+	}
+	if len(uploaded) != 2 {
+		t.Fatalf("chunks = %d, want 2", len(uploaded))
+	}
+	last := uploaded[1]
+	want := strings.Split(strings.TrimSuffix(lines(7, 8, long), "\n"), "\n")
+	if last.FirstLine != 7 || strings.Join(last.Lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("post-compaction chunk = first_line %d lines %q, want first_line 7 lines %q", last.FirstLine, last.Lines, want)
+	}
+}
+
+// TestEngine_SyncAllFinal_UnterminatedTail verifies that SyncAll holds
+// back a last line without its newline, SyncAllFinal uploads it, and
+// HoldTailOnFinalSync keeps holding it even then.
+func TestEngine_SyncAllFinal_UnterminatedTail(t *testing.T) {
+	for _, hold := range []bool{false, true} {
+		t.Run(fmt.Sprintf("hold=%v", hold), func(t *testing.T) {
+			mock := newMockBackend(t)
+			server := httptest.NewServer(mock)
+			defer server.Close()
+
+			tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+			if err := os.WriteFile(transcriptPath, []byte(`{"n":1}`+"\n"+`{"n":2}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+			engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+				ExternalID:          "unterminated-tail",
+				TranscriptPath:      transcriptPath,
+				CWD:                 tmpDir,
+				HoldTailOnFinalSync: hold,
+			})
+			if err := engine.Init(); err != nil {
+				t.Fatalf("Init failed: %v", err)
+			}
+
+			if _, err := engine.SyncAll(); err != nil {
+				t.Fatalf("SyncAll failed: %v", err)
+			}
+			if len(mock.chunkRequests) != 1 || len(mock.chunkRequests[0].Lines) != 1 {
+				t.Fatalf("SyncAll chunks = %+v, want line 1 only", mock.chunkRequests)
+			}
+
+			if _, err := engine.SyncAllFinal(); err != nil {
+				t.Fatalf("SyncAllFinal failed: %v", err)
+			}
+			want := 2
+			if hold {
+				want = 1
+			}
+			if len(mock.chunkRequests) != want {
+				t.Fatalf("chunks after SyncAllFinal = %+v, want %d", mock.chunkRequests, want)
+			}
+			if !hold && mock.chunkRequests[1].FirstLine != 2 {
+				t.Errorf("final chunk FirstLine = %d, want 2", mock.chunkRequests[1].FirstLine)
+			}
+		})
+	}
+}
+
+// TestEngine_SeedOffsetHints_SkipsSyncedLines verifies that a restarted
+// engine seeded with persisted offsets resumes reading at the saved byte
+// offset instead of re-scanning a large transcript. The synced region is
+// corrupted in place (a newline turned into a space) so a full re-scan would
+// miscount lines and upload the wrong content.
+func TestEngine_SeedOffsetHints_SkipsSyncedLines(t *testing.T) {
+	const numLines = 100000
+
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	var sb strings.Builder
+	for i := 1; i <= numLines; i++ {
+		fmt.Fprintf(&sb, `{"n":%d}`+"\n", i)
+	}
+	os.WriteFile(transcriptPath, []byte(sb.String()), 0644)
+
+	newEngine := func() *Engine {
+		return newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+			ExternalID:     "offset-hints-test",
+			TranscriptPath: transcriptPath,
+			CWD:            tmpDir,
+		})
+	}
+
+	first := newEngine()
+	if err := first.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := first.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	hints := first.FileOffsets()
+	if got := hints["transcript.jsonl"]; got.LastSyncedLine != numLines || got.ByteOffset != int64(sb.Len()) {
+		t.Fatalf("FileOffsets() = %+v, want line %d at byte %d", got, numLines, sb.Len())
+	}
+
+	// Corrupt the synced region without changing its size, then append.
+	f, _ := os.OpenFile(transcriptPath, os.O_RDWR, 0644)
+	f.WriteAt([]byte(" "), int64(strings.IndexByte(sb.String(), '\n')))
+	f.Seek(0, io.SeekEnd)
+	f.WriteString(`{"n":"new"}` + "\n")
+	f.Close()
+
+	mock.initResponse.Files = map[string]FileState{"transcript.jsonl": {LastSyncedLine: numLines}}
+	mock.chunkRequests = nil
+
+	resumed := newEngine()
+	resumed.SeedOffsetHints(hints)
+	if err := resumed.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := resumed.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+
+	if len(mock.chunkRequests) != 1 {
+		t.Fatalf("expected 1 chunk request, got %d", len(mock.chunkRequests))
 	}
-	if decoded.SessionID != hookInput.SessionID ||
-		decoded.HookEventName != hookInput.HookEventName ||
-		decoded.Reason != hookInput.Reason {
-		t.Errorf("payload round-trip mismatch: got %+v, want %+v", decoded, hookInput)
+	chunk := mock.chunkRequests[0]
+	if chunk.FirstLine != numLines+1 {
+		t.Errorf("FirstLine = %d, want %d", chunk.FirstLine, numLines+1)
+	}
+	if len(chunk.Lines) != 1 || chunk.Lines[0] != `{"n":"new"}` {
+		t.Errorf("Lines = %q, want only the appended line", chunk.Lines)
 	}
 }
 
-// TestEngine_SendSessionEnd_NotInitialized verifies SendSessionEnd is a
-// no-op (no error, no backend call) when the engine isn't initialized.
-// Protects the early-return at engine.go:382.
-func TestEngine_SendSessionEnd_NotInitialized(t *testing.T) {
+// TestEngine_SyncAll_RemoteOnlyAgent verifies that an agent file the backend
+// knows about but which doesn't exist locally (synced from another machine)
+// is tracked as remote-only and doesn't produce a read error every cycle.
+func TestEngine_SyncAll_RemoteOnlyAgent(t *testing.T) {
 	mock := newMockBackend(t)
+	mock.initResponse.Files = map[string]FileState{
+		"transcript.jsonl":      {LastSyncedLine: 1},
+		"agent-elsewhere.jsonl": {LastSyncedLine: 40},
+	}
 	server := httptest.NewServer(mock)
 	defer server.Close()
+
 	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
 	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"), 0644)
-	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{ExternalID: "not-init", TranscriptPath: transcriptPath, CWD: tmpDir})
-	// No Init() call.
-	if err := engine.SendSessionEnd(&types.ClaudeHookInput{}, time.Now()); err != nil {
-		t.Errorf("SendSessionEnd on uninitialized engine returned %v, want nil (no-op)", err)
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "remote-only-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
 	}
-	if len(mock.eventRequests) != 0 {
-		t.Errorf("uninitialized engine made %d event requests, want 0", len(mock.eventRequests))
+
+	for i := 0; i < 3; i++ {
+		if _, err := engine.SyncAll(); err != nil {
+			t.Fatalf("SyncAll cycle %d returned read error for remote-only file: %v", i, err)
+		}
+	}
+
+	var agent *TrackedFile
+	for _, f := range engine.Tracker().GetTrackedFiles() {
+		if f.Name == "agent-elsewhere.jsonl" {
+			agent = f
+		}
+	}
+	if agent == nil {
+		t.Fatal("expected backend agent to remain tracked")
+	}
+	if !agent.RemoteOnly {
+		t.Error("expected agent to be tracked as remote-only")
+	}
+	if len(mock.chunkRequests) != 0 {
+		t.Errorf("expected no chunk uploads, got %d", len(mock.chunkRequests))
 	}
 }
 
-// TestEngine_Init_RecordsProviderField asserts that the provider name
-// supplied via EngineConfig propagates to the InitRequest payload. It
-// does NOT exercise any Codex-specific provider behavior — for that,
-// see TestEngine_SyncAll_CodexRoot_FirstChunk_EmitsCodexRolloutMeta and
-// related Codex dispatch tests at the bottom of this file.
-func TestEngine_Init_RecordsProviderField(t *testing.T) {
+// TestEngine_InitOverride_ReplaysFromLineOne verifies EngineConfig.InitOverride
+// replaces the backend's reported file state, so a fully-synced transcript is
+// re-uploaded from line 1 (confab replay), and OnChunkUploaded and
+// OnChunkStats see each chunk.
+func TestEngine_InitOverride_ReplaysFromLineOne(t *testing.T) {
 	mock := newMockBackend(t)
+	mock.initResponse.Files = map[string]FileState{
+		"transcript.jsonl": {LastSyncedLine: 2},
+	}
 	server := httptest.NewServer(mock)
 	defer server.Close()
 
 	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
-	os.WriteFile(transcriptPath, []byte(`{"type":"session_meta","payload":{}}`+"\n"), 0644)
+	os.WriteFile(transcriptPath, []byte(`{"n":1}`+"\n"+`{"n":2}`+"\n"), 0644)
 
+	var callbackLines int
+	var stats []ChunkStats
 	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
-		Provider:       "codex",
-		ExternalID:     "codex-external-id",
-		TranscriptPath: transcriptPath,
-		CWD:            tmpDir,
+		ExternalID:      "override-test",
+		TranscriptPath:  transcriptPath,
+		CWD:             tmpDir,
+		InitOverride:    &InitResponse{Files: map[string]FileState{}},
+		OnChunkUploaded: func(_ string, lines int) { callbackLines += lines },
+		OnChunkStats:    func(s ChunkStats) { stats = append(stats, s) },
 	})
-
 	if err := engine.Init(); err != nil {
 		t.Fatalf("Init failed: %v", err)
 	}
+	if engine.SessionID() != "test-session-id" {
+		t.Errorf("expected backend session ID to be kept, got %q", engine.SessionID())
+	}
 
-	if len(mock.initRequests) != 1 {
-		t.Fatalf("expected 1 init request, got %d", len(mock.initRequests))
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
 	}
-	if got := mock.initRequests[0].Provider; got != "codex" {
-		t.Fatalf("provider = %q, want codex", got)
+	if len(mock.chunkRequests) != 1 || mock.chunkRequests[0].FirstLine != 1 {
+		t.Fatalf("expected one chunk from line 1, got %+v", mock.chunkRequests)
+	}
+	if callbackLines != 2 {
+		t.Errorf("OnChunkUploaded saw %d lines, want 2", callbackLines)
+	}
+	want := ChunkStats{FileName: "transcript.jsonl", FileType: "transcript", Lines: 2, Bytes: 16}
+	if len(stats) != 1 || stats[0] != want {
+		t.Errorf("OnChunkStats saw %+v, want [%+v]", stats, want)
 	}
 }
 
-func TestEngine_Init_ResumeSession(t *testing.T) {
+// TestEngine_OnProgress checks the progress callback across a backfill
+// split into many chunks: linesSynced only grows, ends at the file's line
+// count, and the estimate is close to it throughout.
+func TestEngine_OnProgress(t *testing.T) {
 	mock := newMockBackend(t)
-	// Backend already has some lines synced
-	mock.initResponse.Files = map[string]FileState{
-		"transcript.jsonl": {LastSyncedLine: 5},
-	}
 	server := httptest.NewServer(mock)
 	defer server.Close()
 
 	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
-
-	// Create transcript with more lines
-	content := ""
-	for i := 1; i <= 10; i++ {
-		content += `{"line":` + string(rune('0'+i)) + `}` + "\n"
+	const totalLines = 200
+	var content strings.Builder
+	for i := 1; i <= totalLines; i++ {
+		fmt.Fprintf(&content, `{"type":"system","n":%d,"pad":"%s"}`+"\n", i, strings.Repeat("x", i%7))
 	}
-	os.WriteFile(transcriptPath, []byte(content), 0644)
+	os.WriteFile(transcriptPath, []byte(content.String()), 0644)
 
+	type progress struct{ synced, estimate int }
+	var got []progress
 	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
-		ExternalID:     "resume-test",
+		ExternalID:     "progress-test",
 		TranscriptPath: transcriptPath,
 		CWD:            tmpDir,
+		OnProgress: func(file string, linesSynced, totalLinesEstimate int) {
+			if file != "transcript.jsonl" {
+				t.Errorf("OnProgress file = %q, want transcript.jsonl", file)
+			}
+			got = append(got, progress{linesSynced, totalLinesEstimate})
+		},
 	})
-
+	// About ten lines per chunk.
+	engine.chunker = newAdaptiveChunker(time.Hour, 512, 512)
 	if err := engine.Init(); err != nil {
 		t.Fatalf("Init failed: %v", err)
 	}
-
-	// Sync should only upload new lines
-	chunks, err := engine.SyncAll()
-	if err != nil {
+	if _, err := engine.SyncAll(); err != nil {
 		t.Fatalf("SyncAll failed: %v", err)
 	}
 
-	if chunks != 1 {
-		t.Errorf("expected 1 chunk, got %d", chunks)
-	}
-
-	// Verify chunk starts at line 6
-	if len(mock.chunkRequests) != 1 {
-		t.Fatalf("expected 1 chunk request, got %d", len(mock.chunkRequests))
+	if len(got) < 10 || len(got) != len(mock.chunkRequests) {
+		t.Fatalf("OnProgress called %d times for %d chunks, want one per chunk and at least 10", len(got), len(mock.chunkRequests))
 	}
-
-	chunkReq := mock.chunkRequests[0]
-	if chunkReq.FirstLine != 6 {
-		t.Errorf("expected FirstLine 6, got %d", chunkReq.FirstLine)
+	for i, p := range got {
+		if i > 0 && p.synced <= got[i-1].synced {
+			t.Errorf("linesSynced went %d → %d, want it to grow", got[i-1].synced, p.synced)
+		}
+		if p.estimate < p.synced || p.estimate < totalLines*3/4 || p.estimate > totalLines*5/4 {
+			t.Errorf("estimate %d at line %d, want within 25%% of %d", p.estimate, p.synced, totalLines)
+		}
 	}
-	if len(chunkReq.Lines) != 5 {
-		t.Errorf("expected 5 lines (6-10), got %d", len(chunkReq.Lines))
+	if last := got[len(got)-1]; last != (progress{totalLines, totalLines}) {
+		t.Errorf("last progress = %+v, want %d of %d", last, totalLines, totalLines)
 	}
 }
 
@@ -768,7 +1653,7 @@ func TestEngine_GetSyncStats(t *testing.T) {
 	}
 
 	// Before sync
-	stats := engine.GetSyncStats()
+	stats := engine.GetSyncStats().FileLines
 	if stats["transcript.jsonl"] != 0 {
 		t.Errorf("expected 0 lines synced before sync, got %d", stats["transcript.jsonl"])
 	}
@@ -776,7 +1661,7 @@ func TestEngine_GetSyncStats(t *testing.T) {
 	// After sync
 	engine.SyncAll()
 
-	stats = engine.GetSyncStats()
+	stats = engine.GetSyncStats().FileLines
 	if stats["transcript.jsonl"] != 3 {
 		t.Errorf("expected 3 lines synced after sync, got %d", stats["transcript.jsonl"])
 	}
@@ -1047,6 +1932,160 @@ func TestEngine_SyncAll_MaxIterations(t *testing.T) {
 	}
 }
 
+// TestEngine_SyncAll_MaxAgentDepth verifies agents nested at MaxAgentDepth
+// or deeper are skipped, even though the subagents directory scan finds
+// them before the chain reaches them, and that synced agents carry their
+// depth in chunk metadata.
+func TestEngine_SyncAll_MaxAgentDepth(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	subagentsDir := filepath.Join(filepath.Dir(transcriptPath), "transcript", "subagents")
+	os.MkdirAll(subagentsDir, 0755)
+
+	// transcript -> agent-00000001 -> ... -> agent-00000006
+	os.WriteFile(transcriptPath, []byte(`{"type":"user","toolUseResult":{"agentId":"00000001","result":"done"}}`+"\n"), 0644)
+	for i := 1; i <= 6; i++ {
+		content := fmt.Sprintf(`{"type":"user","toolUseResult":{"agentId":"%08d","result":"done"}}`, i+1) + "\n"
+		os.WriteFile(filepath.Join(subagentsDir, fmt.Sprintf("agent-%08d.jsonl", i)), []byte(content), 0644)
+	}
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "max-agent-depth-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		MaxAgentDepth:  3,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	for range 2 { // the second pass must not pick up the skipped agents
+		if _, err := engine.SyncAll(); err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+	}
+
+	depths := make(map[string]int)
+	for _, req := range mock.chunkRequests {
+		if req.FileType != provider.FileTypeAgent {
+			continue
+		}
+		if req.Metadata == nil {
+			t.Fatalf("%s: chunk has no metadata", req.FileName)
+		}
+		depths[req.FileName] = req.Metadata.AgentDepth
+	}
+	want := map[string]int{"agent-00000001.jsonl": 1, "agent-00000002.jsonl": 2}
+	if !maps.Equal(depths, want) {
+		t.Errorf("synced agent depths = %v, want %v", depths, want)
+	}
+}
+
+// TestEngine_SyncAll_UnreferencedSiblingsAreDepthOne verifies running
+// subagents the directory scan finds before any line references them count
+// as depth 1, however many cycles they are found across, and don't send a
+// guessed depth.
+func TestEngine_SyncAll_UnreferencedSiblingsAreDepthOne(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	subagentsDir := filepath.Join(filepath.Dir(transcriptPath), "transcript", "subagents")
+	os.MkdirAll(subagentsDir, 0755)
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","message":"start"}`+"\n"), 0644)
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "sibling-depth-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	const siblings = 7 // more than DefaultMaxAgentDepth
+	for i := 1; i <= siblings; i++ {
+		os.WriteFile(filepath.Join(subagentsDir, fmt.Sprintf("agent-%08d.jsonl", i)), []byte(`{"type":"agent"}`+"\n"), 0644)
+		if _, err := engine.SyncAll(); err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+	}
+
+	synced := make(map[string]bool)
+	for _, req := range mock.chunkRequests {
+		if req.FileType != provider.FileTypeAgent {
+			continue
+		}
+		synced[req.FileName] = true
+		if req.Metadata != nil && req.Metadata.AgentDepth != 0 {
+			t.Errorf("%s: agent_depth = %d for an unreferenced agent, want it omitted", req.FileName, req.Metadata.AgentDepth)
+		}
+	}
+	if len(synced) != siblings {
+		t.Errorf("synced %d of %d sibling agents: %v", len(synced), siblings, synced)
+	}
+}
+
+// TestEngine_SyncAll_MaxTotalAgentFiles verifies agents past
+// MaxTotalAgentFiles are skipped, and stay skipped on later cycles.
+func TestEngine_SyncAll_MaxTotalAgentFiles(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	subagentsDir := filepath.Join(filepath.Dir(transcriptPath), "transcript", "subagents")
+	os.MkdirAll(subagentsDir, 0755)
+
+	var transcript string
+	for i := 1; i <= 5; i++ {
+		transcript += fmt.Sprintf(`{"type":"user","toolUseResult":{"agentId":"%08d","result":"done"}}`, i) + "\n"
+		os.WriteFile(filepath.Join(subagentsDir, fmt.Sprintf("agent-%08d.jsonl", i)), []byte(`{"type":"agent"}`+"\n"), 0644)
+	}
+	os.WriteFile(transcriptPath, []byte(transcript), 0644)
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:         "max-total-agents-test",
+		TranscriptPath:     transcriptPath,
+		CWD:                tmpDir,
+		MaxTotalAgentFiles: 3,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+
+	// New lines in every agent file: only the admitted ones sync again.
+	for i := 1; i <= 5; i++ {
+		f, _ := os.OpenFile(filepath.Join(subagentsDir, fmt.Sprintf("agent-%08d.jsonl", i)), os.O_APPEND|os.O_WRONLY, 0644)
+		f.WriteString(`{"type":"agent","more":true}` + "\n")
+		f.Close()
+	}
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+
+	uploads := make(map[string]int)
+	for _, req := range mock.chunkRequests {
+		if req.FileType == provider.FileTypeAgent {
+			uploads[req.FileName]++
+		}
+	}
+	if len(uploads) != 3 {
+		t.Fatalf("synced %d agent files (%v), want 3", len(uploads), uploads)
+	}
+	for name, n := range uploads {
+		if n != 2 {
+			t.Errorf("%s uploaded %d times, want 2", name, n)
+		}
+	}
+}
+
 // TestEngine_SyncAll_AgentFileAppearsLater tests that if an agent file doesn't
 // exist when first referenced, it can still be discovered on a later SyncAll call.
 func TestEngine_SyncAll_AgentFileAppearsLater(t *testing.T) {
@@ -1333,6 +2372,55 @@ func TestEngine_SyncAll_RefreshStateOnContiguityError(t *testing.T) {
 	}
 }
 
+// TestEngine_SyncAll_ChunkChecksum verifies each uploaded chunk carries the
+// SHA-256 of its lines, each followed by a newline, so the backend can
+// check the body it received.
+func TestEngine_SyncAll_ChunkChecksum(t *testing.T) {
+	var received []ChunkRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := readRequestBody(r)
+
+		switch r.URL.Path {
+		case "/api/v1/sync/init":
+			json.NewEncoder(w).Encode(InitResponse{SessionID: "test-session-id", Files: map[string]FileState{}})
+		case "/api/v1/sync/chunk":
+			var req ChunkRequest
+			json.Unmarshal(body, &req)
+			received = append(received, req)
+			json.NewEncoder(w).Encode(ChunkResponse{LastSyncedLine: req.FirstLine + len(req.Lines) - 1})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"line":1}`+"\n"+`{"line":2}`+"\n"), 0644)
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "checksum-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+
+	if len(received) == 0 {
+		t.Fatal("no chunk uploaded")
+	}
+	for _, req := range received {
+		sum := sha256.Sum256([]byte(strings.Join(req.Lines, "\n") + "\n"))
+		if want := hex.EncodeToString(sum[:]); req.Checksum != want {
+			t.Errorf("chunk at line %d: checksum = %q, want %q", req.FirstLine, req.Checksum, want)
+		}
+	}
+}
+
 // TestEngine_SyncAll_AuthErrorDuringRefreshPropagated tests that when refresh fails
 // with an auth error (e.g., token expired mid-sync), the auth error is propagated
 // so the daemon can handle it properly.
@@ -1555,6 +2643,77 @@ func TestEngine_SyncAll_DirScanDiscoversUnknownAgent(t *testing.T) {
 	}
 }
 
+// subtaskExtractor is an AgentExtractor for a framework that logs
+// {"subtask":"<id>"} lines and writes children to subtask-<id>.jsonl.
+type subtaskExtractor struct{}
+
+func (subtaskExtractor) ExtractChildIDs(line []byte) []string {
+	var msg struct {
+		Subtask string `json:"subtask"`
+	}
+	if json.Unmarshal(line, &msg) != nil || msg.Subtask == "" {
+		return nil
+	}
+	return []string{msg.Subtask}
+}
+
+func (subtaskExtractor) ChildFileName(id string) string {
+	return "subtask-" + id + ".jsonl"
+}
+
+func TestEngine_SyncAll_CustomAgentExtractor(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+
+	// One child referenced from the transcript, which itself references a
+	// grandchild; a Claude-style reference the custom extractor ignores.
+	os.WriteFile(transcriptPath, []byte(`{"type":"system","message":"start"}
+{"subtask":"child01"}
+{"type":"user","toolUseResult":{"agentId":"abc12345"}}
+`), 0644)
+
+	subagentsDir := filepath.Join(filepath.Dir(transcriptPath), "transcript", "subagents")
+	os.MkdirAll(subagentsDir, 0755)
+	write := func(name, content string) {
+		os.WriteFile(filepath.Join(subagentsDir, name), []byte(content), 0644)
+	}
+	write("subtask-child01.jsonl", `{"subtask":"grandchild01"}`+"\n")
+	write("subtask-grandchild01.jsonl", `{"message":"deep"}`+"\n")
+	// Only on disk: found by the directory scan, using the same naming.
+	write("subtask-orphan01.jsonl", `{"message":"orphan"}`+"\n")
+	// Claude's naming isn't this framework's, so neither file is synced.
+	write("agent-abc12345.jsonl", `{"message":"claude"}`+"\n")
+	write("notes.jsonl", `{"message":"unrelated"}`+"\n")
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "custom-extractor-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+		AgentExtractor: subtaskExtractor{},
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := engine.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+
+	var agents []string
+	for _, req := range mock.chunkRequests {
+		if req.FileType == "agent" {
+			agents = append(agents, req.FileName)
+		}
+	}
+	slices.Sort(agents)
+	want := []string{"subtask-child01.jsonl", "subtask-grandchild01.jsonl", "subtask-orphan01.jsonl"}
+	if !slices.Equal(agents, want) {
+		t.Errorf("uploaded agent files = %v, want %v", agents, want)
+	}
+}
+
 // TestEngine_SyncAll_MixedAgentIDFormats tests that a single transcript can
 // reference both legacy 8-char hex and new-format agent IDs, and all are
 // discovered and synced correctly.
@@ -1644,7 +2803,7 @@ func mustNewClient(t *testing.T, serverURL, tmpDir string) *Client {
 		APIKey:     "test-api-key-12345678",
 	}
 
-	client, err := NewClient(cfg)
+	client, err := NewClient(cfg, 0)
 	if err != nil {
 		t.Fatalf("failed to create client: %v", err)
 	}
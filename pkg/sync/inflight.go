@@ -0,0 +1,54 @@
+package sync
+
+import "sync"
+
+// inFlightLimiter is a byte-weighted semaphore bounding the total size of
+// chunk bodies held by uploads in progress. acquire blocks until the new
+// chunk fits under the limit; a chunk larger than the whole limit is let
+// through only when nothing else is in flight, so it can't deadlock.
+type inFlightLimiter struct {
+	max int64
+
+	mu    sync.Mutex
+	cond  *sync.Cond
+	bytes int64
+}
+
+func newInFlightLimiter(max int64) *inFlightLimiter {
+	l := &inFlightLimiter{max: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *inFlightLimiter) acquire(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.bytes > 0 && l.bytes+n > l.max {
+		l.cond.Wait()
+	}
+	l.bytes += n
+}
+
+func (l *inFlightLimiter) release(n int64) {
+	l.mu.Lock()
+	l.bytes -= n
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// inFlight returns the bytes currently held.
+func (l *inFlightLimiter) inFlight() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.bytes
+}
+
+// chunkBodySize approximates a chunk's in-memory request size as the sum of
+// its line lengths, which dominates the JSON envelope.
+func chunkBodySize(lines []string) int64 {
+	var n int64
+	for _, l := range lines {
+		n += int64(len(l))
+	}
+	return n
+}
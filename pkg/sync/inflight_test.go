@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+)
+
+func TestClient_UploadChunk_MaxInFlightBytes(t *testing.T) {
+	const chunkBytes = 100
+	const limit = 250 // room for two chunks, not three
+
+	var client *Client
+	var current, peakConcurrent atomic.Int64
+	var peakBytes atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peakConcurrent.Load()
+			if n <= p || peakConcurrent.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		if b := client.inFlight.inFlight(); b > peakBytes.Load() {
+			peakBytes.Store(b)
+		}
+		time.Sleep(20 * time.Millisecond)
+		json.NewEncoder(w).Encode(ChunkResponse{LastSyncedLine: 1})
+	}))
+	defer server.Close()
+
+	cfg := &config.UploadConfig{BackendURL: server.URL, APIKey: "test-key", MaxInFlightBytes: limit}
+	var err error
+	if client, err = NewClient(cfg, 0); err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	line := strings.Repeat("x", chunkBytes)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.UploadChunk("s", "f.jsonl", "transcript", 1, []string{line}, nil); err != nil {
+				t.Errorf("UploadChunk: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peakBytes.Load(); got > limit {
+		t.Errorf("peak in-flight bytes = %d, want <= %d", got, limit)
+	}
+	if got := peakConcurrent.Load(); got > limit/chunkBytes {
+		t.Errorf("peak concurrent uploads = %d, want <= %d", got, limit/chunkBytes)
+	}
+	if got := client.inFlight.inFlight(); got != 0 {
+		t.Errorf("in-flight bytes after all uploads = %d, want 0", got)
+	}
+}
+
+func TestInFlightLimiter_OversizedChunkRunsAlone(t *testing.T) {
+	l := newInFlightLimiter(10)
+	l.acquire(5)
+
+	acquired := make(chan struct{})
+	go func() {
+		l.acquire(50)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("oversized chunk acquired while another chunk was in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	l.release(5)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("oversized chunk never acquired once the limiter was empty")
+	}
+	if got := l.inFlight(); got != 50 {
+		t.Errorf("inFlight = %d, want 50", got)
+	}
+}
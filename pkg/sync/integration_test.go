@@ -11,6 +11,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
 )
@@ -529,7 +530,7 @@ func TestEngine_RetryAfterReset(t *testing.T) {
 		BackendURL: server.URL,
 		APIKey:     "test-api-key-12345678",
 	}
-	client, _ := NewClient(cfg)
+	client, _ := NewClient(cfg, 0)
 
 	engine := newEngineWithBackend(t, client, nil, EngineConfig{
 		ExternalID:     "retry-test",
@@ -554,3 +555,252 @@ func TestEngine_RetryAfterReset(t *testing.T) {
 		t.Error("Expected engine to be initialized after retry")
 	}
 }
+
+// slowBackend is an in-process Backend whose uploads take a fixed delay and
+// which records peak upload concurrency.
+type slowBackend struct {
+	delay   time.Duration
+	files   map[string]FileState
+	current atomic.Int32
+	peak    atomic.Int32
+}
+
+func (b *slowBackend) Init(string, string, string, *InitMetadata, *InitOptions) (*InitResponse, error) {
+	return &InitResponse{SessionID: "slow-session", Files: b.files}, nil
+}
+
+func (b *slowBackend) UploadChunk(_, _, _ string, firstLine int, lines []string, _ *ChunkMetadata) (int, error) {
+	n := b.current.Add(1)
+	defer b.current.Add(-1)
+	for {
+		p := b.peak.Load()
+		if n <= p || b.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	time.Sleep(b.delay)
+	return firstLine + len(lines) - 1, nil
+}
+
+func (b *slowBackend) SendEvent(string, string, time.Time, json.RawMessage) error { return nil }
+
+func (b *slowBackend) UpdateSessionSummary(string, string) error { return nil }
+
+func (b *slowBackend) Capabilities() (Capabilities, error) { return Capabilities{}, nil }
+
+// TestEngine_ConcurrentAgentUploads syncs a transcript plus 5 agent files
+// sequentially and with MaxConcurrentUploads=4, checking agents actually
+// overlap and the concurrent run is clearly faster.
+func TestEngine_ConcurrentAgentUploads(t *testing.T) {
+	const delay = 100 * time.Millisecond
+
+	run := func(maxConcurrent int) (time.Duration, int32) {
+		tmpDir := t.TempDir()
+		transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
+		os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"), 0644)
+		subagentsDir := filepath.Join(tmpDir, "transcript", "subagents")
+		os.MkdirAll(subagentsDir, 0755)
+
+		files := map[string]FileState{}
+		for i := 0; i < 5; i++ {
+			name := fmt.Sprintf("agent-%08d.jsonl", i)
+			os.WriteFile(filepath.Join(subagentsDir, name), []byte(`{"agent":true}`+"\n"), 0644)
+			files[name] = FileState{}
+		}
+
+		backend := &slowBackend{delay: delay, files: files}
+		engine := newEngineWithBackend(t, backend, nil, EngineConfig{
+			ExternalID:           "concurrent-test",
+			TranscriptPath:       transcriptPath,
+			CWD:                  tmpDir,
+			MaxConcurrentUploads: maxConcurrent,
+		})
+		if err := engine.Init(); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+
+		start := time.Now()
+		chunks, err := engine.SyncAll()
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+		if chunks != 6 {
+			t.Fatalf("expected 6 chunks (transcript + 5 agents), got %d", chunks)
+		}
+		for name, lines := range engine.GetSyncStats().FileLines {
+			if lines != 1 {
+				t.Errorf("%s: last synced line = %d, want 1", name, lines)
+			}
+		}
+		return elapsed, backend.peak.Load()
+	}
+
+	sequential, seqPeak := run(1)
+	concurrent, concPeak := run(4)
+
+	if seqPeak != 1 {
+		t.Errorf("sequential run peaked at %d concurrent uploads, want 1", seqPeak)
+	}
+	if concPeak < 2 || concPeak > 4 {
+		t.Errorf("concurrent run peaked at %d concurrent uploads, want 2-4", concPeak)
+	}
+	if concurrent >= 2*sequential {
+		t.Errorf("concurrent sync took %v, want < 2x sequential (%v)", concurrent, sequential)
+	}
+	if concurrent >= sequential*3/4 {
+		t.Errorf("concurrent sync took %v, expected a clear speedup over sequential (%v)", concurrent, sequential)
+	}
+}
+
+// failingAgentBackend is a slowBackend that records each file's last synced
+// line once an upload completes, reports it from Init, and fails uploads of
+// one file straight away.
+type failingAgentBackend struct {
+	*slowBackend
+	failFile string
+	mu       sync.Mutex
+	synced   map[string]FileState
+	inits    int
+}
+
+func (b *failingAgentBackend) Init(string, string, string, *InitMetadata, *InitOptions) (*InitResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inits++
+	files := make(map[string]FileState, len(b.synced))
+	for name, state := range b.synced {
+		files[name] = state
+	}
+	return &InitResponse{SessionID: "slow-session", Files: files}, nil
+}
+
+func (b *failingAgentBackend) UploadChunk(sessionID, fileName, fileType string, firstLine int, lines []string, metadata *ChunkMetadata) (int, error) {
+	if fileName == b.failFile {
+		return 0, fmt.Errorf("upload of %s failed", fileName)
+	}
+	last, err := b.slowBackend.UploadChunk(sessionID, fileName, fileType, firstLine, lines, metadata)
+	b.mu.Lock()
+	b.synced[fileName] = FileState{LastSyncedLine: last}
+	b.mu.Unlock()
+	return last, err
+}
+
+// TestEngine_ConcurrentUploadFailureKeepsOtherProgress fails one agent's
+// upload while the other agents' uploads are still in flight: the refresh
+// from backend state that follows must not drop their progress, and runs
+// once for the cycle.
+func TestEngine_ConcurrentUploadFailureKeepsOtherProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"), 0644)
+	subagentsDir := filepath.Join(tmpDir, "transcript", "subagents")
+	os.MkdirAll(subagentsDir, 0755)
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("agent-%08d.jsonl", i)
+		os.WriteFile(filepath.Join(subagentsDir, name), []byte(`{"agent":true}`+"\n"+`{"agent":true}`+"\n"), 0644)
+	}
+
+	backend := &failingAgentBackend{
+		slowBackend: &slowBackend{delay: 100 * time.Millisecond},
+		failFile:    "agent-00000000.jsonl",
+		synced:      map[string]FileState{},
+	}
+	for i := 0; i < 4; i++ {
+		backend.synced[fmt.Sprintf("agent-%08d.jsonl", i)] = FileState{}
+	}
+	engine := newEngineWithBackend(t, backend, nil, EngineConfig{
+		ExternalID:           "concurrent-failure-test",
+		TranscriptPath:       transcriptPath,
+		CWD:                  tmpDir,
+		MaxConcurrentUploads: 4,
+		InitDedupWindow:      time.Nanosecond, // the refresh always replaces the tracked files
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	if _, err := engine.SyncAll(); err == nil {
+		t.Fatal("SyncAll succeeded with a failing upload")
+	}
+	if backend.inits != 2 {
+		t.Errorf("init calls = %d, want Init plus one refresh", backend.inits)
+	}
+	want := map[string]int{
+		"transcript.jsonl":     1,
+		"agent-00000000.jsonl": 0,
+		"agent-00000001.jsonl": 2,
+		"agent-00000002.jsonl": 2,
+		"agent-00000003.jsonl": 2,
+	}
+	got := engine.GetSyncStats().FileLines
+	for name, lines := range want {
+		if got[name] != lines {
+			t.Errorf("%s: last synced line = %d, want %d", name, got[name], lines)
+		}
+	}
+}
+
+// uploadCountingBackend is a slowBackend that counts uploads per file.
+type uploadCountingBackend struct {
+	*slowBackend
+	mu      sync.Mutex
+	uploads map[string]int
+}
+
+func (b *uploadCountingBackend) UploadChunk(sessionID, fileName, fileType string, firstLine int, lines []string, metadata *ChunkMetadata) (int, error) {
+	b.mu.Lock()
+	b.uploads[fileName]++
+	b.mu.Unlock()
+	return b.slowBackend.UploadChunk(sessionID, fileName, fileType, firstLine, lines, metadata)
+}
+
+// TestEngine_OverlappingSyncAllUploadsEachFileOnce runs two SyncAll calls
+// at once, as when a slow cycle overruns the daemon's interval: the agent
+// files both discover must each be uploaded exactly once.
+func TestEngine_OverlappingSyncAllUploadsEachFileOnce(t *testing.T) {
+	tmpDir := t.TempDir()
+	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"), 0644)
+	subagentsDir := filepath.Join(tmpDir, "transcript", "subagents")
+	os.MkdirAll(subagentsDir, 0755)
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("agent-%08d.jsonl", i)
+		os.WriteFile(filepath.Join(subagentsDir, name), []byte(`{"agent":true}`+"\n"), 0644)
+	}
+
+	backend := &uploadCountingBackend{
+		slowBackend: &slowBackend{delay: 50 * time.Millisecond, files: map[string]FileState{}},
+		uploads:     map[string]int{},
+	}
+	engine := newEngineWithBackend(t, backend, nil, EngineConfig{
+		ExternalID:           "overlap-test",
+		TranscriptPath:       transcriptPath,
+		CWD:                  tmpDir,
+		MaxConcurrentUploads: 4,
+	})
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := engine.SyncAll(); err != nil {
+				t.Errorf("SyncAll failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(backend.uploads) != 6 {
+		t.Errorf("uploaded %d files, want transcript + 5 agents: %v", len(backend.uploads), backend.uploads)
+	}
+	for name, n := range backend.uploads {
+		if n != 1 {
+			t.Errorf("%s uploaded %d times, want 1", name, n)
+		}
+	}
+}
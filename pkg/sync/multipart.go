@@ -0,0 +1,257 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"sync"
+
+	"github.com/ConfabulousDev/confab/pkg/http"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+// DefaultUploadPartSize is the part size for multipart chunk uploads when
+// MultipartUploadConfig.PartSize and the upload config's upload_part_size
+// are unset.
+const DefaultUploadPartSize = 1024 * 1024
+
+// MultipartUploadConfig enables multipart chunk uploads: a chunk whose
+// (compressed) request body is larger than PartSize is sent as a series
+// of byte ranges under one upload ID, so an upload interrupted partway
+// resumes from the first part the backend is missing.
+type MultipartUploadConfig struct {
+	Enabled bool
+	// PartSize is the size of each part in bytes. 0 uses the upload
+	// config's upload_part_size, or DefaultUploadPartSize.
+	PartSize int
+}
+
+// PendingUpload is a multipart chunk upload the backend has started but
+// not yet completed. The daemon persists these (Engine.PendingUploads) so
+// a restarted engine resumes them instead of re-sending every part.
+type PendingUpload struct {
+	UploadID string `json:"upload_id"`
+	// Digest is the SHA-256 of the encoded chunk body, so a chunk re-read
+	// with different content starts a new upload instead of resuming.
+	Digest   string `json:"digest"`
+	Size     int    `json:"size"`
+	PartSize int    `json:"part_size"`
+}
+
+// MultipartStartRequest is the body of POST /api/v1/sync/chunk/start. The
+// parts together form a ChunkRequest body, encoded as ContentEncoding.
+type MultipartStartRequest struct {
+	SessionID       string `json:"session_id"`
+	FileName        string `json:"file_name"`
+	FileType        string `json:"file_type"`
+	FirstLine       int    `json:"first_line"`
+	TotalSize       int    `json:"total_size"`
+	PartSize        int    `json:"part_size"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+}
+
+// MultipartStartResponse is the response to POST /api/v1/sync/chunk/start.
+type MultipartStartResponse struct {
+	UploadID string `json:"upload_id"`
+}
+
+// MultipartStatusResponse is the response to GET
+// /api/v1/sync/chunk/status: the indexes of the parts received so far.
+type MultipartStatusResponse struct {
+	ReceivedParts []int `json:"received_parts"`
+}
+
+// MultipartPartResponse is the response to a part upload. Complete is set
+// once the backend has every part and has stored the chunk, with
+// LastSyncedLine as for a single-part upload.
+type MultipartPartResponse struct {
+	Complete       bool `json:"complete"`
+	LastSyncedLine int  `json:"last_synced_line"`
+}
+
+// multipartUploads is a client's multipart state: the part size, whether
+// the backend turned out not to support multipart uploads, and the
+// uploads in progress keyed by ChunkIdempotencyKey.
+type multipartUploads struct {
+	partSize int
+
+	mu          sync.Mutex
+	unsupported bool
+	pending     map[string]PendingUpload
+}
+
+func newMultipartUploads(partSize int) *multipartUploads {
+	if partSize <= 0 {
+		partSize = DefaultUploadPartSize
+	}
+	return &multipartUploads{partSize: partSize, pending: make(map[string]PendingUpload)}
+}
+
+// applies reports whether a body of size bytes should go multipart.
+func (m *multipartUploads) applies(size int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.unsupported && size > m.partSize
+}
+
+func (m *multipartUploads) get(key string) (PendingUpload, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pending[key]
+	return p, ok
+}
+
+func (m *multipartUploads) set(key string, p PendingUpload) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending[key] = p
+}
+
+func (m *multipartUploads) remove(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, key)
+}
+
+// PendingUploads returns a copy of the client's unfinished multipart
+// uploads, for persisting across restarts. Nil when multipart uploads are
+// off.
+func (c *Client) PendingUploads() map[string]PendingUpload {
+	if c.multipart == nil {
+		return nil
+	}
+	c.multipart.mu.Lock()
+	defer c.multipart.mu.Unlock()
+	return maps.Clone(c.multipart.pending)
+}
+
+// SeedPendingUploads restores uploads persisted by an earlier client (see
+// PendingUploads). A no-op when multipart uploads are off.
+func (c *Client) SeedPendingUploads(pending map[string]PendingUpload) {
+	if c.multipart == nil {
+		return
+	}
+	c.multipart.mu.Lock()
+	defer c.multipart.mu.Unlock()
+	maps.Copy(c.multipart.pending, pending)
+}
+
+// errMultipartUnsupported means the backend answered the start request
+// with 404; the chunk goes single-part instead.
+var errMultipartUnsupported = errors.New("backend does not support multipart uploads")
+
+// uploadMultipart sends the encoded ChunkRequest body in parts and returns
+// the backend's last synced line. An upload already pending under key for
+// the same body is resumed from the first part the backend is missing;
+// otherwise a new one is started. Returns errMultipartUnsupported when
+// the backend has no multipart endpoints.
+func (c *Client) uploadMultipart(key string, req *ChunkRequest, body []byte, contentEncoding string) (int, error) {
+	sum := sha256.Sum256(body)
+	digest := hex.EncodeToString(sum[:])
+
+	var received map[int]bool
+	upload, ok := c.multipart.get(key)
+	if ok && upload.Digest == digest && upload.Size == len(body) {
+		status, err := c.multipartStatus(upload.UploadID)
+		switch {
+		case err == nil:
+			received = make(map[int]bool, len(status.ReceivedParts))
+			for _, i := range status.ReceivedParts {
+				received[i] = true
+			}
+			logger.Info("Resuming multipart chunk upload",
+				"component", "sync", "file", req.FileName, "upload_id", upload.UploadID,
+				"received_parts", len(received))
+		case errors.Is(err, http.ErrSessionNotFound):
+			ok = false // expired or unknown upload: start over
+		default:
+			return 0, err
+		}
+	} else {
+		ok = false
+	}
+
+	if !ok {
+		start := MultipartStartRequest{
+			SessionID:       req.SessionID,
+			FileName:        req.FileName,
+			FileType:        req.FileType,
+			FirstLine:       req.FirstLine,
+			TotalSize:       len(body),
+			PartSize:        c.multipart.partSize,
+			ContentEncoding: contentEncoding,
+		}
+		var resp MultipartStartResponse
+		err := c.do(c.withRetries(func() error {
+			return c.httpClient.PostWithHeaders("/api/v1/sync/chunk/start", map[string]string{IdempotencyKeyHeader: key}, start, &resp, nil)
+		}))
+		if isNotFound(err) {
+			c.multipart.mu.Lock()
+			c.multipart.unsupported = true
+			c.multipart.mu.Unlock()
+			return 0, errMultipartUnsupported
+		}
+		if err != nil {
+			return 0, fmt.Errorf("multipart upload start failed: %w", err)
+		}
+		upload = PendingUpload{UploadID: resp.UploadID, Digest: digest, Size: len(body), PartSize: c.multipart.partSize}
+		c.multipart.set(key, upload)
+	}
+
+	parts := (len(body) + upload.PartSize - 1) / upload.PartSize
+	var resp *MultipartPartResponse
+	for i := 0; i < parts; i++ {
+		// Once every part is with the backend the last one is sent again
+		// regardless, since the response that completed the upload may
+		// have been lost.
+		if received[i] && (i < parts-1 || resp != nil) {
+			continue
+		}
+		from := i * upload.PartSize
+		to := min(from+upload.PartSize, len(body))
+		var err error
+		if resp, err = c.uploadPart(upload.UploadID, body[from:to], from); err != nil {
+			return 0, fmt.Errorf("multipart upload part %d/%d failed: %w", i+1, parts, err)
+		}
+		if resp.Complete {
+			c.multipart.remove(key)
+			return resp.LastSyncedLine, nil
+		}
+	}
+	return 0, fmt.Errorf("multipart upload %s incomplete after all %d parts", upload.UploadID, parts)
+}
+
+// uploadPart sends one part, starting at byte from of the upload's body,
+// with a Range header giving its (inclusive) byte range.
+func (c *Client) uploadPart(uploadID string, part []byte, from int) (*MultipartPartResponse, error) {
+	headers := map[string]string{"Range": fmt.Sprintf("bytes=%d-%d", from, from+len(part)-1)}
+	var resp MultipartPartResponse
+	err := c.do(c.withRetries(func() error {
+		return c.httpClient.PostBytes("/api/v1/sync/chunk/part?upload_id="+url.QueryEscape(uploadID), headers, part, &resp, c.throttleBody)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// multipartStatus asks the backend which parts of an upload it has.
+func (c *Client) multipartStatus(uploadID string) (*MultipartStatusResponse, error) {
+	var resp MultipartStatusResponse
+	err := c.do(c.withRetries(func() error {
+		return c.httpClient.Get("/api/v1/sync/chunk/status?upload_id="+url.QueryEscape(uploadID), &resp)
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("multipart upload status failed: %w", err)
+	}
+	return &resp, nil
+}
+
+// isNotFound reports whether err is a 404 response.
+func isNotFound(err error) bool {
+	var statusErr *http.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == 404
+}
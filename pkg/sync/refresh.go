@@ -0,0 +1,130 @@
+package sync
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+// TokenRefreshPath is the backend endpoint that exchanges a refresh token
+// for a new access token.
+const TokenRefreshPath = "/auth/token/refresh"
+
+// TokenRefreshRequest is the request body for POST /auth/token/refresh
+type TokenRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse is the response for POST /auth/token/refresh. RefreshToken
+// is empty when the backend doesn't rotate refresh tokens; ExpiresIn is in
+// seconds (0 = no expiry).
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+}
+
+// errNoRefreshToken is returned when a refresh is needed but no refresh
+// token is known.
+var errNoRefreshToken = errors.New("no refresh token")
+
+// refreshAfterUnauthorized is called when a request sent with usedKey was
+// rejected as unauthorized. It reports whether the client now holds a
+// different token worth retrying with: one another request (or another
+// process sharing the config) already refreshed, or a freshly refreshed one.
+func (c *Client) refreshAfterUnauthorized(usedKey string) bool {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.refreshToken == "" {
+		return false
+	}
+	if c.httpClient.APIKey() != usedKey || c.adoptConfigTokenLocked(usedKey) {
+		return true
+	}
+	if err := c.refreshLocked(); err != nil {
+		logger.Warnf("Token refresh after unauthorized response failed: %v", err)
+		return false
+	}
+	return true
+}
+
+// RefreshTokenIfExpiring refreshes the access token when it expires within
+// the given window, reporting whether a refresh happened. A no-op for
+// clients without a refresh token or a known expiry.
+func (c *Client) RefreshTokenIfExpiring(within time.Duration) (bool, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.refreshToken == "" || c.expiresAt.IsZero() || time.Until(c.expiresAt) > within {
+		return false, nil
+	}
+	if c.adoptConfigTokenLocked(c.httpClient.APIKey()) && time.Until(c.expiresAt) > within {
+		return false, nil
+	}
+	if err := c.refreshLocked(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// adoptConfigTokenLocked re-reads the client's credentials from config and
+// switches to them if the stored access token differs from currentKey.
+// Daemons for different sessions share credentials, so one of them may
+// already have refreshed (and rotated the refresh token). Caller holds
+// tokenMu.
+func (c *Client) adoptConfigTokenLocked(currentKey string) bool {
+	cfg, err := config.GetUploadConfigFor(c.binding)
+	if err != nil {
+		logger.Debugf("Token refresh: could not re-read config: %v", err)
+		return false
+	}
+	if cfg.RefreshToken != "" {
+		c.refreshToken = cfg.RefreshToken
+	}
+	if cfg.APIKey == "" || cfg.APIKey == currentKey {
+		return false
+	}
+	logger.Info("Using access token refreshed by another process")
+	c.httpClient.SetAPIKey(cfg.APIKey)
+	c.expiresAt = cfg.ExpiresAt
+	return true
+}
+
+// refreshLocked exchanges the refresh token for a new access token, starts
+// using it, and saves it to config. Caller holds tokenMu.
+func (c *Client) refreshLocked() error {
+	if c.refreshToken == "" {
+		return errNoRefreshToken
+	}
+	var resp TokenResponse
+	if err := c.httpClient.Post(TokenRefreshPath, TokenRefreshRequest{RefreshToken: c.refreshToken}, &resp); err != nil {
+		return fmt.Errorf("token refresh failed: %w", err)
+	}
+	if resp.AccessToken == "" {
+		return fmt.Errorf("token refresh failed: response has no access_token")
+	}
+
+	var expiresAt time.Time
+	if resp.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	c.httpClient.SetAPIKey(resp.AccessToken)
+	c.refreshToken = cmp.Or(resp.RefreshToken, c.refreshToken)
+	c.expiresAt = expiresAt
+	logger.Info("Refreshed access token", "expires_at", formatExpiry(expiresAt))
+
+	if err := config.SetBindingToken(c.binding, resp.AccessToken, c.refreshToken, expiresAt); err != nil {
+		logger.Warnf("Failed to save refreshed access token: %v", err)
+	}
+	return nil
+}
+
+func formatExpiry(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,176 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	confabhttp "github.com/ConfabulousDev/confab/pkg/http"
+)
+
+const (
+	oldAccessToken = "cfb_old-access-token-1234"
+	newAccessToken = "cfb_new-access-token-5678"
+)
+
+// tokenBackend accepts sync events only with its current access token and
+// hands out new ones at TokenRefreshPath.
+type tokenBackend struct {
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	refreshCalls int
+}
+
+func (b *tokenBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch r.URL.Path {
+	case TokenRefreshPath:
+		b.refreshCalls++
+		var req TokenRefreshRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.RefreshToken != b.refreshToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b.accessToken = newAccessToken
+		b.refreshToken = "refresh-2"
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: newAccessToken, RefreshToken: "refresh-2", ExpiresIn: 3600})
+	case "/api/v1/sync/event":
+		if r.Header.Get("Authorization") != "Bearer "+b.accessToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(EventResponse{Success: true})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// setupTokenConfig writes a config holding an expired access token and a
+// refresh token, and returns it as loaded.
+func setupTokenConfig(t *testing.T, serverURL string, expiresAt time.Time) *config.UploadConfig {
+	t.Helper()
+	t.Setenv("CONFAB_CONFIG_PATH", filepath.Join(t.TempDir(), "config.json"))
+	if err := config.SaveUploadConfig(&config.UploadConfig{
+		BackendURL:   serverURL,
+		APIKey:       oldAccessToken,
+		RefreshToken: "refresh-1",
+		ExpiresAt:    expiresAt,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.GetUploadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestClient_RefreshesTokenOnUnauthorized(t *testing.T) {
+	backend := &tokenBackend{accessToken: "cfb_server-side-token-0000", refreshToken: "refresh-1"}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client, err := NewClient(setupTokenConfig(t, server.URL, time.Now().Add(-time.Minute)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.SendEvent("s", "session_end", time.Now(), nil); err != nil {
+				t.Errorf("SendEvent: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if backend.refreshCalls != 1 {
+		t.Errorf("refresh calls = %d, want 1 for concurrent 401s", backend.refreshCalls)
+	}
+	saved, err := config.GetUploadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.APIKey != newAccessToken || saved.RefreshToken != "refresh-2" {
+		t.Errorf("saved tokens = %q/%q, want refreshed ones", saved.APIKey, saved.RefreshToken)
+	}
+	if until := time.Until(saved.ExpiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("saved expires_at is %v from now, want ~1h", until)
+	}
+}
+
+func TestClient_AdoptsTokenRefreshedByAnotherProcess(t *testing.T) {
+	backend := &tokenBackend{accessToken: newAccessToken, refreshToken: "refresh-2"}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client, err := NewClient(setupTokenConfig(t, server.URL, time.Now().Add(-time.Minute)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another daemon refreshed and rotated the refresh token meanwhile.
+	if err := config.SetBindingToken(config.Binding{IsDefault: true}, newAccessToken, "refresh-2", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.SendEvent("s", "session_end", time.Now(), nil); err != nil {
+		t.Fatalf("SendEvent: %v", err)
+	}
+	if backend.refreshCalls != 0 {
+		t.Errorf("refresh calls = %d, want 0 when config already holds a new token", backend.refreshCalls)
+	}
+}
+
+func TestClient_UnauthorizedWithoutRefreshToken(t *testing.T) {
+	backend := &tokenBackend{accessToken: "cfb_server-side-token-0000"}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client := mustNewTestClient(t, server.URL)
+	err := client.SendEvent("s", "session_end", time.Now(), nil)
+	if !errors.Is(err, confabhttp.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	if backend.refreshCalls != 0 {
+		t.Errorf("refresh calls = %d, want 0 for a plain API key", backend.refreshCalls)
+	}
+}
+
+func TestClient_RefreshTokenIfExpiring(t *testing.T) {
+	backend := &tokenBackend{accessToken: oldAccessToken, refreshToken: "refresh-1"}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client, err := NewClient(setupTokenConfig(t, server.URL, time.Now().Add(time.Hour)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed, err := client.RefreshTokenIfExpiring(5 * time.Minute); err != nil || refreshed {
+		t.Fatalf("token expiring in 1h: refreshed=%v err=%v, want no refresh", refreshed, err)
+	}
+
+	client.expiresAt = time.Now().Add(2 * time.Minute)
+	refreshed, err := client.RefreshTokenIfExpiring(5 * time.Minute)
+	if err != nil || !refreshed {
+		t.Fatalf("token expiring in 2m: refreshed=%v err=%v, want refresh", refreshed, err)
+	}
+	if got := client.httpClient.APIKey(); !strings.HasPrefix(got, "cfb_new") {
+		t.Errorf("client token after refresh = %q", got)
+	}
+	if err := client.SendEvent("s", "session_end", time.Now(), nil); err != nil {
+		t.Errorf("SendEvent with refreshed token: %v", err)
+	}
+}
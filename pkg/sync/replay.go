@@ -0,0 +1,101 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+)
+
+// ReplayRange re-uploads lines fromLine..toLine (1-based, inclusive) of one
+// tracked file, regardless of the backend's reported LastSyncedLine. An
+// empty fileName means the transcript; toLine <= 0 means through EOF. It
+// reads with ReadChunk from an explicit start line on a private copy of the
+// tracked file, so normal sync state is left untouched. Returns the number
+// of lines re-sent. Must be called after Init.
+func (e *Engine) ReplayRange(fileName string, fromLine, toLine int) (int, error) {
+	if !e.initialized {
+		return 0, fmt.Errorf("engine not initialized")
+	}
+	if fromLine < 1 {
+		return 0, fmt.Errorf("from line must be at least 1, got %d", fromLine)
+	}
+	if toLine > 0 && toLine < fromLine {
+		return 0, fmt.Errorf("to line %d is before from line %d", toLine, fromLine)
+	}
+
+	file, err := e.trackedFile(fileName)
+	if err != nil {
+		return 0, err
+	}
+
+	total, err := CountLines(file.Path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count lines in %s: %w", file.Name, err)
+	}
+	if fromLine > total {
+		return 0, fmt.Errorf("from line %d is beyond end of %s (%d lines)", fromLine, file.Name, total)
+	}
+	if toLine <= 0 || toLine > total {
+		toLine = total
+	}
+
+	cursor := &TrackedFile{
+		Path:           file.Path,
+		Name:           file.Name,
+		Type:           file.Type,
+		LastSyncedLine: fromLine - 1,
+	}
+	if info, err := os.Stat(file.Path); err == nil {
+		cursor.LastSize = info.Size() // for OnProgress's estimate
+	}
+	sent := 0
+	for cursor.LastSyncedLine < toLine {
+		chunk, err := e.tracker.ReadChunk(cursor, e.redactor, DefaultMaxChunkBytes)
+		if err != nil {
+			return sent, fmt.Errorf("failed to read chunk: %w", err)
+		}
+		if chunk == nil {
+			break
+		}
+		if last := chunk.FirstLine + len(chunk.Lines) - 1; last > toLine {
+			chunk.Lines = chunk.Lines[:toLine-chunk.FirstLine+1]
+		}
+		if e.model != "" && chunk.FileType == provider.FileTypeTranscript {
+			ensureChunkMetadata(chunk).Model = e.model
+		}
+
+		if _, err := e.backend.UploadChunk(e.sessionID, chunk.FileName, chunk.FileType, chunk.FirstLine, chunk.Lines, chunk.Metadata); err != nil {
+			return sent, fmt.Errorf("failed to upload lines %d-%d of %s: %w",
+				chunk.FirstLine, chunk.FirstLine+len(chunk.Lines)-1, chunk.FileName, err)
+		}
+
+		sent += len(chunk.Lines)
+		cursor.LastSyncedLine = chunk.FirstLine + len(chunk.Lines) - 1
+		cursor.ByteOffset = chunk.NewOffset
+		e.reportChunk(cursor, chunk)
+		logger.Debug("Replayed range",
+			"file", chunk.FileName, "first_line", chunk.FirstLine, "lines", len(chunk.Lines))
+	}
+	return sent, nil
+}
+
+// trackedFile returns the tracked file named fileName, or the transcript
+// when fileName is empty.
+func (e *Engine) trackedFile(fileName string) (*TrackedFile, error) {
+	file := e.tracker.GetTranscriptFile()
+	if fileName != "" {
+		file = nil
+		for _, f := range e.tracker.GetTrackedFiles() {
+			if f.Name == fileName {
+				file = f
+				break
+			}
+		}
+	}
+	if file == nil {
+		return nil, fmt.Errorf("file %q is not tracked in this session", fileName)
+	}
+	return file, nil
+}
@@ -0,0 +1,66 @@
+package sync
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/url"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/http"
+	"github.com/ConfabulousDev/confab/pkg/logger"
+)
+
+// maxRetryBackoff caps the exponential retry delay (Retry-After is honored
+// as sent, within pkg/http's own cap).
+const maxRetryBackoff = 30 * time.Second
+
+// withRetries wraps an idempotent request so that retryable failures are
+// retried up to c.maxRetries times. The circuit breaker in do sees the
+// whole sequence as one call.
+func (c *Client) withRetries(call func() error) func() error {
+	return func() error {
+		err := call()
+		for attempt := 0; attempt < c.maxRetries && retryable(err); attempt++ {
+			wait := c.retryDelay(attempt, err)
+			logger.Warn("Request failed, retrying",
+				"component", "sync", "retry_in", wait.String(), "attempt", attempt+1,
+				"max_retries", c.maxRetries, "error", err)
+			time.Sleep(wait)
+			err = call()
+		}
+		return err
+	}
+}
+
+// retryable reports whether err is worth retrying: a 5xx response, a
+// timeout (http.ErrTimeout) or another transport failure. 4xx responses (400, 401, 404, ...) won't change on
+// retry. pkg/http has already retried 429s internally by the time one
+// surfaces here as ErrRateLimited, so those are left to the caller, which
+// should wait out its Retry-After (see http.RetryAfter).
+func retryable(err error) bool {
+	if errors.Is(err, http.ErrTimeout) {
+		return true
+	}
+	var statusErr *http.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// retryDelay is the response's Retry-After when it sent one, otherwise
+// baseBackoff doubled per attempt, capped at maxRetryBackoff, with the
+// upper half jittered so clients that failed together don't retry together.
+func (c *Client) retryDelay(attempt int, err error) time.Duration {
+	var statusErr *http.StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+	backoff := c.baseBackoff << attempt
+	if backoff > maxRetryBackoff || backoff <= 0 {
+		backoff = maxRetryBackoff
+	}
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
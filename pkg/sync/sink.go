@@ -0,0 +1,94 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/config"
+	"github.com/ConfabulousDev/confab/pkg/redactor"
+)
+
+// NDJSONSink is a Backend that writes each chunk to an io.Writer as one
+// JSON-encoded ChunkRequest per line instead of uploading it, for piping
+// into custom tooling. It holds no sync state: Init reports no files, so
+// every line is written from line 1. Events and summaries are dropped,
+// and Capabilities advertises no optional features.
+type NDJSONSink struct {
+	sessionID string
+
+	mu     sync.Mutex // serializes writes from concurrent sidechain uploads
+	enc    *json.Encoder
+	chunks int
+	lines  int
+}
+
+// NewNDJSONSink returns a sink writing to w. sessionID is stamped on every
+// ChunkRequest in place of a backend-assigned session ID.
+func NewNDJSONSink(w io.Writer, sessionID string) *NDJSONSink {
+	return &NDJSONSink{sessionID: sessionID, enc: json.NewEncoder(w)}
+}
+
+// Counts returns the chunks and lines written so far.
+func (s *NDJSONSink) Counts() (chunks, lines int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chunks, s.lines
+}
+
+func (s *NDJSONSink) Init(_, _, _ string, _ *InitMetadata, _ *InitOptions) (*InitResponse, error) {
+	return &InitResponse{SessionID: s.sessionID, Files: map[string]FileState{}}, nil
+}
+
+func (s *NDJSONSink) UploadChunk(sessionID, fileName, fileType string, firstLine int, lines []string, metadata *ChunkMetadata) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.enc.Encode(ChunkRequest{
+		SessionID: sessionID,
+		FileName:  fileName,
+		FileType:  fileType,
+		FirstLine: firstLine,
+		Lines:     lines,
+		Checksum:  ChunkChecksum(lines),
+		Metadata:  metadata,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to write chunk: %w", err)
+	}
+	s.chunks++
+	s.lines += len(lines)
+	return firstLine + len(lines) - 1, nil
+}
+
+func (s *NDJSONSink) SendEvent(string, string, time.Time, json.RawMessage) error { return nil }
+
+func (s *NDJSONSink) UpdateSessionSummary(string, string) error { return nil }
+
+func (s *NDJSONSink) Capabilities() (Capabilities, error) { return Capabilities{}, nil }
+
+// NewRedactor builds the redactor an upload with cfg would use (its
+// effective redaction config, which may be a profile), or nil when
+// redaction is disabled. For engines built with NewWithBackend.
+func NewRedactor(cfg *config.UploadConfig) (*redactor.Redactor, error) {
+	redaction := cfg.GetEffectiveRedactionConfig()
+	if redaction == nil || !redaction.Enabled {
+		return nil, nil
+	}
+	r, err := redactor.NewFromConfig(redaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create redactor: %w", err)
+	}
+	return r, nil
+}
+
+// BypassMarker returns the redaction bypass marker of cfg's effective
+// redaction config, which is stripped from uploaded lines even with
+// redaction disabled. For EngineConfig.BypassMarker.
+func BypassMarker(cfg *config.UploadConfig) string {
+	if redaction := cfg.GetEffectiveRedactionConfig(); redaction != nil {
+		return redaction.BypassMarker
+	}
+	return ""
+}
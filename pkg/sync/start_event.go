@@ -0,0 +1,114 @@
+package sync
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/ConfabulousDev/confab/pkg/logger"
+	"github.com/ConfabulousDev/confab/pkg/provider"
+)
+
+// clientVersion is the confab version sent with session_start events, set
+// once at startup via SetClientVersion.
+var clientVersion string
+
+// SetClientVersion sets the confab version reported in session_start
+// events. Should be called once at startup, before any engine is created.
+func SetClientVersion(v string) {
+	clientVersion = v
+}
+
+// startEventScanLines is how many transcript lines SendStartEvent reads
+// looking for the Claude Code version.
+const startEventScanLines = 20
+
+// StartEventPayload is the payload of the session_start event: the
+// environment the session is synced from.
+type StartEventPayload struct {
+	ClientVersion string `json:"client_version"`
+	OS            string `json:"os"`
+	Arch          string `json:"arch"`
+	Provider      string `json:"provider"`
+	// ClaudeCodeVersion is the Claude Code version recorded in the
+	// transcript; empty for other providers or when it isn't found.
+	ClaudeCodeVersion string `json:"claude_code_version,omitempty"`
+	CWD               string `json:"cwd"`
+}
+
+// SendStartEvent sends a session_start event with StartEventPayload, once
+// per backend session: later calls are no-ops, as are calls for a session
+// seeded with SeedStartEventSent. Must be called after a successful Init.
+// The daemon treats a failure as non-fatal.
+func (e *Engine) SendStartEvent() error {
+	if !e.initialized || e.sessionID == "" || e.startEventSessionID == e.sessionID {
+		return nil
+	}
+
+	version := clientVersion
+	if version == "" {
+		version = "dev"
+	}
+	payload := StartEventPayload{
+		ClientVersion: version,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		Provider:      e.provider.Name(),
+		CWD:           e.cwd,
+	}
+	if payload.Provider == provider.NameClaudeCode {
+		payload.ClaudeCodeVersion = transcriptClaudeVersion(e.transcriptPath)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session_start payload: %w", err)
+	}
+
+	if err := e.backend.SendEvent(e.sessionID, "session_start", time.Now(), data); err != nil {
+		return fmt.Errorf("failed to send session_start event: %w", err)
+	}
+	e.startEventSessionID = e.sessionID
+
+	logger.Info("Sent session_start event",
+		"component", "sync", "client_version", version, "claude_code_version", payload.ClaudeCodeVersion)
+	return nil
+}
+
+// StartEventSessionID returns the backend session ID the session_start
+// event was sent for, or "" if none was, for persisting across restarts.
+func (e *Engine) StartEventSessionID() string {
+	return e.startEventSessionID
+}
+
+// SeedStartEventSent records that an earlier engine already sent
+// session_start for backend session sessionID (see StartEventSessionID),
+// so SendStartEvent skips it once Init resumes that session.
+func (e *Engine) SeedStartEventSent(sessionID string) {
+	e.startEventSessionID = sessionID
+}
+
+// transcriptClaudeVersion returns the Claude Code version from the
+// "version" field of the first transcript lines that carry one, or "" if
+// none of the first startEventScanLines do (or the file can't be read).
+func transcriptClaudeVersion(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), DefaultMaxChunkBytes)
+	for i := 0; i < startEventScanLines && scanner.Scan(); i++ {
+		var line struct {
+			Version string `json:"version"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &line) == nil && line.Version != "" {
+			return line.Version
+		}
+	}
+	return ""
+}
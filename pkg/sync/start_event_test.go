@@ -0,0 +1,117 @@
+package sync
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestEngine_SendStartEvent_Once(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	defer func(v string) { clientVersion = v }(clientVersion)
+	SetClientVersion("1.2.3-test")
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	transcript := `{"type":"summary"}` + "\n" + `{"type":"user","version":"2.0.14","cwd":"/work"}` + "\n"
+	os.WriteFile(transcriptPath, []byte(transcript), 0644)
+
+	engine := newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+		ExternalID:     "start-event-test",
+		TranscriptPath: transcriptPath,
+		CWD:            tmpDir,
+	})
+
+	// Before Init there is no session to attach the event to.
+	if err := engine.SendStartEvent(); err != nil {
+		t.Fatalf("SendStartEvent before Init: %v", err)
+	}
+	if err := engine.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := engine.SendStartEvent(); err != nil {
+			t.Fatalf("SendStartEvent: %v", err)
+		}
+	}
+
+	if len(mock.eventRequests) != 1 {
+		t.Fatalf("event requests = %d, want exactly 1", len(mock.eventRequests))
+	}
+	req := mock.eventRequests[0]
+	if req.EventType != "session_start" || req.SessionID != engine.SessionID() {
+		t.Errorf("event = %s for session %q, want session_start for %q", req.EventType, req.SessionID, engine.SessionID())
+	}
+	var payload StartEventPayload
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	want := StartEventPayload{
+		ClientVersion:     "1.2.3-test",
+		OS:                runtime.GOOS,
+		Arch:              runtime.GOARCH,
+		Provider:          "claude-code",
+		ClaudeCodeVersion: "2.0.14",
+		CWD:               tmpDir,
+	}
+	if payload != want {
+		t.Errorf("payload = %+v, want %+v", payload, want)
+	}
+}
+
+func TestEngine_SendStartEvent_Seeded(t *testing.T) {
+	mock := newMockBackend(t)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tmpDir, transcriptPath := setupTestEnv(t, server.URL)
+	os.WriteFile(transcriptPath, []byte(`{"type":"user"}`+"\n"), 0644)
+
+	newEngine := func() *Engine {
+		return newEngineWithBackend(t, mustNewClient(t, server.URL, tmpDir), nil, EngineConfig{
+			ExternalID:     "start-event-seeded-test",
+			TranscriptPath: transcriptPath,
+			CWD:            tmpDir,
+		})
+	}
+	first := newEngine()
+	if err := first.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if err := first.SendStartEvent(); err != nil {
+		t.Fatalf("SendStartEvent: %v", err)
+	}
+	if got := first.StartEventSessionID(); got != first.SessionID() {
+		t.Fatalf("StartEventSessionID = %q, want %q", got, first.SessionID())
+	}
+
+	// A restarted engine seeded with the sent session doesn't resend.
+	second := newEngine()
+	second.SeedStartEventSent(first.StartEventSessionID())
+	if err := second.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if err := second.SendStartEvent(); err != nil {
+		t.Fatalf("SendStartEvent: %v", err)
+	}
+	if len(mock.eventRequests) != 1 {
+		t.Errorf("event requests = %d, want 1", len(mock.eventRequests))
+	}
+
+	// One seeded with a different backend session sends it for this one.
+	third := newEngine()
+	third.SeedStartEventSent("other-session")
+	if err := third.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if err := third.SendStartEvent(); err != nil {
+		t.Fatalf("SendStartEvent: %v", err)
+	}
+	if len(mock.eventRequests) != 2 {
+		t.Errorf("event requests = %d, want 2", len(mock.eventRequests))
+	}
+}
@@ -22,7 +22,7 @@ const maxLinesToSearch = 10
 func FindSessionByLeafUUID(transcriptDir, leafUUID, excludeFile string) string {
 	entries, err := os.ReadDir(transcriptDir)
 	if err != nil {
-		logger.Debug("Failed to read transcript directory: %v", err)
+		logger.Debugf("Failed to read transcript directory: %v", err)
 		return ""
 	}
 
@@ -102,7 +102,7 @@ func hasUUIDInLastLines(filePath, targetUUID string) bool {
 // The summary should already be sanitized by the caller.
 // Errors are logged but not returned (non-disruptive to main sync flow).
 func (e *Engine) linkSummaryToPreviousSession(summary, leafUUID string) {
-	logger.Debug("Found summary with leafUuid: %s", leafUUID)
+	logger.Debugf("Found summary with leafUuid: %s", leafUUID)
 
 	// Search for the previous session
 	transcriptDir := filepath.Dir(e.transcriptPath)
@@ -110,17 +110,17 @@ func (e *Engine) linkSummaryToPreviousSession(summary, leafUUID string) {
 	previousSessionID := FindSessionByLeafUUID(transcriptDir, leafUUID, currentFile)
 
 	if previousSessionID == "" {
-		logger.Debug("No matching session found for leafUuid: %s", leafUUID)
+		logger.Debugf("No matching session found for leafUuid: %s", leafUUID)
 		return
 	}
 
-	logger.Info("Linking summary to previous session: %s", previousSessionID)
+	logger.Infof("Linking summary to previous session: %s", previousSessionID)
 
 	// Update the previous session's summary via API
 	if err := e.backend.UpdateSessionSummary(previousSessionID, summary); err != nil {
-		logger.Error("Failed to update summary for session %s: %v", previousSessionID, err)
+		logger.Errorf("Failed to update summary for session %s: %v", previousSessionID, err)
 		return
 	}
 
-	logger.Info("Successfully updated summary for session %s", previousSessionID)
+	logger.Infof("Successfully updated summary for session %s", previousSessionID)
 }
@@ -2,12 +2,19 @@ package sync
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/git"
@@ -34,6 +41,48 @@ type TrackedFile struct {
 	// Roots and descendants both carry this; only the engine's emission
 	// gate (FirstLine==1) determines when it goes on the wire.
 	CodexRollout *CodexRolloutMetadata
+
+	// RemoteOnly marks a file the backend knows about (from init state) that
+	// does not exist locally — e.g. an agent synced from another machine.
+	// HasFileChanged reports no change for it until the file appears on
+	// disk, so the sync loop doesn't hit a read error every cycle.
+	RemoteOnly bool
+
+	// Rejected-chunk isolation, owned by Engine.syncFile (see
+	// Engine.handleRejectedChunk). rejections counts consecutive 400s for
+	// the chunk starting at rejectedLine; while chunkLineLimit > 0 ReadChunk
+	// returns at most that many lines; quarantineNext makes the next upload
+	// replace its single line with a placeholder.
+	rejectedLine   int
+	rejections     int
+	chunkLineLimit int
+	quarantineNext bool
+}
+
+// carryRejections keeps prev's rejected-chunk state across a refresh from
+// backend state (which follows every failed upload), as long as the file
+// resumes at the same line.
+func (f *TrackedFile) carryRejections(prev *TrackedFile) {
+	if prev == nil || prev.LastSyncedLine != f.LastSyncedLine {
+		return
+	}
+	f.rejectedLine, f.rejections = prev.rejectedLine, prev.rejections
+	f.chunkLineLimit, f.quarantineNext = prev.chunkLineLimit, prev.quarantineNext
+}
+
+// clearRejections ends rejected-chunk isolation for the file.
+func (f *TrackedFile) clearRejections() {
+	f.rejectedLine, f.rejections, f.chunkLineLimit, f.quarantineNext = 0, 0, 0, false
+}
+
+// FileOffset is a file's local read position, persisted across daemon
+// restarts so ReadChunk can seek straight to the resume point instead of
+// re-scanning from line 1. See FileTracker.SeedOffsetHints.
+type FileOffset struct {
+	LastSyncedLine int       `json:"last_synced_line"`
+	ByteOffset     int64     `json:"byte_offset"`
+	LastSize       int64     `json:"last_size"`
+	LastModTime    time.Time `json:"last_mod_time"`
 }
 
 // Chunk represents a range of lines read from a file with extracted metadata
@@ -45,6 +94,10 @@ type Chunk struct {
 	NewOffset int64          // Byte offset after reading these lines
 	Metadata  *ChunkMetadata // Metadata to send to backend
 	AgentIDs  []string       // Agent IDs discovered (local use only, not sent to backend)
+	// AttachmentPaths are document attachments referenced by the chunk's
+	// tool results (local use only; see FileTracker.DiscoverAttachments).
+	AttachmentPaths []string
+	ModTime         time.Time // File's modification time when the chunk was read
 }
 
 // FileTracker tracks files and their sync state for a session
@@ -53,6 +106,43 @@ type FileTracker struct {
 	subagentsDir   string // <session-id>/subagents/ directory for agent files
 	files          map[string]*TrackedFile
 	knownAgentIDs  map[string]bool // Agent IDs we've already discovered
+	// agentExtractor finds child agent IDs in transcript lines and names
+	// their files (see EngineConfig.AgentExtractor).
+	agentExtractor AgentExtractor
+	// knownAttachments are attachment paths DiscoverAttachments has
+	// already considered.
+	knownAttachments map[string]bool
+	offsetHints      map[string]FileOffset // consumed by the next InitFromBackendState
+
+	// uploadPartialTail turns off ReadChunk's deferral of an incomplete
+	// final line (see EngineConfig.UploadPartialTail).
+	uploadPartialTail bool
+	// syncAttachments makes ReadChunk collect document attachment paths
+	// from tool results (see EngineConfig.SyncAttachments).
+	syncAttachments bool
+	// skipOversizeLines makes ReadChunk replace a line larger than the
+	// chunk limit with a placeholder instead of failing (see
+	// EngineConfig.SkipOversizeLines).
+	skipOversizeLines bool
+	// bypassMarker is stripped from lines ReadChunk reads without a
+	// redactor (see EngineConfig.BypassMarker).
+	bypassMarker string
+	// flushTail turns the deferral off for the duration of
+	// Engine.SyncAllFinal.
+	flushTail atomic.Bool
+
+	// mu serializes updates that can race while the engine uploads agent
+	// files concurrently: per-file sync state (UpdateAfterSync) and the
+	// files map (InitFromBackendState on a mid-cycle refresh).
+	mu sync.Mutex
+	// syncing holds the names of files being uploaded (see BeginSync), so
+	// overlapping SyncAll calls don't upload the same file twice. Guarded
+	// by mu.
+	syncing map[string]bool
+	// discoverMu serializes discovery (DiscoverNewFiles,
+	// DiscoverAttachments) across overlapping SyncAll calls, so each new
+	// file is tracked and returned once.
+	discoverMu sync.Mutex
 }
 
 // NewFileTracker creates a new file tracker for a session
@@ -60,12 +150,18 @@ func NewFileTracker(transcriptPath string) *FileTracker {
 	// Derive subagents directory from transcript path:
 	// transcript: <project>/<session-id>.jsonl
 	// subagents:  <project>/<session-id>/subagents/
-	base := strings.TrimSuffix(transcriptPath, filepath.Ext(transcriptPath))
+	// A rotated <session-id>.jsonl.gz shares the same subagents directory.
+	base := strings.TrimSuffix(transcriptPath, ".gz")
+	base = strings.TrimSuffix(base, filepath.Ext(base))
 	return &FileTracker{
 		transcriptPath: transcriptPath,
 		subagentsDir:   filepath.Join(base, "subagents"),
 		files:          make(map[string]*TrackedFile),
 		knownAgentIDs:  make(map[string]bool),
+		agentExtractor: ClaudeAgentExtractor{},
+
+		knownAttachments: make(map[string]bool),
+		syncing:          make(map[string]bool),
 	}
 }
 
@@ -78,18 +174,26 @@ func NewFileTracker(transcriptPath string) *FileTracker {
 // must survive — otherwise a retried first chunk would lose its codex_rollout
 // payload. We preserve CodexRollout for existing entries and only refresh the
 // fields that can legitimately drift (sync position).
+//
+// Read positions seeded with SeedOffsetHints are applied here (first call
+// only), so a restarted daemon can seek past already-synced lines.
 func (t *FileTracker) InitFromBackendState(backendFiles map[string]FileState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	transcriptName := filepath.Base(t.transcriptPath)
 
 	// Add transcript
 	transcriptState := backendFiles[transcriptName]
-	t.files[transcriptName] = t.buildTrackedFromState(TrackedFile{
+	transcript := t.buildTrackedFromState(TrackedFile{
 		Path:           t.transcriptPath,
 		Name:           transcriptName,
 		Type:           provider.FileTypeTranscript,
 		LastSyncedLine: transcriptState.LastSyncedLine,
 		ByteOffset:     0, // Will be set on first read
 	})
+	transcript.carryRejections(t.files[transcriptName])
+	t.files[transcriptName] = transcript
 
 	// Add any other files from backend state (agent files)
 	for fileName, state := range backendFiles {
@@ -98,30 +202,51 @@ func (t *FileTracker) InitFromBackendState(backendFiles map[string]FileState) {
 		}
 
 		path := ""
+		fileType := provider.FileTypeAgent
 		if prev, ok := t.files[fileName]; ok {
 			path = prev.Path
 		}
-		if path == "" {
-			// First time we've seen this file; default to subagents dir.
+		if isAttachmentName(fileName) {
+			fileType = provider.FileTypeAttachment
+		}
+		if path == "" && fileType == provider.FileTypeAttachment {
+			// An attachment's name is a hash of its path, which can't be
+			// recovered; DiscoverAttachments fills it in if a transcript
+			// line references the file again.
+			logger.Debugf("Backend attachment has no known local path, tracking as remote-only: %s", fileName)
+		} else if path == "" {
 			// Codex children, when present in the tracker, already have an
 			// absolute rollout path from AddCodexRollout, so this branch is
 			// only taken for genuinely-new Claude agent files.
 			path = filepath.Join(t.subagentsDir, fileName)
 		}
-		t.files[fileName] = t.buildTrackedFromState(TrackedFile{
+		tracked := t.buildTrackedFromState(TrackedFile{
 			Path:           path,
 			Name:           fileName,
-			Type:           provider.FileTypeAgent,
+			Type:           fileType,
 			LastSyncedLine: state.LastSyncedLine,
 			ByteOffset:     0, // Will be set on first read
 		})
+		if path == "" {
+			tracked.RemoteOnly = true
+		} else if _, err := os.Stat(path); os.IsNotExist(err) {
+			logger.Debugf("Backend file not present locally, tracking as remote-only: %s", fileName)
+			tracked.RemoteOnly = true
+		}
+		tracked.carryRejections(t.files[fileName])
+		t.files[fileName] = tracked
 	}
+
+	// Offset hints describe the state at startup only; a later refresh
+	// rescans rather than trusting them.
+	t.offsetHints = nil
 }
 
 func (t *FileTracker) buildTrackedFromState(next TrackedFile) *TrackedFile {
 	if prev, ok := t.files[next.Name]; ok {
 		next.CodexRollout = prev.CodexRollout
 	}
+	t.applyOffsetHint(&next)
 	return &next
 }
 
@@ -188,6 +313,8 @@ func (t *FileTracker) RegisterSidechainFile(path, name, fileType string) bool {
 
 // GetTrackedFiles returns all currently tracked files
 func (t *FileTracker) GetTrackedFiles() []*TrackedFile {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	result := make([]*TrackedFile, 0, len(t.files))
 	for _, f := range t.files {
 		result = append(result, f)
@@ -197,17 +324,50 @@ func (t *FileTracker) GetTrackedFiles() []*TrackedFile {
 
 // IsTracked returns true if a file is already being tracked
 func (t *FileTracker) IsTracked(fileName string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	_, ok := t.files[fileName]
 	return ok
 }
 
+// BeginSync marks file as being uploaded and reports whether the caller
+// owns it: false means another SyncAll is uploading it, and the caller
+// should skip it. Every successful BeginSync must be paired with EndSync,
+// whether the upload succeeded or failed.
+func (t *FileTracker) BeginSync(file *TrackedFile) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.syncing[file.Name] {
+		return false
+	}
+	t.syncing[file.Name] = true
+	return true
+}
+
+// EndSync clears BeginSync's mark for file.
+func (t *FileTracker) EndSync(file *TrackedFile) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.syncing, file.Name)
+}
+
 // HasFileChanged checks if a file has more data to sync.
 // Returns true if:
 // - The file has grown (more bytes than our last known offset)
 // - The file has been modified (mod time changed)
 // - We haven't read the file yet (no byte offset)
+//
+// Remote-only files report false while they are still missing locally; once
+// the file appears, RemoteOnly is cleared and it syncs like any other file.
 func (t *FileTracker) HasFileChanged(file *TrackedFile) bool {
 	info, err := os.Stat(file.Path)
+	if file.RemoteOnly {
+		if err != nil {
+			return false
+		}
+		logger.Infof("Remote-only file appeared locally: %s", file.Name)
+		file.RemoteOnly = false
+	}
 	if err != nil {
 		// Can't stat - assume changed to be safe
 		return true
@@ -216,8 +376,10 @@ func (t *FileTracker) HasFileChanged(file *TrackedFile) bool {
 	size := info.Size()
 	modTime := info.ModTime()
 
-	// If we have a byte offset, check if there's more data beyond it
-	if file.ByteOffset > 0 && size > file.ByteOffset {
+	// If we have a byte offset, check if there's more data beyond it.
+	// A compressed file's offset counts decompressed bytes, so only its
+	// size and mtime are compared.
+	if file.ByteOffset > 0 && size > file.ByteOffset && !isCompressed(file.Path) {
 		return true
 	}
 
@@ -229,12 +391,190 @@ func (t *FileTracker) HasFileChanged(file *TrackedFile) bool {
 	return false
 }
 
+// ShrunkFiles returns the names of tracked files now smaller on disk than
+// the byte offset of their last synced line. Such a file was rewritten
+// (e.g. Claude Code compacting a transcript), so the lines past that offset
+// no longer follow the ones already uploaded.
+func (t *FileTracker) ShrunkFiles() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var shrunk []string
+	for name, f := range t.files {
+		if f.ByteOffset <= 0 || f.RemoteOnly || isCompressed(f.Path) {
+			continue
+		}
+		if info, err := os.Stat(f.Path); err == nil && info.Size() < f.ByteOffset {
+			shrunk = append(shrunk, name)
+		}
+	}
+	sort.Strings(shrunk)
+	return shrunk
+}
+
+// CountLines returns the number of lines ReadChunk would see in the file:
+// newline-terminated lines plus a trailing partial line, if any. It counts
+// newline bytes in fixed-size blocks without splitting or decoding lines.
+func CountLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var n int
+	buf := make([]byte, 64*1024)
+	last := byte('\n')
+	for {
+		k, err := f.Read(buf)
+		if k > 0 {
+			n += bytes.Count(buf[:k], []byte{'\n'})
+			last = buf[k-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		n++
+	}
+	return n, nil
+}
+
+// lineEstimateThreshold is the file size above which EstimateLines
+// samples instead of counting every newline. A variable for tests.
+var lineEstimateThreshold int64 = 100 * 1024 * 1024
+
+// lineEstimateSamples blocks of lineEstimateBlockSize bytes, spread evenly
+// over the file, make up EstimateLines' sample.
+const (
+	lineEstimateSamples   = 10
+	lineEstimateBlockSize = 4 * 1024
+)
+
+// EstimateLines returns the file's line count: exact (CountLines) up to
+// 100 MB, and above that extrapolated from the newlines in 10 evenly spaced
+// 4 KB blocks, with estimated set. An estimate is at least 1.
+func EstimateLines(path string) (n int, estimated bool, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false, err
+	}
+	size := info.Size()
+	if size <= lineEstimateThreshold {
+		n, err := CountLines(path)
+		return n, false, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, lineEstimateBlockSize)
+	var newlines, sampled int
+	step := (size - lineEstimateBlockSize) / (lineEstimateSamples - 1)
+	for i := int64(0); i < lineEstimateSamples; i++ {
+		k, err := f.ReadAt(buf, i*step)
+		if err != nil && err != io.EOF {
+			return 0, false, err
+		}
+		newlines += bytes.Count(buf[:k], []byte{'\n'})
+		sampled += k
+	}
+	if sampled == 0 {
+		return 0, true, nil
+	}
+	return max(1, int(float64(size)*float64(newlines)/float64(sampled))), true, nil
+}
+
+// isCompressed reports whether path is a gzip-compressed transcript or
+// agent file (e.g. a rotated "transcript.jsonl.gz"), which ReadChunk
+// decompresses on the fly.
+func isCompressed(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// countingReader counts the bytes read through it: the decompressed
+// position in a gzip stream, which can't be found with Seek.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 // DefaultMaxChunkBytes is the default maximum size of a chunk in bytes.
 // This is a backend-imposed limit: the server rejects chunks larger than 16MB.
 // We use 14MB to leave headroom for JSON encoding overhead and compression.
 // If the backend limit changes, this constant must be updated accordingly.
 const DefaultMaxChunkBytes = 14 * 1024 * 1024 // 14MB
 
+// oversizePlaceholder replaces a line too large for any chunk in the
+// upload, so the line numbering stays intact.
+func oversizePlaceholder(line, size int) string {
+	return fmt.Sprintf(`{"type":"confab_oversize_line","line":%d,"bytes":%d,"reason":"line exceeds the max chunk size"}`, line, size)
+}
+
+// chunkUsage accumulates the model and token usage of a chunk's Claude
+// assistant messages ({"type":"assistant","message":{"id", "model",
+// "usage"}}). Claude Code writes one line per content block of a response,
+// each repeating the message's usage, so usage is counted once per
+// message ID.
+type chunkUsage struct {
+	model string
+	total *TokenUsage
+	seen  map[string]bool
+}
+
+// add records msg if it is an assistant message. Fields of the wrong type
+// are ignored, so a malformed line can't fail the read.
+func (u *chunkUsage) add(msg map[string]interface{}) {
+	if t, _ := msg["type"].(string); t != "assistant" {
+		return
+	}
+	message, ok := msg["message"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if model, _ := message["model"].(string); model != "" {
+		u.model = model
+	}
+	raw, ok := message["usage"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if id, _ := message["id"].(string); id != "" {
+		if u.seen[id] {
+			return
+		}
+		if u.seen == nil {
+			u.seen = make(map[string]bool)
+		}
+		u.seen[id] = true
+	}
+	count := func(key string) int64 {
+		n, _ := raw[key].(float64)
+		return int64(max(n, 0))
+	}
+	if u.total == nil {
+		u.total = &TokenUsage{}
+	}
+	u.total.InputTokens += count("input_tokens")
+	u.total.OutputTokens += count("output_tokens")
+	u.total.CacheCreationInputTokens += count("cache_creation_input_tokens")
+	u.total.CacheReadInputTokens += count("cache_read_input_tokens")
+	u.total.TotalTokens = u.total.InputTokens + u.total.OutputTokens +
+		u.total.CacheCreationInputTokens + u.total.CacheReadInputTokens
+}
+
 // gitInfoFromClaudeMessage extracts per-chunk git info from a Claude
 // transcript message (inline `gitBranch` + `cwd`). Returns nil for any
 // other shape (including agent files, where Type != "transcript").
@@ -288,15 +628,31 @@ func gitInfoFromCodexSessionMeta(msg map[string]interface{}) *git.GitInfo {
 
 // ReadChunk reads new lines from a file starting after LastSyncedLine.
 // Uses ByteOffset to seek directly to the right position if available.
-// Applies redaction if a redactor is provided.
+// A ".gz" file is decompressed while reading; its offsets aren't stable
+// across rewrites of the compressed stream, so it is always re-scanned
+// from the start, and ByteOffset counts decompressed bytes.
+// Applies redaction if a redactor is provided; without one, only the
+// bypass marker is stripped.
 // Stops reading when accumulated bytes would exceed maxBytes (aligned to line boundary).
 // Returns nil if there are no new lines.
 func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxBytes int) (*Chunk, error) {
+	return t.readChunk(file, r, maxBytes, maxBytes)
+}
+
+// readChunk is ReadChunk with a soft limit below the hard one: chunks stop
+// at maxBytes, except that a first line larger than maxBytes but within
+// hardMaxBytes is returned on its own rather than as an error. Used by the
+// engine's adaptive chunk sizing.
+func (t *FileTracker) readChunk(file *TrackedFile, r *redactor.Redactor, maxBytes, hardMaxBytes int) (*Chunk, error) {
 	f, err := os.Open(file.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer f.Close()
+	var modTime time.Time
+	if info, err := f.Stat(); err == nil {
+		modTime = info.ModTime()
+	}
 
 	var lines []string
 	var metadata *ChunkMetadata
@@ -305,13 +661,34 @@ func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxByte
 	var currentOffset int64
 	var readingFromStart bool // true if we're reading from start (offset 0)
 
-	// If we have a byte offset from a previous read, try to seek to it
-	if file.ByteOffset > 0 && file.LastSyncedLine > 0 {
+	// src is what the scanner reads; position returns how far into it
+	// the scanner has read.
+	var src io.Reader = f
+	position := func() int64 {
+		pos, _ := f.Seek(0, io.SeekCurrent)
+		return pos
+	}
+
+	if isCompressed(file.Path) {
+		zr, err := gzip.NewReader(f)
+		if err == io.EOF {
+			return nil, nil // Empty file, no gzip header yet
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer zr.Close()
+		counter := &countingReader{r: zr}
+		src = counter
+		position = func() int64 { return counter.n }
+		readingFromStart = true
+	} else if file.ByteOffset > 0 && file.LastSyncedLine > 0 {
+		// If we have a byte offset from a previous read, try to seek to it
 		// Seek to the saved offset
 		if _, err := f.Seek(file.ByteOffset, io.SeekStart); err != nil {
 			// Seek failed, fall back to reading from start.
 			// Use local state rather than mutating file.ByteOffset.
-			logger.Debug("Seek to offset %d failed, falling back to start: %v", file.ByteOffset, err)
+			logger.Debugf("Seek to offset %d failed, falling back to start: %v", file.ByteOffset, err)
 			readingFromStart = true
 			if _, err := f.Seek(0, io.SeekStart); err != nil {
 				return nil, fmt.Errorf("failed to seek to start: %w", err)
@@ -327,8 +704,8 @@ func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxByte
 	// line exceeds the chunk limit. This intentionally doesn't use types.NewJSONLScanner
 	// because the buffer must exceed DefaultMaxChunkBytes (14MB) + headroom = ~24MB,
 	// which is larger than the standard 10MB JSONL scanner buffer.
-	scanner := bufio.NewScanner(f)
-	maxLineSize := maxBytes + types.MaxJSONLLineSize // maxBytes + 10MB headroom
+	scanner := bufio.NewScanner(src)
+	maxLineSize := hardMaxBytes + types.MaxJSONLLineSize // hardMaxBytes + 10MB headroom
 	scanner.Buffer(make([]byte, bufio.MaxScanTokenSize), maxLineSize)
 
 	lineNum := file.LastSyncedLine // Start counting from where we left off
@@ -338,8 +715,10 @@ func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxByte
 
 	// Extract metadata from transcript and agent files (for transitive agent discovery)
 	extractMetadata := file.Type == provider.FileTypeTranscript || file.Type == provider.FileTypeAgent
-	var agentIDs []string
+	msgExtractor, _ := t.agentExtractor.(messageAgentExtractor)
+	var agentIDs, attachmentPaths []string
 	var gitInfo *git.GitInfo
+	var usage chunkUsage
 	seenAgents := make(map[string]bool)
 
 	// Copy known agent IDs to seen set so we don't re-report them
@@ -347,6 +726,12 @@ func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxByte
 		seenAgents[id] = true
 	}
 
+	// The last line appended and its length, for deferring a tail line
+	// that is still being written (see below).
+	var lastLineBytes int
+	var lastLineComplete bool
+	stoppedEarly := false
+
 	for scanner.Scan() {
 		lineNum++
 		lineWithNewline := len(scanner.Bytes()) + 1 // +1 for newline
@@ -357,42 +742,73 @@ func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxByte
 			continue
 		}
 
+		// Isolating a line the backend rejects: stop at the line limit.
+		// Checked once another line follows, so a held-back tail line is
+		// still deferred below.
+		if file.chunkLineLimit > 0 && len(lines) >= file.chunkLineLimit {
+			newOffset = currentOffset
+			stoppedEarly = true
+			break
+		}
+
 		line := scanner.Text()
 
 		// Check if adding this line would exceed the chunk size limit
 		// Account for JSON array overhead: quotes, comma, etc. (~4 bytes per line)
 		lineBytes := len(line) + 4
 
+		if lineBytes > hardMaxBytes && t.skipOversizeLines {
+			logger.Warnf("Line %d of %s exceeds max chunk size (%d bytes > %d bytes); uploading a placeholder in its place", lineNum, file.Path, lineBytes, hardMaxBytes)
+			line = oversizePlaceholder(lineNum, lineBytes)
+			lineBytes = len(line) + 4
+		}
+
 		if totalBytes+lineBytes > maxBytes {
 			if totalBytes == 0 {
-				// First line of chunk exceeds limit - cannot proceed past this line
-				return nil, fmt.Errorf("line %d exceeds max chunk size (%d bytes > %d bytes)", lineNum, lineBytes, maxBytes)
+				if lineBytes > hardMaxBytes {
+					// First line of chunk exceeds limit - cannot proceed past this line
+					return nil, fmt.Errorf("line %d exceeds max chunk size (%d bytes > %d bytes)", lineNum, lineBytes, hardMaxBytes)
+				}
+				// Over the soft limit only: send it alone (the next line
+				// stops the chunk, since totalBytes is then over maxBytes).
+			} else {
+				// Would exceed limit - stop here, this line will be read next time
+				// newOffset stays at current position (before this line)
+				newOffset = currentOffset
+				stoppedEarly = true
+				break
 			}
-			// Would exceed limit - stop here, this line will be read next time
-			// newOffset stays at current position (before this line)
-			newOffset = currentOffset
-			break
 		}
 		totalBytes += lineBytes
 		currentOffset += int64(lineWithNewline)
+		lastLineBytes = lineWithNewline
+		lastLineComplete = len(bytes.TrimSpace(scanner.Bytes())) == 0 || json.Valid(scanner.Bytes())
 
 		// Extract metadata from transcript and agent lines
 		if extractMetadata {
 			var msg map[string]interface{}
-			if err := json.Unmarshal([]byte(line), &msg); err == nil {
-				// Extract agent IDs (agents can spawn other agents).
-				// Agent-ID extraction is Claude-only — Codex tracks
-				// subagents via its SQLite thread tree, not via inline
-				// IDs in rollout JSONL. Hard-binding to ClaudeCode here
-				// is the explicit "this is Claude's transcript schema"
-				// signal; see pkg/provider/claude_agentids.go.
-				for _, agentID := range (provider.ClaudeCode{}).ExtractAgentIDsFromMessage(msg) {
-					if !seenAgents[agentID] {
-						seenAgents[agentID] = true
-						agentIDs = append(agentIDs, agentID)
-					}
+			msgErr := json.Unmarshal([]byte(line), &msg)
+
+			// Extract agent IDs (agents can spawn other agents). The
+			// default extractor is Claude-only — Codex tracks subagents
+			// via its SQLite thread tree, not via inline IDs in rollout
+			// JSONL; see ClaudeAgentExtractor.
+			var childIDs []string
+			if msgExtractor != nil {
+				if msgErr == nil {
+					childIDs = msgExtractor.ExtractChildIDsFromMessage(msg)
 				}
+			} else {
+				childIDs = t.agentExtractor.ExtractChildIDs([]byte(line))
+			}
+			for _, agentID := range childIDs {
+				if !seenAgents[agentID] {
+					seenAgents[agentID] = true
+					agentIDs = append(agentIDs, agentID)
+				}
+			}
 
+			if msgErr == nil {
 				// Extract git info — first message wins. Two provider-
 				// agnostic paths, keyed by message shape:
 				//   - Claude: any message with inline `gitBranch` + `cwd`
@@ -409,6 +825,13 @@ func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxByte
 				if gitInfo == nil {
 					gitInfo = gitInfoFromCodexSessionMeta(msg)
 				}
+
+				// Model and token usage of Claude assistant messages.
+				usage.add(msg)
+
+				if t.syncAttachments {
+					attachmentPaths = append(attachmentPaths, attachmentPathsFromMessage(msg)...)
+				}
 			}
 		}
 
@@ -417,15 +840,38 @@ func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxByte
 		// and Codex rollouts all flow through the same pattern set. The
 		// backend's per-provider Redactions analytics cards depend on
 		// this being the sole place lines are scrubbed before upload.
-		if r != nil {
-			line = r.RedactJSONLine(line)
-		}
+		line = t.redactLine(r, line)
 
 		lines = append(lines, line)
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to scan file: %w", err)
+		// A gzip stream still being written ends without its trailer.
+		// The scanner has already returned what was decompressed, with
+		// the cut-off last line deferred below like an unterminated one.
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("failed to scan file: %w", err)
+		}
+		logger.Debugf("Compressed file %s ends mid-stream, reading up to line %d", file.Path, lineNum)
+	}
+
+	// A final line without its trailing newline, or that isn't valid JSON,
+	// is most likely still being written. Uploading it now would send the
+	// truncated line and then, once complete, the same line number again,
+	// so hold it back until it is terminated and parses, or another line
+	// follows it. Only the file's last line is deferred; invalid lines in
+	// the middle are uploaded as-is. The scanner has consumed the file, so
+	// the file position is EOF; a tracked offset past it means the last
+	// line had no newline (currentOffset counts one for every line).
+	if !stoppedEarly && len(lines) > 0 && !t.uploadPartialTail && !t.flushTail.Load() {
+		eof := position()
+		if unterminated := currentOffset > eof; unterminated || !lastLineComplete {
+			logger.Debug("Deferring incomplete last line until it is complete",
+				"line", lineNum, "file", file.Path, "unterminated", unterminated)
+			lines = lines[:len(lines)-1]
+			currentOffset -= int64(lastLineBytes)
+			newOffset = currentOffset
+		}
 	}
 
 	if len(lines) == 0 {
@@ -440,19 +886,21 @@ func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxByte
 	// files without trailing newlines, Seek and the tracked currentOffset could differ.
 	// This is acceptable since Claude Code always writes properly formatted JSONL.
 	if newOffset == 0 {
-		seekOffset, _ := f.Seek(0, io.SeekCurrent)
+		seekOffset := position()
 		// Detect offset discrepancy that could indicate a malformed file
 		if seekOffset != currentOffset {
-			logger.Debug("Offset discrepancy in %s: tracked=%d, seek=%d (possible missing trailing newline)",
-				file.Path, currentOffset, seekOffset)
+			logger.Debug("Offset discrepancy (possible missing trailing newline)",
+				"file", file.Path, "tracked", currentOffset, "seek", seekOffset)
 		}
 		newOffset = seekOffset
 	}
 
-	// Build metadata for backend (git info only)
-	if gitInfo != nil {
+	// Build metadata for backend
+	if gitInfo != nil || usage.model != "" || usage.total != nil {
 		metadata = &ChunkMetadata{
-			GitInfo: gitInfo,
+			GitInfo:    gitInfo,
+			ModelName:  usage.model,
+			TokenUsage: usage.total,
 		}
 	}
 
@@ -464,6 +912,9 @@ func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxByte
 		NewOffset: newOffset,
 		Metadata:  metadata,
 		AgentIDs:  agentIDs, // Local use only, not sent to backend
+		ModTime:   modTime,
+
+		AttachmentPaths: attachmentPaths,
 	}, nil
 }
 
@@ -471,6 +922,9 @@ func (t *FileTracker) ReadChunk(file *TrackedFile, r *redactor.Redactor, maxByte
 // This updates both the sync position and the cached file stats (modtime/size)
 // so HasFileChanged won't re-trigger until the file actually changes again.
 func (t *FileTracker) UpdateAfterSync(file *TrackedFile, lastLine int, newOffset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	file.LastSyncedLine = lastLine
 	file.ByteOffset = newOffset
 
@@ -481,22 +935,46 @@ func (t *FileTracker) UpdateAfterSync(file *TrackedFile, lastLine int, newOffset
 	}
 }
 
+// agentIDPattern is the allowlist for agent IDs used to build agent file
+// names: lowercase hex in the legacy 8-character form, and the longer
+// lowercase IDs newer Claude versions write (e.g. "a3eaf63159a07953f",
+// "acompact-2aaa241e456ebc94"). Nothing that could form a path ('/', '.')
+// gets through.
+var agentIDPattern = regexp.MustCompile(`^[0-9a-z_-]{6,128}$`)
+
+// isValidAgentID reports whether id is safe to turn into an
+// agent-<id>.jsonl (or AgentExtractor.ChildFileName) path under the
+// subagents directory. IDs come from
+// transcript JSON (and state files), so a crafted value must not reach
+// filepath.Join.
+func isValidAgentID(id string) bool {
+	return agentIDPattern.MatchString(id)
+}
+
 // DiscoverNewFiles checks for new agent files based on agent IDs
 // discovered in previous chunk reads, and also scans the subagents
-// directory for any agent files not already tracked.
+// directory for any agent files not already tracked. File names come from
+// the tracker's AgentExtractor. IDs failing
+// isValidAgentID are logged and skipped.
 // Returns newly discovered files.
 func (t *FileTracker) DiscoverNewFiles(newAgentIDs []string) []*TrackedFile {
+	t.discoverMu.Lock()
+	defer t.discoverMu.Unlock()
 	var newFiles []*TrackedFile
 
 	// Add new agent IDs to known set
 	for _, agentID := range newAgentIDs {
+		if !isValidAgentID(agentID) {
+			logger.Warnf("Skipping invalid agent ID %q found in transcript", agentID)
+			continue
+		}
 		t.knownAgentIDs[agentID] = true
 	}
 
 	// Check all known agent IDs for files that now exist
 	for agentID := range t.knownAgentIDs {
-		agentFileName := fmt.Sprintf("agent-%s.jsonl", agentID)
-		if t.IsTracked(agentFileName) {
+		agentFileName := t.childFileName(agentID)
+		if agentFileName == "" || t.IsTracked(agentFileName) {
 			continue
 		}
 		if tracked := t.trackAgentFile(agentFileName); tracked != nil {
@@ -511,7 +989,7 @@ func (t *FileTracker) DiscoverNewFiles(newAgentIDs []string) []*TrackedFile {
 	if err == nil {
 		for _, entry := range entries {
 			name := entry.Name()
-			if entry.IsDir() || !strings.HasPrefix(name, "agent-") || !strings.HasSuffix(name, ".jsonl") {
+			if entry.IsDir() || !t.isChildFileName(name) {
 				continue
 			}
 			if t.IsTracked(name) {
@@ -526,6 +1004,31 @@ func (t *FileTracker) DiscoverNewFiles(newAgentIDs []string) []*TrackedFile {
 	return newFiles
 }
 
+// KnownAgentIDs returns every agent ID the tracker has discovered so far,
+// sorted for stable persistence. The daemon saves this into its state file
+// so a restart can re-check disk for agents referenced before it.
+func (t *FileTracker) KnownAgentIDs() []string {
+	ids := make([]string, 0, len(t.knownAgentIDs))
+	for id := range t.knownAgentIDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SeedKnownAgentIDs adds previously-discovered agent IDs (e.g. loaded from a
+// prior daemon's state file) to the known set. DiscoverNewFiles then keeps
+// checking disk for their files even though the transcript lines that
+// referenced them were synced before the restart and won't be re-read.
+// Invalid IDs (see isValidAgentID) are dropped.
+func (t *FileTracker) SeedKnownAgentIDs(ids []string) {
+	for _, id := range ids {
+		if isValidAgentID(id) {
+			t.knownAgentIDs[id] = true
+		}
+	}
+}
+
 // trackAgentFile attempts to start tracking an agent file by name.
 // Returns the TrackedFile if the file exists on disk, nil otherwise.
 func (t *FileTracker) trackAgentFile(fileName string) *TrackedFile {
@@ -538,7 +1041,9 @@ func (t *FileTracker) trackAgentFile(fileName string) *TrackedFile {
 		Name: fileName,
 		Type: provider.FileTypeAgent,
 	}
+	t.mu.Lock()
 	t.files[fileName] = tracked
+	t.mu.Unlock()
 	return tracked
 }
 
@@ -578,3 +1083,78 @@ func (t *FileTracker) AddCodexRollout(path, fileName string, isRoot bool, meta C
 	t.files[fileName] = tracked
 	return tracked
 }
+
+// SeedOffsetHints records read positions persisted by a previous daemon run.
+// The next InitFromBackendState applies a hint to its file when the hint
+// still describes it: same LastSyncedLine as the backend reports, and the
+// file on disk has not shrunk (nor changed in place at the same size).
+// Otherwise the file is re-scanned from the start as usual.
+func (t *FileTracker) SeedOffsetHints(hints map[string]FileOffset) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.offsetHints = hints
+}
+
+// Offsets returns the read position of every tracked file that has one,
+// for persisting and later passing to SeedOffsetHints.
+func (t *FileTracker) Offsets() map[string]FileOffset {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	offsets := make(map[string]FileOffset, len(t.files))
+	for name, f := range t.files {
+		if f.ByteOffset > 0 && f.LastSyncedLine > 0 {
+			offsets[name] = FileOffset{
+				LastSyncedLine: f.LastSyncedLine,
+				ByteOffset:     f.ByteOffset,
+				LastSize:       f.LastSize,
+				LastModTime:    f.LastModTime,
+			}
+		}
+	}
+	return offsets
+}
+
+// Paths returns the local path of every tracked file present on disk
+// (backend file name → path), skipping remote-only files.
+func (t *FileTracker) Paths() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	paths := make(map[string]string, len(t.files))
+	for name, f := range t.files {
+		if !f.RemoteOnly && f.Path != "" {
+			paths[name] = f.Path
+		}
+	}
+	return paths
+}
+
+// applyOffsetHint seeds f's read position from a persisted hint if the hint
+// is still valid for the file on disk. Caller holds t.mu.
+func (t *FileTracker) applyOffsetHint(f *TrackedFile) {
+	hint, ok := t.offsetHints[f.Name]
+	if !ok || hint.LastSyncedLine != f.LastSyncedLine || hint.ByteOffset <= 0 {
+		return
+	}
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return
+	}
+	if info.Size() < hint.LastSize || hint.ByteOffset > info.Size() ||
+		(info.Size() == hint.LastSize && !info.ModTime().Equal(hint.LastModTime)) {
+		logger.Debug("Discarding stale offset hint", "file", f.Name, "size", info.Size(), "hinted_size", hint.LastSize)
+		return
+	}
+	f.ByteOffset = hint.ByteOffset
+	f.LastSize = hint.LastSize
+	f.LastModTime = hint.LastModTime
+}
+
+// redactLine runs line through r, or with no redactor (redaction
+// disabled) strips just the bypass marker, which never reaches the
+// backend either way.
+func (t *FileTracker) redactLine(r *redactor.Redactor, line string) string {
+	if r != nil {
+		return r.RedactJSONLine(line)
+	}
+	return redactor.StripBypassMarker(line, t.bypassMarker)
+}
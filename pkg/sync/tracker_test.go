@@ -1,13 +1,17 @@
 package sync
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ConfabulousDev/confab/pkg/config"
 	"github.com/ConfabulousDev/confab/pkg/redactor"
@@ -78,6 +82,117 @@ func TestFileTracker_InitFromBackendState(t *testing.T) {
 	}
 }
 
+func TestFileTracker_InitFromBackendState_RemoteOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
+	os.WriteFile(transcriptPath, []byte(`{"type":"system"}`+"\n"), 0644)
+
+	ft := NewFileTracker(transcriptPath)
+	ft.InitFromBackendState(map[string]FileState{
+		"transcript.jsonl":     {LastSyncedLine: 1},
+		"agent-remote01.jsonl": {LastSyncedLine: 2},
+	})
+
+	agent := ft.files["agent-remote01.jsonl"]
+	if agent == nil {
+		t.Fatal("expected backend agent file to be tracked")
+	}
+	if !agent.RemoteOnly {
+		t.Error("expected missing agent file to be tracked as remote-only")
+	}
+	if ft.GetTranscriptFile().RemoteOnly {
+		t.Error("transcript must never be remote-only")
+	}
+	if ft.HasFileChanged(agent) {
+		t.Error("expected no change for remote-only file still missing on disk")
+	}
+
+	// File appears locally (e.g. user copied it over): it becomes a normal
+	// tracked file and resumes after the backend's last synced line.
+	os.MkdirAll(ft.subagentsDir, 0755)
+	os.WriteFile(agent.Path, []byte("{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n"), 0644)
+	if !ft.HasFileChanged(agent) {
+		t.Fatal("expected change once remote-only file appears on disk")
+	}
+	if agent.RemoteOnly {
+		t.Error("expected RemoteOnly to be cleared once the file exists")
+	}
+	chunk, err := ft.ReadChunk(agent, nil, DefaultMaxChunkBytes)
+	if err != nil {
+		t.Fatalf("ReadChunk failed: %v", err)
+	}
+	if chunk == nil || chunk.FirstLine != 3 || len(chunk.Lines) != 1 {
+		t.Errorf("expected single line 3 after appearing, got %+v", chunk)
+	}
+}
+
+func TestFileTracker_OffsetHints(t *testing.T) {
+	content := "{\"n\":1}\n{\"n\":2}\n"
+	newTracker := func(t *testing.T) (*FileTracker, string, FileOffset) {
+		t.Helper()
+		transcriptPath := filepath.Join(t.TempDir(), "transcript.jsonl")
+		os.WriteFile(transcriptPath, []byte(content), 0644)
+		info, _ := os.Stat(transcriptPath)
+		hint := FileOffset{LastSyncedLine: 2, ByteOffset: int64(len(content)), LastSize: info.Size(), LastModTime: info.ModTime()}
+		return NewFileTracker(transcriptPath), transcriptPath, hint
+	}
+
+	t.Run("valid hint seeds offset", func(t *testing.T) {
+		ft, path, hint := newTracker(t)
+		f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		f.WriteString("{\"n\":3}\n")
+		f.Close()
+
+		ft.SeedOffsetHints(map[string]FileOffset{"transcript.jsonl": hint})
+		ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 2}})
+		if got := ft.GetTranscriptFile().ByteOffset; got != hint.ByteOffset {
+			t.Errorf("ByteOffset = %d, want %d from hint", got, hint.ByteOffset)
+		}
+		if got := ft.Offsets()["transcript.jsonl"]; got.ByteOffset != hint.ByteOffset {
+			t.Errorf("Offsets() = %+v, want byte offset %d", got, hint.ByteOffset)
+		}
+	})
+
+	t.Run("backend line mismatch ignores hint", func(t *testing.T) {
+		ft, _, hint := newTracker(t)
+		ft.SeedOffsetHints(map[string]FileOffset{"transcript.jsonl": hint})
+		ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 1}})
+		if got := ft.GetTranscriptFile().ByteOffset; got != 0 {
+			t.Errorf("ByteOffset = %d, want 0 (full scan)", got)
+		}
+	})
+
+	t.Run("shrunk file ignores hint", func(t *testing.T) {
+		ft, path, hint := newTracker(t)
+		os.WriteFile(path, []byte("{\"n\":1}\n"), 0644)
+		ft.SeedOffsetHints(map[string]FileOffset{"transcript.jsonl": hint})
+		ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 2}})
+		if got := ft.GetTranscriptFile().ByteOffset; got != 0 {
+			t.Errorf("ByteOffset = %d, want 0 (full scan)", got)
+		}
+	})
+
+	t.Run("same size but modified ignores hint", func(t *testing.T) {
+		ft, path, hint := newTracker(t)
+		hint.LastModTime = hint.LastModTime.Add(-time.Hour)
+		ft.SeedOffsetHints(map[string]FileOffset{"transcript.jsonl": hint})
+		ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 2}})
+		if got := ft.GetTranscriptFile().ByteOffset; got != 0 {
+			t.Errorf("ByteOffset = %d, want 0 (full scan) for %s", got, path)
+		}
+	})
+
+	t.Run("hints apply to the first init only", func(t *testing.T) {
+		ft, _, hint := newTracker(t)
+		ft.SeedOffsetHints(map[string]FileOffset{"transcript.jsonl": hint})
+		ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 2}})
+		ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 2}})
+		if got := ft.GetTranscriptFile().ByteOffset; got != 0 {
+			t.Errorf("ByteOffset = %d after refresh, want 0", got)
+		}
+	})
+}
+
 func TestFileTracker_ReadChunk_AllLines(t *testing.T) {
 	tmpDir := t.TempDir()
 	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
@@ -364,6 +479,105 @@ func TestFileTracker_ReadChunk_AppliesRedactor(t *testing.T) {
 	}
 }
 
+// TestFileTracker_ReadChunk_BypassMarker verifies a line carrying the
+// configured bypass marker is uploaded unredacted, that the marker field is
+// stripped from the uploaded content, and that unmarked lines in the same
+// chunk are still redacted.
+func TestFileTracker_ReadChunk_BypassMarker(t *testing.T) {
+	useDefaults := false
+	r, err := redactor.NewFromConfig(&config.RedactionConfig{
+		UseDefaultPatterns: &useDefaults,
+		Patterns: []config.RedactionPattern{
+			{Name: "test-secret", Pattern: `SECRET-VALUE-\d+`, Type: "test"},
+		},
+		BypassMarker: "_confab_noredact",
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+
+	transcriptPath := filepath.Join(t.TempDir(), "transcript.jsonl")
+	content := `{"type":"user","message":"fixture id SECRET-VALUE-123","_confab_noredact":true}` + "\n" +
+		`{"type":"user","message":"my key is SECRET-VALUE-456"}` + "\n"
+	os.WriteFile(transcriptPath, []byte(content), 0644)
+
+	ft := NewFileTracker(transcriptPath)
+	ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 0}})
+	chunk, err := ft.ReadChunk(ft.GetTranscriptFile(), r, DefaultMaxChunkBytes)
+	if err != nil {
+		t.Fatalf("ReadChunk: %v", err)
+	}
+	if chunk == nil || len(chunk.Lines) != 2 {
+		t.Fatalf("expected 2 lines, got chunk=%v", chunk)
+	}
+
+	if want := `{"message":"fixture id SECRET-VALUE-123","type":"user"}`; chunk.Lines[0] != want {
+		t.Errorf("marked line = %q, want %q (unredacted, marker stripped)", chunk.Lines[0], want)
+	}
+	if strings.Contains(chunk.Lines[1], "SECRET-VALUE-456") {
+		t.Errorf("unmarked line was not redacted: %q", chunk.Lines[1])
+	}
+}
+
+// TestFileTracker_ReadChunk_BypassMarkerWithoutRedactor verifies that with
+// redaction disabled (no redactor) the marker field is still stripped, and
+// every other line is uploaded byte-for-byte.
+func TestFileTracker_ReadChunk_BypassMarkerWithoutRedactor(t *testing.T) {
+	transcriptPath := filepath.Join(t.TempDir(), "transcript.jsonl")
+	content := `{"type":"user","message":"hi","_confab_noredact":true}` + "\n" +
+		`{"type":"user", "message":"plain"}` + "\n"
+	os.WriteFile(transcriptPath, []byte(content), 0644)
+
+	ft := NewFileTracker(transcriptPath)
+	ft.bypassMarker = BypassMarker(&config.UploadConfig{Redaction: &config.RedactionConfig{BypassMarker: "_confab_noredact"}})
+	ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 0}})
+	chunk, err := ft.ReadChunk(ft.GetTranscriptFile(), nil, DefaultMaxChunkBytes)
+	if err != nil {
+		t.Fatalf("ReadChunk: %v", err)
+	}
+	if chunk == nil || len(chunk.Lines) != 2 {
+		t.Fatalf("expected 2 lines, got chunk=%v", chunk)
+	}
+	if want := `{"message":"hi","type":"user"}`; chunk.Lines[0] != want {
+		t.Errorf("marked line = %q, want %q (marker stripped)", chunk.Lines[0], want)
+	}
+	if want := `{"type":"user", "message":"plain"}`; chunk.Lines[1] != want {
+		t.Errorf("unmarked line = %q, want %q (unchanged)", chunk.Lines[1], want)
+	}
+}
+
+func TestFileTracker_ReadChunk_EntropyRedaction(t *testing.T) {
+	useDefaults := false
+	r, err := redactor.NewFromConfig(&config.RedactionConfig{
+		UseDefaultPatterns: &useDefaults,
+		Entropy:            &config.EntropyConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+
+	transcriptPath := filepath.Join(t.TempDir(), "transcript.jsonl")
+	content := `{"type":"user","message":"the vendor token is Zx9Qm2Lr7Tk4Wv8Bn3Hc6Jp1Yd5Fs0Ga"}` + "\n" +
+		`{"type":"user","message":"please refactor the session handler"}` + "\n"
+	os.WriteFile(transcriptPath, []byte(content), 0644)
+
+	ft := NewFileTracker(transcriptPath)
+	ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 0}})
+	chunk, err := ft.ReadChunk(ft.GetTranscriptFile(), r, DefaultMaxChunkBytes)
+	if err != nil {
+		t.Fatalf("ReadChunk: %v", err)
+	}
+	if chunk == nil || len(chunk.Lines) != 2 {
+		t.Fatalf("expected 2 lines, got chunk=%v", chunk)
+	}
+	if !strings.Contains(chunk.Lines[0], "the vendor token is [REDACTED:HIGH_ENTROPY]") {
+		t.Errorf("high-entropy token not redacted: %q", chunk.Lines[0])
+	}
+	if strings.Contains(chunk.Lines[1], "REDACTED") {
+		t.Errorf("prose was redacted: %q", chunk.Lines[1])
+	}
+}
+
 func TestFileTracker_ReadChunk_ExtractsGitInfo(t *testing.T) {
 	tmpDir := t.TempDir()
 	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
@@ -737,6 +951,80 @@ func TestFileTracker_DiscoverNewFiles_MissingAgent(t *testing.T) {
 	}
 }
 
+func TestFileTracker_SeedKnownAgentIDs(t *testing.T) {
+	tmpDir := t.TempDir()
+	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
+
+	ft := NewFileTracker(transcriptPath)
+	ft.SeedKnownAgentIDs([]string{"bbb22222", "", "aaa11111"})
+
+	got := ft.KnownAgentIDs()
+	want := []string{"aaa11111", "bbb22222"}
+	if len(got) != len(want) {
+		t.Fatalf("KnownAgentIDs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("KnownAgentIDs()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// A seeded ID is re-checked on disk without being re-reported by a chunk.
+	os.MkdirAll(ft.subagentsDir, 0755)
+	agentPath := filepath.Join(ft.subagentsDir, "agent-aaa11111.jsonl")
+	if err := os.WriteFile(agentPath, []byte(`{"line": 1}`), 0644); err != nil {
+		t.Fatalf("failed to write agent file: %v", err)
+	}
+	newFiles := ft.DiscoverNewFiles(nil)
+	if len(newFiles) != 1 || newFiles[0].Name != "agent-aaa11111.jsonl" {
+		t.Errorf("expected seeded agent to be discovered, got %v", newFiles)
+	}
+}
+
+func TestFileTracker_ReadChunk_ModelAndTokenUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
+
+	// msg_1 spans two lines (one per content block) repeating its usage;
+	// msg_2 has a later model. The malformed line and the usage of the
+	// wrong type are skipped.
+	content := `{"type":"user","message":{"content":"hi"}}
+{"type":"assistant","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":100,"output_tokens":20,"cache_read_input_tokens":500}}}
+{"type":"assistant","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":100,"output_tokens":20,"cache_read_input_tokens":500}}}
+{"type":"assistant","message":{"id":"msg_2","model":"claude-opus-4-1","usage":{"input_tokens":10,"output_tokens":5,"cache_creation_input_tokens":7}}}
+{"type":"assistant","message":{"id":"msg_3","usage":"lots"}}
+{"type":"assistant","message":
+`
+	if err := os.WriteFile(transcriptPath, []byte(content+`{"type":"user"}`+"\n"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	ft := NewFileTracker(transcriptPath)
+	ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 0}})
+	chunk, err := ft.ReadChunk(ft.GetTranscriptFile(), nil, DefaultMaxChunkBytes)
+	if err != nil {
+		t.Fatalf("failed to read chunk: %v", err)
+	}
+	if chunk == nil || len(chunk.Lines) != 7 || chunk.Metadata == nil {
+		t.Fatalf("chunk = %+v, want 7 lines with metadata", chunk)
+	}
+	if got := chunk.Metadata.ModelName; got != "claude-opus-4-1" {
+		t.Errorf("ModelName = %q, want the last assistant model", got)
+	}
+	want := TokenUsage{InputTokens: 110, OutputTokens: 25, CacheCreationInputTokens: 7, CacheReadInputTokens: 500, TotalTokens: 642}
+	if got := chunk.Metadata.TokenUsage; got == nil || *got != want {
+		t.Errorf("TokenUsage = %+v, want %+v", got, want)
+	}
+
+	// Without assistant lines there is no usage metadata.
+	os.WriteFile(transcriptPath, []byte(`{"type":"user","message":{"content":"hi"}}`+"\n"), 0644)
+	ft = NewFileTracker(transcriptPath)
+	ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 0}})
+	if chunk, err := ft.ReadChunk(ft.GetTranscriptFile(), nil, DefaultMaxChunkBytes); err != nil || chunk.Metadata != nil {
+		t.Errorf("ReadChunk = %+v, %v; want no metadata", chunk, err)
+	}
+}
+
 func TestFileTracker_ReadChunk_MalformedJSON(t *testing.T) {
 	tmpDir := t.TempDir()
 	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
@@ -1480,3 +1768,248 @@ func TestFileTracker_RegisterSidechainFile_OverwritesPreservingPosition(t *testi
 		t.Errorf("tracked files = %d, want 1 (in-place correction, no dup)", got)
 	}
 }
+
+func TestCountLines(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"", 0},
+		{"a\n", 1},
+		{"a\nb\n", 2},
+		{"a\nb", 2}, // trailing partial line
+		{"a\n\nb\nc\n\n", 5},
+		{strings.Repeat("x\n", 100000), 100000}, // spans several read blocks
+	}
+	for i, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("f%d.jsonl", i))
+		os.WriteFile(path, []byte(tt.content), 0644)
+		got, err := CountLines(path)
+		if err != nil {
+			t.Fatalf("CountLines: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("case %d: CountLines = %d, want %d", i, got, tt.want)
+		}
+	}
+	if _, err := CountLines(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestEstimateLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "transcript.jsonl")
+	line := `{"type":"user","message":"` + strings.Repeat("x", 90) + `"}` + "\n" // 100 bytes
+	os.WriteFile(path, []byte(strings.Repeat(line, 20000)), 0644)
+
+	n, estimated, err := EstimateLines(path)
+	if err != nil || estimated || n != 20000 {
+		t.Errorf("below threshold: EstimateLines = %d, %v, %v; want exact 20000", n, estimated, err)
+	}
+
+	old := lineEstimateThreshold
+	lineEstimateThreshold = 1024
+	defer func() { lineEstimateThreshold = old }()
+	n, estimated, err = EstimateLines(path)
+	if err != nil || !estimated {
+		t.Fatalf("above threshold: EstimateLines = %d, %v, %v; want an estimate", n, estimated, err)
+	}
+	if n < 19000 || n > 21000 {
+		t.Errorf("estimate = %d, want within 5%% of 20000", n)
+	}
+
+	if _, _, err := EstimateLines(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestFileTracker_ReadChunk_PartialTail(t *testing.T) {
+	tmpDir := t.TempDir()
+	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
+	// An invalid line in the middle is uploaded; only the tail is deferred.
+	content := `{"line": 1}` + "\n" + `not json` + "\n" + `{"line": 3}` + "\n" + `{"line": ` + "\n"
+	if err := os.WriteFile(transcriptPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	ft := NewFileTracker(transcriptPath)
+	ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 0}})
+	chunk, err := ft.ReadChunk(ft.GetTranscriptFile(), nil, DefaultMaxChunkBytes)
+	if err != nil {
+		t.Fatalf("ReadChunk: %v", err)
+	}
+	if chunk == nil || len(chunk.Lines) != 3 {
+		t.Fatalf("chunk = %+v, want lines 1-3", chunk)
+	}
+	if want := int64(len(content) - len(`{"line": `+"\n")); chunk.NewOffset != want {
+		t.Errorf("NewOffset = %d, want %d (start of the deferred line)", chunk.NewOffset, want)
+	}
+
+	// With upload_partial_tail, the tail goes out as-is.
+	ft.uploadPartialTail = true
+	chunk, err = ft.ReadChunk(ft.GetTranscriptFile(), nil, DefaultMaxChunkBytes)
+	if err != nil {
+		t.Fatalf("ReadChunk: %v", err)
+	}
+	if chunk == nil || len(chunk.Lines) != 4 {
+		t.Errorf("chunk with uploadPartialTail = %+v, want all 4 lines", chunk)
+	}
+}
+
+// writeGzipLines writes lines as a gzip-compressed JSONL file.
+func writeGzipLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for _, line := range lines {
+		fmt.Fprintln(zw, line)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+}
+
+func TestFileTracker_ReadChunk_Gzip(t *testing.T) {
+	tmpDir := t.TempDir()
+	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl.gz")
+	var lines []string
+	for i := 1; i <= 5; i++ {
+		lines = append(lines, fmt.Sprintf(`{"line": %d}`, i))
+	}
+	writeGzipLines(t, transcriptPath, lines[:3])
+
+	ft := NewFileTracker(transcriptPath)
+	if want := filepath.Join(tmpDir, "transcript", "subagents"); ft.SubagentsDir() != want {
+		t.Errorf("SubagentsDir() = %q, want %q", ft.SubagentsDir(), want)
+	}
+	ft.InitFromBackendState(map[string]FileState{"transcript.jsonl.gz": {LastSyncedLine: 0}})
+	file := ft.GetTranscriptFile()
+
+	// Room for two lines per chunk (each line is 11 bytes + 4 overhead).
+	const maxBytes = 30
+	read := func(wantFirst int, wantLines ...string) {
+		t.Helper()
+		chunk, err := ft.ReadChunk(file, nil, maxBytes)
+		if err != nil {
+			t.Fatalf("ReadChunk: %v", err)
+		}
+		if chunk == nil {
+			t.Fatalf("ReadChunk = nil, want lines from %d", wantFirst)
+		}
+		if chunk.FirstLine != wantFirst || !slices.Equal(chunk.Lines, wantLines) {
+			t.Fatalf("chunk = FirstLine %d %q, want FirstLine %d %q", chunk.FirstLine, chunk.Lines, wantFirst, wantLines)
+		}
+		ft.UpdateAfterSync(file, chunk.FirstLine+len(chunk.Lines)-1, chunk.NewOffset)
+	}
+
+	read(1, lines[0], lines[1])
+	read(3, lines[2])
+	if chunk, err := ft.ReadChunk(file, nil, maxBytes); err != nil || chunk != nil {
+		t.Fatalf("ReadChunk at end = %+v, %v; want nil", chunk, err)
+	}
+
+	// Rotation rewrites the whole compressed stream; reading resumes by
+	// line number, not by a (meaningless) compressed byte offset.
+	writeGzipLines(t, transcriptPath, lines)
+	if !ft.HasFileChanged(file) {
+		t.Error("HasFileChanged = false after the compressed file grew")
+	}
+	if shrunk := ft.ShrunkFiles(); len(shrunk) != 0 {
+		t.Errorf("ShrunkFiles() = %v, want none (offsets of compressed files are decompressed)", shrunk)
+	}
+	read(4, lines[3], lines[4])
+}
+
+func TestFileTracker_ReadChunk_SkipOversizeLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
+	huge := `{"line": 2, "padding": "` + strings.Repeat("x", 500) + `"}`
+	content := `{"line": 1}` + "\n" + huge + "\n" + `{"line": 3}` + "\n"
+	if err := os.WriteFile(transcriptPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	ft := NewFileTracker(transcriptPath)
+	ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 0}})
+	file := ft.GetTranscriptFile()
+
+	// By default the oversize line stops the file after line 1.
+	chunk, err := ft.ReadChunk(file, nil, 200)
+	if err != nil || chunk == nil || len(chunk.Lines) != 1 {
+		t.Fatalf("ReadChunk = %+v, %v; want line 1 alone", chunk, err)
+	}
+	ft.UpdateAfterSync(file, 1, chunk.NewOffset)
+	if _, err := ft.ReadChunk(file, nil, 200); err == nil || !strings.Contains(err.Error(), "line 2 exceeds max chunk size") {
+		t.Fatalf("ReadChunk error = %v, want line 2 too large", err)
+	}
+
+	ft.skipOversizeLines = true
+	chunk, err = ft.ReadChunk(file, nil, 200)
+	if err != nil {
+		t.Fatalf("ReadChunk with skipOversizeLines: %v", err)
+	}
+	want := []string{oversizePlaceholder(2, len(huge)+4), `{"line": 3}`}
+	if chunk == nil || chunk.FirstLine != 2 || !slices.Equal(chunk.Lines, want) {
+		t.Fatalf("chunk = %+v, want placeholder for line 2 then line 3", chunk)
+	}
+	if !json.Valid([]byte(chunk.Lines[0])) {
+		t.Errorf("placeholder %q is not valid JSON", chunk.Lines[0])
+	}
+	if chunk.NewOffset != int64(len(content)) {
+		t.Errorf("NewOffset = %d, want %d (past the oversize line)", chunk.NewOffset, len(content))
+	}
+}
+
+func TestIsValidAgentID(t *testing.T) {
+	for _, tt := range []struct {
+		id   string
+		want bool
+	}{
+		{"abc12345", true},
+		{"a3eaf63159a07953f", true},
+		{"acompact-2aaa241e456ebc94", true},
+		{"aprompt_suggestion-ba74af", true},
+		{"", false},
+		{"../secret", false},
+		{"../../../../etc/passwd", false},
+		{"ABCD1234", false},
+		{"abcD1234", false},
+		{"abc/1234", false},
+		{"abc.1234", false},
+		{"abc", false},
+		{strings.Repeat("a", 129), false},
+	} {
+		if got := isValidAgentID(tt.id); got != tt.want {
+			t.Errorf("isValidAgentID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+// TestFileTracker_DiscoverNewFiles_SkipsInvalidAgentIDs: agent IDs that
+// fail validation are neither remembered nor tracked, even when the path
+// they form exists.
+func TestFileTracker_DiscoverNewFiles_SkipsInvalidAgentIDs(t *testing.T) {
+	tmpDir := t.TempDir()
+	transcriptPath := filepath.Join(tmpDir, "transcript.jsonl")
+	os.WriteFile(transcriptPath, []byte(`{}`), 0644)
+	ft := NewFileTracker(transcriptPath)
+	os.MkdirAll(ft.subagentsDir, 0755)
+	// The file "../secret" would name: subagents/agent-../secret.jsonl.
+	os.MkdirAll(filepath.Join(ft.subagentsDir, "agent-.."), 0755)
+	os.WriteFile(filepath.Join(ft.subagentsDir, "agent-..", "secret.jsonl"), []byte(`{"secret":1}`+"\n"), 0644)
+	ft.InitFromBackendState(map[string]FileState{"transcript.jsonl": {LastSyncedLine: 0}})
+
+	ft.SeedKnownAgentIDs([]string{"../../../../etc/passwd"})
+	newFiles := ft.DiscoverNewFiles([]string{"../secret", "", "ABCD1234"})
+	if len(newFiles) != 0 {
+		t.Errorf("tracked %d file(s) from invalid agent IDs: %s", len(newFiles), newFiles[0].Path)
+	}
+	if ids := ft.KnownAgentIDs(); len(ids) != 0 {
+		t.Errorf("KnownAgentIDs = %q, want none", ids)
+	}
+}
@@ -0,0 +1,239 @@
+package sync
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/ConfabulousDev/confab/pkg/types"
+)
+
+// FileVerification is the local-vs-backend comparison for one file.
+type FileVerification struct {
+	FileName     string `json:"file_name"`
+	LocalLines   int    `json:"local_lines"`
+	BackendLines int    `json:"backend_lines"`
+	// LocalMissing is set when the backend has the file but it can't be
+	// read locally (e.g. an agent file that has since been deleted).
+	LocalMissing bool `json:"local_missing,omitempty"`
+	// LocalHash and BackendHash are only set when content hashes were
+	// compared, which happens only for files whose line counts agree.
+	LocalHash   string `json:"local_hash,omitempty"`
+	BackendHash string `json:"backend_hash,omitempty"`
+}
+
+// Diverged reports whether local and backend disagree for this file.
+func (v FileVerification) Diverged() bool {
+	return v.LocalMissing || v.LocalLines != v.BackendLines || v.LocalHash != v.BackendHash
+}
+
+// RemoteContentFunc writes the backend's stored content for fileName to w.
+type RemoteContentFunc func(fileName string, w io.Writer) error
+
+// Verify compares each file the backend knows about (plus the transcript)
+// against its local copy, without uploading anything. Must be called after
+// Init. When fetch is non-nil, files whose line counts agree also have their
+// content hashed: the local side is redacted line by line exactly as an
+// upload would be, so the two hashes match only if the backend holds the
+// same bytes we would send. Results are sorted by file name.
+func (e *Engine) Verify(fetch RemoteContentFunc) ([]FileVerification, error) {
+	if !e.initialized {
+		return nil, fmt.Errorf("engine not initialized")
+	}
+
+	files := e.tracker.GetTrackedFiles()
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	results := make([]FileVerification, 0, len(files))
+	for _, f := range files {
+		v := FileVerification{FileName: f.Name, BackendLines: f.LastSyncedLine}
+		n, err := CountLines(f.Path)
+		if err != nil {
+			v.LocalMissing = true
+			results = append(results, v)
+			continue
+		}
+		v.LocalLines = n
+
+		if fetch != nil && v.LocalLines == v.BackendLines {
+			if v.LocalHash, err = e.hashLocalLines(f.Path, v.LocalLines); err != nil {
+				return nil, fmt.Errorf("failed to hash %s: %w", f.Name, err)
+			}
+			h := newLineHasher()
+			if err := fetch(f.Name, h); err != nil {
+				return nil, fmt.Errorf("failed to fetch %s: %w", f.Name, err)
+			}
+			v.BackendHash = h.Sum()
+		}
+		results = append(results, v)
+	}
+	return results, nil
+}
+
+// hashLocalLines hashes the first n lines of path after applying the
+// engine's redactor, matching what ReadChunk would have uploaded.
+func (e *Engine) hashLocalLines(path string, n int) (string, error) {
+	h := sha256.New()
+	err := e.forEachRedactedLine(path, n, func(line string) {
+		io.WriteString(h, line)
+		h.Write([]byte{'\n'})
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// forEachRedactedLine calls fn with each of the first n lines of path (all
+// of them when n < 0), redacted as ReadChunk would before upload.
+func (e *Engine) forEachRedactedLine(path string, n int, fn func(line string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, bufio.MaxScanTokenSize), DefaultMaxChunkBytes+types.MaxJSONLLineSize)
+	for i := 0; (n < 0 || i < n) && scanner.Scan(); i++ {
+		fn(e.tracker.redactLine(e.redactor, scanner.Text()))
+	}
+	return scanner.Err()
+}
+
+// LineVerification is the line-by-line comparison of one file against the
+// backend's copy, by checksum.
+type LineVerification struct {
+	FileName   string `json:"file_name"`
+	LocalLines int    `json:"local_lines"`
+	// Missing lists the lines (1-based) past the end of the backend's
+	// copy; Mismatched those it holds with different content.
+	Missing    []int `json:"missing,omitempty"`
+	Mismatched []int `json:"mismatched,omitempty"`
+}
+
+// Diverged reports whether any line is missing or mismatched.
+func (v LineVerification) Diverged() bool {
+	return len(v.Missing) > 0 || len(v.Mismatched) > 0
+}
+
+// Ranges merges the missing and mismatched lines into sorted, inclusive
+// [from, to] runs.
+func (v LineVerification) Ranges() [][2]int {
+	return LineRanges(append(append([]int(nil), v.Mismatched...), v.Missing...))
+}
+
+// LineRanges sorts lines in place and merges them into inclusive
+// [from, to] runs.
+func LineRanges(lines []int) [][2]int {
+	sort.Ints(lines)
+	var ranges [][2]int
+	for _, n := range lines {
+		if last := len(ranges) - 1; last >= 0 && n <= ranges[last][1]+1 {
+			ranges[last][1] = max(ranges[last][1], n)
+			continue
+		}
+		ranges = append(ranges, [2]int{n, n})
+	}
+	return ranges
+}
+
+// checksumBackend is implemented by backends that can compare line
+// checksums against their stored copy of a file (the HTTP client).
+type checksumBackend interface {
+	Checksum(req *ChecksumRequest) (*ChecksumResponse, error)
+}
+
+// VerifyLines compares every line of one tracked file (the transcript when
+// fileName is empty) with the backend's copy by CRC32 of the redacted line.
+// Lines the backend answers for with a mismatch are Mismatched; lines past
+// its last synced line, or that its answer stops short of, are Missing.
+// Must be called after Init.
+func (e *Engine) VerifyLines(fileName string) (*LineVerification, error) {
+	if !e.initialized {
+		return nil, fmt.Errorf("engine not initialized")
+	}
+	cb, ok := e.backend.(checksumBackend)
+	if !ok {
+		return nil, fmt.Errorf("backend does not support line checksums")
+	}
+	file, err := e.trackedFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ChecksumRequest{SessionID: e.sessionID, FileName: file.Name, Lines: []LineChecksum{}}
+	err = e.forEachRedactedLine(file.Path, -1, func(line string) {
+		req.Lines = append(req.Lines, LineChecksum{
+			Line:  len(req.Lines) + 1,
+			CRC32: crc32.ChecksumIEEE([]byte(line)),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	resp, err := cb.Checksum(req)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &LineVerification{FileName: file.Name, LocalLines: len(req.Lines)}
+	for i, l := range req.Lines {
+		switch {
+		case l.Line > file.LastSyncedLine || i >= len(resp.Matches):
+			v.Missing = append(v.Missing, l.Line)
+		case !resp.Matches[i]:
+			v.Mismatched = append(v.Mismatched, l.Line)
+		}
+	}
+	return v, nil
+}
+
+// RepairLines re-uploads v's missing and mismatched ranges with
+// ReplayRange, returning the number of lines sent.
+func (e *Engine) RepairLines(v *LineVerification) (int, error) {
+	sent := 0
+	for _, r := range v.Ranges() {
+		n, err := e.ReplayRange(v.FileName, r[0], r[1])
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// lineHasher is an io.Writer that hashes its input with a trailing newline
+// guaranteed, so backends that omit the final "\n" hash the same as the
+// local file.
+type lineHasher struct {
+	h    hash.Hash
+	last byte
+	n    int64
+}
+
+func newLineHasher() *lineHasher {
+	return &lineHasher{h: sha256.New()}
+}
+
+func (l *lineHasher) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		l.last = p[len(p)-1]
+		l.n += int64(len(p))
+	}
+	return l.h.Write(p)
+}
+
+// Sum returns the hex digest, appending the final newline if missing.
+func (l *lineHasher) Sum() string {
+	if l.n > 0 && l.last != '\n' {
+		l.h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(l.h.Sum(nil))
+}
@@ -317,6 +317,21 @@ type ClaudeHookResponse struct {
 	SystemMessage  string `json:"systemMessage,omitempty"`
 }
 
+// UserPromptSubmitResponse is the JSON response for Claude Code's
+// UserPromptSubmit hook: a ClaudeHookResponse plus, optionally, context
+// added to the prompt.
+type UserPromptSubmitResponse struct {
+	ClaudeHookResponse
+	HookSpecificOutput *UserPromptSubmitOutput `json:"hookSpecificOutput,omitempty"`
+}
+
+// UserPromptSubmitOutput carries the context Claude Code adds alongside the
+// submitted prompt.
+type UserPromptSubmitOutput struct {
+	HookEventName     string `json:"hookEventName"`
+	AdditionalContext string `json:"additionalContext,omitempty"`
+}
+
 // PreToolUseResponse is the JSON response for PreToolUse hooks. The shape
 // is provider-agnostic: Claude Code and Codex both accept the same
 // hookSpecificOutput.permissionDecision contract per their respective